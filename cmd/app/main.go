@@ -40,6 +40,7 @@ import (
 	orderInfrastructure "tsb-service/internal/modules/order/infrastructure"
 	orderInterfaces "tsb-service/internal/modules/order/interfaces"
 	paymentApplication "tsb-service/internal/modules/payment/application"
+	paymentDomain "tsb-service/internal/modules/payment/domain"
 	paymentInfrastructure "tsb-service/internal/modules/payment/infrastructure"
 	paymentInterfaces "tsb-service/internal/modules/payment/interfaces"
 
@@ -56,6 +57,8 @@ import (
 	notificationInfrastructure "tsb-service/internal/modules/notification/infrastructure"
 	restaurantApplication "tsb-service/internal/modules/restaurant/application"
 	restaurantInfrastructure "tsb-service/internal/modules/restaurant/infrastructure"
+	webhookApplication "tsb-service/internal/modules/webhook/application"
+	webhookInfrastructure "tsb-service/internal/modules/webhook/infrastructure"
 	"tsb-service/internal/shared/middleware"
 	"tsb-service/pkg/apns"
 	"tsb-service/pkg/db"
@@ -126,8 +129,15 @@ func main() {
 	broker := pubsub.NewBroker()
 
 	// ENV checks & third-party setup
-	mollieApiKey := os.Getenv("MOLLIE_API_TOKEN")
-	if mollieApiKey == "" {
+	// PAYMENT_PROVIDER=fake swaps Mollie for an in-process fake whose checkout
+	// page lets a developer pay, fail or expire a payment by hand. An unset
+	// APP_ENV counts as production, as for Sentry.
+	useFakePayments := os.Getenv("PAYMENT_PROVIDER") == "fake"
+	if useFakePayments && cmp.Or(os.Getenv("APP_ENV"), "production") == "production" {
+		zap.L().Error("PAYMENT_PROVIDER=fake is not allowed in production")
+		os.Exit(1)
+	}
+	if !useFakePayments && os.Getenv("MOLLIE_API_TOKEN") == "" {
 		zap.L().Error("MOLLIE_API_TOKEN is required")
 		os.Exit(1)
 	}
@@ -148,30 +158,49 @@ func main() {
 	// bounce poller below) so we protect our sender reputation / hard-bounce rate.
 	scaleway.SetSuppressionStore(emailModule.NewSuppressionRepository(dbPool))
 
-	mollieTesting := os.Getenv("MOLLIE_TESTING") == "true"
-	var mollieCfg *mollie.Config
-	if mollieTesting {
-		mollieCfg = mollie.NewAPITestingConfig(true)
-		zap.L().Info("mollie client initialized", zap.String("mode", "testing"))
+	var paymentProvider paymentDomain.PaymentProvider
+	var fakePaymentProvider *paymentInfrastructure.FakeProvider
+	if useFakePayments {
+		apiBaseURL := cmp.Or(os.Getenv("API_BASE_URL"), "http://localhost:8080")
+		fakePaymentProvider = paymentInfrastructure.NewFakeProvider(apiBaseURL+"/api/v1/payments/fake", nil)
+		paymentProvider = fakePaymentProvider
+		zap.L().Warn("payments go through the fake provider", zap.String("checkout", apiBaseURL+"/api/v1/payments/fake"))
 	} else {
-		mollieCfg = mollie.NewAPIConfig(true)
-		zap.L().Info("mollie client initialized", zap.String("mode", "production"))
-	}
-	mollieClient, err := mollie.NewClient(nil, mollieCfg)
-	if err != nil {
-		zap.L().Error("failed to initialize mollie client", zap.Error(err))
-		os.Exit(1)
+		mollieTesting := os.Getenv("MOLLIE_TESTING") == "true"
+		var mollieCfg *mollie.Config
+		if mollieTesting {
+			mollieCfg = mollie.NewAPITestingConfig(true)
+			zap.L().Info("mollie client initialized", zap.String("mode", "testing"))
+		} else {
+			mollieCfg = mollie.NewAPIConfig(true)
+			zap.L().Info("mollie client initialized", zap.String("mode", "production"))
+		}
+		mollieClient, err := mollie.NewClient(nil, mollieCfg)
+		if err != nil {
+			zap.L().Error("failed to initialize mollie client", zap.Error(err))
+			os.Exit(1)
+		}
+		paymentProvider = paymentInfrastructure.NewMollieProvider(*mollieClient)
 	}
 
 	// Repos / services / handlers
 	couponRepo := couponInfrastructure.NewCouponRepository(dbPool)
 	notificationRepo := notificationInfrastructure.NewNotificationRepository(dbPool)
 	orderRepo := orderInfrastructure.NewOrderRepository(dbPool)
+	slotReservationRepo := orderInfrastructure.NewSlotReservationRepository(dbPool)
+	outboxRepo := orderInfrastructure.NewOutboxRepository(dbPool)
+	idempotencyRepo := orderInfrastructure.NewIdempotencyRepository(dbPool)
+	tableRepo := orderInfrastructure.NewTableRepository(dbPool)
+	reportRepo := orderInfrastructure.NewReportRepository(dbPool)
+	deliveryRepo := orderInfrastructure.NewDeliveryRepository(dbPool)
+	etaRepo := orderInfrastructure.NewEtaRepository(dbPool)
 	paymentRepo := paymentInfrastructure.NewPaymentRepository(dbPool)
 	productRepo := productInfrastructure.NewProductRepository(dbPool)
 	restaurantRepo := restaurantInfrastructure.NewRestaurantRepository(dbPool)
 	scheduleOverrideRepo := restaurantInfrastructure.NewScheduleOverrideRepository(dbPool)
+	deliveryZoneRepo := restaurantInfrastructure.NewDeliveryZoneRepository(dbPool)
 	userRepo := userInfrastructure.NewUserRepository(dbPool)
+	webhookRepo := webhookInfrastructure.NewWebhookRepository(dbPool)
 
 	// Google Maps address caching setup
 	googleAPIKey := os.Getenv("GOOGLE_MAPS_API_KEY")
@@ -201,11 +230,39 @@ func main() {
 	addressService := addressApplication.NewAddressService(addressCacheRepo, googleClient, googleLang)
 	couponService := couponApplication.NewCouponService(couponRepo)
 	notificationService := notificationApplication.NewNotificationService(notificationRepo)
-	orderService := orderApplication.NewOrderService(orderRepo, couponService)
+	orderService := orderApplication.NewOrderService(orderRepo, slotReservationRepo)
+	outboxService := orderApplication.NewOutboxService(outboxRepo)
+	idempotencyService := orderApplication.NewIdempotencyService(idempotencyRepo)
+	// Table QR codes are signed with TABLE_QR_SECRET. The ephemeral fallback
+	// voids every printed QR code on restart; production MUST set it.
+	tableQRSecret := []byte(os.Getenv("TABLE_QR_SECRET"))
+	if len(tableQRSecret) == 0 {
+		zap.L().Warn("TABLE_QR_SECRET not set — generating ephemeral secret (table QR codes invalid on restart)")
+		ephemeral := make([]byte, 32)
+		if _, err := cryptoRand.Read(ephemeral); err != nil {
+			zap.L().Error("rand for TABLE_QR_SECRET failed", zap.Error(err))
+			os.Exit(1)
+		}
+		tableQRSecret = ephemeral
+	}
+	tableService := orderApplication.NewTableService(tableRepo, orderRepo, tableQRSecret)
+	deliveryService := orderApplication.NewDeliveryService(deliveryRepo, orderRepo)
+	// The end-of-day report is emailed once the local time is past
+	// DAILY_REPORT_TIME (HH:MM), late enough for the last deliveries.
+	dailyReportTime, err := time.Parse("15:04", cmp.Or(os.Getenv("DAILY_REPORT_TIME"), "23:30"))
+	if err != nil {
+		zap.L().Error("DAILY_REPORT_TIME must be HH:MM", zap.Error(err))
+		os.Exit(1)
+	}
+	reportService := orderApplication.NewReportService(reportRepo,
+		time.Duration(dailyReportTime.Hour())*time.Hour+time.Duration(dailyReportTime.Minute())*time.Minute)
 	productService := productApplication.NewProductService(productRepo)
-	restaurantService := restaurantApplication.NewRestaurantService(restaurantRepo, scheduleOverrideRepo, os.Getenv("APP_ENV") != "production")
+	restaurantService := restaurantApplication.NewRestaurantService(restaurantRepo, scheduleOverrideRepo, deliveryZoneRepo, os.Getenv("APP_ENV") != "production")
 	userService := userApplication.NewUserService(userRepo, zitadelUserFetcher{})
-	paymentService := paymentApplication.NewPaymentService(paymentRepo, *mollieClient, orderService, userService, productService)
+	webhookService := webhookApplication.NewWebhookService(webhookRepo, webhookInfrastructure.NewHTTPSender(nil))
+	pricingService := orderApplication.NewPricingService(orderRepo, productService, restaurantService, addressService, couponService)
+	etaService := orderApplication.NewEtaService(etaRepo, orderRepo, restaurantService, addressService)
+	paymentService := paymentApplication.NewPaymentService(paymentRepo, paymentProvider, orderService, outboxService)
 
 	// OIDC verifier — validates JWTs via JWKS + resolves Zitadel sub → app user UUID
 	zitadelInternalURL := os.Getenv("ZITADEL_INTERNAL_URL") // Optional: internal Docker URL for OIDC discovery
@@ -296,7 +353,8 @@ func main() {
 		}
 	}
 
-	orderHandler := orderInterfaces.NewOrderHandler(orderService, userService, productService)
+	orderHandler := orderInterfaces.NewOrderHandler(orderService, userService, productService, paymentService, tableService)
+	reportHandler := orderInterfaces.NewReportHandler(reportService)
 
 	// Gin HTTP setup
 	router := gin.New()
@@ -377,7 +435,7 @@ func main() {
 	api.GET("/up", healthCheck)
 	api.Use(middleware.LanguageExtractor())
 	api.Use(middleware.DataLoaderMiddleware(
-		orderService, paymentService, productService, userService,
+		orderService, paymentService, productService, userService, etaService,
 	))
 
 	// Per-user rate limit on validateCoupon GraphQL query to block brute-force
@@ -387,12 +445,13 @@ func main() {
 	// GraphQL
 	rootResolver := resolver.NewResolver(
 		broker, apnsClient, fcmClient,
-		addressService, couponService, notificationService, orderService, paymentService, productService, restaurantService, userService, posService,
+		addressService, couponService, deliveryService, etaService, idempotencyService, notificationService, orderService, outboxService, paymentService, pricingService, productService, reportService, restaurantService, tableService, userService, webhookService, posService,
 		couponValidateLimiter,
 	)
 	// Payment webhook depends on the resolver to fan out the new-order push
-	// notification once the Mollie payment transitions to paid.
-	paymentHandler := paymentInterfaces.NewPaymentHandler(paymentService, broker, rootResolver)
+	// notification and the outbound webhooks once the Mollie payment
+	// transitions to paid.
+	paymentHandler := paymentInterfaces.NewPaymentHandler(paymentService, broker)
 	graphqlHandler := resolver.GraphQLHandler(rootResolver, []string{appBaseURL, appDashboardURL, "capacitor://localhost", "https://localhost"}, oidcVerifier)
 	optionalAuth := oidcVerifier.OptionalAuthMiddleware()
 
@@ -427,10 +486,17 @@ func main() {
 	// from an attacker who has guessed a valid tr_* payment ID.
 	mollieLimiter := middleware.NewRateLimiter(1.0, 10) // 60 req/min per IP, burst 10
 	api.POST("/payments/webhook", mollieLimiter.Middleware(), paymentHandler.UpdatePaymentStatusHandler)
+	if fakePaymentProvider != nil {
+		api.GET("/payments/fake/:id", gin.WrapH(fakePaymentProvider))
+		api.POST("/payments/fake/:id", gin.WrapH(fakePaymentProvider))
+	}
 
 	strictAuth := oidcVerifier.StrictAuthMiddleware()
 	api.POST("/images/preview", strictAuth, images.PreviewHandler)
 	api.GET("/orders/:id/invoice", strictAuth, orderHandler.DownloadInvoice)
+	api.GET("/orders/export", strictAuth, orderHandler.ExportOrders)
+	api.GET("/orders/:id/ticket", strictAuth, orderHandler.PrintTicket)
+	api.GET("/reports/daily/:date", strictAuth, reportHandler.DownloadDailyReport)
 
 	feedbackLimiter := middleware.NewRateLimiter(2.0/60, 2) // 2 req/min per IP
 	api.POST("/feedback", feedbackLimiter.Middleware(), feedback.HandleFeedback)
@@ -460,7 +526,9 @@ func main() {
 	}()
 
 	// Periodically purge expired Live Activity tokens (12h TTL) so stale push
-	// tokens don't accumulate. Runs hourly until shutdown.
+	// tokens don't accumulate, expired createOrder idempotency keys, and outbox
+	// messages and webhook deliveries delivered over a week ago. Runs hourly
+	// until shutdown.
 	purgeCtx, stopPurge := context.WithCancel(context.Background())
 	go func() {
 		ticker := time.NewTicker(1 * time.Hour)
@@ -473,6 +541,15 @@ func main() {
 				if err := notificationService.PurgeExpiredLiveActivityTokens(purgeCtx); err != nil {
 					zap.L().Warn("failed to purge expired live activity tokens", zap.Error(err))
 				}
+				if _, err := idempotencyService.PurgeExpired(utils.SetIsAdmin(purgeCtx, true)); err != nil {
+					zap.L().Warn("failed to purge expired idempotency keys", zap.Error(err))
+				}
+				if _, err := outboxService.PurgeDelivered(utils.SetIsAdmin(purgeCtx, true), 7*24*time.Hour); err != nil {
+					zap.L().Warn("failed to purge delivered outbox messages", zap.Error(err))
+				}
+				if _, err := webhookService.PurgeDelivered(utils.SetIsAdmin(purgeCtx, true), 7*24*time.Hour); err != nil {
+					zap.L().Warn("failed to purge delivered webhook deliveries", zap.Error(err))
+				}
 			}
 		}
 	}()
@@ -480,7 +557,8 @@ func main() {
 	// Auto-cancel store-review test orders 10 min after creation so they never
 	// linger as open orders. Admin context → writes via the admin DB pool.
 	// TEMPORARY (revert after launch). Runs every minute until shutdown.
-	sweepCtx, stopSweep := context.WithCancel(utils.SetIsAdmin(context.Background(), true))
+	sweepCtx, stopSweep := context.WithCancel(
+		utils.SetJob(utils.SetIsAdmin(context.Background(), true), "stale-test-order-sweeper"))
 	go func() {
 		ticker := time.NewTicker(1 * time.Minute)
 		defer ticker.Stop()
@@ -499,6 +577,154 @@ func main() {
 		}
 	}()
 
+	// Give back kitchen capacity reserved for orders that were never saved (a
+	// crash between the reservation and the order insert). Admin context →
+	// writes via the admin DB pool. Runs every 5 minutes until shutdown.
+	slotReapCtx, stopSlotReap := context.WithCancel(
+		utils.SetJob(utils.SetIsAdmin(context.Background(), true), "slot-reservation-reaper"))
+	go func() {
+		ticker := time.NewTicker(5 * time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-slotReapCtx.Done():
+				return
+			case <-ticker.C:
+				n, err := orderService.ReapSlotReservations(slotReapCtx)
+				if err != nil {
+					zap.L().Warn("failed to reap slot reservations", zap.Error(err))
+				} else if n > 0 {
+					zap.L().Info("reaped unattached slot reservations", zap.Int("count", n))
+				}
+			}
+		}
+	}()
+
+	// Chase orders nobody confirms: remind staff once, through the outbox,
+	// after the configured escalation delay, then cancel them as KITCHEN_CLOSED after the
+	// cancellation delay; the cancellation refunds, emails the customer and
+	// rolls back the coupon through the outbox. Admin context → writes via the
+	// admin DB pool. Runs every minute until shutdown.
+	unconfirmedCtx, stopUnconfirmed := context.WithCancel(
+		utils.SetJob(utils.SetIsAdmin(context.Background(), true), "unconfirmed-order-sweeper"))
+	go func() {
+		ticker := time.NewTicker(1 * time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-unconfirmedCtx.Done():
+				return
+			case <-ticker.C:
+				config, err := restaurantService.GetConfig(unconfirmedCtx)
+				if err != nil {
+					zap.L().Warn("failed to load restaurant config for unconfirmed orders", zap.Error(err))
+					continue
+				}
+				if after := config.UnconfirmedCancel(); after > 0 {
+					n, err := orderService.CancelUnconfirmedOrders(unconfirmedCtx, after)
+					if err != nil {
+						zap.L().Warn("failed to cancel unconfirmed orders", zap.Error(err))
+					} else if n > 0 {
+						outboxService.Notify()
+						zap.L().Info("cancelled unconfirmed orders", zap.Int("count", n))
+					}
+				}
+				if after := config.UnconfirmedEscalation(); after > 0 {
+					n, err := orderService.EscalateUnconfirmedOrders(unconfirmedCtx, after)
+					if err != nil {
+						zap.L().Warn("failed to escalate unconfirmed orders", zap.Error(err))
+					} else if n > 0 {
+						outboxService.Notify()
+						zap.L().Info("escalated unconfirmed orders to staff", zap.Int("count", n))
+					}
+				}
+			}
+		}
+	}()
+
+	// Reconcile Mollie payments whose webhook never arrived (a Mollie outage,
+	// or our endpoint failing past Mollie's retry window): payments still
+	// unsettled 15 minutes after creation are synced as if the webhook had
+	// arrived, those synced longest ago first. Admin context → admin DB pool. Runs every 5 minutes until
+	// shutdown.
+	reconcileCtx, stopReconcile := context.WithCancel(
+		utils.SetJob(utils.SetIsAdmin(context.Background(), true), "mollie-reconciler"))
+	go paymentHandler.RunReconciler(reconcileCtx, 5*time.Minute, 15*time.Minute)
+
+	// Deliver order side effects (emails, pushes, refunds, subscription
+	// events) from the outbox, retrying failures with backoff. Resolvers wake
+	// the worker after each order change; the poll picks up retries and
+	// messages left by a previous pod. Admin context → admin DB pool.
+	rootResolver.RegisterOutboxHandlers(outboxService)
+	outboxCtx, stopOutbox := context.WithCancel(utils.SetIsAdmin(context.Background(), true))
+	go outboxService.Run(outboxCtx, 10*time.Second)
+
+	// Send signed webhook events to the admin-registered endpoints, retrying
+	// failures with backoff. Emitting an event wakes the worker; the poll
+	// picks up retries. Admin context → admin DB pool.
+	webhookCtx, stopWebhooks := context.WithCancel(utils.SetIsAdmin(context.Background(), true))
+	go webhookService.Run(webhookCtx, 30*time.Second)
+
+	// Release orders scheduled for a later time to the staff's live list once
+	// they are within the configured lead time; the release enqueues their
+	// announcement to staff in the outbox, the way CreateOrder (or the Mollie
+	// webhook) announces an ASAP order. Admin context → writes via the admin
+	// DB pool. Runs every minute until shutdown.
+	releaseCtx, stopRelease := context.WithCancel(utils.SetIsAdmin(context.Background(), true))
+	go func() {
+		ticker := time.NewTicker(1 * time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-releaseCtx.Done():
+				return
+			case <-ticker.C:
+				config, err := restaurantService.GetConfig(releaseCtx)
+				if err != nil {
+					zap.L().Warn("failed to load restaurant config for scheduled order release", zap.Error(err))
+					continue
+				}
+				n, err := orderService.ReleaseScheduledOrders(releaseCtx, config.ScheduledOrderLead())
+				if err != nil {
+					zap.L().Warn("failed to release scheduled orders", zap.Error(err))
+					continue
+				}
+				if n > 0 {
+					outboxService.Notify()
+					zap.L().Info("released scheduled orders to staff", zap.Int("count", n))
+				}
+			}
+		}
+	}()
+
+	// Email the end-of-day report to the restaurant once the day is over. The
+	// lease in daily_reports makes each day go out once across pods and
+	// restarts; a report missed while down, or whose pod died mid-send, goes
+	// out on a later run if its day is still the latest one due. Admin
+	// context → admin DB pool. Checks every 5 minutes until shutdown.
+	reportCtx, stopReport := context.WithCancel(utils.SetIsAdmin(context.Background(), true))
+	go func() {
+		ticker := time.NewTicker(5 * time.Minute)
+		defer ticker.Stop()
+		send := func() {
+			day, err := reportService.SendDueReport(reportCtx, time.Now())
+			if err != nil {
+				zap.L().Warn("failed to send daily report", zap.Error(err))
+			} else if day != nil {
+				zap.L().Info("sent daily report", zap.String("day", day.Format(time.DateOnly)))
+			}
+		}
+		send()
+		for {
+			select {
+			case <-reportCtx.Done():
+				return
+			case <-ticker.C:
+				send()
+			}
+		}
+	}()
+
 	// Periodically pull hard-bounced / undeliverable recipients from Scaleway TEM
 	// into the suppression list so dispatch() stops emailing them, keeping our
 	// hard-bounce rate down. Runs hourly until shutdown; each run re-scans a wide
@@ -530,6 +756,13 @@ func main() {
 	zap.L().Info("shutting down server")
 	stopPurge()
 	stopSweep()
+	stopSlotReap()
+	stopUnconfirmed()
+	stopReconcile()
+	stopRelease()
+	stopOutbox()
+	stopWebhooks()
+	stopReport()
 	stopBouncePoll()
 	authLimiter.Stop()
 	couponValidateLimiter.Stop()
@@ -559,3 +792,7 @@ func (zitadelUserFetcher) FetchUserInfo(ctx context.Context, userID string) (str
 func (zitadelUserFetcher) DeleteUser(ctx context.Context, userID string) error {
 	return auth.DeleteZitadelUser(ctx, userID)
 }
+
+func (zitadelUserFetcher) HasRole(ctx context.Context, userID, role string) (bool, error) {
+	return auth.ZitadelUserHasRole(ctx, userID, role)
+}
@@ -26,6 +26,7 @@ require (
 	github.com/vektah/gqlparser/v2 v2.5.36
 	github.com/zitadel/zitadel-go/v3 v3.29.1
 	go.uber.org/zap v1.28.0
+	golang.org/x/text v0.40.0
 	golang.org/x/time v0.15.0
 )
 
@@ -166,7 +167,6 @@ require (
 	golang.org/x/crypto v0.54.0 // indirect
 	golang.org/x/net v0.57.0 // indirect
 	golang.org/x/sys v0.47.0 // indirect
-	golang.org/x/text v0.40.0 // indirect
 	google.golang.org/protobuf v1.36.11 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -138,6 +138,33 @@ func DeleteZitadelUser(_ context.Context, userID string) error {
 	return nil
 }
 
+// ZitadelUserHasRole reports whether the Zitadel user was granted role, in
+// any project. Uses the admin PAT (user grant read permission).
+func ZitadelUserHasRole(_ context.Context, userID, role string) (bool, error) {
+	body := map[string]any{
+		"query": map[string]any{"limit": 1},
+		"queries": []map[string]any{
+			{"userIdQuery": map[string]any{"userId": userID}},
+			{"roleKeyQuery": map[string]any{"roleKey": role, "method": "TEXT_QUERY_METHOD_EQUALS"}},
+		},
+	}
+	respBody, status, err := zitadelAdminRequest("POST", "/management/v1/users/grants/_search", body)
+	if err != nil {
+		return false, fmt.Errorf("search user grants: %w", err)
+	}
+	if status != http.StatusOK {
+		return false, fmt.Errorf("search user grants returned status %d: %s", status, respBody)
+	}
+
+	var grants struct {
+		Result []json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(respBody, &grants); err != nil {
+		return false, fmt.Errorf("parse user grants response: %w", err)
+	}
+	return len(grants.Result) > 0, nil
+}
+
 // placeholderProfileMarker is the sentinel value stored in givenName/familyName
 // when a Zitadel user is provisioned without a real name (Pattern B identifier-
 // first signup). Pure "-" is short and unlikely to collide with a legitimate
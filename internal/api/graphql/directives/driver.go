@@ -0,0 +1,35 @@
+package directives
+
+import (
+	"context"
+	"tsb-service/pkg/utils"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Driver admits delivery drivers, i.e. callers granted the Zitadel "driver"
+// role. Admins pass too, so staff can stand in for a courier.
+func Driver(ctx context.Context, obj any, next graphql.Resolver) (any, error) {
+	userID := utils.GetUserID(ctx)
+	if userID == "" {
+		return nil, &gqlerror.Error{
+			Message:    "UNAUTHENTICATED: please login",
+			Path:       graphql.GetPath(ctx),
+			Extensions: map[string]any{"code": "UNAUTHENTICATED"},
+		}
+	}
+	if err := tokenExpired(ctx); err != nil {
+		return nil, err
+	}
+
+	if !utils.GetIsDriver(ctx) && !utils.GetIsAdmin(ctx) {
+		return nil, &gqlerror.Error{
+			Message:    "FORBIDDEN: driver role required",
+			Path:       graphql.GetPath(ctx),
+			Extensions: map[string]any{"code": "FORBIDDEN"},
+		}
+	}
+
+	return next(ctx)
+}
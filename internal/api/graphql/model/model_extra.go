@@ -30,6 +30,7 @@ type Order struct {
 	CouponCode         *string            `json:"couponCode,omitempty"`
 	CancellationReason *domain.OrderCancellationReason `json:"cancellationReason,omitempty"`
 	CashPaymentAmount  *string            `json:"cashPaymentAmount,omitempty"`
+	Tip                *string            `json:"tip,omitempty"`
 
 	// Non-schema fields: denormalized address for Address() resolver
 	AddressID        *string  `json:"-"`
@@ -43,6 +44,9 @@ type Order struct {
 	Postcode         *string  `json:"-"`
 	AddressDistance   *float64 `json:"-"`
 	IsManualAddr     *bool    `json:"-"`
+
+	// Non-schema field for the TableSession() resolver
+	TableSessionID *uuid.UUID `json:"-"`
 }
 
 // User is the custom GraphQL User model. It mirrors the schema fields and
@@ -34,6 +34,42 @@ type AddressSuggestion struct {
 	SecondaryText string `json:"secondaryText"`
 }
 
+type CartQuote struct {
+	Lines                   []*CartQuoteLine       `json:"lines"`
+	Subtotal                string                 `json:"subtotal"`
+	TakeawayDiscount        string                 `json:"takeawayDiscount"`
+	TakeawayDiscountPercent *string                `json:"takeawayDiscountPercent,omitempty"`
+	CouponDiscount          string                 `json:"couponDiscount"`
+	CouponCode              *string                `json:"couponCode,omitempty"`
+	DeliveryFee             string                 `json:"deliveryFee"`
+	TransactionFee          string                 `json:"transactionFee"`
+	Total                   string                 `json:"total"`
+	Tip                     string                 `json:"tip"`
+	TotalWithTip            string                 `json:"totalWithTip"`
+	VatBreakdown            []*CartVatLine         `json:"vatBreakdown"`
+	Errors                  []*CartValidationError `json:"errors"`
+}
+
+type CartQuoteLine struct {
+	Index      int       `json:"index"`
+	ProductID  uuid.UUID `json:"productId"`
+	Name       string    `json:"name"`
+	Quantity   int       `json:"quantity"`
+	UnitPrice  string    `json:"unitPrice"`
+	TotalPrice string    `json:"totalPrice"`
+	VatRate    string    `json:"vatRate"`
+}
+
+type CartValidationError struct {
+	Field   *string `json:"field,omitempty"`
+	Message string  `json:"message"`
+}
+
+type CartVatLine struct {
+	Rate   string `json:"rate"`
+	Amount string `json:"amount"`
+}
+
 type ChoiceTranslation struct {
 	Locale string `json:"locale"`
 	Name   string `json:"name"`
@@ -91,6 +127,10 @@ type CreateOrderInput struct {
 	CouponCode         *string                 `json:"couponCode,omitempty"`
 	CashPaymentAmount  *string                 `json:"cashPaymentAmount,omitempty"`
 	PaymentRedirectURL *string                 `json:"paymentRedirectUrl,omitempty"`
+	IdempotencyKey     *string                 `json:"idempotencyKey,omitempty"`
+	TableToken         *string                 `json:"tableToken,omitempty"`
+	TipAmount          *string                 `json:"tipAmount,omitempty"`
+	TipPercent         *int                    `json:"tipPercent,omitempty"`
 }
 
 type CreateOrderItemInput struct {
@@ -140,6 +180,14 @@ type CreateProductInput struct {
 	Translations     []*TranslationInput `json:"translations"`
 }
 
+type CreateWebhookEndpointInput struct {
+	URL    string             `json:"url"`
+	Events []WebhookEventType `json:"events"`
+	// Optional. When omitted or blank, the server generates one.
+	Secret   *string `json:"secret,omitempty"`
+	IsActive *bool   `json:"isActive,omitempty"`
+}
+
 type CustomerStats struct {
 	UserID             uuid.UUID     `json:"userId"`
 	FirstName          string        `json:"firstName"`
@@ -176,6 +224,45 @@ type CustomerStatsSummary struct {
 	TotalOrders       int    `json:"totalOrders"`
 }
 
+type DailyReport struct {
+	Date              string                `json:"date"`
+	OrderCount        int                   `json:"orderCount"`
+	Revenue           string                `json:"revenue"`
+	ByOrderType       []*DailyReportBucket  `json:"byOrderType"`
+	ByPaymentMethod   []*DailyReportBucket  `json:"byPaymentMethod"`
+	TakeawayDiscounts string                `json:"takeawayDiscounts"`
+	CouponDiscounts   string                `json:"couponDiscounts"`
+	DeliveryFees      string                `json:"deliveryFees"`
+	TransactionFees   string                `json:"transactionFees"`
+	Tips              string                `json:"tips"`
+	RefundCount       int                   `json:"refundCount"`
+	RefundTotal       string                `json:"refundTotal"`
+	CancelledCount    int                   `json:"cancelledCount"`
+	CancelledTotal    string                `json:"cancelledTotal"`
+	VatBreakdown      []*DailyReportVatLine `json:"vatBreakdown"`
+	TotalVat          string                `json:"totalVat"`
+}
+
+type DailyReportBucket struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+	Total string `json:"total"`
+}
+
+type DailyReportVatLine struct {
+	SceCode string `json:"sceCode"`
+	Rate    string `json:"rate"`
+	Gross   string `json:"gross"`
+	Vat     string `json:"vat"`
+	Net     string `json:"net"`
+}
+
+type DailyTips struct {
+	Date       string `json:"date"`
+	OrderCount int    `json:"orderCount"`
+	Total      string `json:"total"`
+}
+
 type DaySchedule struct {
 	Open        string  `json:"open"`
 	Close       string  `json:"close"`
@@ -190,6 +277,80 @@ type DayScheduleInput struct {
 	DinnerClose *string `json:"dinnerClose,omitempty"`
 }
 
+type Delivery struct {
+	DriverID     *uuid.UUID      `json:"driverId,omitempty"`
+	DriverName   *string         `json:"driverName,omitempty"`
+	AssignedAt   *time.Time      `json:"assignedAt,omitempty"`
+	DispatchedAt *time.Time      `json:"dispatchedAt,omitempty"`
+	DeliveredAt  *time.Time      `json:"deliveredAt,omitempty"`
+	Location     *DriverLocation `json:"location,omitempty"`
+}
+
+type DeliveryFeeTier struct {
+	// Exclusive upper bound; the last tier's bound is the delivery radius
+	MaxDistanceMeters int    `json:"maxDistanceMeters"`
+	Fee               string `json:"fee"`
+}
+
+type DeliveryFeeTierInput struct {
+	MaxDistanceMeters int    `json:"maxDistanceMeters"`
+	Fee               string `json:"fee"`
+}
+
+type DeliveryZone struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	// GeoJSON Polygon or MultiPolygon geometry, coordinates in [lng, lat] order
+	Geometry     any    `json:"geometry"`
+	DeliveryFee  string `json:"deliveryFee"`
+	MinimumOrder string `json:"minimumOrder"`
+	// Added to the preparation time for orders delivered in this zone
+	ExtraPreparationMinutes int       `json:"extraPreparationMinutes"`
+	IsActive                bool      `json:"isActive"`
+	UpdatedAt               time.Time `json:"updatedAt"`
+}
+
+type DeliveryZoneInput struct {
+	Name string `json:"name"`
+	// GeoJSON Polygon or MultiPolygon geometry (a Feature wrapping one is accepted)
+	Geometry                any    `json:"geometry"`
+	DeliveryFee             string `json:"deliveryFee"`
+	MinimumOrder            string `json:"minimumOrder"`
+	ExtraPreparationMinutes int    `json:"extraPreparationMinutes"`
+	IsActive                bool   `json:"isActive"`
+}
+
+type DiningTable struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Seats     int       `json:"seats"`
+	IsActive  bool      `json:"isActive"`
+	QRToken   string    `json:"qrToken"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type DiningTableInput struct {
+	Name     string `json:"name"`
+	Seats    int    `json:"seats"`
+	IsActive bool   `json:"isActive"`
+}
+
+type DriverLocation struct {
+	OrderID    uuid.UUID `json:"orderId"`
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	Accuracy   *float64  `json:"accuracy,omitempty"`
+	Heading    *float64  `json:"heading,omitempty"`
+	RecordedAt time.Time `json:"recordedAt"`
+}
+
+type DriverLocationInput struct {
+	Latitude  float64  `json:"latitude"`
+	Longitude float64  `json:"longitude"`
+	Accuracy  *float64 `json:"accuracy,omitempty"`
+	Heading   *float64 `json:"heading,omitempty"`
+}
+
 type Mutation struct {
 }
 
@@ -203,19 +364,53 @@ type OpeningHoursInput struct {
 	Sunday    *DayScheduleInput `json:"sunday,omitempty"`
 }
 
+type OrderConnection struct {
+	Edges      []*OrderEdge `json:"edges"`
+	PageInfo   *PageInfo    `json:"pageInfo"`
+	TotalCount int          `json:"totalCount"`
+}
+
+type OrderEdge struct {
+	Cursor string `json:"cursor"`
+	Node   *Order `json:"node"`
+}
+
 type OrderExtraInput struct {
 	Name    string   `json:"name"`
 	Options []string `json:"options,omitempty"`
 }
 
+type OrderHistoryConnection struct {
+	Edges      []*OrderEdge         `json:"edges"`
+	PageInfo   *PageInfo            `json:"pageInfo"`
+	TotalCount int                  `json:"totalCount"`
+	Summary    *OrderHistorySummary `json:"summary"`
+}
+
+type OrderHistoryFilterInput struct {
+	StartDate *time.Time          `json:"startDate,omitempty"`
+	EndDate   *time.Time          `json:"endDate,omitempty"`
+	Status    *domain.OrderStatus `json:"status,omitempty"`
+	OrderType *OrderTypeEnum      `json:"orderType,omitempty"`
+	// Same matching as OrderHistoryInput.search, but results stay newest first
+	Search             *string           `json:"search,omitempty"`
+	ChangedByActorType *domain.ActorType `json:"changedByActorType,omitempty"`
+	ChangedByActorID   *string           `json:"changedByActorId,omitempty"`
+}
+
 type OrderHistoryInput struct {
 	StartDate *time.Time          `json:"startDate,omitempty"`
 	EndDate   *time.Time          `json:"endDate,omitempty"`
 	Status    *domain.OrderStatus `json:"status,omitempty"`
 	OrderType *OrderTypeEnum      `json:"orderType,omitempty"`
-	Search    *string             `json:"search,omitempty"`
-	First     *int                `json:"first,omitempty"`
-	Page      *int                `json:"page,omitempty"`
+	// Order ID or invoice reference, customer name, email or phone, product, coupon code, address or note; matches come first by relevance
+	Search *string `json:"search,omitempty"`
+	// Orders with a status change made by this kind of actor
+	ChangedByActorType *domain.ActorType `json:"changedByActorType,omitempty"`
+	// Narrows changedByActorType to one actor
+	ChangedByActorID *string `json:"changedByActorId,omitempty"`
+	First            *int    `json:"first,omitempty"`
+	Page             *int    `json:"page,omitempty"`
 }
 
 type OrderHistoryResponse struct {
@@ -230,6 +425,7 @@ type OrderHistorySummary struct {
 }
 
 type OrderItem struct {
+	ID             uuid.UUID             `json:"id"`
 	Product        *Product              `json:"product"`
 	ProductID      uuid.UUID             `json:"productID"`
 	UnitPrice      string                `json:"unitPrice"`
@@ -250,9 +446,37 @@ type OrderItemSelection struct {
 }
 
 type OrderStatusHistory struct {
-	ID        uuid.UUID          `json:"id"`
-	Status    domain.OrderStatus `json:"status"`
-	ChangedAt time.Time          `json:"changedAt"`
+	ID     uuid.UUID          `json:"id"`
+	Status domain.OrderStatus `json:"status"`
+	// True when an admin bypassed the status transition rules
+	Forced    bool      `json:"forced"`
+	ChangedAt time.Time `json:"changedAt"`
+	// Who made the change; null for changes recorded before actors were tracked
+	ActorType *domain.ActorType `json:"actorType,omitempty"`
+	// User or POS device ID, or the job name for SYSTEM
+	ActorID                    *string                         `json:"actorId,omitempty"`
+	CancellationReason         *domain.OrderCancellationReason `json:"cancellationReason,omitempty"`
+	PreviousEstimatedReadyTime *time.Time                      `json:"previousEstimatedReadyTime,omitempty"`
+	EstimatedReadyTime         *time.Time                      `json:"estimatedReadyTime,omitempty"`
+}
+
+type OutboxMessage struct {
+	ID            uuid.UUID        `json:"id"`
+	OrderID       uuid.UUID        `json:"orderId"`
+	Kind          string           `json:"kind"`
+	Status        OutboxStatusEnum `json:"status"`
+	Attempts      int              `json:"attempts"`
+	NextAttemptAt time.Time        `json:"nextAttemptAt"`
+	LastError     *string          `json:"lastError,omitempty"`
+	CreatedAt     time.Time        `json:"createdAt"`
+	DeliveredAt   *time.Time       `json:"deliveredAt,omitempty"`
+}
+
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor,omitempty"`
+	EndCursor       *string `json:"endCursor,omitempty"`
 }
 
 type Payment struct {
@@ -289,6 +513,26 @@ type Payment struct {
 	SettlementAmount                *float64   `json:"settlementAmount,omitempty"`
 }
 
+type PricingRules struct {
+	DeliveryFeeTiers          []*DeliveryFeeTier `json:"deliveryFeeTiers"`
+	ExcludedDeliveryPostcodes []string           `json:"excludedDeliveryPostcodes"`
+	DeliveryMinimum           string             `json:"deliveryMinimum"`
+	PickupMinimum             string             `json:"pickupMinimum"`
+	TakeawayDiscountPercent   string             `json:"takeawayDiscountPercent"`
+	TakeawayDiscountThreshold string             `json:"takeawayDiscountThreshold"`
+	TransactionFee            string             `json:"transactionFee"`
+}
+
+type PricingRulesInput struct {
+	DeliveryFeeTiers          []*DeliveryFeeTierInput `json:"deliveryFeeTiers"`
+	ExcludedDeliveryPostcodes []string                `json:"excludedDeliveryPostcodes"`
+	DeliveryMinimum           string                  `json:"deliveryMinimum"`
+	PickupMinimum             string                  `json:"pickupMinimum"`
+	TakeawayDiscountPercent   string                  `json:"takeawayDiscountPercent"`
+	TakeawayDiscountThreshold string                  `json:"takeawayDiscountThreshold"`
+	TransactionFee            string                  `json:"transactionFee"`
+}
+
 type Product struct {
 	Code           *string               `json:"code,omitempty"`
 	CreatedAt      time.Time             `json:"createdAt"`
@@ -345,16 +589,50 @@ type ProductChoiceGroup struct {
 type Query struct {
 }
 
+type Refund struct {
+	ID        uuid.UUID     `json:"id"`
+	Amount    string        `json:"amount"`
+	Reason    string        `json:"reason"`
+	CreatedAt time.Time     `json:"createdAt"`
+	Lines     []*RefundLine `json:"lines"`
+}
+
+type RefundLine struct {
+	OrderItemID uuid.UUID `json:"orderItemId"`
+	Quantity    int       `json:"quantity"`
+	Amount      string    `json:"amount"`
+}
+
+type RefundLineInput struct {
+	OrderItemID uuid.UUID `json:"orderItemId"`
+	Quantity    int       `json:"quantity"`
+}
+
 type RestaurantConfig struct {
-	OrderingEnabled         bool        `json:"orderingEnabled"`
-	OpeningHours            any         `json:"openingHours"`
-	OrderingHours           any         `json:"orderingHours,omitempty"`
-	PreparationMinutes      int         `json:"preparationMinutes"`
-	IsCurrentlyOpen         bool        `json:"isCurrentlyOpen"`
-	IsOrderingCurrentlyOpen bool        `json:"isOrderingCurrentlyOpen"`
-	AvailableSlotsToday     []*TimeSlot `json:"availableSlotsToday"`
-	NextOpeningAt           *time.Time  `json:"nextOpeningAt,omitempty"`
-	UpdatedAt               time.Time   `json:"updatedAt"`
+	OrderingEnabled    bool          `json:"orderingEnabled"`
+	OpeningHours       any           `json:"openingHours"`
+	OrderingHours      any           `json:"orderingHours,omitempty"`
+	PreparationMinutes int           `json:"preparationMinutes"`
+	Pricing            *PricingRules `json:"pricing"`
+	// How many days past today customers may schedule an order; 0 means same day only
+	SchedulingHorizonDays int `json:"schedulingHorizonDays"`
+	// How long before its ready time a scheduled order appears in the staff's order list
+	ScheduledOrderLeadMinutes int                 `json:"scheduledOrderLeadMinutes"`
+	SlotCapacity              []*SlotCapacityRule `json:"slotCapacity"`
+	// How long after placing it a customer may still cancel a confirmed order; pending orders can always be cancelled
+	CancellationGraceMinutes int `json:"cancellationGraceMinutes"`
+	// Whether confirming an order without a ready time applies its suggestedReadyTime
+	AutoApplyEta bool `json:"autoApplyEta"`
+	// How long an order may wait for confirmation before staff are alerted again; 0 disables the reminder
+	UnconfirmedEscalationMinutes int `json:"unconfirmedEscalationMinutes"`
+	// How long an order may wait for confirmation before it is cancelled as KITCHEN_CLOSED and refunded; 0 disables the cancellation
+	UnconfirmedCancelMinutes int  `json:"unconfirmedCancelMinutes"`
+	IsCurrentlyOpen          bool `json:"isCurrentlyOpen"`
+	IsOrderingCurrentlyOpen  bool `json:"isOrderingCurrentlyOpen"`
+	// Pass orderType to also apply the capacity rules scoped to it
+	AvailableSlotsToday []*TimeSlot `json:"availableSlotsToday"`
+	NextOpeningAt       *time.Time  `json:"nextOpeningAt,omitempty"`
+	UpdatedAt           time.Time   `json:"updatedAt"`
 }
 
 type ScheduleOverride struct {
@@ -372,13 +650,50 @@ type ScheduleOverrideInput struct {
 	Note     *string           `json:"note,omitempty"`
 }
 
+type SlotCapacityRule struct {
+	// Lowercase English weekday the rule is limited to; null for every day
+	Weekday *string `json:"weekday,omitempty"`
+	// Order type the rule counts; null counts every order
+	OrderType *OrderTypeEnum `json:"orderType,omitempty"`
+	// Maximum orders per 15-minute slot; 0 means unlimited
+	MaxOrders int `json:"maxOrders"`
+	// Maximum items (sum of quantities) per 15-minute slot; 0 means unlimited
+	MaxItems int `json:"maxItems"`
+}
+
+type SlotCapacityRuleInput struct {
+	Weekday   *string        `json:"weekday,omitempty"`
+	OrderType *OrderTypeEnum `json:"orderType,omitempty"`
+	MaxOrders int            `json:"maxOrders"`
+	MaxItems  int            `json:"maxItems"`
+}
+
 type Subscription struct {
 }
 
+type TableBill struct {
+	Rounds       int            `json:"rounds"`
+	Total        string         `json:"total"`
+	VatBreakdown []*CartVatLine `json:"vatBreakdown"`
+}
+
+type TableSession struct {
+	ID            uuid.UUID           `json:"id"`
+	TableID       uuid.UUID           `json:"tableId"`
+	TableName     string              `json:"tableName"`
+	OpenedAt      time.Time           `json:"openedAt"`
+	ClosedAt      *time.Time          `json:"closedAt,omitempty"`
+	PaymentMethod *TablePaymentMethod `json:"paymentMethod,omitempty"`
+	Bill          *TableBill          `json:"bill"`
+	Orders        []*Order            `json:"orders"`
+}
+
 type TimeSlot struct {
 	Label              string    `json:"label"`
 	Value              time.Time `json:"value"`
 	IsLunchOnlyAllowed bool      `json:"isLunchOnlyAllowed"`
+	// False when the kitchen capacity for this slot is used up
+	IsAvailable bool `json:"isAvailable"`
 }
 
 type Translation struct {
@@ -409,6 +724,8 @@ type UpdateOrderInput struct {
 	Status             *domain.OrderStatus             `json:"status,omitempty"`
 	EstimatedReadyTime *time.Time                      `json:"estimatedReadyTime,omitempty"`
 	CancellationReason *domain.OrderCancellationReason `json:"cancellationReason,omitempty"`
+	// Admin-only: bypass the status transition rules (recorded in the status history)
+	Force *bool `json:"force,omitempty"`
 }
 
 type UpdateProductChoiceGroupInput struct {
@@ -452,6 +769,39 @@ type UpdateUserInput struct {
 	NotifyOrderUpdates *bool   `json:"notifyOrderUpdates,omitempty"`
 }
 
+type UpdateWebhookEndpointInput struct {
+	URL      *string            `json:"url,omitempty"`
+	Events   []WebhookEventType `json:"events,omitempty"`
+	Secret   *string            `json:"secret,omitempty"`
+	IsActive *bool              `json:"isActive,omitempty"`
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID             `json:"id"`
+	EndpointID     uuid.UUID             `json:"endpointId"`
+	EventID        uuid.UUID             `json:"eventId"`
+	EventType      WebhookEventType      `json:"eventType"`
+	Payload        any                   `json:"payload"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	NextAttemptAt  time.Time             `json:"nextAttemptAt"`
+	ResponseStatus *int                  `json:"responseStatus,omitempty"`
+	LastError      *string               `json:"lastError,omitempty"`
+	CreatedAt      time.Time             `json:"createdAt"`
+	DeliveredAt    *time.Time            `json:"deliveredAt,omitempty"`
+}
+
+type WebhookEndpoint struct {
+	ID         uuid.UUID          `json:"id"`
+	URL        string             `json:"url"`
+	Events     []WebhookEventType `json:"events"`
+	Secret     string             `json:"secret"`
+	IsActive   bool               `json:"isActive"`
+	CreatedAt  time.Time          `json:"createdAt"`
+	UpdatedAt  time.Time          `json:"updatedAt"`
+	Deliveries []*WebhookDelivery `json:"deliveries"`
+}
+
 type CouponStatus string
 
 const (
@@ -518,16 +868,18 @@ type OrderTypeEnum string
 const (
 	OrderTypeEnumDelivery OrderTypeEnum = "DELIVERY"
 	OrderTypeEnumPickup   OrderTypeEnum = "PICKUP"
+	OrderTypeEnumDineIn   OrderTypeEnum = "DINE_IN"
 )
 
 var AllOrderTypeEnum = []OrderTypeEnum{
 	OrderTypeEnumDelivery,
 	OrderTypeEnumPickup,
+	OrderTypeEnumDineIn,
 }
 
 func (e OrderTypeEnum) IsValid() bool {
 	switch e {
-	case OrderTypeEnumDelivery, OrderTypeEnumPickup:
+	case OrderTypeEnumDelivery, OrderTypeEnumPickup, OrderTypeEnumDineIn:
 		return true
 	}
 	return false
@@ -567,3 +919,231 @@ func (e OrderTypeEnum) MarshalJSON() ([]byte, error) {
 	e.MarshalGQL(&buf)
 	return buf.Bytes(), nil
 }
+
+type OutboxStatusEnum string
+
+const (
+	OutboxStatusEnumPending   OutboxStatusEnum = "PENDING"
+	OutboxStatusEnumDelivered OutboxStatusEnum = "DELIVERED"
+	OutboxStatusEnumDead      OutboxStatusEnum = "DEAD"
+)
+
+var AllOutboxStatusEnum = []OutboxStatusEnum{
+	OutboxStatusEnumPending,
+	OutboxStatusEnumDelivered,
+	OutboxStatusEnumDead,
+}
+
+func (e OutboxStatusEnum) IsValid() bool {
+	switch e {
+	case OutboxStatusEnumPending, OutboxStatusEnumDelivered, OutboxStatusEnumDead:
+		return true
+	}
+	return false
+}
+
+func (e OutboxStatusEnum) String() string {
+	return string(e)
+}
+
+func (e *OutboxStatusEnum) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = OutboxStatusEnum(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid OutboxStatusEnum", str)
+	}
+	return nil
+}
+
+func (e OutboxStatusEnum) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *OutboxStatusEnum) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e OutboxStatusEnum) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type TablePaymentMethod string
+
+const (
+	TablePaymentMethodCash TablePaymentMethod = "CASH"
+	TablePaymentMethodCard TablePaymentMethod = "CARD"
+)
+
+var AllTablePaymentMethod = []TablePaymentMethod{
+	TablePaymentMethodCash,
+	TablePaymentMethodCard,
+}
+
+func (e TablePaymentMethod) IsValid() bool {
+	switch e {
+	case TablePaymentMethodCash, TablePaymentMethodCard:
+		return true
+	}
+	return false
+}
+
+func (e TablePaymentMethod) String() string {
+	return string(e)
+}
+
+func (e *TablePaymentMethod) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = TablePaymentMethod(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid TablePaymentMethod", str)
+	}
+	return nil
+}
+
+func (e TablePaymentMethod) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *TablePaymentMethod) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e TablePaymentMethod) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "PENDING"
+	WebhookDeliveryStatusDelivered WebhookDeliveryStatus = "DELIVERED"
+	WebhookDeliveryStatusDead      WebhookDeliveryStatus = "DEAD"
+)
+
+var AllWebhookDeliveryStatus = []WebhookDeliveryStatus{
+	WebhookDeliveryStatusPending,
+	WebhookDeliveryStatusDelivered,
+	WebhookDeliveryStatusDead,
+}
+
+func (e WebhookDeliveryStatus) IsValid() bool {
+	switch e {
+	case WebhookDeliveryStatusPending, WebhookDeliveryStatusDelivered, WebhookDeliveryStatusDead:
+		return true
+	}
+	return false
+}
+
+func (e WebhookDeliveryStatus) String() string {
+	return string(e)
+}
+
+func (e *WebhookDeliveryStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = WebhookDeliveryStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid WebhookDeliveryStatus", str)
+	}
+	return nil
+}
+
+func (e WebhookDeliveryStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *WebhookDeliveryStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e WebhookDeliveryStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type WebhookEventType string
+
+const (
+	WebhookEventTypeOrderCreated       WebhookEventType = "ORDER_CREATED"
+	WebhookEventTypeOrderStatusChanged WebhookEventType = "ORDER_STATUS_CHANGED"
+	WebhookEventTypePaymentPaid        WebhookEventType = "PAYMENT_PAID"
+	WebhookEventTypeRefundCreated      WebhookEventType = "REFUND_CREATED"
+)
+
+var AllWebhookEventType = []WebhookEventType{
+	WebhookEventTypeOrderCreated,
+	WebhookEventTypeOrderStatusChanged,
+	WebhookEventTypePaymentPaid,
+	WebhookEventTypeRefundCreated,
+}
+
+func (e WebhookEventType) IsValid() bool {
+	switch e {
+	case WebhookEventTypeOrderCreated, WebhookEventTypeOrderStatusChanged, WebhookEventTypePaymentPaid, WebhookEventTypeRefundCreated:
+		return true
+	}
+	return false
+}
+
+func (e WebhookEventType) String() string {
+	return string(e)
+}
+
+func (e *WebhookEventType) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = WebhookEventType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid WebhookEventType", str)
+	}
+	return nil
+}
+
+func (e WebhookEventType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *WebhookEventType) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e WebhookEventType) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
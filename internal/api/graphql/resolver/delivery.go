@@ -0,0 +1,177 @@
+package resolver
+
+// This file will be automatically regenerated based on the schema, any resolver
+// implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.92
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"tsb-service/internal/api/graphql/model"
+	orderDomain "tsb-service/internal/modules/order/domain"
+	"tsb-service/pkg/utils"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/google/uuid"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// AssignDriver is the resolver for the assignDriver field.
+func (r *mutationResolver) AssignDriver(ctx context.Context, orderID uuid.UUID, driverID uuid.UUID) (*model.Order, error) {
+	isDriver, err := r.UserService.HasRole(ctx, driverID.String(), "driver")
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to check driver role: %w", err)
+	}
+	if !isDriver {
+		return nil, &gqlerror.Error{
+			Message:    "this user is not a delivery driver",
+			Path:       graphql.GetPath(ctx),
+			Extensions: map[string]any{"code": "USER_ERROR", "field": "driverId"},
+		}
+	}
+	if _, err := r.DeliveryService.AssignDriver(ctx, orderID, driverID); err != nil {
+		if errors.Is(err, orderDomain.ErrDriverNotAssignable) {
+			return nil, &gqlerror.Error{
+				Message:    err.Error(),
+				Path:       graphql.GetPath(ctx),
+				Extensions: map[string]any{"code": "USER_ERROR", "field": "orderId"},
+			}
+		}
+		return nil, fmt.Errorf("failed to assign driver: %w", err)
+	}
+
+	o, _, err := r.OrderService.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+	gql := ToGQLOrder(o)
+	r.Broker.Publish("orderUpdated", gql)
+	r.Broker.Publish(fmt.Sprintf("orderUpdated:%s", o.ID), gql)
+	return gql, nil
+}
+
+// ReportDriverLocation is the resolver for the reportDriverLocation field.
+func (r *mutationResolver) ReportDriverLocation(ctx context.Context, input model.DriverLocationInput) (int, error) {
+	// The @driver directive guarantees a non-empty userID.
+	driverID, err := uuid.Parse(utils.GetUserID(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	locations, err := r.DeliveryService.ReportLocation(ctx, driverID, input.Latitude, input.Longitude, input.Accuracy, input.Heading)
+	if err != nil {
+		if errors.Is(err, orderDomain.ErrInvalidLocation) {
+			return 0, &gqlerror.Error{
+				Message:    err.Error(),
+				Path:       graphql.GetPath(ctx),
+				Extensions: map[string]any{"code": "USER_ERROR", "field": "input"},
+			}
+		}
+		return 0, fmt.Errorf("failed to report location: %w", err)
+	}
+
+	for _, l := range locations {
+		r.Broker.Publish(fmt.Sprintf("driverLocation:%s", l.OrderID), toGQLDriverLocation(l))
+	}
+	return len(locations), nil
+}
+
+// Delivery is the resolver for the delivery field.
+func (r *orderResolver) Delivery(ctx context.Context, obj *model.Order) (*model.Delivery, error) {
+	d, err := r.DeliveryService.GetDelivery(ctx, obj.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery: %w", err)
+	}
+	if d == nil {
+		return nil, nil
+	}
+
+	out := &model.Delivery{
+		DriverID:     d.DriverID,
+		AssignedAt:   d.AssignedAt,
+		DispatchedAt: d.DispatchedAt,
+		DeliveredAt:  d.DeliveredAt,
+	}
+	if l := d.Location(); l != nil {
+		out.Location = toGQLDriverLocation(l)
+	}
+	if d.DriverID != nil {
+		driver, err := r.UserService.GetUserByID(ctx, d.DriverID.String())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get driver: %w", err)
+		}
+		if driver != nil {
+			out.DriverName = &driver.FirstName
+		}
+	}
+	return out, nil
+}
+
+// MyDeliveries is the resolver for the myDeliveries field.
+func (r *queryResolver) MyDeliveries(ctx context.Context) ([]*model.Order, error) {
+	// The @driver directive guarantees a non-empty userID.
+	driverID, err := uuid.Parse(utils.GetUserID(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	orders, err := r.DeliveryService.GetDriverOrders(ctx, driverID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deliveries: %w", err)
+	}
+	return Map(orders, ToGQLOrder), nil
+}
+
+// MyOrderDriverLocation is the resolver for the myOrderDriverLocation field.
+func (r *subscriptionResolver) MyOrderDriverLocation(ctx context.Context, orderID uuid.UUID) (<-chan *model.DriverLocation, error) {
+	// The @auth directive guarantees a non-empty userID.
+	userID := utils.GetUserID(ctx)
+
+	// Verify the order belongs to this user
+	order, _, err := r.OrderService.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, &gqlerror.Error{
+			Message:    "NOT_FOUND: order not found",
+			Path:       graphql.GetPath(ctx),
+			Extensions: map[string]any{"code": "NOT_FOUND"},
+		}
+	}
+	if order.UserID.String() != userID {
+		return nil, &gqlerror.Error{
+			Message:    "FORBIDDEN: order does not belong to caller",
+			Path:       graphql.GetPath(ctx),
+			Extensions: map[string]any{"code": "FORBIDDEN"},
+		}
+	}
+
+	topic := fmt.Sprintf("driverLocation:%s", orderID)
+	ch := make(chan *model.DriverLocation, 1)
+	sub := r.Broker.Subscribe(topic)
+
+	go func() {
+		defer close(ch)
+		defer r.Broker.Unsubscribe(topic, sub)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-sub:
+				if !ok {
+					return
+				}
+				if l, ok := msg.(*model.DriverLocation); ok {
+					select {
+					case ch <- l:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	return ch, nil
+}
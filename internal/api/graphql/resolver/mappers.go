@@ -18,6 +18,7 @@ import (
 	productDomain "tsb-service/internal/modules/product/domain"
 	restaurantDomain "tsb-service/internal/modules/restaurant/domain"
 	userDomain "tsb-service/internal/modules/user/domain"
+	webhookDomain "tsb-service/internal/modules/webhook/domain"
 	"tsb-service/pkg/timezone"
 
 	"github.com/lib/pq"
@@ -148,6 +149,11 @@ func ToGQLOrder(o *orderDomain.Order) *model.Order {
 
 	cashPaymentAmountStr := decimalPtrStr(o.CashPaymentAmount)
 
+	var tipStr *string
+	if o.Tip.IsPositive() {
+		tipStr = decimalPtrStr(&o.Tip)
+	}
+
 	isManual := o.IsManualAddress
 
 	return &model.Order{
@@ -178,6 +184,32 @@ func ToGQLOrder(o *orderDomain.Order) *model.Order {
 		IsManualAddr:       &isManual,
 		CancellationReason: o.CancellationReason,
 		CashPaymentAmount:  cashPaymentAmountStr,
+		Tip:                tipStr,
+		TableSessionID:     o.TableSessionID,
+	}
+}
+
+func toGQLOrderEdges(orders []*orderDomain.Order) []*model.OrderEdge {
+	return Map(orders, func(o *orderDomain.Order) *model.OrderEdge {
+		return &model.OrderEdge{Cursor: orderDomain.CursorOf(o).Encode(), Node: ToGQLOrder(o)}
+	})
+}
+
+func toGQLPageInfo(edges []*model.OrderEdge, hasNextPage, hasPreviousPage bool) *model.PageInfo {
+	info := &model.PageInfo{HasNextPage: hasNextPage, HasPreviousPage: hasPreviousPage}
+	if len(edges) > 0 {
+		info.StartCursor = &edges[0].Cursor
+		info.EndCursor = &edges[len(edges)-1].Cursor
+	}
+	return info
+}
+
+func toGQLOrderConnection(page *orderDomain.OrderPage, req orderDomain.OrderPageRequest) *model.OrderConnection {
+	edges := toGQLOrderEdges(page.Orders)
+	return &model.OrderConnection{
+		Edges:      edges,
+		PageInfo:   toGQLPageInfo(edges, page.HasNextPage, req.After != nil),
+		TotalCount: page.TotalCount,
 	}
 }
 
@@ -192,6 +224,7 @@ func ToGQLOrderItem(oi *orderDomain.OrderProductRaw) *model.OrderItem {
 	}
 
 	return &model.OrderItem{
+		ID:             oi.ID,
 		ProductID:      oi.ProductID,
 		Quantity:       int(oi.Quantity),
 		UnitPrice:      oi.UnitPrice.String(),
@@ -202,6 +235,24 @@ func ToGQLOrderItem(oi *orderDomain.OrderProductRaw) *model.OrderItem {
 	}
 }
 
+func toGQLRefund(r *paymentDomain.Refund) *model.Refund {
+	lines := make([]*model.RefundLine, len(r.Lines))
+	for i, l := range r.Lines {
+		lines[i] = &model.RefundLine{
+			OrderItemID: l.OrderProductID,
+			Quantity:    int(l.Quantity),
+			Amount:      l.Amount.StringFixed(2),
+		}
+	}
+	return &model.Refund{
+		ID:        r.ID,
+		Amount:    r.Amount.StringFixed(2),
+		Reason:    r.Reason,
+		CreatedAt: r.CreatedAt,
+		Lines:     lines,
+	}
+}
+
 func ToGQLPayment(p *paymentDomain.MolliePayment) *model.Payment {
 	var links map[string]any
 	_ = json.Unmarshal(p.Links, &links)
@@ -253,6 +304,47 @@ func toDomainTranslations(in []*model.TranslationInput) []productDomain.Translat
 	return out
 }
 
+func toGQLCartQuote(q *orderDomain.Quote) *model.CartQuote {
+	out := &model.CartQuote{
+		Lines:            make([]*model.CartQuoteLine, len(q.Lines)),
+		Subtotal:         q.ItemsTotal.StringFixed(2),
+		TakeawayDiscount: q.TakeawayDiscount.StringFixed(2),
+		CouponDiscount:   q.CouponDiscount.StringFixed(2),
+		CouponCode:       q.CouponCode,
+		DeliveryFee:      q.DeliveryFee.StringFixed(2),
+		TransactionFee:   q.TransactionFee.StringFixed(2),
+		Total:            q.Total.StringFixed(2),
+		Tip:              q.Tip.StringFixed(2),
+		TotalWithTip:     q.Total.Add(q.Tip).StringFixed(2),
+		VatBreakdown:     toGQLVatLines(q.VatBreakdown),
+		Errors:           make([]*model.CartValidationError, len(q.Issues)),
+	}
+	if q.TakeawayDiscountPercent != nil {
+		pct := q.TakeawayDiscountPercent.String()
+		out.TakeawayDiscountPercent = &pct
+	}
+	for i, l := range q.Lines {
+		out.Lines[i] = &model.CartQuoteLine{
+			Index:      l.Index,
+			ProductID:  l.ProductID,
+			Name:       l.Product.Name,
+			Quantity:   int(l.Quantity),
+			UnitPrice:  l.UnitPrice.StringFixed(2),
+			TotalPrice: l.TotalPrice.StringFixed(2),
+			VatRate:    l.VatRateApplied.StringFixed(2),
+		}
+	}
+	for i, issue := range q.Issues {
+		e := &model.CartValidationError{Message: issue.Message}
+		if issue.Field != "" {
+			field := issue.Field
+			e.Field = &field
+		}
+		out.Errors[i] = e
+	}
+	return out
+}
+
 func toGQLRestaurantConfig(c *restaurantDomain.RestaurantConfig) *model.RestaurantConfig {
 	var openingHours map[string]any
 	_ = json.Unmarshal(c.OpeningHours, &openingHours)
@@ -262,13 +354,271 @@ func toGQLRestaurantConfig(c *restaurantDomain.RestaurantConfig) *model.Restaura
 		_ = json.Unmarshal(c.OrderingHours, &orderingHours)
 	}
 
+	pricing, err := c.GetPricing()
+	if err != nil {
+		pricing = restaurantDomain.DefaultPricingRules()
+	}
+	capacity, _ := c.GetSlotCapacity()
+
 	return &model.RestaurantConfig{
-		OrderingEnabled:    c.OrderingEnabled,
-		OpeningHours:       openingHours,
-		OrderingHours:      orderingHours,
-		PreparationMinutes: c.PreparationMinutes,
-		UpdatedAt:          c.UpdatedAt,
+		OrderingEnabled:              c.OrderingEnabled,
+		OpeningHours:                 openingHours,
+		OrderingHours:                orderingHours,
+		PreparationMinutes:           c.PreparationMinutes,
+		Pricing:                      toGQLPricingRules(pricing),
+		SchedulingHorizonDays:        c.SchedulingHorizonDays,
+		ScheduledOrderLeadMinutes:    c.ScheduledOrderLeadMinutes,
+		SlotCapacity:                 toGQLSlotCapacity(capacity),
+		CancellationGraceMinutes:     c.CancellationGraceMinutes,
+		AutoApplyEta:                 c.AutoApplyEta,
+		UnconfirmedEscalationMinutes: c.UnconfirmedEscalationMinutes,
+		UnconfirmedCancelMinutes:     c.UnconfirmedCancelMinutes,
+		UpdatedAt:                    c.UpdatedAt,
+	}
+}
+
+func toGQLSlotCapacity(rules restaurantDomain.SlotCapacity) []*model.SlotCapacityRule {
+	out := make([]*model.SlotCapacityRule, len(rules))
+	for i, rule := range rules {
+		out[i] = &model.SlotCapacityRule{MaxOrders: rule.MaxOrders, MaxItems: rule.MaxItems}
+		if rule.Weekday != "" {
+			weekday := rule.Weekday
+			out[i].Weekday = &weekday
+		}
+		if rule.OrderType != "" {
+			orderType := model.OrderTypeEnum(rule.OrderType)
+			out[i].OrderType = &orderType
+		}
+	}
+	return out
+}
+
+func slotCapacityFromInput(in []*model.SlotCapacityRuleInput) restaurantDomain.SlotCapacity {
+	rules := make(restaurantDomain.SlotCapacity, len(in))
+	for i, rule := range in {
+		rules[i] = restaurantDomain.SlotCapacityRule{MaxOrders: rule.MaxOrders, MaxItems: rule.MaxItems}
+		if rule.Weekday != nil {
+			rules[i].Weekday = strings.ToLower(strings.TrimSpace(*rule.Weekday))
+		}
+		if rule.OrderType != nil {
+			rules[i].OrderType = string(*rule.OrderType)
+		}
+	}
+	return rules
+}
+
+func toGQLPricingRules(p restaurantDomain.PricingRules) *model.PricingRules {
+	tiers := make([]*model.DeliveryFeeTier, len(p.DeliveryFeeTiers))
+	for i, t := range p.DeliveryFeeTiers {
+		tiers[i] = &model.DeliveryFeeTier{
+			MaxDistanceMeters: t.MaxDistanceMeters,
+			Fee:               t.Fee.StringFixed(2),
+		}
+	}
+	postcodes := p.ExcludedDeliveryPostcodes
+	if postcodes == nil {
+		postcodes = []string{}
+	}
+	return &model.PricingRules{
+		DeliveryFeeTiers:          tiers,
+		ExcludedDeliveryPostcodes: postcodes,
+		DeliveryMinimum:           p.DeliveryMinimum.StringFixed(2),
+		PickupMinimum:             p.PickupMinimum.StringFixed(2),
+		TakeawayDiscountPercent:   p.TakeawayDiscountPercent.String(),
+		TakeawayDiscountThreshold: p.TakeawayDiscountThreshold.StringFixed(2),
+		TransactionFee:            p.TransactionFee.StringFixed(2),
+	}
+}
+
+// pricingRulesFromInput parses the admin input into domain pricing rules.
+// Range checks live in PricingRules.Validate.
+func pricingRulesFromInput(input model.PricingRulesInput) (restaurantDomain.PricingRules, error) {
+	parse := func(field, value string) (decimal.Decimal, error) {
+		d, err := decimal.NewFromString(strings.TrimSpace(value))
+		if err != nil {
+			return decimal.Zero, fmt.Errorf("invalid %s: %w", field, err)
+		}
+		return d, nil
+	}
+
+	var rules restaurantDomain.PricingRules
+	rules.DeliveryFeeTiers = make([]restaurantDomain.DeliveryFeeTier, len(input.DeliveryFeeTiers))
+	for i, t := range input.DeliveryFeeTiers {
+		fee, err := parse("delivery fee", t.Fee)
+		if err != nil {
+			return rules, err
+		}
+		rules.DeliveryFeeTiers[i] = restaurantDomain.DeliveryFeeTier{MaxDistanceMeters: t.MaxDistanceMeters, Fee: fee}
+	}
+	rules.ExcludedDeliveryPostcodes = make([]string, len(input.ExcludedDeliveryPostcodes))
+	for i, pc := range input.ExcludedDeliveryPostcodes {
+		rules.ExcludedDeliveryPostcodes[i] = strings.TrimSpace(pc)
+	}
+
+	var err error
+	if rules.DeliveryMinimum, err = parse("delivery minimum", input.DeliveryMinimum); err != nil {
+		return rules, err
+	}
+	if rules.PickupMinimum, err = parse("pickup minimum", input.PickupMinimum); err != nil {
+		return rules, err
+	}
+	if rules.TakeawayDiscountPercent, err = parse("takeaway discount percent", input.TakeawayDiscountPercent); err != nil {
+		return rules, err
+	}
+	if rules.TakeawayDiscountThreshold, err = parse("takeaway discount threshold", input.TakeawayDiscountThreshold); err != nil {
+		return rules, err
+	}
+	if rules.TransactionFee, err = parse("transaction fee", input.TransactionFee); err != nil {
+		return rules, err
+	}
+	return rules, nil
+}
+
+func toGQLDeliveryZone(z *restaurantDomain.DeliveryZone) *model.DeliveryZone {
+	var geometry map[string]any
+	_ = json.Unmarshal(z.Geometry, &geometry)
+	return &model.DeliveryZone{
+		ID:                      z.ID,
+		Name:                    z.Name,
+		Geometry:                geometry,
+		DeliveryFee:             z.DeliveryFee.StringFixed(2),
+		MinimumOrder:            z.MinimumOrder.StringFixed(2),
+		ExtraPreparationMinutes: z.ExtraPreparationMinutes,
+		IsActive:                z.IsActive,
+		UpdatedAt:               z.UpdatedAt,
+	}
+}
+
+func toGQLVatLines(lines []orderDomain.VatLine) []*model.CartVatLine {
+	out := make([]*model.CartVatLine, len(lines))
+	for i, v := range lines {
+		out[i] = &model.CartVatLine{
+			Rate:   v.Rate.StringFixed(2),
+			Amount: v.Amount.StringFixed(2),
+		}
+	}
+	return out
+}
+
+func toGQLDailyTips(row *orderDomain.DailyTipsRow) *model.DailyTips {
+	return &model.DailyTips{
+		Date:       row.Day.Format(time.DateOnly),
+		OrderCount: row.OrderCount,
+		Total:      row.Total.StringFixed(2),
+	}
+}
+
+func toGQLDailyReport(r *orderDomain.DailyReport) *model.DailyReport {
+	bucket := func(b orderDomain.ReportBucket) *model.DailyReportBucket {
+		return &model.DailyReportBucket{Key: b.Key, Count: b.Count, Total: b.Total.StringFixed(2)}
+	}
+	vat := func(v orderDomain.ReportVatLine) *model.DailyReportVatLine {
+		return &model.DailyReportVatLine{
+			SceCode: v.SceCode,
+			Rate:    v.Rate.StringFixed(2),
+			Gross:   v.Gross.StringFixed(2),
+			Vat:     v.Vat.StringFixed(2),
+			Net:     v.Net.StringFixed(2),
+		}
+	}
+	return &model.DailyReport{
+		Date:              r.Date.Format(time.DateOnly),
+		OrderCount:        r.Orders,
+		Revenue:           r.Revenue.StringFixed(2),
+		ByOrderType:       Map(r.ByOrderType, bucket),
+		ByPaymentMethod:   Map(r.ByPaymentMethod, bucket),
+		TakeawayDiscounts: r.TakeawayDiscounts.StringFixed(2),
+		CouponDiscounts:   r.CouponDiscounts.StringFixed(2),
+		DeliveryFees:      r.DeliveryFees.StringFixed(2),
+		TransactionFees:   r.TransactionFees.StringFixed(2),
+		Tips:              r.Tips.StringFixed(2),
+		RefundCount:       r.Refunds.Count,
+		RefundTotal:       r.Refunds.Total.StringFixed(2),
+		CancelledCount:    r.Cancellations.Count,
+		CancelledTotal:    r.Cancellations.Total.StringFixed(2),
+		VatBreakdown:      Map(r.Vat, vat),
+		TotalVat:          r.TotalVat.StringFixed(2),
+	}
+}
+
+func toGQLDiningTable(t *orderDomain.DiningTable, qrToken string) *model.DiningTable {
+	return &model.DiningTable{
+		ID:        t.ID,
+		Name:      t.Name,
+		Seats:     t.Seats,
+		IsActive:  t.IsActive,
+		QRToken:   qrToken,
+		UpdatedAt: t.UpdatedAt,
+	}
+}
+
+func toGQLTableSession(s *orderDomain.TableSession) *model.TableSession {
+	out := &model.TableSession{
+		ID:        s.ID,
+		TableID:   s.TableID,
+		TableName: s.TableName,
+		OpenedAt:  s.OpenedAt,
+		ClosedAt:  s.ClosedAt,
+	}
+	if s.PaymentMethod != nil {
+		method := model.TablePaymentMethod(*s.PaymentMethod)
+		out.PaymentMethod = &method
+	}
+	return out
+}
+
+func toGQLTableBill(b *orderDomain.TableBill) *model.TableBill {
+	return &model.TableBill{
+		Rounds:       b.Rounds,
+		Total:        b.Total.StringFixed(2),
+		VatBreakdown: toGQLVatLines(b.VatBreakdown),
+	}
+}
+
+func toGQLDriverLocation(l *orderDomain.DriverLocation) *model.DriverLocation {
+	return &model.DriverLocation{
+		OrderID:    l.OrderID,
+		Latitude:   l.Latitude,
+		Longitude:  l.Longitude,
+		Accuracy:   l.Accuracy,
+		Heading:    l.Heading,
+		RecordedAt: l.RecordedAt,
+	}
+}
+
+// diningTableFromInput validates a table as entered in the admin.
+func diningTableFromInput(input model.DiningTableInput) (*orderDomain.DiningTable, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, fmt.Errorf("table name is required")
+	}
+	if input.Seats < 0 {
+		return nil, fmt.Errorf("seats must not be negative")
+	}
+	return &orderDomain.DiningTable{
+		Name:     name,
+		Seats:    input.Seats,
+		IsActive: input.IsActive,
+	}, nil
+}
+
+// deliveryZoneFromInput parses the money strings and re-encodes the GeoJSON
+// geometry. The returned zone still needs an ID and is validated by the
+// domain constructor.
+func deliveryZoneFromInput(input model.DeliveryZoneInput) (*restaurantDomain.DeliveryZone, error) {
+	fee, err := decimal.NewFromString(strings.TrimSpace(input.DeliveryFee))
+	if err != nil {
+		return nil, fmt.Errorf("invalid delivery fee: %w", err)
 	}
+	minimum, err := decimal.NewFromString(strings.TrimSpace(input.MinimumOrder))
+	if err != nil {
+		return nil, fmt.Errorf("invalid minimum order: %w", err)
+	}
+	geometry, err := json.Marshal(input.Geometry)
+	if err != nil {
+		return nil, fmt.Errorf("invalid geometry: %w", err)
+	}
+	return restaurantDomain.NewDeliveryZone(input.Name, geometry, fee, minimum, input.ExtraPreparationMinutes, input.IsActive)
 }
 
 func toGQLScheduleOverride(ov *restaurantDomain.ScheduleOverride) *model.ScheduleOverride {
@@ -310,6 +660,7 @@ func toGQLTimeSlots(slots []restaurantDomain.TimeSlot) []*model.TimeSlot {
 			Label:              s.Label,
 			Value:              s.Value,
 			IsLunchOnlyAllowed: s.IsLunchOnlyAllowed,
+			IsAvailable:        true,
 		}
 	}
 	return out
@@ -404,40 +755,6 @@ func emailContext() (context.Context, context.CancelFunc) {
 	return context.WithTimeout(context.Background(), 30*time.Second)
 }
 
-// excludedDeliveryPostcodes lists postcodes we never deliver to, regardless of distance.
-var excludedDeliveryPostcodes = map[string]bool{
-	"4610": true, // Beyne-Heusay
-}
-
-// isExcludedDeliveryPostcode reports whether the given postcode is excluded from delivery.
-func isExcludedDeliveryPostcode(postcode string) bool {
-	return excludedDeliveryPostcodes[strings.TrimSpace(postcode)]
-}
-
-// deliveryFeeFromDistance computes the delivery fee based on distance in meters.
-func deliveryFeeFromDistance(distance float64) decimal.Decimal {
-	var dFee int64
-	switch {
-	case distance < 3000:
-		dFee = 0
-	case distance < 4000:
-		dFee = 1
-	case distance < 5000:
-		dFee = 2
-	case distance < 6000:
-		dFee = 3
-	case distance < 7000:
-		dFee = 4
-	case distance < 8000:
-		dFee = 5
-	case distance < 9000:
-		dFee = 6
-	default:
-		dFee = 10 // unreachable; order.go rejects distance >= 9000 first
-	}
-	return decimal.NewFromInt(dFee)
-}
-
 // addressFromOrder constructs an addressDomain.Address from an order's denormalized fields.
 func addressFromOrder(o *orderDomain.Order) *addressDomain.Address {
 	if o.StreetName == nil {
@@ -487,12 +804,14 @@ func validatePreferredReadyTime(preferred *time.Time, config *restaurantDomain.R
 	nowLocal := timezone.In(now)
 	slot := timezone.In(*preferred)
 
-	if slot.Year() != nowLocal.Year() || slot.Month() != nowLocal.Month() || slot.Day() != nowLocal.Day() {
-		return fmt.Errorf("preferred ready time must be on the same day")
+	if !config.IsWithinSchedulingHorizon(now, slot) {
+		if config.SchedulingHorizonDays <= 0 {
+			return fmt.Errorf("preferred ready time must be on the same day")
+		}
+		return fmt.Errorf("preferred ready time must be within the next %d days", config.SchedulingHorizonDays)
 	}
 
-	prepBuffer := max(time.Duration(config.PreparationMinutes)*time.Minute, 15*time.Minute)
-	if slot.Before(nowLocal.Add(prepBuffer)) {
+	if slot.Before(nowLocal.Add(preparationBuffer(config))) {
 		return fmt.Errorf("preferred ready time is no longer available — it is within the minimum preparation window")
 	}
 
@@ -504,9 +823,12 @@ func validatePreferredReadyTime(preferred *time.Time, config *restaurantDomain.R
 	// resolveDaySchedule is not exported; replicate its priority here:
 	// override > weekly. We use IsOrderingCurrentlyOpen already checks this,
 	// so here we just need the resolved schedule for slot containment.
-	schedule := resolveSchedule(config, overrides, now)
+	schedule := resolveSchedule(config, overrides, slot)
 	if schedule == nil {
-		return fmt.Errorf("ordering is closed today")
+		if localDate(slot) == localDate(nowLocal) {
+			return fmt.Errorf("ordering is closed today")
+		}
+		return fmt.Errorf("ordering is closed on %s", localDate(slot))
 	}
 
 	slotMins := slot.Hour()*60 + slot.Minute()
@@ -517,11 +839,17 @@ func validatePreferredReadyTime(preferred *time.Time, config *restaurantDomain.R
 	return nil
 }
 
-// resolveSchedule returns the effective schedule for `now`, using the
-// same override > ordering hours > opening hours priority as domain resolution.
-func resolveSchedule(config *restaurantDomain.RestaurantConfig, overrides map[string]*restaurantDomain.ScheduleOverride, now time.Time) *restaurantDomain.DaySchedule {
-	// Override for today wins regardless of weekly config.
-	local := timezone.In(now)
+// preparationBuffer is the minimum lead time for a preferred ready time.
+func preparationBuffer(config *restaurantDomain.RestaurantConfig) time.Duration {
+	return max(time.Duration(config.PreparationMinutes)*time.Minute, 15*time.Minute)
+}
+
+// resolveSchedule returns the effective schedule for the local day of `day`,
+// using the same override > ordering hours > opening hours priority as domain
+// resolution.
+func resolveSchedule(config *restaurantDomain.RestaurantConfig, overrides map[string]*restaurantDomain.ScheduleOverride, day time.Time) *restaurantDomain.DaySchedule {
+	// Override for that date wins regardless of weekly config.
+	local := timezone.In(day)
 	dateKey := local.Format("2006-01-02")
 	if ov, ok := overrides[dateKey]; ok && ov != nil {
 		if ov.Closed {
@@ -548,6 +876,11 @@ func resolveSchedule(config *restaurantDomain.RestaurantConfig, overrides map[st
 	return schedule
 }
 
+// localDate formats t as "2006-01-02" in the restaurant timezone.
+func localDate(t time.Time) string {
+	return timezone.In(t).Format("2006-01-02")
+}
+
 func isSlotInAllowedInterval(slotMins int, schedule *restaurantDomain.DaySchedule) bool {
 	intervals := make([][2]string, 0, 2)
 	intervals = append(intervals, [2]string{schedule.Open, schedule.Close})
@@ -622,15 +955,77 @@ func ToGQLProductChoiceGroup(g *productDomain.ProductChoiceGroup, lang string) *
 	}
 }
 
-// lateNotificationThreshold caps how long after the estimated ready time we
-// still send status notifications (suppresses very-late noise).
-const lateNotificationThreshold = 40 * time.Minute
+func toGQLOutboxMessage(m *orderDomain.OutboxMessage) *model.OutboxMessage {
+	return &model.OutboxMessage{
+		ID:            m.ID,
+		OrderID:       m.OrderID,
+		Kind:          string(m.Kind),
+		Status:        model.OutboxStatusEnum(m.Status),
+		Attempts:      m.Attempts,
+		NextAttemptAt: m.NextAttemptAt,
+		LastError:     m.LastError,
+		CreatedAt:     m.CreatedAt,
+		DeliveredAt:   m.DeliveredAt,
+	}
+}
+
+// webhookEventTypes maps the GraphQL event enum to the names sent to
+// endpoints.
+var webhookEventTypes = map[model.WebhookEventType]webhookDomain.EventType{
+	model.WebhookEventTypeOrderCreated:       webhookDomain.EventOrderCreated,
+	model.WebhookEventTypeOrderStatusChanged: webhookDomain.EventOrderStatusChanged,
+	model.WebhookEventTypePaymentPaid:        webhookDomain.EventPaymentPaid,
+	model.WebhookEventTypeRefundCreated:      webhookDomain.EventRefundCreated,
+}
+
+func toGQLWebhookEventType(t webhookDomain.EventType) model.WebhookEventType {
+	for gql, domainType := range webhookEventTypes {
+		if domainType == t {
+			return gql
+		}
+	}
+	return model.WebhookEventType(t)
+}
+
+func fromGQLWebhookEvents(events []model.WebhookEventType) pq.StringArray {
+	out := make(pq.StringArray, len(events))
+	for i, e := range events {
+		out[i] = string(webhookEventTypes[e])
+	}
+	return out
+}
+
+func toGQLWebhookEndpoint(e *webhookDomain.Endpoint) *model.WebhookEndpoint {
+	events := make([]model.WebhookEventType, len(e.Events))
+	for i, ev := range e.Events {
+		events[i] = toGQLWebhookEventType(webhookDomain.EventType(ev))
+	}
+	return &model.WebhookEndpoint{
+		ID:        e.ID,
+		URL:       e.URL,
+		Events:    events,
+		Secret:    e.Secret,
+		IsActive:  e.IsActive,
+		CreatedAt: e.CreatedAt,
+		UpdatedAt: e.UpdatedAt,
+	}
+}
 
-// isOrderUpdateTooLate reports whether now is more than lateNotificationThreshold
-// past the order's estimated ready time. Returns false when no ETA is set.
-func isOrderUpdateTooLate(now time.Time, estimatedReadyTime *time.Time) bool {
-	if estimatedReadyTime == nil {
-		return false
+func toGQLWebhookDelivery(d *webhookDomain.Delivery) *model.WebhookDelivery {
+	var payload any
+	_ = json.Unmarshal(d.Payload, &payload)
+	return &model.WebhookDelivery{
+		ID:             d.ID,
+		EndpointID:     d.EndpointID,
+		EventID:        d.EventID,
+		EventType:      toGQLWebhookEventType(d.EventType),
+		Payload:        payload,
+		Status:         model.WebhookDeliveryStatus(d.Status),
+		Attempts:       d.Attempts,
+		NextAttemptAt:  d.NextAttemptAt,
+		ResponseStatus: d.ResponseStatus,
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt,
+		DeliveredAt:    d.DeliveredAt,
 	}
-	return now.Sub(*estimatedReadyTime) > lateNotificationThreshold
 }
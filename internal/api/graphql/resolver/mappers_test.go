@@ -162,28 +162,44 @@ func TestValidatePreferredReadyTime_RejectsSlotNotAlignedToQuarter(t *testing.T)
 	}
 }
 
-func TestIsOrderUpdateTooLate(t *testing.T) {
-	now := time.Date(2026, 5, 13, 19, 0, 0, 0, time.UTC)
-	ptr := func(d time.Duration) *time.Time {
-		ts := now.Add(d)
-		return &ts
-	}
-	tests := []struct {
-		name string
-		eta  *time.Time
-		want bool
-	}{
-		{"nil ETA never suppresses", nil, false},
-		{"ETA in the future", ptr(30 * time.Minute), false},
-		{"ETA 30 min ago — still inside threshold", ptr(-30 * time.Minute), false},
-		{"ETA exactly 40 min ago — boundary, not yet stale", ptr(-40 * time.Minute), false},
-		{"ETA 41 min ago — over threshold", ptr(-41 * time.Minute), true},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := isOrderUpdateTooLate(now, tt.eta); got != tt.want {
-				t.Errorf("got %v, want %v", got, tt.want)
-			}
-		})
+func TestValidatePreferredReadyTime_FutureDayWithinHorizon(t *testing.T) {
+	cfg := &restaurantDomain.RestaurantConfig{
+		OrderingEnabled:       true,
+		OpeningHours:          weeklyOpeningHours(t),
+		PreparationMinutes:    30,
+		SchedulingHorizonDays: 7,
+	}
+
+	// Thursday evening, after closing: a Saturday lunch slot is accepted.
+	now := atBrussels(t, "2026-05-14", "22:30")
+	preferred := atBrussels(t, "2026-05-16", "13:00")
+	if err := validatePreferredReadyTime(&preferred, cfg, nil, now, false); err != nil {
+		t.Fatalf("expected Saturday slot to be accepted, got: %v", err)
+	}
+
+	// Tuesday is closed every week.
+	preferred = atBrussels(t, "2026-05-19", "13:00")
+	if err := validatePreferredReadyTime(&preferred, cfg, nil, now, false); err == nil {
+		t.Fatalf("expected a slot on a closed day to be rejected")
+	}
+
+	// Beyond the 7-day horizon.
+	preferred = atBrussels(t, "2026-05-22", "13:00")
+	if err := validatePreferredReadyTime(&preferred, cfg, nil, now, false); err == nil {
+		t.Fatalf("expected a slot past the horizon to be rejected")
+	}
+}
+
+func TestValidatePreferredReadyTime_SameDayOnlyWithoutHorizon(t *testing.T) {
+	cfg := &restaurantDomain.RestaurantConfig{
+		OrderingEnabled:    true,
+		OpeningHours:       weeklyOpeningHours(t),
+		PreparationMinutes: 30,
+	}
+
+	now := atBrussels(t, "2026-05-13", "12:00")
+	preferred := atBrussels(t, "2026-05-14", "12:30")
+	if err := validatePreferredReadyTime(&preferred, cfg, nil, now, true); err == nil {
+		t.Fatalf("expected next-day slot to be rejected when scheduling is off")
 	}
 }
@@ -12,92 +12,98 @@ import (
 	"tsb-service/pkg/fcm"
 )
 
-// SendNewOrderPush fans out a "new order" push notification to admin devices
-// (phones / dashboard) and POS handhelds. Safe to call when FCM and APNs are
-// both unconfigured (no-op). Runs in its own goroutine — callers should not
-// wrap it in `go`.
-//
-// Online-payment orders must only call this once the Mollie webhook confirms
-// payment; cash orders call it at creation time.
-func (r *Resolver) SendNewOrderPush(order *orderDomain.Order) {
-	if r.FCMClient == nil && r.APNsClient == nil {
-		return
+// sendNewOrderPush fans out a "new order" push notification to admin devices
+// (phones / dashboard) and POS handhelds; the outbox handlers call it once an
+// order reaches staff. escalation marks the reminder sent when staff leave the
+// order unconfirmed.
+func (r *Resolver) sendNewOrderPush(ctx context.Context, order *orderDomain.Order, escalation bool) {
+	msg := notificationApplication.GetNewOrderNotification(order.Language, string(order.OrderType), order.TotalPrice.StringFixed(2))
+	data := staffPushData(order, "new_order")
+	if escalation {
+		msg = notificationApplication.GetUnconfirmedOrderNotification(order.Language)
+		data["escalation"] = "true"
+	}
+	r.sendStaffPush(ctx, order, msg.Title, msg.Body, data)
+}
+
+// sendCustomerCancelledPush tells staff a customer cancelled their order.
+func (r *Resolver) sendCustomerCancelledPush(ctx context.Context, order *orderDomain.Order) {
+	msg := notificationApplication.GetCustomerCancelledNotification(order.Language)
+	r.sendStaffPush(ctx, order, msg.Title, msg.Body, staffPushData(order, "customer_cancelled"))
+}
+
+// staffPushData is the payload of a staff alert about order; pushType lets
+// the apps tell the alerts apart.
+func staffPushData(order *orderDomain.Order, pushType string) map[string]string {
+	return map[string]string{
+		"orderId": order.ID.String(),
+		"type":    pushType,
 	}
-	if order == nil {
+}
+
+// sendStaffPush fans an alert about order out to admin devices and POS
+// handhelds. Per-device failures are logged, not returned: retrying would
+// re-alert the devices that already got the push.
+func (r *Resolver) sendStaffPush(ctx context.Context, order *orderDomain.Order, title, body string, data map[string]string) {
+	if r.FCMClient == nil && r.APNsClient == nil {
 		return
 	}
-	// Store-review test orders never alert staff (no admin push, no POS push).
-	// TEMPORARY (revert after launch).
-	if order.IsTest {
-		zap.L().Info("suppressing new-order push for store-review test order",
+
+	// Admin devices (phones / dashboard). Independent of POS devices: an
+	// empty admin list must NOT short-circuit POS delivery.
+	adminTokens, tokenErr := r.NotificationService.GetAdminDeviceTokens(ctx)
+	if tokenErr != nil {
+		zap.L().Warn("failed to fetch admin device tokens",
 			zap.String("order_id", order.ID.String()),
+			zap.Error(tokenErr),
 		)
-		return
 	}
-
-	go func() {
-		msg := notificationApplication.GetNewOrderNotification(order.Language, string(order.OrderType), order.TotalPrice.StringFixed(2))
-		data := map[string]string{
-			"orderId": order.ID.String(),
-			"type":    "new_order",
-		}
-
-		// Admin devices (phones / dashboard). Independent of POS devices: an
-		// empty admin list must NOT short-circuit POS delivery.
-		adminTokens, tokenErr := r.NotificationService.GetAdminDeviceTokens(context.Background())
-		if tokenErr != nil {
-			zap.L().Warn("failed to fetch admin device tokens",
-				zap.String("order_id", order.ID.String()),
-				zap.Error(tokenErr),
-			)
-		}
-		for _, dt := range adminTokens {
-			if dt.Platform == "android" && r.FCMClient != nil {
-				if pushErr := r.FCMClient.SendAlert(dt.DeviceToken, msg.Title, msg.Body, data); pushErr != nil {
-					if errors.Is(pushErr, fcm.ErrTokenInvalid) {
-						_ = r.NotificationService.UnregisterDeviceToken(context.Background(), dt.UserID, dt.DeviceToken)
-					} else {
-						zap.L().Error("failed to send admin FCM push",
-							zap.String("order_id", order.ID.String()),
-							zap.Error(pushErr),
-						)
-					}
+	for _, dt := range adminTokens {
+		if dt.Platform == "android" && r.FCMClient != nil {
+			if pushErr := r.FCMClient.SendAlert(dt.DeviceToken, title, body, data); pushErr != nil {
+				if errors.Is(pushErr, fcm.ErrTokenInvalid) {
+					_ = r.NotificationService.UnregisterDeviceToken(ctx, dt.UserID, dt.DeviceToken)
+				} else {
+					zap.L().Error("failed to send admin FCM push",
+						zap.String("order_id", order.ID.String()),
+						zap.Error(pushErr),
+					)
 				}
-			} else if dt.Platform == "ios" && r.APNsClient != nil {
-				if pushErr := r.APNsClient.SendAlert(dt.DeviceToken, msg.Title, msg.Body, data); pushErr != nil {
-					if errors.Is(pushErr, apns.ErrTokenInvalid) {
-						_ = r.NotificationService.UnregisterDeviceToken(context.Background(), dt.UserID, dt.DeviceToken)
-					} else {
-						zap.L().Error("failed to send admin APNs push",
-							zap.String("order_id", order.ID.String()),
-							zap.Error(pushErr),
-						)
-					}
+			}
+		} else if dt.Platform == "ios" && r.APNsClient != nil {
+			if pushErr := r.APNsClient.SendAlert(dt.DeviceToken, title, body, data); pushErr != nil {
+				if errors.Is(pushErr, apns.ErrTokenInvalid) {
+					_ = r.NotificationService.UnregisterDeviceToken(ctx, dt.UserID, dt.DeviceToken)
+				} else {
+					zap.L().Error("failed to send admin APNs push",
+						zap.String("order_id", order.ID.String()),
+						zap.Error(pushErr),
+					)
 				}
 			}
 		}
+	}
 
-		// POS devices (Sunmi handhelds).
-		if r.PosService != nil && r.FCMClient != nil {
-			posTokens, posErr := r.PosService.GetActiveFCMTokens(context.Background())
-			if posErr != nil {
-				zap.L().Warn("failed to fetch POS FCM tokens",
-					zap.String("order_id", order.ID.String()),
-					zap.Error(posErr),
-				)
-			}
-			zap.L().Info("sending POS FCM pushes",
+	// POS devices (Sunmi handhelds).
+	if r.PosService != nil && r.FCMClient != nil {
+		posTokens, posErr := r.PosService.GetActiveFCMTokens(ctx)
+		if posErr != nil {
+			zap.L().Warn("failed to fetch POS FCM tokens",
 				zap.String("order_id", order.ID.String()),
-				zap.Int("token_count", len(posTokens)),
+				zap.Error(posErr),
 			)
-			for _, token := range posTokens {
-				if pushErr := r.FCMClient.SendAlert(token, msg.Title, msg.Body, data); pushErr != nil {
-					zap.L().Warn("failed to send POS FCM push",
-						zap.String("order_id", order.ID.String()),
-						zap.Error(pushErr),
-					)
-				}
+		}
+		zap.L().Info("sending POS FCM pushes",
+			zap.String("order_id", order.ID.String()),
+			zap.Int("token_count", len(posTokens)),
+		)
+		for _, token := range posTokens {
+			if pushErr := r.FCMClient.SendAlert(token, title, body, data); pushErr != nil {
+				zap.L().Warn("failed to send POS FCM push",
+					zap.String("order_id", order.ID.String()),
+					zap.Error(pushErr),
+				)
 			}
 		}
-	}()
+	}
 }
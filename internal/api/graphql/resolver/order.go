@@ -7,6 +7,7 @@ package resolver
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"strings"
@@ -14,8 +15,6 @@ import (
 	"tsb-service/internal/api/auth"
 	graphql1 "tsb-service/internal/api/graphql"
 	"tsb-service/internal/api/graphql/model"
-	addressDomain "tsb-service/internal/modules/address/domain"
-	notificationApplication "tsb-service/internal/modules/notification/application"
 	orderApplication "tsb-service/internal/modules/order/application"
 	orderDomain "tsb-service/internal/modules/order/domain"
 	paymentApplication "tsb-service/internal/modules/payment/application"
@@ -24,10 +23,6 @@ import (
 	productDomain "tsb-service/internal/modules/product/domain"
 	userApplication "tsb-service/internal/modules/user/application"
 	userDomain "tsb-service/internal/modules/user/domain"
-	"tsb-service/pkg/apns"
-	es "tsb-service/pkg/email/scaleway"
-	"tsb-service/pkg/fcm"
-	"tsb-service/pkg/money"
 	"tsb-service/pkg/utils"
 
 	"github.com/99designs/gqlgen/graphql"
@@ -46,6 +41,27 @@ func (r *mutationResolver) CreateOrder(ctx context.Context, input model.CreateOr
 		return nil, fmt.Errorf("invalid user ID: %w", err)
 	}
 
+	// A retry carrying an idempotency key already used by this user gets the
+	// order (and so the Mollie checkout URL) created the first time. The order
+	// is recorded on the key in the transaction that creates it; until then
+	// the claim is released on any failure below so the key can be retried.
+	idemKey := idempotencyKeyFromInput(input)
+	idemCompleted := false
+	if idemKey != "" {
+		existingID, err := r.IdempotencyService.Begin(ctx, userUUID, idemKey, createOrderFingerprint(input))
+		if err != nil {
+			return nil, idempotencyError(ctx, err)
+		}
+		if existingID != uuid.Nil {
+			return r.replayCreatedOrder(ctx, existingID)
+		}
+		defer func() {
+			if !idemCompleted {
+				r.releaseIdempotencyKey(ctx, userUUID, idemKey)
+			}
+		}()
+	}
+
 	// Resolve the ordering user up front. It is needed both to flag store-review
 	// orders (Google Play / App Store reviewers place a real order on prod to
 	// validate checkout) and to let those reviewers bypass the opening-hours gate
@@ -59,328 +75,37 @@ func (r *mutationResolver) CreateOrder(ctx context.Context, input model.CreateOr
 	isTestOrder := user != nil && auth.IsReviewUser(user.Email, user.FirstName, user.LastName)
 
 	// 0) Validate ordering availability and preferred ready time constraints.
-	// allowLunchOnly defaults to true so dev mode does not block testing of
-	// lunch-only items; production paths populate it from the resolved schedule.
 	// Store-review accounts skip the gate entirely so a reviewer can place an
 	// order outside opening hours. TEMPORARY (revert after launch).
-	allowLunchOnly := true
-	if !r.RestaurantService.IsDevMode() && !isTestOrder {
-		config, overrides, err := r.RestaurantService.GetConfigWithOverrides(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("failed to load restaurant config: %w", err)
-		}
-		if !config.OrderingEnabled {
-			return nil, fmt.Errorf("ordering is currently unavailable")
-		}
-
-		now := time.Now()
-		isOpenNow := config.IsOrderingCurrentlyOpen(now, overrides)
-		if err := validatePreferredReadyTime(input.PreferredReadyTime, config, overrides, now, isOpenNow); err != nil {
-			return nil, &gqlerror.Error{
-				Message:    err.Error(),
-				Path:       graphql.GetPath(ctx),
-				Extensions: map[string]any{"code": "USER_ERROR", "field": "preferredReadyTime"},
-			}
-		}
-
-		slotTime := now
-		if input.PreferredReadyTime != nil {
-			slotTime = *input.PreferredReadyTime
-		}
-		allowLunchOnly = config.IsLunchOnlyAllowed(slotTime, overrides)
-	}
-
-	// 2) Fetch products and build price map
-	prodCount := len(input.Items)
-	if prodCount == 0 {
-		return nil, fmt.Errorf("order must contain at least one item")
-	}
-	if prodCount > 50 {
-		return nil, fmt.Errorf("order cannot contain more than 50 different items")
-	}
-	ids := make([]string, prodCount)
-	for i, op := range input.Items {
-		ids[i] = op.ProductID.String()
-	}
-	products, err := r.ProductService.GetProductsByIDs(ctx, ids)
+	gate, err := r.checkOrderingWindow(ctx, input.PreferredReadyTime, isTestOrder)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve products: %w", err)
-	}
-	priceMap := make(map[uuid.UUID]decimal.Decimal, len(products))
-	vatCategoryMap := make(map[uuid.UUID]productDomain.VatCategory, len(products))
-	nameMap := make(map[uuid.UUID]string, len(products))
-	for _, p := range products {
-		priceMap[p.ID] = p.Price
-		vatCategoryMap[p.ID] = p.VatCategory
-		nameMap[p.ID] = p.Name
-	}
-	productLabel := func(id uuid.UUID) string {
-		if name, ok := nameMap[id]; ok && name != "" {
-			return name
-		}
-		return id.String()
+		return nil, err
 	}
-
-	// 3) Reject lunch-only products when the chosen slot is not a weekday lunch slot.
-	if !allowLunchOnly {
-		for _, p := range products {
-			if p.IsLunchOnly {
-				return nil, fmt.Errorf("product %q is only available for a weekday lunch slot", productLabel(p.ID))
-			}
-		}
-	}
-
-	// 4) Determine order type
-	var odType orderDomain.OrderType
-	orderServiceType := productDomain.ServiceTypeTakeaway
-	switch input.OrderType {
-	case model.OrderTypeEnumDelivery:
-		odType = orderDomain.OrderTypeDelivery
-		orderServiceType = productDomain.ServiceTypeDelivery
-	default:
-		odType = orderDomain.OrderTypePickUp
+	if gate.issue != nil {
+		return nil, pricingIssueError(ctx, *gate.issue)
 	}
 
-	orderLang := utils.GetLang(ctx)
-
-	// 5) Compute line totals and overall total
-	rawItems := make([]orderDomain.OrderProductRaw, 0, prodCount)
-	total := decimal.Zero
-	choiceCache := make(map[uuid.UUID]*productDomain.ProductChoice)
-	groupCache := make(map[uuid.UUID]*productDomain.ProductChoiceGroup)
-	groupsByProductCache := make(map[uuid.UUID][]*productDomain.ProductChoiceGroup)
-	loadChoice := func(id uuid.UUID) (*productDomain.ProductChoice, error) {
-		if choice, ok := choiceCache[id]; ok {
-			return choice, nil
-		}
-		choice, err := r.ProductService.GetChoiceByID(ctx, id)
+	// 1) Dine-in rounds are billed to the open session of the table whose QR
+	// code the guest scanned.
+	var tableSession *orderDomain.TableSession
+	if input.OrderType == model.OrderTypeEnumDineIn {
+		tableSession, err = r.dineInSession(ctx, input)
 		if err != nil {
 			return nil, err
 		}
-		choiceCache[id] = choice
-		return choice, nil
 	}
-	loadGroupsByProduct := func(productID uuid.UUID) ([]*productDomain.ProductChoiceGroup, error) {
-		if groups, ok := groupsByProductCache[productID]; ok {
-			return groups, nil
-		}
-		groups, err := r.ProductService.GetChoiceGroupsByProductID(ctx, productID)
-		if err != nil {
-			return nil, err
-		}
-		groupsByProductCache[productID] = groups
-		for _, group := range groups {
-			groupCache[group.ID] = group
-		}
-		return groups, nil
-	}
-	for _, op := range input.Items {
-		pid := op.ProductID
-		unitPrice, ok := priceMap[pid]
-		if !ok {
-			return nil, fmt.Errorf("product %s not found", productLabel(pid))
-		}
-		qty := int64(op.Quantity)
-		if qty <= 0 || qty > 99 {
-			return nil, fmt.Errorf("invalid quantity for %s: must be between 1 and 99", productLabel(pid))
-		}
-
-		selectionByChoice := make(map[uuid.UUID]int)
-		selectionGroupByChoice := make(map[uuid.UUID]uuid.UUID)
-		selectionCountByGroup := make(map[uuid.UUID]int)
-
-		if op.ChoiceID != nil {
-			choice, err := loadChoice(*op.ChoiceID)
-			if err != nil {
-				return nil, fmt.Errorf("failed to retrieve choice %s: %w", op.ChoiceID, err)
-			}
-			if choice == nil {
-				return nil, fmt.Errorf("choice %s not found", op.ChoiceID)
-			}
-			if choice.ProductID != pid {
-				return nil, fmt.Errorf("choice %s does not belong to product %s", op.ChoiceID, productLabel(pid))
-			}
-			selectionByChoice[choice.ID]++
-			selectionGroupByChoice[choice.ID] = choice.ChoiceGroupID
-			selectionCountByGroup[choice.ChoiceGroupID]++
-		}
 
-		if op.Selections != nil {
-			for _, selection := range op.Selections {
-				if selection.Quantity <= 0 {
-					return nil, fmt.Errorf("selection quantity must be > 0 for product %s", productLabel(pid))
-				}
-				choice, err := loadChoice(selection.ChoiceID)
-				if err != nil {
-					return nil, fmt.Errorf("failed to retrieve choice %s: %w", selection.ChoiceID, err)
-				}
-				if choice == nil {
-					return nil, fmt.Errorf("choice %s not found", selection.ChoiceID)
-				}
-				if choice.ProductID != pid {
-					return nil, fmt.Errorf("choice %s does not belong to product %s", selection.ChoiceID, productLabel(pid))
-				}
-				if choice.ChoiceGroupID != selection.GroupID {
-					return nil, fmt.Errorf("choice %s does not belong to group %s", selection.ChoiceID, selection.GroupID)
-				}
-				selectionByChoice[selection.ChoiceID] += selection.Quantity
-				selectionGroupByChoice[selection.ChoiceID] = selection.GroupID
-				selectionCountByGroup[selection.GroupID] += selection.Quantity
-			}
-		}
-
-		groups, err := loadGroupsByProduct(pid)
-		if err != nil {
-			return nil, fmt.Errorf("failed to load choice groups for product %s: %w", productLabel(pid), err)
-		}
-		for _, group := range groups {
-			selectedCount := selectionCountByGroup[group.ID]
-			minRequired := group.MinSelections * int(qty)
-			maxAllowed := group.MaxSelections * int(qty)
-			if selectedCount < minRequired || selectedCount > maxAllowed {
-				return nil, fmt.Errorf(
-					"invalid number of selections for group %s on product %s: expected between %d and %d, got %d",
-					group.GetTranslationFor(orderLang),
-					productLabel(pid),
-					minRequired,
-					maxAllowed,
-					selectedCount,
-				)
-			}
-		}
-
-		selections := make([]orderDomain.OrderProductSelection, 0, len(selectionByChoice))
-		for choiceIDValue, quantity := range selectionByChoice {
-			choice := choiceCache[choiceIDValue]
-			modifier := choice.PriceModifier
-			if modifier.Sign() < 0 {
-				modifier = decimal.Zero
-			}
-			unitPrice = unitPrice.Add(modifier.Mul(decimal.NewFromInt(int64(quantity))))
-			selections = append(selections, orderDomain.OrderProductSelection{
-				GroupID:  selectionGroupByChoice[choiceIDValue],
-				ChoiceID: choiceIDValue,
-				Quantity: quantity,
-			})
-		}
-
-		if unitPrice.LessThan(decimal.Zero) {
-			return nil, fmt.Errorf("invalid price for product %s: price cannot be negative", productLabel(pid))
-		}
-
-		var choiceID *uuid.UUID
-		if len(selectionByChoice) == 1 {
-			for selectedChoiceID, quantity := range selectionByChoice {
-				if quantity == 1 {
-					cid := selectedChoiceID
-					choiceID = &cid
-				}
-			}
-		}
-
-		lineTotal := unitPrice.Mul(decimal.NewFromInt(qty))
-		vatRateApplied := vatCategoryMap[pid].VatRatePercent(orderServiceType)
-		total = total.Add(lineTotal)
-		rawItems = append(rawItems, orderDomain.OrderProductRaw{
-			ProductID:       pid,
-			Quantity:        qty,
-			UnitPrice:       unitPrice,
-			TotalPrice:      lineTotal,
-			VatRateApplied:  decimal.NewFromFloat(vatRateApplied),
-			ProductChoiceID: choiceID,
-			Selections:      selections,
-		})
-	}
-
-	// 6) Enforce minimum amounts (pickup has no minimum)
-	if odType == orderDomain.OrderTypeDelivery && total.LessThan(decimal.NewFromInt(25)) {
-		return nil, fmt.Errorf("minimum order amount for delivery is 25")
-	}
-
-	// 6) Compute delivery fee and build address snapshot
-	fee := decimal.NewFromInt(0)
-	var addrSnapshot *orderDomain.AddressSnapshot
-
-	if odType == orderDomain.OrderTypeDelivery {
-		if input.AddressPlaceID == nil || *input.AddressPlaceID == "" {
-			return nil, fmt.Errorf("addressPlaceId required for delivery")
-		}
-
-		addr, err := r.AddressService.Resolve(ctx, *input.AddressPlaceID, "")
-		if err != nil {
-			return nil, fmt.Errorf("failed to resolve address: %w", err)
-		}
-		if addr.Distance >= 9000 {
-			return nil, fmt.Errorf("address too far for delivery")
-		}
-		if isExcludedDeliveryPostcode(addr.Postcode) {
-			return nil, fmt.Errorf("address not eligible for delivery: excluded area")
-		}
-
-		fee = deliveryFeeFromDistance(addr.Distance)
-		total = total.Add(fee)
-
-		addrSnapshot = &orderDomain.AddressSnapshot{
-			StreetName:       &addr.StreetName,
-			HouseNumber:      &addr.HouseNumber,
-			BoxNumber:        addr.BoxNumber,
-			MunicipalityName: &addr.MunicipalityName,
-			Postcode:         &addr.Postcode,
-			Distance:         &addr.Distance,
-			PlaceID:          &addr.PlaceID,
-			Lat:              addr.Lat,
-			Lng:              addr.Lng,
-			IsManual:         false,
-		}
-	}
-
-	// Compute takeaway discount for PICKUP orders (10% on discountable items, only when subtotal ≥ 20€).
-	// The raw 10% is then rounded to 0,10 € so the customer sees a clean
-	// multiple of 10 cents on every surface (cart, receipt, Mollie).
-	takeawayDiscount := decimal.Zero
-	if odType == orderDomain.OrderTypePickUp && total.GreaterThanOrEqual(decimal.NewFromInt(20)) {
-		discountMap := make(map[uuid.UUID]bool, len(products))
-		for _, p := range products {
-			discountMap[p.ID] = p.IsDiscountable
-		}
-		for _, item := range rawItems {
-			if discountMap[item.ProductID] {
-				takeawayDiscount = takeawayDiscount.Add(item.TotalPrice.Mul(decimal.NewFromFloat(0.10)))
-			}
-		}
-		takeawayDiscount = money.RoundToNearest10Cents(takeawayDiscount)
-	}
-
-	// Validate and apply coupon discount (stacks with pickup discount)
-	couponDiscount := decimal.Zero
-	var couponCode *string
-	var validatedCouponID *uuid.UUID
-	if input.CouponCode != nil && *input.CouponCode != "" {
-		coupon, cd, err := r.CouponService.ValidateCoupon(ctx, *input.CouponCode, total, userUUID)
-		if err != nil {
-			return nil, fmt.Errorf("invalid coupon: %w", err)
-		}
-		// One coupon at a time: reject if the user already has another
-		// non-terminal order still holding a coupon.
-		hasActive, err := r.OrderService.HasActiveCouponOrder(ctx, userUUID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to check active coupon orders: %w", err)
-		}
-		if hasActive {
-			return nil, fmt.Errorf("you already have an active order using a coupon")
-		}
-		couponDiscount = money.RoundToNearest10Cents(cd)
-		couponCode = input.CouponCode
-		validatedCouponID = &coupon.ID
+	// 2) Price the cart: choice groups, modifiers, VAT, discounts, coupon and
+	// delivery fee. priceCart runs the same service, so the quote the app
+	// showed is the total charged here.
+	orderLang := utils.GetLang(ctx)
+	cart := cartFromInput(input, userUUID, orderLang, gate)
+	quote, err := r.PricingService.PriceCart(ctx, cart)
+	if err != nil {
+		return nil, err
 	}
-
-	// Ensure combined discounts never exceed the order total.
-	totalDiscount := takeawayDiscount.Add(couponDiscount)
-	if totalDiscount.GreaterThan(total) {
-		// Scale both proportionally, then snap to 0,10 €.
-		ratio := total.Div(totalDiscount)
-		takeawayDiscount = money.RoundToNearest10Cents(takeawayDiscount.Mul(ratio))
-		couponDiscount = money.RoundToNearest10Cents(total.Sub(takeawayDiscount))
+	if len(quote.Issues) > 0 {
+		return nil, pricingIssueError(ctx, quote.Issues[0])
 	}
 
 	var extras []orderDomain.OrderExtra
@@ -413,37 +138,55 @@ func (r *mutationResolver) CreateOrder(ctx context.Context, input model.CreateOr
 
 	tempOrder := orderDomain.NewOrder(
 		userUUID,
-		odType,
+		cart.OrderType,
 		input.IsOnlinePayment,
 		nil, // addressID is legacy; no longer used
 		input.AddressExtra,
 		input.OrderNote,
 		input.PreferredReadyTime,
 		extras,
-		&fee,
-		takeawayDiscount,
-		couponDiscount,
+		&quote.DeliveryFee,
+		quote.TakeawayDiscount,
+		quote.CouponDiscount,
 		orderLang,
-		addrSnapshot,
+		quote.Address,
 		cashPaymentAmount,
+		quote.TransactionFee,
 	)
-	tempOrder.CouponCode = couponCode
+	tempOrder.TakeawayDiscountPercent = quote.TakeawayDiscountPercent
+	tempOrder.CouponCode = quote.CouponCode
 	tempOrder.IsTest = isTestOrder
+	tempOrder.HeldForSchedule = gate.heldForSchedule
+	tempOrder.Tip = quote.Tip
+	if tableSession != nil {
+		tempOrder.TableSessionID = &tableSession.ID
+	}
 
-	// Atomically reserve coupon usage BEFORE creating the order to prevent race conditions
+	// Reserve kitchen capacity in the slot, then coupon usage, BEFORE creating
+	// the order to prevent race conditions.
+	slotReservation, err := r.reserveSlot(ctx, gate, cart)
+	if err != nil {
+		return nil, err
+	}
+	validatedCouponID := quote.CouponID
 	if validatedCouponID != nil {
 		ok, err := r.CouponService.IncrementUsageAtomic(ctx, *validatedCouponID, userUUID)
 		if err != nil {
+			r.cancelSlotReservation(ctx, slotReservation)
 			return nil, fmt.Errorf("failed to reserve coupon: %w", err)
 		}
 		if !ok {
+			r.cancelSlotReservation(ctx, slotReservation)
 			return nil, fmt.Errorf("coupon is no longer valid or usage limit reached")
 		}
 	}
 
-	// 8) Persist via service
-	order, itemsRaw, err := r.OrderService.CreateOrder(ctx, tempOrder, &rawItems)
+	// 8) Persist via service, attaching the slot reservation in the same
+	// transaction
+	rawItems := quote.RawItems()
+	order, itemsRaw, err := r.OrderService.CreateOrder(ctx, tempOrder, &rawItems, slotReservationID(slotReservation), idemKey)
 	if err != nil {
+		r.cancelSlotReservation(ctx, slotReservation)
 		if validatedCouponID != nil {
 			if rbErr := r.CouponService.DecrementUsageAtomic(ctx, *validatedCouponID, userUUID); rbErr != nil {
 				zap.L().Error("failed to rollback coupon after order creation failure",
@@ -458,14 +201,23 @@ func (r *mutationResolver) CreateOrder(ctx context.Context, input model.CreateOr
 		if isActiveCouponOrderConflict(err) {
 			return nil, fmt.Errorf("you already have an active order using a coupon")
 		}
+		if errors.Is(err, orderDomain.ErrIdempotencyKeyInFlight) {
+			return nil, idempotencyError(ctx, err)
+		}
+		// The table was settled since the guest joined its session.
+		if errors.Is(err, orderDomain.ErrTableSessionClosed) {
+			return nil, tableError(ctx, "tableToken", err)
+		}
 		return nil, fmt.Errorf("failed to create order: %w", err)
 	}
+	// Deleting the order below, should its payment fail, frees the key too.
+	idemCompleted = true
 
 	// 9) Enrich each raw item with its product details
 	//    build a lookup map from product ID → product info
-	prodMap := make(map[uuid.UUID]productDomain.ProductOrderDetails, len(products))
-	for _, p := range products {
-		prodMap[p.ID] = *p
+	prodMap := make(map[uuid.UUID]orderDomain.Product, len(quote.Lines))
+	for _, l := range quote.Lines {
+		prodMap[l.ProductID] = l.Product
 	}
 
 	// now map persisted raw items → full OrderProduct
@@ -477,13 +229,7 @@ func (r *mutationResolver) CreateOrder(ctx context.Context, input model.CreateOr
 			return nil, fmt.Errorf("missing product details for %s", ir.ProductID)
 		}
 		items[i] = orderDomain.OrderProduct{
-			Product: orderDomain.Product{
-				ID:           pd.ID,
-				Code:         pd.Code,
-				CategoryName: pd.CategoryName,
-				Name:         pd.Name,
-				VatCategory:  string(pd.VatCategory),
-			},
+			Product:    pd,
 			Quantity:   ir.Quantity,
 			UnitPrice:  ir.UnitPrice,
 			TotalPrice: ir.TotalPrice,
@@ -511,435 +257,198 @@ func (r *mutationResolver) CreateOrder(ctx context.Context, input model.CreateOr
 			}
 			return nil, fmt.Errorf("failed to create payment: %w", err)
 		}
-	} else {
-		// If offline payment, send the notification e-mail already
-		if user.NotifyOrderUpdates {
-			go func() {
-				_, cancel := emailContext()
-				defer cancel()
-				if err := es.SendOrderPendingEmail(*user, order.Language, *order, items); err != nil {
-					zap.L().Error("failed to send order pending email", zap.String("order_id", order.ID.String()), zap.Error(err))
-				}
-			}()
-		}
 	}
 
 	// 10) Map to GraphQL model
 	gql := ToGQLOrder(order)
 
-	// 11) Publish subscription. Online-payment orders are only published to
-	// the admin dashboard once the Mollie webhook confirms payment (see
-	// payment/interfaces/handler.go) — unpaid orders are not actionable.
-	// Store-review test orders are never published — they must not appear in any
-	// admin/POS client's live order list. TEMPORARY (revert after launch).
-	if !input.IsOnlinePayment && !order.IsTest {
-		r.Broker.Publish("orderCreated", gql)
-	}
-
-	// 12) Send push notification to admin devices + POS handhelds (non-blocking).
-	// Online-payment orders defer this until the Mollie webhook confirms payment
-	// (see payment/interfaces/handler.go) — we must not wake up POS/staff for an
-	// order that may never be paid.
-	if !input.IsOnlinePayment && !order.IsTest {
-		r.SendNewOrderPush(order)
-	}
+	// 11) The pending email, the orderCreated event and the staff push of cash
+	// orders were written to the outbox with the order (see
+	// orderDomain.CreatedOrderEffects). Online-payment orders are announced by
+	// the Mollie webhook once paid (see payment/interfaces/handler.go), and
+	// orders scheduled for later by the release sweep (see cmd/app/main.go).
+	r.OutboxService.Notify()
 
 	return gql, nil
 }
 
 // UpdateOrder is the resolver for the updateOrder field.
 func (r *mutationResolver) UpdateOrder(ctx context.Context, id uuid.UUID, input model.UpdateOrderInput) (*model.Order, error) {
-	oldOrder, _, err := r.OrderService.GetOrderByID(ctx, id)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get order: %w", err)
+	force := input.Force != nil && *input.Force
+	if force && !utils.GetIsAdmin(ctx) {
+		return nil, &gqlerror.Error{
+			Message:    "FORBIDDEN: only admins can force a status change",
+			Path:       graphql.GetPath(ctx),
+			Extensions: map[string]any{"code": "FORBIDDEN"},
+		}
 	}
 
-	err = r.OrderService.UpdateOrder(ctx, id, input.Status, input.EstimatedReadyTime, input.CancellationReason)
+	readyTime := input.EstimatedReadyTime
+	var eta *orderDomain.EtaEstimate
+	if input.Status != nil && *input.Status == orderDomain.OrderStatusConfirmed {
+		eta, readyTime = r.confirmationEta(ctx, id, readyTime)
+	}
+
+	err := r.OrderService.UpdateOrder(ctx, id, input.Status, readyTime, input.CancellationReason,
+		orderApplication.UpdateOrderOptions{Force: force, Notify: true, Eta: eta})
 	if err != nil {
+		var transitionErr *orderDomain.StatusTransitionError
+		if errors.As(err, &transitionErr) {
+			return nil, &gqlerror.Error{
+				Message:    transitionErr.Error(),
+				Path:       graphql.GetPath(ctx),
+				Extensions: map[string]any{"code": "USER_ERROR", "field": "status"},
+			}
+		}
+		if errors.Is(err, orderDomain.ErrOrderStatusChanged) {
+			return nil, &gqlerror.Error{
+				Message:    "the order was updated meanwhile, reload it and try again",
+				Path:       graphql.GetPath(ctx),
+				Extensions: map[string]any{"code": "USER_ERROR", "field": "status"},
+			}
+		}
 		return nil, fmt.Errorf("failed to update order status: %w", err)
 	}
 
+	// Emails, pushes, the refund and the subscription events were written to
+	// the outbox with the update (see orderDomain.StatusChangeEffects and
+	// outbox_handlers.go); wake the worker so they go out right away.
+	r.OutboxService.Notify()
+
 	// Fetch the updated order
-	o, opRaw, err := r.OrderService.GetOrderByID(ctx, id)
+	o, _, err := r.OrderService.GetOrderByID(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get order: %w", err)
 	}
+	return ToGQLOrder(o), nil
+}
 
-	// Use the stored order language for all emails (captured at order creation)
-	lang := o.Language
-
-	// Skip progression-status notifications when the update lands well after
-	// the customer has likely already received the order. CANCELLED / FAILED
-	// stay exempt — those carry information regardless of timing (refund,
-	// "your order wasn't fulfilled").
-	suppressLate := isOrderUpdateTooLate(time.Now(), o.EstimatedReadyTime) &&
-		o.OrderStatus != orderDomain.OrderStatusCanceled &&
-		o.OrderStatus != orderDomain.OrderStatusFailed
-	if suppressLate {
-		zap.L().Info("suppressed late order notification",
-			zap.String("order_id", o.ID.String()),
-			zap.String("status", string(o.OrderStatus)),
-			zap.Time("estimated_ready_time", *o.EstimatedReadyTime),
-		)
-	}
-
-	// If new status is "CONFIRMED" or "PREPARING", send the confirmation email
-	if oldOrder.OrderStatus == orderDomain.OrderStatusPending && (o.OrderStatus == orderDomain.OrderStatusConfirmed || o.OrderStatus == orderDomain.OrderStatusPreparing) {
-		go func() {
-			ctx, cancel := emailContext()
-			defer cancel()
-			user, err := r.UserService.GetUserByID(ctx, o.UserID.String())
-			if err != nil {
-				zap.L().Error("failed to retrieve user", zap.String("order_id", o.ID.String()), zap.Error(err))
-				return
-			}
-
-			// 1) collect product IDs as strings
-			raws := *opRaw
-			ids := make([]string, len(raws))
-			for i, ir := range raws {
-				ids[i] = ir.ProductID.String()
-			}
-
-			// 2) fetch all products in one go
-			prodDetailsSlice, err := r.ProductService.GetProductsByIDs(ctx, ids)
-			if err != nil {
-				zap.L().Error("failed to retrieve products", zap.String("order_id", o.ID.String()), zap.Error(err))
-				return
-			}
-
-			// 3) build a map for quick lookup
-			prodMap := make(map[uuid.UUID]productDomain.ProductOrderDetails, len(prodDetailsSlice))
-			for _, pd := range prodDetailsSlice {
-				prodMap[pd.ID] = *pd
-			}
-
-			// 4) enrich raws into your final items
-			items := make([]orderDomain.OrderProduct, len(raws))
-			for i, ir := range raws {
-				pd, ok := prodMap[ir.ProductID]
-				if !ok {
-					zap.L().Error("missing product details", zap.String("order_id", o.ID.String()), zap.String("product_id", ir.ProductID.String()))
-					return
-				}
-				items[i] = orderDomain.OrderProduct{
-					Product: orderDomain.Product{
-						ID:           pd.ID,
-						Code:         pd.Code,
-						CategoryName: pd.CategoryName,
-						Name:         pd.Name,
-						VatCategory:  string(pd.VatCategory),
-					},
-					Quantity:   ir.Quantity,
-					UnitPrice:  ir.UnitPrice,
-					TotalPrice: ir.TotalPrice,
-				}
-			}
-
-			var address *addressDomain.Address
-			// Use denormalized address fields from the order (all new orders have these)
-			if o.StreetName != nil {
-				address = addressFromOrder(o)
-			}
+// CancelMyOrder is the resolver for the cancelMyOrder field.
+func (r *mutationResolver) CancelMyOrder(ctx context.Context, id uuid.UUID) (*model.Order, error) {
+	userID := utils.GetUserID(ctx)
 
-			if !user.NotifyOrderUpdates {
-				return
-			}
-			// address may be nil for very old orders; email service should handle gracefully
-			err = es.SendOrderConfirmedEmail(*user, lang, *o, items, address)
-			if err != nil {
-				zap.L().Error("failed to send order confirmed email", zap.String("order_id", o.ID.String()), zap.Error(err))
-			}
-		}()
+	o, _, err := r.OrderService.GetOrderByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", err)
 	}
-
-	// Send order ready email for actionable statuses:
-	// - AWAITING_PICK_UP: pickup customers need to come get their order
-	// - OUT_FOR_DELIVERY: delivery customers need to know food is on its way
-	if (o.OrderStatus == orderDomain.OrderStatusAwaitingUp || o.OrderStatus == orderDomain.OrderStatusOutForDelivery) && !suppressLate {
-		go func() {
-			ctx, cancel := emailContext()
-			defer cancel()
-			user, err := r.UserService.GetUserByID(ctx, o.UserID.String())
-			if err != nil {
-				zap.L().Error("failed to retrieve user", zap.String("order_id", o.ID.String()), zap.Error(err))
-				return
-			}
-
-			if !user.NotifyOrderUpdates {
-				return
-			}
-			err = es.SendOrderReadyEmail(*user, lang, *o)
-			if err != nil {
-				zap.L().Error("failed to send order ready email", zap.String("order_id", o.ID.String()), zap.Error(err))
-			}
-		}()
+	if o == nil || o.UserID.String() != userID {
+		return nil, &gqlerror.Error{
+			Message:    "FORBIDDEN: order does not belong to caller",
+			Path:       graphql.GetPath(ctx),
+			Extensions: map[string]any{"code": "FORBIDDEN"},
+		}
 	}
 
-	// If estimated ready time changed on an already-confirmed order (not the initial confirmation)
-	isInitialConfirmation := oldOrder.OrderStatus == orderDomain.OrderStatusPending &&
-		(o.OrderStatus == orderDomain.OrderStatusConfirmed || o.OrderStatus == orderDomain.OrderStatusPreparing)
-	readyTimeWasUpdated := input.EstimatedReadyTime != nil &&
-		o.EstimatedReadyTime != nil &&
-		!isInitialConfirmation &&
-		(oldOrder.EstimatedReadyTime == nil || !oldOrder.EstimatedReadyTime.Equal(*o.EstimatedReadyTime))
-	if readyTimeWasUpdated {
-		go func() {
-			ctx, cancel := emailContext()
-			defer cancel()
-			user, err := r.UserService.GetUserByID(ctx, o.UserID.String())
-			if err != nil {
-				zap.L().Error("failed to retrieve user", zap.String("order_id", o.ID.String()), zap.Error(err))
-				return
-			}
-
-			if !user.NotifyOrderUpdates {
-				return
-			}
-			err = es.SendReadyTimeUpdatedEmail(*user, lang, *o)
-			if err != nil {
-				zap.L().Error("failed to send ready time updated email", zap.String("order_id", o.ID.String()), zap.Error(err))
-			}
-		}()
+	config, err := r.RestaurantService.GetConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get restaurant config: %w", err)
 	}
 
-	// If new status is "CANCELED", refund & send the cancellation email
-	if o.OrderStatus == orderDomain.OrderStatusCanceled {
-		payment, err := r.PaymentService.GetPaymentByOrderID(ctx, o.ID)
-		if err == nil && payment != nil {
-			refundErr := r.PaymentService.CreateFullRefund(ctx, payment.MolliePaymentID)
-			if refundErr != nil {
-				return nil, fmt.Errorf("failed to initiate refund: %w", refundErr)
+	if err := r.OrderService.CancelMyOrder(ctx, id, config.CancellationGrace()); err != nil {
+		if errors.Is(err, orderDomain.ErrCustomerCancelNotAllowed) || errors.Is(err, orderDomain.ErrOrderStatusChanged) {
+			return nil, &gqlerror.Error{
+				Message:    orderDomain.ErrCustomerCancelNotAllowed.Error(),
+				Path:       graphql.GetPath(ctx),
+				Extensions: map[string]any{"code": "USER_ERROR", "field": "status"},
 			}
-
-			// Send refund issued email
-			go func() {
-				ctx, cancel := emailContext()
-				defer cancel()
-				user, err := r.UserService.GetUserByID(ctx, o.UserID.String())
-				if err != nil {
-					zap.L().Error("failed to retrieve user", zap.String("order_id", o.ID.String()), zap.Error(err))
-					return
-				}
-
-				if !user.NotifyOrderUpdates {
-					return
-				}
-				refundAmount := utils.FormatDecimal(o.TotalPrice)
-				err = es.SendRefundIssuedEmail(*user, lang, o.ID.String(), refundAmount)
-				if err != nil {
-					zap.L().Error("failed to send refund issued email", zap.String("order_id", o.ID.String()), zap.Error(err))
-				}
-			}()
 		}
-
-		go func() {
-			ctx, cancel := emailContext()
-			defer cancel()
-			user, err := r.UserService.GetUserByID(ctx, o.UserID.String())
-			if err != nil {
-				zap.L().Error("failed to retrieve user", zap.String("order_id", o.ID.String()), zap.Error(err))
-				return
-			}
-
-			if !user.NotifyOrderUpdates {
-				return
-			}
-			err = es.SendOrderCanceledEmail(*user, lang, o.ID.String(), o.CancellationReason)
-			if err != nil {
-				zap.L().Error("failed to send order canceled email", zap.String("order_id", o.ID.String()), zap.Error(err))
-			}
-		}()
+		return nil, fmt.Errorf("failed to cancel order: %w", err)
 	}
 
-	// Map the order to the GraphQL model
-	order := ToGQLOrder(o)
+	// The refund, emails, staff push and subscription events were written to
+	// the outbox with the cancellation (see orderDomain.CustomerCancelEffects).
+	r.OutboxService.Notify()
 
-	// Publish the order update to the broker
-	r.Broker.Publish("orderUpdated", order)
-	r.Broker.Publish(fmt.Sprintf("orderUpdated:%s", id), order)
-
-	// Send standard alert push notifications to user's devices (non-blocking).
-	// Only fired when the status actually changed.
-	//
-	// Exception: OUT_FOR_DELIVERY → DELIVERED is suppressed. The driver hands the
-	// food directly to the customer, so a "Your order has been delivered" push
-	// would be noise arriving at exactly the moment the customer already knows.
-	statusChanged := oldOrder.OrderStatus != o.OrderStatus
-	suppressDelivered := oldOrder.OrderStatus == orderDomain.OrderStatusOutForDelivery &&
-		o.OrderStatus == orderDomain.OrderStatusDelivered
-	if r.APNsClient != nil && statusChanged && !suppressDelivered && !suppressLate {
-		go func() {
-			deviceTokens, tokenErr := r.NotificationService.GetDeviceTokens(context.Background(), o.UserID)
-			if tokenErr != nil || len(deviceTokens) == 0 {
-				return
-			}
-
-			msg := notificationApplication.GetOrderStatusNotification(o.OrderStatus, o.Language, string(o.OrderType), o.CancellationReason)
-			if msg.Body == "" {
-				return
-			}
-
-			data := map[string]string{
-				"orderId": id.String(),
-				"status":  string(o.OrderStatus),
-			}
-
-			for _, dt := range deviceTokens {
-				if dt.Platform == "ios" && r.APNsClient != nil {
-					if pushErr := r.APNsClient.SendAlert(dt.DeviceToken, msg.Title, msg.Body, data); pushErr != nil {
-						if errors.Is(pushErr, apns.ErrTokenInvalid) {
-							_ = r.NotificationService.UnregisterDeviceToken(context.Background(), o.UserID, dt.DeviceToken)
-						} else {
-							zap.L().Error("failed to send alert push",
-								zap.String("order_id", id.String()),
-								zap.Error(pushErr),
-							)
-						}
-					}
-				} else if dt.Platform == "android" && r.FCMClient != nil {
-					if pushErr := r.FCMClient.SendAlert(dt.DeviceToken, msg.Title, msg.Body, data); pushErr != nil {
-						if errors.Is(pushErr, fcm.ErrTokenInvalid) {
-							_ = r.NotificationService.UnregisterDeviceToken(context.Background(), o.UserID, dt.DeviceToken)
-						} else {
-							zap.L().Error("failed to send FCM push",
-								zap.String("order_id", id.String()),
-								zap.Error(pushErr),
-							)
-						}
-					}
-				}
-			}
-		}()
+	o, _, err = r.OrderService.GetOrderByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", err)
 	}
+	return ToGQLOrder(o), nil
+}
 
-	// Update the iOS Live Activity (Lock Screen / Dynamic Island) for this order.
-	// Fires on EVERY status change — including OUT_FOR_DELIVERY → DELIVERED, whose
-	// *alert* is suppressed above — because the activity must be ended on terminal
-	// statuses regardless of alert suppression.
-	if r.APNsClient != nil && statusChanged {
-		go func() {
-			laTokens, tokenErr := r.NotificationService.GetLiveActivityTokens(context.Background(), id)
-			if tokenErr != nil || len(laTokens) == 0 {
-				return
-			}
-
-			terminal := o.OrderStatus == orderDomain.OrderStatusDelivered ||
-				o.OrderStatus == orderDomain.OrderStatusPickedUp ||
-				o.OrderStatus == orderDomain.OrderStatusCanceled ||
-				o.OrderStatus == orderDomain.OrderStatusFailed
-			event := "update"
-			if terminal {
-				event = "end"
-			}
-
-			contentState := notificationApplication.GetLiveActivityContentState(
-				o.OrderStatus, o.Language, string(o.OrderType), o.CancellationReason,
-			)
-
-			for _, lt := range laTokens {
-				if pushErr := r.APNsClient.SendLiveActivity(lt.PushToken, contentState, event); pushErr != nil {
-					zap.L().Warn("failed to send live activity push",
-						zap.String("order_id", id.String()),
-						zap.Error(pushErr),
-					)
-				}
-			}
+// RetryOrderPayment is the resolver for the retryOrderPayment field.
+func (r *mutationResolver) RetryOrderPayment(ctx context.Context, orderID uuid.UUID, paymentRedirectURL *string) (*model.Order, error) {
+	userID := utils.GetUserID(ctx)
 
-			if terminal {
-				if clearErr := r.NotificationService.ClearLiveActivityTokens(context.Background(), id); clearErr != nil {
-					zap.L().Warn("failed to clear live activity tokens",
-						zap.String("order_id", id.String()),
-						zap.Error(clearErr),
-					)
-				}
-			}
-		}()
+	o, raws, err := r.OrderService.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", err)
 	}
-
-	// Drive the Android Live Update (promoted ongoing notification) via an FCM
-	// data message to the user's Android devices. Like the iOS block, this fires
-	// on every status change so the Live Update ends on terminal statuses.
-	if r.FCMClient != nil && statusChanged {
-		go func() {
-			deviceTokens, tokenErr := r.NotificationService.GetDeviceTokens(context.Background(), o.UserID)
-			if tokenErr != nil || len(deviceTokens) == 0 {
-				return
-			}
-
-			deepLink := fmt.Sprintf("tsbmobile://order-completed/%s", id.String())
-			data := notificationApplication.GetLiveUpdateData(
-				id.String(), o.OrderStatus, o.Language, string(o.OrderType), deepLink, o.CancellationReason,
-			)
-
-			for _, dt := range deviceTokens {
-				if dt.Platform != "android" {
-					continue
-				}
-				if pushErr := r.FCMClient.SendDataMessage(dt.DeviceToken, data); pushErr != nil {
-					if errors.Is(pushErr, fcm.ErrTokenInvalid) {
-						_ = r.NotificationService.UnregisterDeviceToken(context.Background(), o.UserID, dt.DeviceToken)
-					} else {
-						zap.L().Warn("failed to send live update data message",
-							zap.String("order_id", id.String()),
-							zap.Error(pushErr),
-						)
-					}
-				}
-			}
-		}()
+	if o == nil || o.UserID.String() != userID {
+		return nil, &gqlerror.Error{
+			Message:    "FORBIDDEN: order does not belong to caller",
+			Path:       graphql.GetPath(ctx),
+			Extensions: map[string]any{"code": "FORBIDDEN"},
+		}
+	}
+	if err := orderDomain.CheckPaymentRetry(o, time.Now()); err != nil {
+		return nil, pricingIssueError(ctx, orderDomain.PricingIssue{Field: "status", Message: err.Error()})
+	}
+	user, err := r.UserService.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve user: %w", err)
+	}
+	var lines []orderDomain.OrderProductRaw
+	if raws != nil {
+		lines = *raws
+	}
+	items, err := r.orderEmailItems(ctx, lines)
+	if err != nil {
+		return nil, err
+	}
+	for i, l := range lines {
+		items[i].VatRate = l.VatRateApplied
 	}
 
-	// Send a visible alert when the estimated ready time changes.
-	if (r.APNsClient != nil || r.FCMClient != nil) && readyTimeWasUpdated {
-		go func() {
-			deviceTokens, tokenErr := r.NotificationService.GetDeviceTokens(context.Background(), o.UserID)
-			if tokenErr != nil || len(deviceTokens) == 0 {
-				return
-			}
-
-			msg := notificationApplication.GetReadyTimeUpdatedNotification(o.Language, o.EstimatedReadyTime)
-			if msg.Body == "" {
-				return
-			}
-
-			data := map[string]string{
-				"orderId": id.String(),
-				"status":  string(o.OrderStatus),
-			}
-			if o.EstimatedReadyTime != nil {
-				data["estimatedReadyTime"] = o.EstimatedReadyTime.Format(time.RFC3339)
-			}
+	// The order must still fit the opening hours and the kitchen capacity
+	// its cancellation gave back.
+	gate, err := r.checkOrderingWindow(ctx, o.PreferredReadyTime, o.IsTest)
+	if err != nil {
+		return nil, err
+	}
+	if gate.issue != nil {
+		return nil, pricingIssueError(ctx, *gate.issue)
+	}
+	cart := orderDomain.Cart{OrderType: o.OrderType, Items: make([]orderDomain.CartItem, len(lines))}
+	for i, l := range lines {
+		cart.Items[i] = orderDomain.CartItem{ProductID: l.ProductID, Quantity: int(l.Quantity)}
+	}
+	slotReservation, err := r.reserveSlot(ctx, gate, cart)
+	if err != nil {
+		return nil, err
+	}
 
-			for _, dt := range deviceTokens {
-				if dt.Platform == "ios" && r.APNsClient != nil {
-					if pushErr := r.APNsClient.SendAlert(dt.DeviceToken, msg.Title, msg.Body, data); pushErr != nil {
-						if errors.Is(pushErr, apns.ErrTokenInvalid) {
-							_ = r.NotificationService.UnregisterDeviceToken(context.Background(), o.UserID, dt.DeviceToken)
-						} else {
-							zap.L().Error("failed to send ready time APNs push",
-								zap.String("order_id", id.String()),
-								zap.Error(pushErr),
-							)
-						}
-					}
-				} else if dt.Platform == "android" && r.FCMClient != nil {
-					if pushErr := r.FCMClient.SendAlert(dt.DeviceToken, msg.Title, msg.Body, data); pushErr != nil {
-						if errors.Is(pushErr, fcm.ErrTokenInvalid) {
-							_ = r.NotificationService.UnregisterDeviceToken(context.Background(), o.UserID, dt.DeviceToken)
-						} else {
-							zap.L().Error("failed to send ready time FCM push",
-								zap.String("order_id", id.String()),
-								zap.Error(pushErr),
-							)
-						}
-					}
-				}
-			}
-		}()
+	order, err := r.OrderService.ReopenForPaymentRetry(ctx, orderID, slotReservationID(slotReservation))
+	if err != nil {
+		r.cancelSlotReservation(ctx, slotReservation)
+		switch {
+		case errors.Is(err, orderDomain.ErrPaymentRetryNotAllowed) || errors.Is(err, orderDomain.ErrOrderStatusChanged):
+			return nil, pricingIssueError(ctx, orderDomain.PricingIssue{Field: "status", Message: orderDomain.ErrPaymentRetryNotAllowed.Error()})
+		case errors.Is(err, orderDomain.ErrPaymentRetryCouponUnavailable):
+			return nil, pricingIssueError(ctx, orderDomain.PricingIssue{Field: "couponCode", Message: err.Error()})
+		case isActiveCouponOrderConflict(err):
+			return nil, fmt.Errorf("you already have an active order using a coupon")
+		}
+		return nil, fmt.Errorf("failed to reopen order: %w", err)
+	}
+	// The reopening was written to the outbox with the order (see
+	// orderDomain.ReopenedOrderEffects).
+	r.OutboxService.Notify()
+
+	molliePayment, err := r.PaymentService.CreatePayment(ctx, *order, items, *user, addressFromOrder(order), paymentRedirectURL)
+	if err != nil || molliePayment == nil {
+		// Cancel the order again as it was; this gives back the slot and the
+		// coupon, and leaves the retry open.
+		canceled := orderDomain.OrderStatusCanceled
+		reason := orderDomain.OrderCancellationReasonPaymentFailed
+		if cErr := r.OrderService.UpdateOrder(ctx, order.ID, &canceled, nil, &reason, orderApplication.UpdateOrderOptions{}); cErr != nil {
+			zap.L().Error("failed to cancel order after payment retry failure",
+				zap.String("order_id", order.ID.String()), zap.Error(cErr))
+		}
+		return nil, fmt.Errorf("failed to create payment: %w", err)
 	}
 
-	return order, nil
+	return ToGQLOrder(order), nil
 }
 
 // RegisterDeviceToken is the resolver for the registerDeviceToken field.
@@ -1033,6 +542,71 @@ func (r *mutationResolver) UpdateMyOrdersLanguage(ctx context.Context, language
 	return len(orders), nil
 }
 
+// RefundOrder is the resolver for the refundOrder field.
+func (r *mutationResolver) RefundOrder(ctx context.Context, orderID uuid.UUID, lines []*model.RefundLineInput, amount *string, reason string) (*model.Refund, error) {
+	req := paymentDomain.RefundRequest{Reason: reason}
+	for _, l := range lines {
+		req.Lines = append(req.Lines, paymentDomain.RefundLineRequest{
+			OrderProductID: l.OrderItemID,
+			Quantity:       int64(l.Quantity),
+		})
+	}
+	if amount != nil && strings.TrimSpace(*amount) != "" {
+		parsed, err := decimal.NewFromString(strings.TrimSpace(*amount))
+		if err != nil {
+			return nil, &gqlerror.Error{
+				Message:    "invalid amount",
+				Path:       graphql.GetPath(ctx),
+				Extensions: map[string]any{"code": "USER_ERROR", "field": "amount"},
+			}
+		}
+		req.Amount = &parsed
+	}
+
+	refund, err := r.PaymentService.RefundOrder(ctx, orderID, req)
+	if err != nil {
+		if errors.Is(err, paymentDomain.ErrInvalidRefund) {
+			return nil, &gqlerror.Error{
+				Message:    err.Error(),
+				Path:       graphql.GetPath(ctx),
+				Extensions: map[string]any{"code": "USER_ERROR", "field": "refund"},
+			}
+		}
+		return nil, fmt.Errorf("failed to refund order: %w", err)
+	}
+
+	r.OutboxService.Notify()
+	return toGQLRefund(refund), nil
+}
+
+// RetryOutboxMessage is the resolver for the retryOutboxMessage field.
+func (r *mutationResolver) RetryOutboxMessage(ctx context.Context, id uuid.UUID) (*model.OutboxMessage, error) {
+	msg, err := r.OutboxService.RetryMessage(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("outbox message not found or already delivered")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retry outbox message: %w", err)
+	}
+	return toGQLOutboxMessage(msg), nil
+}
+
+// SuggestedReadyTime is the resolver for the suggestedReadyTime field.
+func (r *orderResolver) SuggestedReadyTime(ctx context.Context, obj *model.Order) (*time.Time, error) {
+	loader := orderApplication.GetSuggestedReadyTimeLoader(ctx)
+	if loader == nil {
+		return nil, fmt.Errorf("no suggested ready time loader found")
+	}
+	t, err := loader.Loader.Load(ctx, obj.ID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load suggested ready time: %w", err)
+	}
+	if len(t) == 0 {
+		return nil, nil
+	}
+	return &t[0], nil
+}
+
 // OrderExtra is the resolver for the orderExtra field.
 func (r *orderResolver) OrderExtra(ctx context.Context, obj *model.Order) (any, error) {
 	return obj.OrderExtra, nil
@@ -1111,7 +685,8 @@ func (r *orderResolver) Payment(ctx context.Context, obj *model.Order) (*model.P
 		return nil, nil
 	}
 
-	// Return the first payment found. (Assuming one order belongs to one payment)
+	// Return the latest attempt; an order gets one payment per retry and
+	// the loader lists them latest first.
 	return payments[0], nil
 }
 
@@ -1145,6 +720,18 @@ func (r *orderResolver) IsManualAddress(ctx context.Context, obj *model.Order) (
 	return false, nil
 }
 
+// TableSession is the resolver for the tableSession field.
+func (r *orderResolver) TableSession(ctx context.Context, obj *model.Order) (*model.TableSession, error) {
+	if obj.TableSessionID == nil {
+		return nil, nil
+	}
+	session, err := r.TableService.GetSession(ctx, *obj.TableSessionID)
+	if err != nil {
+		return nil, fmt.Errorf("get table session: %w", err)
+	}
+	return toGQLTableSession(session), nil
+}
+
 // StatusHistory is the resolver for the statusHistory field.
 func (r *orderResolver) StatusHistory(ctx context.Context, obj *model.Order) ([]*model.OrderStatusHistory, error) {
 	history, err := r.OrderService.GetStatusHistory(ctx, obj.ID)
@@ -1157,12 +744,28 @@ func (r *orderResolver) StatusHistory(ctx context.Context, obj *model.Order) ([]
 		result[i] = &model.OrderStatusHistory{
 			ID:        h.ID,
 			Status:    h.Status,
+			Forced:    h.Forced,
 			ChangedAt: h.ChangedAt,
+
+			ActorType:                  h.ActorType,
+			ActorID:                    h.ActorID,
+			CancellationReason:         h.CancellationReason,
+			PreviousEstimatedReadyTime: h.PreviousEstimatedReadyTime,
+			EstimatedReadyTime:         h.EstimatedReadyTime,
 		}
 	}
 	return result, nil
 }
 
+// Refunds is the resolver for the refunds field.
+func (r *orderResolver) Refunds(ctx context.Context, obj *model.Order) ([]*model.Refund, error) {
+	refunds, err := r.PaymentService.GetRefundsByOrderID(ctx, obj.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load refunds: %w", err)
+	}
+	return Map(refunds, toGQLRefund), nil
+}
+
 // DisplayCustomerName is the resolver for the displayCustomerName field.
 func (r *orderResolver) DisplayCustomerName(ctx context.Context, obj *model.Order) (string, error) {
 	customer, err := r.Customer(ctx, obj)
@@ -1241,6 +844,41 @@ func (r *orderItemResolver) Choice(ctx context.Context, obj *model.OrderItem) (*
 	return ToGQLProductChoice(choice, userLang), nil
 }
 
+// PriceCart is the resolver for the priceCart field.
+func (r *queryResolver) PriceCart(ctx context.Context, input model.CreateOrderInput) (*model.CartQuote, error) {
+	userUUID, err := uuid.Parse(utils.GetUserID(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+	user, err := r.UserService.GetUserByID(ctx, userUUID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve user: %w", err)
+	}
+	isTestOrder := user != nil && auth.IsReviewUser(user.Email, user.FirstName, user.LastName)
+
+	gate, err := r.checkOrderingWindow(ctx, input.PreferredReadyTime, isTestOrder)
+	if err != nil {
+		return nil, err
+	}
+	cart := cartFromInput(input, userUUID, utils.GetLang(ctx), gate)
+	quote, err := r.PricingService.PriceCart(ctx, cart)
+	if err != nil {
+		return nil, err
+	}
+	if gate.issue != nil {
+		quote.Issues = append([]orderDomain.PricingIssue{*gate.issue}, quote.Issues...)
+	} else {
+		issue, err := r.slotCapacityIssue(ctx, gate, cart)
+		if err != nil {
+			return nil, err
+		}
+		if issue != nil {
+			quote.Issues = append([]orderDomain.PricingIssue{*issue}, quote.Issues...)
+		}
+	}
+	return toGQLCartQuote(quote), nil
+}
+
 // Orders is the resolver for the orders field.
 func (r *queryResolver) Orders(ctx context.Context) ([]*model.Order, error) {
 	o, err := r.OrderService.GetPaginatedOrders(ctx, 1, 200, nil)
@@ -1255,6 +893,19 @@ func (r *queryResolver) Orders(ctx context.Context) ([]*model.Order, error) {
 	return orders, nil
 }
 
+// OrdersConnection is the resolver for the ordersConnection field.
+func (r *queryResolver) OrdersConnection(ctx context.Context, first *int, after *string) (*model.OrderConnection, error) {
+	req, err := orderPageRequest(ctx, first, after, 50)
+	if err != nil {
+		return nil, err
+	}
+	page, err := r.OrderService.GetOrdersPage(ctx, nil, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get orders: %w", err)
+	}
+	return toGQLOrderConnection(page, req), nil
+}
+
 // Order is the resolver for the order field.
 func (r *queryResolver) Order(ctx context.Context, id uuid.UUID) (*model.Order, error) {
 	o, _, err := r.OrderService.GetOrderByID(ctx, id)
@@ -1297,6 +948,19 @@ func (r *queryResolver) CustomerOrders(ctx context.Context, userID uuid.UUID, fi
 	}), nil
 }
 
+// CustomerOrdersConnection is the resolver for the customerOrdersConnection field.
+func (r *queryResolver) CustomerOrdersConnection(ctx context.Context, userID uuid.UUID, first *int, after *string) (*model.OrderConnection, error) {
+	req, err := orderPageRequest(ctx, first, after, 20)
+	if err != nil {
+		return nil, err
+	}
+	page, err := r.OrderService.GetOrdersPage(ctx, &userID, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customer orders: %w", err)
+	}
+	return toGQLOrderConnection(page, req), nil
+}
+
 // OrderHistory is the resolver for the orderHistory field.
 func (r *queryResolver) OrderHistory(ctx context.Context, input *model.OrderHistoryInput) (*model.OrderHistoryResponse, error) {
 	filter := orderDomain.OrderHistoryFilter{
@@ -1314,6 +978,8 @@ func (r *queryResolver) OrderHistory(ctx context.Context, input *model.OrderHist
 		filter.StartDate = input.StartDate
 		filter.EndDate = input.EndDate
 		filter.Search = input.Search
+		filter.ChangedBy = input.ChangedByActorType
+		filter.ChangedByID = input.ChangedByActorID
 
 		if input.Status != nil {
 			s := orderDomain.OrderStatus(*input.Status)
@@ -1344,6 +1010,44 @@ func (r *queryResolver) OrderHistory(ctx context.Context, input *model.OrderHist
 	}, nil
 }
 
+// OrderHistoryConnection is the resolver for the orderHistoryConnection field.
+func (r *queryResolver) OrderHistoryConnection(ctx context.Context, filter *model.OrderHistoryFilterInput, first *int, after *string) (*model.OrderHistoryConnection, error) {
+	req, err := orderPageRequest(ctx, first, after, 20)
+	if err != nil {
+		return nil, err
+	}
+
+	var f orderDomain.OrderHistoryFilter
+	if filter != nil {
+		f.StartDate = filter.StartDate
+		f.EndDate = filter.EndDate
+		f.Status = filter.Status
+		f.Search = filter.Search
+		f.ChangedBy = filter.ChangedByActorType
+		f.ChangedByID = filter.ChangedByActorID
+		if filter.OrderType != nil {
+			t := orderDomain.OrderType(*filter.OrderType)
+			f.OrderType = &t
+		}
+	}
+
+	page, summary, err := r.OrderService.GetOrderHistoryPage(ctx, f, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order history: %w", err)
+	}
+	edges := toGQLOrderEdges(page.Orders)
+	return &model.OrderHistoryConnection{
+		Edges:      edges,
+		PageInfo:   toGQLPageInfo(edges, page.HasNextPage, req.After != nil),
+		TotalCount: page.TotalCount,
+		Summary: &model.OrderHistorySummary{
+			TotalOrders:  summary.TotalOrders,
+			TotalRevenue: summary.TotalRevenue,
+			AverageOrder: summary.AverageOrder,
+		},
+	}, nil
+}
+
 // MyOrders is the resolver for the myOrders field.
 func (r *queryResolver) MyOrders(ctx context.Context, first *int, page *int) ([]*model.Order, error) {
 	const (
@@ -1379,6 +1083,26 @@ func (r *queryResolver) MyOrders(ctx context.Context, first *int, page *int) ([]
 	return orders, nil
 }
 
+// MyOrdersConnection is the resolver for the myOrdersConnection field.
+func (r *queryResolver) MyOrdersConnection(ctx context.Context, first *int, after *string) (*model.OrderConnection, error) {
+	req, err := orderPageRequest(ctx, first, after, 5)
+	if err != nil {
+		return nil, err
+	}
+
+	// The @auth directive guarantees a non-empty userID.
+	userUUID, err := uuid.Parse(utils.GetUserID(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	page, err := r.OrderService.GetOrdersPage(ctx, &userUUID, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get orders: %w", err)
+	}
+	return toGQLOrderConnection(page, req), nil
+}
+
 // MyOrder is the resolver for the myOrder field.
 func (r *queryResolver) MyOrder(ctx context.Context, id uuid.UUID) (*model.Order, error) {
 	userID := utils.GetUserID(ctx)
@@ -1408,6 +1132,44 @@ func (r *queryResolver) MyOrder(ctx context.Context, id uuid.UUID) (*model.Order
 	return order, nil
 }
 
+// OutboxMessages is the resolver for the outboxMessages field.
+func (r *queryResolver) OutboxMessages(ctx context.Context, status *model.OutboxStatusEnum, limit *int) ([]*model.OutboxMessage, error) {
+	const (
+		defaultLimit = 100
+		maxLimit     = 500
+	)
+	l := defaultLimit
+	if limit != nil && *limit > 0 {
+		l = min(*limit, maxLimit)
+	}
+	var st *orderDomain.OutboxStatus
+	if status != nil {
+		v := orderDomain.OutboxStatus(*status)
+		st = &v
+	}
+	msgs, err := r.OutboxService.ListMessages(ctx, st, l)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list outbox messages: %w", err)
+	}
+	return Map(msgs, toGQLOutboxMessage), nil
+}
+
+// DailyTips is the resolver for the dailyTips field.
+func (r *queryResolver) DailyTips(ctx context.Context, startDate time.Time, endDate time.Time) ([]*model.DailyTips, error) {
+	if !endDate.After(startDate) {
+		return nil, &gqlerror.Error{
+			Message:    "endDate must be after startDate",
+			Path:       graphql.GetPath(ctx),
+			Extensions: map[string]any{"code": "USER_ERROR", "field": "endDate"},
+		}
+	}
+	rows, err := r.OrderService.GetDailyTips(ctx, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily tips: %w", err)
+	}
+	return Map(rows, toGQLDailyTips), nil
+}
+
 // OrderCreated is the resolver for the orderCreated field.
 func (r *subscriptionResolver) OrderCreated(ctx context.Context) (<-chan *model.Order, error) {
 	ch := make(chan *model.Order, 1)
@@ -7,11 +7,21 @@ package resolver
 
 import (
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/google/uuid"
+	"github.com/vektah/gqlparser/v2/gqlerror"
 	"go.uber.org/zap"
 
+	"tsb-service/internal/api/graphql/model"
 	notificationApplication "tsb-service/internal/modules/notification/application"
 	orderDomain "tsb-service/internal/modules/order/domain"
 )
@@ -67,3 +77,327 @@ func (r *mutationResolver) repushActivitiesLanguage(orders []*orderDomain.Order,
 		}
 	}
 }
+
+// orderingGate is the outcome of the opening-hours checks shared by
+// createOrder and priceCart.
+type orderingGate struct {
+	allowLunchOnly bool
+	prepBuffer     time.Duration
+	// heldForSchedule is set when the preferred ready time is further away
+	// than the scheduled-order lead time.
+	heldForSchedule bool
+	// slot is the kitchen capacity slot the order lands in; slotLimits are
+	// the capacity rules that apply to it (none when the gate was skipped).
+	slot       time.Time
+	slotLimits []orderDomain.SlotLimit
+	issue      *orderDomain.PricingIssue
+}
+
+// checkOrderingWindow validates ordering availability and the preferred ready
+// time. allowLunchOnly defaults to true so dev mode does not block testing of
+// lunch-only items; production paths populate it from the resolved schedule.
+// skip bypasses the gate for store-review accounts. TEMPORARY (revert after
+// launch).
+func (r *Resolver) checkOrderingWindow(ctx context.Context, preferred *time.Time, skip bool) (orderingGate, error) {
+	gate := orderingGate{allowLunchOnly: true}
+	if skip {
+		return gate, nil
+	}
+	config, overrides, err := r.RestaurantService.GetConfigWithOverrides(ctx)
+	if err != nil {
+		return gate, fmt.Errorf("failed to load restaurant config: %w", err)
+	}
+	now := time.Now()
+	gate.heldForSchedule = preferred != nil && preferred.After(now.Add(config.ScheduledOrderLead()))
+	if preferred != nil {
+		gate.slot = orderDomain.SlotStart(*preferred)
+	} else {
+		gate.slot = orderDomain.SlotStart(now.Add(preparationBuffer(config)))
+	}
+	gate.slotLimits = slotLimits(config, gate.slot)
+	if r.RestaurantService.IsDevMode() {
+		return gate, nil
+	}
+	if !config.OrderingEnabled {
+		gate.issue = &orderDomain.PricingIssue{Message: "ordering is currently unavailable"}
+		return gate, nil
+	}
+
+	gate.prepBuffer = preparationBuffer(config)
+	isOpenNow := config.IsOrderingCurrentlyOpen(now, overrides)
+	if err := validatePreferredReadyTime(preferred, config, overrides, now, isOpenNow); err != nil {
+		gate.issue = &orderDomain.PricingIssue{Field: "preferredReadyTime", Message: err.Error()}
+		return gate, nil
+	}
+
+	slotTime := now
+	if preferred != nil {
+		slotTime = *preferred
+	}
+	gate.allowLunchOnly = config.IsLunchOnlyAllowed(slotTime, overrides)
+	return gate, nil
+}
+
+// cartFromInput maps the checkout input to the cart the pricing service prices.
+func cartFromInput(input model.CreateOrderInput, userID uuid.UUID, lang string, gate orderingGate) orderDomain.Cart {
+	orderType := orderDomain.OrderTypePickUp
+	switch input.OrderType {
+	case model.OrderTypeEnumDelivery:
+		orderType = orderDomain.OrderTypeDelivery
+	case model.OrderTypeEnumDineIn:
+		orderType = orderDomain.OrderTypeDineIn
+	}
+	items := make([]orderDomain.CartItem, len(input.Items))
+	for i, it := range input.Items {
+		selections := make([]orderDomain.CartSelection, len(it.Selections))
+		for j, sel := range it.Selections {
+			selections[j] = orderDomain.CartSelection{
+				GroupID:  sel.GroupID,
+				ChoiceID: sel.ChoiceID,
+				Quantity: sel.Quantity,
+			}
+		}
+		items[i] = orderDomain.CartItem{
+			ProductID:  it.ProductID,
+			Quantity:   it.Quantity,
+			ChoiceID:   it.ChoiceID,
+			Selections: selections,
+		}
+	}
+	return orderDomain.Cart{
+		UserID:             userID,
+		OrderType:          orderType,
+		IsOnlinePayment:    input.IsOnlinePayment,
+		AddressPlaceID:     input.AddressPlaceID,
+		PreferredReadyTime: input.PreferredReadyTime,
+		CouponCode:         input.CouponCode,
+		TipAmount:          input.TipAmount,
+		TipPercent:         input.TipPercent,
+		Language:           lang,
+		Items:              items,
+		AllowLunchOnly:     gate.allowLunchOnly,
+		PreparationBuffer:  gate.prepBuffer,
+	}
+}
+
+// pricingIssueError surfaces a cart validation failure as a USER_ERROR,
+// naming the offending input field when there is one.
+func pricingIssueError(ctx context.Context, issue orderDomain.PricingIssue) error {
+	ext := map[string]any{"code": "USER_ERROR"}
+	if issue.Field != "" {
+		ext["field"] = issue.Field
+	}
+	return &gqlerror.Error{
+		Message:    issue.Message,
+		Path:       graphql.GetPath(ctx),
+		Extensions: ext,
+	}
+}
+
+// errSlotFullIssue is the checkout issue raised when the kitchen has no room
+// left in the chosen slot.
+var errSlotFullIssue = orderDomain.PricingIssue{
+	Field:   "preferredReadyTime",
+	Message: "this time slot is fully booked, please pick another time",
+}
+
+// reserveSlot holds kitchen capacity for the cart in the gate's slot. It
+// returns a nil reservation when no capacity rule applies.
+func (r *Resolver) reserveSlot(ctx context.Context, gate orderingGate, cart orderDomain.Cart) (*orderDomain.SlotReservation, error) {
+	if len(gate.slotLimits) == 0 {
+		return nil, nil
+	}
+	res := &orderDomain.SlotReservation{
+		SlotStart: gate.slot,
+		OrderType: cart.OrderType,
+		ItemCount: cart.ItemCount(),
+	}
+	if err := r.OrderService.ReserveSlot(ctx, res, gate.slotLimits); err != nil {
+		if errors.Is(err, orderDomain.ErrSlotFull) {
+			return nil, pricingIssueError(ctx, errSlotFullIssue)
+		}
+		return nil, fmt.Errorf("failed to reserve slot: %w", err)
+	}
+	return res, nil
+}
+
+// slotReservationID is the ID of res, or nil without a reservation.
+func slotReservationID(res *orderDomain.SlotReservation) *uuid.UUID {
+	if res == nil {
+		return nil
+	}
+	return &res.ID
+}
+
+// cancelSlotReservation gives back capacity held for an order that was not
+// created.
+func (r *Resolver) cancelSlotReservation(ctx context.Context, res *orderDomain.SlotReservation) {
+	if res == nil {
+		return
+	}
+	if err := r.OrderService.CancelSlotReservation(ctx, res.ID); err != nil {
+		zap.L().Error("failed to release slot reservation",
+			zap.String("reservation_id", res.ID.String()), zap.Error(err))
+	}
+}
+
+// slotCapacityIssue reports, without reserving, whether the cart would still
+// fit in the gate's slot. priceCart uses it so the app can warn before
+// checkout; createOrder's reservation remains the authoritative check.
+func (r *Resolver) slotCapacityIssue(ctx context.Context, gate orderingGate, cart orderDomain.Cart) (*orderDomain.PricingIssue, error) {
+	if len(gate.slotLimits) == 0 {
+		return nil, nil
+	}
+	usage, err := r.OrderService.GetSlotUsage(ctx, gate.slot, gate.slot.Add(orderDomain.SlotStep))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get slot usage: %w", err)
+	}
+	if orderDomain.SlotHasRoom(gate.slotLimits, usage, cart.OrderType, cart.ItemCount()) {
+		return nil, nil
+	}
+	issue := errSlotFullIssue
+	return &issue, nil
+}
+
+// idempotencyKeyFromInput returns the trimmed idempotency key of the input,
+// or "" when the client sent none.
+func idempotencyKeyFromInput(input model.CreateOrderInput) string {
+	if input.IdempotencyKey == nil {
+		return ""
+	}
+	return strings.TrimSpace(*input.IdempotencyKey)
+}
+
+// createOrderFingerprint hashes everything in the input but the key itself,
+// so a retry matches the request that first used the key and any other
+// payload does not.
+func createOrderFingerprint(input model.CreateOrderInput) string {
+	input.IdempotencyKey = nil
+	payload, _ := json.Marshal(input)
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// idempotencyError surfaces a rejected idempotency key as a USER_ERROR on the
+// idempotencyKey field.
+func idempotencyError(ctx context.Context, err error) error {
+	if errors.Is(err, orderDomain.ErrIdempotencyKeyReused) ||
+		errors.Is(err, orderDomain.ErrIdempotencyKeyInFlight) ||
+		errors.Is(err, orderDomain.ErrIdempotencyKeyInvalid) {
+		return pricingIssueError(ctx, orderDomain.PricingIssue{Field: "idempotencyKey", Message: err.Error()})
+	}
+	return fmt.Errorf("failed to check idempotency key: %w", err)
+}
+
+// replayCreatedOrder returns the order an earlier createOrder with the same
+// idempotency key created. Its payment field resolves to the same Mollie
+// payment, so the client gets the original checkout URL. The key is recorded
+// with the order, before its payment exists: until then, or if the payment
+// failed and the order was deleted, the earlier request is still in flight.
+func (r *Resolver) replayCreatedOrder(ctx context.Context, orderID uuid.UUID) (*model.Order, error) {
+	order, _, err := r.OrderService.GetOrderByID(ctx, orderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, idempotencyError(ctx, orderDomain.ErrIdempotencyKeyInFlight)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load order: %w", err)
+	}
+	if order.IsOnlinePayment {
+		_, err := r.PaymentService.GetPaymentByOrderID(ctx, orderID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, idempotencyError(ctx, orderDomain.ErrIdempotencyKeyInFlight)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load payment: %w", err)
+		}
+	}
+	return ToGQLOrder(order), nil
+}
+
+// releaseIdempotencyKey frees the key of a createOrder that failed. It runs
+// detached from the request so a client hanging up still frees the key.
+func (r *Resolver) releaseIdempotencyKey(ctx context.Context, userID uuid.UUID, key string) {
+	if err := r.IdempotencyService.Release(context.WithoutCancel(ctx), userID, key); err != nil {
+		zap.L().Error("failed to release idempotency key",
+			zap.String("user_id", userID.String()), zap.Error(err))
+	}
+}
+
+// tableError surfaces a refused table operation as a USER_ERROR on field.
+func tableError(ctx context.Context, field string, err error) error {
+	if errors.Is(err, orderDomain.ErrInvalidTableToken) ||
+		errors.Is(err, orderDomain.ErrTableSessionClosed) ||
+		errors.Is(err, orderDomain.ErrTableRoundsInProgress) {
+		return pricingIssueError(ctx, orderDomain.PricingIssue{Field: field, Message: err.Error()})
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("table session not found")
+	}
+	return fmt.Errorf("table session: %w", err)
+}
+
+// dineInSession returns the table session a DINE_IN order is a round of. The
+// guest is seated, so the round cannot be scheduled for later.
+func (r *Resolver) dineInSession(ctx context.Context, input model.CreateOrderInput) (*orderDomain.TableSession, error) {
+	if input.TableToken == nil || strings.TrimSpace(*input.TableToken) == "" {
+		return nil, pricingIssueError(ctx, orderDomain.PricingIssue{
+			Field:   "tableToken",
+			Message: "scan the QR code on your table to order",
+		})
+	}
+	if input.PreferredReadyTime != nil {
+		return nil, pricingIssueError(ctx, orderDomain.PricingIssue{
+			Field:   "preferredReadyTime",
+			Message: "table orders are prepared right away",
+		})
+	}
+	session, err := r.TableService.JoinTable(ctx, *input.TableToken)
+	if err != nil {
+		return nil, tableError(ctx, "tableToken", err)
+	}
+	return session, nil
+}
+
+// confirmationEta suggests a ready time for an order staff are confirming and
+// returns it with the ready time to confirm: readyTime, or the suggestion
+// when staff gave none and restaurantConfig.autoApplyEta is on. A failed
+// suggestion only leaves readyTime as is.
+func (r *Resolver) confirmationEta(ctx context.Context, orderID uuid.UUID, readyTime *time.Time) (*orderDomain.EtaEstimate, *time.Time) {
+	eta, err := r.EtaService.Suggest(ctx, orderID)
+	if err != nil {
+		zap.L().Warn("failed to suggest ready time", zap.String("order_id", orderID.String()), zap.Error(err))
+		return nil, readyTime
+	}
+	if readyTime == nil {
+		config, err := r.RestaurantService.GetConfig(ctx)
+		if err != nil {
+			zap.L().Warn("failed to get restaurant config", zap.Error(err))
+		} else if config.AutoApplyEta {
+			readyTime = &eta.SuggestedReadyTime
+			eta.Applied = true
+		}
+	}
+	eta.EstimatedReadyTime = readyTime
+	return eta, readyTime
+}
+
+// orderPageRequest reads the first and after arguments of an order
+// connection. An unknown cursor is a USER_ERROR on after.
+func orderPageRequest(ctx context.Context, first *int, after *string, defaultFirst int) (orderDomain.OrderPageRequest, error) {
+	req := orderDomain.OrderPageRequest{First: defaultFirst}
+	if first != nil && *first > 0 {
+		req.First = min(*first, orderDomain.MaxPageSize)
+	}
+	if after != nil && *after != "" {
+		cursor, err := orderDomain.DecodeOrderCursor(*after)
+		if err != nil {
+			return req, &gqlerror.Error{
+				Message:    err.Error(),
+				Path:       graphql.GetPath(ctx),
+				Extensions: map[string]any{"code": "USER_ERROR", "field": "after"},
+			}
+		}
+		req.After = &cursor
+	}
+	return req, nil
+}
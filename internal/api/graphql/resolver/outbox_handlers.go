@@ -0,0 +1,576 @@
+package resolver
+
+// Outbox handlers deliver the side effects that order changes write to the
+// outbox (see orderDomain.CreatedOrderEffects and StatusChangeEffects). They
+// run on the outbox worker, possibly more than once, and return an error only
+// for failures worth retrying: per-device push failures are logged instead,
+// since a retry would re-alert the devices that already got the push.
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	addressDomain "tsb-service/internal/modules/address/domain"
+	notificationApplication "tsb-service/internal/modules/notification/application"
+	orderApplication "tsb-service/internal/modules/order/application"
+	orderDomain "tsb-service/internal/modules/order/domain"
+	paymentDomain "tsb-service/internal/modules/payment/domain"
+	productDomain "tsb-service/internal/modules/product/domain"
+	userDomain "tsb-service/internal/modules/user/domain"
+	"tsb-service/pkg/apns"
+	es "tsb-service/pkg/email/scaleway"
+	"tsb-service/pkg/fcm"
+	"tsb-service/pkg/utils"
+)
+
+// RegisterOutboxHandlers routes every outbox message kind to its handler.
+func (r *Resolver) RegisterOutboxHandlers(outbox orderApplication.OutboxService) {
+	outbox.Handle(orderDomain.OutboxPubsubOrderCreated, r.outboxPublishOrderCreated)
+	outbox.Handle(orderDomain.OutboxPubsubOrderUpdated, r.outboxPublishOrderUpdated)
+	outbox.Handle(orderDomain.OutboxPushNewOrder, r.outboxPushNewOrder)
+	outbox.Handle(orderDomain.OutboxPushEscalation, r.outboxPushEscalation)
+	outbox.Handle(orderDomain.OutboxPushCustomerCancel, r.outboxPushCustomerCancel)
+	outbox.Handle(orderDomain.OutboxPushStatusAlert, r.outboxPushStatusAlert)
+	outbox.Handle(orderDomain.OutboxPushLiveActivity, r.outboxPushLiveActivity)
+	outbox.Handle(orderDomain.OutboxPushLiveUpdate, r.outboxPushLiveUpdate)
+	outbox.Handle(orderDomain.OutboxPushReadyTime, r.outboxPushReadyTime)
+	outbox.Handle(orderDomain.OutboxEmailOrderPending, r.outboxEmailOrderPending)
+	outbox.Handle(orderDomain.OutboxEmailOrderConfirmed, r.outboxEmailOrderConfirmed)
+	outbox.Handle(orderDomain.OutboxEmailOrderReady, r.outboxEmailOrderReady)
+	outbox.Handle(orderDomain.OutboxEmailReadyTime, r.outboxEmailReadyTime)
+	outbox.Handle(orderDomain.OutboxEmailOrderCanceled, r.outboxEmailOrderCanceled)
+	outbox.Handle(orderDomain.OutboxEmailRefundIssued, r.outboxEmailRefundIssued)
+	outbox.Handle(orderDomain.OutboxEmailDoublePayment, r.outboxEmailDoublePayment)
+	outbox.Handle(orderDomain.OutboxRefundFullPayment, r.outboxRefundFullPayment)
+	outbox.Handle(orderDomain.OutboxRefundSuperseded, r.outboxRefundSupersededPayment)
+	outbox.Handle(orderDomain.OutboxWebhookRefund, r.outboxWebhookRefund)
+	outbox.Handle(orderDomain.OutboxWebhookPaymentPaid, r.outboxWebhookPaymentPaid)
+}
+
+// outboxOrder loads the message's order as of the change that raised it: the
+// status comes from the message so a late delivery does not describe a later
+// change. Subscription events use the live order instead.
+func (r *Resolver) outboxOrder(ctx context.Context, m *orderDomain.OutboxMessage) (*orderDomain.Order, *[]orderDomain.OrderProductRaw, error) {
+	o, raws, err := r.OrderService.GetOrderByID(ctx, m.OrderID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get order: %w", err)
+	}
+	ev, err := m.Event()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid outbox payload: %w", err)
+	}
+	if ev.Status != "" {
+		o.OrderStatus = ev.Status
+	}
+	return o, raws, nil
+}
+
+// outboxRecipient returns the order's customer, or nil when they opted out of
+// order update emails.
+func (r *Resolver) outboxRecipient(ctx context.Context, o *orderDomain.Order) (*userDomain.User, error) {
+	user, err := r.UserService.GetUserByID(ctx, o.UserID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve user: %w", err)
+	}
+	if user == nil || !user.NotifyOrderUpdates {
+		return nil, nil
+	}
+	return user, nil
+}
+
+func (r *Resolver) outboxPublishOrderCreated(ctx context.Context, m *orderDomain.OutboxMessage) error {
+	o, _, err := r.OrderService.GetOrderByID(ctx, m.OrderID)
+	if err != nil {
+		return fmt.Errorf("failed to get order: %w", err)
+	}
+	r.Broker.Publish("orderCreated", ToGQLOrder(o))
+	return r.emitOrderCreated(ctx, o)
+}
+
+func (r *Resolver) outboxPublishOrderUpdated(ctx context.Context, m *orderDomain.OutboxMessage) error {
+	o, _, err := r.OrderService.GetOrderByID(ctx, m.OrderID)
+	if err != nil {
+		return fmt.Errorf("failed to get order: %w", err)
+	}
+	gql := ToGQLOrder(o)
+	r.Broker.Publish("orderUpdated", gql)
+	r.Broker.Publish(fmt.Sprintf("orderUpdated:%s", o.ID), gql)
+
+	// Subscribers hear about the change the message was raised for, keyed by
+	// the message so a retry does not announce it twice.
+	ev, err := m.Event()
+	if err != nil {
+		return fmt.Errorf("invalid outbox payload: %w", err)
+	}
+	if ev.Status == "" || ev.Status == ev.PreviousStatus {
+		return nil
+	}
+	changed := *o
+	changed.OrderStatus = ev.Status
+	return r.emitOrderStatusChanged(ctx, &changed, ev.PreviousStatus, m.ID.String())
+}
+
+func (r *Resolver) outboxPushNewOrder(ctx context.Context, m *orderDomain.OutboxMessage) error {
+	o, _, err := r.OrderService.GetOrderByID(ctx, m.OrderID)
+	if err != nil {
+		return fmt.Errorf("failed to get order: %w", err)
+	}
+	r.sendNewOrderPush(ctx, o, false)
+	return nil
+}
+
+// outboxPushEscalation reminds staff of an order they left unconfirmed,
+// unless they confirmed or cancelled it since.
+func (r *Resolver) outboxPushEscalation(ctx context.Context, m *orderDomain.OutboxMessage) error {
+	o, _, err := r.OrderService.GetOrderByID(ctx, m.OrderID)
+	if err != nil {
+		return fmt.Errorf("failed to get order: %w", err)
+	}
+	if o.OrderStatus != orderDomain.OrderStatusPending {
+		return nil
+	}
+	r.sendNewOrderPush(ctx, o, true)
+	return nil
+}
+
+func (r *Resolver) outboxPushCustomerCancel(ctx context.Context, m *orderDomain.OutboxMessage) error {
+	o, _, err := r.OrderService.GetOrderByID(ctx, m.OrderID)
+	if err != nil {
+		return fmt.Errorf("failed to get order: %w", err)
+	}
+	r.sendCustomerCancelledPush(ctx, o)
+	return nil
+}
+
+// outboxPushAlert sends a visible alert to the customer's phones.
+func (r *Resolver) outboxPushAlert(ctx context.Context, o *orderDomain.Order, title, body string, data map[string]string) error {
+	if r.APNsClient == nil && r.FCMClient == nil {
+		return nil
+	}
+	deviceTokens, err := r.NotificationService.GetDeviceTokens(ctx, o.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get device tokens: %w", err)
+	}
+	for _, dt := range deviceTokens {
+		if dt.Platform == "ios" && r.APNsClient != nil {
+			if pushErr := r.APNsClient.SendAlert(dt.DeviceToken, title, body, data); pushErr != nil {
+				if errors.Is(pushErr, apns.ErrTokenInvalid) {
+					_ = r.NotificationService.UnregisterDeviceToken(ctx, o.UserID, dt.DeviceToken)
+				} else {
+					zap.L().Error("failed to send alert push",
+						zap.String("order_id", o.ID.String()),
+						zap.Error(pushErr),
+					)
+				}
+			}
+		} else if dt.Platform == "android" && r.FCMClient != nil {
+			if pushErr := r.FCMClient.SendAlert(dt.DeviceToken, title, body, data); pushErr != nil {
+				if errors.Is(pushErr, fcm.ErrTokenInvalid) {
+					_ = r.NotificationService.UnregisterDeviceToken(ctx, o.UserID, dt.DeviceToken)
+				} else {
+					zap.L().Error("failed to send FCM push",
+						zap.String("order_id", o.ID.String()),
+						zap.Error(pushErr),
+					)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Resolver) outboxPushStatusAlert(ctx context.Context, m *orderDomain.OutboxMessage) error {
+	o, _, err := r.outboxOrder(ctx, m)
+	if err != nil {
+		return err
+	}
+	msg := notificationApplication.GetOrderStatusNotification(o.OrderStatus, o.Language, string(o.OrderType), o.CancellationReason)
+	if msg.Body == "" {
+		return nil
+	}
+	return r.outboxPushAlert(ctx, o, msg.Title, msg.Body, map[string]string{
+		"orderId": o.ID.String(),
+		"status":  string(o.OrderStatus),
+	})
+}
+
+func (r *Resolver) outboxPushReadyTime(ctx context.Context, m *orderDomain.OutboxMessage) error {
+	o, _, err := r.outboxOrder(ctx, m)
+	if err != nil {
+		return err
+	}
+	msg := notificationApplication.GetReadyTimeUpdatedNotification(o.Language, o.EstimatedReadyTime)
+	if msg.Body == "" {
+		return nil
+	}
+	data := map[string]string{
+		"orderId": o.ID.String(),
+		"status":  string(o.OrderStatus),
+	}
+	if o.EstimatedReadyTime != nil {
+		data["estimatedReadyTime"] = o.EstimatedReadyTime.Format(time.RFC3339)
+	}
+	return r.outboxPushAlert(ctx, o, msg.Title, msg.Body, data)
+}
+
+// outboxPushLiveActivity updates the iOS Live Activity (Lock Screen / Dynamic
+// Island) and ends it on terminal statuses, even when the alert is suppressed.
+func (r *Resolver) outboxPushLiveActivity(ctx context.Context, m *orderDomain.OutboxMessage) error {
+	if r.APNsClient == nil {
+		return nil
+	}
+	o, _, err := r.outboxOrder(ctx, m)
+	if err != nil {
+		return err
+	}
+	laTokens, err := r.NotificationService.GetLiveActivityTokens(ctx, o.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get live activity tokens: %w", err)
+	}
+	if len(laTokens) == 0 {
+		return nil
+	}
+
+	terminal := orderDomain.IsTerminalStatus(o.OrderStatus)
+	event := "update"
+	if terminal {
+		event = "end"
+	}
+	contentState := notificationApplication.GetLiveActivityContentState(
+		o.OrderStatus, o.Language, string(o.OrderType), o.CancellationReason,
+	)
+	for _, lt := range laTokens {
+		if pushErr := r.APNsClient.SendLiveActivity(lt.PushToken, contentState, event); pushErr != nil {
+			zap.L().Warn("failed to send live activity push",
+				zap.String("order_id", o.ID.String()),
+				zap.Error(pushErr),
+			)
+		}
+	}
+
+	if terminal {
+		if clearErr := r.NotificationService.ClearLiveActivityTokens(ctx, o.ID); clearErr != nil {
+			zap.L().Warn("failed to clear live activity tokens",
+				zap.String("order_id", o.ID.String()),
+				zap.Error(clearErr),
+			)
+		}
+	}
+	return nil
+}
+
+// outboxPushLiveUpdate drives the Android Live Update (promoted ongoing
+// notification) through an FCM data message.
+func (r *Resolver) outboxPushLiveUpdate(ctx context.Context, m *orderDomain.OutboxMessage) error {
+	if r.FCMClient == nil {
+		return nil
+	}
+	o, _, err := r.outboxOrder(ctx, m)
+	if err != nil {
+		return err
+	}
+	deviceTokens, err := r.NotificationService.GetDeviceTokens(ctx, o.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get device tokens: %w", err)
+	}
+
+	deepLink := fmt.Sprintf("tsbmobile://order-completed/%s", o.ID.String())
+	data := notificationApplication.GetLiveUpdateData(
+		o.ID.String(), o.OrderStatus, o.Language, string(o.OrderType), deepLink, o.CancellationReason,
+	)
+	for _, dt := range deviceTokens {
+		if dt.Platform != "android" {
+			continue
+		}
+		if pushErr := r.FCMClient.SendDataMessage(dt.DeviceToken, data); pushErr != nil {
+			if errors.Is(pushErr, fcm.ErrTokenInvalid) {
+				_ = r.NotificationService.UnregisterDeviceToken(ctx, o.UserID, dt.DeviceToken)
+			} else {
+				zap.L().Warn("failed to send live update data message",
+					zap.String("order_id", o.ID.String()),
+					zap.Error(pushErr),
+				)
+			}
+		}
+	}
+	return nil
+}
+
+// orderEmailItems enriches the order's lines with their product details for
+// the order emails.
+func (r *Resolver) orderEmailItems(ctx context.Context, raws []orderDomain.OrderProductRaw) ([]orderDomain.OrderProduct, error) {
+	ids := make([]string, len(raws))
+	for i, ir := range raws {
+		ids[i] = ir.ProductID.String()
+	}
+	details, err := r.ProductService.GetProductsByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve products: %w", err)
+	}
+	prodMap := make(map[uuid.UUID]productDomain.ProductOrderDetails, len(details))
+	for _, pd := range details {
+		prodMap[pd.ID] = *pd
+	}
+
+	items := make([]orderDomain.OrderProduct, len(raws))
+	for i, ir := range raws {
+		pd, ok := prodMap[ir.ProductID]
+		if !ok {
+			return nil, fmt.Errorf("missing product details for %s", ir.ProductID)
+		}
+		items[i] = orderDomain.OrderProduct{
+			Product: orderDomain.Product{
+				ID:           pd.ID,
+				Code:         pd.Code,
+				CategoryName: pd.CategoryName,
+				Name:         pd.Name,
+				VatCategory:  string(pd.VatCategory),
+			},
+			Quantity:   ir.Quantity,
+			UnitPrice:  ir.UnitPrice,
+			TotalPrice: ir.TotalPrice,
+		}
+	}
+	return items, nil
+}
+
+func (r *Resolver) outboxEmailOrderPending(ctx context.Context, m *orderDomain.OutboxMessage) error {
+	o, raws, err := r.outboxOrder(ctx, m)
+	if err != nil {
+		return err
+	}
+	user, err := r.outboxRecipient(ctx, o)
+	if err != nil || user == nil {
+		return err
+	}
+	var lines []orderDomain.OrderProductRaw
+	if raws != nil {
+		lines = *raws
+	}
+	items, err := r.orderEmailItems(ctx, lines)
+	if err != nil {
+		return err
+	}
+	if err := es.SendOrderPendingEmail(*user, o.Language, *o, items); err != nil {
+		return fmt.Errorf("failed to send order pending email: %w", err)
+	}
+	return nil
+}
+
+func (r *Resolver) outboxEmailOrderConfirmed(ctx context.Context, m *orderDomain.OutboxMessage) error {
+	o, raws, err := r.outboxOrder(ctx, m)
+	if err != nil {
+		return err
+	}
+	user, err := r.outboxRecipient(ctx, o)
+	if err != nil || user == nil {
+		return err
+	}
+	var lines []orderDomain.OrderProductRaw
+	if raws != nil {
+		lines = *raws
+	}
+	items, err := r.orderEmailItems(ctx, lines)
+	if err != nil {
+		return err
+	}
+	// Use denormalized address fields from the order; nil for very old
+	// orders, which the email renders without an address.
+	var address *addressDomain.Address
+	if o.StreetName != nil {
+		address = addressFromOrder(o)
+	}
+	if err := es.SendOrderConfirmedEmail(*user, o.Language, *o, items, address); err != nil {
+		return fmt.Errorf("failed to send order confirmed email: %w", err)
+	}
+	return nil
+}
+
+func (r *Resolver) outboxEmailOrderReady(ctx context.Context, m *orderDomain.OutboxMessage) error {
+	o, _, err := r.outboxOrder(ctx, m)
+	if err != nil {
+		return err
+	}
+	user, err := r.outboxRecipient(ctx, o)
+	if err != nil || user == nil {
+		return err
+	}
+	if err := es.SendOrderReadyEmail(*user, o.Language, *o); err != nil {
+		return fmt.Errorf("failed to send order ready email: %w", err)
+	}
+	return nil
+}
+
+func (r *Resolver) outboxEmailReadyTime(ctx context.Context, m *orderDomain.OutboxMessage) error {
+	o, _, err := r.outboxOrder(ctx, m)
+	if err != nil {
+		return err
+	}
+	user, err := r.outboxRecipient(ctx, o)
+	if err != nil || user == nil {
+		return err
+	}
+	if err := es.SendReadyTimeUpdatedEmail(*user, o.Language, *o); err != nil {
+		return fmt.Errorf("failed to send ready time updated email: %w", err)
+	}
+	return nil
+}
+
+func (r *Resolver) outboxEmailOrderCanceled(ctx context.Context, m *orderDomain.OutboxMessage) error {
+	o, _, err := r.outboxOrder(ctx, m)
+	if err != nil {
+		return err
+	}
+	user, err := r.outboxRecipient(ctx, o)
+	if err != nil || user == nil {
+		return err
+	}
+	if err := es.SendOrderCanceledEmail(*user, o.Language, o.ID.String(), o.CancellationReason); err != nil {
+		return fmt.Errorf("failed to send order canceled email: %w", err)
+	}
+	return nil
+}
+
+func (r *Resolver) outboxEmailRefundIssued(ctx context.Context, m *orderDomain.OutboxMessage) error {
+	ev, err := m.Event()
+	if err != nil {
+		return fmt.Errorf("invalid outbox payload: %w", err)
+	}
+	if ev.RefundAmount == nil {
+		return fmt.Errorf("outbox message %s names no refund amount", m.ID)
+	}
+	o, _, err := r.outboxOrder(ctx, m)
+	if err != nil {
+		return err
+	}
+	user, err := r.outboxRecipient(ctx, o)
+	if err != nil || user == nil {
+		return err
+	}
+	refundAmount := utils.FormatDecimal(*ev.RefundAmount)
+	if err := es.SendRefundIssuedEmail(*user, o.Language, o.ID.String(), refundAmount); err != nil {
+		return fmt.Errorf("failed to send refund issued email: %w", err)
+	}
+	return nil
+}
+
+func (r *Resolver) outboxEmailDoublePayment(ctx context.Context, m *orderDomain.OutboxMessage) error {
+	ev, err := m.Event()
+	if err != nil {
+		return fmt.Errorf("invalid outbox payload: %w", err)
+	}
+	if ev.RefundAmount == nil {
+		return fmt.Errorf("outbox message %s names no refund amount", m.ID)
+	}
+	o, _, err := r.outboxOrder(ctx, m)
+	if err != nil {
+		return err
+	}
+	user, err := r.outboxRecipient(ctx, o)
+	if err != nil || user == nil {
+		return err
+	}
+	refundAmount := utils.FormatDecimal(*ev.RefundAmount)
+	if err := es.SendDuplicatePaymentRefundedEmail(*user, o.Language, o.ID.String(), refundAmount); err != nil {
+		return fmt.Errorf("failed to send duplicate payment refunded email: %w", err)
+	}
+	return nil
+}
+
+// outboxRefundFullPayment refunds what is left of a cancelled order's paid
+// Mollie payment; the refund enqueues its own email and webhook. Cash orders
+// have no payment, and once nothing is left to refund (an earlier attempt went
+// through, or staff already refunded everything) it is a no-op — that keeps a
+// retry from refunding twice. A message naming the payment comes from it
+// being paid after the cancellation, while its sync may not have stored the
+// paid status yet; the refund waits for the sync on the payment lock.
+func (r *Resolver) outboxRefundFullPayment(ctx context.Context, m *orderDomain.OutboxMessage) error {
+	ev, err := m.Event()
+	if err != nil {
+		return fmt.Errorf("invalid outbox payload: %w", err)
+	}
+	if ev.PaymentID != "" {
+		if _, err := r.PaymentService.CreateFullRefund(ctx, ev.PaymentID, "Order cancelled"); err != nil {
+			return fmt.Errorf("failed to initiate refund: %w", err)
+		}
+		return nil
+	}
+
+	payment, err := r.PaymentService.GetPaymentByOrderID(ctx, m.OrderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if payment == nil || payment.Status != paymentDomain.PaymentStatusPaid {
+		return nil
+	}
+	if _, err := r.PaymentService.CreateFullRefund(ctx, payment.MolliePaymentID, "Order cancelled"); err != nil {
+		return fmt.Errorf("failed to initiate refund: %w", err)
+	}
+	return nil
+}
+
+// outboxRefundSupersededPayment refunds the payment attempt the message names.
+func (r *Resolver) outboxRefundSupersededPayment(ctx context.Context, m *orderDomain.OutboxMessage) error {
+	ev, err := m.Event()
+	if err != nil {
+		return fmt.Errorf("invalid outbox payload: %w", err)
+	}
+	if ev.PaymentID == "" {
+		return fmt.Errorf("outbox message %s names no payment", m.ID)
+	}
+	if _, err := r.PaymentService.RefundSupersededPayment(ctx, ev.PaymentID); err != nil {
+		return fmt.Errorf("failed to refund superseded payment: %w", err)
+	}
+	return nil
+}
+
+// outboxWebhookRefund emits refund.created for the refund the message names.
+func (r *Resolver) outboxWebhookRefund(ctx context.Context, m *orderDomain.OutboxMessage) error {
+	ev, err := m.Event()
+	if err != nil {
+		return fmt.Errorf("invalid outbox payload: %w", err)
+	}
+	if ev.RefundID == nil {
+		return fmt.Errorf("outbox message %s names no refund", m.ID)
+	}
+	refunds, err := r.PaymentService.GetRefundsByOrderID(ctx, m.OrderID)
+	if err != nil {
+		return fmt.Errorf("failed to get refunds: %w", err)
+	}
+	for _, refund := range refunds {
+		if refund.ID == *ev.RefundID {
+			return r.emitRefundCreated(ctx, refund)
+		}
+	}
+	return fmt.Errorf("refund %s not found", *ev.RefundID)
+}
+
+// outboxWebhookPaymentPaid emits payment.paid for the payment the message
+// names.
+func (r *Resolver) outboxWebhookPaymentPaid(ctx context.Context, m *orderDomain.OutboxMessage) error {
+	ev, err := m.Event()
+	if err != nil {
+		return fmt.Errorf("invalid outbox payload: %w", err)
+	}
+	if ev.PaymentID == "" {
+		return fmt.Errorf("outbox message %s names no payment", m.ID)
+	}
+	o, _, err := r.outboxOrder(ctx, m)
+	if err != nil {
+		return err
+	}
+	payment, err := r.PaymentService.GetPaymentByExternalID(ctx, ev.PaymentID)
+	if err != nil {
+		return err
+	}
+	return r.emitPaymentPaid(ctx, o, payment)
+}
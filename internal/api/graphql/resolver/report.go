@@ -0,0 +1,22 @@
+package resolver
+
+// This file will be automatically regenerated based on the schema, any resolver
+// implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.92
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"tsb-service/internal/api/graphql/model"
+)
+
+// DailyReport is the resolver for the dailyReport field.
+func (r *queryResolver) DailyReport(ctx context.Context, date time.Time) (*model.DailyReport, error) {
+	report, err := r.ReportService.GetDailyReport(ctx, date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily report: %w", err)
+	}
+	return toGQLDailyReport(report), nil
+}
@@ -30,12 +30,12 @@ import (
 	productApplication "tsb-service/internal/modules/product/application"
 	restaurantApplication "tsb-service/internal/modules/restaurant/application"
 	userApplication "tsb-service/internal/modules/user/application"
+	webhookApplication "tsb-service/internal/modules/webhook/application"
 	"tsb-service/internal/shared/middleware"
 	"tsb-service/pkg/apns"
 	"tsb-service/pkg/fcm"
 	"tsb-service/pkg/logging"
 	"tsb-service/pkg/pubsub"
-	"tsb-service/pkg/utils"
 )
 
 type Resolver struct {
@@ -44,12 +44,20 @@ type Resolver struct {
 	FCMClient             *fcm.Client  // nil if FCM not configured
 	AddressService        addressApplication.AddressService
 	CouponService         couponApplication.CouponService
+	DeliveryService       orderApplication.DeliveryService
+	EtaService            orderApplication.EtaService
+	IdempotencyService    orderApplication.IdempotencyService
 	NotificationService   notificationApplication.NotificationService
 	OrderService          orderApplication.OrderService
+	OutboxService         orderApplication.OutboxService
 	PaymentService        paymentApplication.PaymentService
+	PricingService        orderApplication.PricingService
 	ProductService        productApplication.ProductService
+	ReportService         orderApplication.ReportService
 	RestaurantService     restaurantApplication.RestaurantService
+	TableService          orderApplication.TableService
 	UserService           userApplication.UserService
+	WebhookService        webhookApplication.WebhookService
 	PosService            *posApplication.Service
 	CouponValidateLimiter *middleware.RateLimiter
 }
@@ -61,12 +69,20 @@ func NewResolver(
 	fcmClient *fcm.Client,
 	addressService addressApplication.AddressService,
 	couponService couponApplication.CouponService,
+	deliveryService orderApplication.DeliveryService,
+	etaService orderApplication.EtaService,
+	idempotencyService orderApplication.IdempotencyService,
 	notificationService notificationApplication.NotificationService,
 	orderService orderApplication.OrderService,
+	outboxService orderApplication.OutboxService,
 	paymentService paymentApplication.PaymentService,
+	pricingService orderApplication.PricingService,
 	productService productApplication.ProductService,
+	reportService orderApplication.ReportService,
 	restaurantService restaurantApplication.RestaurantService,
+	tableService orderApplication.TableService,
 	userService userApplication.UserService,
+	webhookService webhookApplication.WebhookService,
 	posService *posApplication.Service,
 	couponValidateLimiter *middleware.RateLimiter,
 ) *Resolver {
@@ -76,12 +92,20 @@ func NewResolver(
 		FCMClient:             fcmClient,
 		AddressService:        addressService,
 		CouponService:         couponService,
+		DeliveryService:       deliveryService,
+		EtaService:            etaService,
+		IdempotencyService:    idempotencyService,
 		NotificationService:   notificationService,
 		OrderService:          orderService,
+		OutboxService:         outboxService,
 		PaymentService:        paymentService,
+		PricingService:        pricingService,
 		ProductService:        productService,
+		ReportService:         reportService,
 		RestaurantService:     restaurantService,
+		TableService:          tableService,
 		UserService:           userService,
+		WebhookService:        webhookService,
 		PosService:            posService,
 		CouponValidateLimiter: couponValidateLimiter,
 	}
@@ -93,6 +117,7 @@ func GraphQLHandler(resolver *Resolver, allowedOrigins []string, oidcVerifier *m
 	cfg.Directives.Auth = directives.Auth
 	cfg.Directives.Admin = directives.Admin
 	cfg.Directives.Staff = directives.Staff
+	cfg.Directives.Driver = directives.Driver
 
 	h := handler.New(graphql.NewExecutableSchema(cfg))
 
@@ -106,59 +131,7 @@ func GraphQLHandler(resolver *Resolver, allowedOrigins []string, oidcVerifier *m
 		// our own origin allowlist (see originCheckedWebsocket) to preserve the prior
 		// CheckOrigin behaviour.
 		Implementation: originCheckedWebsocket{allowedOrigins: allowedOrigins},
-		InitFunc: func(ctx context.Context, initPayload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
-			// If auth was already set by HTTP middleware (via cookie), keep it
-			if utils.GetUserID(ctx) != "" {
-				return ctx, &initPayload, nil
-			}
-			// Fall back to connectionParams Authorization header
-			auth := initPayload.Authorization()
-			if auth == "" {
-				return ctx, &initPayload, nil
-			}
-			tokenStr := strings.TrimPrefix(auth, "Bearer ")
-			sub, isAdmin, isPOS, exp, err := oidcVerifier.VerifyToken(ctx, tokenStr)
-			if err == nil && sub != "" {
-				if isPOS {
-					// Device tokens carry the device UUID in `sub`; no Zitadel
-					// resolution needed.
-					ctx = utils.SetUserID(ctx, sub)
-				} else {
-					appID, lookupErr := resolver.UserService.ResolveZitadelID(ctx, sub, "", "", "")
-					if lookupErr != nil {
-						// Don't set a raw Zitadel sub (often a numeric Google
-						// user ID) as the userID — it will hit Postgres UUID
-						// columns and produce "invalid input syntax for type
-						// uuid". Leave userID empty so the @auth directive
-						// sees no authenticated user and returns UNAUTHENTICATED.
-						zap.L().Warn("failed to resolve Zitadel user on WS init — proceeding unauthenticated",
-							zap.String("sub", sub), zap.Error(lookupErr))
-						return ctx, &initPayload, nil
-					}
-					ctx = utils.SetUserID(ctx, appID)
-				}
-				ctx = utils.SetIsAdmin(ctx, isAdmin)
-				ctx = utils.SetTokenExpiry(ctx, exp)
-				// Bind the WebSocket context lifetime to the access token.
-				// When exp hits, ctx.Done() fires, every in-flight subscription
-				// unblocks on its <-ctx.Done() select arm, and gqlgen tears
-				// down the connection. Clients must reconnect with a fresh
-				// token (both tsb-core and tsb-dashboard already do this via
-				// silentRenew + graphql-ws retry).
-				//
-				// The WS transport cancels the parent ctx on socket close, so
-				// the cancel func here is redundant — the deadline fires via
-				// the runtime clock and its Timer is GC'd by context.cancelCtx
-				// once the parent terminates. Kept in a named var so govet's
-				// lostcancel check is satisfied.
-				if !exp.IsZero() {
-					var cancel context.CancelFunc
-					ctx, cancel = context.WithDeadline(ctx, exp)
-					_ = cancel
-				}
-			}
-			return ctx, &initPayload, nil
-		},
+		InitFunc:              websocketInit(oidcVerifier, resolver.UserService),
 		KeepAlivePingInterval: 10 * time.Second,
 	})
 	h.AddTransport(transport.Options{})
@@ -7,11 +7,18 @@ package resolver
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 	graphql1 "tsb-service/internal/api/graphql"
 	"tsb-service/internal/api/graphql/model"
+	"tsb-service/pkg/utils"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/google/uuid"
+	"github.com/vektah/gqlparser/v2/gqlerror"
 )
 
 // UpdateOrderingEnabled is the resolver for the updateOrderingEnabled field.
@@ -69,6 +76,109 @@ func (r *mutationResolver) UpdatePreparationMinutes(ctx context.Context, minutes
 	return gqlConfig, nil
 }
 
+// UpdatePricingRules is the resolver for the updatePricingRules field.
+func (r *mutationResolver) UpdatePricingRules(ctx context.Context, input model.PricingRulesInput) (*model.RestaurantConfig, error) {
+	rules, err := pricingRulesFromInput(input)
+	if err == nil {
+		err = rules.Validate()
+	}
+	if err != nil {
+		return nil, &gqlerror.Error{
+			Message:    err.Error(),
+			Path:       graphql.GetPath(ctx),
+			Extensions: map[string]any{"code": "USER_ERROR", "field": "pricing"},
+		}
+	}
+	config, err := r.RestaurantService.UpdatePricing(ctx, rules)
+	if err != nil {
+		return nil, fmt.Errorf("update pricing rules: %w", err)
+	}
+	gqlConfig := toGQLRestaurantConfig(config)
+	r.Broker.Publish("restaurantConfigUpdated", gqlConfig)
+	return gqlConfig, nil
+}
+
+// UpdateScheduling is the resolver for the updateScheduling field.
+func (r *mutationResolver) UpdateScheduling(ctx context.Context, horizonDays int, leadMinutes int) (*model.RestaurantConfig, error) {
+	if horizonDays < 0 || horizonDays > 60 {
+		return nil, fmt.Errorf("scheduling horizon must be between 0 and 60 days")
+	}
+	if leadMinutes < 0 || leadMinutes > 1440 {
+		return nil, fmt.Errorf("scheduled order lead time must be between 0 and 1440 minutes")
+	}
+	config, err := r.RestaurantService.UpdateScheduling(ctx, horizonDays, leadMinutes)
+	if err != nil {
+		return nil, fmt.Errorf("update scheduling: %w", err)
+	}
+	gqlConfig := toGQLRestaurantConfig(config)
+	r.Broker.Publish("restaurantConfigUpdated", gqlConfig)
+	return gqlConfig, nil
+}
+
+// UpdateCancellationGrace is the resolver for the updateCancellationGrace field.
+func (r *mutationResolver) UpdateCancellationGrace(ctx context.Context, minutes int) (*model.RestaurantConfig, error) {
+	if minutes < 0 || minutes > 60 {
+		return nil, fmt.Errorf("cancellation grace must be between 0 and 60 minutes")
+	}
+	config, err := r.RestaurantService.UpdateCancellationGrace(ctx, minutes)
+	if err != nil {
+		return nil, fmt.Errorf("update cancellation grace: %w", err)
+	}
+	gqlConfig := toGQLRestaurantConfig(config)
+	r.Broker.Publish("restaurantConfigUpdated", gqlConfig)
+	return gqlConfig, nil
+}
+
+// UpdateAutoApplyEta is the resolver for the updateAutoApplyEta field.
+func (r *mutationResolver) UpdateAutoApplyEta(ctx context.Context, enabled bool) (*model.RestaurantConfig, error) {
+	config, err := r.RestaurantService.UpdateAutoApplyEta(ctx, enabled)
+	if err != nil {
+		return nil, fmt.Errorf("update auto-apply eta: %w", err)
+	}
+	gqlConfig := toGQLRestaurantConfig(config)
+	r.Broker.Publish("restaurantConfigUpdated", gqlConfig)
+	return gqlConfig, nil
+}
+
+// UpdateUnconfirmedOrderTimeouts is the resolver for the updateUnconfirmedOrderTimeouts field.
+func (r *mutationResolver) UpdateUnconfirmedOrderTimeouts(ctx context.Context, escalationMinutes int, cancelMinutes int) (*model.RestaurantConfig, error) {
+	if escalationMinutes < 0 || escalationMinutes > 240 {
+		return nil, fmt.Errorf("unconfirmed order escalation must be between 0 and 240 minutes")
+	}
+	if cancelMinutes < 0 || cancelMinutes > 240 {
+		return nil, fmt.Errorf("unconfirmed order cancellation must be between 0 and 240 minutes")
+	}
+	if escalationMinutes > 0 && cancelMinutes > 0 && cancelMinutes <= escalationMinutes {
+		return nil, fmt.Errorf("unconfirmed orders must be escalated before they are cancelled")
+	}
+	config, err := r.RestaurantService.UpdateUnconfirmedTimeouts(ctx, escalationMinutes, cancelMinutes)
+	if err != nil {
+		return nil, fmt.Errorf("update unconfirmed order timeouts: %w", err)
+	}
+	gqlConfig := toGQLRestaurantConfig(config)
+	r.Broker.Publish("restaurantConfigUpdated", gqlConfig)
+	return gqlConfig, nil
+}
+
+// UpdateSlotCapacity is the resolver for the updateSlotCapacity field.
+func (r *mutationResolver) UpdateSlotCapacity(ctx context.Context, rules []*model.SlotCapacityRuleInput) (*model.RestaurantConfig, error) {
+	capacity := slotCapacityFromInput(rules)
+	if err := capacity.Validate(); err != nil {
+		return nil, &gqlerror.Error{
+			Message:    err.Error(),
+			Path:       graphql.GetPath(ctx),
+			Extensions: map[string]any{"code": "USER_ERROR", "field": "slotCapacity"},
+		}
+	}
+	config, err := r.RestaurantService.UpdateSlotCapacity(ctx, capacity)
+	if err != nil {
+		return nil, fmt.Errorf("update slot capacity: %w", err)
+	}
+	gqlConfig := toGQLRestaurantConfig(config)
+	r.Broker.Publish("restaurantConfigUpdated", gqlConfig)
+	return gqlConfig, nil
+}
+
 // UpsertScheduleOverride is the resolver for the upsertScheduleOverride field.
 func (r *mutationResolver) UpsertScheduleOverride(ctx context.Context, input model.ScheduleOverrideInput) (*model.ScheduleOverride, error) {
 	if !input.Closed && input.Schedule == nil {
@@ -110,6 +220,52 @@ func (r *mutationResolver) DeleteScheduleOverride(ctx context.Context, date time
 	return true, nil
 }
 
+// CreateDeliveryZone is the resolver for the createDeliveryZone field.
+func (r *mutationResolver) CreateDeliveryZone(ctx context.Context, input model.DeliveryZoneInput) (*model.DeliveryZone, error) {
+	zone, err := deliveryZoneFromInput(input)
+	if err != nil {
+		return nil, &gqlerror.Error{
+			Message:    err.Error(),
+			Path:       graphql.GetPath(ctx),
+			Extensions: map[string]any{"code": "USER_ERROR", "field": "deliveryZone"},
+		}
+	}
+	created, err := r.RestaurantService.CreateDeliveryZone(ctx, zone)
+	if err != nil {
+		return nil, fmt.Errorf("create delivery zone: %w", err)
+	}
+	return toGQLDeliveryZone(created), nil
+}
+
+// UpdateDeliveryZone is the resolver for the updateDeliveryZone field.
+func (r *mutationResolver) UpdateDeliveryZone(ctx context.Context, id uuid.UUID, input model.DeliveryZoneInput) (*model.DeliveryZone, error) {
+	zone, err := deliveryZoneFromInput(input)
+	if err != nil {
+		return nil, &gqlerror.Error{
+			Message:    err.Error(),
+			Path:       graphql.GetPath(ctx),
+			Extensions: map[string]any{"code": "USER_ERROR", "field": "deliveryZone"},
+		}
+	}
+	zone.ID = id
+	updated, err := r.RestaurantService.UpdateDeliveryZone(ctx, zone)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("delivery zone not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update delivery zone: %w", err)
+	}
+	return toGQLDeliveryZone(updated), nil
+}
+
+// DeleteDeliveryZone is the resolver for the deleteDeliveryZone field.
+func (r *mutationResolver) DeleteDeliveryZone(ctx context.Context, id uuid.UUID) (bool, error) {
+	if err := r.RestaurantService.DeleteDeliveryZone(ctx, id); err != nil {
+		return false, fmt.Errorf("delete delivery zone: %w", err)
+	}
+	return true, nil
+}
+
 // RestaurantConfig is the resolver for the restaurantConfig field.
 func (r *queryResolver) RestaurantConfig(ctx context.Context) (*model.RestaurantConfig, error) {
 	config, err := r.RestaurantService.GetConfig(ctx)
@@ -119,6 +275,26 @@ func (r *queryResolver) RestaurantConfig(ctx context.Context) (*model.Restaurant
 	return toGQLRestaurantConfig(config), nil
 }
 
+// AvailableSlots is the resolver for the availableSlots field.
+func (r *queryResolver) AvailableSlots(ctx context.Context, date time.Time, orderType *model.OrderTypeEnum) ([]*model.TimeSlot, error) {
+	config, overrides, err := r.RestaurantService.GetConfigWithOverrides(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get restaurant config: %w", err)
+	}
+	now := time.Now()
+	slots := config.AvailableSlots(now, date, overrides)
+	// Same store-review bypass as availableSlotsToday, for today only.
+	// TEMPORARY (revert after launch).
+	if len(slots) == 0 && localDate(date) == localDate(now) && r.isReviewContextUser(ctx) {
+		return toGQLTimeSlots(config.ReviewSlotsToday(now)), nil
+	}
+	out := toGQLTimeSlots(slots)
+	if err := r.markFullSlots(ctx, config, out, orderType); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ScheduleOverrides is the resolver for the scheduleOverrides field.
 func (r *queryResolver) ScheduleOverrides(ctx context.Context, from time.Time, to time.Time) ([]*model.ScheduleOverride, error) {
 	overrides, err := r.RestaurantService.ListOverrides(ctx, from, to)
@@ -132,6 +308,22 @@ func (r *queryResolver) ScheduleOverrides(ctx context.Context, from time.Time, t
 	return out, nil
 }
 
+// DeliveryZones is the resolver for the deliveryZones field.
+func (r *queryResolver) DeliveryZones(ctx context.Context) ([]*model.DeliveryZone, error) {
+	zones, err := r.RestaurantService.ListDeliveryZones(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list delivery zones: %w", err)
+	}
+	isAdmin := utils.GetIsAdmin(ctx)
+	out := make([]*model.DeliveryZone, 0, len(zones))
+	for _, z := range zones {
+		if z.IsActive || isAdmin {
+			out = append(out, toGQLDeliveryZone(z))
+		}
+	}
+	return out, nil
+}
+
 // IsCurrentlyOpen is the resolver for the isCurrentlyOpen field.
 func (r *restaurantConfigResolver) IsCurrentlyOpen(ctx context.Context, obj *model.RestaurantConfig) (bool, error) {
 	config, overrides, err := r.RestaurantService.GetConfigWithOverrides(ctx)
@@ -157,7 +349,7 @@ func (r *restaurantConfigResolver) IsOrderingCurrentlyOpen(ctx context.Context,
 }
 
 // AvailableSlotsToday is the resolver for the availableSlotsToday field.
-func (r *restaurantConfigResolver) AvailableSlotsToday(ctx context.Context, obj *model.RestaurantConfig) ([]*model.TimeSlot, error) {
+func (r *restaurantConfigResolver) AvailableSlotsToday(ctx context.Context, obj *model.RestaurantConfig, orderType *model.OrderTypeEnum) ([]*model.TimeSlot, error) {
 	config, overrides, err := r.RestaurantService.GetConfigWithOverrides(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("get restaurant config: %w", err)
@@ -169,9 +361,13 @@ func (r *restaurantConfigResolver) AvailableSlotsToday(ctx context.Context, obj
 	// hours. Gated on the empty result so normal traffic pays no extra cost.
 	// TEMPORARY (revert after launch).
 	if len(slots) == 0 && r.isReviewContextUser(ctx) {
-		slots = config.ReviewSlotsToday(now)
+		return toGQLTimeSlots(config.ReviewSlotsToday(now)), nil
 	}
-	return toGQLTimeSlots(slots), nil
+	out := toGQLTimeSlots(slots)
+	if err := r.markFullSlots(ctx, config, out, orderType); err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
 // NextOpeningAt is the resolver for the nextOpeningAt field.
@@ -2,8 +2,14 @@ package resolver
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"time"
 
 	"tsb-service/internal/api/auth"
+	"tsb-service/internal/api/graphql/model"
+	orderDomain "tsb-service/internal/modules/order/domain"
+	restaurantDomain "tsb-service/internal/modules/restaurant/domain"
 	"tsb-service/pkg/utils"
 )
 
@@ -25,3 +31,63 @@ func (r *Resolver) isReviewContextUser(ctx context.Context) bool {
 	}
 	return auth.IsReviewUser(user.Email, user.FirstName, user.LastName)
 }
+
+// slotLimits returns the capacity limits that apply to the slot starting at
+// slot. A config whose capacity rules fail to parse imposes no limit rather
+// than blocking checkout.
+func slotLimits(config *restaurantDomain.RestaurantConfig, slot time.Time) []orderDomain.SlotLimit {
+	capacity, err := config.GetSlotCapacity()
+	if err != nil {
+		return nil
+	}
+	rules := capacity.RulesFor(slot)
+	limits := make([]orderDomain.SlotLimit, 0, len(rules))
+	for _, rule := range rules {
+		if rule.MaxOrders == 0 && rule.MaxItems == 0 {
+			continue
+		}
+		l := orderDomain.SlotLimit{MaxOrders: rule.MaxOrders, MaxItems: rule.MaxItems}
+		if rule.OrderType != "" {
+			orderType := orderDomain.OrderType(rule.OrderType)
+			l.OrderType = &orderType
+		}
+		limits = append(limits, l)
+	}
+	return limits
+}
+
+// markFullSlots flags the slots whose kitchen capacity is used up. Without an
+// order type only the limits counting every order are checked.
+func (r *Resolver) markFullSlots(ctx context.Context, config *restaurantDomain.RestaurantConfig, slots []*model.TimeSlot, orderType *model.OrderTypeEnum) error {
+	if len(slots) == 0 {
+		return nil
+	}
+	capacity, err := config.GetSlotCapacity()
+	if err != nil || len(capacity) == 0 {
+		return nil
+	}
+	from := orderDomain.SlotStart(slots[0].Value)
+	to := orderDomain.SlotStart(slots[len(slots)-1].Value).Add(orderDomain.SlotStep)
+	usage, err := r.OrderService.GetSlotUsage(ctx, from, to)
+	if err != nil {
+		return fmt.Errorf("get slot usage: %w", err)
+	}
+	bySlot := make(map[time.Time][]orderDomain.SlotUsage)
+	for _, u := range usage {
+		key := u.SlotStart.UTC()
+		bySlot[key] = append(bySlot[key], u)
+	}
+	for _, s := range slots {
+		start := orderDomain.SlotStart(s.Value)
+		limits := slotLimits(config, start)
+		if orderType == nil {
+			limits = slices.DeleteFunc(limits, func(l orderDomain.SlotLimit) bool { return l.OrderType != nil })
+		}
+		typ := orderDomain.OrderTypePickUp
+		if orderType != nil && *orderType == model.OrderTypeEnumDelivery {
+			typ = orderDomain.OrderTypeDelivery
+		}
+		s.IsAvailable = orderDomain.SlotHasRoom(limits, bySlot[start.UTC()], typ, 0)
+	}
+	return nil
+}
@@ -125,7 +125,7 @@ func TestIsOrderingCurrentlyOpen_ReviewBypass(t *testing.T) {
 func TestAvailableSlotsToday_ReviewBypass(t *testing.T) {
 	t.Run("review user gets synthetic slots while closed", func(t *testing.T) {
 		res := closedConfigResolver(t, reviewUser())
-		slots, err := res.AvailableSlotsToday(authedCtx(), nil)
+		slots, err := res.AvailableSlotsToday(authedCtx(), nil, nil)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -136,7 +136,7 @@ func TestAvailableSlotsToday_ReviewBypass(t *testing.T) {
 
 	t.Run("normal user gets no slots while closed", func(t *testing.T) {
 		res := closedConfigResolver(t, normalUser())
-		slots, err := res.AvailableSlotsToday(authedCtx(), nil)
+		slots, err := res.AvailableSlotsToday(authedCtx(), nil, nil)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -147,7 +147,7 @@ func TestAvailableSlotsToday_ReviewBypass(t *testing.T) {
 
 	t.Run("anonymous caller gets no slots while closed", func(t *testing.T) {
 		res := closedConfigResolver(t, normalUser())
-		slots, err := res.AvailableSlotsToday(context.Background(), nil)
+		slots, err := res.AvailableSlotsToday(context.Background(), nil, nil)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -0,0 +1,143 @@
+package resolver
+
+// This file will be automatically regenerated based on the schema, any resolver
+// implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.92
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	graphql1 "tsb-service/internal/api/graphql"
+	"tsb-service/internal/api/graphql/model"
+	orderDomain "tsb-service/internal/modules/order/domain"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/google/uuid"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// JoinTable is the resolver for the joinTable field.
+func (r *mutationResolver) JoinTable(ctx context.Context, token string) (*model.TableSession, error) {
+	session, err := r.TableService.JoinTable(ctx, token)
+	if err != nil {
+		return nil, tableError(ctx, "token", err)
+	}
+	return toGQLTableSession(session), nil
+}
+
+// CreateDiningTable is the resolver for the createDiningTable field.
+func (r *mutationResolver) CreateDiningTable(ctx context.Context, input model.DiningTableInput) (*model.DiningTable, error) {
+	table, err := diningTableFromInput(input)
+	if err != nil {
+		return nil, &gqlerror.Error{
+			Message:    err.Error(),
+			Path:       graphql.GetPath(ctx),
+			Extensions: map[string]any{"code": "USER_ERROR", "field": "diningTable"},
+		}
+	}
+	created, err := r.TableService.CreateTable(ctx, table)
+	if err != nil {
+		return nil, fmt.Errorf("create dining table: %w", err)
+	}
+	return toGQLDiningTable(created, r.TableService.TableToken(created)), nil
+}
+
+// UpdateDiningTable is the resolver for the updateDiningTable field.
+func (r *mutationResolver) UpdateDiningTable(ctx context.Context, id uuid.UUID, input model.DiningTableInput) (*model.DiningTable, error) {
+	table, err := diningTableFromInput(input)
+	if err != nil {
+		return nil, &gqlerror.Error{
+			Message:    err.Error(),
+			Path:       graphql.GetPath(ctx),
+			Extensions: map[string]any{"code": "USER_ERROR", "field": "diningTable"},
+		}
+	}
+	table.ID = id
+	updated, err := r.TableService.UpdateTable(ctx, table)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("dining table not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update dining table: %w", err)
+	}
+	return toGQLDiningTable(updated, r.TableService.TableToken(updated)), nil
+}
+
+// RotateDiningTableToken is the resolver for the rotateDiningTableToken field.
+func (r *mutationResolver) RotateDiningTableToken(ctx context.Context, id uuid.UUID) (*model.DiningTable, error) {
+	rotated, err := r.TableService.RotateTableToken(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("dining table not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("rotate dining table token: %w", err)
+	}
+	return toGQLDiningTable(rotated, r.TableService.TableToken(rotated)), nil
+}
+
+// SettleTableSession is the resolver for the settleTableSession field.
+func (r *mutationResolver) SettleTableSession(ctx context.Context, id uuid.UUID, paymentMethod model.TablePaymentMethod) (*model.TableSession, error) {
+	session, err := r.TableService.SettleSession(ctx, id, orderDomain.TablePaymentMethod(paymentMethod))
+	if err != nil {
+		return nil, tableError(ctx, "tableSession", err)
+	}
+	return toGQLTableSession(session), nil
+}
+
+// DiningTables is the resolver for the diningTables field.
+func (r *queryResolver) DiningTables(ctx context.Context) ([]*model.DiningTable, error) {
+	tables, err := r.TableService.ListTables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list dining tables: %w", err)
+	}
+	return Map(tables, func(t *orderDomain.DiningTable) *model.DiningTable {
+		return toGQLDiningTable(t, r.TableService.TableToken(t))
+	}), nil
+}
+
+// OpenTableSessions is the resolver for the openTableSessions field.
+func (r *queryResolver) OpenTableSessions(ctx context.Context) ([]*model.TableSession, error) {
+	sessions, err := r.TableService.ListOpenSessions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list open table sessions: %w", err)
+	}
+	return Map(sessions, toGQLTableSession), nil
+}
+
+// TableSession is the resolver for the tableSession field.
+func (r *queryResolver) TableSession(ctx context.Context, id uuid.UUID) (*model.TableSession, error) {
+	session, err := r.TableService.GetSession(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get table session: %w", err)
+	}
+	return toGQLTableSession(session), nil
+}
+
+// Bill is the resolver for the bill field.
+func (r *tableSessionResolver) Bill(ctx context.Context, obj *model.TableSession) (*model.TableBill, error) {
+	bill, err := r.TableService.GetBill(ctx, obj.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get table bill: %w", err)
+	}
+	return toGQLTableBill(bill), nil
+}
+
+// Orders is the resolver for the orders field.
+func (r *tableSessionResolver) Orders(ctx context.Context, obj *model.TableSession) ([]*model.Order, error) {
+	orders, err := r.TableService.GetSessionOrders(ctx, obj.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get table rounds: %w", err)
+	}
+	return Map(orders, ToGQLOrder), nil
+}
+
+// TableSession returns graphql1.TableSessionResolver implementation.
+func (r *Resolver) TableSession() graphql1.TableSessionResolver { return &tableSessionResolver{r} }
+
+type tableSessionResolver struct{ *Resolver }
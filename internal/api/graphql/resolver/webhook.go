@@ -0,0 +1,133 @@
+package resolver
+
+// This file will be automatically regenerated based on the schema, any resolver
+// implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.92
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	graphql1 "tsb-service/internal/api/graphql"
+	"tsb-service/internal/api/graphql/model"
+	webhookDomain "tsb-service/internal/modules/webhook/domain"
+
+	"github.com/google/uuid"
+)
+
+// CreateWebhookEndpoint is the resolver for the createWebhookEndpoint field.
+func (r *mutationResolver) CreateWebhookEndpoint(ctx context.Context, input model.CreateWebhookEndpointInput) (*model.WebhookEndpoint, error) {
+	endpoint := &webhookDomain.Endpoint{
+		URL:      input.URL,
+		Events:   fromGQLWebhookEvents(input.Events),
+		IsActive: input.IsActive == nil || *input.IsActive,
+	}
+	if input.Secret != nil {
+		endpoint.Secret = strings.TrimSpace(*input.Secret)
+	}
+	if err := r.WebhookService.CreateEndpoint(ctx, endpoint); err != nil {
+		if userErr := webhookInputError(ctx, err); userErr != nil {
+			return nil, userErr
+		}
+		return nil, fmt.Errorf("failed to create webhook endpoint: %w", err)
+	}
+	return toGQLWebhookEndpoint(endpoint), nil
+}
+
+// UpdateWebhookEndpoint is the resolver for the updateWebhookEndpoint field.
+func (r *mutationResolver) UpdateWebhookEndpoint(ctx context.Context, id uuid.UUID, input model.UpdateWebhookEndpointInput) (*model.WebhookEndpoint, error) {
+	endpoint, err := r.WebhookService.GetEndpoint(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if input.URL != nil {
+		endpoint.URL = *input.URL
+	}
+	if input.Events != nil {
+		endpoint.Events = fromGQLWebhookEvents(input.Events)
+	}
+	// A blank secret keeps the current one.
+	if input.Secret != nil && strings.TrimSpace(*input.Secret) != "" {
+		endpoint.Secret = strings.TrimSpace(*input.Secret)
+	}
+	if input.IsActive != nil {
+		endpoint.IsActive = *input.IsActive
+	}
+	if err := r.WebhookService.UpdateEndpoint(ctx, endpoint); err != nil {
+		if userErr := webhookInputError(ctx, err); userErr != nil {
+			return nil, userErr
+		}
+		return nil, fmt.Errorf("failed to update webhook endpoint: %w", err)
+	}
+	return toGQLWebhookEndpoint(endpoint), nil
+}
+
+// DeleteWebhookEndpoint is the resolver for the deleteWebhookEndpoint field.
+func (r *mutationResolver) DeleteWebhookEndpoint(ctx context.Context, id uuid.UUID) (bool, error) {
+	if err := r.WebhookService.DeleteEndpoint(ctx, id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RetryWebhookDelivery is the resolver for the retryWebhookDelivery field.
+func (r *mutationResolver) RetryWebhookDelivery(ctx context.Context, id uuid.UUID) (*model.WebhookDelivery, error) {
+	delivery, err := r.WebhookService.RetryDelivery(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("webhook delivery not found or already delivered")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retry webhook delivery: %w", err)
+	}
+	return toGQLWebhookDelivery(delivery), nil
+}
+
+// WebhookEndpoints is the resolver for the webhookEndpoints field.
+func (r *queryResolver) WebhookEndpoints(ctx context.Context) ([]*model.WebhookEndpoint, error) {
+	endpoints, err := r.WebhookService.GetEndpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Map(endpoints, toGQLWebhookEndpoint), nil
+}
+
+// WebhookEndpoint is the resolver for the webhookEndpoint field.
+func (r *queryResolver) WebhookEndpoint(ctx context.Context, id uuid.UUID) (*model.WebhookEndpoint, error) {
+	endpoint, err := r.WebhookService.GetEndpoint(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toGQLWebhookEndpoint(endpoint), nil
+}
+
+// Deliveries is the resolver for the deliveries field.
+func (r *webhookEndpointResolver) Deliveries(ctx context.Context, obj *model.WebhookEndpoint, status *model.WebhookDeliveryStatus, limit *int) ([]*model.WebhookDelivery, error) {
+	const (
+		defaultLimit = 50
+		maxLimit     = 500
+	)
+	l := defaultLimit
+	if limit != nil && *limit > 0 {
+		l = min(*limit, maxLimit)
+	}
+	var st *webhookDomain.DeliveryStatus
+	if status != nil {
+		v := webhookDomain.DeliveryStatus(*status)
+		st = &v
+	}
+	deliveries, err := r.WebhookService.ListDeliveries(ctx, obj.ID, st, l)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return Map(deliveries, toGQLWebhookDelivery), nil
+}
+
+// WebhookEndpoint returns graphql1.WebhookEndpointResolver implementation.
+func (r *Resolver) WebhookEndpoint() graphql1.WebhookEndpointResolver {
+	return &webhookEndpointResolver{r}
+}
+
+type webhookEndpointResolver struct{ *Resolver }
@@ -0,0 +1,127 @@
+package resolver
+
+// Webhook events are emitted by the outbox handlers, next to the subscription
+// events they mirror. Each event has a stable key so a retried handler never
+// sends it twice.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	orderDomain "tsb-service/internal/modules/order/domain"
+	paymentDomain "tsb-service/internal/modules/payment/domain"
+	webhookDomain "tsb-service/internal/modules/webhook/domain"
+	"tsb-service/pkg/invoice"
+)
+
+// webhookOrder is the order as described to webhook subscribers: a stable
+// summary rather than the GraphQL type, which evolves with the dashboard.
+type webhookOrder struct {
+	ID                 uuid.UUID               `json:"id"`
+	Reference          string                  `json:"reference"`
+	Status             orderDomain.OrderStatus `json:"status"`
+	PreviousStatus     orderDomain.OrderStatus `json:"previousStatus,omitempty"`
+	OrderType          orderDomain.OrderType   `json:"orderType"`
+	IsOnlinePayment    bool                    `json:"isOnlinePayment"`
+	TotalPrice         decimal.Decimal         `json:"totalPrice"`
+	PreferredReadyTime *time.Time              `json:"preferredReadyTime,omitempty"`
+	EstimatedReadyTime *time.Time              `json:"estimatedReadyTime,omitempty"`
+	CreatedAt          time.Time               `json:"createdAt"`
+}
+
+func toWebhookOrder(o *orderDomain.Order) webhookOrder {
+	return webhookOrder{
+		ID:                 o.ID,
+		Reference:          invoice.OrderReference(o.ID.String(), o.CreatedAt),
+		Status:             o.OrderStatus,
+		OrderType:          o.OrderType,
+		IsOnlinePayment:    o.IsOnlinePayment,
+		TotalPrice:         o.TotalPrice,
+		PreferredReadyTime: o.PreferredReadyTime,
+		EstimatedReadyTime: o.EstimatedReadyTime,
+		CreatedAt:          o.CreatedAt,
+	}
+}
+
+// webhookInputError turns an endpoint validation failure into a user error,
+// or returns nil for other errors.
+func webhookInputError(ctx context.Context, err error) error {
+	var field string
+	switch {
+	case errors.Is(err, webhookDomain.ErrInvalidURL):
+		field = "url"
+	case errors.Is(err, webhookDomain.ErrInvalidEvents):
+		field = "events"
+	default:
+		return nil
+	}
+	return &gqlerror.Error{
+		Message:    err.Error(),
+		Path:       graphql.GetPath(ctx),
+		Extensions: map[string]any{"code": "USER_ERROR", "field": field},
+	}
+}
+
+// emitWebhook queues an event for the subscribed endpoints. It is a no-op
+// when webhooks are not wired, as in tests.
+func (r *Resolver) emitWebhook(ctx context.Context, t webhookDomain.EventType, key string, data any) error {
+	if r.WebhookService == nil {
+		return nil
+	}
+	if err := r.WebhookService.Emit(ctx, t, key, data); err != nil {
+		return fmt.Errorf("failed to emit %s webhook: %w", t, err)
+	}
+	return nil
+}
+
+// emitOrderCreated emits order.created, once per order.
+func (r *Resolver) emitOrderCreated(ctx context.Context, o *orderDomain.Order) error {
+	return r.emitWebhook(ctx, webhookDomain.EventOrderCreated, o.ID.String(), toWebhookOrder(o))
+}
+
+// emitOrderStatusChanged emits order.status_changed for the change identified
+// by key.
+func (r *Resolver) emitOrderStatusChanged(ctx context.Context, o *orderDomain.Order, previous orderDomain.OrderStatus, key string) error {
+	data := toWebhookOrder(o)
+	data.PreviousStatus = previous
+	return r.emitWebhook(ctx, webhookDomain.EventOrderStatusChanged, key, data)
+}
+
+// emitPaymentPaid emits payment.paid, once per payment.
+func (r *Resolver) emitPaymentPaid(ctx context.Context, o *orderDomain.Order, p *paymentDomain.MolliePayment) error {
+	data := struct {
+		PaymentID string          `json:"paymentId"`
+		Amount    decimal.Decimal `json:"amount"`
+		Order     webhookOrder    `json:"order"`
+	}{
+		PaymentID: p.MolliePaymentID,
+		Amount:    p.Amount,
+		Order:     toWebhookOrder(o),
+	}
+	return r.emitWebhook(ctx, webhookDomain.EventPaymentPaid, p.MolliePaymentID, data)
+}
+
+// emitRefundCreated emits refund.created, once per refund.
+func (r *Resolver) emitRefundCreated(ctx context.Context, refund *paymentDomain.Refund) error {
+	data := struct {
+		ID        uuid.UUID       `json:"id"`
+		OrderID   uuid.UUID       `json:"orderId"`
+		Amount    decimal.Decimal `json:"amount"`
+		Reason    string          `json:"reason"`
+		CreatedAt time.Time       `json:"createdAt"`
+	}{
+		ID:        refund.ID,
+		OrderID:   refund.OrderID,
+		Amount:    refund.Amount,
+		Reason:    refund.Reason,
+		CreatedAt: refund.CreatedAt,
+	}
+	return r.emitWebhook(ctx, webhookDomain.EventRefundCreated, refund.ID.String(), data)
+}
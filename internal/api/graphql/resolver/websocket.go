@@ -1,13 +1,17 @@
 package resolver
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/99designs/gqlgen/graphql/handler/transport"
 	coderws "github.com/coder/websocket"
 	"go.uber.org/zap"
+
+	"tsb-service/pkg/utils"
 )
 
 // originCheckedWebsocket wraps gqlgen's default Coder websocket implementation
@@ -47,3 +51,80 @@ func (o originCheckedWebsocket) Accept(w http.ResponseWriter, r *http.Request, o
 		AcceptOptions: coderws.AcceptOptions{InsecureSkipVerify: true},
 	}.Accept(w, r, options)
 }
+
+// wsTokenVerifier checks the token of a WebSocket connection. Satisfied by
+// *middleware.OIDCVerifier.
+type wsTokenVerifier interface {
+	VerifyToken(ctx context.Context, tokenStr string) (subject string, isAdmin, isDriver, isPOS bool, exp time.Time, err error)
+}
+
+// wsUserResolver maps a Zitadel subject to the app user ID. Satisfied by
+// userApplication.UserService.
+type wsUserResolver interface {
+	ResolveZitadelID(ctx context.Context, zitadelID, email, firstName, lastName string) (string, error)
+}
+
+// websocketInit authenticates a WebSocket connection the way the HTTP auth
+// middleware does a request, so subscriptions and operations sent over the
+// socket see the same caller: user ID, admin, driver and POS device flags,
+// and token expiry.
+func websocketInit(verifier wsTokenVerifier, users wsUserResolver) transport.WebsocketInitFunc {
+	return func(ctx context.Context, initPayload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+		// If auth was already set by HTTP middleware (via cookie), keep it
+		if utils.GetUserID(ctx) != "" {
+			return ctx, &initPayload, nil
+		}
+		// Fall back to connectionParams Authorization header
+		auth := initPayload.Authorization()
+		if auth == "" {
+			return ctx, &initPayload, nil
+		}
+		tokenStr := strings.TrimPrefix(auth, "Bearer ")
+		sub, isAdmin, isDriver, isPOS, exp, err := verifier.VerifyToken(ctx, tokenStr)
+		if err != nil || sub == "" {
+			return ctx, &initPayload, nil
+		}
+		if isPOS {
+			// Device tokens carry the device UUID in `sub`; no Zitadel
+			// resolution needed.
+			ctx = utils.SetUserID(ctx, sub)
+		} else {
+			appID, lookupErr := users.ResolveZitadelID(ctx, sub, "", "", "")
+			if lookupErr != nil {
+				// Don't set a raw Zitadel sub (often a numeric Google
+				// user ID) as the userID — it will hit Postgres UUID
+				// columns and produce "invalid input syntax for type
+				// uuid". Leave userID empty so the @auth directive
+				// sees no authenticated user and returns UNAUTHENTICATED.
+				zap.L().Warn("failed to resolve Zitadel user on WS init — proceeding unauthenticated",
+					zap.String("sub", sub), zap.Error(lookupErr))
+				return ctx, &initPayload, nil
+			}
+			ctx = utils.SetUserID(ctx, appID)
+		}
+		ctx = utils.SetIsAdmin(ctx, isAdmin)
+		ctx = utils.SetIsDriver(ctx, isDriver)
+		// Status changes made over the socket are attributed to the device
+		// (see orderApplication.actorFromContext).
+		ctx = utils.SetIsPOS(ctx, isPOS)
+		ctx = utils.SetTokenExpiry(ctx, exp)
+		// Bind the WebSocket context lifetime to the access token.
+		// When exp hits, ctx.Done() fires, every in-flight subscription
+		// unblocks on its <-ctx.Done() select arm, and gqlgen tears
+		// down the connection. Clients must reconnect with a fresh
+		// token (both tsb-core and tsb-dashboard already do this via
+		// silentRenew + graphql-ws retry).
+		//
+		// The WS transport cancels the parent ctx on socket close, so
+		// the cancel func here is redundant — the deadline fires via
+		// the runtime clock and its Timer is GC'd by context.cancelCtx
+		// once the parent terminates. Kept in a named var so govet's
+		// lostcancel check is satisfied.
+		if !exp.IsZero() {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, exp)
+			_ = cancel
+		}
+		return ctx, &initPayload, nil
+	}
+}
@@ -0,0 +1,82 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+
+	"tsb-service/pkg/utils"
+)
+
+type fakeWSVerifier struct {
+	sub          string
+	admin, isPOS bool
+	err          error
+}
+
+func (f fakeWSVerifier) VerifyToken(context.Context, string) (string, bool, bool, bool, time.Time, error) {
+	return f.sub, f.admin, false, f.isPOS, time.Time{}, f.err
+}
+
+type fakeWSUsers map[string]string
+
+func (f fakeWSUsers) ResolveZitadelID(_ context.Context, sub, _, _, _ string) (string, error) {
+	if id, ok := f[sub]; ok {
+		return id, nil
+	}
+	return "", errors.New("unknown subject")
+}
+
+func TestWebsocketInit(t *testing.T) {
+	payload := transport.InitPayload{"Authorization": "Bearer token"}
+	deviceID := "0b5e2c84-7d1e-4c3b-9a55-2f0b6c1d9e77"
+	userID := "5c1f0a3e-2b7d-4f6a-8e9c-1d2e3f4a5b6c"
+
+	t.Run("POS device token", func(t *testing.T) {
+		init := websocketInit(fakeWSVerifier{sub: deviceID, admin: true, isPOS: true}, fakeWSUsers{})
+		ctx, _, err := init(context.Background(), payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if utils.GetUserID(ctx) != deviceID || !utils.GetIsPOS(ctx) || !utils.GetIsAdmin(ctx) {
+			t.Errorf("user %q, pos %v, admin %v; want the admin POS device", utils.GetUserID(ctx), utils.GetIsPOS(ctx), utils.GetIsAdmin(ctx))
+		}
+	})
+
+	t.Run("Zitadel token", func(t *testing.T) {
+		init := websocketInit(fakeWSVerifier{sub: "zitadel-sub", admin: true}, fakeWSUsers{"zitadel-sub": userID})
+		ctx, _, err := init(context.Background(), payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if utils.GetUserID(ctx) != userID || utils.GetIsPOS(ctx) {
+			t.Errorf("user %q, pos %v; want the resolved user, not a POS device", utils.GetUserID(ctx), utils.GetIsPOS(ctx))
+		}
+	})
+
+	t.Run("HTTP-authenticated POS device keeps its flag", func(t *testing.T) {
+		init := websocketInit(fakeWSVerifier{err: errors.New("unused")}, fakeWSUsers{})
+		httpCtx := utils.SetIsPOS(utils.SetUserID(context.Background(), deviceID), true)
+		ctx, _, err := init(httpCtx, transport.InitPayload{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !utils.GetIsPOS(ctx) {
+			t.Error("the POS flag set by the HTTP middleware was dropped")
+		}
+	})
+
+	t.Run("invalid token stays anonymous", func(t *testing.T) {
+		init := websocketInit(fakeWSVerifier{err: errors.New("bad token")}, fakeWSUsers{})
+		ctx, _, err := init(context.Background(), payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if utils.GetUserID(ctx) != "" || utils.GetIsPOS(ctx) {
+			t.Error("an invalid token must not authenticate the socket")
+		}
+	})
+}
@@ -4,8 +4,6 @@ import (
 	"context"
 	"testing"
 
-	"github.com/VictorAvelar/mollie-api-go/v4/mollie"
-
 	"tsb-service/internal/api/graphql/resolver"
 	"tsb-service/internal/api/graphql/testhelpers"
 	addressApplication "tsb-service/internal/modules/address/application"
@@ -74,34 +72,43 @@ func createTestResolver(testDB *testhelpers.TestDatabase) *resolver.Resolver {
 	addressCacheRepo := addressInfrastructure.NewAddressCacheRepository(pool)
 	couponRepo := couponInfrastructure.NewCouponRepository(pool)
 	orderRepo := orderInfrastructure.NewOrderRepository(pool)
+	etaRepo := orderInfrastructure.NewEtaRepository(pool)
+	slotReservationRepo := orderInfrastructure.NewSlotReservationRepository(pool)
+	outboxRepo := orderInfrastructure.NewOutboxRepository(pool)
 	paymentRepo := paymentInfrastructure.NewPaymentRepository(pool)
 	productRepo := productInfrastructure.NewProductRepository(pool)
 	restaurantRepo := restaurantInfrastructure.NewRestaurantRepository(pool)
 	scheduleOverrideRepo := restaurantInfrastructure.NewScheduleOverrideRepository(pool)
+	deliveryZoneRepo := restaurantInfrastructure.NewDeliveryZoneRepository(pool)
 	userRepo := userInfrastructure.NewUserRepository(pool)
 
-	// Create Mollie client (test mode)
-	mollieCfg := mollie.NewAPITestingConfig(true)
-	mollieClient, _ := mollie.NewClient(nil, mollieCfg)
+	// Payments go through the in-process fake provider
+	paymentProvider := paymentInfrastructure.NewFakeProvider("http://localhost:8080/api/v1/payments/fake", nil)
 
 	// Create services (use a mock Google client for tests; real API calls require actual API key)
 	// For now, we'll use nil for googleClient since this test doesn't call Autocomplete/Resolve
 	googleClient := (*mockGoogleClient)(nil)
 	addressService := addressApplication.NewAddressService(addressCacheRepo, googleClient, "fr")
 	couponService := couponApplication.NewCouponService(couponRepo)
-	orderService := orderApplication.NewOrderService(orderRepo, couponService)
+	orderService := orderApplication.NewOrderService(orderRepo, slotReservationRepo)
 	productService := productApplication.NewProductService(productRepo)
-	restaurantService := restaurantApplication.NewRestaurantService(restaurantRepo, scheduleOverrideRepo, true)
+	restaurantService := restaurantApplication.NewRestaurantService(restaurantRepo, scheduleOverrideRepo, deliveryZoneRepo, true)
 	userService := userApplication.NewUserService(userRepo, nil)
-	paymentService := paymentApplication.NewPaymentService(paymentRepo, *mollieClient, orderService, userService, productService)
+	pricingService := orderApplication.NewPricingService(orderRepo, productService, restaurantService, addressService, couponService)
+	etaService := orderApplication.NewEtaService(etaRepo, orderRepo, restaurantService, addressService)
+	outboxService := orderApplication.NewOutboxService(outboxRepo)
+	paymentService := paymentApplication.NewPaymentService(paymentRepo, paymentProvider, orderService, outboxService)
 
 	// Create resolver
 	return &resolver.Resolver{
 		Broker:            broker,
 		AddressService:    addressService,
 		CouponService:     couponService,
+		EtaService:        etaService,
 		OrderService:      orderService,
+		OutboxService:     outboxService,
 		PaymentService:    paymentService,
+		PricingService:    pricingService,
 		ProductService:    productService,
 		RestaurantService: restaurantService,
 		UserService:       userService,
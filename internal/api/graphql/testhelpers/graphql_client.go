@@ -39,7 +39,7 @@ func NewGraphQLTestClient(r *resolver.Resolver, jwtSecret string) *GraphQLTestCl
 		// Attach DataLoaders to context (required for resolvers)
 		ctx = productApplication.AttachDataLoaders(ctx, r.ProductService)
 		ctx = paymentApplication.AttachDataLoaders(ctx, r.PaymentService)
-		ctx = orderApplication.AttachDataLoaders(ctx, r.OrderService)
+		ctx = orderApplication.AttachDataLoaders(ctx, r.OrderService, r.EtaService)
 		ctx = userApplication.AttachDataLoaders(ctx, r.UserService)
 
 		// Extract Authorization header and set user context if present
@@ -55,24 +55,28 @@ func GetOrderStatusNotification(status orderDomain.OrderStatus, language string,
 // OTHER is intentionally omitted — we keep the generic body.
 var cancellationReasonPushLabels = map[string]map[orderDomain.OrderCancellationReason]string{
 	"fr": {
-		orderDomain.OrderCancellationReasonOutOfStock:    "rupture de stock",
-		orderDomain.OrderCancellationReasonKitchenClosed: "cuisine fermée",
-		orderDomain.OrderCancellationReasonDeliveryArea:  "hors zone de livraison",
+		orderDomain.OrderCancellationReasonOutOfStock:      "rupture de stock",
+		orderDomain.OrderCancellationReasonKitchenClosed:   "cuisine fermée",
+		orderDomain.OrderCancellationReasonDeliveryArea:    "hors zone de livraison",
+		orderDomain.OrderCancellationReasonCustomerRequest: "à votre demande",
 	},
 	"en": {
-		orderDomain.OrderCancellationReasonOutOfStock:    "out of stock",
-		orderDomain.OrderCancellationReasonKitchenClosed: "kitchen closed",
-		orderDomain.OrderCancellationReasonDeliveryArea:  "outside delivery area",
+		orderDomain.OrderCancellationReasonOutOfStock:      "out of stock",
+		orderDomain.OrderCancellationReasonKitchenClosed:   "kitchen closed",
+		orderDomain.OrderCancellationReasonDeliveryArea:    "outside delivery area",
+		orderDomain.OrderCancellationReasonCustomerRequest: "at your request",
 	},
 	"nl": {
-		orderDomain.OrderCancellationReasonOutOfStock:    "uitverkocht",
-		orderDomain.OrderCancellationReasonKitchenClosed: "keuken gesloten",
-		orderDomain.OrderCancellationReasonDeliveryArea:  "buiten bezorggebied",
+		orderDomain.OrderCancellationReasonOutOfStock:      "uitverkocht",
+		orderDomain.OrderCancellationReasonKitchenClosed:   "keuken gesloten",
+		orderDomain.OrderCancellationReasonDeliveryArea:    "buiten bezorggebied",
+		orderDomain.OrderCancellationReasonCustomerRequest: "op uw verzoek",
 	},
 	"zh": {
-		orderDomain.OrderCancellationReasonOutOfStock:    "缺货",
-		orderDomain.OrderCancellationReasonKitchenClosed: "厨房已关闭",
-		orderDomain.OrderCancellationReasonDeliveryArea:  "超出配送范围",
+		orderDomain.OrderCancellationReasonOutOfStock:      "缺货",
+		orderDomain.OrderCancellationReasonKitchenClosed:   "厨房已关闭",
+		orderDomain.OrderCancellationReasonDeliveryArea:    "超出配送范围",
+		orderDomain.OrderCancellationReasonCustomerRequest: "应您的要求",
 	},
 }
 
@@ -169,6 +173,40 @@ var newOrderTexts = map[string]*notificationText{
 	"nl": {Title: "Nieuwe bestelling", Body: "Wacht op bevestiging"},
 }
 
+// GetUnconfirmedOrderNotification returns localized push notification text
+// for admin/POS devices when an order is still awaiting confirmation.
+func GetUnconfirmedOrderNotification(language string) notificationText {
+	texts := unconfirmedOrderTexts[language]
+	if texts == nil {
+		texts = unconfirmedOrderTexts["fr"]
+	}
+	return *texts
+}
+
+var unconfirmedOrderTexts = map[string]*notificationText{
+	"fr": {Title: "Commande non confirmée", Body: "Une commande attend toujours votre confirmation"},
+	"en": {Title: "Unconfirmed order", Body: "An order is still awaiting your confirmation"},
+	"zh": {Title: "订单未确认", Body: "有订单仍在等待您的确认"},
+	"nl": {Title: "Onbevestigde bestelling", Body: "Een bestelling wacht nog op uw bevestiging"},
+}
+
+// GetCustomerCancelledNotification returns localized push notification text
+// for admin/POS devices when a customer cancels their own order.
+func GetCustomerCancelledNotification(language string) notificationText {
+	texts := customerCancelledTexts[language]
+	if texts == nil {
+		texts = customerCancelledTexts["fr"]
+	}
+	return *texts
+}
+
+var customerCancelledTexts = map[string]*notificationText{
+	"fr": {Title: "Commande annulée", Body: "Le client a annulé sa commande"},
+	"en": {Title: "Order cancelled", Body: "The customer cancelled their order"},
+	"zh": {Title: "订单已取消", Body: "顾客已取消订单"},
+	"nl": {Title: "Bestelling geannuleerd", Body: "De klant heeft de bestelling geannuleerd"},
+}
+
 var readyTimeUpdatedTexts = map[string]*notificationText{
 	"fr": {Title: "Heure de retrait mise à jour", Body: "Nouvelle heure estimée : %s."},
 	"en": {Title: "Ready time updated", Body: "New estimated ready time: %s."},
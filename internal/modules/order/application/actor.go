@@ -0,0 +1,28 @@
+package application
+
+import (
+	"context"
+
+	"tsb-service/internal/modules/order/domain"
+	"tsb-service/pkg/utils"
+)
+
+// actorFromContext attributes a change to the caller: a named job first, then
+// a POS device, a dashboard user or the customer. A context without a user is
+// an unnamed system task.
+func actorFromContext(ctx context.Context) domain.Actor {
+	if job := utils.GetJob(ctx); job != "" {
+		return domain.Actor{Type: domain.ActorSystem, ID: job}
+	}
+	userID := utils.GetUserID(ctx)
+	switch {
+	case userID == "":
+		return domain.Actor{Type: domain.ActorSystem}
+	case utils.GetIsPOS(ctx):
+		return domain.Actor{Type: domain.ActorPOS, ID: userID}
+	case utils.GetIsAdmin(ctx):
+		return domain.Actor{Type: domain.ActorStaff, ID: userID}
+	default:
+		return domain.Actor{Type: domain.ActorCustomer, ID: userID}
+	}
+}
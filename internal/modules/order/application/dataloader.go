@@ -2,6 +2,7 @@ package application
 
 import (
 	"context"
+	"time"
 
 	"tsb-service/internal/modules/order/domain"
 	"tsb-service/pkg/db"
@@ -12,6 +13,7 @@ type contextKey string
 const (
 	userOrderLoaderKey contextKey = "userOrderLoader"
 	orderItemLoaderKey contextKey = "orderItemLoader"
+	readyTimeLoaderKey contextKey = "suggestedReadyTimeLoader"
 )
 
 type UserOrderLoader struct {
@@ -22,10 +24,15 @@ type OrderItemLoader struct {
 	Loader *db.TypedLoader[*domain.OrderProductRaw]
 }
 
+type SuggestedReadyTimeLoader struct {
+	Loader *db.TypedLoader[time.Time]
+}
+
 // AttachDataLoaders attaches all necessary DataLoaders for products to the context.
-func AttachDataLoaders(ctx context.Context, os OrderService) context.Context {
+func AttachDataLoaders(ctx context.Context, os OrderService, es EtaService) context.Context {
 	ctx = context.WithValue(ctx, userOrderLoaderKey, NewUserOrderLoader(os))
 	ctx = context.WithValue(ctx, orderItemLoaderKey, NewOrderItemLoader(os))
+	ctx = context.WithValue(ctx, readyTimeLoaderKey, NewSuggestedReadyTimeLoader(es))
 
 	return ctx
 }
@@ -54,6 +61,19 @@ func NewOrderItemLoader(os OrderService) *OrderItemLoader {
 	}
 }
 
+// NewSuggestedReadyTimeLoader creates a new Order -> suggested ready time
+// loader, so the staff dashboard computes its suggestions in one batch.
+func NewSuggestedReadyTimeLoader(es EtaService) *SuggestedReadyTimeLoader {
+	return &SuggestedReadyTimeLoader{
+		Loader: db.NewTypedLoader[time.Time](
+			func(ctx context.Context, orderIDs []string) (map[string][]time.Time, error) {
+				return es.BatchGetSuggestedReadyTimes(ctx, orderIDs)
+			},
+			"failed to fetch suggested ready times",
+		),
+	}
+}
+
 // GetUserOrderLoader reads the loader from context.
 func GetUserOrderLoader(ctx context.Context) *UserOrderLoader {
 	loader, ok := ctx.Value(userOrderLoaderKey).(*UserOrderLoader)
@@ -71,3 +91,12 @@ func GetOrderItemLoader(ctx context.Context) *OrderItemLoader {
 	}
 	return loader
 }
+
+// GetSuggestedReadyTimeLoader reads the loader from context.
+func GetSuggestedReadyTimeLoader(ctx context.Context) *SuggestedReadyTimeLoader {
+	loader, ok := ctx.Value(readyTimeLoaderKey).(*SuggestedReadyTimeLoader)
+	if !ok {
+		return nil
+	}
+	return loader
+}
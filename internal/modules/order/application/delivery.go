@@ -0,0 +1,79 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tsb-service/internal/modules/order/domain"
+)
+
+type DeliveryService interface {
+	// AssignDriver sets the driver of a delivery order, replacing any
+	// previous one. It returns domain.ErrDriverNotAssignable for other order
+	// types and finished orders.
+	AssignDriver(ctx context.Context, orderID, driverID uuid.UUID) (*domain.Delivery, error)
+	// GetDelivery returns nil when the order has no driver and was not
+	// dispatched yet.
+	GetDelivery(ctx context.Context, orderID uuid.UUID) (*domain.Delivery, error)
+	// ReportLocation records the driver's position on the orders they are
+	// delivering and returns it once per order, for the caller to publish.
+	// It returns domain.ErrInvalidLocation for impossible coordinates.
+	ReportLocation(ctx context.Context, driverID uuid.UUID, latitude, longitude float64, accuracy, heading *float64) ([]*domain.DriverLocation, error)
+	// GetDriverOrders returns the unfinished orders assigned to the driver.
+	GetDriverOrders(ctx context.Context, driverID uuid.UUID) ([]*domain.Order, error)
+}
+
+type deliveryService struct {
+	repo      domain.DeliveryRepository
+	orderRepo domain.OrderRepository
+}
+
+func NewDeliveryService(repo domain.DeliveryRepository, orderRepo domain.OrderRepository) DeliveryService {
+	return &deliveryService{repo: repo, orderRepo: orderRepo}
+}
+
+func (s *deliveryService) AssignDriver(ctx context.Context, orderID, driverID uuid.UUID) (*domain.Delivery, error) {
+	order, _, err := s.orderRepo.FindByID(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load order: %w", err)
+	}
+	if err := domain.CheckDriverAssignable(order); err != nil {
+		return nil, err
+	}
+	return s.repo.AssignDriver(ctx, orderID, driverID)
+}
+
+func (s *deliveryService) GetDelivery(ctx context.Context, orderID uuid.UUID) (*domain.Delivery, error) {
+	return s.repo.FindByOrderID(ctx, orderID)
+}
+
+func (s *deliveryService) ReportLocation(ctx context.Context, driverID uuid.UUID, latitude, longitude float64, accuracy, heading *float64) ([]*domain.DriverLocation, error) {
+	loc := domain.DriverLocation{
+		Latitude:   latitude,
+		Longitude:  longitude,
+		Accuracy:   accuracy,
+		Heading:    heading,
+		RecordedAt: time.Now().UTC(),
+	}
+	if err := loc.Validate(); err != nil {
+		return nil, err
+	}
+	deliveries, err := s.repo.RecordLocation(ctx, driverID, loc)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*domain.DriverLocation, 0, len(deliveries))
+	for _, d := range deliveries {
+		if l := d.Location(); l != nil {
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
+
+func (s *deliveryService) GetDriverOrders(ctx context.Context, driverID uuid.UUID) ([]*domain.Order, error) {
+	return s.repo.FindDriverOrders(ctx, driverID)
+}
@@ -0,0 +1,152 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	addressApplication "tsb-service/internal/modules/address/application"
+	"tsb-service/internal/modules/order/domain"
+	restaurantApplication "tsb-service/internal/modules/restaurant/application"
+)
+
+type EtaService interface {
+	// Suggest estimates the ready time of the order from the current kitchen
+	// load, its size and, for a delivery, the cached route duration (see
+	// domain.SuggestReadyTime). Nothing is recorded: the estimate of an order
+	// being confirmed is recorded with its update (see OrderService.UpdateOrder).
+	Suggest(ctx context.Context, orderID uuid.UUID) (*domain.EtaEstimate, error)
+	// BatchGetSuggestedReadyTimes returns, per order, the time suggested at
+	// confirmation, or a live suggestion while the order is still pending.
+	// Orders with neither are left out.
+	BatchGetSuggestedReadyTimes(ctx context.Context, orderIDs []string) (map[string][]time.Time, error)
+}
+
+type etaService struct {
+	repo              domain.EtaRepository
+	orderRepo         domain.OrderRepository
+	restaurantService restaurantApplication.RestaurantService
+	addressService    addressApplication.AddressService
+}
+
+func NewEtaService(
+	repo domain.EtaRepository,
+	orderRepo domain.OrderRepository,
+	restaurantService restaurantApplication.RestaurantService,
+	addressService addressApplication.AddressService,
+) EtaService {
+	return &etaService{
+		repo:              repo,
+		orderRepo:         orderRepo,
+		restaurantService: restaurantService,
+		addressService:    addressService,
+	}
+}
+
+func (s *etaService) Suggest(ctx context.Context, orderID uuid.UUID) (*domain.EtaEstimate, error) {
+	order, items, err := s.orderRepo.FindByID(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load order: %w", err)
+	}
+	preparationMinutes, err := s.preparationMinutes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	load, err := s.repo.KitchenLoad(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	itemCount := 0
+	if items != nil {
+		for _, item := range *items {
+			itemCount += int(item.Quantity)
+		}
+	}
+	return s.suggest(ctx, order, itemCount, preparationMinutes, load, time.Now())
+}
+
+func (s *etaService) preparationMinutes(ctx context.Context) (int, error) {
+	config, err := s.restaurantService.GetConfig(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get restaurant config: %w", err)
+	}
+	return config.PreparationMinutes, nil
+}
+
+func (s *etaService) suggest(ctx context.Context, order *domain.Order, itemCount, preparationMinutes, load int, now time.Time) (*domain.EtaEstimate, error) {
+	e := &domain.EtaEstimate{
+		OrderID:            order.ID,
+		PreparationMinutes: preparationMinutes,
+		KitchenLoad:        load,
+		ItemCount:          itemCount,
+	}
+	// The route duration was cached when the order was priced; a miss only
+	// leaves the travel time out.
+	if order.OrderType == domain.OrderTypeDelivery && order.AddressPlaceID != nil {
+		addr, err := s.addressService.GetByPlaceID(ctx, *order.AddressPlaceID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get address: %w", err)
+		}
+		if addr != nil && addr.Duration != nil {
+			e.TravelSeconds = *addr.Duration
+		}
+	}
+
+	e.SuggestedReadyTime = domain.SuggestReadyTime(order, e, now)
+	return e, nil
+}
+
+func (s *etaService) BatchGetSuggestedReadyTimes(ctx context.Context, orderIDs []string) (map[string][]time.Time, error) {
+	recorded, err := s.repo.FindByOrderIDs(ctx, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string][]time.Time, len(orderIDs))
+	var rest []string
+	for _, id := range orderIDs {
+		if e, ok := recorded[id]; ok {
+			out[id] = []time.Time{e.SuggestedReadyTime}
+		} else {
+			rest = append(rest, id)
+		}
+	}
+
+	pending, err := s.repo.FindPendingOrders(ctx, rest)
+	if err != nil || len(pending) == 0 {
+		return out, err
+	}
+	// Pending orders are not in the kitchen load, so one count and one
+	// config read serve them all.
+	preparationMinutes, err := s.preparationMinutes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	load, err := s.repo.KitchenLoad(ctx, uuid.Nil)
+	if err != nil {
+		return nil, err
+	}
+	pendingIDs := make([]string, len(pending))
+	for i, o := range pending {
+		pendingIDs[i] = o.ID.String()
+	}
+	items, err := s.orderRepo.FindByOrderIDs(ctx, pendingIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load order items: %w", err)
+	}
+
+	now := time.Now()
+	for _, o := range pending {
+		itemCount := 0
+		for _, item := range items[o.ID.String()] {
+			itemCount += int(item.Quantity)
+		}
+		e, err := s.suggest(ctx, o, itemCount, preparationMinutes, load, now)
+		if err != nil {
+			return nil, err
+		}
+		out[o.ID.String()] = []time.Time{e.SuggestedReadyTime}
+	}
+	return out, nil
+}
@@ -0,0 +1,83 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tsb-service/internal/modules/order/domain"
+)
+
+// IdempotencyService makes createOrder safe to retry: a request carrying an
+// idempotency key the user already used gets the order created the first
+// time instead of a duplicate.
+type IdempotencyService interface {
+	// Begin claims key for userID. It returns uuid.Nil when the caller holds
+	// the claim and must create the order under it (see
+	// domain.OrderWrite.IdempotencyKey) or Release it; or
+	// the order a previous request with the same fingerprint created. While
+	// that request is still running Begin waits for it, up to a few seconds.
+	Begin(ctx context.Context, userID uuid.UUID, key, fingerprint string) (uuid.UUID, error)
+	Release(ctx context.Context, userID uuid.UUID, key string) error
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
+const (
+	// idempotencyWait bounds how long a retry waits for the request that
+	// first used its key. Creating an online order includes the Mollie call,
+	// so this covers the usual case without holding the client much longer.
+	idempotencyWait = 10 * time.Second
+	idempotencyPoll = 250 * time.Millisecond
+)
+
+type idempotencyService struct {
+	repo domain.IdempotencyRepository
+	wait time.Duration
+	poll time.Duration
+}
+
+func NewIdempotencyService(repo domain.IdempotencyRepository) IdempotencyService {
+	return &idempotencyService{repo: repo, wait: idempotencyWait, poll: idempotencyPoll}
+}
+
+func (s *idempotencyService) Begin(ctx context.Context, userID uuid.UUID, key, fingerprint string) (uuid.UUID, error) {
+	if len(key) > domain.MaxIdempotencyKeyLength {
+		return uuid.Nil, domain.ErrIdempotencyKeyInvalid
+	}
+	deadline := time.Now().Add(s.wait)
+	for {
+		existing, err := s.repo.Claim(ctx, userID, key, fingerprint, domain.IdempotencyKeyTTL, domain.IdempotencyClaimLease)
+		var orderID uuid.UUID
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			// The holder released the key between our claim and the read:
+			// claim it again, as after any wait.
+			err = domain.ErrIdempotencyKeyInFlight
+		case err != nil:
+			return uuid.Nil, err
+		case existing == nil:
+			return uuid.Nil, nil
+		default:
+			orderID, err = existing.Replay(fingerprint)
+		}
+		if !errors.Is(err, domain.ErrIdempotencyKeyInFlight) || time.Now().After(deadline) {
+			return orderID, err
+		}
+		select {
+		case <-ctx.Done():
+			return uuid.Nil, ctx.Err()
+		case <-time.After(s.poll):
+		}
+	}
+}
+
+func (s *idempotencyService) Release(ctx context.Context, userID uuid.UUID, key string) error {
+	return s.repo.Release(ctx, userID, key)
+}
+
+func (s *idempotencyService) PurgeExpired(ctx context.Context) (int64, error) {
+	return s.repo.DeleteExpired(ctx)
+}
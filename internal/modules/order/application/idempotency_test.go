@@ -0,0 +1,98 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tsb-service/internal/modules/order/domain"
+)
+
+// fakeIdempotencyRepo returns its claims in turn, one per Claim call; a nil
+// claim means the key was free. A non-nil err is returned by every call.
+type fakeIdempotencyRepo struct {
+	domain.IdempotencyRepository
+	claims []*domain.IdempotencyKey
+	err    error
+	calls  int
+}
+
+func (f *fakeIdempotencyRepo) Claim(context.Context, uuid.UUID, string, string, time.Duration, time.Duration) (*domain.IdempotencyKey, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	if len(f.claims) == 0 {
+		return nil, nil
+	}
+	k := f.claims[0]
+	if len(f.claims) > 1 {
+		f.claims = f.claims[1:]
+	}
+	return k, nil
+}
+
+func TestIdempotencyBegin(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	orderID := uuid.New()
+
+	t.Run("free key is claimed", func(t *testing.T) {
+		svc := &idempotencyService{repo: &fakeIdempotencyRepo{}, wait: time.Second, poll: time.Millisecond}
+		got, err := svc.Begin(ctx, userID, "k", "fp")
+		if err != nil || got != uuid.Nil {
+			t.Fatalf("Begin = %s, %v; want a fresh claim", got, err)
+		}
+	})
+
+	t.Run("waits for the first request to finish", func(t *testing.T) {
+		repo := &fakeIdempotencyRepo{claims: []*domain.IdempotencyKey{
+			{Fingerprint: "fp"},
+			{Fingerprint: "fp", OrderID: &orderID},
+		}}
+		svc := &idempotencyService{repo: repo, wait: time.Second, poll: time.Millisecond}
+		got, err := svc.Begin(ctx, userID, "k", "fp")
+		if err != nil || got != orderID {
+			t.Fatalf("Begin = %s, %v; want the replayed order %s", got, err, orderID)
+		}
+		if repo.calls != 2 {
+			t.Fatalf("claimed %d times, want 2", repo.calls)
+		}
+	})
+
+	t.Run("gives up on a request still in flight", func(t *testing.T) {
+		repo := &fakeIdempotencyRepo{claims: []*domain.IdempotencyKey{{Fingerprint: "fp"}}}
+		svc := &idempotencyService{repo: repo, wait: 5 * time.Millisecond, poll: time.Millisecond}
+		if _, err := svc.Begin(ctx, userID, "k", "fp"); !errors.Is(err, domain.ErrIdempotencyKeyInFlight) {
+			t.Fatalf("err = %v, want ErrIdempotencyKeyInFlight", err)
+		}
+	})
+
+	t.Run("waits between claims of a key released under it", func(t *testing.T) {
+		repo := &fakeIdempotencyRepo{err: sql.ErrNoRows}
+		svc := &idempotencyService{repo: repo, wait: 20 * time.Millisecond, poll: 5 * time.Millisecond}
+		if _, err := svc.Begin(ctx, userID, "k", "fp"); !errors.Is(err, domain.ErrIdempotencyKeyInFlight) {
+			t.Fatalf("err = %v, want ErrIdempotencyKeyInFlight", err)
+		}
+		if repo.calls > 10 {
+			t.Fatalf("claimed %d times in 20ms, want a poll between claims", repo.calls)
+		}
+	})
+
+	t.Run("rejects keys that are too long", func(t *testing.T) {
+		repo := &fakeIdempotencyRepo{}
+		svc := &idempotencyService{repo: repo, wait: time.Second, poll: time.Millisecond}
+		key := strings.Repeat("k", domain.MaxIdempotencyKeyLength+1)
+		if _, err := svc.Begin(ctx, userID, key, "fp"); !errors.Is(err, domain.ErrIdempotencyKeyInvalid) {
+			t.Fatalf("err = %v, want ErrIdempotencyKeyInvalid", err)
+		}
+		if repo.calls != 0 {
+			t.Fatal("an invalid key must not be claimed")
+		}
+	})
+}
@@ -0,0 +1,181 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"tsb-service/internal/modules/order/domain"
+	"tsb-service/pkg/logging"
+)
+
+// OutboxHandler delivers one outbox message. A returned error schedules a
+// retry with backoff; handlers must therefore be safe to run again.
+type OutboxHandler func(ctx context.Context, msg *domain.OutboxMessage) error
+
+// OutboxService delivers the side effects order changes write to the outbox.
+type OutboxService interface {
+	// Handle registers the handler for a message kind. Messages of a kind
+	// without handler are dead-lettered.
+	Handle(kind domain.OutboxKind, handler OutboxHandler)
+	// Enqueue adds follow-up messages outside of an order change.
+	Enqueue(ctx context.Context, msgs ...*domain.OutboxMessage) error
+	// Notify wakes the worker after a commit so fresh messages go out
+	// without waiting for the next poll.
+	Notify()
+	// DispatchDue delivers the messages that are due and returns how many
+	// were attempted.
+	DispatchDue(ctx context.Context) (int, error)
+	// Run dispatches every interval and on Notify until ctx is done.
+	Run(ctx context.Context, interval time.Duration)
+
+	ListMessages(ctx context.Context, status *domain.OutboxStatus, limit int) ([]*domain.OutboxMessage, error)
+	// RetryMessage makes a dead or pending message due now with a fresh
+	// attempt budget.
+	RetryMessage(ctx context.Context, id uuid.UUID) (*domain.OutboxMessage, error)
+	// PurgeDelivered deletes the messages delivered more than olderThan ago
+	// and returns how many were deleted.
+	PurgeDelivered(ctx context.Context, olderThan time.Duration) (int64, error)
+}
+
+const (
+	outboxBatchSize = 20
+	// outboxLease keeps a claimed message away from other workers while a
+	// slow handler (email, Mollie) runs.
+	outboxLease = 2 * time.Minute
+)
+
+type outboxService struct {
+	repo   domain.OutboxRepository
+	wake   chan struct{}
+	mu     sync.RWMutex
+	routes map[domain.OutboxKind]OutboxHandler
+}
+
+func NewOutboxService(repo domain.OutboxRepository) OutboxService {
+	return &outboxService{
+		repo:   repo,
+		wake:   make(chan struct{}, 1),
+		routes: make(map[domain.OutboxKind]OutboxHandler),
+	}
+}
+
+func (s *outboxService) Handle(kind domain.OutboxKind, handler OutboxHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes[kind] = handler
+}
+
+func (s *outboxService) Enqueue(ctx context.Context, msgs ...*domain.OutboxMessage) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	if err := s.repo.Enqueue(ctx, msgs...); err != nil {
+		return err
+	}
+	s.Notify()
+	return nil
+}
+
+func (s *outboxService) Notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *outboxService) DispatchDue(ctx context.Context) (int, error) {
+	msgs, err := s.repo.ClaimDue(ctx, outboxBatchSize, outboxLease)
+	if err != nil {
+		return 0, err
+	}
+	for _, m := range msgs {
+		s.deliver(ctx, m)
+	}
+	return len(msgs), nil
+}
+
+// deliver runs the handler of one claimed message and records the outcome.
+// Attempts was already incremented by the claim.
+func (s *outboxService) deliver(ctx context.Context, m *domain.OutboxMessage) {
+	log := logging.FromContext(ctx).With(
+		zap.String("outbox_id", m.ID.String()),
+		zap.String("order_id", m.OrderID.String()),
+		zap.String("kind", string(m.Kind)),
+		zap.Int("attempt", m.Attempts),
+	)
+
+	s.mu.RLock()
+	handler, ok := s.routes[m.Kind]
+	s.mu.RUnlock()
+
+	var err error
+	if !ok {
+		err = fmt.Errorf("no handler registered for %s", m.Kind)
+	} else {
+		err = handler(ctx, m)
+	}
+	if err == nil {
+		if markErr := s.repo.MarkDelivered(ctx, m.ID); markErr != nil {
+			log.Error("failed to mark outbox message delivered", zap.Error(markErr))
+		}
+		return
+	}
+
+	var retryAt *time.Time
+	if ok && m.Attempts < domain.OutboxMaxAttempts {
+		at := time.Now().Add(domain.OutboxBackoff(m.Attempts))
+		retryAt = &at
+		log.Warn("outbox delivery failed, will retry", zap.Time("retry_at", at), zap.Error(err))
+	} else {
+		log.Error("outbox delivery failed, dead-lettered", zap.Error(err))
+	}
+	if markErr := s.repo.MarkFailed(ctx, m.ID, err.Error(), retryAt); markErr != nil {
+		log.Error("failed to record outbox delivery failure", zap.Error(markErr))
+	}
+}
+
+func (s *outboxService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// Drain full batches back to back; a short batch means nothing is due.
+		for {
+			n, err := s.DispatchDue(ctx)
+			if err != nil {
+				logging.FromContext(ctx).Warn("outbox dispatch failed", zap.Error(err))
+				break
+			}
+			if n < outboxBatchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+func (s *outboxService) ListMessages(ctx context.Context, status *domain.OutboxStatus, limit int) ([]*domain.OutboxMessage, error) {
+	return s.repo.List(ctx, status, limit)
+}
+
+func (s *outboxService) RetryMessage(ctx context.Context, id uuid.UUID) (*domain.OutboxMessage, error) {
+	msg, err := s.repo.Retry(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.Notify()
+	return msg, nil
+}
+
+func (s *outboxService) PurgeDelivered(ctx context.Context, olderThan time.Duration) (int64, error) {
+	return s.repo.DeleteDelivered(ctx, time.Now().Add(-olderThan))
+}
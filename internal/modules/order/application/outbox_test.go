@@ -0,0 +1,83 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tsb-service/internal/modules/order/domain"
+)
+
+// fakeOutboxRepo hands out its due messages once and records the outcome of
+// each delivery.
+type fakeOutboxRepo struct {
+	domain.OutboxRepository
+	due       []*domain.OutboxMessage
+	delivered []uuid.UUID
+	failed    map[uuid.UUID]*time.Time
+}
+
+func (f *fakeOutboxRepo) ClaimDue(context.Context, int, time.Duration) ([]*domain.OutboxMessage, error) {
+	due := f.due
+	f.due = nil
+	for _, m := range due {
+		m.Attempts++
+	}
+	return due, nil
+}
+
+func (f *fakeOutboxRepo) MarkDelivered(_ context.Context, id uuid.UUID) error {
+	f.delivered = append(f.delivered, id)
+	return nil
+}
+
+func (f *fakeOutboxRepo) MarkFailed(_ context.Context, id uuid.UUID, _ string, retryAt *time.Time) error {
+	if f.failed == nil {
+		f.failed = make(map[uuid.UUID]*time.Time)
+	}
+	f.failed[id] = retryAt
+	return nil
+}
+
+func TestOutboxDispatchDue(t *testing.T) {
+	orderID := uuid.New()
+	msgs := domain.NewOutboxMessages(orderID, domain.OutboxEvent{Status: domain.OrderStatusCanceled},
+		[]domain.OutboxKind{domain.OutboxEmailOrderCanceled, domain.OutboxPushStatusAlert, domain.OutboxRefundFullPayment, domain.OutboxPushLiveUpdate})
+	sent, flaky, exhausted, unrouted := msgs[0], msgs[1], msgs[2], msgs[3]
+	exhausted.Attempts = domain.OutboxMaxAttempts - 1
+
+	repo := &fakeOutboxRepo{due: msgs}
+	svc := NewOutboxService(repo)
+	svc.Handle(domain.OutboxEmailOrderCanceled, func(_ context.Context, m *domain.OutboxMessage) error {
+		if ev, err := m.Event(); err != nil || ev.Status != domain.OrderStatusCanceled {
+			t.Errorf("event = %+v, %v; want the CANCELLED status", ev, err)
+		}
+		return nil
+	})
+	svc.Handle(domain.OutboxPushStatusAlert, func(context.Context, *domain.OutboxMessage) error {
+		return errors.New("apns unavailable")
+	})
+	svc.Handle(domain.OutboxRefundFullPayment, func(context.Context, *domain.OutboxMessage) error {
+		return errors.New("mollie unavailable")
+	})
+
+	n, err := svc.DispatchDue(context.Background())
+	if err != nil || n != 4 {
+		t.Fatalf("DispatchDue = %d, %v; want 4 attempted", n, err)
+	}
+	if len(repo.delivered) != 1 || repo.delivered[0] != sent.ID {
+		t.Errorf("delivered = %v, want only the email", repo.delivered)
+	}
+	if retryAt, ok := repo.failed[flaky.ID]; !ok || retryAt == nil {
+		t.Errorf("first failure should be retried, got %v", retryAt)
+	}
+	if retryAt, ok := repo.failed[exhausted.ID]; !ok || retryAt != nil {
+		t.Errorf("last attempt should be dead-lettered, got retry at %v", retryAt)
+	}
+	if retryAt, ok := repo.failed[unrouted.ID]; !ok || retryAt != nil {
+		t.Errorf("message without handler should be dead-lettered, got retry at %v", retryAt)
+	}
+}
@@ -0,0 +1,508 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	addressApplication "tsb-service/internal/modules/address/application"
+	couponApplication "tsb-service/internal/modules/coupon/application"
+	"tsb-service/internal/modules/order/domain"
+	productApplication "tsb-service/internal/modules/product/application"
+	productDomain "tsb-service/internal/modules/product/domain"
+	restaurantApplication "tsb-service/internal/modules/restaurant/application"
+	restaurantDomain "tsb-service/internal/modules/restaurant/domain"
+	"tsb-service/pkg/money"
+)
+
+// PricingService prices carts. priceCart and createOrder both go through it so
+// the quoted total is exactly the charged total.
+type PricingService interface {
+	// PriceCart validates and prices the cart without creating anything.
+	// Validation failures are reported on Quote.Issues; the error is only set
+	// for infrastructure failures. The one write is the coupon brute-force
+	// counter, which must guard quotes as much as orders.
+	PriceCart(ctx context.Context, cart domain.Cart) (*domain.Quote, error)
+}
+
+type pricingService struct {
+	orderRepo         domain.OrderRepository
+	productService    productApplication.ProductService
+	restaurantService restaurantApplication.RestaurantService
+	addressService    addressApplication.AddressService
+	couponService     couponApplication.CouponService
+}
+
+func NewPricingService(
+	orderRepo domain.OrderRepository,
+	productService productApplication.ProductService,
+	restaurantService restaurantApplication.RestaurantService,
+	addressService addressApplication.AddressService,
+	couponService couponApplication.CouponService,
+) PricingService {
+	return &pricingService{
+		orderRepo:         orderRepo,
+		productService:    productService,
+		restaurantService: restaurantService,
+		addressService:    addressService,
+		couponService:     couponService,
+	}
+}
+
+func (s *pricingService) PriceCart(ctx context.Context, cart domain.Cart) (*domain.Quote, error) {
+	q := &domain.Quote{}
+
+	if err := s.priceItems(ctx, cart, q); err != nil {
+		return nil, err
+	}
+
+	pricing, err := s.restaurantService.GetPricing(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pricing rules: %w", err)
+	}
+	minimum := pricing.PickupMinimum
+	if cart.OrderType == domain.OrderTypeDelivery {
+		minimum = pricing.DeliveryMinimum
+		zoneMinimum, err := s.priceDelivery(ctx, cart, pricing, q)
+		if err != nil {
+			return nil, err
+		}
+		if zoneMinimum != nil {
+			minimum = *zoneMinimum
+		}
+	}
+	// A dine-in round adds to the table's bill, which is settled at the end of
+	// the meal: no minimum, coupon or online payment per round.
+	if cart.OrderType == domain.OrderTypeDineIn {
+		minimum = decimal.Zero
+		if cart.IsOnlinePayment {
+			q.AddIssue("isOnlinePayment", "dine-in orders are paid at the table when the bill is settled")
+		}
+		if cart.CouponCode != nil && *cart.CouponCode != "" {
+			q.AddIssue("couponCode", "coupons cannot be used for dine-in orders")
+			cart.CouponCode = nil
+		}
+	}
+	if len(q.Lines) > 0 && q.ItemsTotal.LessThan(minimum) {
+		q.AddIssue("items", "minimum order amount for %s is %s", strings.ToLower(string(cart.OrderType)), minimum.StringFixed(2))
+	}
+
+	// Takeaway discount for PICKUP orders: the pricing percentage on
+	// discountable items, once the subtotal reaches the threshold. The raw
+	// amount is rounded to 0,10 € so the customer sees a clean multiple of 10
+	// cents on every surface (cart, receipt, Mollie).
+	subtotal := q.ItemsTotal.Add(q.DeliveryFee)
+	if cart.OrderType == domain.OrderTypePickUp && pricing.TakeawayDiscountPercent.IsPositive() &&
+		subtotal.GreaterThanOrEqual(pricing.TakeawayDiscountThreshold) {
+		rate := pricing.TakeawayDiscountRate()
+		takeaway := decimal.Zero
+		for _, l := range q.Lines {
+			if l.Discountable {
+				takeaway = takeaway.Add(l.TotalPrice.Mul(rate))
+			}
+		}
+		q.TakeawayDiscount = money.RoundToNearest10Cents(takeaway)
+		if q.TakeawayDiscount.IsPositive() {
+			percent := pricing.TakeawayDiscountPercent
+			q.TakeawayDiscountPercent = &percent
+		}
+	}
+
+	// Coupon discount (stacks with the takeaway discount).
+	if cart.CouponCode != nil && *cart.CouponCode != "" {
+		if err := s.priceCoupon(ctx, cart, subtotal, q); err != nil {
+			return nil, err
+		}
+	}
+
+	// Combined discounts never exceed the order total.
+	q.TakeawayDiscount, q.CouponDiscount = domain.CapDiscounts(subtotal, q.TakeawayDiscount, q.CouponDiscount)
+
+	// The transaction fee covers PSP costs and only applies to online payments.
+	if cart.IsOnlinePayment {
+		q.TransactionFee = pricing.TransactionFee
+	}
+
+	raw := q.RawItems()
+	q.Total = domain.ComputeTotal(q.ItemsTotal, q.DeliveryFee, q.TakeawayDiscount, q.CouponDiscount, q.TransactionFee)
+	q.VatBreakdown = domain.ComputeVatBreakdown(raw)
+	priceTip(cart, q)
+	return q, nil
+}
+
+// priceTip sets the tip the customer added, as a fixed amount or a percentage
+// of the total. Tips are only taken with an online payment, and never exceed
+// the order total.
+func priceTip(cart domain.Cart, q *domain.Quote) {
+	field := "tipAmount"
+	if cart.TipPercent != nil {
+		field = "tipPercent"
+	}
+	var tip decimal.Decimal
+	switch {
+	case cart.TipAmount == nil && cart.TipPercent == nil:
+		return
+	case cart.TipAmount != nil && cart.TipPercent != nil:
+		q.AddIssue("tipPercent", "choose either a tip amount or a tip percentage")
+		return
+	case cart.TipPercent != nil:
+		if *cart.TipPercent < 0 || *cart.TipPercent > domain.MaxTipPercent {
+			q.AddIssue(field, "tip percentage must be between 0 and %d", domain.MaxTipPercent)
+			return
+		}
+		tip = domain.TipFromPercent(q.Total, *cart.TipPercent)
+	default:
+		amount, err := decimal.NewFromString(strings.TrimSpace(*cart.TipAmount))
+		if err != nil {
+			q.AddIssue(field, "invalid tip amount")
+			return
+		}
+		if amount.IsNegative() {
+			q.AddIssue(field, "tip amount must not be negative")
+			return
+		}
+		tip = amount.Round(2)
+	}
+	if tip.IsZero() {
+		return
+	}
+	if !cart.IsOnlinePayment {
+		q.AddIssue(field, "tips can only be added to orders paid online")
+		return
+	}
+	if tip.GreaterThan(q.Total) {
+		q.AddIssue(field, "tip cannot exceed the order total")
+		return
+	}
+	q.Tip = tip
+}
+
+// priceItems validates every cart line against the catalogue (choice groups,
+// lunch-only products) and prices it with its modifiers and VAT rate. Invalid
+// lines are reported and left out of the totals.
+func (s *pricingService) priceItems(ctx context.Context, cart domain.Cart, q *domain.Quote) error {
+	if len(cart.Items) == 0 {
+		q.AddIssue("items", "order must contain at least one item")
+		return nil
+	}
+	if len(cart.Items) > 50 {
+		q.AddIssue("items", "order cannot contain more than 50 different items")
+		return nil
+	}
+
+	ids := make([]string, len(cart.Items))
+	for i, item := range cart.Items {
+		ids[i] = item.ProductID.String()
+	}
+	products, err := s.productService.GetProductsByIDs(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve products: %w", err)
+	}
+	productMap := make(map[uuid.UUID]*productDomain.ProductOrderDetails, len(products))
+	for _, p := range products {
+		productMap[p.ID] = p
+	}
+
+	serviceType := cart.OrderType.ServiceType()
+
+	lp := &linePricer{
+		s:                    s,
+		ctx:                  ctx,
+		language:             cart.Language,
+		productMap:           productMap,
+		choiceCache:          make(map[uuid.UUID]*productDomain.ProductChoice),
+		groupsByProductCache: make(map[uuid.UUID][]*productDomain.ProductChoiceGroup),
+	}
+	for i, item := range cart.Items {
+		field := fmt.Sprintf("items.%d", i)
+		p, ok := productMap[item.ProductID]
+		if !ok {
+			q.AddIssue(field, "product %s not found", item.ProductID)
+			continue
+		}
+		if !cart.AllowLunchOnly && p.IsLunchOnly {
+			q.AddIssue(field, "product %q is only available for a weekday lunch slot", lp.label(p.ID))
+			continue
+		}
+		line, err := lp.price(item)
+		var issue domain.PricingIssue
+		if errors.As(err, &issue) {
+			q.AddIssue(field, "%s", issue.Message)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		line.Index = i
+		line.VatRateApplied = decimal.NewFromFloat(p.VatCategory.VatRatePercent(serviceType))
+		line.Product = domain.Product{
+			ID:           p.ID,
+			Code:         p.Code,
+			CategoryName: p.CategoryName,
+			Name:         p.Name,
+			VatCategory:  string(p.VatCategory),
+		}
+		line.Discountable = p.IsDiscountable
+		q.Lines = append(q.Lines, *line)
+		q.ItemsTotal = q.ItemsTotal.Add(line.TotalPrice)
+	}
+	return nil
+}
+
+// linePricer holds the per-cart lookups shared by every line.
+type linePricer struct {
+	s                    *pricingService
+	ctx                  context.Context
+	language             string
+	productMap           map[uuid.UUID]*productDomain.ProductOrderDetails
+	choiceCache          map[uuid.UUID]*productDomain.ProductChoice
+	groupsByProductCache map[uuid.UUID][]*productDomain.ProductChoiceGroup
+}
+
+func (lp *linePricer) label(id uuid.UUID) string {
+	if p, ok := lp.productMap[id]; ok && p.Name != "" {
+		return p.Name
+	}
+	return id.String()
+}
+
+func (lp *linePricer) loadChoice(id uuid.UUID) (*productDomain.ProductChoice, error) {
+	if choice, ok := lp.choiceCache[id]; ok {
+		return choice, nil
+	}
+	choice, err := lp.s.productService.GetChoiceByID(lp.ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	lp.choiceCache[id] = choice
+	return choice, nil
+}
+
+func (lp *linePricer) loadGroups(productID uuid.UUID) ([]*productDomain.ProductChoiceGroup, error) {
+	if groups, ok := lp.groupsByProductCache[productID]; ok {
+		return groups, nil
+	}
+	groups, err := lp.s.productService.GetChoiceGroupsByProductID(lp.ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	lp.groupsByProductCache[productID] = groups
+	return groups, nil
+}
+
+// price validates one line's choices against the product's choice groups and
+// returns it priced with modifiers. Validation failures are PricingIssues.
+func (lp *linePricer) price(item domain.CartItem) (*domain.QuoteLine, error) {
+	issue := func(format string, args ...any) error {
+		return domain.PricingIssue{Message: fmt.Sprintf(format, args...)}
+	}
+	pid := item.ProductID
+	qty := int64(item.Quantity)
+	if qty <= 0 || qty > 99 {
+		return nil, issue("invalid quantity for %s: must be between 1 and 99", lp.label(pid))
+	}
+
+	selectionByChoice := make(map[uuid.UUID]int)
+	selectionGroupByChoice := make(map[uuid.UUID]uuid.UUID)
+	selectionCountByGroup := make(map[uuid.UUID]int)
+
+	if item.ChoiceID != nil {
+		choice, err := lp.loadChoice(*item.ChoiceID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve choice %s: %w", item.ChoiceID, err)
+		}
+		if choice == nil {
+			return nil, issue("choice %s not found", item.ChoiceID)
+		}
+		if choice.ProductID != pid {
+			return nil, issue("choice %s does not belong to product %s", item.ChoiceID, lp.label(pid))
+		}
+		selectionByChoice[choice.ID]++
+		selectionGroupByChoice[choice.ID] = choice.ChoiceGroupID
+		selectionCountByGroup[choice.ChoiceGroupID]++
+	}
+
+	for _, selection := range item.Selections {
+		if selection.Quantity <= 0 {
+			return nil, issue("selection quantity must be > 0 for product %s", lp.label(pid))
+		}
+		choice, err := lp.loadChoice(selection.ChoiceID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve choice %s: %w", selection.ChoiceID, err)
+		}
+		if choice == nil {
+			return nil, issue("choice %s not found", selection.ChoiceID)
+		}
+		if choice.ProductID != pid {
+			return nil, issue("choice %s does not belong to product %s", selection.ChoiceID, lp.label(pid))
+		}
+		if choice.ChoiceGroupID != selection.GroupID {
+			return nil, issue("choice %s does not belong to group %s", selection.ChoiceID, selection.GroupID)
+		}
+		selectionByChoice[selection.ChoiceID] += selection.Quantity
+		selectionGroupByChoice[selection.ChoiceID] = selection.GroupID
+		selectionCountByGroup[selection.GroupID] += selection.Quantity
+	}
+
+	groups, err := lp.loadGroups(pid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load choice groups for product %s: %w", lp.label(pid), err)
+	}
+	for _, group := range groups {
+		selectedCount := selectionCountByGroup[group.ID]
+		minRequired := group.MinSelections * int(qty)
+		maxAllowed := group.MaxSelections * int(qty)
+		if selectedCount < minRequired || selectedCount > maxAllowed {
+			return nil, issue(
+				"invalid number of selections for group %s on product %s: expected between %d and %d, got %d",
+				group.GetTranslationFor(lp.language),
+				lp.label(pid),
+				minRequired,
+				maxAllowed,
+				selectedCount,
+			)
+		}
+	}
+
+	unitPrice := lp.productMap[pid].Price
+	selections := make([]domain.OrderProductSelection, 0, len(selectionByChoice))
+	for choiceIDValue, quantity := range selectionByChoice {
+		modifier := lp.choiceCache[choiceIDValue].PriceModifier
+		if modifier.Sign() < 0 {
+			modifier = decimal.Zero
+		}
+		unitPrice = unitPrice.Add(modifier.Mul(decimal.NewFromInt(int64(quantity))))
+		selections = append(selections, domain.OrderProductSelection{
+			GroupID:  selectionGroupByChoice[choiceIDValue],
+			ChoiceID: choiceIDValue,
+			Quantity: quantity,
+		})
+	}
+	if unitPrice.LessThan(decimal.Zero) {
+		return nil, issue("invalid price for product %s: price cannot be negative", lp.label(pid))
+	}
+
+	var choiceID *uuid.UUID
+	if len(selectionByChoice) == 1 {
+		for selectedChoiceID, quantity := range selectionByChoice {
+			if quantity == 1 {
+				cid := selectedChoiceID
+				choiceID = &cid
+			}
+		}
+	}
+
+	return &domain.QuoteLine{
+		OrderProductRaw: domain.OrderProductRaw{
+			ProductID:       pid,
+			Quantity:        qty,
+			UnitPrice:       unitPrice,
+			TotalPrice:      unitPrice.Mul(decimal.NewFromInt(qty)),
+			ProductChoiceID: choiceID,
+			Selections:      selections,
+		},
+	}, nil
+}
+
+// priceDelivery resolves the address, picks the delivery zone (or the radius
+// tiers when no zone is configured) and sets the fee and address snapshot.
+// It returns the zone's minimum order when a zone applies.
+func (s *pricingService) priceDelivery(ctx context.Context, cart domain.Cart, pricing restaurantDomain.PricingRules, q *domain.Quote) (*decimal.Decimal, error) {
+	if cart.AddressPlaceID == nil || *cart.AddressPlaceID == "" {
+		q.AddIssue("addressPlaceId", "addressPlaceId required for delivery")
+		return nil, nil
+	}
+	addr, err := s.addressService.Resolve(ctx, *cart.AddressPlaceID, "")
+	if err != nil {
+		q.AddIssue("addressPlaceId", "failed to resolve address: %v", err)
+		return nil, nil
+	}
+	if addr.Lat == nil || addr.Lng == nil {
+		q.AddIssue("addressPlaceId", "address has no coordinates")
+		return nil, nil
+	}
+
+	// Delivery zones, when configured, replace the radius tiers and the
+	// postcode blocklist.
+	var minimum *decimal.Decimal
+	zone, err := s.restaurantService.ResolveDeliveryZone(ctx, *addr.Lat, *addr.Lng)
+	switch {
+	case errors.Is(err, restaurantDomain.ErrOutsideDeliveryZones):
+		q.AddIssue("addressPlaceId", "address not eligible for delivery: outside our delivery zones")
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to resolve delivery zone: %w", err)
+	case zone != nil:
+		q.DeliveryFee = zone.DeliveryFee
+		minimum = &zone.MinimumOrder
+		if err := validateZonePreparationTime(cart.PreferredReadyTime, zone, cart.PreparationBuffer, time.Now()); err != nil {
+			q.AddIssue("preferredReadyTime", "%s", err.Error())
+		}
+	default:
+		fee, inRange := pricing.DeliveryFee(addr.Distance)
+		if !inRange {
+			q.AddIssue("addressPlaceId", "address too far for delivery")
+			return nil, nil
+		}
+		if pricing.IsExcludedPostcode(addr.Postcode) {
+			q.AddIssue("addressPlaceId", "address not eligible for delivery: excluded area")
+			return nil, nil
+		}
+		q.DeliveryFee = fee
+	}
+
+	q.Address = &domain.AddressSnapshot{
+		StreetName:       &addr.StreetName,
+		HouseNumber:      &addr.HouseNumber,
+		BoxNumber:        addr.BoxNumber,
+		MunicipalityName: &addr.MunicipalityName,
+		Postcode:         &addr.Postcode,
+		Distance:         &addr.Distance,
+		PlaceID:          &addr.PlaceID,
+		Lat:              addr.Lat,
+		Lng:              addr.Lng,
+		IsManual:         false,
+	}
+	return minimum, nil
+}
+
+// validateZonePreparationTime rejects preferred ready times that leave no
+// room for the zone's extra preparation minutes. A zero prepBuffer means the
+// opening-hours gate was skipped (dev mode, store review) and so is this.
+func validateZonePreparationTime(preferred *time.Time, zone *restaurantDomain.DeliveryZone, prepBuffer time.Duration, now time.Time) error {
+	if preferred == nil || prepBuffer == 0 || zone.ExtraPreparationMinutes == 0 {
+		return nil
+	}
+	earliest := now.Add(prepBuffer + time.Duration(zone.ExtraPreparationMinutes)*time.Minute)
+	if preferred.Before(earliest) {
+		return fmt.Errorf("deliveries to %s need %d extra minutes of preparation — please pick a later time", zone.Name, zone.ExtraPreparationMinutes)
+	}
+	return nil
+}
+
+// priceCoupon validates the coupon against the subtotal and enforces one
+// active coupon order per customer.
+func (s *pricingService) priceCoupon(ctx context.Context, cart domain.Cart, subtotal decimal.Decimal, q *domain.Quote) error {
+	coupon, discount, err := s.couponService.ValidateCoupon(ctx, *cart.CouponCode, subtotal, cart.UserID)
+	if err != nil {
+		q.AddIssue("couponCode", "invalid coupon: %v", err)
+		return nil
+	}
+	hasActive, err := s.orderRepo.HasActiveCouponOrder(ctx, cart.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to check active coupon orders: %w", err)
+	}
+	if hasActive {
+		q.AddIssue("couponCode", "you already have an active order using a coupon")
+		return nil
+	}
+	q.CouponDiscount = money.RoundToNearest10Cents(discount)
+	q.CouponCode = cart.CouponCode
+	q.CouponID = &coupon.ID
+	return nil
+}
@@ -0,0 +1,282 @@
+package application
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	addressApplication "tsb-service/internal/modules/address/application"
+	addressDomain "tsb-service/internal/modules/address/domain"
+	couponDomain "tsb-service/internal/modules/coupon/domain"
+	"tsb-service/internal/modules/order/domain"
+	productApplication "tsb-service/internal/modules/product/application"
+	productDomain "tsb-service/internal/modules/product/domain"
+	restaurantApplication "tsb-service/internal/modules/restaurant/application"
+	restaurantDomain "tsb-service/internal/modules/restaurant/domain"
+)
+
+// The pricing fakes embed the interface they stand in for and only implement
+// the methods PriceCart calls; anything else panics on the nil embed.
+
+type fakeProductService struct {
+	productApplication.ProductService
+	products []*productDomain.ProductOrderDetails
+}
+
+func (f *fakeProductService) GetProductsByIDs(context.Context, []string) ([]*productDomain.ProductOrderDetails, error) {
+	return f.products, nil
+}
+
+func (f *fakeProductService) GetChoiceGroupsByProductID(context.Context, uuid.UUID) ([]*productDomain.ProductChoiceGroup, error) {
+	return nil, nil
+}
+
+type fakeRestaurantService struct {
+	restaurantApplication.RestaurantService
+	zone    *restaurantDomain.DeliveryZone
+	zoneErr error
+}
+
+func (f *fakeRestaurantService) GetPricing(context.Context) (restaurantDomain.PricingRules, error) {
+	return restaurantDomain.DefaultPricingRules(), nil
+}
+
+func (f *fakeRestaurantService) ResolveDeliveryZone(context.Context, float64, float64) (*restaurantDomain.DeliveryZone, error) {
+	return f.zone, f.zoneErr
+}
+
+type fakeAddressService struct {
+	addressApplication.AddressService
+	address *addressDomain.Address
+}
+
+func (f *fakeAddressService) Resolve(context.Context, string, string) (*addressDomain.Address, error) {
+	return f.address, nil
+}
+
+func testProduct(name string, price string, vat productDomain.VatCategory, discountable bool) *productDomain.ProductOrderDetails {
+	return &productDomain.ProductOrderDetails{
+		ID:             uuid.New(),
+		Name:           name,
+		Price:          decimal.RequireFromString(price),
+		IsDiscountable: discountable,
+		VatCategory:    vat,
+	}
+}
+
+func newTestPricingService(products []*productDomain.ProductOrderDetails, restaurant *fakeRestaurantService, coupons *fakeCouponService) PricingService {
+	lat, lng := 50.63, 5.57
+	addresses := &fakeAddressService{address: &addressDomain.Address{
+		PlaceID:  "place",
+		Postcode: "4000",
+		Distance: 3500,
+		Lat:      &lat,
+		Lng:      &lng,
+	}}
+	if coupons == nil {
+		coupons = &fakeCouponService{}
+	}
+	return NewPricingService(&fakeOrderRepo{}, &fakeProductService{products: products}, restaurant, addresses, coupons)
+}
+
+func assertIssues(t *testing.T, q *domain.Quote, fields ...string) {
+	t.Helper()
+	if len(q.Issues) != len(fields) {
+		t.Fatalf("issues = %+v, want fields %v", q.Issues, fields)
+	}
+	for i, f := range fields {
+		if q.Issues[i].Field != f {
+			t.Errorf("issue %d field = %q, want %q (%s)", i, q.Issues[i].Field, f, q.Issues[i].Message)
+		}
+	}
+}
+
+func assertMoney(t *testing.T, name string, got decimal.Decimal, want string) {
+	t.Helper()
+	if !got.Equal(decimal.RequireFromString(want)) {
+		t.Errorf("%s = %s, want %s", name, got.StringFixed(2), want)
+	}
+}
+
+func TestPriceCartPickup(t *testing.T) {
+	maki := testProduct("Maki", "12.00", productDomain.VatCategoryFood, true)
+	beer := testProduct("Beer", "3.50", productDomain.VatCategoryBeverage, false)
+	svc := newTestPricingService([]*productDomain.ProductOrderDetails{maki, beer}, &fakeRestaurantService{}, nil)
+
+	q, err := svc.PriceCart(context.Background(), domain.Cart{
+		OrderType:       domain.OrderTypePickUp,
+		IsOnlinePayment: true,
+		Items: []domain.CartItem{
+			{ProductID: maki.ID, Quantity: 2},
+			{ProductID: beer.ID, Quantity: 1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("PriceCart: %v", err)
+	}
+	assertIssues(t, q)
+	assertMoney(t, "items total", q.ItemsTotal, "27.50")
+	// 10% on the discountable maki only.
+	assertMoney(t, "takeaway discount", q.TakeawayDiscount, "2.40")
+	assertMoney(t, "transaction fee", q.TransactionFee, "0.30")
+	assertMoney(t, "total", q.Total, "25.40")
+	if q.TakeawayDiscountPercent == nil || !q.TakeawayDiscountPercent.Equal(decimal.NewFromInt(10)) {
+		t.Errorf("takeaway percent = %v, want 10", q.TakeawayDiscountPercent)
+	}
+	if len(q.VatBreakdown) != 2 || !q.VatBreakdown[0].Rate.Equal(decimal.NewFromInt(21)) {
+		t.Fatalf("vat breakdown = %+v, want 21%% first then food", q.VatBreakdown)
+	}
+	assertMoney(t, "beverage vat", q.VatBreakdown[0].Amount, "0.61")
+}
+
+func TestPriceCartDeliveryBelowMinimum(t *testing.T) {
+	maki := testProduct("Maki", "12.00", productDomain.VatCategoryFood, true)
+	svc := newTestPricingService([]*productDomain.ProductOrderDetails{maki}, &fakeRestaurantService{}, nil)
+
+	q, err := svc.PriceCart(context.Background(), domain.Cart{
+		OrderType:      domain.OrderTypeDelivery,
+		AddressPlaceID: strPtr("place"),
+		Items:          []domain.CartItem{{ProductID: maki.ID, Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatalf("PriceCart: %v", err)
+	}
+	assertIssues(t, q, "items")
+	// 3.5 km falls in the second radius tier; no transaction fee when paying cash.
+	assertMoney(t, "delivery fee", q.DeliveryFee, "1")
+	assertMoney(t, "total", q.Total, "13.00")
+	if q.Address == nil || *q.Address.Postcode != "4000" {
+		t.Errorf("address snapshot = %+v, want postcode 4000", q.Address)
+	}
+}
+
+func TestPriceCartDeliveryZone(t *testing.T) {
+	maki := testProduct("Maki", "12.00", productDomain.VatCategoryFood, true)
+	items := []domain.CartItem{{ProductID: maki.ID, Quantity: 1}}
+
+	zone := &restaurantDomain.DeliveryZone{Name: "centre", DeliveryFee: decimal.NewFromInt(2), MinimumOrder: decimal.NewFromInt(10)}
+	svc := newTestPricingService([]*productDomain.ProductOrderDetails{maki}, &fakeRestaurantService{zone: zone}, nil)
+	q, err := svc.PriceCart(context.Background(), domain.Cart{OrderType: domain.OrderTypeDelivery, AddressPlaceID: strPtr("place"), Items: items})
+	if err != nil {
+		t.Fatalf("PriceCart: %v", err)
+	}
+	assertIssues(t, q)
+	assertMoney(t, "zone fee", q.DeliveryFee, "2")
+
+	svc = newTestPricingService([]*productDomain.ProductOrderDetails{maki}, &fakeRestaurantService{zoneErr: restaurantDomain.ErrOutsideDeliveryZones}, nil)
+	q, err = svc.PriceCart(context.Background(), domain.Cart{OrderType: domain.OrderTypeDelivery, AddressPlaceID: strPtr("place"), Items: items})
+	if err != nil {
+		t.Fatalf("PriceCart: %v", err)
+	}
+	assertIssues(t, q, "addressPlaceId", "items")
+}
+
+func TestPriceCartReportsInvalidLines(t *testing.T) {
+	maki := testProduct("Maki", "12.00", productDomain.VatCategoryFood, true)
+	svc := newTestPricingService([]*productDomain.ProductOrderDetails{maki}, &fakeRestaurantService{}, nil)
+
+	q, err := svc.PriceCart(context.Background(), domain.Cart{
+		OrderType: domain.OrderTypePickUp,
+		Items: []domain.CartItem{
+			{ProductID: uuid.New(), Quantity: 1},
+			{ProductID: maki.ID, Quantity: 0},
+			{ProductID: maki.ID, Quantity: 1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("PriceCart: %v", err)
+	}
+	assertIssues(t, q, "items.0", "items.1")
+	if len(q.Lines) != 1 || q.Lines[0].Index != 2 {
+		t.Fatalf("lines = %+v, want only the valid third line", q.Lines)
+	}
+	assertMoney(t, "total", q.Total, "12.00")
+}
+
+func TestPriceCartCapsCoupon(t *testing.T) {
+	maki := testProduct("Maki", "12.00", productDomain.VatCategoryFood, true)
+	coupon := &couponDomain.Coupon{ID: uuid.New()}
+	coupons := &fakeCouponService{coupon: coupon, discount: decimal.NewFromInt(15)}
+	svc := newTestPricingService([]*productDomain.ProductOrderDetails{maki}, &fakeRestaurantService{}, coupons)
+
+	q, err := svc.PriceCart(context.Background(), domain.Cart{
+		OrderType:  domain.OrderTypePickUp,
+		CouponCode: strPtr("TOKYO15"),
+		Items:      []domain.CartItem{{ProductID: maki.ID, Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatalf("PriceCart: %v", err)
+	}
+	assertIssues(t, q)
+	assertMoney(t, "coupon discount", q.CouponDiscount, "12.00")
+	assertMoney(t, "total", q.Total, "0")
+	if q.CouponID == nil || *q.CouponID != coupon.ID {
+		t.Errorf("coupon id = %v, want %s", q.CouponID, coupon.ID)
+	}
+}
+
+func TestPriceCartDineIn(t *testing.T) {
+	maki := testProduct("Maki", "4.00", productDomain.VatCategoryFood, true)
+	svc := newTestPricingService([]*productDomain.ProductOrderDetails{maki}, &fakeRestaurantService{}, nil)
+
+	q, err := svc.PriceCart(context.Background(), domain.Cart{
+		OrderType:       domain.OrderTypeDineIn,
+		IsOnlinePayment: true,
+		CouponCode:      strPtr("TOKYO15"),
+		Items:           []domain.CartItem{{ProductID: maki.ID, Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatalf("PriceCart: %v", err)
+	}
+	// No minimum for a round, but no online payment or coupon either.
+	assertIssues(t, q, "isOnlinePayment", "couponCode")
+	assertMoney(t, "takeaway discount", q.TakeawayDiscount, "0")
+	if len(q.Lines) != 1 || !q.Lines[0].VatRateApplied.Equal(decimal.NewFromInt(12)) {
+		t.Fatalf("lines = %+v, want food at the 12%% dine-in rate", q.Lines)
+	}
+}
+
+func TestPriceCartTip(t *testing.T) {
+	maki := testProduct("Maki", "12.00", productDomain.VatCategoryFood, false)
+	svc := newTestPricingService([]*productDomain.ProductOrderDetails{maki}, &fakeRestaurantService{}, nil)
+	percent := func(p int) *int { return &p }
+
+	cases := []struct {
+		name    string
+		online  bool
+		amount  *string
+		percent *int
+		wantTip string
+		issues  []string
+	}{
+		{"no tip", true, nil, nil, "0", nil},
+		{"fixed amount", true, strPtr("2.555"), nil, "2.56", nil},
+		// 10% of the 24.30 total, snapped to 0,10 €.
+		{"percentage", true, nil, percent(10), "2.40", nil},
+		{"both", true, strPtr("1"), percent(5), "0", []string{"tipPercent"}},
+		{"cash payment", false, strPtr("1"), nil, "0", []string{"tipAmount"}},
+		{"zero on cash", false, nil, percent(0), "0", nil},
+		{"negative", true, strPtr("-1"), nil, "0", []string{"tipAmount"}},
+		{"not a number", true, strPtr("abc"), nil, "0", []string{"tipAmount"}},
+		{"percentage too high", true, nil, percent(domain.MaxTipPercent + 1), "0", []string{"tipPercent"}},
+		{"more than the total", true, strPtr("30"), nil, "0", []string{"tipAmount"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := svc.PriceCart(context.Background(), domain.Cart{
+				OrderType:       domain.OrderTypePickUp,
+				IsOnlinePayment: tc.online,
+				TipAmount:       tc.amount,
+				TipPercent:      tc.percent,
+				Items:           []domain.CartItem{{ProductID: maki.ID, Quantity: 2}},
+			})
+			if err != nil {
+				t.Fatalf("PriceCart: %v", err)
+			}
+			assertIssues(t, q, tc.issues...)
+			assertMoney(t, "tip", q.Tip, tc.wantTip)
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"tsb-service/internal/modules/order/domain"
+	es "tsb-service/pkg/email/scaleway"
+	"tsb-service/pkg/invoice"
+	"tsb-service/pkg/utils"
+)
+
+// ReportService builds the end-of-day (Z) report accounting closes each day
+// with, and emails it to the restaurant once the day is over.
+type ReportService interface {
+	// GetDailyReport returns the report of the restaurant day date falls on.
+	GetDailyReport(ctx context.Context, date time.Time) (*domain.DailyReport, error)
+	RenderDailyReportPDF(report *domain.DailyReport) ([]byte, error)
+	// SendDueReport emails the report of the last day past its send time,
+	// unless it was sent already. It returns the day it sent, or nil.
+	SendDueReport(ctx context.Context, now time.Time) (*time.Time, error)
+}
+
+type reportService struct {
+	repo      domain.ReportRepository
+	sendAfter time.Duration
+}
+
+// NewReportService sends each day's report once the local time is past
+// sendAfter, a time of day.
+func NewReportService(repo domain.ReportRepository, sendAfter time.Duration) ReportService {
+	return &reportService{repo: repo, sendAfter: sendAfter}
+}
+
+func (s *reportService) GetDailyReport(ctx context.Context, date time.Time) (*domain.DailyReport, error) {
+	start, end := domain.ReportDay(date)
+	orders, err := s.repo.FindReportOrders(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	vat, err := s.repo.FindReportVat(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	refunds, err := s.repo.FindReportRefunds(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return domain.NewDailyReport(start, orders, vat, refunds), nil
+}
+
+func (s *reportService) RenderDailyReportPDF(report *domain.DailyReport) ([]byte, error) {
+	return invoice.GenerateDailyReportPDF(dailyReportData(report, time.Now()))
+}
+
+func (s *reportService) SendDueReport(ctx context.Context, now time.Time) (*time.Time, error) {
+	day := domain.DueReportDay(now, s.sendAfter)
+	claimed, err := s.repo.ClaimDailyReport(ctx, day, domain.DailyReportClaimLease)
+	if err != nil || !claimed {
+		return nil, err
+	}
+	if err := s.sendReport(ctx, day); err != nil {
+		if releaseErr := s.repo.ReleaseDailyReport(ctx, day); releaseErr != nil {
+			return nil, fmt.Errorf("%w (and failed to release the claim: %v)", err, releaseErr)
+		}
+		return nil, err
+	}
+	// Left unmarked, the report goes out again once the lease runs out.
+	if err := s.repo.MarkDailyReportSent(ctx, day); err != nil {
+		return nil, err
+	}
+	return &day, nil
+}
+
+func (s *reportService) sendReport(ctx context.Context, day time.Time) error {
+	report, err := s.GetDailyReport(ctx, day)
+	if err != nil {
+		return fmt.Errorf("failed to build daily report: %w", err)
+	}
+	pdf, err := s.RenderDailyReportPDF(report)
+	if err != nil {
+		return fmt.Errorf("failed to render daily report: %w", err)
+	}
+	if err := es.SendDailyReportEmail(*report, invoice.DailyReportFilename(day), pdf); err != nil {
+		return fmt.Errorf("failed to email daily report: %w", err)
+	}
+	return nil
+}
+
+func dailyReportData(r *domain.DailyReport, generatedAt time.Time) invoice.DailyReportData {
+	row := func(b domain.ReportBucket) invoice.DailyReportRow {
+		return invoice.DailyReportRow{Key: b.Key, Count: b.Count, Amount: utils.FormatDecimal(b.Total)}
+	}
+	data := invoice.DailyReportData{
+		Date:              r.Date,
+		GeneratedAt:       generatedAt,
+		Orders:            r.Orders,
+		Revenue:           utils.FormatDecimal(r.Revenue),
+		TakeawayDiscounts: utils.FormatDecimal(r.TakeawayDiscounts),
+		CouponDiscounts:   utils.FormatDecimal(r.CouponDiscounts),
+		DeliveryFees:      utils.FormatDecimal(r.DeliveryFees),
+		TransactionFees:   utils.FormatDecimal(r.TransactionFees),
+		Tips:              utils.FormatDecimal(r.Tips),
+		Refunds:           row(r.Refunds),
+		Cancellations:     row(r.Cancellations),
+		TotalVat:          utils.FormatDecimal(r.TotalVat),
+	}
+	for _, b := range r.ByOrderType {
+		data.ByOrderType = append(data.ByOrderType, row(b))
+	}
+	for _, b := range r.ByPaymentMethod {
+		data.ByPaymentMethod = append(data.ByPaymentMethod, row(b))
+	}
+	for _, v := range r.Vat {
+		data.Vat = append(data.Vat, invoice.DailyReportVatLine{
+			SceCode: v.SceCode,
+			Rate:    v.Rate.String(),
+			Gross:   utils.FormatDecimal(v.Gross),
+			Vat:     utils.FormatDecimal(v.Vat),
+			Net:     utils.FormatDecimal(v.Net),
+		})
+	}
+	return data
+}
@@ -2,21 +2,51 @@ package application
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 
-	couponApplication "tsb-service/internal/modules/coupon/application"
 	"tsb-service/internal/modules/order/domain"
 	"tsb-service/pkg/logging"
 )
 
 type OrderService interface {
-	CreateOrder(ctx context.Context, order *domain.Order, orderProducts *[]domain.OrderProductRaw) (*domain.Order, *[]domain.OrderProductRaw, error)
+	// CreateOrder saves the order and enqueues its side effects (see
+	// domain.CreatedOrderEffects) in the same transaction. slotReservationID,
+	// when set, is the reservation taken for the order (see ReserveSlot); it
+	// is attached in that transaction too, as is the order to idempotencyKey
+	// when the customer sent one (see IdempotencyService.Begin).
+	CreateOrder(ctx context.Context, order *domain.Order, orderProducts *[]domain.OrderProductRaw, slotReservationID *uuid.UUID, idempotencyKey string) (*domain.Order, *[]domain.OrderProductRaw, error)
 	GetPaginatedOrders(ctx context.Context, page int, limit int, userID *uuid.UUID) ([]*domain.Order, error)
-	UpdateOrder(ctx context.Context, orderID uuid.UUID, newStatus *domain.OrderStatus, estimatedReadyTime *time.Time, cancellationReason *domain.OrderCancellationReason) error
+	// GetOrdersPage is GetPaginatedOrders with keyset pagination, stable
+	// while new orders arrive.
+	GetOrdersPage(ctx context.Context, userID *uuid.UUID, req domain.OrderPageRequest) (*domain.OrderPage, error)
+	// UpdateOrder applies a status / ETA / cancellation-reason change. Status
+	// changes are checked against the per-type transition table and rejected
+	// with a *domain.StatusTransitionError unless opts.Force is set (see
+	// UpdateOrderOptions).
+	UpdateOrder(ctx context.Context, orderID uuid.UUID, newStatus *domain.OrderStatus, estimatedReadyTime *time.Time, cancellationReason *domain.OrderCancellationReason, opts UpdateOrderOptions) error
+	// CancelMyOrder cancels an order on behalf of its customer, provided
+	// domain.CheckCustomerCancel allows it with the given grace period. It
+	// shares UpdateOrder's coupon rollback and enqueues
+	// domain.CustomerCancelEffects (refund included). Ownership is the
+	// caller's responsibility.
+	CancelMyOrder(ctx context.Context, orderID uuid.UUID, grace time.Duration) error
+	// CancelForFailedPayment cancels an order as PAYMENT_FAILED once its
+	// online payment failed, with UpdateOrder's bookkeeping, and enqueues
+	// domain.PaymentFailedEffects keyed by paymentKey.
+	CancelForFailedPayment(ctx context.Context, orderID uuid.UUID, paymentKey string) error
+	// ReopenForPaymentRetry puts an order cancelled by its failed payment
+	// back to PENDING so its customer can pay it again, provided
+	// domain.CheckPaymentRetry allows it. In the same transaction it takes
+	// back the coupon usage the cancellation gave up, attaches
+	// slotReservationID like CreateOrder and enqueues the announcement of the
+	// change (see domain.ReopenedOrderEffects). Ownership and reserving slot
+	// capacity are the caller's responsibility.
+	ReopenForPaymentRetry(ctx context.Context, orderID uuid.UUID, slotReservationID *uuid.UUID) (*domain.Order, error)
 	GetOrderByID(ctx context.Context, orderID uuid.UUID) (*domain.Order, *[]domain.OrderProductRaw, error)
 	GetStatusHistory(ctx context.Context, orderID uuid.UUID) ([]*domain.OrderStatusHistory, error)
 
@@ -26,35 +56,78 @@ type OrderService interface {
 	UpdateActiveOrdersLanguage(ctx context.Context, userID uuid.UUID, language string) ([]*domain.Order, error)
 	HasActiveCouponOrder(ctx context.Context, userID uuid.UUID) (bool, error)
 	GetCustomerStats(ctx context.Context, startDate, endDate *time.Time, orderType *string, minOrders *int) ([]*domain.CustomerStatsRow, error)
+	GetDailyTips(ctx context.Context, startDate, endDate time.Time) ([]*domain.DailyTipsRow, error)
 	GetOrderHistory(ctx context.Context, filter domain.OrderHistoryFilter) ([]*domain.Order, *domain.OrderHistorySummary, error)
+	GetOrderHistoryPage(ctx context.Context, filter domain.OrderHistoryFilter, req domain.OrderPageRequest) (*domain.OrderPage, *domain.OrderHistorySummary, error)
+	// ExportOrderLines streams the lines of every order matching the history
+	// filter to fn, so an export of any range runs in constant memory.
+	ExportOrderLines(ctx context.Context, filter domain.OrderHistoryFilter, fn func(*domain.ExportLine) error) error
 	// CancelStaleTestOrders auto-cancels store-review test orders older than
 	// olderThan and returns how many were cancelled. TEMPORARY (revert after launch).
 	CancelStaleTestOrders(ctx context.Context, olderThan time.Duration) (int, error)
+	// ReleaseScheduledOrders releases held scheduled orders whose ready time
+	// is within lead of now, enqueueing their announcement to staff (see
+	// domain.ReleasedOrderEffects), and returns how many were released.
+	ReleaseScheduledOrders(ctx context.Context, lead time.Duration) (int, error)
+	// EscalateUnconfirmedOrders enqueues, once per order, a reminder to staff
+	// of the orders they have left unconfirmed for longer than after (see
+	// domain.EscalatedOrderEffects), and returns how many were escalated.
+	EscalateUnconfirmedOrders(ctx context.Context, after time.Duration) (int, error)
+	// CancelUnconfirmedOrders cancels the orders staff have left unconfirmed
+	// for longer than after as KITCHEN_CLOSED, with the side effects of a
+	// staff cancellation (refund, customer email, coupon rollback), and
+	// returns how many were cancelled.
+	CancelUnconfirmedOrders(ctx context.Context, after time.Duration) (int, error)
+
+	// ReserveSlot holds kitchen capacity for an order about to be created and
+	// returns domain.ErrSlotFull when the slot has no room left. The caller
+	// passes the reservation to CreateOrder, or cancels it on failure.
+	// Capacity is released automatically when the order is cancelled or fails.
+	ReserveSlot(ctx context.Context, reservation *domain.SlotReservation, limits []domain.SlotLimit) error
+	CancelSlotReservation(ctx context.Context, reservationID uuid.UUID) error
+	// ReapSlotReservations deletes the reservations no order took within
+	// domain.SlotReservationTTL and returns how many were deleted.
+	ReapSlotReservations(ctx context.Context) (int, error)
+	GetSlotUsage(ctx context.Context, from, to time.Time) ([]domain.SlotUsage, error)
+}
+
+// UpdateOrderOptions tunes how OrderService.UpdateOrder applies a change.
+type UpdateOrderOptions struct {
+	// Force bypasses the status transition check (admin override) and is
+	// recorded on the status history row.
+	Force bool
+	// Notify enqueues the customer and staff side effects of the change
+	// (emails, pushes, refund, subscription events) in the outbox,
+	// atomically with the update.
+	Notify bool
+	// Eta, when set, is the ready time suggestion to record with the update
+	// (see EtaService.Suggest).
+	Eta *domain.EtaEstimate
 }
 
 type orderService struct {
-	repo          domain.OrderRepository
-	couponService couponApplication.CouponService
+	repo     domain.OrderRepository
+	slotRepo domain.SlotReservationRepository
 }
 
-func NewOrderService(repo domain.OrderRepository, couponService couponApplication.CouponService) OrderService {
+func NewOrderService(repo domain.OrderRepository, slotRepo domain.SlotReservationRepository) OrderService {
 	return &orderService{
-		repo:          repo,
-		couponService: couponService,
+		repo:     repo,
+		slotRepo: slotRepo,
 	}
 }
 
-func (s *orderService) CreateOrder(ctx context.Context, o *domain.Order, op *[]domain.OrderProductRaw) (*domain.Order, *[]domain.OrderProductRaw, error) {
-	order, orderProducts, err := s.repo.Save(ctx, o, op)
+func (s *orderService) CreateOrder(ctx context.Context, o *domain.Order, op *[]domain.OrderProductRaw, slotReservationID *uuid.UUID, idempotencyKey string) (*domain.Order, *[]domain.OrderProductRaw, error) {
+	w := domain.OrderWrite{
+		History:        domain.NewStatusHistory(nil, o, false, actorFromContext(ctx)),
+		AttachSlot:     slotReservationID,
+		Outbox:         domain.NewOutboxMessages(uuid.Nil, domain.OutboxEvent{Status: o.OrderStatus}, domain.CreatedOrderEffects(o)),
+		IdempotencyKey: idempotencyKey,
+	}
+	order, orderProducts, err := s.repo.Save(ctx, o, op, w)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to save order: %w", err)
 	}
-
-	// Record initial status in history
-	if err := s.repo.InsertStatusHistory(ctx, order.ID, order.OrderStatus); err != nil {
-		logging.FromContext(ctx).Error("failed to record initial status history", zap.String("order_id", order.ID.String()), zap.Error(err))
-	}
-
 	return order, orderProducts, nil
 }
 
@@ -66,17 +139,118 @@ func (s *orderService) GetPaginatedOrders(ctx context.Context, page int, limit i
 	return s.repo.FindPaginated(ctx, page, limit, userID)
 }
 
-func (s *orderService) UpdateOrder(ctx context.Context, orderID uuid.UUID, newStatus *domain.OrderStatus, estimatedReadyTime *time.Time, cancellationReason *domain.OrderCancellationReason) error {
+func (s *orderService) GetOrdersPage(ctx context.Context, userID *uuid.UUID, req domain.OrderPageRequest) (*domain.OrderPage, error) {
+	return s.repo.FindPage(ctx, userID, req)
+}
+
+func (s *orderService) UpdateOrder(ctx context.Context, orderID uuid.UUID, newStatus *domain.OrderStatus, estimatedReadyTime *time.Time, cancellationReason *domain.OrderCancellationReason, opts UpdateOrderOptions) error {
 	// Retrieve the order
 	order, _, err := s.repo.FindByID(ctx, orderID)
 	if err != nil {
 		return err
 	}
 
+	var effects func(old, updated *domain.Order, readyTimeChanged bool) []domain.OutboxKind
+	if opts.Notify {
+		effects = statusChangeEffects
+	}
+	return s.applyUpdate(ctx, order, newStatus, estimatedReadyTime, cancellationReason, opts.Force, effects, "", opts.Eta)
+}
+
+// statusChangeEffects are the side effects of a staff update, as of now.
+func statusChangeEffects(old, updated *domain.Order, readyTimeChanged bool) []domain.OutboxKind {
+	return domain.StatusChangeEffects(old, updated, readyTimeChanged, time.Now())
+}
+
+func (s *orderService) CancelMyOrder(ctx context.Context, orderID uuid.UUID, grace time.Duration) error {
+	order, _, err := s.repo.FindByID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if err := domain.CheckCustomerCancel(order, time.Now(), grace); err != nil {
+		return err
+	}
+
+	canceled := domain.OrderStatusCanceled
+	reason := domain.OrderCancellationReasonCustomerRequest
+	effects := func(_, updated *domain.Order, _ bool) []domain.OutboxKind {
+		return domain.CustomerCancelEffects(updated)
+	}
+	return s.applyUpdate(ctx, order, &canceled, nil, &reason, false, effects, "", nil)
+}
+
+func (s *orderService) CancelForFailedPayment(ctx context.Context, orderID uuid.UUID, paymentKey string) error {
+	order, _, err := s.repo.FindByID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	// The customer, staff or the kitchen-closed sweep may have cancelled it
+	// first; their reason stands, so the order does not become retryable.
+	if order.OrderStatus == domain.OrderStatusCanceled {
+		return nil
+	}
+
+	canceled := domain.OrderStatusCanceled
+	reason := domain.OrderCancellationReasonPaymentFailed
+	effects := func(_, _ *domain.Order, _ bool) []domain.OutboxKind {
+		return domain.PaymentFailedEffects()
+	}
+	return s.applyUpdate(ctx, order, &canceled, nil, &reason, false, effects, paymentKey, nil)
+}
+
+func (s *orderService) ReopenForPaymentRetry(ctx context.Context, orderID uuid.UUID, slotReservationID *uuid.UUID) (*domain.Order, error) {
+	order, _, err := s.repo.FindByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := domain.CheckPaymentRetry(order, time.Now()); err != nil {
+		return nil, err
+	}
+
+	oldOrder := *order
+	order.OrderStatus = domain.OrderStatusPending
+	order.CancellationReason = nil
+	// The status guard lets only one of two concurrent retries through, and
+	// the coupon usage the cancellation gave up is taken back with it.
+	event := domain.OutboxEvent{Status: order.OrderStatus, PreviousStatus: oldOrder.OrderStatus}
+	w := domain.OrderWrite{
+		History:     domain.NewStatusHistory(&oldOrder, order, false, actorFromContext(ctx)),
+		AttachSlot:  slotReservationID,
+		ClaimCoupon: order.CouponCode != nil && *order.CouponCode != "",
+		Outbox:      domain.NewOutboxMessages(order.ID, event, domain.ReopenedOrderEffects()),
+	}
+	if err := s.repo.Update(ctx, order, oldOrder.OrderStatus, w); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// applyUpdate applies a change to order, as loaded by the caller, together
+// with the bookkeeping every status change needs (history, slot capacity,
+// coupon rollback). effects, when set, lists the outbox messages to write with
+// the update; outboxKey, when set, derives their IDs (see
+// domain.NewKeyedOutboxMessages).
+func (s *orderService) applyUpdate(
+	ctx context.Context,
+	order *domain.Order,
+	newStatus *domain.OrderStatus,
+	estimatedReadyTime *time.Time,
+	cancellationReason *domain.OrderCancellationReason,
+	force bool,
+	effects func(old, updated *domain.Order, readyTimeChanged bool) []domain.OutboxKind,
+	outboxKey string,
+	eta *domain.EtaEstimate,
+) error {
+	oldOrder := *order
 	oldStatus := order.OrderStatus
 
 	// Check if there a new status
 	if newStatus != nil {
+		if !force {
+			if err := domain.ValidateStatusTransition(order.OrderType, oldStatus, *newStatus); err != nil {
+				return err
+			}
+		}
 		order.OrderStatus = *newStatus
 	}
 
@@ -85,54 +259,101 @@ func (s *orderService) UpdateOrder(ctx context.Context, orderID uuid.UUID, newSt
 		order.EstimatedReadyTime = estimatedReadyTime
 	}
 
-	// Persist cancellation reason only when transitioning to CANCELLED.
-	if cancellationReason != nil && order.OrderStatus == domain.OrderStatusCanceled {
+	// Persist cancellation reason only when transitioning to CANCELLED; an
+	// already cancelled order keeps the reason it was cancelled for.
+	if cancellationReason != nil && order.OrderStatus == domain.OrderStatusCanceled &&
+		oldStatus != domain.OrderStatusCanceled {
 		order.CancellationReason = cancellationReason
 	}
 
-	if err := s.repo.Update(ctx, order); err != nil {
-		return err
-	}
-
-	// Record status change in history
+	w := domain.OrderWrite{Eta: eta}
 	if order.OrderStatus != oldStatus {
-		if err := s.repo.InsertStatusHistory(ctx, order.ID, order.OrderStatus); err != nil {
-			logging.FromContext(ctx).Error("failed to record status history", zap.String("order_id", order.ID.String()), zap.String("status", string(order.OrderStatus)), zap.Error(err))
-		}
+		w.History = domain.NewStatusHistory(&oldOrder, order, force, actorFromContext(ctx))
+		// Free the order's kitchen capacity once it will no longer be prepared.
+		w.ReleaseSlot = order.OrderStatus == domain.OrderStatusCanceled || order.OrderStatus == domain.OrderStatusFailed
+		// Roll back coupon usage when the order transitions into CANCELED.
+		// This is the single source of cancel-time rollback: it covers cash
+		// orders, admin and POS cancellations, and the payment-failed webhook
+		// (which cancels via this method). The status guard of Update makes
+		// it idempotent, so a duplicate cancellation never double-decrements.
+		w.ReleaseCoupon = order.OrderStatus == domain.OrderStatusCanceled &&
+			order.CouponCode != nil && *order.CouponCode != ""
 	}
-
-	// Roll back coupon usage when the order transitions into CANCELED. This is
-	// the single source of cancel-time rollback: it covers cash orders, admin
-	// and POS cancellations, and the payment-failed webhook (which cancels via
-	// this method). The transition guard (oldStatus != CANCELED) makes it
-	// idempotent, so a duplicate cancellation never double-decrements.
-	if s.couponService != nil &&
-		order.OrderStatus == domain.OrderStatusCanceled && oldStatus != domain.OrderStatusCanceled &&
-		order.CouponCode != nil && *order.CouponCode != "" {
-		if coupon, cErr := s.couponService.GetCouponByCode(ctx, *order.CouponCode); cErr != nil || coupon == nil {
-			logging.FromContext(ctx).Error("failed to fetch coupon for rollback on cancellation",
-				zap.String("order_id", order.ID.String()), zap.String("coupon_code", *order.CouponCode), zap.Error(cErr))
-		} else if dErr := s.couponService.DecrementUsageAtomic(ctx, coupon.ID, order.UserID); dErr != nil {
-			logging.FromContext(ctx).Error("failed to roll back coupon on cancellation",
-				zap.String("order_id", order.ID.String()), zap.String("coupon_code", *order.CouponCode), zap.Error(dErr))
+	if effects != nil {
+		readyTimeChanged := estimatedReadyTime != nil &&
+			(oldOrder.EstimatedReadyTime == nil || !oldOrder.EstimatedReadyTime.Equal(*estimatedReadyTime))
+		event := domain.OutboxEvent{Status: order.OrderStatus, PreviousStatus: oldStatus}
+		kinds := effects(&oldOrder, order, readyTimeChanged)
+		if outboxKey != "" {
+			w.Outbox = domain.NewKeyedOutboxMessages(order.ID, outboxKey, event, kinds)
+		} else {
+			w.Outbox = domain.NewOutboxMessages(order.ID, event, kinds)
 		}
 	}
-
-	return nil
+	return s.repo.Update(ctx, order, oldStatus, w)
 }
 
 func (s *orderService) CancelStaleTestOrders(ctx context.Context, olderThan time.Duration) (int, error) {
-	ids, err := s.repo.CancelStaleTestOrders(ctx, olderThan)
+	orders, err := s.repo.CancelStaleTestOrders(ctx, olderThan, actorFromContext(ctx))
+	return len(orders), err
+}
+
+func (s *orderService) ReleaseScheduledOrders(ctx context.Context, lead time.Duration) (int, error) {
+	orders, err := s.repo.ReleaseScheduledOrders(ctx, time.Now().Add(lead), domain.ReleasedOrderEffects())
+	return len(orders), err
+}
+
+func (s *orderService) EscalateUnconfirmedOrders(ctx context.Context, after time.Duration) (int, error) {
+	orders, err := s.repo.EscalateUnconfirmedOrders(ctx, time.Now().Add(-after), domain.EscalatedOrderEffects())
+	return len(orders), err
+}
+
+func (s *orderService) CancelUnconfirmedOrders(ctx context.Context, after time.Duration) (int, error) {
+	ids, err := s.repo.FindUnconfirmedOrderIDs(ctx, time.Now().Add(-after))
 	if err != nil {
 		return 0, err
 	}
+	canceled := domain.OrderStatusCanceled
+	reason := domain.OrderCancellationReasonKitchenClosed
+	n := 0
 	for _, id := range ids {
-		if err := s.repo.InsertStatusHistory(ctx, id, domain.OrderStatusCanceled); err != nil {
-			logging.FromContext(ctx).Warn("failed to record auto-cancel status history",
+		order, _, err := s.repo.FindByID(ctx, id)
+		if err != nil {
+			logging.FromContext(ctx).Warn("failed to load unconfirmed order",
 				zap.String("order_id", id.String()), zap.Error(err))
+			continue
+		}
+		// Staff may have confirmed it since the lookup; Update's status guard
+		// covers a confirmation racing the cancellation itself.
+		if order.OrderStatus != domain.OrderStatusPending {
+			continue
+		}
+		if err := s.applyUpdate(ctx, order, &canceled, nil, &reason, false, statusChangeEffects, "", nil); err != nil {
+			if !errors.Is(err, domain.ErrOrderStatusChanged) {
+				logging.FromContext(ctx).Warn("failed to cancel unconfirmed order",
+					zap.String("order_id", id.String()), zap.Error(err))
+			}
+			continue
 		}
+		n++
 	}
-	return len(ids), nil
+	return n, nil
+}
+
+func (s *orderService) ReserveSlot(ctx context.Context, reservation *domain.SlotReservation, limits []domain.SlotLimit) error {
+	return s.slotRepo.Reserve(ctx, reservation, limits)
+}
+
+func (s *orderService) ReapSlotReservations(ctx context.Context) (int, error) {
+	return s.slotRepo.DeleteUnattached(ctx, time.Now().Add(-domain.SlotReservationTTL))
+}
+
+func (s *orderService) CancelSlotReservation(ctx context.Context, reservationID uuid.UUID) error {
+	return s.slotRepo.Delete(ctx, reservationID)
+}
+
+func (s *orderService) GetSlotUsage(ctx context.Context, from, to time.Time) ([]domain.SlotUsage, error) {
+	return s.slotRepo.Usage(ctx, from, to)
 }
 
 func (s *orderService) GetOrderByID(ctx context.Context, orderID uuid.UUID) (*domain.Order, *[]domain.OrderProductRaw, error) {
@@ -163,6 +384,18 @@ func (s *orderService) GetCustomerStats(ctx context.Context, startDate, endDate
 	return s.repo.GetCustomerStats(ctx, startDate, endDate, orderType, minOrders)
 }
 
+func (s *orderService) GetDailyTips(ctx context.Context, startDate, endDate time.Time) ([]*domain.DailyTipsRow, error) {
+	return s.repo.GetDailyTips(ctx, startDate, endDate)
+}
+
 func (s *orderService) GetOrderHistory(ctx context.Context, filter domain.OrderHistoryFilter) ([]*domain.Order, *domain.OrderHistorySummary, error) {
 	return s.repo.FindFiltered(ctx, filter)
 }
+
+func (s *orderService) GetOrderHistoryPage(ctx context.Context, filter domain.OrderHistoryFilter, req domain.OrderPageRequest) (*domain.OrderPage, *domain.OrderHistorySummary, error) {
+	return s.repo.FindFilteredPage(ctx, filter, req)
+}
+
+func (s *orderService) ExportOrderLines(ctx context.Context, filter domain.OrderHistoryFilter, fn func(*domain.ExportLine) error) error {
+	return s.repo.StreamExportLines(ctx, filter, fn)
+}
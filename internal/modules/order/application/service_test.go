@@ -2,6 +2,7 @@ package application
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -10,24 +11,65 @@ import (
 
 	couponDomain "tsb-service/internal/modules/coupon/domain"
 	"tsb-service/internal/modules/order/domain"
+	"tsb-service/pkg/utils"
 )
 
-// fakeOrderRepo implements domain.OrderRepository. Only FindByID/Update/
+// fakeOrderRepo implements domain.OrderRepository. Only FindByID/Save/Update/
 // InsertStatusHistory carry behaviour for these tests; the rest are stubs.
+// Save and Update record what they were asked to write with the order.
+// couponExhausted makes a write claiming the order's coupon fail.
 type fakeOrderRepo struct {
-	order        *domain.Order
-	updatedOrder *domain.Order
+	order           *domain.Order
+	updatedOrder    *domain.Order
+	history         []historyCall
+	outbox          []*domain.OutboxMessage
+	slotReleases    int
+	couponReleases  int
+	couponClaims    int
+	couponExhausted bool
+	etas           []*domain.EtaEstimate
+	unconfirmed    []uuid.UUID
 }
 
-func (f *fakeOrderRepo) Save(_ context.Context, o *domain.Order, op *[]domain.OrderProductRaw) (*domain.Order, *[]domain.OrderProductRaw, error) {
+type historyCall struct {
+	status domain.OrderStatus
+	forced bool
+	actor  domain.Actor
+}
+
+func (f *fakeOrderRepo) Save(ctx context.Context, o *domain.Order, op *[]domain.OrderProductRaw, w domain.OrderWrite) (*domain.Order, *[]domain.OrderProductRaw, error) {
+	f.write(ctx, w)
 	return o, op, nil
 }
 
-func (f *fakeOrderRepo) Update(_ context.Context, o *domain.Order) error {
+func (f *fakeOrderRepo) Update(ctx context.Context, o *domain.Order, _ domain.OrderStatus, w domain.OrderWrite) error {
+	if w.ClaimCoupon && f.couponExhausted {
+		return domain.ErrPaymentRetryCouponUnavailable
+	}
 	f.updatedOrder = o
+	f.write(ctx, w)
 	return nil
 }
 
+func (f *fakeOrderRepo) write(ctx context.Context, w domain.OrderWrite) {
+	if w.History != nil {
+		_ = f.InsertStatusHistory(ctx, w.History)
+	}
+	if w.ReleaseSlot {
+		f.slotReleases++
+	}
+	if w.ReleaseCoupon {
+		f.couponReleases++
+	}
+	if w.ClaimCoupon {
+		f.couponClaims++
+	}
+	if w.Eta != nil {
+		f.etas = append(f.etas, w.Eta)
+	}
+	f.outbox = append(f.outbox, w.Outbox...)
+}
+
 func (f *fakeOrderRepo) FindByID(_ context.Context, _ uuid.UUID) (*domain.Order, *[]domain.OrderProductRaw, error) {
 	// Return a copy so the service mutates its own instance, mirroring the real repo.
 	cp := *f.order
@@ -42,6 +84,18 @@ func (f *fakeOrderRepo) FindFiltered(_ context.Context, _ domain.OrderHistoryFil
 	return nil, nil, nil
 }
 
+func (f *fakeOrderRepo) FindPage(_ context.Context, _ *uuid.UUID, _ domain.OrderPageRequest) (*domain.OrderPage, error) {
+	return nil, nil
+}
+
+func (f *fakeOrderRepo) FindFilteredPage(_ context.Context, _ domain.OrderHistoryFilter, _ domain.OrderPageRequest) (*domain.OrderPage, *domain.OrderHistorySummary, error) {
+	return nil, nil, nil
+}
+
+func (f *fakeOrderRepo) StreamExportLines(_ context.Context, _ domain.OrderHistoryFilter, _ func(*domain.ExportLine) error) error {
+	return nil
+}
+
 func (f *fakeOrderRepo) FindByOrderIDs(_ context.Context, _ []string) (map[string][]*domain.OrderProductRaw, error) {
 	return nil, nil
 }
@@ -58,14 +112,31 @@ func (f *fakeOrderRepo) HasActiveCouponOrder(_ context.Context, _ uuid.UUID) (bo
 	return false, nil
 }
 
-func (f *fakeOrderRepo) InsertStatusHistory(_ context.Context, _ uuid.UUID, _ domain.OrderStatus) error {
+func (f *fakeOrderRepo) InsertStatusHistory(_ context.Context, entry *domain.OrderStatusHistory) error {
+	call := historyCall{status: entry.Status, forced: entry.Forced, actor: domain.Actor{Type: *entry.ActorType}}
+	if entry.ActorID != nil {
+		call.actor.ID = *entry.ActorID
+	}
+	f.history = append(f.history, call)
 	return nil
 }
 
-func (f *fakeOrderRepo) CancelStaleTestOrders(_ context.Context, _ time.Duration) ([]uuid.UUID, error) {
+func (f *fakeOrderRepo) CancelStaleTestOrders(_ context.Context, _ time.Duration, _ domain.Actor) ([]*domain.Order, error) {
+	return nil, nil
+}
+
+func (f *fakeOrderRepo) ReleaseScheduledOrders(_ context.Context, _ time.Time, _ []domain.OutboxKind) ([]*domain.Order, error) {
+	return nil, nil
+}
+
+func (f *fakeOrderRepo) EscalateUnconfirmedOrders(_ context.Context, _ time.Time, _ []domain.OutboxKind) ([]*domain.Order, error) {
 	return nil, nil
 }
 
+func (f *fakeOrderRepo) FindUnconfirmedOrderIDs(_ context.Context, _ time.Time) ([]uuid.UUID, error) {
+	return f.unconfirmed, nil
+}
+
 func (f *fakeOrderRepo) FindStatusHistoryByOrderID(_ context.Context, _ uuid.UUID) ([]*domain.OrderStatusHistory, error) {
 	return nil, nil
 }
@@ -76,12 +147,17 @@ func (f *fakeOrderRepo) GetCustomerStats(_ context.Context, _, _ *time.Time, _ *
 	return nil, nil
 }
 
-// fakeCouponService implements couponApplication.CouponService and records the
-// (couponID, userID) of every DecrementUsageAtomic call.
+func (f *fakeOrderRepo) GetDailyTips(_ context.Context, _, _ time.Time) ([]*domain.DailyTipsRow, error) {
+	return nil, nil
+}
+
+// fakeCouponService implements couponApplication.CouponService for the
+// pricing tests.
 type fakeCouponService struct {
-	coupon         *couponDomain.Coupon
-	getByCodeErr   error
-	decrementCalls [][2]uuid.UUID
+	coupon       *couponDomain.Coupon
+	getByCodeErr error
+	discount     decimal.Decimal
+	validateErr  error
 }
 
 func (f *fakeCouponService) GetCouponByCode(_ context.Context, _ string) (*couponDomain.Coupon, error) {
@@ -91,13 +167,15 @@ func (f *fakeCouponService) GetCouponByCode(_ context.Context, _ string) (*coupo
 	return f.coupon, nil
 }
 
-func (f *fakeCouponService) DecrementUsageAtomic(_ context.Context, id uuid.UUID, userID uuid.UUID) error {
-	f.decrementCalls = append(f.decrementCalls, [2]uuid.UUID{id, userID})
+func (f *fakeCouponService) DecrementUsageAtomic(context.Context, uuid.UUID, uuid.UUID) error {
 	return nil
 }
 
 func (f *fakeCouponService) ValidateCoupon(context.Context, string, decimal.Decimal, uuid.UUID) (*couponDomain.Coupon, decimal.Decimal, error) {
-	panic("unused")
+	if f.validateErr != nil {
+		return nil, decimal.Zero, f.validateErr
+	}
+	return f.coupon, f.discount, nil
 }
 func (f *fakeCouponService) IncrementUsage(context.Context, uuid.UUID) error { return nil }
 func (f *fakeCouponService) IncrementUsageAtomic(context.Context, uuid.UUID, uuid.UUID) (bool, error) {
@@ -116,52 +194,415 @@ func strPtr(s string) *string { return &s }
 
 func TestUpdateOrderCouponRollback(t *testing.T) {
 	canceled := domain.OrderStatusCanceled
-	couponID := uuid.New()
 	userID := uuid.New()
 
 	newOrder := func(status domain.OrderStatus, code *string) *domain.Order {
-		return &domain.Order{ID: uuid.New(), UserID: userID, OrderStatus: status, CouponCode: code}
+		return &domain.Order{ID: uuid.New(), UserID: userID, OrderType: domain.OrderTypeDelivery, OrderStatus: status, CouponCode: code}
 	}
 
 	t.Run("cancelling an order with a coupon rolls back usage once", func(t *testing.T) {
 		repo := &fakeOrderRepo{order: newOrder(domain.OrderStatusConfirmed, strPtr("TOKYO10"))}
-		coupons := &fakeCouponService{coupon: &couponDomain.Coupon{ID: couponID}}
-		svc := NewOrderService(repo, coupons)
+		svc := NewOrderService(repo, nil)
 
-		if err := svc.UpdateOrder(context.Background(), repo.order.ID, &canceled, nil, nil); err != nil {
+		if err := svc.UpdateOrder(context.Background(), repo.order.ID, &canceled, nil, nil, UpdateOrderOptions{}); err != nil {
 			t.Fatalf("UpdateOrder: %v", err)
 		}
-		if len(coupons.decrementCalls) != 1 {
-			t.Fatalf("expected 1 rollback, got %d", len(coupons.decrementCalls))
-		}
-		if coupons.decrementCalls[0] != [2]uuid.UUID{couponID, userID} {
-			t.Fatalf("rollback used wrong ids: %v", coupons.decrementCalls[0])
+		if repo.couponReleases != 1 {
+			t.Fatalf("expected 1 rollback, got %d", repo.couponReleases)
 		}
 	})
 
 	t.Run("re-cancelling an already-cancelled order does not roll back again", func(t *testing.T) {
 		repo := &fakeOrderRepo{order: newOrder(domain.OrderStatusCanceled, strPtr("TOKYO10"))}
-		coupons := &fakeCouponService{coupon: &couponDomain.Coupon{ID: couponID}}
-		svc := NewOrderService(repo, coupons)
+		svc := NewOrderService(repo, nil)
 
-		if err := svc.UpdateOrder(context.Background(), repo.order.ID, &canceled, nil, nil); err != nil {
+		if err := svc.UpdateOrder(context.Background(), repo.order.ID, &canceled, nil, nil, UpdateOrderOptions{}); err != nil {
 			t.Fatalf("UpdateOrder: %v", err)
 		}
-		if len(coupons.decrementCalls) != 0 {
-			t.Fatalf("expected no rollback on no-op transition, got %d", len(coupons.decrementCalls))
+		if repo.couponReleases != 0 {
+			t.Fatalf("expected no rollback on no-op transition, got %d", repo.couponReleases)
 		}
 	})
 
 	t.Run("cancelling an order without a coupon rolls back nothing", func(t *testing.T) {
 		repo := &fakeOrderRepo{order: newOrder(domain.OrderStatusConfirmed, nil)}
-		coupons := &fakeCouponService{coupon: &couponDomain.Coupon{ID: couponID}}
-		svc := NewOrderService(repo, coupons)
+		svc := NewOrderService(repo, nil)
+
+		if err := svc.UpdateOrder(context.Background(), repo.order.ID, &canceled, nil, nil, UpdateOrderOptions{}); err != nil {
+			t.Fatalf("UpdateOrder: %v", err)
+		}
+		if repo.couponReleases != 0 {
+			t.Fatalf("expected no rollback without a coupon, got %d", repo.couponReleases)
+		}
+	})
+}
+
+func TestUpdateOrderRecordsEta(t *testing.T) {
+	repo := &fakeOrderRepo{order: &domain.Order{ID: uuid.New(), OrderType: domain.OrderTypePickUp, OrderStatus: domain.OrderStatusPending}}
+	svc := NewOrderService(repo, nil)
+
+	confirmed := domain.OrderStatusConfirmed
+	readyTime := time.Now().Add(30 * time.Minute)
+	eta := &domain.EtaEstimate{OrderID: repo.order.ID, SuggestedReadyTime: readyTime, EstimatedReadyTime: &readyTime, Applied: true}
+	if err := svc.UpdateOrder(context.Background(), repo.order.ID, &confirmed, &readyTime, nil, UpdateOrderOptions{Notify: true, Eta: eta}); err != nil {
+		t.Fatalf("UpdateOrder: %v", err)
+	}
+	if len(repo.etas) != 1 || repo.etas[0] != eta {
+		t.Fatalf("etas written = %v, want the suggestion in the update", repo.etas)
+	}
+}
+
+func TestUpdateOrderStatusTransitions(t *testing.T) {
+	newOrder := func(orderType domain.OrderType, status domain.OrderStatus) *domain.Order {
+		return &domain.Order{ID: uuid.New(), UserID: uuid.New(), OrderType: orderType, OrderStatus: status}
+	}
+	statusPtr := func(s domain.OrderStatus) *domain.OrderStatus { return &s }
+
+	t.Run("allowed transition is persisted and recorded", func(t *testing.T) {
+		repo := &fakeOrderRepo{order: newOrder(domain.OrderTypePickUp, domain.OrderStatusPreparing)}
+		svc := NewOrderService(repo, nil)
+
+		if err := svc.UpdateOrder(context.Background(), repo.order.ID, statusPtr(domain.OrderStatusAwaitingUp), nil, nil, UpdateOrderOptions{}); err != nil {
+			t.Fatalf("UpdateOrder: %v", err)
+		}
+		if repo.updatedOrder == nil || repo.updatedOrder.OrderStatus != domain.OrderStatusAwaitingUp {
+			t.Fatalf("expected order to be updated to AWAITING_PICK_UP, got %+v", repo.updatedOrder)
+		}
+		if len(repo.history) != 1 || repo.history[0] != (historyCall{domain.OrderStatusAwaitingUp, false, domain.Actor{Type: domain.ActorSystem}}) {
+			t.Fatalf("unexpected history rows: %+v", repo.history)
+		}
+	})
+
+	t.Run("invalid transition is rejected without touching the order", func(t *testing.T) {
+		repo := &fakeOrderRepo{order: newOrder(domain.OrderTypePickUp, domain.OrderStatusPreparing)}
+		svc := NewOrderService(repo, nil)
+
+		err := svc.UpdateOrder(context.Background(), repo.order.ID, statusPtr(domain.OrderStatusOutForDelivery), nil, nil, UpdateOrderOptions{})
+		if !errors.Is(err, domain.ErrInvalidStatusTransition) {
+			t.Fatalf("expected ErrInvalidStatusTransition, got %v", err)
+		}
+		if repo.updatedOrder != nil || len(repo.history) != 0 {
+			t.Fatal("rejected transition must not persist anything")
+		}
+	})
+
+	t.Run("forced transition bypasses the table and is flagged in history", func(t *testing.T) {
+		repo := &fakeOrderRepo{order: newOrder(domain.OrderTypeDelivery, domain.OrderStatusDelivered)}
+		svc := NewOrderService(repo, nil)
+
+		if err := svc.UpdateOrder(context.Background(), repo.order.ID, statusPtr(domain.OrderStatusOutForDelivery), nil, nil, UpdateOrderOptions{Force: true}); err != nil {
+			t.Fatalf("UpdateOrder: %v", err)
+		}
+		if len(repo.history) != 1 || repo.history[0] != (historyCall{domain.OrderStatusOutForDelivery, true, domain.Actor{Type: domain.ActorSystem}}) {
+			t.Fatalf("expected a forced history row, got %+v", repo.history)
+		}
+	})
+
+	t.Run("change is attributed to the caller", func(t *testing.T) {
+		staffID := uuid.NewString()
+		cases := []struct {
+			name string
+			ctx  context.Context
+			want domain.Actor
+		}{
+			{"staff", utils.SetIsAdmin(utils.SetUserID(context.Background(), staffID), true), domain.Actor{Type: domain.ActorStaff, ID: staffID}},
+			{"pos", utils.SetIsPOS(utils.SetIsAdmin(utils.SetUserID(context.Background(), staffID), true), true), domain.Actor{Type: domain.ActorPOS, ID: staffID}},
+			{"customer", utils.SetUserID(context.Background(), staffID), domain.Actor{Type: domain.ActorCustomer, ID: staffID}},
+			{"job", utils.SetJob(utils.SetIsAdmin(context.Background(), true), "mollie-webhook"), domain.Actor{Type: domain.ActorSystem, ID: "mollie-webhook"}},
+		}
+		for _, tc := range cases {
+			repo := &fakeOrderRepo{order: newOrder(domain.OrderTypePickUp, domain.OrderStatusPreparing)}
+			svc := NewOrderService(repo, nil)
+			if err := svc.UpdateOrder(tc.ctx, repo.order.ID, statusPtr(domain.OrderStatusAwaitingUp), nil, nil, UpdateOrderOptions{}); err != nil {
+				t.Fatalf("%s: UpdateOrder: %v", tc.name, err)
+			}
+			if len(repo.history) != 1 || repo.history[0].actor != tc.want {
+				t.Errorf("%s: history actor = %+v, want %+v", tc.name, repo.history, tc.want)
+			}
+		}
+	})
+}
 
-		if err := svc.UpdateOrder(context.Background(), repo.order.ID, &canceled, nil, nil); err != nil {
+func TestUpdateOrderReleasesSlotCapacity(t *testing.T) {
+	statusPtr := func(s domain.OrderStatus) *domain.OrderStatus { return &s }
+
+	t.Run("cancelling frees the slot", func(t *testing.T) {
+		repo := &fakeOrderRepo{order: &domain.Order{ID: uuid.New(), OrderType: domain.OrderTypePickUp, OrderStatus: domain.OrderStatusConfirmed}}
+		svc := NewOrderService(repo, nil)
+
+		if err := svc.UpdateOrder(context.Background(), repo.order.ID, statusPtr(domain.OrderStatusCanceled), nil, nil, UpdateOrderOptions{}); err != nil {
 			t.Fatalf("UpdateOrder: %v", err)
 		}
-		if len(coupons.decrementCalls) != 0 {
-			t.Fatalf("expected no rollback without a coupon, got %d", len(coupons.decrementCalls))
+		if repo.slotReleases != 1 {
+			t.Fatalf("slot released %d times, want once", repo.slotReleases)
+		}
+	})
+
+	t.Run("progressing keeps the slot", func(t *testing.T) {
+		repo := &fakeOrderRepo{order: &domain.Order{ID: uuid.New(), OrderType: domain.OrderTypePickUp, OrderStatus: domain.OrderStatusConfirmed}}
+		svc := NewOrderService(repo, nil)
+
+		if err := svc.UpdateOrder(context.Background(), repo.order.ID, statusPtr(domain.OrderStatusPreparing), nil, nil, UpdateOrderOptions{}); err != nil {
+			t.Fatalf("UpdateOrder: %v", err)
+		}
+		if repo.slotReleases != 0 {
+			t.Fatalf("slot released %d times, want never", repo.slotReleases)
+		}
+	})
+}
+
+func TestUpdateOrderOutbox(t *testing.T) {
+	canceled := domain.OrderStatusCanceled
+	newRepo := func() *fakeOrderRepo {
+		return &fakeOrderRepo{order: &domain.Order{ID: uuid.New(), OrderType: domain.OrderTypePickUp, OrderStatus: domain.OrderStatusConfirmed}}
+	}
+
+	t.Run("notify writes the side effects with the update", func(t *testing.T) {
+		repo := newRepo()
+		svc := NewOrderService(repo, nil)
+
+		if err := svc.UpdateOrder(context.Background(), repo.order.ID, &canceled, nil, nil, UpdateOrderOptions{Notify: true}); err != nil {
+			t.Fatalf("UpdateOrder: %v", err)
+		}
+		kinds := make(map[domain.OutboxKind]bool)
+		for _, m := range repo.outbox {
+			if m.OrderID != repo.order.ID {
+				t.Errorf("outbox message for order %s, want %s", m.OrderID, repo.order.ID)
+			}
+			kinds[m.Kind] = true
+		}
+		if !kinds[domain.OutboxRefundFullPayment] || !kinds[domain.OutboxEmailOrderCanceled] || !kinds[domain.OutboxPubsubOrderUpdated] {
+			t.Fatalf("outbox = %v, want refund, cancellation email and orderUpdated", kinds)
+		}
+	})
+
+	t.Run("without notify nothing is enqueued", func(t *testing.T) {
+		repo := newRepo()
+		svc := NewOrderService(repo, nil)
+
+		if err := svc.UpdateOrder(context.Background(), repo.order.ID, &canceled, nil, nil, UpdateOrderOptions{}); err != nil {
+			t.Fatalf("UpdateOrder: %v", err)
+		}
+		if len(repo.outbox) != 0 {
+			t.Fatalf("outbox = %+v, want empty", repo.outbox)
+		}
+	})
+}
+
+func TestCancelForFailedPayment(t *testing.T) {
+	repo := &fakeOrderRepo{order: &domain.Order{ID: uuid.New(), OrderType: domain.OrderTypeDelivery, OrderStatus: domain.OrderStatusPending}}
+	svc := NewOrderService(repo, nil)
+
+	if err := svc.CancelForFailedPayment(context.Background(), repo.order.ID, "payment"); err != nil {
+		t.Fatalf("CancelForFailedPayment: %v", err)
+	}
+	if repo.updatedOrder.OrderStatus != domain.OrderStatusCanceled || repo.updatedOrder.CancellationReason == nil ||
+		*repo.updatedOrder.CancellationReason != domain.OrderCancellationReasonPaymentFailed {
+		t.Fatalf("order = %s (%v), want CANCELLED as PAYMENT_FAILED", repo.updatedOrder.OrderStatus, repo.updatedOrder.CancellationReason)
+	}
+	if len(repo.outbox) != 1 || repo.outbox[0].Kind != domain.OutboxPubsubOrderUpdated {
+		t.Fatalf("outbox = %+v, want only orderUpdated", repo.outbox)
+	}
+	want := domain.NewKeyedOutboxMessages(repo.order.ID, "payment", domain.OutboxEvent{}, domain.PaymentFailedEffects())
+	if repo.outbox[0].ID != want[0].ID {
+		t.Errorf("outbox message ID = %s, want %s keyed by the payment", repo.outbox[0].ID, want[0].ID)
+	}
+}
+
+func TestCancelForFailedPaymentAlreadyCancelled(t *testing.T) {
+	reason := domain.OrderCancellationReasonCustomerRequest
+	repo := &fakeOrderRepo{order: &domain.Order{
+		ID:                 uuid.New(),
+		OrderType:          domain.OrderTypeDelivery,
+		OrderStatus:        domain.OrderStatusCanceled,
+		CancellationReason: &reason,
+		IsOnlinePayment:    true,
+		CreatedAt:          time.Now(),
+	}}
+	svc := NewOrderService(repo, nil)
+
+	if err := svc.CancelForFailedPayment(context.Background(), repo.order.ID, "payment"); err != nil {
+		t.Fatalf("CancelForFailedPayment: %v", err)
+	}
+	if repo.updatedOrder != nil || len(repo.outbox) != 0 {
+		t.Fatalf("order written (%+v) with outbox %+v, want no write", repo.updatedOrder, repo.outbox)
+	}
+	if r := repo.order.CancellationReason; r == nil || *r != domain.OrderCancellationReasonCustomerRequest {
+		t.Errorf("cancellation reason = %v, want CUSTOMER_REQUEST", r)
+	}
+	if err := domain.CheckPaymentRetry(repo.order, time.Now()); !errors.Is(err, domain.ErrPaymentRetryNotAllowed) {
+		t.Errorf("CheckPaymentRetry = %v, want ErrPaymentRetryNotAllowed", err)
+	}
+}
+
+func TestCancelMyOrder(t *testing.T) {
+	grace := 5 * time.Minute
+	newRepo := func(status domain.OrderStatus, age time.Duration) *fakeOrderRepo {
+		return &fakeOrderRepo{order: &domain.Order{
+			ID:          uuid.New(),
+			OrderType:   domain.OrderTypePickUp,
+			OrderStatus: status,
+			CreatedAt:   time.Now().Add(-age),
+		}}
+	}
+
+	t.Run("pending order is cancelled and staff are pushed", func(t *testing.T) {
+		repo := newRepo(domain.OrderStatusPending, time.Hour)
+		svc := NewOrderService(repo, nil)
+
+		if err := svc.CancelMyOrder(context.Background(), repo.order.ID, grace); err != nil {
+			t.Fatalf("CancelMyOrder: %v", err)
+		}
+		if repo.updatedOrder == nil || repo.updatedOrder.OrderStatus != domain.OrderStatusCanceled {
+			t.Fatalf("order not cancelled: %+v", repo.updatedOrder)
+		}
+		if r := repo.updatedOrder.CancellationReason; r == nil || *r != domain.OrderCancellationReasonCustomerRequest {
+			t.Errorf("cancellation reason = %v, want CUSTOMER_REQUEST", r)
+		}
+		kinds := make(map[domain.OutboxKind]bool)
+		for _, m := range repo.outbox {
+			kinds[m.Kind] = true
+		}
+		if !kinds[domain.OutboxPushCustomerCancel] || !kinds[domain.OutboxRefundFullPayment] {
+			t.Errorf("outbox = %v, want the staff push and the refund", kinds)
+		}
+	})
+
+	t.Run("preparing order is rejected", func(t *testing.T) {
+		repo := newRepo(domain.OrderStatusPreparing, time.Minute)
+		svc := NewOrderService(repo, nil)
+
+		err := svc.CancelMyOrder(context.Background(), repo.order.ID, grace)
+		if !errors.Is(err, domain.ErrCustomerCancelNotAllowed) {
+			t.Fatalf("expected ErrCustomerCancelNotAllowed, got %v", err)
+		}
+		if repo.updatedOrder != nil {
+			t.Fatal("order should not have been updated")
+		}
+	})
+
+	t.Run("confirmed order past the grace window is rejected", func(t *testing.T) {
+		repo := newRepo(domain.OrderStatusConfirmed, 10*time.Minute)
+		svc := NewOrderService(repo, nil)
+
+		err := svc.CancelMyOrder(context.Background(), repo.order.ID, grace)
+		if !errors.Is(err, domain.ErrCustomerCancelNotAllowed) {
+			t.Fatalf("expected ErrCustomerCancelNotAllowed, got %v", err)
+		}
+	})
+}
+
+func TestCancelUnconfirmedOrders(t *testing.T) {
+	newRepo := func(status domain.OrderStatus) *fakeOrderRepo {
+		o := &domain.Order{ID: uuid.New(), UserID: uuid.New(), OrderType: domain.OrderTypeDelivery, OrderStatus: status, CouponCode: strPtr("TOKYO10")}
+		return &fakeOrderRepo{order: o, unconfirmed: []uuid.UUID{o.ID}}
+	}
+	ctx := utils.SetJob(utils.SetIsAdmin(context.Background(), true), "unconfirmed-order-sweeper")
+
+	t.Run("pending order is cancelled like a staff cancellation", func(t *testing.T) {
+		repo := newRepo(domain.OrderStatusPending)
+		svc := NewOrderService(repo, nil)
+
+		n, err := svc.CancelUnconfirmedOrders(ctx, 20*time.Minute)
+		if err != nil || n != 1 {
+			t.Fatalf("CancelUnconfirmedOrders = %d, %v", n, err)
+		}
+		if r := repo.updatedOrder.CancellationReason; r == nil || *r != domain.OrderCancellationReasonKitchenClosed {
+			t.Errorf("cancellation reason = %v, want KITCHEN_CLOSED", r)
+		}
+		kinds := make(map[domain.OutboxKind]bool)
+		for _, m := range repo.outbox {
+			kinds[m.Kind] = true
+		}
+		if !kinds[domain.OutboxRefundFullPayment] || !kinds[domain.OutboxEmailOrderCanceled] {
+			t.Errorf("outbox = %v, want the refund and the cancellation email", kinds)
+		}
+		if repo.couponReleases != 1 {
+			t.Errorf("expected the coupon to be rolled back, got %d rollbacks", repo.couponReleases)
+		}
+		want := domain.Actor{Type: domain.ActorSystem, ID: "unconfirmed-order-sweeper"}
+		if len(repo.history) != 1 || repo.history[0].actor != want {
+			t.Errorf("history = %+v, want a row by %+v", repo.history, want)
+		}
+	})
+
+	t.Run("order confirmed since the lookup is left alone", func(t *testing.T) {
+		repo := newRepo(domain.OrderStatusConfirmed)
+		svc := NewOrderService(repo, nil)
+
+		n, err := svc.CancelUnconfirmedOrders(ctx, 20*time.Minute)
+		if err != nil || n != 0 {
+			t.Fatalf("CancelUnconfirmedOrders = %d, %v", n, err)
+		}
+		if repo.updatedOrder != nil {
+			t.Fatal("a confirmed order must not be cancelled")
+		}
+	})
+}
+
+func TestReopenForPaymentRetry(t *testing.T) {
+	newRepo := func(reason domain.OrderCancellationReason, age time.Duration) *fakeOrderRepo {
+		return &fakeOrderRepo{order: &domain.Order{
+			ID:                 uuid.New(),
+			UserID:             uuid.New(),
+			OrderType:          domain.OrderTypeDelivery,
+			IsOnlinePayment:    true,
+			OrderStatus:        domain.OrderStatusCanceled,
+			CancellationReason: &reason,
+			CouponCode:         strPtr("TOKYO10"),
+			CreatedAt:          time.Now().Add(-age),
+		}}
+	}
+
+	t.Run("order cancelled by its payment is reopened with its coupon", func(t *testing.T) {
+		repo := newRepo(domain.OrderCancellationReasonPaymentFailed, 10*time.Minute)
+		svc := NewOrderService(repo, nil)
+
+		order, err := svc.ReopenForPaymentRetry(context.Background(), repo.order.ID, nil)
+		if err != nil {
+			t.Fatalf("ReopenForPaymentRetry: %v", err)
+		}
+		if order.OrderStatus != domain.OrderStatusPending || order.CancellationReason != nil {
+			t.Errorf("order = %s (%v), want PENDING without a reason", order.OrderStatus, order.CancellationReason)
+		}
+		if repo.couponClaims != 1 {
+			t.Errorf("expected the coupon to be taken again with the update, got %d claims", repo.couponClaims)
+		}
+		if len(repo.history) != 1 || repo.history[0].status != domain.OrderStatusPending {
+			t.Errorf("history = %+v, want the reopening", repo.history)
+		}
+		if len(repo.outbox) != 1 || repo.outbox[0].Kind != domain.OutboxPubsubOrderUpdated {
+			t.Fatalf("outbox = %+v, want the reopening announced", repo.outbox)
+		}
+		if ev, _ := repo.outbox[0].Event(); ev.Status != domain.OrderStatusPending || ev.PreviousStatus != domain.OrderStatusCanceled {
+			t.Errorf("event = %+v, want CANCELLED -> PENDING", ev)
+		}
+	})
+
+	t.Run("order cancelled by staff is rejected", func(t *testing.T) {
+		repo := newRepo(domain.OrderCancellationReasonOutOfStock, 10*time.Minute)
+		svc := NewOrderService(repo, nil)
+
+		_, err := svc.ReopenForPaymentRetry(context.Background(), repo.order.ID, nil)
+		if !errors.Is(err, domain.ErrPaymentRetryNotAllowed) {
+			t.Fatalf("expected ErrPaymentRetryNotAllowed, got %v", err)
+		}
+		if repo.updatedOrder != nil {
+			t.Fatal("order should not have been updated")
+		}
+	})
+
+	t.Run("used up coupon is rejected", func(t *testing.T) {
+		repo := newRepo(domain.OrderCancellationReasonPaymentFailed, 10*time.Minute)
+		repo.couponExhausted = true
+		svc := NewOrderService(repo, nil)
+
+		_, err := svc.ReopenForPaymentRetry(context.Background(), repo.order.ID, nil)
+		if !errors.Is(err, domain.ErrPaymentRetryCouponUnavailable) {
+			t.Fatalf("expected ErrPaymentRetryCouponUnavailable, got %v", err)
+		}
+		if repo.updatedOrder != nil {
+			t.Fatal("order should not have been updated")
 		}
 	})
 }
@@ -0,0 +1,128 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"tsb-service/internal/modules/order/domain"
+)
+
+// TableService manages the dining tables, their QR tokens and the sessions
+// their DINE_IN rounds are billed to.
+type TableService interface {
+	ListTables(ctx context.Context) ([]*domain.DiningTable, error)
+	CreateTable(ctx context.Context, table *domain.DiningTable) (*domain.DiningTable, error)
+	UpdateTable(ctx context.Context, table *domain.DiningTable) (*domain.DiningTable, error)
+	RotateTableToken(ctx context.Context, id uuid.UUID) (*domain.DiningTable, error)
+	// TableToken is the token to encode in the table's QR code.
+	TableToken(table *domain.DiningTable) string
+
+	// JoinTable returns the open session of the table the QR token was issued
+	// for, opening one if needed. It returns domain.ErrInvalidTableToken for
+	// forged or rotated tokens and inactive tables.
+	JoinTable(ctx context.Context, token string) (*domain.TableSession, error)
+	GetSession(ctx context.Context, id uuid.UUID) (*domain.TableSession, error)
+	ListOpenSessions(ctx context.Context) ([]*domain.TableSession, error)
+	GetSessionOrders(ctx context.Context, id uuid.UUID) ([]*domain.Order, error)
+	GetBill(ctx context.Context, id uuid.UUID) (*domain.TableBill, error)
+	// SettleSession closes the session with its bill total once every round
+	// is terminal (domain.ErrTableRoundsInProgress otherwise). A session
+	// settled already returns domain.ErrTableSessionClosed.
+	SettleSession(ctx context.Context, id uuid.UUID, method domain.TablePaymentMethod) (*domain.TableSession, error)
+}
+
+type tableService struct {
+	repo      domain.TableRepository
+	orderRepo domain.OrderRepository
+	secret    []byte
+}
+
+// NewTableService signs QR tokens with secret.
+func NewTableService(repo domain.TableRepository, orderRepo domain.OrderRepository, secret []byte) TableService {
+	return &tableService{repo: repo, orderRepo: orderRepo, secret: secret}
+}
+
+func (s *tableService) ListTables(ctx context.Context) ([]*domain.DiningTable, error) {
+	return s.repo.ListTables(ctx)
+}
+
+func (s *tableService) CreateTable(ctx context.Context, table *domain.DiningTable) (*domain.DiningTable, error) {
+	return s.repo.CreateTable(ctx, table)
+}
+
+func (s *tableService) UpdateTable(ctx context.Context, table *domain.DiningTable) (*domain.DiningTable, error) {
+	return s.repo.UpdateTable(ctx, table)
+}
+
+func (s *tableService) RotateTableToken(ctx context.Context, id uuid.UUID) (*domain.DiningTable, error) {
+	return s.repo.RotateToken(ctx, id)
+}
+
+func (s *tableService) TableToken(table *domain.DiningTable) string {
+	return domain.SignTableToken(s.secret, table.ID, table.TokenVersion)
+}
+
+func (s *tableService) JoinTable(ctx context.Context, token string) (*domain.TableSession, error) {
+	tableID, version, err := domain.ParseTableToken(s.secret, token)
+	if err != nil {
+		return nil, err
+	}
+	table, err := s.repo.GetTable(ctx, tableID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrInvalidTableToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load table: %w", err)
+	}
+	if !table.IsActive || table.TokenVersion != version {
+		return nil, domain.ErrInvalidTableToken
+	}
+	return s.repo.OpenSession(ctx, table.ID)
+}
+
+func (s *tableService) GetSession(ctx context.Context, id uuid.UUID) (*domain.TableSession, error) {
+	return s.repo.GetSession(ctx, id)
+}
+
+func (s *tableService) ListOpenSessions(ctx context.Context) ([]*domain.TableSession, error) {
+	return s.repo.ListOpenSessions(ctx)
+}
+
+func (s *tableService) GetSessionOrders(ctx context.Context, id uuid.UUID) ([]*domain.Order, error) {
+	return s.repo.FindSessionOrders(ctx, id)
+}
+
+func (s *tableService) GetBill(ctx context.Context, id uuid.UUID) (*domain.TableBill, error) {
+	orders, err := s.repo.FindSessionOrders(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load table rounds: %w", err)
+	}
+	ids := make([]string, len(orders))
+	for i, o := range orders {
+		ids[i] = o.ID.String()
+	}
+	items, err := s.orderRepo.FindByOrderIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load table round items: %w", err)
+	}
+	bill := domain.NewTableBill(orders, items)
+	return &bill, nil
+}
+
+func (s *tableService) SettleSession(ctx context.Context, id uuid.UUID, method domain.TablePaymentMethod) (*domain.TableSession, error) {
+	session, err := s.repo.CloseSession(ctx, id, method, func(rounds []*domain.Order) (decimal.Decimal, error) {
+		if err := domain.CheckTableSettle(rounds); err != nil {
+			return decimal.Zero, err
+		}
+		return domain.NewTableBill(rounds, nil).Total, nil
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrTableSessionClosed
+	}
+	return session, err
+}
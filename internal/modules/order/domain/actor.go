@@ -0,0 +1,53 @@
+package domain
+
+import "time"
+
+// ActorType is the kind of party that changed an order.
+type ActorType string
+
+const (
+	// ActorCustomer is the customer, from the app or the website.
+	ActorCustomer ActorType = "CUSTOMER"
+	// ActorStaff is a dashboard user.
+	ActorStaff ActorType = "STAFF"
+	// ActorPOS is a POS handheld.
+	ActorPOS ActorType = "POS"
+	// ActorSystem is a webhook or a background job.
+	ActorSystem ActorType = "SYSTEM"
+)
+
+// Actor is who made a change. ID is the user or POS device UUID, or the job
+// name for ActorSystem; it is empty when unknown.
+type Actor struct {
+	Type ActorType
+	ID   string
+}
+
+// NewStatusHistory describes the change of old into updated made by actor.
+// old is nil for the initial status of a new order.
+func NewStatusHistory(old, updated *Order, forced bool, actor Actor) *OrderStatusHistory {
+	h := &OrderStatusHistory{
+		OrderID:   updated.ID,
+		Status:    updated.OrderStatus,
+		Forced:    forced,
+		ActorType: &actor.Type,
+	}
+	if actor.ID != "" {
+		h.ActorID = &actor.ID
+	}
+	if updated.OrderStatus == OrderStatusCanceled {
+		h.CancellationReason = updated.CancellationReason
+	}
+	if old != nil && !sameTime(old.EstimatedReadyTime, updated.EstimatedReadyTime) {
+		h.PreviousEstimatedReadyTime = old.EstimatedReadyTime
+		h.EstimatedReadyTime = updated.EstimatedReadyTime
+	}
+	return h
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestNewStatusHistory(t *testing.T) {
+	at := time.Date(2026, 7, 18, 19, 0, 0, 0, time.UTC)
+	later := at.Add(15 * time.Minute)
+	reason := OrderCancellationReasonOutOfStock
+	staff := Actor{Type: ActorStaff, ID: uuid.NewString()}
+
+	t.Run("initial status has no ready time change", func(t *testing.T) {
+		o := &Order{ID: uuid.New(), OrderStatus: OrderStatusPending, EstimatedReadyTime: &at}
+		h := NewStatusHistory(nil, o, false, Actor{Type: ActorCustomer, ID: "c1"})
+		if *h.ActorType != ActorCustomer || *h.ActorID != "c1" {
+			t.Errorf("actor = %v/%v, want CUSTOMER/c1", *h.ActorType, *h.ActorID)
+		}
+		if h.EstimatedReadyTime != nil || h.PreviousEstimatedReadyTime != nil {
+			t.Errorf("initial entry must not record a ready time change")
+		}
+	})
+
+	t.Run("cancellation keeps its reason", func(t *testing.T) {
+		old := &Order{ID: uuid.New(), OrderStatus: OrderStatusPreparing, EstimatedReadyTime: &at}
+		updated := *old
+		updated.OrderStatus = OrderStatusCanceled
+		updated.CancellationReason = &reason
+		h := NewStatusHistory(old, &updated, true, staff)
+		if h.CancellationReason == nil || *h.CancellationReason != reason || !h.Forced {
+			t.Errorf("unexpected entry %+v", h)
+		}
+		if h.EstimatedReadyTime != nil {
+			t.Errorf("unchanged ready time must not be recorded")
+		}
+	})
+
+	t.Run("ready time change is recorded", func(t *testing.T) {
+		old := &Order{ID: uuid.New(), OrderStatus: OrderStatusConfirmed, EstimatedReadyTime: &at, CancellationReason: &reason}
+		updated := *old
+		updated.OrderStatus = OrderStatusPreparing
+		updated.EstimatedReadyTime = &later
+		h := NewStatusHistory(old, &updated, false, Actor{Type: ActorSystem})
+		if h.PreviousEstimatedReadyTime != &at || h.EstimatedReadyTime != &later {
+			t.Errorf("ready time change = %v -> %v", h.PreviousEstimatedReadyTime, h.EstimatedReadyTime)
+		}
+		if h.CancellationReason != nil {
+			t.Errorf("reason must only be recorded on cancellation")
+		}
+		if h.ActorID != nil {
+			t.Errorf("an unnamed system actor has no ID")
+		}
+	})
+}
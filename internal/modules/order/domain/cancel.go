@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrCustomerCancelNotAllowed is returned when a customer tries to cancel
+	// an order the kitchen may already be working on.
+	ErrCustomerCancelNotAllowed = errors.New("this order can no longer be cancelled, please contact the restaurant")
+	// ErrOrderStatusChanged is returned when the order's status changed between
+	// reading and updating it (e.g. staff confirmed it meanwhile).
+	ErrOrderStatusChanged = errors.New("order status changed concurrently")
+)
+
+// CheckCustomerCancel reports whether the customer may still cancel o: always
+// while it is PENDING, and for grace after it was placed as long as the
+// kitchen hasn't started preparing it (i.e. it is only CONFIRMED).
+func CheckCustomerCancel(o *Order, now time.Time, grace time.Duration) error {
+	switch o.OrderStatus {
+	case OrderStatusPending:
+		return nil
+	case OrderStatusConfirmed:
+		if now.Sub(o.CreatedAt) <= grace {
+			return nil
+		}
+	}
+	return ErrCustomerCancelNotAllowed
+}
+
+// CustomerCancelEffects lists the side effects of a customer cancelling their
+// own order. Unlike a staff cancellation the customer gets no status alert —
+// they just tapped the button — but staff are pushed about it.
+func CustomerCancelEffects(o *Order) []OutboxKind {
+	kinds := []OutboxKind{
+		OutboxRefundFullPayment,
+		OutboxEmailOrderCanceled,
+		OutboxPubsubOrderUpdated,
+		OutboxPushLiveActivity,
+		OutboxPushLiveUpdate,
+	}
+	// Same rule as CreatedOrderEffects: staff never heard of these orders.
+	if !o.IsTest && !o.HeldForSchedule {
+		kinds = append(kinds, OutboxPushCustomerCancel)
+	}
+	return kinds
+}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckCustomerCancel(t *testing.T) {
+	now := time.Date(2026, 7, 8, 19, 0, 0, 0, time.UTC)
+	grace := 5 * time.Minute
+	cases := []struct {
+		name    string
+		status  OrderStatus
+		age     time.Duration
+		allowed bool
+	}{
+		{"pending is always cancellable", OrderStatusPending, time.Hour, true},
+		{"confirmed within grace", OrderStatusConfirmed, 4 * time.Minute, true},
+		{"confirmed after grace", OrderStatusConfirmed, 6 * time.Minute, false},
+		{"preparing within grace", OrderStatusPreparing, time.Minute, false},
+		{"already cancelled", OrderStatusCanceled, time.Minute, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			o := &Order{OrderStatus: tc.status, CreatedAt: now.Add(-tc.age)}
+			err := CheckCustomerCancel(o, now, grace)
+			if tc.allowed && err != nil {
+				t.Fatalf("expected cancel to be allowed, got %v", err)
+			}
+			if !tc.allowed && !errors.Is(err, ErrCustomerCancelNotAllowed) {
+				t.Fatalf("expected ErrCustomerCancelNotAllowed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCustomerCancelEffects(t *testing.T) {
+	has := func(kinds []OutboxKind, want OutboxKind) bool {
+		for _, k := range kinds {
+			if k == want {
+				return true
+			}
+		}
+		return false
+	}
+
+	kinds := CustomerCancelEffects(&Order{})
+	if !has(kinds, OutboxPushCustomerCancel) || !has(kinds, OutboxRefundFullPayment) {
+		t.Errorf("effects = %v, want the staff push and the refund", kinds)
+	}
+	if has(kinds, OutboxPushStatusAlert) {
+		t.Errorf("effects = %v, the customer should not be alerted about their own cancel", kinds)
+	}
+	if kinds := CustomerCancelEffects(&Order{IsTest: true}); has(kinds, OutboxPushCustomerCancel) {
+		t.Errorf("test order effects = %v, want no staff push", kinds)
+	}
+}
@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrSlotFull is returned when reserving capacity in a slot that already
+// reached one of its limits.
+var ErrSlotFull = errors.New("this time slot is fully booked")
+
+// ErrSlotReservationExpired is returned when saving an order whose slot
+// reservation was reaped before the order took it.
+var ErrSlotReservationExpired = errors.New("the time slot reservation expired")
+
+// SlotReservationTTL is how long a reservation may wait for its order before
+// it is reaped. Orders are saved within the request that reserved, so only a
+// crash leaves one behind.
+const SlotReservationTTL = 10 * time.Minute
+
+// SlotStep is the length of a kitchen capacity slot, aligned with the
+// ready-time slots offered to customers.
+const SlotStep = 15 * time.Minute
+
+// SlotLimit caps one slot. A nil OrderType counts every order; zero maxima
+// are unlimited.
+type SlotLimit struct {
+	OrderType *OrderType
+	MaxOrders int
+	MaxItems  int
+}
+
+// SlotUsage is the capacity already reserved in a slot by one order type.
+type SlotUsage struct {
+	SlotStart time.Time `db:"slot_start"`
+	OrderType OrderType `db:"order_type"`
+	Orders    int       `db:"orders"`
+	Items     int       `db:"items"`
+}
+
+// SlotReservation holds capacity for one order. OrderID is nil between the
+// reservation and the order insert; reservations left unattached (a crash in
+// between) are reaped after SlotReservationTTL.
+type SlotReservation struct {
+	ID        uuid.UUID  `db:"id"`
+	OrderID   *uuid.UUID `db:"order_id"`
+	SlotStart time.Time  `db:"slot_start"`
+	OrderType OrderType  `db:"order_type"`
+	ItemCount int        `db:"item_count"`
+	CreatedAt time.Time  `db:"created_at"`
+}
+
+// SlotStart returns the start of the capacity slot containing t.
+func SlotStart(t time.Time) time.Time {
+	return t.Truncate(SlotStep)
+}
+
+// SlotHasRoom reports whether an order of orderType with items items still
+// fits in a slot with the given usage. Pass items 0 to only check that the
+// slot is not already full.
+func SlotHasRoom(limits []SlotLimit, usage []SlotUsage, orderType OrderType, items int) bool {
+	for _, l := range limits {
+		if l.OrderType != nil && *l.OrderType != orderType {
+			continue
+		}
+		orders, used := 0, 0
+		for _, u := range usage {
+			if l.OrderType == nil || u.OrderType == *l.OrderType {
+				orders += u.Orders
+				used += u.Items
+			}
+		}
+		if l.MaxOrders > 0 && orders+1 > l.MaxOrders {
+			return false
+		}
+		if l.MaxItems > 0 && used+max(items, 1) > l.MaxItems {
+			return false
+		}
+	}
+	return true
+}
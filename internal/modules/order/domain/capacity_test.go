@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSlotStart(t *testing.T) {
+	at := time.Date(2026, 7, 6, 12, 29, 59, 0, time.UTC)
+	if got, want := SlotStart(at), time.Date(2026, 7, 6, 12, 15, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("SlotStart = %s, want %s", got, want)
+	}
+}
+
+func TestSlotHasRoom(t *testing.T) {
+	delivery := OrderTypeDelivery
+	usage := []SlotUsage{
+		{OrderType: OrderTypeDelivery, Orders: 2, Items: 6},
+		{OrderType: OrderTypePickUp, Orders: 1, Items: 3},
+	}
+	cases := []struct {
+		name      string
+		limits    []SlotLimit
+		orderType OrderType
+		items     int
+		want      bool
+	}{
+		{"no limits", nil, OrderTypePickUp, 50, true},
+		{"order cap reached", []SlotLimit{{MaxOrders: 3}}, OrderTypePickUp, 1, false},
+		{"order cap with room", []SlotLimit{{MaxOrders: 4}}, OrderTypePickUp, 1, true},
+		{"item cap exceeded by the cart", []SlotLimit{{MaxItems: 12}}, OrderTypePickUp, 4, false},
+		{"item cap fits the cart", []SlotLimit{{MaxItems: 12}}, OrderTypePickUp, 3, true},
+		{"typed limit counts its own type", []SlotLimit{{OrderType: &delivery, MaxOrders: 2}}, OrderTypeDelivery, 1, false},
+		{"typed limit ignores other types", []SlotLimit{{OrderType: &delivery, MaxOrders: 2}}, OrderTypePickUp, 1, true},
+		{"full check without items", []SlotLimit{{MaxItems: 9}}, OrderTypePickUp, 0, false},
+	}
+	for _, tc := range cases {
+		if got := SlotHasRoom(tc.limits, usage, tc.orderType, tc.items); got != tc.want {
+			t.Errorf("%s: SlotHasRoom = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrDriverNotAssignable is returned when assigning a driver to an order
+	// that is not a delivery, or is already finished.
+	ErrDriverNotAssignable = errors.New("a driver can only be assigned to a delivery order that is still in progress")
+	// ErrInvalidLocation is returned for coordinates outside the globe.
+	ErrInvalidLocation = errors.New("latitude must be between -90 and 90 and longitude between -180 and 180")
+)
+
+// Delivery is the courier side of a DELIVERY order: the driver assigned to
+// it, its delivery timeline and the driver's last reported position. The
+// timeline is recorded by the status changes to OUT_FOR_DELIVERY and
+// DELIVERED, whether or not a driver was assigned.
+type Delivery struct {
+	OrderID      uuid.UUID  `db:"order_id"`
+	DriverID     *uuid.UUID `db:"driver_id"`
+	AssignedAt   *time.Time `db:"assigned_at"`
+	DispatchedAt *time.Time `db:"dispatched_at"`
+	DeliveredAt  *time.Time `db:"delivered_at"`
+	Latitude     *float64   `db:"latitude"`
+	Longitude    *float64   `db:"longitude"`
+	Accuracy     *float64   `db:"accuracy"`
+	Heading      *float64   `db:"heading"`
+	LocatedAt    *time.Time `db:"located_at"`
+}
+
+// Location is the driver's last reported position, or nil before the first
+// report.
+func (d *Delivery) Location() *DriverLocation {
+	if d.Latitude == nil || d.Longitude == nil || d.LocatedAt == nil {
+		return nil
+	}
+	return &DriverLocation{
+		OrderID:    d.OrderID,
+		Latitude:   *d.Latitude,
+		Longitude:  *d.Longitude,
+		Accuracy:   d.Accuracy,
+		Heading:    d.Heading,
+		RecordedAt: *d.LocatedAt,
+	}
+}
+
+// DriverLocation is a position reported by a driver's device. Accuracy is
+// in metres and Heading in degrees clockwise from north, when known.
+type DriverLocation struct {
+	OrderID    uuid.UUID
+	Latitude   float64
+	Longitude  float64
+	Accuracy   *float64
+	Heading    *float64
+	RecordedAt time.Time
+}
+
+// Validate reports ErrInvalidLocation for impossible coordinates.
+func (l *DriverLocation) Validate() error {
+	if l.Latitude < -90 || l.Latitude > 90 || l.Longitude < -180 || l.Longitude > 180 {
+		return ErrInvalidLocation
+	}
+	return nil
+}
+
+// CheckDriverAssignable reports whether a driver can be assigned to o: only
+// delivery orders can, until they reach a terminal status. Reassigning an
+// order already out for delivery is allowed, e.g. when a courier hands over.
+func CheckDriverAssignable(o *Order) error {
+	if o.OrderType != OrderTypeDelivery || IsTerminalStatus(o.OrderStatus) {
+		return ErrDriverNotAssignable
+	}
+	return nil
+}
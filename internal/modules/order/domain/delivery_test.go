@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckDriverAssignable(t *testing.T) {
+	tests := []struct {
+		name    string
+		typ     OrderType
+		status  OrderStatus
+		wantErr bool
+	}{
+		{"delivery being prepared", OrderTypeDelivery, OrderStatusPreparing, false},
+		{"delivery out for delivery", OrderTypeDelivery, OrderStatusOutForDelivery, false},
+		{"delivery delivered", OrderTypeDelivery, OrderStatusDelivered, true},
+		{"delivery cancelled", OrderTypeDelivery, OrderStatusCanceled, true},
+		{"pickup", OrderTypePickUp, OrderStatusConfirmed, true},
+		{"dine-in", OrderTypeDineIn, OrderStatusConfirmed, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckDriverAssignable(&Order{OrderType: tt.typ, OrderStatus: tt.status})
+			if got := errors.Is(err, ErrDriverNotAssignable); got != tt.wantErr {
+				t.Errorf("CheckDriverAssignable() err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDriverLocationValidate(t *testing.T) {
+	tests := []struct {
+		lat, lng float64
+		valid    bool
+	}{
+		{50.4541, 3.9523, true},
+		{-90, 180, true},
+		{90.5, 3.9, false},
+		{50.4, -180.1, false},
+	}
+	for _, tt := range tests {
+		l := DriverLocation{Latitude: tt.lat, Longitude: tt.lng}
+		if err := l.Validate(); (err == nil) != tt.valid {
+			t.Errorf("Validate(%v, %v) = %v, want valid %v", tt.lat, tt.lng, err, tt.valid)
+		}
+	}
+}
+
+func TestDeliveryLocation(t *testing.T) {
+	if (&Delivery{}).Location() != nil {
+		t.Error("Location() without a report should be nil")
+	}
+	lat, lng, at := 50.45, 3.95, time.Now()
+	l := (&Delivery{Latitude: &lat, Longitude: &lng, LocatedAt: &at}).Location()
+	if l == nil || l.Latitude != lat || l.Longitude != lng || !l.RecordedAt.Equal(at) {
+		t.Errorf("Location() = %+v", l)
+	}
+}
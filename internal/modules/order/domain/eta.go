@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// etaMinutesPerQueuedOrder is the delay each order already confirmed or
+	// being prepared adds to a new one.
+	etaMinutesPerQueuedOrder = 3
+	// etaFreeItems is how many items fit in the base preparation time; each
+	// further item adds etaMinutesPerItem.
+	etaFreeItems      = 3
+	etaMinutesPerItem = 1
+	// etaRounding is what suggestions are rounded up to, as staff would.
+	etaRounding = 5 * time.Minute
+)
+
+// EtaEstimate is a ready time suggested for an order when it was confirmed,
+// with the inputs it was computed from. EstimatedReadyTime is the time staff
+// confirmed (Applied when it was the suggestion), ActualReadyTime when the
+// order was actually ready: picked up, or delivered.
+type EtaEstimate struct {
+	OrderID            uuid.UUID  `db:"order_id"`
+	SuggestedReadyTime time.Time  `db:"suggested_ready_time"`
+	PreparationMinutes int        `db:"preparation_minutes"`
+	KitchenLoad        int        `db:"kitchen_load"`
+	ItemCount          int        `db:"item_count"`
+	TravelSeconds      int        `db:"travel_seconds"`
+	EstimatedReadyTime *time.Time `db:"estimated_ready_time"`
+	Applied            bool       `db:"applied"`
+	ActualReadyTime    *time.Time `db:"actual_ready_time"`
+	CreatedAt          time.Time  `db:"created_at"`
+}
+
+// SuggestReadyTime estimates when o will be ready, or delivered for a
+// DELIVERY order: the base preparation time, lengthened by the orders ahead
+// in the kitchen and by large orders, plus the route duration. The result is
+// rounded up to five minutes and never earlier than the customer's preferred
+// ready time.
+func SuggestReadyTime(o *Order, e *EtaEstimate, now time.Time) time.Time {
+	minutes := e.PreparationMinutes +
+		e.KitchenLoad*etaMinutesPerQueuedOrder +
+		max(e.ItemCount-etaFreeItems, 0)*etaMinutesPerItem
+	d := time.Duration(minutes) * time.Minute
+	if o.OrderType == OrderTypeDelivery {
+		d += time.Duration(e.TravelSeconds) * time.Second
+	}
+
+	t := now.Add(d)
+	if r := t.Truncate(etaRounding); !r.Equal(t) {
+		t = r.Add(etaRounding)
+	}
+	if o.PreferredReadyTime != nil && o.PreferredReadyTime.After(t) {
+		t = *o.PreferredReadyTime
+	}
+	return t
+}
+
+// IsReadyStatus reports whether reaching status means an order of type typ
+// is ready in the sense of its ETA: waiting at the counter or picked up, or
+// delivered.
+func IsReadyStatus(typ OrderType, status OrderStatus) bool {
+	if typ == OrderTypeDelivery {
+		return status == OrderStatusDelivered
+	}
+	return status == OrderStatusAwaitingUp || status == OrderStatusPickedUp
+}
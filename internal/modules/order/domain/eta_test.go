@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSuggestReadyTime(t *testing.T) {
+	now := time.Date(2026, 7, 14, 18, 2, 0, 0, time.UTC)
+	later := time.Date(2026, 7, 14, 20, 0, 0, 0, time.UTC)
+	at := func(h, m int) time.Time { return time.Date(2026, 7, 14, h, m, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name     string
+		order    Order
+		estimate EtaEstimate
+		want     time.Time
+	}{
+		{
+			name:     "idle kitchen, rounded up",
+			order:    Order{OrderType: OrderTypePickUp},
+			estimate: EtaEstimate{PreparationMinutes: 20, ItemCount: 2},
+			want:     at(18, 25),
+		},
+		{
+			name:     "busy kitchen and a large order",
+			order:    Order{OrderType: OrderTypePickUp},
+			estimate: EtaEstimate{PreparationMinutes: 20, KitchenLoad: 4, ItemCount: 8},
+			want:     at(18, 40), // 20 + 4*3 + 5*1 = 37 min
+		},
+		{
+			name:     "delivery adds the route",
+			order:    Order{OrderType: OrderTypeDelivery},
+			estimate: EtaEstimate{PreparationMinutes: 20, ItemCount: 3, TravelSeconds: 780},
+			want:     at(18, 35), // 20 min + 13 min
+		},
+		{
+			name:     "travel ignored for pickup",
+			order:    Order{OrderType: OrderTypePickUp},
+			estimate: EtaEstimate{PreparationMinutes: 20, TravelSeconds: 780},
+			want:     at(18, 25),
+		},
+		{
+			name:     "never before the preferred time",
+			order:    Order{OrderType: OrderTypePickUp, PreferredReadyTime: &later},
+			estimate: EtaEstimate{PreparationMinutes: 20},
+			want:     later,
+		},
+		{
+			name:     "exact multiple is not rounded",
+			order:    Order{OrderType: OrderTypePickUp},
+			estimate: EtaEstimate{PreparationMinutes: 18},
+			want:     at(18, 20),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SuggestReadyTime(&tt.order, &tt.estimate, now); !got.Equal(tt.want) {
+				t.Errorf("SuggestReadyTime() = %s, want %s", got.Format("15:04"), tt.want.Format("15:04"))
+			}
+		})
+	}
+}
+
+func TestIsReadyStatus(t *testing.T) {
+	tests := []struct {
+		typ    OrderType
+		status OrderStatus
+		want   bool
+	}{
+		{OrderTypePickUp, OrderStatusAwaitingUp, true},
+		{OrderTypePickUp, OrderStatusPickedUp, true},
+		{OrderTypePickUp, OrderStatusPreparing, false},
+		{OrderTypeDelivery, OrderStatusOutForDelivery, false},
+		{OrderTypeDelivery, OrderStatusDelivered, true},
+	}
+	for _, tt := range tests {
+		if got := IsReadyStatus(tt.typ, tt.status); got != tt.want {
+			t.Errorf("IsReadyStatus(%s, %s) = %v, want %v", tt.typ, tt.status, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// ExportLine is one order line of the accounting export, with the figures of
+// its order.
+type ExportLine struct {
+	OrderID          uuid.UUID           `db:"order_id"`
+	CreatedAt        time.Time           `db:"created_at"`
+	OrderStatus      OrderStatus         `db:"order_status"`
+	OrderType        OrderType           `db:"order_type"`
+	PaymentMethod    ReportPaymentMethod `db:"payment_method"`
+	MolliePaymentID  *string             `db:"mollie_payment_id"`
+	TakeawayDiscount decimal.Decimal     `db:"takeaway_discount"`
+	CouponDiscount   decimal.Decimal     `db:"coupon_discount"`
+	CouponCode       *string             `db:"coupon_code"`
+	DeliveryFee      *decimal.Decimal    `db:"delivery_fee"`
+	TransactionFee   decimal.Decimal     `db:"transaction_fee"`
+	Tip              decimal.Decimal     `db:"tip"`
+	TotalPrice       decimal.Decimal     `db:"total_price"`
+	Refunded         decimal.Decimal     `db:"refunded"`
+
+	ProductCode *string         `db:"product_code"`
+	ProductName *string         `db:"product_name"`
+	Quantity    int64           `db:"quantity"`
+	UnitPrice   decimal.Decimal `db:"unit_price"`
+	LineTotal   decimal.Decimal `db:"line_total"`
+	VatRate     decimal.Decimal `db:"vat_rate_applied"`
+}
+
+// LineVat is the VAT included in the line total.
+func (l *ExportLine) LineVat() decimal.Decimal {
+	return VatIncluded(l.LineTotal, l.VatRate).Round(2)
+}
+
+// LineNet is the line total without VAT.
+func (l *ExportLine) LineNet() decimal.Decimal {
+	return l.LineTotal.Sub(l.LineVat())
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrIdempotencyKeyReused is returned when a client sends an idempotency
+	// key it already used for a different request.
+	ErrIdempotencyKeyReused = errors.New("this idempotency key was already used for a different order")
+	// ErrIdempotencyKeyInFlight is returned when the request that first used
+	// the key is still being processed.
+	ErrIdempotencyKeyInFlight = errors.New("this order is still being placed, please try again in a moment")
+	// ErrIdempotencyKeyInvalid is returned for keys longer than
+	// MaxIdempotencyKeyLength.
+	ErrIdempotencyKeyInvalid = errors.New("idempotency key is too long")
+)
+
+const (
+	// IdempotencyKeyTTL is how long a key replays its order. It only has to
+	// outlive client retries.
+	IdempotencyKeyTTL = 24 * time.Hour
+	// IdempotencyClaimLease is how long an unfinished claim blocks its key.
+	// Past it the request that claimed it is assumed dead (pod restart) and
+	// the key can be claimed again.
+	IdempotencyClaimLease = 2 * time.Minute
+	// MaxIdempotencyKeyLength leaves room for any UUID or ULID format clients
+	// may generate.
+	MaxIdempotencyKeyLength = 128
+)
+
+// IdempotencyKey is a client-chosen key scoped to one user. OrderID is nil
+// while the request that claimed the key is still creating the order.
+type IdempotencyKey struct {
+	UserID      uuid.UUID  `db:"user_id"`
+	Key         string     `db:"key"`
+	Fingerprint string     `db:"fingerprint"`
+	OrderID     *uuid.UUID `db:"order_id"`
+	CreatedAt   time.Time  `db:"created_at"`
+	ExpiresAt   time.Time  `db:"expires_at"`
+}
+
+// Replay decides what a request with fingerprint gets from a key another
+// request already claimed: the order it created, or an error when the
+// payloads differ or that order doesn't exist yet.
+func (k *IdempotencyKey) Replay(fingerprint string) (uuid.UUID, error) {
+	if k.Fingerprint != fingerprint {
+		return uuid.Nil, ErrIdempotencyKeyReused
+	}
+	if k.OrderID == nil {
+		return uuid.Nil, ErrIdempotencyKeyInFlight
+	}
+	return *k.OrderID, nil
+}
@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestIdempotencyKeyReplay(t *testing.T) {
+	orderID := uuid.New()
+	cases := []struct {
+		name        string
+		key         IdempotencyKey
+		fingerprint string
+		want        uuid.UUID
+		err         error
+	}{
+		{"same payload replays the order", IdempotencyKey{Fingerprint: "a", OrderID: &orderID}, "a", orderID, nil},
+		{"different payload is rejected", IdempotencyKey{Fingerprint: "a", OrderID: &orderID}, "b", uuid.Nil, ErrIdempotencyKeyReused},
+		{"unfinished claim is in flight", IdempotencyKey{Fingerprint: "a"}, "a", uuid.Nil, ErrIdempotencyKeyInFlight},
+		{"payload mismatch wins over in flight", IdempotencyKey{Fingerprint: "a"}, "b", uuid.Nil, ErrIdempotencyKeyReused},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.key.Replay(tc.fingerprint)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("err = %v, want %v", err, tc.err)
+			}
+			if got != tc.want {
+				t.Fatalf("order = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"cmp"
 	"encoding/json"
 	"time"
+	productDomain "tsb-service/internal/modules/product/domain"
 	"tsb-service/pkg/types"
 
 	"github.com/google/uuid"
@@ -29,18 +30,20 @@ const (
 const (
 	OrderTypeDelivery OrderType = "DELIVERY"
 	OrderTypePickUp   OrderType = "PICKUP"
+	// OrderTypeDineIn orders are rounds placed from a table; they are billed
+	// together when the table session is settled.
+	OrderTypeDineIn OrderType = "DINE_IN"
 )
 
 const (
-	OrderCancellationReasonOutOfStock    OrderCancellationReason = "OUT_OF_STOCK"
-	OrderCancellationReasonKitchenClosed OrderCancellationReason = "KITCHEN_CLOSED"
-	OrderCancellationReasonDeliveryArea  OrderCancellationReason = "DELIVERY_AREA"
-	OrderCancellationReasonOther         OrderCancellationReason = "OTHER"
+	OrderCancellationReasonOutOfStock      OrderCancellationReason = "OUT_OF_STOCK"
+	OrderCancellationReasonKitchenClosed   OrderCancellationReason = "KITCHEN_CLOSED"
+	OrderCancellationReasonDeliveryArea    OrderCancellationReason = "DELIVERY_AREA"
+	OrderCancellationReasonOther           OrderCancellationReason = "OTHER"
+	OrderCancellationReasonCustomerRequest OrderCancellationReason = "CUSTOMER_REQUEST" // set by cancelMyOrder
+	OrderCancellationReasonPaymentFailed   OrderCancellationReason = "PAYMENT_FAILED"   // set by the Mollie webhook
 )
 
-// TransactionFee is charged on online (Mollie) payments to cover PSP costs.
-var TransactionFee = decimal.NewFromFloatWithExponent(0.30, -2)
-
 type Order struct {
 	ID                 uuid.UUID          `db:"id" json:"id"`
 	CreatedAt          time.Time          `db:"created_at" json:"createdAt"`
@@ -80,13 +83,40 @@ type Order struct {
 	// IsTest flags orders placed by store-review accounts. TEMPORARY (revert
 	// after launch): such orders are hidden from staff and auto-cancelled.
 	IsTest bool `db:"is_test" json:"isTest"`
+	// TakeawayDiscountPercent snapshots the pricing rate behind TakeawayDiscount
+	// so receipts and invoices keep the rate the customer actually got.
+	TakeawayDiscountPercent *decimal.Decimal `db:"takeaway_discount_percent" json:"takeawayDiscountPercent,omitempty"`
+	// HeldForSchedule keeps an order scheduled for later out of the staff's
+	// live list until the release sweep clears it, shortly before it is due.
+	HeldForSchedule bool `db:"held_for_schedule" json:"heldForSchedule"`
+	// ReleasedAt is when the release sweep showed a scheduled order to staff.
+	ReleasedAt *time.Time `db:"released_at" json:"releasedAt,omitempty"`
+	// EscalatedAt is when staff were alerted again about the order after
+	// leaving it unconfirmed; an order is escalated once.
+	EscalatedAt *time.Time `db:"escalated_at" json:"escalatedAt,omitempty"`
+	// TableSessionID is the table session a DINE_IN order is a round of.
+	TableSessionID *uuid.UUID `db:"table_session_id" json:"tableSessionId,omitempty"`
+	// Tip is the gratuity added to an online order. It is charged on top of
+	// TotalPrice, is outside the scope of VAT and goes to the staff.
+	Tip decimal.Decimal `db:"tip" json:"tip"`
 }
 
+// OrderStatusHistory is one status change of an order. Entries recorded
+// before actors were tracked have no ActorType.
 type OrderStatusHistory struct {
 	ID        uuid.UUID   `db:"id" json:"id"`
 	OrderID   uuid.UUID   `db:"order_id" json:"orderId"`
 	Status    OrderStatus `db:"status" json:"status"`
+	Forced    bool        `db:"forced" json:"forced"`
 	ChangedAt time.Time   `db:"changed_at" json:"changedAt"`
+	ActorType *ActorType  `db:"actor_type" json:"actorType,omitempty"`
+	ActorID   *string     `db:"actor_id" json:"actorId,omitempty"`
+	// CancellationReason is set on changes to CANCELLED.
+	CancellationReason *OrderCancellationReason `db:"cancellation_reason" json:"cancellationReason,omitempty"`
+	// PreviousEstimatedReadyTime and EstimatedReadyTime are set when the
+	// change also moved the estimated ready time.
+	PreviousEstimatedReadyTime *time.Time `db:"previous_estimated_ready_time" json:"previousEstimatedReadyTime,omitempty"`
+	EstimatedReadyTime         *time.Time `db:"estimated_ready_time" json:"estimatedReadyTime,omitempty"`
 }
 
 type OrderExtra struct {
@@ -146,6 +176,19 @@ type CustomerStatsRow struct {
 	PickupCount    int             `db:"pickup_count"`
 }
 
+// ServiceType is the HubRise service type of the order type, which decides
+// the VAT rate of its lines.
+func (t OrderType) ServiceType() productDomain.ServiceType {
+	switch t {
+	case OrderTypeDelivery:
+		return productDomain.ServiceTypeDelivery
+	case OrderTypeDineIn:
+		return productDomain.ServiceTypeDineIn
+	default:
+		return productDomain.ServiceTypeTakeaway
+	}
+}
+
 // NewOrder is a constructor function that creates a new Order domain object.
 // Prices will be set later in the service layer.
 // DiscountAmount returns the total discount (takeaway + coupon).
@@ -153,6 +196,29 @@ func (o *Order) DiscountAmount() decimal.Decimal {
 	return o.TakeawayDiscount.Add(o.CouponDiscount)
 }
 
+// AppliedTakeawayDiscountPercent returns the takeaway rate snapshotted on the
+// order. Orders placed before pricing became configurable carry no snapshot
+// and were always discounted at 10%.
+func (o *Order) AppliedTakeawayDiscountPercent() decimal.Decimal {
+	if o.TakeawayDiscountPercent != nil {
+		return *o.TakeawayDiscountPercent
+	}
+	return decimal.NewFromInt(10)
+}
+
+// AmountCharged is what the customer pays: the order total plus the tip.
+func (o *Order) AmountCharged() decimal.Decimal {
+	return o.TotalPrice.Add(o.Tip)
+}
+
+// DailyTipsRow is the tips collected on one day (restaurant time), for
+// sharing among the staff.
+type DailyTipsRow struct {
+	Day        time.Time       `db:"day"`
+	OrderCount int             `db:"order_count"`
+	Total      decimal.Decimal `db:"total"`
+}
+
 // AddressSnapshot holds the denormalized address fields for an order.
 type AddressSnapshot struct {
 	StreetID         *string
@@ -183,6 +249,7 @@ func NewOrder(
 	language string,
 	addrSnapshot *AddressSnapshot,
 	cashPaymentAmount *decimal.Decimal,
+	transactionFee decimal.Decimal,
 ) *Order {
 	var orderExtraJSON types.NullableJSON
 	if len(orderExtra) > 0 {
@@ -192,9 +259,9 @@ func NewOrder(
 
 	language = cmp.Or(language, "fr")
 
-	transactionFee := decimal.Zero
-	if isOnlinePayment {
-		transactionFee = TransactionFee
+	// The transaction fee covers PSP costs and only applies to online payments.
+	if !isOnlinePayment {
+		transactionFee = decimal.Zero
 	}
 
 	o := &Order{
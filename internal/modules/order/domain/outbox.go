@@ -0,0 +1,290 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// OutboxKind names one side effect of an order change. Each kind has its own
+// outbox row so a failing email never re-sends a push that already went out.
+type OutboxKind string
+
+const (
+	OutboxPubsubOrderCreated  OutboxKind = "PUBSUB_ORDER_CREATED"
+	OutboxPubsubOrderUpdated  OutboxKind = "PUBSUB_ORDER_UPDATED"
+	OutboxPushNewOrder        OutboxKind = "PUSH_NEW_ORDER"
+	OutboxPushEscalation      OutboxKind = "PUSH_ESCALATION"
+	OutboxPushCustomerCancel  OutboxKind = "PUSH_CUSTOMER_CANCELLED"
+	OutboxPushStatusAlert     OutboxKind = "PUSH_STATUS_ALERT"
+	OutboxPushLiveActivity    OutboxKind = "PUSH_LIVE_ACTIVITY"
+	OutboxPushLiveUpdate      OutboxKind = "PUSH_LIVE_UPDATE"
+	OutboxPushReadyTime       OutboxKind = "PUSH_READY_TIME"
+	OutboxEmailOrderPending   OutboxKind = "EMAIL_ORDER_PENDING"
+	OutboxEmailOrderConfirmed OutboxKind = "EMAIL_ORDER_CONFIRMED"
+	OutboxEmailOrderReady     OutboxKind = "EMAIL_ORDER_READY"
+	OutboxEmailReadyTime      OutboxKind = "EMAIL_READY_TIME_UPDATED"
+	OutboxEmailOrderCanceled  OutboxKind = "EMAIL_ORDER_CANCELED"
+	OutboxEmailRefundIssued   OutboxKind = "EMAIL_REFUND_ISSUED"
+	OutboxEmailDoublePayment  OutboxKind = "EMAIL_DUPLICATE_PAYMENT_REFUNDED"
+	OutboxRefundFullPayment   OutboxKind = "REFUND_FULL_PAYMENT"
+	OutboxRefundSuperseded    OutboxKind = "REFUND_SUPERSEDED_PAYMENT"
+	OutboxWebhookRefund       OutboxKind = "WEBHOOK_REFUND_CREATED"
+	OutboxWebhookPaymentPaid  OutboxKind = "WEBHOOK_PAYMENT_PAID"
+)
+
+type OutboxStatus string
+
+const (
+	OutboxStatusPending   OutboxStatus = "PENDING"
+	OutboxStatusDelivered OutboxStatus = "DELIVERED"
+	OutboxStatusDead      OutboxStatus = "DEAD"
+)
+
+// OutboxMaxAttempts is how many deliveries are tried before a message is
+// dead-lettered.
+const OutboxMaxAttempts = 8
+
+// OutboxEvent is the payload of an outbox message: the order status the side
+// effect was raised for, so a late delivery still describes that change even
+// if the order moved on since.
+type OutboxEvent struct {
+	Status         OrderStatus `json:"status"`
+	PreviousStatus OrderStatus `json:"previousStatus,omitempty"`
+	// RefundAmount is the amount an EMAIL_REFUND_ISSUED or
+	// EMAIL_DUPLICATE_PAYMENT_REFUNDED message announces.
+	RefundAmount *decimal.Decimal `json:"refundAmount,omitempty"`
+	// RefundID is the refund a WEBHOOK_REFUND_CREATED message announces.
+	RefundID *uuid.UUID `json:"refundId,omitempty"`
+	// PaymentID is the Mollie payment a REFUND_SUPERSEDED_PAYMENT or
+	// REFUND_FULL_PAYMENT message refunds, or a WEBHOOK_PAYMENT_PAID message
+	// announces.
+	PaymentID string `json:"paymentId,omitempty"`
+}
+
+type OutboxMessage struct {
+	ID            uuid.UUID       `db:"id"`
+	OrderID       uuid.UUID       `db:"order_id"`
+	Kind          OutboxKind      `db:"kind"`
+	Payload       json.RawMessage `db:"payload"`
+	Status        OutboxStatus    `db:"status"`
+	Attempts      int             `db:"attempts"`
+	NextAttemptAt time.Time       `db:"next_attempt_at"`
+	LastError     *string         `db:"last_error"`
+	CreatedAt     time.Time       `db:"created_at"`
+	DeliveredAt   *time.Time      `db:"delivered_at"`
+}
+
+// NewOutboxMessages builds one pending message per kind for the order change
+// described by event.
+func NewOutboxMessages(orderID uuid.UUID, event OutboxEvent, kinds []OutboxKind) []*OutboxMessage {
+	payload, _ := json.Marshal(event)
+	msgs := make([]*OutboxMessage, len(kinds))
+	for i, kind := range kinds {
+		msgs[i] = &OutboxMessage{
+			ID:      uuid.New(),
+			OrderID: orderID,
+			Kind:    kind,
+			Payload: payload,
+			Status:  OutboxStatusPending,
+		}
+	}
+	return msgs
+}
+
+// NewKeyedOutboxMessages is NewOutboxMessages with each message ID derived
+// from key and the kind, so enqueueing the side effects of the same change
+// again (a retried webhook) adds nothing.
+func NewKeyedOutboxMessages(orderID uuid.UUID, key string, event OutboxEvent, kinds []OutboxKind) []*OutboxMessage {
+	msgs := NewOutboxMessages(orderID, event, kinds)
+	for _, m := range msgs {
+		m.ID = uuid.NewSHA1(uuid.NameSpaceOID, []byte(key+"/"+string(m.Kind)))
+	}
+	return msgs
+}
+
+// Event decodes the message payload.
+func (m *OutboxMessage) Event() (OutboxEvent, error) {
+	var ev OutboxEvent
+	if len(m.Payload) == 0 {
+		return ev, nil
+	}
+	err := json.Unmarshal(m.Payload, &ev)
+	return ev, err
+}
+
+// OutboxBackoff is the delay before retrying a message that failed its
+// attempts-th delivery: 30s doubling each time, capped at one hour.
+func OutboxBackoff(attempts int) time.Duration {
+	const (
+		base    = 30 * time.Second
+		ceiling = time.Hour
+	)
+	if attempts < 1 {
+		attempts = 1
+	}
+	d := base
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= ceiling {
+			return ceiling
+		}
+	}
+	return d
+}
+
+// lateNotificationThreshold caps how long after the estimated ready time we
+// still send status notifications (suppresses very-late noise).
+const lateNotificationThreshold = 40 * time.Minute
+
+// IsOrderUpdateTooLate reports whether now is more than lateNotificationThreshold
+// past the order's estimated ready time. Returns false when no ETA is set.
+func IsOrderUpdateTooLate(now time.Time, estimatedReadyTime *time.Time) bool {
+	if estimatedReadyTime == nil {
+		return false
+	}
+	return now.Sub(*estimatedReadyTime) > lateNotificationThreshold
+}
+
+// CreatedOrderEffects lists the side effects of placing an order. Online
+// payments have none: they are announced by the payment webhook once paid.
+// Store-review test orders and orders held for a later schedule never reach
+// staff. Dine-in guests are at the table, so their rounds send no email.
+func CreatedOrderEffects(o *Order) []OutboxKind {
+	if o.IsOnlinePayment {
+		return nil
+	}
+	var kinds []OutboxKind
+	if o.OrderType != OrderTypeDineIn {
+		kinds = append(kinds, OutboxEmailOrderPending)
+	}
+	if !o.IsTest && !o.HeldForSchedule {
+		kinds = append(kinds, OutboxPubsubOrderCreated, OutboxPushNewOrder)
+	}
+	return kinds
+}
+
+// PaidOrderEffects lists the side effects of an online payment going
+// through: the order is announced to staff the way CreatedOrderEffects
+// announces a cash order, unless it is a test order or held for a later
+// schedule, and webhook endpoints are told of the payment unless it is a test
+// order. An order cancelled before its payment went through (the customer
+// cancelled it while checkout was still open) is refunded instead.
+func PaidOrderEffects(o *Order) []OutboxKind {
+	if o.OrderStatus == OrderStatusCanceled || o.OrderStatus == OrderStatusFailed {
+		return []OutboxKind{OutboxRefundFullPayment}
+	}
+	var kinds []OutboxKind
+	if o.OrderType != OrderTypeDineIn {
+		kinds = append(kinds, OutboxEmailOrderPending)
+	}
+	if !o.IsTest && !o.HeldForSchedule {
+		kinds = append(kinds, OutboxPubsubOrderCreated, OutboxPubsubOrderUpdated, OutboxPushNewOrder)
+	}
+	if !o.IsTest {
+		kinds = append(kinds, OutboxWebhookPaymentPaid)
+	}
+	return kinds
+}
+
+// PaymentFailedEffects lists the side effects of cancelling an order whose
+// online payment failed: subscribers and webhook endpoints hear it is
+// cancelled. The customer is not emailed, as they often retry the payment.
+func PaymentFailedEffects() []OutboxKind {
+	return []OutboxKind{OutboxPubsubOrderUpdated}
+}
+
+// RefundEffects lists the side effects of a refund of the order's payment:
+// the customer is emailed and the webhook endpoints are told.
+func RefundEffects() []OutboxKind {
+	return []OutboxKind{OutboxEmailRefundIssued, OutboxWebhookRefund}
+}
+
+// SupersededRefundEffects lists the side effects of refunding a payment
+// attempt the order no longer counts on: the customer is told their duplicate
+// payment was returned. It is not a refund of the order, so webhook endpoints
+// are not told.
+func SupersededRefundEffects() []OutboxKind {
+	return []OutboxKind{OutboxEmailDoublePayment}
+}
+
+// ReleasedOrderEffects lists the side effects of releasing a scheduled order
+// to staff: it is announced the way CreatedOrderEffects announces an ASAP
+// order.
+func ReleasedOrderEffects() []OutboxKind {
+	return []OutboxKind{OutboxPubsubOrderCreated, OutboxPubsubOrderUpdated, OutboxPushNewOrder}
+}
+
+// ReopenedOrderEffects lists the side effects of reopening an order for a
+// payment retry: subscribers and webhook endpoints hear it is pending again.
+// Staff are announced the order by its payment, as for any online order.
+func ReopenedOrderEffects() []OutboxKind {
+	return []OutboxKind{OutboxPubsubOrderUpdated}
+}
+
+// SupersededPaymentEffects lists the side effects of an earlier payment
+// attempt of an order going through after the customer retried: it is
+// refunded, the latest attempt being the one that pays the order.
+func SupersededPaymentEffects() []OutboxKind {
+	return []OutboxKind{OutboxRefundSuperseded}
+}
+
+// EscalatedOrderEffects lists the side effects of escalating an order staff
+// left unconfirmed: a reminder push to staff.
+func EscalatedOrderEffects() []OutboxKind {
+	return []OutboxKind{OutboxPushEscalation}
+}
+
+// StatusChangeEffects lists the side effects of a staff update moving an order
+// from old to updated. readyTimeChanged reports whether the update set a new
+// estimated ready time.
+func StatusChangeEffects(old, updated *Order, readyTimeChanged bool, now time.Time) []OutboxKind {
+	from, to := old.OrderStatus, updated.OrderStatus
+	statusChanged := from != to
+
+	// Skip progression-status notifications when the update lands well after
+	// the customer has likely already received the order. CANCELLED / FAILED
+	// stay exempt — those carry information regardless of timing (refund,
+	// "your order wasn't fulfilled").
+	suppressLate := IsOrderUpdateTooLate(now, updated.EstimatedReadyTime) &&
+		to != OrderStatusCanceled && to != OrderStatusFailed
+	isInitialConfirmation := from == OrderStatusPending &&
+		(to == OrderStatusConfirmed || to == OrderStatusPreparing)
+	// The driver hands a delivery over in person, so a "delivered" alert would
+	// only be noise.
+	suppressDelivered := from == OrderStatusOutForDelivery && to == OrderStatusDelivered
+
+	// Dine-in guests are at the table: no emails.
+	emails := updated.OrderType != OrderTypeDineIn
+
+	var kinds []OutboxKind
+	if isInitialConfirmation && emails {
+		kinds = append(kinds, OutboxEmailOrderConfirmed)
+	}
+	if (to == OrderStatusAwaitingUp || to == OrderStatusOutForDelivery) && !suppressLate && emails {
+		kinds = append(kinds, OutboxEmailOrderReady)
+	}
+	readyTimeChanged = readyTimeChanged && !isInitialConfirmation
+	if readyTimeChanged && emails {
+		kinds = append(kinds, OutboxEmailReadyTime)
+	}
+	if to == OrderStatusCanceled {
+		kinds = append(kinds, OutboxRefundFullPayment)
+		if emails {
+			kinds = append(kinds, OutboxEmailOrderCanceled)
+		}
+	}
+	kinds = append(kinds, OutboxPubsubOrderUpdated)
+	if statusChanged && !suppressDelivered && !suppressLate {
+		kinds = append(kinds, OutboxPushStatusAlert)
+	}
+	if statusChanged {
+		kinds = append(kinds, OutboxPushLiveActivity, OutboxPushLiveUpdate)
+	}
+	if readyTimeChanged {
+		kinds = append(kinds, OutboxPushReadyTime)
+	}
+	return kinds
+}
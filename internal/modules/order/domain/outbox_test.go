@@ -0,0 +1,145 @@
+package domain
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestOutboxBackoff(t *testing.T) {
+	cases := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{4, 4 * time.Minute},
+		{8, time.Hour},
+	}
+	for _, tc := range cases {
+		if got := OutboxBackoff(tc.attempts); got != tc.want {
+			t.Errorf("OutboxBackoff(%d) = %s, want %s", tc.attempts, got, tc.want)
+		}
+	}
+}
+
+func TestCreatedOrderEffects(t *testing.T) {
+	if got := CreatedOrderEffects(&Order{IsOnlinePayment: true}); len(got) != 0 {
+		t.Errorf("online order effects = %v, want none until paid", got)
+	}
+	got := CreatedOrderEffects(&Order{})
+	want := []OutboxKind{OutboxEmailOrderPending, OutboxPubsubOrderCreated, OutboxPushNewOrder}
+	if !slices.Equal(got, want) {
+		t.Errorf("cash order effects = %v, want %v", got, want)
+	}
+	got = CreatedOrderEffects(&Order{HeldForSchedule: true})
+	if !slices.Equal(got, []OutboxKind{OutboxEmailOrderPending}) {
+		t.Errorf("held order effects = %v, want only the pending email", got)
+	}
+	got = CreatedOrderEffects(&Order{OrderType: OrderTypeDineIn})
+	if !slices.Equal(got, []OutboxKind{OutboxPubsubOrderCreated, OutboxPushNewOrder}) {
+		t.Errorf("dine-in round effects = %v, want staff effects only", got)
+	}
+}
+
+func TestPaidOrderEffects(t *testing.T) {
+	got := PaidOrderEffects(&Order{})
+	want := []OutboxKind{OutboxEmailOrderPending, OutboxPubsubOrderCreated, OutboxPubsubOrderUpdated, OutboxPushNewOrder, OutboxWebhookPaymentPaid}
+	if !slices.Equal(got, want) {
+		t.Errorf("paid order effects = %v, want %v", got, want)
+	}
+	got = PaidOrderEffects(&Order{HeldForSchedule: true})
+	if !slices.Equal(got, []OutboxKind{OutboxEmailOrderPending, OutboxWebhookPaymentPaid}) {
+		t.Errorf("held order effects = %v, want the pending email and payment webhook", got)
+	}
+	got = PaidOrderEffects(&Order{IsTest: true})
+	if !slices.Equal(got, []OutboxKind{OutboxEmailOrderPending}) {
+		t.Errorf("test order effects = %v, want only the pending email", got)
+	}
+	got = PaidOrderEffects(&Order{OrderStatus: OrderStatusCanceled})
+	if !slices.Equal(got, []OutboxKind{OutboxRefundFullPayment}) {
+		t.Errorf("cancelled order effects = %v, want only the refund", got)
+	}
+}
+
+func TestStatusChangeEffects(t *testing.T) {
+	now := time.Date(2026, 5, 13, 19, 0, 0, 0, time.UTC)
+	lateETA := now.Add(-time.Hour)
+	order := func(status OrderStatus, eta *time.Time) *Order {
+		return &Order{OrderStatus: status, EstimatedReadyTime: eta}
+	}
+	cases := []struct {
+		name      string
+		old, next *Order
+		readyTime bool
+		want      []OutboxKind
+	}{
+		{
+			"confirmation with an ETA",
+			order(OrderStatusPending, nil), order(OrderStatusConfirmed, nil), true,
+			[]OutboxKind{OutboxEmailOrderConfirmed, OutboxPubsubOrderUpdated, OutboxPushStatusAlert, OutboxPushLiveActivity, OutboxPushLiveUpdate},
+		},
+		{
+			"ETA change only",
+			order(OrderStatusPreparing, nil), order(OrderStatusPreparing, nil), true,
+			[]OutboxKind{OutboxEmailReadyTime, OutboxPubsubOrderUpdated, OutboxPushReadyTime},
+		},
+		{
+			"ready for pick-up",
+			order(OrderStatusPreparing, nil), order(OrderStatusAwaitingUp, nil), false,
+			[]OutboxKind{OutboxEmailOrderReady, OutboxPubsubOrderUpdated, OutboxPushStatusAlert, OutboxPushLiveActivity, OutboxPushLiveUpdate},
+		},
+		{
+			"dine-in ready has no email",
+			&Order{OrderType: OrderTypeDineIn, OrderStatus: OrderStatusPreparing},
+			&Order{OrderType: OrderTypeDineIn, OrderStatus: OrderStatusAwaitingUp}, false,
+			[]OutboxKind{OutboxPubsubOrderUpdated, OutboxPushStatusAlert, OutboxPushLiveActivity, OutboxPushLiveUpdate},
+		},
+		{
+			"late progression is silent",
+			order(OrderStatusPreparing, &lateETA), order(OrderStatusAwaitingUp, &lateETA), false,
+			[]OutboxKind{OutboxPubsubOrderUpdated, OutboxPushLiveActivity, OutboxPushLiveUpdate},
+		},
+		{
+			"delivered has no alert",
+			order(OrderStatusOutForDelivery, nil), order(OrderStatusDelivered, nil), false,
+			[]OutboxKind{OutboxPubsubOrderUpdated, OutboxPushLiveActivity, OutboxPushLiveUpdate},
+		},
+		{
+			"late cancellation still refunds and alerts",
+			order(OrderStatusPreparing, &lateETA), order(OrderStatusCanceled, &lateETA), false,
+			[]OutboxKind{OutboxRefundFullPayment, OutboxEmailOrderCanceled, OutboxPubsubOrderUpdated, OutboxPushStatusAlert, OutboxPushLiveActivity, OutboxPushLiveUpdate},
+		},
+	}
+	for _, tc := range cases {
+		if got := StatusChangeEffects(tc.old, tc.next, tc.readyTime, now); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: effects = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestIsOrderUpdateTooLate(t *testing.T) {
+	now := time.Date(2026, 5, 13, 19, 0, 0, 0, time.UTC)
+	ptr := func(d time.Duration) *time.Time {
+		ts := now.Add(d)
+		return &ts
+	}
+	tests := []struct {
+		name string
+		eta  *time.Time
+		want bool
+	}{
+		{"nil ETA never suppresses", nil, false},
+		{"ETA in the future", ptr(30 * time.Minute), false},
+		{"ETA 30 min ago — still inside threshold", ptr(-30 * time.Minute), false},
+		{"ETA exactly 40 min ago — boundary, not yet stale", ptr(-40 * time.Minute), false},
+		{"ETA 41 min ago — over threshold", ptr(-41 * time.Minute), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsOrderUpdateTooLate(now, tt.eta); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCursor is returned for a pagination cursor that was not issued
+// by OrderCursor.Encode.
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+// MaxPageSize caps how many orders a single page may hold.
+const MaxPageSize = 200
+
+// OrderCursor is the position of an order in a list sorted newest first.
+// The ID breaks ties between orders created in the same instant, so paging
+// neither skips nor repeats orders while new ones arrive.
+type OrderCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// CursorOf returns the cursor of o.
+func CursorOf(o *Order) OrderCursor {
+	return OrderCursor{CreatedAt: o.CreatedAt, ID: o.ID}
+}
+
+// Encode returns the cursor as an opaque string for clients.
+func (c OrderCursor) Encode() string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixMicro(), 10) + ":" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeOrderCursor parses a cursor returned by Encode.
+func DecodeOrderCursor(s string) (OrderCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return OrderCursor{}, ErrInvalidCursor
+	}
+	micros, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return OrderCursor{}, ErrInvalidCursor
+	}
+	usec, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return OrderCursor{}, ErrInvalidCursor
+	}
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return OrderCursor{}, ErrInvalidCursor
+	}
+	return OrderCursor{CreatedAt: time.UnixMicro(usec).UTC(), ID: uid}, nil
+}
+
+// OrderPageRequest asks for the First orders after the After cursor, or from
+// the newest order when After is nil.
+type OrderPageRequest struct {
+	First int
+	After *OrderCursor
+}
+
+// OrderPage is one page of an order list, newest first. TotalCount counts
+// every order of the list, on all pages.
+type OrderPage struct {
+	Orders      []*Order
+	HasNextPage bool
+	TotalCount  int
+}
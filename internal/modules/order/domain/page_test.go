@@ -0,0 +1,23 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestOrderCursor(t *testing.T) {
+	c := OrderCursor{CreatedAt: time.Date(2026, 7, 16, 12, 30, 5, 123456000, time.UTC), ID: uuid.New()}
+	got, err := DecodeOrderCursor(c.Encode())
+	if err != nil || !got.CreatedAt.Equal(c.CreatedAt) || got.ID != c.ID {
+		t.Fatalf("DecodeOrderCursor(Encode()) = %+v, %v; want %+v", got, err, c)
+	}
+
+	for _, s := range []string{"", "!!", "bm90LWEtY3Vyc29y", c.Encode()[:10]} {
+		if _, err := DecodeOrderCursor(s); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeOrderCursor(%q) err = %v, want ErrInvalidCursor", s, err)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// PaymentRetryWindow is how long after placing an order whose online payment
+// failed the customer may pay it again instead of rebuilding their cart.
+const PaymentRetryWindow = 30 * time.Minute
+
+var (
+	// ErrPaymentRetryNotAllowed is returned when retrying the payment of an
+	// order that was not cancelled by a failed payment, or too long ago.
+	ErrPaymentRetryNotAllowed = errors.New("this order can no longer be paid, please place a new order")
+	// ErrPaymentRetryCouponUnavailable is returned when the coupon the order
+	// was placed with can no longer be used.
+	ErrPaymentRetryCouponUnavailable = errors.New("the coupon of this order is no longer valid, please place a new order")
+)
+
+// CheckPaymentRetry reports whether the customer may still pay o again: it
+// must be an online order cancelled because its payment failed, placed at
+// most PaymentRetryWindow ago.
+func CheckPaymentRetry(o *Order, now time.Time) error {
+	if !o.IsOnlinePayment || o.OrderStatus != OrderStatusCanceled ||
+		o.CancellationReason == nil || *o.CancellationReason != OrderCancellationReasonPaymentFailed {
+		return ErrPaymentRetryNotAllowed
+	}
+	if now.Sub(o.CreatedAt) > PaymentRetryWindow {
+		return ErrPaymentRetryNotAllowed
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckPaymentRetry(t *testing.T) {
+	now := time.Date(2026, 7, 20, 19, 0, 0, 0, time.UTC)
+	paymentFailed := OrderCancellationReasonPaymentFailed
+	customer := OrderCancellationReasonCustomerRequest
+	cases := []struct {
+		name    string
+		online  bool
+		status  OrderStatus
+		reason  *OrderCancellationReason
+		age     time.Duration
+		allowed bool
+	}{
+		{"payment failed within the window", true, OrderStatusCanceled, &paymentFailed, 10 * time.Minute, true},
+		{"payment failed too long ago", true, OrderStatusCanceled, &paymentFailed, 31 * time.Minute, false},
+		{"cancelled for another reason", true, OrderStatusCanceled, &customer, time.Minute, false},
+		{"still pending", true, OrderStatusPending, nil, time.Minute, false},
+		{"cash order", false, OrderStatusCanceled, &paymentFailed, time.Minute, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			o := &Order{
+				IsOnlinePayment:    tc.online,
+				OrderStatus:        tc.status,
+				CancellationReason: tc.reason,
+				CreatedAt:          now.Add(-tc.age),
+			}
+			err := CheckPaymentRetry(o, now)
+			if tc.allowed && err != nil {
+				t.Fatalf("expected retry to be allowed, got %v", err)
+			}
+			if !tc.allowed && !errors.Is(err, ErrPaymentRetryNotAllowed) {
+				t.Fatalf("expected ErrPaymentRetryNotAllowed, got %v", err)
+			}
+		})
+	}
+}
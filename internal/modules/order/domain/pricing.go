@@ -0,0 +1,189 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"tsb-service/pkg/money"
+)
+
+// CartSelection is one choice picked inside a product's choice group.
+type CartSelection struct {
+	GroupID  uuid.UUID
+	ChoiceID uuid.UUID
+	Quantity int
+}
+
+// CartItem is a product line as submitted by the apps.
+type CartItem struct {
+	ProductID  uuid.UUID
+	Quantity   int
+	ChoiceID   *uuid.UUID
+	Selections []CartSelection
+}
+
+// Cart is everything needed to price an order without persisting it.
+type Cart struct {
+	UserID             uuid.UUID
+	OrderType          OrderType
+	IsOnlinePayment    bool
+	AddressPlaceID     *string
+	PreferredReadyTime *time.Time
+	CouponCode         *string
+	Language           string
+	Items              []CartItem
+	// TipAmount (a decimal string) and TipPercent are the two ways to add a
+	// tip; at most one may be set.
+	TipAmount  *string
+	TipPercent *int
+
+	// AllowLunchOnly and PreparationBuffer come from the opening-hours gate.
+	// A zero PreparationBuffer means the gate was skipped (dev mode, store
+	// review) and so is the delivery zone's extra preparation check.
+	AllowLunchOnly    bool
+	PreparationBuffer time.Duration
+}
+
+// PricingIssue is a validation failure found while pricing a cart. Field
+// names the offending input ("couponCode", "items.2", ...) and is empty when
+// the issue concerns the cart as a whole.
+type PricingIssue struct {
+	Field   string
+	Message string
+}
+
+func (i PricingIssue) Error() string {
+	return i.Message
+}
+
+// QuoteLine is a priced cart line, ready to be persisted as an order product.
+// Index is the line's position in Cart.Items.
+type QuoteLine struct {
+	OrderProductRaw
+	Index        int
+	Product      Product
+	Discountable bool
+}
+
+// VatLine is the VAT included in the line totals for one rate.
+type VatLine struct {
+	Rate   decimal.Decimal
+	Amount decimal.Decimal
+}
+
+// Quote is the priced cart. Totals are computed on the valid lines only, so
+// a quote with issues still shows the customer where they stand.
+type Quote struct {
+	Lines                   []QuoteLine
+	ItemsTotal              decimal.Decimal
+	TakeawayDiscount        decimal.Decimal
+	TakeawayDiscountPercent *decimal.Decimal
+	CouponDiscount          decimal.Decimal
+	CouponCode              *string
+	CouponID                *uuid.UUID
+	DeliveryFee             decimal.Decimal
+	TransactionFee          decimal.Decimal
+	Total                   decimal.Decimal
+	Tip                     decimal.Decimal
+	VatBreakdown            []VatLine
+	Address                 *AddressSnapshot
+	Issues                  []PricingIssue
+}
+
+// AddIssue records a validation failure on the quote.
+func (q *Quote) AddIssue(field, format string, args ...any) {
+	q.Issues = append(q.Issues, PricingIssue{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// ItemCount is the number of units in the cart, used against per-slot item
+// capacity.
+func (c Cart) ItemCount() int {
+	n := 0
+	for _, it := range c.Items {
+		if it.Quantity > 0 {
+			n += it.Quantity
+		}
+	}
+	return n
+}
+
+// RawItems returns the priced lines in the shape the order repository saves.
+func (q *Quote) RawItems() []OrderProductRaw {
+	out := make([]OrderProductRaw, len(q.Lines))
+	for i, l := range q.Lines {
+		out[i] = l.OrderProductRaw
+	}
+	return out
+}
+
+// MaxTipPercent caps a tip given as a percentage of the order total.
+const MaxTipPercent = 30
+
+// TipFromPercent is a percentage of the order total, snapped to 0,10 € like
+// every other amount the customer sees.
+func TipFromPercent(total decimal.Decimal, percent int) decimal.Decimal {
+	return money.RoundToNearest10Cents(total.Mul(decimal.NewFromInt(int64(percent))).Div(decimal.NewFromInt(100)))
+}
+
+// CapDiscounts scales both discounts down proportionally when together they
+// exceed the amount they apply to, then snaps them to 0,10 €.
+func CapDiscounts(amount, takeaway, coupon decimal.Decimal) (decimal.Decimal, decimal.Decimal) {
+	total := takeaway.Add(coupon)
+	if !total.GreaterThan(amount) {
+		return takeaway, coupon
+	}
+	ratio := amount.Div(total)
+	takeaway = money.RoundToNearest10Cents(takeaway.Mul(ratio))
+	coupon = money.RoundToNearest10Cents(amount.Sub(takeaway))
+	return takeaway, coupon
+}
+
+// ComputeTotal is the amount charged for an order. The order repository uses
+// it when saving, and quotes use it so the quoted and charged totals match.
+func ComputeTotal(itemsTotal, deliveryFee, takeawayDiscount, couponDiscount, transactionFee decimal.Decimal) decimal.Decimal {
+	total := itemsTotal.Add(deliveryFee)
+	if discount := takeawayDiscount.Add(couponDiscount); discount.GreaterThan(decimal.Zero) {
+		total = total.Sub(discount)
+	}
+	if transactionFee.GreaterThan(decimal.Zero) {
+		total = total.Add(transactionFee)
+	}
+	// Snap the total to 0,10 € — defensive: with rounded discounts and
+	// .x0-priced products this is already a no-op, but it guards future
+	// pricing changes (.x5 unit prices, fractional delivery fees, etc.).
+	return money.RoundToNearest10Cents(total)
+}
+
+// VatIncluded is the VAT included in a VAT-inclusive amount at rate
+// (a percentage), unrounded.
+func VatIncluded(gross, rate decimal.Decimal) decimal.Decimal {
+	return gross.Mul(rate).Div(decimal.NewFromInt(100).Add(rate))
+}
+
+// ComputeVatBreakdown sums the VAT included in each line total per rate,
+// highest rate first, the same way the invoice does.
+func ComputeVatBreakdown(lines []OrderProductRaw) []VatLine {
+	byRate := map[string]VatLine{}
+	for _, l := range lines {
+		if l.VatRateApplied.IsZero() {
+			continue
+		}
+		amount := VatIncluded(l.TotalPrice, l.VatRateApplied)
+		key := l.VatRateApplied.StringFixed(2)
+		v := byRate[key]
+		v.Rate = l.VatRateApplied
+		v.Amount = v.Amount.Add(amount)
+		byRate[key] = v
+	}
+	out := make([]VatLine, 0, len(byRate))
+	for _, v := range byRate {
+		v.Amount = v.Amount.Round(2)
+		out = append(out, v)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Rate.GreaterThan(out[j].Rate) })
+	return out
+}
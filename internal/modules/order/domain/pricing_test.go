@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func dec(s string) decimal.Decimal { return decimal.RequireFromString(s) }
+
+func TestCapDiscounts(t *testing.T) {
+	cases := []struct {
+		name                     string
+		amount, takeaway, coupon string
+		wantTakeaway, wantCoupon string
+	}{
+		{"under the amount", "30", "2", "5", "2", "5"},
+		{"exactly the amount", "10", "4", "6", "4", "6"},
+		{"scaled down", "10", "5", "15", "2.5", "7.5"},
+	}
+	for _, tc := range cases {
+		takeaway, coupon := CapDiscounts(dec(tc.amount), dec(tc.takeaway), dec(tc.coupon))
+		if !takeaway.Equal(dec(tc.wantTakeaway)) || !coupon.Equal(dec(tc.wantCoupon)) {
+			t.Errorf("%s: got (%s, %s), want (%s, %s)", tc.name, takeaway, coupon, tc.wantTakeaway, tc.wantCoupon)
+		}
+	}
+}
+
+func TestComputeTotal(t *testing.T) {
+	got := ComputeTotal(dec("27.50"), dec("1"), dec("2.40"), dec("0"), dec("0.30"))
+	if !got.Equal(dec("26.40")) {
+		t.Errorf("ComputeTotal = %s, want 26.40", got)
+	}
+}
+
+func TestComputeVatBreakdown(t *testing.T) {
+	lines := []OrderProductRaw{
+		{TotalPrice: dec("10.60"), VatRateApplied: dec("6")},
+		{TotalPrice: dec("5.30"), VatRateApplied: dec("6")},
+		{TotalPrice: dec("12.10"), VatRateApplied: dec("21")},
+		{TotalPrice: dec("3"), VatRateApplied: dec("0")},
+	}
+	got := ComputeVatBreakdown(lines)
+	if len(got) != 2 {
+		t.Fatalf("breakdown = %+v, want two rates", got)
+	}
+	if !got[0].Rate.Equal(dec("21")) || !got[0].Amount.Equal(dec("2.10")) {
+		t.Errorf("first line = %+v, want 21%% / 2.10", got[0])
+	}
+	if !got[1].Rate.Equal(dec("6")) || !got[1].Amount.Equal(dec("0.90")) {
+		t.Errorf("second line = %+v, want 6%% / 0.90", got[1])
+	}
+}
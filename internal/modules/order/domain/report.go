@@ -0,0 +1,200 @@
+package domain
+
+import (
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	productDomain "tsb-service/internal/modules/product/domain"
+	"tsb-service/pkg/timezone"
+)
+
+// ReportPaymentMethod is how the customer paid: online through Mollie, or at
+// the restaurant. Dine-in rounds take the method their table was settled with.
+type ReportPaymentMethod string
+
+const (
+	ReportPaymentMethodOnline ReportPaymentMethod = "ONLINE"
+	ReportPaymentMethodCash   ReportPaymentMethod = "CASH"
+	ReportPaymentMethodCard   ReportPaymentMethod = "CARD"
+)
+
+// ReportOrderRow is one order placed on the reported day.
+type ReportOrderRow struct {
+	OrderType        OrderType           `db:"order_type"`
+	OrderStatus      OrderStatus         `db:"order_status"`
+	PaymentMethod    ReportPaymentMethod `db:"payment_method"`
+	TakeawayDiscount decimal.Decimal     `db:"takeaway_discount"`
+	CouponDiscount   decimal.Decimal     `db:"coupon_discount"`
+	DeliveryFee      *decimal.Decimal    `db:"delivery_fee"`
+	TransactionFee   decimal.Decimal     `db:"transaction_fee"`
+	TotalPrice       decimal.Decimal     `db:"total_price"`
+	Tip              decimal.Decimal     `db:"tip"`
+}
+
+// ReportVatRow is the line total of the day's sales for one VAT category,
+// order type and applied rate.
+type ReportVatRow struct {
+	VatCategory productDomain.VatCategory `db:"vat_category"`
+	OrderType   OrderType                 `db:"order_type"`
+	Rate        decimal.Decimal           `db:"vat_rate_applied"`
+	Gross       decimal.Decimal           `db:"gross"`
+}
+
+// ReportRefundTotals is what was refunded on the reported day, whatever day
+// the refunded orders were placed.
+type ReportRefundTotals struct {
+	Count  int             `db:"refund_count"`
+	Amount decimal.Decimal `db:"refund_amount"`
+}
+
+// ReportBucket counts the orders of one order type or payment method.
+type ReportBucket struct {
+	Key   string
+	Count int
+	Total decimal.Decimal
+}
+
+// ReportVatLine is the VAT of one SCE code and rate. Gross is VAT included.
+type ReportVatLine struct {
+	SceCode string
+	Rate    decimal.Decimal
+	Gross   decimal.Decimal
+	Vat     decimal.Decimal
+	Net     decimal.Decimal
+}
+
+// DailyReport is the end-of-day (Z) report of one restaurant day. Sales are
+// the day's orders that were not cancelled or failed; tips are on top of
+// them. The VAT breakdown is computed on line totals, the way invoices do.
+type DailyReport struct {
+	Date              time.Time
+	Orders            int
+	Revenue           decimal.Decimal
+	ByOrderType       []ReportBucket
+	ByPaymentMethod   []ReportBucket
+	TakeawayDiscounts decimal.Decimal
+	CouponDiscounts   decimal.Decimal
+	DeliveryFees      decimal.Decimal
+	TransactionFees   decimal.Decimal
+	Tips              decimal.Decimal
+	Refunds           ReportBucket
+	Cancellations     ReportBucket
+	Vat               []ReportVatLine
+	TotalVat          decimal.Decimal
+}
+
+// ReportDay returns the restaurant day t falls on, as local midnight, and
+// the start of the next day.
+func ReportDay(t time.Time) (time.Time, time.Time) {
+	local := timezone.In(t)
+	start := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, timezone.Location)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// DailyReportClaimLease is how long an instance has to send the report it
+// claimed. Past it the instance is assumed dead and another one sends it.
+const DailyReportClaimLease = 10 * time.Minute
+
+// DueReportDay is the last day whose report is due at now: today once the
+// local time is past sendAfter (a time of day), yesterday before that.
+func DueReportDay(now time.Time, sendAfter time.Duration) time.Time {
+	start, _ := ReportDay(now)
+	// Wall-clock time, so the cutoff does not move on DST change days.
+	cutoff := time.Date(start.Year(), start.Month(), start.Day(), 0, int(sendAfter.Minutes()), 0, 0, timezone.Location)
+	if now.Before(cutoff) {
+		start, _ = ReportDay(start.Add(-time.Hour))
+	}
+	return start
+}
+
+// NewDailyReport adds up the day's orders, sales lines and refunds.
+func NewDailyReport(date time.Time, orders []*ReportOrderRow, vat []*ReportVatRow, refunds ReportRefundTotals) *DailyReport {
+	r := &DailyReport{
+		Date:          date,
+		Refunds:       ReportBucket{Key: "REFUNDS", Count: refunds.Count, Total: refunds.Amount},
+		Cancellations: ReportBucket{Key: string(OrderStatusCanceled)},
+	}
+	byType := map[string]*ReportBucket{}
+	byMethod := map[string]*ReportBucket{}
+	for _, o := range orders {
+		switch o.OrderStatus {
+		case OrderStatusCanceled:
+			r.Cancellations.Count++
+			r.Cancellations.Total = r.Cancellations.Total.Add(o.TotalPrice)
+			continue
+		case OrderStatusFailed:
+			continue
+		}
+		r.Orders++
+		r.Revenue = r.Revenue.Add(o.TotalPrice)
+		addToBucket(byType, string(o.OrderType), o.TotalPrice)
+		addToBucket(byMethod, string(o.PaymentMethod), o.TotalPrice)
+		r.TakeawayDiscounts = r.TakeawayDiscounts.Add(o.TakeawayDiscount)
+		r.CouponDiscounts = r.CouponDiscounts.Add(o.CouponDiscount)
+		if o.DeliveryFee != nil {
+			r.DeliveryFees = r.DeliveryFees.Add(*o.DeliveryFee)
+		}
+		r.TransactionFees = r.TransactionFees.Add(o.TransactionFee)
+		r.Tips = r.Tips.Add(o.Tip)
+	}
+	r.ByOrderType = sortedBuckets(byType)
+	r.ByPaymentMethod = sortedBuckets(byMethod)
+	r.Vat = reportVatLines(vat)
+	for _, v := range r.Vat {
+		r.TotalVat = r.TotalVat.Add(v.Vat)
+	}
+	return r
+}
+
+func addToBucket(buckets map[string]*ReportBucket, key string, amount decimal.Decimal) {
+	b, ok := buckets[key]
+	if !ok {
+		b = &ReportBucket{Key: key}
+		buckets[key] = b
+	}
+	b.Count++
+	b.Total = b.Total.Add(amount)
+}
+
+func sortedBuckets(buckets map[string]*ReportBucket) []ReportBucket {
+	out := make([]ReportBucket, 0, len(buckets))
+	for _, b := range buckets {
+		out = append(out, *b)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// reportVatLines groups the sales lines by SCE code and rate, in SCE code
+// order. The rate stored on the line wins over the one the category would
+// give today, so a later rate change does not rewrite past reports.
+func reportVatLines(rows []*ReportVatRow) []ReportVatLine {
+	byKey := map[string]*ReportVatLine{}
+	for _, row := range rows {
+		code := row.VatCategory.SceCode(row.OrderType.ServiceType())
+		key := code + "/" + row.Rate.StringFixed(2)
+		l, ok := byKey[key]
+		if !ok {
+			l = &ReportVatLine{SceCode: code, Rate: row.Rate}
+			byKey[key] = l
+		}
+		l.Gross = l.Gross.Add(row.Gross)
+	}
+	out := make([]ReportVatLine, 0, len(byKey))
+	for _, l := range byKey {
+		if !l.Rate.IsZero() {
+			l.Vat = VatIncluded(l.Gross, l.Rate).Round(2)
+		}
+		l.Net = l.Gross.Sub(l.Vat)
+		out = append(out, *l)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].SceCode != out[j].SceCode {
+			return out[i].SceCode < out[j].SceCode
+		}
+		return out[i].Rate.GreaterThan(out[j].Rate)
+	})
+	return out
+}
@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	productDomain "tsb-service/internal/modules/product/domain"
+	"tsb-service/pkg/timezone"
+)
+
+func TestNewDailyReport(t *testing.T) {
+	d := decimal.RequireFromString
+	fee := d("3.00")
+	orders := []*ReportOrderRow{
+		{OrderType: OrderTypeDelivery, OrderStatus: OrderStatusDelivered, PaymentMethod: ReportPaymentMethodOnline,
+			CouponDiscount: d("2.00"), DeliveryFee: &fee, TransactionFee: d("0.30"), TotalPrice: d("31.30"), Tip: d("2.00")},
+		{OrderType: OrderTypePickUp, OrderStatus: OrderStatusPickedUp, PaymentMethod: ReportPaymentMethodCash,
+			TakeawayDiscount: d("1.50"), TotalPrice: d("13.50")},
+		{OrderType: OrderTypePickUp, OrderStatus: OrderStatusCanceled, PaymentMethod: ReportPaymentMethodCash, TotalPrice: d("20.00")},
+		{OrderType: OrderTypeDelivery, OrderStatus: OrderStatusFailed, PaymentMethod: ReportPaymentMethodOnline, TotalPrice: d("50.00")},
+	}
+	vat := []*ReportVatRow{
+		{VatCategory: productDomain.VatCategoryFood, OrderType: OrderTypeDelivery, Rate: d("6"), Gross: d("21.20")},
+		{VatCategory: productDomain.VatCategoryFood, OrderType: OrderTypePickUp, Rate: d("6"), Gross: d("10.60")},
+		{VatCategory: productDomain.VatCategoryBeverage, OrderType: OrderTypeDelivery, Rate: d("21"), Gross: d("12.10")},
+	}
+
+	r := NewDailyReport(time.Now(), orders, vat, ReportRefundTotals{Count: 1, Amount: d("5.00")})
+
+	if r.Orders != 2 || r.Revenue.StringFixed(2) != "44.80" {
+		t.Errorf("sales = %d orders, %s; want 2 orders, 44.80", r.Orders, r.Revenue)
+	}
+	if len(r.ByOrderType) != 2 || r.ByOrderType[0].Key != "DELIVERY" || r.ByOrderType[0].Count != 1 {
+		t.Errorf("by order type = %+v", r.ByOrderType)
+	}
+	if len(r.ByPaymentMethod) != 2 || r.ByPaymentMethod[0].Key != "CASH" || r.ByPaymentMethod[0].Total.StringFixed(2) != "13.50" {
+		t.Errorf("by payment method = %+v", r.ByPaymentMethod)
+	}
+	if r.Cancellations.Count != 1 || r.Cancellations.Total.StringFixed(2) != "20.00" {
+		t.Errorf("cancellations = %+v, want 1 of 20.00", r.Cancellations)
+	}
+	if r.Tips.StringFixed(2) != "2.00" || r.DeliveryFees.StringFixed(2) != "3.00" ||
+		r.TakeawayDiscounts.StringFixed(2) != "1.50" || r.CouponDiscounts.StringFixed(2) != "2.00" {
+		t.Errorf("adjustments = tips %s, delivery %s, takeaway %s, coupon %s", r.Tips, r.DeliveryFees, r.TakeawayDiscounts, r.CouponDiscounts)
+	}
+
+	// Food at 6% is code C for both delivery and pickup, so they share a line.
+	want := []struct{ code, vat, net string }{
+		{"A", "2.10", "10.00"},
+		{"C", "1.80", "30.00"},
+	}
+	if len(r.Vat) != len(want) {
+		t.Fatalf("VAT lines = %+v, want %d", r.Vat, len(want))
+	}
+	for i, w := range want {
+		v := r.Vat[i]
+		if v.SceCode != w.code || v.Vat.StringFixed(2) != w.vat || v.Net.StringFixed(2) != w.net {
+			t.Errorf("VAT line %d = %s %s/%s, want %s %s/%s", i, v.SceCode, v.Vat, v.Net, w.code, w.vat, w.net)
+		}
+	}
+	if r.TotalVat.StringFixed(2) != "3.90" {
+		t.Errorf("total VAT = %s, want 3.90", r.TotalVat)
+	}
+}
+
+func TestDueReportDay(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.ParseInLocation("2006-01-02 15:04", s, timezone.Location)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	sendAfter := 23*time.Hour + 30*time.Minute
+
+	tests := []struct {
+		now  string
+		want string
+	}{
+		{"2026-07-10 23:45", "2026-07-10"},
+		{"2026-07-10 23:00", "2026-07-09"},
+		{"2026-07-11 00:10", "2026-07-10"},
+		// Spring forward: the day is 23 hours long, the cutoff stays 23:30.
+		{"2026-03-29 23:10", "2026-03-28"},
+		{"2026-03-29 23:40", "2026-03-29"},
+	}
+	for _, tt := range tests {
+		got := DueReportDay(at(tt.now), sendAfter)
+		if got.Format(time.DateOnly) != tt.want || got.Hour() != 0 {
+			t.Errorf("DueReportDay(%s) = %s, want %s at midnight", tt.now, got, tt.want)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 )
 
 // OrderHistoryFilter holds filter parameters for querying order history.
@@ -15,7 +16,11 @@ type OrderHistoryFilter struct {
 	EndDate   *time.Time
 	Status    *OrderStatus
 	OrderType *OrderType
-	Search    *string // customer name search (via JOIN on users)
+	Search    *string // see ParseOrderSearch
+	// ChangedBy and ChangedByID keep the orders with a status change made by
+	// that kind of actor, and that actor when set.
+	ChangedBy   *ActorType
+	ChangedByID *string
 }
 
 // OrderHistorySummary holds aggregate stats for filtered orders.
@@ -25,12 +30,58 @@ type OrderHistorySummary struct {
 	AverageOrder string `db:"average_order"`
 }
 
+// OrderWrite is the bookkeeping written in the same transaction as an order
+// insert or update, so the order never commits without it.
+type OrderWrite struct {
+	// History is the status history row of the change; its order ID is set
+	// by Save.
+	History *OrderStatusHistory
+	// AttachSlot is the ID of the slot reservation holding the order's
+	// kitchen capacity, taken just before the write. A reservation no
+	// longer pending (reaped, or taken by another order) fails the write
+	// with ErrSlotReservationExpired.
+	AttachSlot *uuid.UUID
+	// ReleaseSlot frees the order's kitchen capacity.
+	ReleaseSlot bool
+	// ReleaseCoupon gives the usage of the order's coupon back to its
+	// customer.
+	ReleaseCoupon bool
+	// ClaimCoupon takes a usage of the order's coupon for its customer
+	// again. A coupon no longer usable (inactive, expired or used up) fails
+	// the write with ErrPaymentRetryCouponUnavailable.
+	ClaimCoupon bool
+	// Outbox holds the side effects of the change; their order ID is set by
+	// Save.
+	Outbox []*OutboxMessage
+	// Eta is the ready time suggested when staff confirmed the order, with
+	// the time they confirmed. It replaces an earlier estimate of the order.
+	Eta *EtaEstimate
+	// IdempotencyKey is the key, claimed by the order's user, the order is
+	// created under. Save records the order on it, so a retry replays the
+	// order as soon as it exists. A key no longer claimed (its lease ran out
+	// and another request took it) fails the write with
+	// ErrIdempotencyKeyInFlight.
+	IdempotencyKey string
+}
+
 type OrderRepository interface {
-	Save(ctx context.Context, order *Order, orderProducts *[]OrderProductRaw) (*Order, *[]OrderProductRaw, error)
-	Update(ctx context.Context, order *Order) error
+	// Save and Update apply w in the same transaction as the order write.
+	// Update only applies while the stored status is still from, and returns
+	// ErrOrderStatusChanged otherwise.
+	Save(ctx context.Context, order *Order, orderProducts *[]OrderProductRaw, w OrderWrite) (*Order, *[]OrderProductRaw, error)
+	Update(ctx context.Context, order *Order, from OrderStatus, w OrderWrite) error
 	FindByID(ctx context.Context, orderID uuid.UUID) (*Order, *[]OrderProductRaw, error)
 	FindPaginated(ctx context.Context, page int, limit int, userID *uuid.UUID) ([]*Order, error)
 	FindFiltered(ctx context.Context, filter OrderHistoryFilter) ([]*Order, *OrderHistorySummary, error)
+	// FindPage is the keyset-paginated FindPaginated, newest first.
+	FindPage(ctx context.Context, userID *uuid.UUID, req OrderPageRequest) (*OrderPage, error)
+	// FindFilteredPage is the keyset-paginated FindFiltered, newest first
+	// whatever the search; Page and Limit of the filter are ignored.
+	FindFilteredPage(ctx context.Context, filter OrderHistoryFilter, req OrderPageRequest) (*OrderPage, *OrderHistorySummary, error)
+	// StreamExportLines calls fn with each line of the orders matching filter
+	// (pagination aside), one at a time, oldest order first. It stops at the
+	// first error fn returns.
+	StreamExportLines(ctx context.Context, filter OrderHistoryFilter, fn func(*ExportLine) error) error
 	FindByOrderIDs(ctx context.Context, orderIDs []string) (map[string][]*OrderProductRaw, error)
 	FindByUserIDs(ctx context.Context, userIDs []string) (map[string][]*Order, error)
 	// HasActiveCouponOrder reports whether the user already has a non-terminal
@@ -40,11 +91,155 @@ type OrderRepository interface {
 	// orders and returns the affected orders (id, user, status, type, language) so
 	// callers can re-push their Live Activities in the new language.
 	UpdateActiveOrdersLanguage(ctx context.Context, userID uuid.UUID, language string) ([]*Order, error)
-	InsertStatusHistory(ctx context.Context, orderID uuid.UUID, status OrderStatus) error
+	// InsertStatusHistory records a status change (see NewStatusHistory).
+	InsertStatusHistory(ctx context.Context, entry *OrderStatusHistory) error
 	// CancelStaleTestOrders cancels store-review test orders older than olderThan
-	// that are not already terminal, returning the affected order IDs. TEMPORARY.
-	CancelStaleTestOrders(ctx context.Context, olderThan time.Duration) ([]uuid.UUID, error)
+	// that are not already terminal and, in the same transaction, records the
+	// change by actor in their status history. It returns the affected orders
+	// as they were before. TEMPORARY.
+	CancelStaleTestOrders(ctx context.Context, olderThan time.Duration, actor Actor) ([]*Order, error)
+	// ReleaseScheduledOrders clears HeldForSchedule on the live orders due
+	// before dueBefore (paid ones only, for online payments) and, in the same
+	// transaction, enqueues the given side effects for each. It returns the
+	// released orders.
+	ReleaseScheduledOrders(ctx context.Context, dueBefore time.Time, effects []OutboxKind) ([]*Order, error)
+	// EscalateUnconfirmedOrders sets EscalatedAt on the orders awaiting staff
+	// confirmation since before waitingSince and not escalated yet and, in
+	// the same transaction, enqueues the given side effects for each. It
+	// returns the escalated orders. An order awaits confirmation while it is
+	// PENDING and shown to staff: paid (for online payments), released (when
+	// scheduled), and neither a dine-in round nor a test order.
+	EscalateUnconfirmedOrders(ctx context.Context, waitingSince time.Time, effects []OutboxKind) ([]*Order, error)
+	// FindUnconfirmedOrderIDs returns the orders awaiting staff confirmation
+	// since before waitingSince.
+	FindUnconfirmedOrderIDs(ctx context.Context, waitingSince time.Time) ([]uuid.UUID, error)
 	FindStatusHistoryByOrderID(ctx context.Context, orderID uuid.UUID) ([]*OrderStatusHistory, error)
 	DeleteOrder(ctx context.Context, orderID uuid.UUID) error
 	GetCustomerStats(ctx context.Context, startDate, endDate *time.Time, orderType *string, minOrders *int) ([]*CustomerStatsRow, error)
+	// GetDailyTips sums the tips of paid orders placed in [startDate, endDate)
+	// per day, restaurant time.
+	GetDailyTips(ctx context.Context, startDate, endDate time.Time) ([]*DailyTipsRow, error)
+}
+
+// SlotReservationRepository stores the kitchen capacity held by orders in
+// each 15-minute slot.
+type SlotReservationRepository interface {
+	// Reserve inserts the reservation unless the slot has no room left under
+	// limits, returning ErrSlotFull. Concurrent reservations for the same slot
+	// are serialized so a slot can never be oversubscribed.
+	// Reservations are attached to their order by OrderRepository, in the
+	// order's transaction (see OrderWrite.AttachSlot).
+	Reserve(ctx context.Context, reservation *SlotReservation, limits []SlotLimit) error
+	Delete(ctx context.Context, reservationID uuid.UUID) error
+	// DeleteUnattached deletes the reservations created before createdBefore
+	// that no order took, and returns how many were deleted.
+	DeleteUnattached(ctx context.Context, createdBefore time.Time) (int, error)
+	// Usage returns the reserved capacity per slot and order type for slots
+	// starting in [from, to).
+	Usage(ctx context.Context, from, to time.Time) ([]SlotUsage, error)
+}
+
+// OutboxRepository stores and leases outbox messages. Messages raised by an
+// order change are inserted by OrderRepository in the change's transaction;
+// Enqueue is for follow-up effects raised while delivering another message.
+type OutboxRepository interface {
+	Enqueue(ctx context.Context, msgs ...*OutboxMessage) error
+	// ClaimDue leases up to limit pending messages whose next attempt is due,
+	// counting the attempt and pushing next_attempt_at past lease so another
+	// worker does not pick them up concurrently.
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*OutboxMessage, error)
+	MarkDelivered(ctx context.Context, id uuid.UUID) error
+	// MarkFailed records a failed attempt. A nil retryAt dead-letters the
+	// message.
+	MarkFailed(ctx context.Context, id uuid.UUID, lastErr string, retryAt *time.Time) error
+	List(ctx context.Context, status *OutboxStatus, limit int) ([]*OutboxMessage, error)
+	// Retry makes an undelivered message due now with a fresh attempt budget.
+	// It returns sql.ErrNoRows for unknown or already delivered messages.
+	Retry(ctx context.Context, id uuid.UUID) (*OutboxMessage, error)
+	// DeleteDelivered deletes the messages delivered before deliveredBefore
+	// and returns how many were deleted. Dead messages are kept for review.
+	DeleteDelivered(ctx context.Context, deliveredBefore time.Time) (int64, error)
+}
+
+// IdempotencyRepository stores the createOrder idempotency keys.
+type IdempotencyRepository interface {
+	// Claim takes key for userID and returns (nil, nil) when it was free,
+	// expired, or held by a claim older than lease. Otherwise it returns the
+	// current holder of the key.
+	Claim(ctx context.Context, userID uuid.UUID, key, fingerprint string, ttl, lease time.Duration) (*IdempotencyKey, error)
+	// Release drops an unfinished claim so the key can be used again.
+	Release(ctx context.Context, userID uuid.UUID, key string) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// TableRepository stores the dining tables and their sessions.
+type TableRepository interface {
+	ListTables(ctx context.Context) ([]*DiningTable, error)
+	GetTable(ctx context.Context, id uuid.UUID) (*DiningTable, error)
+	CreateTable(ctx context.Context, table *DiningTable) (*DiningTable, error)
+	UpdateTable(ctx context.Context, table *DiningTable) (*DiningTable, error)
+	// RotateToken bumps the table's token version, voiding its QR codes.
+	RotateToken(ctx context.Context, id uuid.UUID) (*DiningTable, error)
+	// OpenSession returns the table's open session, opening one if there is
+	// none. Concurrent callers for the same table get the same session.
+	OpenSession(ctx context.Context, tableID uuid.UUID) (*TableSession, error)
+	GetSession(ctx context.Context, id uuid.UUID) (*TableSession, error)
+	ListOpenSessions(ctx context.Context) ([]*TableSession, error)
+	// FindSessionOrders returns the session's rounds, oldest first.
+	FindSessionOrders(ctx context.Context, sessionID uuid.UUID) ([]*Order, error)
+	// CloseSession settles an open session with the total bill returns for
+	// its rounds. The session is locked meanwhile, so no round can be added
+	// between the bill and the close; an error from bill aborts. It returns
+	// sql.ErrNoRows when the session is unknown or already settled.
+	CloseSession(ctx context.Context, id uuid.UUID, method TablePaymentMethod, bill func(rounds []*Order) (decimal.Decimal, error)) (*TableSession, error)
+}
+
+// ReportRepository reads the figures of the daily report and records which
+// days were sent to accounting.
+type ReportRepository interface {
+	// FindReportOrders returns the non-test orders created in [start, end).
+	// Online orders count only once their payment went through.
+	FindReportOrders(ctx context.Context, start, end time.Time) ([]*ReportOrderRow, error)
+	// FindReportVat sums the lines of the orders FindReportOrders counts as
+	// sales.
+	FindReportVat(ctx context.Context, start, end time.Time) ([]*ReportVatRow, error)
+	FindReportRefunds(ctx context.Context, start, end time.Time) (ReportRefundTotals, error)
+	// ClaimDailyReport leases the report of day for lease. It returns false
+	// when the report was sent already, or is leased by this or another
+	// instance.
+	ClaimDailyReport(ctx context.Context, day time.Time, lease time.Duration) (bool, error)
+	// MarkDailyReportSent records that the report of day went out.
+	MarkDailyReportSent(ctx context.Context, day time.Time) error
+	// ReleaseDailyReport ends the lease of a report that could not be sent,
+	// so the next run retries it.
+	ReleaseDailyReport(ctx context.Context, day time.Time) error
+}
+
+// DeliveryRepository stores the driver, timeline and driver position of
+// delivery orders. The timeline itself is written by OrderRepository.Update.
+type DeliveryRepository interface {
+	// AssignDriver sets the order's driver, replacing any previous one.
+	AssignDriver(ctx context.Context, orderID, driverID uuid.UUID) (*Delivery, error)
+	// FindByOrderID returns nil when the order has no delivery record yet.
+	FindByOrderID(ctx context.Context, orderID uuid.UUID) (*Delivery, error)
+	// RecordLocation stores loc on every order the driver is delivering
+	// (assigned to them and OUT_FOR_DELIVERY) and returns those deliveries.
+	RecordLocation(ctx context.Context, driverID uuid.UUID, loc DriverLocation) ([]*Delivery, error)
+	// FindDriverOrders returns the non-terminal orders assigned to the
+	// driver, oldest first.
+	FindDriverOrders(ctx context.Context, driverID uuid.UUID) ([]*Order, error)
+}
+
+// EtaRepository stores the ready times suggested at confirmation. Their
+// actual ready time is written by OrderRepository.Update.
+type EtaRepository interface {
+	// KitchenLoad counts the orders other than excludeID that are confirmed
+	// or being prepared.
+	KitchenLoad(ctx context.Context, excludeID uuid.UUID) (int, error)
+	// FindByOrderIDs returns the recorded estimates of the orders, by order
+	// ID. Estimates are recorded by OrderRepository.Update (see
+	// OrderWrite.Eta).
+	FindByOrderIDs(ctx context.Context, orderIDs []string) (map[string]*EtaEstimate, error)
+	// FindPendingOrders returns those of the orders still pending.
+	FindPendingOrders(ctx context.Context, orderIDs []string) ([]*Order, error)
 }
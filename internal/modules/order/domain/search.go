@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	hexPrefixRe = regexp.MustCompile(`^[0-9a-f][0-9a-f-]{3,35}$`)
+	referenceRe = regexp.MustCompile(`^[0-9a-f]{8}$`)
+)
+
+// OrderSearch is an order-history search, normalised. Text is matched
+// against the customer, the order and its products; IDPrefix and Reference
+// are set when the search may also be the start of an order ID, as shown in
+// the admin, or an invoice reference (see invoice.OrderReference), of which
+// only the last 8 hex digits identify the order.
+type OrderSearch struct {
+	Text      string
+	IDPrefix  string
+	Reference string
+}
+
+// ParseOrderSearch normalises search. It returns false when there is nothing
+// to search for.
+func ParseOrderSearch(search string) (OrderSearch, bool) {
+	text := strings.ToLower(strings.TrimSpace(search))
+	if text == "" {
+		return OrderSearch{}, false
+	}
+
+	s := OrderSearch{Text: text}
+	if hexPrefixRe.MatchString(text) {
+		s.IDPrefix = text
+	}
+	// "TSB-2026-1A2B3C4D", or just the part after the last dash.
+	if last := text[strings.LastIndex(text, "-")+1:]; referenceRe.MatchString(last) {
+		s.Reference = last
+	}
+	return s, true
+}
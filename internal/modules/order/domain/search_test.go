@@ -0,0 +1,26 @@
+package domain
+
+import "testing"
+
+func TestParseOrderSearch(t *testing.T) {
+	tests := []struct {
+		search string
+		want   OrderSearch
+		ok     bool
+	}{
+		{"  ", OrderSearch{}, false},
+		{"Dupont", OrderSearch{Text: "dupont"}, true},
+		{"TSB-2026-1A2B3C4D", OrderSearch{Text: "tsb-2026-1a2b3c4d", Reference: "1a2b3c4d"}, true},
+		{"1A2B3C4D", OrderSearch{Text: "1a2b3c4d", IDPrefix: "1a2b3c4d", Reference: "1a2b3c4d"}, true},
+		{"9f3c-", OrderSearch{Text: "9f3c-", IDPrefix: "9f3c-"}, true},
+		{"cafe", OrderSearch{Text: "cafe", IDPrefix: "cafe"}, true},
+		{"bad", OrderSearch{Text: "bad"}, true},
+		{"+32 470 12 34 56", OrderSearch{Text: "+32 470 12 34 56"}, true},
+	}
+	for _, tt := range tests {
+		got, ok := ParseOrderSearch(tt.search)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("ParseOrderSearch(%q) = %+v, %v; want %+v, %v", tt.search, got, ok, tt.want, tt.ok)
+		}
+	}
+}
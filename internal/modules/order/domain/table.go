@@ -0,0 +1,140 @@
+package domain
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// ErrInvalidTableToken is returned for a QR token that is malformed, badly
+	// signed, rotated out or points to an inactive table.
+	ErrInvalidTableToken = errors.New("this table QR code is no longer valid, please ask a member of staff")
+	// ErrTableSessionClosed is returned when settling a session twice, or
+	// adding a round to a settled one.
+	ErrTableSessionClosed = errors.New("this table session is already settled")
+	// ErrTableRoundsInProgress is returned when settling a session while one
+	// of its rounds is still with the kitchen.
+	ErrTableRoundsInProgress = errors.New("some rounds of this table are still being prepared or served")
+)
+
+type TablePaymentMethod string
+
+const (
+	TablePaymentMethodCash TablePaymentMethod = "CASH"
+	TablePaymentMethodCard TablePaymentMethod = "CARD"
+)
+
+// DiningTable is a table guests order from by scanning its QR code. Bumping
+// TokenVersion voids every QR code printed for the table.
+type DiningTable struct {
+	ID           uuid.UUID `db:"id"`
+	Name         string    `db:"name"`
+	Seats        int       `db:"seats"`
+	TokenVersion int       `db:"token_version"`
+	IsActive     bool      `db:"is_active"`
+	CreatedAt    time.Time `db:"created_at"`
+	UpdatedAt    time.Time `db:"updated_at"`
+}
+
+// TableSession groups the rounds ordered at a table between the first scan
+// and the settlement of the bill. Total and PaymentMethod are set when it is
+// settled.
+type TableSession struct {
+	ID            uuid.UUID           `db:"id"`
+	TableID       uuid.UUID           `db:"table_id"`
+	TableName     string              `db:"table_name"`
+	OpenedAt      time.Time           `db:"opened_at"`
+	ClosedAt      *time.Time          `db:"closed_at"`
+	Total         *decimal.Decimal    `db:"total"`
+	PaymentMethod *TablePaymentMethod `db:"payment_method"`
+}
+
+func (s *TableSession) IsOpen() bool {
+	return s.ClosedAt == nil
+}
+
+// tableTokenMACSize truncates the HMAC to 128 bits, which keeps the printed
+// QR code small and is still far beyond guessing.
+const tableTokenMACSize = 16
+
+// SignTableToken returns the QR token of a table: "<table id>.<version>.<mac>".
+func SignTableToken(secret []byte, tableID uuid.UUID, version int) string {
+	payload := tableID.String() + "." + strconv.Itoa(version)
+	return payload + "." + tableTokenMAC(secret, payload)
+}
+
+// ParseTableToken checks the token's signature and returns the table and
+// token version it was issued for. It does not check the version is current.
+func ParseTableToken(secret []byte, token string) (uuid.UUID, int, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return uuid.Nil, 0, ErrInvalidTableToken
+	}
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(tableTokenMAC(secret, payload))) {
+		return uuid.Nil, 0, ErrInvalidTableToken
+	}
+	tableID, err := uuid.Parse(parts[0])
+	if err != nil {
+		return uuid.Nil, 0, ErrInvalidTableToken
+	}
+	version, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return uuid.Nil, 0, ErrInvalidTableToken
+	}
+	return tableID, version, nil
+}
+
+func tableTokenMAC(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:tableTokenMACSize])
+}
+
+// TableBill is what a table owes: its rounds that were not cancelled or
+// failed. Dine-in rounds carry no discount or fee, so the VAT included in
+// the line totals is the VAT of the bill.
+type TableBill struct {
+	Rounds       int
+	Total        decimal.Decimal
+	VatBreakdown []VatLine
+}
+
+// NewTableBill adds up the session's orders; items holds their lines by
+// order ID.
+func NewTableBill(orders []*Order, items map[string][]*OrderProductRaw) TableBill {
+	var bill TableBill
+	var lines []OrderProductRaw
+	for _, o := range orders {
+		if o.OrderStatus == OrderStatusCanceled || o.OrderStatus == OrderStatusFailed {
+			continue
+		}
+		bill.Rounds++
+		bill.Total = bill.Total.Add(o.TotalPrice)
+		for _, l := range items[o.ID.String()] {
+			lines = append(lines, *l)
+		}
+	}
+	bill.VatBreakdown = ComputeVatBreakdown(lines)
+	return bill
+}
+
+// CheckTableSettle reports whether a session with these rounds can be
+// settled: every round must have been served, cancelled or failed.
+func CheckTableSettle(orders []*Order) error {
+	for _, o := range orders {
+		if !IsTerminalStatus(o.OrderStatus) {
+			return fmt.Errorf("%w (round placed at %s is %s)", ErrTableRoundsInProgress, o.CreatedAt.Format("15:04"), o.OrderStatus)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestTableToken(t *testing.T) {
+	secret := []byte("secret")
+	id := uuid.New()
+	token := SignTableToken(secret, id, 3)
+
+	gotID, gotVersion, err := ParseTableToken(secret, " "+token+"\n")
+	if err != nil || gotID != id || gotVersion != 3 {
+		t.Fatalf("ParseTableToken = %s, %d, %v; want %s, 3, nil", gotID, gotVersion, err, id)
+	}
+
+	bad := []string{
+		"",
+		token + ".x",
+		strings.Replace(token, ".3.", ".4.", 1),
+		SignTableToken([]byte("other"), id, 3),
+		SignTableToken(secret, id, 3)[:len(token)-1],
+	}
+	for _, tok := range bad {
+		if _, _, err := ParseTableToken(secret, tok); !errors.Is(err, ErrInvalidTableToken) {
+			t.Errorf("ParseTableToken(%q) err = %v, want ErrInvalidTableToken", tok, err)
+		}
+	}
+}
+
+func TestNewTableBill(t *testing.T) {
+	served := &Order{ID: uuid.New(), OrderStatus: OrderStatusDelivered, TotalPrice: decimal.RequireFromString("22.40")}
+	cancelled := &Order{ID: uuid.New(), OrderStatus: OrderStatusCanceled, TotalPrice: decimal.RequireFromString("10.00")}
+	items := map[string][]*OrderProductRaw{
+		served.ID.String(): {
+			{TotalPrice: decimal.RequireFromString("22.40"), VatRateApplied: decimal.NewFromInt(12)},
+		},
+		cancelled.ID.String(): {
+			{TotalPrice: decimal.RequireFromString("10.00"), VatRateApplied: decimal.NewFromInt(21)},
+		},
+	}
+
+	bill := NewTableBill([]*Order{served, cancelled}, items)
+	if bill.Rounds != 1 || !bill.Total.Equal(decimal.RequireFromString("22.40")) {
+		t.Errorf("bill = %d rounds, %s; want 1 round, 22.40", bill.Rounds, bill.Total)
+	}
+	if len(bill.VatBreakdown) != 1 || bill.VatBreakdown[0].Amount.StringFixed(2) != "2.40" {
+		t.Errorf("VAT breakdown = %v, want 2.40 at 12%%", bill.VatBreakdown)
+	}
+}
+
+func TestCheckTableSettle(t *testing.T) {
+	done := []*Order{{OrderStatus: OrderStatusDelivered}, {OrderStatus: OrderStatusCanceled}}
+	if err := CheckTableSettle(done); err != nil {
+		t.Errorf("CheckTableSettle(served rounds) = %v, want nil", err)
+	}
+	pending := append(done, &Order{OrderStatus: OrderStatusPreparing})
+	if err := CheckTableSettle(pending); !errors.Is(err, ErrTableRoundsInProgress) {
+		t.Errorf("CheckTableSettle(preparing round) = %v, want ErrTableRoundsInProgress", err)
+	}
+}
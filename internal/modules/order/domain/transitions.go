@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInvalidStatusTransition is returned when the requested status is not
+	// reachable from the current one for the order's type.
+	ErrInvalidStatusTransition = errors.New("invalid order status transition")
+	// ErrOrderStatusTerminal is returned when the order already reached a
+	// terminal status (DELIVERED, PICKED_UP, CANCELLED, FAILED).
+	ErrOrderStatusTerminal = errors.New("order status is terminal")
+)
+
+// StatusTransitionError describes a rejected status change. It unwraps to
+// ErrOrderStatusTerminal or ErrInvalidStatusTransition so callers can match
+// with errors.Is and still read the offending statuses via errors.As.
+type StatusTransitionError struct {
+	OrderType OrderType
+	From      OrderStatus
+	To        OrderStatus
+}
+
+func (e *StatusTransitionError) Error() string {
+	if IsTerminalStatus(e.From) {
+		return fmt.Sprintf("order is already %s and can no longer change status", e.From)
+	}
+	return fmt.Sprintf("cannot change a %s order from %s to %s", e.OrderType, e.From, e.To)
+}
+
+func (e *StatusTransitionError) Unwrap() error {
+	if IsTerminalStatus(e.From) {
+		return ErrOrderStatusTerminal
+	}
+	return ErrInvalidStatusTransition
+}
+
+// statusTransitions lists the allowed next statuses per order type. Skipping
+// ahead is allowed where the kitchen commonly does it (PENDING straight to
+// PREPARING, CONFIRMED straight to ready); going backwards never is. The
+// delivery flow mirrors the app timeline and has no AWAITING_PICK_UP step;
+// a dine-in round is DELIVERED once served at the table.
+var statusTransitions = map[OrderType]map[OrderStatus][]OrderStatus{
+	OrderTypeDelivery: {
+		OrderStatusPending:        {OrderStatusConfirmed, OrderStatusPreparing, OrderStatusCanceled, OrderStatusFailed},
+		OrderStatusConfirmed:      {OrderStatusPreparing, OrderStatusOutForDelivery, OrderStatusCanceled, OrderStatusFailed},
+		OrderStatusPreparing:      {OrderStatusOutForDelivery, OrderStatusCanceled, OrderStatusFailed},
+		OrderStatusOutForDelivery: {OrderStatusDelivered, OrderStatusCanceled, OrderStatusFailed},
+	},
+	OrderTypePickUp: {
+		OrderStatusPending:    {OrderStatusConfirmed, OrderStatusPreparing, OrderStatusCanceled, OrderStatusFailed},
+		OrderStatusConfirmed:  {OrderStatusPreparing, OrderStatusAwaitingUp, OrderStatusCanceled, OrderStatusFailed},
+		OrderStatusPreparing:  {OrderStatusAwaitingUp, OrderStatusCanceled, OrderStatusFailed},
+		OrderStatusAwaitingUp: {OrderStatusPickedUp, OrderStatusCanceled, OrderStatusFailed},
+	},
+	OrderTypeDineIn: {
+		OrderStatusPending:   {OrderStatusConfirmed, OrderStatusPreparing, OrderStatusCanceled, OrderStatusFailed},
+		OrderStatusConfirmed: {OrderStatusPreparing, OrderStatusDelivered, OrderStatusCanceled, OrderStatusFailed},
+		OrderStatusPreparing: {OrderStatusDelivered, OrderStatusCanceled, OrderStatusFailed},
+	},
+}
+
+// IsTerminalStatus reports whether no further status change is allowed.
+func IsTerminalStatus(status OrderStatus) bool {
+	switch status {
+	case OrderStatusDelivered, OrderStatusPickedUp, OrderStatusCanceled, OrderStatusFailed:
+		return true
+	default:
+		return false
+	}
+}
+
+// ValidateStatusTransition returns a *StatusTransitionError when an order of
+// the given type may not move from one status to the other. Re-applying the
+// current status is always allowed so ETA-only updates and duplicate
+// cancellations stay no-ops.
+func ValidateStatusTransition(orderType OrderType, from, to OrderStatus) error {
+	if from == to {
+		return nil
+	}
+	for _, next := range statusTransitions[orderType][from] {
+		if next == to {
+			return nil
+		}
+	}
+	return &StatusTransitionError{OrderType: orderType, From: from, To: to}
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateStatusTransition(t *testing.T) {
+	cases := []struct {
+		name      string
+		orderType OrderType
+		from, to  OrderStatus
+		wantErr   error
+	}{
+		{"pickup happy path confirm", OrderTypePickUp, OrderStatusPending, OrderStatusConfirmed, nil},
+		{"pickup ready", OrderTypePickUp, OrderStatusPreparing, OrderStatusAwaitingUp, nil},
+		{"pickup collected", OrderTypePickUp, OrderStatusAwaitingUp, OrderStatusPickedUp, nil},
+		{"delivery out for delivery", OrderTypeDelivery, OrderStatusPreparing, OrderStatusOutForDelivery, nil},
+		{"delivery delivered", OrderTypeDelivery, OrderStatusOutForDelivery, OrderStatusDelivered, nil},
+		{"dine-in served", OrderTypeDineIn, OrderStatusPreparing, OrderStatusDelivered, nil},
+		{"pending straight to preparing", OrderTypeDelivery, OrderStatusPending, OrderStatusPreparing, nil},
+		{"same status is a no-op", OrderTypePickUp, OrderStatusCanceled, OrderStatusCanceled, nil},
+		{"pickup cannot go out for delivery", OrderTypePickUp, OrderStatusPreparing, OrderStatusOutForDelivery, ErrInvalidStatusTransition},
+		{"delivery cannot be picked up", OrderTypeDelivery, OrderStatusOutForDelivery, OrderStatusPickedUp, ErrInvalidStatusTransition},
+		{"dine-in is not picked up", OrderTypeDineIn, OrderStatusPreparing, OrderStatusAwaitingUp, ErrInvalidStatusTransition},
+		{"no going backwards", OrderTypePickUp, OrderStatusAwaitingUp, OrderStatusPreparing, ErrInvalidStatusTransition},
+		{"delivered is terminal", OrderTypeDelivery, OrderStatusDelivered, OrderStatusPending, ErrOrderStatusTerminal},
+		{"cancelled is terminal", OrderTypePickUp, OrderStatusCanceled, OrderStatusConfirmed, ErrOrderStatusTerminal},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateStatusTransition(tc.orderType, tc.from, tc.to)
+			if tc.wantErr == nil {
+				if err != nil {
+					t.Fatalf("expected transition to be allowed, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected %v, got %v", tc.wantErr, err)
+			}
+			var te *StatusTransitionError
+			if !errors.As(err, &te) || te.From != tc.from || te.To != tc.to {
+				t.Fatalf("expected a StatusTransitionError for %s→%s, got %#v", tc.from, tc.to, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"tsb-service/internal/modules/order/domain"
+	"tsb-service/pkg/db"
+)
+
+const deliveryColumns = `order_id, driver_id, assigned_at, dispatched_at, delivered_at,
+	latitude, longitude, accuracy, heading, located_at`
+
+type DeliveryRepository struct {
+	pool *db.DBPool
+}
+
+func NewDeliveryRepository(pool *db.DBPool) domain.DeliveryRepository {
+	return &DeliveryRepository{pool: pool}
+}
+
+func (r *DeliveryRepository) AssignDriver(ctx context.Context, orderID, driverID uuid.UUID) (*domain.Delivery, error) {
+	var d domain.Delivery
+	err := r.pool.ForContext(ctx).GetContext(ctx, &d, `
+		INSERT INTO order_deliveries (order_id, driver_id, assigned_at)
+		VALUES ($1, $2, now())
+		ON CONFLICT (order_id) DO UPDATE
+		SET driver_id = EXCLUDED.driver_id, assigned_at = EXCLUDED.assigned_at
+		RETURNING `+deliveryColumns, orderID, driverID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assign driver: %w", err)
+	}
+	return &d, nil
+}
+
+func (r *DeliveryRepository) FindByOrderID(ctx context.Context, orderID uuid.UUID) (*domain.Delivery, error) {
+	var d domain.Delivery
+	err := r.pool.ForContext(ctx).GetContext(ctx, &d,
+		`SELECT `+deliveryColumns+` FROM order_deliveries WHERE order_id = $1`, orderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery: %w", err)
+	}
+	return &d, nil
+}
+
+func (r *DeliveryRepository) RecordLocation(ctx context.Context, driverID uuid.UUID, loc domain.DriverLocation) ([]*domain.Delivery, error) {
+	var out []*domain.Delivery
+	err := r.pool.ForContext(ctx).SelectContext(ctx, &out, `
+		UPDATE order_deliveries d
+		SET latitude = $2, longitude = $3, accuracy = $4, heading = $5, located_at = $6
+		FROM orders o
+		WHERE o.id = d.order_id
+		  AND d.driver_id = $1
+		  AND o.order_status = 'OUT_FOR_DELIVERY'
+		RETURNING d.order_id, d.driver_id, d.assigned_at, d.dispatched_at, d.delivered_at,
+			d.latitude, d.longitude, d.accuracy, d.heading, d.located_at`,
+		driverID, loc.Latitude, loc.Longitude, loc.Accuracy, loc.Heading, loc.RecordedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record driver location: %w", err)
+	}
+	return out, nil
+}
+
+func (r *DeliveryRepository) FindDriverOrders(ctx context.Context, driverID uuid.UUID) ([]*domain.Order, error) {
+	var out []*domain.Order
+	err := r.pool.ForContext(ctx).SelectContext(ctx, &out, `
+		SELECT o.*
+		FROM orders o
+		JOIN order_deliveries d ON d.order_id = o.id
+		WHERE d.driver_id = $1
+		  AND o.order_status NOT IN ('DELIVERED', 'PICKED_UP', 'CANCELLED', 'FAILED')
+		ORDER BY o.created_at ASC`, driverID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list driver orders: %w", err)
+	}
+	return out, nil
+}
+
+// recordDeliveryMilestone stamps the delivery timeline of order when its
+// status moved from `from` to OUT_FOR_DELIVERY or DELIVERED. It runs in the
+// status update's transaction.
+func recordDeliveryMilestone(ctx context.Context, exec sqlx.ExecerContext, order *domain.Order, from domain.OrderStatus) error {
+	if order.OrderType != domain.OrderTypeDelivery || order.OrderStatus == from {
+		return nil
+	}
+	var column string
+	switch order.OrderStatus {
+	case domain.OrderStatusOutForDelivery:
+		column = "dispatched_at"
+	case domain.OrderStatusDelivered:
+		column = "delivered_at"
+	default:
+		return nil
+	}
+	_, err := exec.ExecContext(ctx, `
+		INSERT INTO order_deliveries (order_id, `+column+`)
+		VALUES ($1, now())
+		ON CONFLICT (order_id) DO UPDATE SET `+column+` = EXCLUDED.`+column, order.ID)
+	if err != nil {
+		return fmt.Errorf("failed to record delivery timeline: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"tsb-service/internal/modules/order/domain"
+	"tsb-service/pkg/db"
+)
+
+type EtaRepository struct {
+	pool *db.DBPool
+}
+
+func NewEtaRepository(pool *db.DBPool) domain.EtaRepository {
+	return &EtaRepository{pool: pool}
+}
+
+func (r *EtaRepository) KitchenLoad(ctx context.Context, excludeID uuid.UUID) (int, error) {
+	var n int
+	err := r.pool.ForContext(ctx).GetContext(ctx, &n, `
+		SELECT COUNT(*) FROM orders
+		WHERE order_status IN ('CONFIRMED', 'PREPARING') AND id <> $1`, excludeID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count kitchen load: %w", err)
+	}
+	return n, nil
+}
+
+func (r *EtaRepository) FindByOrderIDs(ctx context.Context, orderIDs []string) (map[string]*domain.EtaEstimate, error) {
+	out := make(map[string]*domain.EtaEstimate, len(orderIDs))
+	if len(orderIDs) == 0 {
+		return out, nil
+	}
+	query, args, err := sqlx.In(`SELECT * FROM order_eta_estimates WHERE order_id IN (?)`, orderIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build eta estimates query: %w", err)
+	}
+	var rows []*domain.EtaEstimate
+	query = r.pool.ForContext(ctx).Rebind(query)
+	if err := r.pool.ForContext(ctx).SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get eta estimates: %w", err)
+	}
+	for _, e := range rows {
+		out[e.OrderID.String()] = e
+	}
+	return out, nil
+}
+
+func (r *EtaRepository) FindPendingOrders(ctx context.Context, orderIDs []string) ([]*domain.Order, error) {
+	if len(orderIDs) == 0 {
+		return nil, nil
+	}
+	query, args, err := sqlx.In(`SELECT * FROM orders WHERE id IN (?) AND order_status = 'PENDING'`, orderIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build pending orders query: %w", err)
+	}
+	var out []*domain.Order
+	query = r.pool.ForContext(ctx).Rebind(query)
+	if err := r.pool.ForContext(ctx).SelectContext(ctx, &out, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get pending orders: %w", err)
+	}
+	return out, nil
+}
+
+// saveEtaEstimate records e, replacing an earlier estimate of the same
+// order. It runs in the transaction of the update confirming the order.
+func saveEtaEstimate(ctx context.Context, exec sqlx.ExecerContext, e *domain.EtaEstimate) error {
+	_, err := exec.ExecContext(ctx, `
+		INSERT INTO order_eta_estimates (
+			order_id, suggested_ready_time, preparation_minutes, kitchen_load,
+			item_count, travel_seconds, estimated_ready_time, applied
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (order_id) DO UPDATE SET
+			suggested_ready_time = EXCLUDED.suggested_ready_time,
+			preparation_minutes = EXCLUDED.preparation_minutes,
+			kitchen_load = EXCLUDED.kitchen_load,
+			item_count = EXCLUDED.item_count,
+			travel_seconds = EXCLUDED.travel_seconds,
+			estimated_ready_time = EXCLUDED.estimated_ready_time,
+			applied = EXCLUDED.applied,
+			created_at = now()`,
+		e.OrderID, e.SuggestedReadyTime, e.PreparationMinutes, e.KitchenLoad,
+		e.ItemCount, e.TravelSeconds, e.EstimatedReadyTime, e.Applied)
+	if err != nil {
+		return fmt.Errorf("failed to save eta estimate: %w", err)
+	}
+	return nil
+}
+
+// recordEtaActual stamps the actual ready time on the order's ETA estimate
+// when its status moved from `from` to ready (see domain.IsReadyStatus). It
+// runs in the status update's transaction; only the first ready status counts.
+func recordEtaActual(ctx context.Context, exec sqlx.ExecerContext, order *domain.Order, from domain.OrderStatus) error {
+	if order.OrderStatus == from || !domain.IsReadyStatus(order.OrderType, order.OrderStatus) {
+		return nil
+	}
+	_, err := exec.ExecContext(ctx, `
+		UPDATE order_eta_estimates SET actual_ready_time = now()
+		WHERE order_id = $1 AND actual_ready_time IS NULL`, order.ID)
+	if err != nil {
+		return fmt.Errorf("failed to record actual ready time: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tsb-service/internal/modules/order/domain"
+	"tsb-service/pkg/db"
+)
+
+type IdempotencyRepository struct {
+	pool *db.DBPool
+}
+
+func NewIdempotencyRepository(pool *db.DBPool) domain.IdempotencyRepository {
+	return &IdempotencyRepository{pool: pool}
+}
+
+func (r *IdempotencyRepository) Claim(ctx context.Context, userID uuid.UUID, key, fingerprint string, ttl, lease time.Duration) (*domain.IdempotencyKey, error) {
+	// The upsert only overwrites a row nobody can still rely on: an expired
+	// key, or a claim whose request died before completing it. RETURNING
+	// yields nothing when the existing row was kept.
+	const claim = `
+		INSERT INTO order_idempotency_keys (user_id, key, fingerprint, expires_at)
+		VALUES ($1, $2, $3, now() + make_interval(secs => $4))
+		ON CONFLICT (user_id, key) DO UPDATE
+		SET fingerprint = EXCLUDED.fingerprint,
+		    order_id = NULL,
+		    created_at = now(),
+		    expires_at = EXCLUDED.expires_at
+		WHERE order_idempotency_keys.expires_at <= now()
+		   OR (order_idempotency_keys.order_id IS NULL
+		       AND order_idempotency_keys.created_at <= now() - make_interval(secs => $5))
+		RETURNING user_id;
+	`
+	var claimed uuid.UUID
+	err := r.pool.ForContext(ctx).GetContext(ctx, &claimed, claim, userID, key, fingerprint, ttl.Seconds(), lease.Seconds())
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+
+	var existing domain.IdempotencyKey
+	if err := r.pool.ForContext(ctx).GetContext(ctx, &existing, `
+		SELECT * FROM order_idempotency_keys WHERE user_id = $1 AND key = $2`, userID, key); err != nil {
+		return nil, fmt.Errorf("failed to load idempotency key: %w", err)
+	}
+	return &existing, nil
+}
+
+func (r *IdempotencyRepository) Release(ctx context.Context, userID uuid.UUID, key string) error {
+	_, err := r.pool.ForContext(ctx).ExecContext(ctx,
+		`DELETE FROM order_idempotency_keys WHERE user_id = $1 AND key = $2 AND order_id IS NULL`, userID, key)
+	return err
+}
+
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	res, err := r.pool.ForContext(ctx).ExecContext(ctx,
+		`DELETE FROM order_idempotency_keys WHERE expires_at <= now()`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"tsb-service/internal/modules/order/domain"
+	"tsb-service/pkg/db"
+)
+
+type OutboxRepository struct {
+	pool *db.DBPool
+}
+
+func NewOutboxRepository(pool *db.DBPool) domain.OutboxRepository {
+	return &OutboxRepository{pool: pool}
+}
+
+// insertOutbox writes msgs with the given executor, so OrderRepository can
+// enqueue side effects inside the transaction of the order change. Messages
+// already enqueued (see domain.NewKeyedOutboxMessages) are skipped.
+func insertOutbox(ctx context.Context, exec sqlx.ExecerContext, msgs []*domain.OutboxMessage) error {
+	const query = `
+		INSERT INTO order_outbox (id, order_id, kind, payload)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (id) DO NOTHING
+	`
+	for _, m := range msgs {
+		if _, err := exec.ExecContext(ctx, query, m.ID, m.OrderID, m.Kind, m.Payload); err != nil {
+			return fmt.Errorf("failed to insert outbox message: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *OutboxRepository) Enqueue(ctx context.Context, msgs ...*domain.OutboxMessage) error {
+	return insertOutbox(ctx, r.pool.ForContext(ctx), msgs)
+}
+
+func (r *OutboxRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*domain.OutboxMessage, error) {
+	const query = `
+		UPDATE order_outbox
+		SET attempts = attempts + 1,
+		    next_attempt_at = now() + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id FROM order_outbox
+			WHERE status = 'PENDING' AND next_attempt_at <= now()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *;
+	`
+	var msgs []*domain.OutboxMessage
+	if err := r.pool.ForContext(ctx).SelectContext(ctx, &msgs, query, limit, lease.Seconds()); err != nil {
+		return nil, fmt.Errorf("failed to claim outbox messages: %w", err)
+	}
+	return msgs, nil
+}
+
+func (r *OutboxRepository) MarkDelivered(ctx context.Context, id uuid.UUID) error {
+	const query = `
+		UPDATE order_outbox
+		SET status = 'DELIVERED', delivered_at = now(), last_error = NULL
+		WHERE id = $1
+	`
+	if _, err := r.pool.ForContext(ctx).ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to mark outbox message delivered: %w", err)
+	}
+	return nil
+}
+
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id uuid.UUID, lastErr string, retryAt *time.Time) error {
+	const query = `
+		UPDATE order_outbox
+		SET status = CASE WHEN $3::timestamptz IS NULL THEN 'DEAD' ELSE 'PENDING' END,
+		    next_attempt_at = COALESCE($3::timestamptz, next_attempt_at),
+		    last_error = $2
+		WHERE id = $1
+	`
+	if _, err := r.pool.ForContext(ctx).ExecContext(ctx, query, id, lastErr, retryAt); err != nil {
+		return fmt.Errorf("failed to mark outbox message failed: %w", err)
+	}
+	return nil
+}
+
+func (r *OutboxRepository) List(ctx context.Context, status *domain.OutboxStatus, limit int) ([]*domain.OutboxMessage, error) {
+	const query = `
+		SELECT * FROM order_outbox
+		WHERE ($1::text IS NULL OR status = $1)
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	var msgs []*domain.OutboxMessage
+	if err := r.pool.ForContext(ctx).SelectContext(ctx, &msgs, query, status, limit); err != nil {
+		return nil, fmt.Errorf("failed to list outbox messages: %w", err)
+	}
+	return msgs, nil
+}
+
+func (r *OutboxRepository) Retry(ctx context.Context, id uuid.UUID) (*domain.OutboxMessage, error) {
+	const query = `
+		UPDATE order_outbox
+		SET status = 'PENDING', attempts = 0, next_attempt_at = now()
+		WHERE id = $1 AND status <> 'DELIVERED'
+		RETURNING *;
+	`
+	var msg domain.OutboxMessage
+	if err := r.pool.ForContext(ctx).GetContext(ctx, &msg, query, id); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func (r *OutboxRepository) DeleteDelivered(ctx context.Context, deliveredBefore time.Time) (int64, error) {
+	res, err := r.pool.ForContext(ctx).ExecContext(ctx,
+		`DELETE FROM order_outbox WHERE status = 'DELIVERED' AND delivered_at < $1`, deliveredBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge delivered outbox messages: %w", err)
+	}
+	return res.RowsAffected()
+}
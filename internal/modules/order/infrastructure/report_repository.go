@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"tsb-service/internal/modules/order/domain"
+	"tsb-service/pkg/db"
+)
+
+// reportOrdersWhere selects the day's orders the report covers: test orders
+// never count, and an online order only once Mollie reported it paid.
+const reportOrdersWhere = `
+	o.is_test = false
+	AND o.created_at >= $1 AND o.created_at < $2
+	AND (NOT o.is_online_payment
+	     OR EXISTS (SELECT 1 FROM mollie_payments mp WHERE mp.order_id = o.id AND mp.status = 'paid'))`
+
+// reportPaymentMethod is how an order was paid, as a ReportPaymentMethod.
+// It needs the order's table session joined as s.
+const reportPaymentMethod = `
+	CASE
+		WHEN o.is_online_payment THEN 'ONLINE'
+		WHEN s.payment_method = 'CARD' THEN 'CARD'
+		ELSE 'CASH'
+	END AS payment_method`
+
+type ReportRepository struct {
+	pool *db.DBPool
+}
+
+func NewReportRepository(pool *db.DBPool) domain.ReportRepository {
+	return &ReportRepository{pool: pool}
+}
+
+func (r *ReportRepository) FindReportOrders(ctx context.Context, start, end time.Time) ([]*domain.ReportOrderRow, error) {
+	query := `
+		SELECT
+			o.order_type, o.order_status,
+			` + reportPaymentMethod + `,
+			o.takeaway_discount, o.coupon_discount, o.delivery_fee, o.transaction_fee,
+			o.total_price, o.tip
+		FROM orders o
+		LEFT JOIN table_sessions s ON s.id = o.table_session_id
+		WHERE` + reportOrdersWhere
+
+	var rows []*domain.ReportOrderRow
+	if err := r.pool.ForContext(ctx).SelectContext(ctx, &rows, query, start, end); err != nil {
+		return nil, fmt.Errorf("failed to query report orders: %w", err)
+	}
+	return rows, nil
+}
+
+func (r *ReportRepository) FindReportVat(ctx context.Context, start, end time.Time) ([]*domain.ReportVatRow, error) {
+	// Products created before VAT categories existed were backfilled as food.
+	query := `
+		SELECT
+			COALESCE(p.vat_category, 'food') AS vat_category,
+			o.order_type,
+			op.vat_rate_applied,
+			SUM(op.total_price) AS gross
+		FROM order_product op
+		JOIN orders o ON o.id = op.order_id
+		JOIN products p ON p.id = op.product_id
+		WHERE o.order_status NOT IN ('CANCELLED', 'FAILED')
+		  AND` + reportOrdersWhere + `
+		GROUP BY 1, 2, 3`
+
+	var rows []*domain.ReportVatRow
+	if err := r.pool.ForContext(ctx).SelectContext(ctx, &rows, query, start, end); err != nil {
+		return nil, fmt.Errorf("failed to query report VAT: %w", err)
+	}
+	return rows, nil
+}
+
+func (r *ReportRepository) FindReportRefunds(ctx context.Context, start, end time.Time) (domain.ReportRefundTotals, error) {
+	const query = `
+		SELECT COUNT(*) AS refund_count, COALESCE(SUM(amount), 0) AS refund_amount
+		FROM order_refunds
+		WHERE created_at >= $1 AND created_at < $2
+		  AND NOT superseded`
+
+	var totals domain.ReportRefundTotals
+	if err := r.pool.ForContext(ctx).GetContext(ctx, &totals, query, start, end); err != nil {
+		return totals, fmt.Errorf("failed to query report refunds: %w", err)
+	}
+	return totals, nil
+}
+
+func (r *ReportRepository) ClaimDailyReport(ctx context.Context, day time.Time, lease time.Duration) (bool, error) {
+	// The upsert only takes over a lease that ran out on an unsent report.
+	res, err := r.pool.ForContext(ctx).ExecContext(ctx, `
+		INSERT INTO daily_reports (day, claimed_until)
+		VALUES ($1, now() + make_interval(secs => $2))
+		ON CONFLICT (day) DO UPDATE
+		SET claimed_until = EXCLUDED.claimed_until
+		WHERE daily_reports.sent_at IS NULL
+		  AND daily_reports.claimed_until <= now()`, day.Format(time.DateOnly), lease.Seconds())
+	if err != nil {
+		return false, fmt.Errorf("failed to claim daily report: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim daily report: %w", err)
+	}
+	return n == 1, nil
+}
+
+func (r *ReportRepository) MarkDailyReportSent(ctx context.Context, day time.Time) error {
+	_, err := r.pool.ForContext(ctx).ExecContext(ctx,
+		`UPDATE daily_reports SET sent_at = now() WHERE day = $1`, day.Format(time.DateOnly))
+	if err != nil {
+		return fmt.Errorf("failed to mark daily report sent: %w", err)
+	}
+	return nil
+}
+
+func (r *ReportRepository) ReleaseDailyReport(ctx context.Context, day time.Time) error {
+	_, err := r.pool.ForContext(ctx).ExecContext(ctx,
+		`DELETE FROM daily_reports WHERE day = $1 AND sent_at IS NULL`, day.Format(time.DateOnly))
+	if err != nil {
+		return fmt.Errorf("failed to release daily report: %w", err)
+	}
+	return nil
+}
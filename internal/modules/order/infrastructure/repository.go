@@ -2,13 +2,14 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 	"tsb-service/internal/modules/order/domain"
 	"tsb-service/pkg/db"
 	"tsb-service/pkg/logging"
-	"tsb-service/pkg/money"
 	"tsb-service/pkg/utils"
 
 	"github.com/google/uuid"
@@ -27,7 +28,7 @@ func NewOrderRepository(pool *db.DBPool) domain.OrderRepository {
 
 // Save inserts a new order, creates a Mollie payment, updates the order with payment details,
 // and links the order with its product lines.
-func (r *OrderRepository) Save(ctx context.Context, o *domain.Order, op *[]domain.OrderProductRaw) (*domain.Order, *[]domain.OrderProductRaw, error) {
+func (r *OrderRepository) Save(ctx context.Context, o *domain.Order, op *[]domain.OrderProductRaw, w domain.OrderWrite) (*domain.Order, *[]domain.OrderProductRaw, error) {
 	// Begin a transaction.
 	tx, err := r.pool.ForContext(ctx).BeginTxx(ctx, nil)
 	if err != nil {
@@ -47,26 +48,26 @@ func (r *OrderRepository) Save(ctx context.Context, o *domain.Order, op *[]domai
 			computedTotal = computedTotal.Add(prod.TotalPrice)
 		}
 
-		// Add delivery fee if applicable.
+		deliveryFee := decimal.Zero
 		if o.DeliveryFee != nil {
-			computedTotal = computedTotal.Add(*o.DeliveryFee)
+			deliveryFee = *o.DeliveryFee
 		}
+		o.TotalPrice = domain.ComputeTotal(computedTotal, deliveryFee, o.TakeawayDiscount, o.CouponDiscount, o.TransactionFee)
+	}
 
-		// Subtract discounts if applicable.
-		totalDiscount := o.TakeawayDiscount.Add(o.CouponDiscount)
-		if totalDiscount.GreaterThan(decimal.Zero) {
-			computedTotal = computedTotal.Sub(totalDiscount)
+	// A DINE_IN round locks its session, as settling it does, so it either
+	// lands on the bill or finds the session settled.
+	if o.TableSessionID != nil {
+		var closedAt *time.Time
+		err = tx.GetContext(ctx, &closedAt,
+			`SELECT closed_at FROM table_sessions WHERE id = $1 FOR UPDATE`, *o.TableSessionID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to lock table session: %w", err)
 		}
-
-		// Add transaction fee for online payments.
-		if o.TransactionFee.GreaterThan(decimal.Zero) {
-			computedTotal = computedTotal.Add(o.TransactionFee)
+		if closedAt != nil {
+			err = domain.ErrTableSessionClosed
+			return nil, nil, err
 		}
-
-		// Snap the total to 0,10 € — defensive: with rounded discounts and
-		// .x0-priced products this is already a no-op, but it guards future
-		// pricing changes (.x5 unit prices, fractional delivery fees, etc.).
-		o.TotalPrice = money.RoundToNearest10Cents(computedTotal)
 	}
 
 	// Insert the order record.
@@ -79,7 +80,8 @@ func (r *OrderRepository) Save(ctx context.Context, o *domain.Order, op *[]domai
 			street_id, street_name, house_number, box_number,
 			municipality_name, postcode, address_distance, is_manual_address,
 			address_place_id, address_lat, address_lng,
-			cash_payment_amount, is_test
+			cash_payment_amount, is_test, takeaway_discount_percent, held_for_schedule,
+			table_session_id, tip
 		) VALUES (
 			$1, $2, $3, $4,
 			$5, $6, $7, $8, $9,
@@ -88,7 +90,8 @@ func (r *OrderRepository) Save(ctx context.Context, o *domain.Order, op *[]domai
 			$18, $19, $20, $21,
 			$22, $23, $24, $25,
 			$26, $27, $28,
-			$29, $30
+			$29, $30, $31, $32,
+			$33, $34
 		)
 		RETURNING id, created_at, updated_at;
 	`
@@ -130,6 +133,10 @@ func (r *OrderRepository) Save(ctx context.Context, o *domain.Order, op *[]domai
 		o.AddressLng,
 		o.CashPaymentAmount,
 		o.IsTest,
+		o.TakeawayDiscountPercent,
+		o.HeldForSchedule,
+		o.TableSessionID,
+		o.Tip,
 	)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to insert order: %w", err)
@@ -184,6 +191,18 @@ func (r *OrderRepository) Save(ctx context.Context, o *domain.Order, op *[]domai
 		}
 	}
 
+	// Record the initial status and enqueue the order's side effects; the
+	// outbox worker delivers them once the order is committed.
+	if w.History != nil {
+		w.History.OrderID = o.ID
+	}
+	for _, m := range w.Outbox {
+		m.OrderID = o.ID
+	}
+	if err = applyOrderWrite(ctx, tx, o, w); err != nil {
+		return nil, nil, err
+	}
+
 	// Commit the transaction.
 	if err = tx.Commit(); err != nil {
 		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
@@ -192,15 +211,161 @@ func (r *OrderRepository) Save(ctx context.Context, o *domain.Order, op *[]domai
 	return o, op, nil
 }
 
-func (r *OrderRepository) Update(ctx context.Context, order *domain.Order) error {
+func (r *OrderRepository) Update(ctx context.Context, order *domain.Order, from domain.OrderStatus, w domain.OrderWrite) (err error) {
+	tx, err := r.pool.ForContext(ctx).BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
 	query := `
 		UPDATE orders
 		SET order_status = $1, estimated_ready_time = $2, cancellation_reason = $3, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $4;
+		WHERE id = $4 AND order_status = $5;
 	`
-	if _, err := r.pool.ForContext(ctx).ExecContext(ctx, query, order.OrderStatus, order.EstimatedReadyTime, order.CancellationReason, order.ID); err != nil {
+	res, err := tx.ExecContext(ctx, query, order.OrderStatus, order.EstimatedReadyTime, order.CancellationReason, order.ID, from)
+	if err != nil {
 		return fmt.Errorf("failed to update order: %w", err)
 	}
+	if n, raErr := res.RowsAffected(); raErr == nil && n == 0 {
+		err = domain.ErrOrderStatusChanged
+		return err
+	}
+	if err = recordDeliveryMilestone(ctx, tx, order, from); err != nil {
+		return err
+	}
+	if err = recordEtaActual(ctx, tx, order, from); err != nil {
+		return err
+	}
+	if err = applyOrderWrite(ctx, tx, order, w); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// applyOrderWrite writes the bookkeeping of an order change with the
+// transaction of the change.
+func applyOrderWrite(ctx context.Context, tx *sqlx.Tx, order *domain.Order, w domain.OrderWrite) error {
+	if w.History != nil {
+		if err := insertStatusHistory(ctx, tx, w.History); err != nil {
+			return err
+		}
+	}
+	if w.AttachSlot != nil {
+		res, err := tx.ExecContext(ctx,
+			`UPDATE slot_reservations SET order_id = $2 WHERE id = $1 AND order_id IS NULL`, *w.AttachSlot, order.ID)
+		if err != nil {
+			return fmt.Errorf("failed to attach slot reservation: %w", err)
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return domain.ErrSlotReservationExpired
+		}
+	}
+	if w.ReleaseSlot {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM slot_reservations WHERE order_id = $1`, order.ID); err != nil {
+			return fmt.Errorf("failed to release slot reservation: %w", err)
+		}
+	}
+	if w.ReleaseCoupon && order.CouponCode != nil {
+		if err := releaseCouponUsage(ctx, tx, *order.CouponCode, order.UserID); err != nil {
+			return err
+		}
+	}
+	if w.ClaimCoupon && order.CouponCode != nil {
+		if err := claimCouponUsage(ctx, tx, *order.CouponCode, order.UserID); err != nil {
+			return err
+		}
+	}
+	if w.Eta != nil {
+		if err := saveEtaEstimate(ctx, tx, w.Eta); err != nil {
+			return err
+		}
+	}
+	if w.IdempotencyKey != "" {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE order_idempotency_keys SET order_id = $3
+			WHERE user_id = $1 AND key = $2 AND order_id IS NULL`, order.UserID, w.IdempotencyKey, order.ID)
+		if err != nil {
+			return fmt.Errorf("failed to complete idempotency key: %w", err)
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return domain.ErrIdempotencyKeyInFlight
+		}
+	}
+	return insertOutbox(ctx, tx, w.Outbox)
+}
+
+// releaseCouponUsage gives one use of the coupon back to the user, globally
+// and per user, as the coupon module's DecrementUsageAtomic does. Codes are
+// matched as the coupon module normalizes them.
+func releaseCouponUsage(ctx context.Context, tx *sqlx.Tx, code string, userID uuid.UUID) error {
+	const perUser = `
+		UPDATE coupon_users cu SET used_count = cu.used_count - 1
+		FROM coupons c
+		WHERE c.code = upper(btrim($1)) AND cu.coupon_id = c.id
+		  AND cu.user_id = $2 AND cu.used_count > 0
+	`
+	if _, err := tx.ExecContext(ctx, perUser, code, userID); err != nil {
+		return fmt.Errorf("failed to release per-user coupon usage: %w", err)
+	}
+	const global = `
+		UPDATE coupons SET used_count = used_count - 1
+		WHERE code = upper(btrim($1)) AND used_count > 0
+	`
+	if _, err := tx.ExecContext(ctx, global, code); err != nil {
+		return fmt.Errorf("failed to release coupon usage: %w", err)
+	}
+	return nil
+}
+
+// claimCouponUsage takes one use of the coupon for the user, globally and per
+// user, under the same checks as the coupon module's RedeemAtomic. It returns
+// domain.ErrPaymentRetryCouponUnavailable when the coupon can no longer be
+// used.
+func claimCouponUsage(ctx context.Context, tx *sqlx.Tx, code string, userID uuid.UUID) error {
+	var coupon struct {
+		ID             uuid.UUID `db:"id"`
+		MaxUsesPerUser *int32    `db:"max_uses_per_user"`
+	}
+	err := tx.GetContext(ctx, &coupon, `
+		SELECT id, max_uses_per_user
+		FROM coupons
+		WHERE code = upper(btrim($1))
+		  AND is_active = true
+		  AND (max_uses IS NULL OR used_count < max_uses)
+		  AND (valid_from IS NULL OR valid_from <= NOW())
+		  AND (valid_until IS NULL OR valid_until >= NOW())
+		FOR UPDATE`, code)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrPaymentRetryCouponUnavailable
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock coupon: %w", err)
+	}
+
+	const perUser = `
+		INSERT INTO coupon_users (coupon_id, user_id, used_count)
+		VALUES ($1, $2, 1)
+		ON CONFLICT (coupon_id, user_id) DO UPDATE SET used_count = coupon_users.used_count + 1
+		WHERE $3::int IS NULL OR coupon_users.used_count < $3
+	`
+	res, err := tx.ExecContext(ctx, perUser, coupon.ID, userID, coupon.MaxUsesPerUser)
+	if err != nil {
+		return fmt.Errorf("failed to claim per-user coupon usage: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return domain.ErrPaymentRetryCouponUnavailable
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE coupons SET used_count = used_count + 1 WHERE id = $1`, coupon.ID); err != nil {
+		return fmt.Errorf("failed to claim coupon usage: %w", err)
+	}
 	return nil
 }
 
@@ -327,15 +492,9 @@ func (r *OrderRepository) FindByID(ctx context.Context, orderID uuid.UUID) (*dom
 	return &order, &orderProducts, nil
 }
 
-func (r *OrderRepository) FindPaginated(ctx context.Context, page int, limit int, userID *uuid.UUID) ([]*domain.Order, error) {
-	// Basic pagination safety : ensure page & limit are > 0
-	page = max(page, 1)
-	if limit < 1 {
-		limit = 10
-	}
-
-	offset := (page - 1) * limit
-
+// orderListWhere is the WHERE clause of the live order list, or of a
+// customer's orders when userID is set, on orders o, and its arguments.
+func orderListWhere(userID *uuid.UUID) (string, []any) {
 	// Hide online-payment orders that haven't been paid yet — those are not
 	// actionable from the admin dashboard. Cash orders are always returned.
 	conditions := []string{
@@ -345,20 +504,33 @@ func (r *OrderRepository) FindPaginated(ctx context.Context, page int, limit int
 		))`,
 	}
 	var args []any
-	placeholderIndex := 1
 
 	if userID != nil {
-		conditions = append(conditions, fmt.Sprintf("o.user_id = $%d", placeholderIndex))
+		conditions = append(conditions, "o.user_id = $1")
 		args = append(args, *userID)
-		placeholderIndex++
 	} else {
 		// Global (admin/POS) listing — never surface store-review test orders.
 		// User-scoped listings (myOrders) still show the reviewer their own
 		// order so they can validate the checkout flow. TEMPORARY.
 		conditions = append(conditions, "o.is_test = false")
+		// Orders scheduled for later stay out of the live list until released.
+		conditions = append(conditions, "o.held_for_schedule = false")
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func (r *OrderRepository) FindPaginated(ctx context.Context, page int, limit int, userID *uuid.UUID) ([]*domain.Order, error) {
+	// Basic pagination safety : ensure page & limit are > 0
+	page = max(page, 1)
+	if limit < 1 {
+		limit = 10
 	}
 
-	whereClause := "WHERE " + strings.Join(conditions, " AND ")
+	offset := (page - 1) * limit
+
+	whereClause, args := orderListWhere(userID)
+	placeholderIndex := len(args) + 1
 
 	limitPlaceholder := placeholderIndex
 	offsetPlaceholder := placeholderIndex + 1
@@ -385,12 +557,128 @@ func (r *OrderRepository) FindPaginated(ctx context.Context, page int, limit int
 	return orders, nil
 }
 
-func (r *OrderRepository) FindFiltered(ctx context.Context, filter domain.OrderHistoryFilter) ([]*domain.Order, *domain.OrderHistorySummary, error) {
-	filter.Page = max(filter.Page, 1)
-	if filter.Limit < 1 {
-		filter.Limit = 20
+func (r *OrderRepository) FindPage(ctx context.Context, userID *uuid.UUID, req domain.OrderPageRequest) (*domain.OrderPage, error) {
+	whereClause, args := orderListWhere(userID)
+
+	var total int
+	if err := r.pool.ForContext(ctx).GetContext(ctx, &total,
+		`SELECT COUNT(*) FROM orders o `+whereClause, args...); err != nil {
+		return nil, fmt.Errorf("failed to count orders: %w", err)
+	}
+	return r.findPage(ctx, "FROM orders o", whereClause, args, req, total)
+}
+
+func (r *OrderRepository) FindFilteredPage(ctx context.Context, filter domain.OrderHistoryFilter, req domain.OrderPageRequest) (*domain.OrderPage, *domain.OrderHistorySummary, error) {
+	whereClause, args := historyWhere(filter)
+	summary, err := r.historySummary(ctx, whereClause, args)
+	if err != nil {
+		return nil, nil, err
+	}
+	page, err := r.findPage(ctx, "FROM orders o LEFT JOIN users u ON o.user_id = u.id", whereClause, args, req, summary.TotalOrders)
+	if err != nil {
+		return nil, nil, err
 	}
+	return page, summary, nil
+}
+
+// findPage returns the orders of `from` matching whereClause that come
+// after req.After, newest first. It fetches one order more than asked to
+// tell whether there is a next page.
+func (r *OrderRepository) findPage(ctx context.Context, from, whereClause string, args []any, req domain.OrderPageRequest, total int) (*domain.OrderPage, error) {
+	if req.After != nil {
+		n := len(args)
+		whereClause += fmt.Sprintf(" AND (o.created_at, o.id) < ($%d, $%d)", n+1, n+2)
+		args = append(args, req.After.CreatedAt, req.After.ID)
+	}
+	query := fmt.Sprintf(`SELECT o.* %s %s ORDER BY o.created_at DESC, o.id DESC LIMIT $%d`,
+		from, whereClause, len(args)+1)
+	args = append(args, req.First+1)
+
+	var orders []*domain.Order
+	if err := r.pool.ForContext(ctx).SelectContext(ctx, &orders, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to query orders page: %w", err)
+	}
+
+	page := &domain.OrderPage{Orders: orders, TotalCount: total}
+	if len(orders) > req.First {
+		page.Orders = orders[:req.First]
+		page.HasNextPage = true
+	}
+	return page, nil
+}
+
+// The texts the order-history search looks into, besides the order ID and the
+// product names. They must stay identical to the trigram indexes of
+// migrations/20260715120000_add_order_search_indexes.sql.
+const (
+	searchUserText  = `(first_name || ' ' || last_name || ' ' || email || ' ' || coalesce(phone_number, ''))`
+	searchOrderText = `(coalesce(o.coupon_code, '') || ' ' || coalesce(o.street_name, '') || ' ' || coalesce(o.municipality_name, '') || ' ' || coalesce(o.order_note, ''))`
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// searchIDMatches returns the conditions under which search is the ID or the
+// invoice reference of order o, and their arguments, numbered from idx.
+func searchIDMatches(search domain.OrderSearch, idx int) ([]string, []any) {
+	var conds []string
+	var args []any
+	if search.Reference != "" {
+		conds = append(conds, fmt.Sprintf("right(replace(o.id::text, '-', ''), 8) = $%d", idx))
+		args = append(args, search.Reference)
+		idx++
+	}
+	if search.IDPrefix != "" {
+		conds = append(conds, fmt.Sprintf("o.id::text LIKE $%d", idx))
+		args = append(args, search.IDPrefix+"%")
+	}
+	return conds, args
+}
+
+// historySearch returns the condition matching search on orders o and its
+// arguments, numbered from idx.
+func historySearch(search domain.OrderSearch, idx int) (string, []any) {
+	matches, args := searchIDMatches(search, idx)
+	pattern := idx + len(args)
+	args = append(args, "%"+likeEscaper.Replace(search.Text)+"%")
+
+	matches = append(matches,
+		fmt.Sprintf("%s ILIKE $%d", searchOrderText, pattern),
+		fmt.Sprintf("o.user_id IN (SELECT id FROM users WHERE %s ILIKE $%d)", searchUserText, pattern),
+		fmt.Sprintf(`o.id IN (
+			SELECT op.order_id FROM order_product op
+			JOIN product_translations pt ON pt.product_id = op.product_id
+			WHERE pt.name ILIKE $%d)`, pattern),
+	)
+	return "(" + strings.Join(matches, " OR ") + ")", args
+}
+
+// historySearchRank returns the relevance of order o to search, and its
+// arguments numbered from idx: 1 for its ID or invoice reference, otherwise
+// how closely its customer, its own texts or one of its products match.
+func historySearchRank(search domain.OrderSearch, idx int) (string, []any) {
+	idMatches, args := searchIDMatches(search, idx)
+	text := idx + len(args)
+	args = append(args, search.Text)
+
+	idRank := "0"
+	if len(idMatches) > 0 {
+		idRank = "CASE WHEN " + strings.Join(idMatches, " OR ") + " THEN 1 ELSE 0 END"
+	}
+	rank := fmt.Sprintf(`GREATEST(
+			%s,
+			word_similarity($%d, %s),
+			COALESCE((SELECT word_similarity($%d, %s) FROM users WHERE id = o.user_id), 0),
+			COALESCE((
+				SELECT MAX(word_similarity($%d, pt.name)) FROM order_product op
+				JOIN product_translations pt ON pt.product_id = op.product_id
+				WHERE op.order_id = o.id), 0)
+		)`, idRank, text, searchOrderText, text, searchUserText, text)
+	return rank, args
+}
 
+// historyWhere is the WHERE clause of the order-history filter, on orders o
+// joined with users u, and its arguments.
+func historyWhere(filter domain.OrderHistoryFilter) (string, []any) {
 	// The order-history view tracks fulfilled business — cancelled and failed
 	// orders never count toward revenue, average, or the listing. Pin these
 	// exclusions server-side so the summary aggregates and the page list stay
@@ -419,10 +707,23 @@ func (r *OrderRepository) FindFiltered(ctx context.Context, filter domain.OrderH
 		args = append(args, string(*filter.OrderType))
 		idx++
 	}
-	if filter.Search != nil && *filter.Search != "" {
-		conditions = append(conditions, fmt.Sprintf("(u.first_name ILIKE $%d OR u.last_name ILIKE $%d)", idx, idx))
-		args = append(args, "%"+*filter.Search+"%")
+	if filter.ChangedBy != nil {
+		cond := fmt.Sprintf("h.actor_type = $%d", idx)
+		args = append(args, string(*filter.ChangedBy))
 		idx++
+		if filter.ChangedByID != nil {
+			cond += fmt.Sprintf(" AND h.actor_id = $%d", idx)
+			args = append(args, *filter.ChangedByID)
+			idx++
+		}
+		conditions = append(conditions,
+			"EXISTS (SELECT 1 FROM order_status_history h WHERE h.order_id = o.id AND "+cond+")")
+	}
+	if search, ok := historySearchOf(filter); ok {
+		cond, searchArgs := historySearch(search, idx)
+		conditions = append(conditions, cond)
+		args = append(args, searchArgs...)
+		idx += len(searchArgs)
 	}
 
 	whereClause := ""
@@ -432,8 +733,60 @@ func (r *OrderRepository) FindFiltered(ctx context.Context, filter domain.OrderH
 			whereClause += " AND " + c
 		}
 	}
+	return whereClause, args
+}
+
+func historySearchOf(filter domain.OrderHistoryFilter) (domain.OrderSearch, bool) {
+	if filter.Search == nil {
+		return domain.OrderSearch{}, false
+	}
+	return domain.ParseOrderSearch(*filter.Search)
+}
 
-	// Summary query
+func (r *OrderRepository) FindFiltered(ctx context.Context, filter domain.OrderHistoryFilter) ([]*domain.Order, *domain.OrderHistorySummary, error) {
+	filter.Page = max(filter.Page, 1)
+	if filter.Limit < 1 {
+		filter.Limit = 20
+	}
+
+	whereClause, args := historyWhere(filter)
+	idx := len(args) + 1
+
+	summary, err := r.historySummary(ctx, whereClause, args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Orders query with pagination; searches list the best matches first.
+	offset := (filter.Page - 1) * filter.Limit
+	orderArgs := append(args, filter.Limit, offset) //nolint:gocritic
+	orderBy := "o.created_at DESC"
+	if search, ok := historySearchOf(filter); ok {
+		rank, rankArgs := historySearchRank(search, idx+2)
+		orderBy = rank + " DESC, " + orderBy
+		orderArgs = append(orderArgs, rankArgs...)
+	}
+	ordersQuery := fmt.Sprintf(`
+		SELECT o.*
+		FROM orders o
+		LEFT JOIN users u ON o.user_id = u.id
+		%s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d
+	`, whereClause, orderBy, idx, idx+1)
+
+	var orders []*domain.Order
+	if err := r.pool.ForContext(ctx).SelectContext(ctx, &orders, ordersQuery, orderArgs...); err != nil {
+		logging.FromContext(ctx).Error("error querying order history", zap.Error(err))
+		return nil, nil, fmt.Errorf("failed to query order history: %w", err)
+	}
+
+	return orders, summary, nil
+}
+
+// historySummary aggregates the orders matching the order-history WHERE
+// clause.
+func (r *OrderRepository) historySummary(ctx context.Context, whereClause string, args []any) (*domain.OrderHistorySummary, error) {
 	summaryQuery := fmt.Sprintf(`
 		SELECT COUNT(*) as total_orders,
 			   COALESCE(SUM(o.total_price), 0) as total_revenue,
@@ -446,29 +799,63 @@ func (r *OrderRepository) FindFiltered(ctx context.Context, filter domain.OrderH
 	var summary domain.OrderHistorySummary
 	if err := r.pool.ForContext(ctx).GetContext(ctx, &summary, summaryQuery, args...); err != nil {
 		logging.FromContext(ctx).Error("error querying order history summary", zap.Error(err))
-		return nil, nil, fmt.Errorf("failed to query order history summary: %w", err)
+		return nil, fmt.Errorf("failed to query order history summary: %w", err)
 	}
+	return &summary, nil
+}
 
-	// Orders query with pagination
-	offset := (filter.Page - 1) * filter.Limit
-	ordersQuery := fmt.Sprintf(`
-		SELECT o.*
+func (r *OrderRepository) StreamExportLines(ctx context.Context, filter domain.OrderHistoryFilter, fn func(*domain.ExportLine) error) error {
+	whereClause, args := historyWhere(filter)
+	args = append(args, utils.GetLang(ctx))
+	langIdx := len(args)
+
+	// Lines come ordered by order so callers can tell where an order starts.
+	// The Mollie payment shown is the paid one, or the latest attempt.
+	query := fmt.Sprintf(`
+		SELECT
+			o.id AS order_id, o.created_at, o.order_status, o.order_type,
+			`+reportPaymentMethod+`,
+			mp.mollie_payment_id,
+			o.takeaway_discount, o.coupon_discount, o.coupon_code, o.delivery_fee,
+			o.transaction_fee, o.tip, o.total_price,
+			COALESCE((SELECT SUM(rf.amount) FROM order_refunds rf WHERE rf.order_id = o.id AND NOT rf.superseded), 0) AS refunded,
+			p.code AS product_code, pt.name AS product_name,
+			op.quantity, op.unit_price, op.total_price AS line_total, op.vat_rate_applied
 		FROM orders o
 		LEFT JOIN users u ON o.user_id = u.id
+		LEFT JOIN table_sessions s ON s.id = o.table_session_id
+		LEFT JOIN LATERAL (
+			SELECT mollie_payment_id FROM mollie_payments
+			WHERE order_id = o.id
+			ORDER BY (status = 'paid') DESC, created_at DESC
+			LIMIT 1
+		) mp ON true
+		JOIN order_product op ON op.order_id = o.id
+		LEFT JOIN products p ON p.id = op.product_id
+		LEFT JOIN product_translations pt ON pt.product_id = op.product_id AND pt.language = $%d
 		%s
-		ORDER BY o.created_at DESC
-		LIMIT $%d OFFSET $%d
-	`, whereClause, idx, idx+1)
-
-	orderArgs := append(args, filter.Limit, offset) //nolint:gocritic
+		ORDER BY o.created_at ASC, o.id, p.code, op.id
+	`, langIdx, whereClause)
 
-	var orders []*domain.Order
-	if err := r.pool.ForContext(ctx).SelectContext(ctx, &orders, ordersQuery, orderArgs...); err != nil {
-		logging.FromContext(ctx).Error("error querying order history", zap.Error(err))
-		return nil, nil, fmt.Errorf("failed to query order history: %w", err)
+	rows, err := r.pool.ForContext(ctx).QueryxContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query export lines: %w", err)
 	}
+	defer rows.Close()
 
-	return orders, &summary, nil
+	for rows.Next() {
+		var line domain.ExportLine
+		if err := rows.StructScan(&line); err != nil {
+			return fmt.Errorf("failed to scan export line: %w", err)
+		}
+		if err := fn(&line); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read export lines: %w", err)
+	}
+	return nil
 }
 
 func (r *OrderRepository) FindByOrderIDs(ctx context.Context, orderIDs []string) (map[string][]*domain.OrderProductRaw, error) {
@@ -609,43 +996,183 @@ func (r *OrderRepository) FindByUserIDs(ctx context.Context, userIDs []string) (
 	return result, nil
 }
 
-func (r *OrderRepository) InsertStatusHistory(ctx context.Context, orderID uuid.UUID, status domain.OrderStatus) error {
-	query := `INSERT INTO order_status_history (order_id, status) VALUES ($1, $2)`
-	if _, err := r.pool.ForContext(ctx).ExecContext(ctx, query, orderID, status); err != nil {
+func (r *OrderRepository) InsertStatusHistory(ctx context.Context, entry *domain.OrderStatusHistory) error {
+	return insertStatusHistory(ctx, r.pool.ForContext(ctx), entry)
+}
+
+func insertStatusHistory(ctx context.Context, exec sqlx.ExecerContext, entry *domain.OrderStatusHistory) error {
+	query := `
+		INSERT INTO order_status_history (order_id, status, forced, actor_type, actor_id,
+			cancellation_reason, previous_estimated_ready_time, estimated_ready_time)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	if _, err := exec.ExecContext(ctx, query,
+		entry.OrderID, entry.Status, entry.Forced, entry.ActorType, entry.ActorID,
+		entry.CancellationReason, entry.PreviousEstimatedReadyTime, entry.EstimatedReadyTime,
+	); err != nil {
 		return fmt.Errorf("failed to insert status history: %w", err)
 	}
 	return nil
 }
 
 // CancelStaleTestOrders cancels store-review test orders older than olderThan
-// that are not already terminal, and returns the affected order IDs so the
-// caller can record status-history rows. TEMPORARY (revert after launch).
-func (r *OrderRepository) CancelStaleTestOrders(ctx context.Context, olderThan time.Duration) ([]uuid.UUID, error) {
-	const query = `
-		UPDATE orders
-		SET order_status = $1,
-		    cancellation_reason = $2,
-		    updated_at = now()
+// that are not already terminal, records each cancellation in the status
+// history and returns the affected orders as they were before. TEMPORARY
+// (revert after launch).
+func (r *OrderRepository) CancelStaleTestOrders(ctx context.Context, olderThan time.Duration, actor domain.Actor) (orders []*domain.Order, err error) {
+	tx, err := r.pool.ForContext(ctx).BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// Lock the stale orders first so the statuses read are the ones replaced.
+	const selectQuery = `
+		SELECT * FROM orders
 		WHERE is_test = true
-		  AND order_status NOT IN ($3, $4)
-		  AND created_at < now() - make_interval(mins => $5)
-		RETURNING id;
+		  AND order_status NOT IN ($1, $2)
+		  AND created_at < now() - make_interval(mins => $3)
+		FOR UPDATE;
 	`
-	var ids []uuid.UUID
-	if err := r.pool.ForContext(ctx).SelectContext(ctx, &ids, query,
-		string(domain.OrderStatusCanceled),
-		string(domain.OrderCancellationReasonOther),
+	if err = tx.SelectContext(ctx, &orders, selectQuery,
 		string(domain.OrderStatusCanceled),
 		string(domain.OrderStatusFailed),
 		int(olderThan.Minutes()),
 	); err != nil {
-		return nil, fmt.Errorf("failed to cancel stale test orders: %w", err)
+		return nil, fmt.Errorf("failed to find stale test orders: %w", err)
+	}
+
+	const updateQuery = `
+		UPDATE orders
+		SET order_status = $1, cancellation_reason = $2, updated_at = now()
+		WHERE id = $3;
+	`
+	reason := domain.OrderCancellationReasonOther
+	for _, o := range orders {
+		if _, err = tx.ExecContext(ctx, updateQuery, string(domain.OrderStatusCanceled), string(reason), o.ID); err != nil {
+			return nil, fmt.Errorf("failed to cancel stale test order: %w", err)
+		}
+		cancelled := *o
+		cancelled.OrderStatus = domain.OrderStatusCanceled
+		cancelled.CancellationReason = &reason
+		if err = insertStatusHistory(ctx, tx, domain.NewStatusHistory(o, &cancelled, false, actor)); err != nil {
+			return nil, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return orders, nil
+}
+
+// ReleaseScheduledOrders clears the hold on scheduled orders due before
+// dueBefore, enqueues their announcement and returns them. Online-payment
+// orders are only released once paid, like the live listing; orders cancelled
+// or failed while held are left alone.
+func (r *OrderRepository) ReleaseScheduledOrders(ctx context.Context, dueBefore time.Time, effects []domain.OutboxKind) (orders []*domain.Order, err error) {
+	tx, err := r.pool.ForContext(ctx).BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	const query = `
+		UPDATE orders o
+		SET held_for_schedule = false, released_at = now()
+		WHERE o.held_for_schedule = true
+		  AND o.preferred_ready_time <= $1
+		  AND o.order_status NOT IN ('CANCELLED', 'FAILED')
+		  AND (o.is_online_payment = false OR EXISTS (
+			SELECT 1 FROM mollie_payments p
+			WHERE p.order_id = o.id AND p.status = 'paid'
+		  ))
+		RETURNING o.*;
+	`
+	if err = tx.SelectContext(ctx, &orders, query, dueBefore); err != nil {
+		return nil, fmt.Errorf("failed to release scheduled orders: %w", err)
+	}
+	for _, o := range orders {
+		event := domain.OutboxEvent{Status: o.OrderStatus, PreviousStatus: o.OrderStatus}
+		if err = insertOutbox(ctx, tx, domain.NewOutboxMessages(o.ID, event, effects)); err != nil {
+			return nil, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return orders, nil
+}
+
+// unconfirmedOrders matches the orders on o awaiting staff confirmation (see
+// domain.OrderRepository.EscalateUnconfirmedOrders) since before $1. Staff are
+// alerted when the order is placed, paid or released, whichever comes last.
+const unconfirmedOrders = `
+	o.order_status = 'PENDING'
+	AND o.held_for_schedule = false
+	AND o.is_test = false
+	AND o.order_type <> 'DINE_IN'
+	AND (o.is_online_payment = false OR EXISTS (
+		SELECT 1 FROM mollie_payments p
+		WHERE p.order_id = o.id AND p.status = 'paid'
+	))
+	AND GREATEST(o.created_at, o.released_at, (
+		SELECT max(p.paid_at) FROM mollie_payments p
+		WHERE p.order_id = o.id AND p.status = 'paid'
+	)) < $1`
+
+func (r *OrderRepository) EscalateUnconfirmedOrders(ctx context.Context, waitingSince time.Time, effects []domain.OutboxKind) (orders []*domain.Order, err error) {
+	tx, err := r.pool.ForContext(ctx).BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	query := `
+		UPDATE orders o
+		SET escalated_at = now()
+		WHERE o.escalated_at IS NULL AND ` + unconfirmedOrders + `
+		RETURNING o.*;
+	`
+	if err = tx.SelectContext(ctx, &orders, query, waitingSince); err != nil {
+		return nil, fmt.Errorf("failed to escalate unconfirmed orders: %w", err)
+	}
+	for _, o := range orders {
+		event := domain.OutboxEvent{Status: o.OrderStatus, PreviousStatus: o.OrderStatus}
+		if err = insertOutbox(ctx, tx, domain.NewOutboxMessages(o.ID, event, effects)); err != nil {
+			return nil, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return orders, nil
+}
+
+func (r *OrderRepository) FindUnconfirmedOrderIDs(ctx context.Context, waitingSince time.Time) ([]uuid.UUID, error) {
+	query := `SELECT o.id FROM orders o WHERE ` + unconfirmedOrders + ` ORDER BY o.created_at`
+	var ids []uuid.UUID
+	if err := r.pool.ForContext(ctx).SelectContext(ctx, &ids, query, waitingSince); err != nil {
+		return nil, fmt.Errorf("failed to find unconfirmed orders: %w", err)
 	}
 	return ids, nil
 }
 
 func (r *OrderRepository) FindStatusHistoryByOrderID(ctx context.Context, orderID uuid.UUID) ([]*domain.OrderStatusHistory, error) {
-	query := `SELECT id, order_id, status, changed_at FROM order_status_history WHERE order_id = $1 ORDER BY changed_at ASC`
+	query := `
+		SELECT id, order_id, status, forced, changed_at, actor_type, actor_id,
+			cancellation_reason, previous_estimated_ready_time, estimated_ready_time
+		FROM order_status_history WHERE order_id = $1 ORDER BY changed_at ASC`
 	var history []*domain.OrderStatusHistory
 	if err := r.pool.ForContext(ctx).SelectContext(ctx, &history, query, orderID); err != nil {
 		return nil, fmt.Errorf("failed to query status history: %w", err)
@@ -735,3 +1262,27 @@ func (r *OrderRepository) GetCustomerStats(ctx context.Context, startDate, endDa
 	}
 	return rows, nil
 }
+
+func (r *OrderRepository) GetDailyTips(ctx context.Context, startDate, endDate time.Time) ([]*domain.DailyTipsRow, error) {
+	// Only tips actually collected count: the Mollie payment went through and
+	// the order was not cancelled (its refund returns the tip too).
+	const query = `
+		SELECT
+			(o.created_at AT TIME ZONE 'Europe/Brussels')::date AS day,
+			COUNT(*) AS order_count,
+			SUM(o.tip) AS total
+		FROM orders o
+		WHERE o.tip > 0
+		  AND EXISTS (SELECT 1 FROM mollie_payments p WHERE p.order_id = o.id AND p.status = 'paid')
+		  AND o.is_test = false
+		  AND o.order_status NOT IN ('CANCELLED', 'FAILED')
+		  AND o.created_at >= $1 AND o.created_at < $2
+		GROUP BY day
+		ORDER BY day ASC`
+
+	var rows []*domain.DailyTipsRow
+	if err := r.pool.ForContext(ctx).SelectContext(ctx, &rows, query, startDate, endDate); err != nil {
+		return nil, fmt.Errorf("failed to query daily tips: %w", err)
+	}
+	return rows, nil
+}
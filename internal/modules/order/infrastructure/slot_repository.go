@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tsb-service/internal/modules/order/domain"
+	"tsb-service/pkg/db"
+)
+
+type SlotReservationRepository struct {
+	pool *db.DBPool
+}
+
+func NewSlotReservationRepository(pool *db.DBPool) domain.SlotReservationRepository {
+	return &SlotReservationRepository{pool: pool}
+}
+
+func (r *SlotReservationRepository) Reserve(ctx context.Context, res *domain.SlotReservation, limits []domain.SlotLimit) (err error) {
+	tx, err := r.pool.ForContext(ctx).BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// Serialize reservations per slot: the lock is held until commit, so the
+	// usage read below cannot go stale before the insert.
+	if _, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('slot_reservations'), hashtext($1))`,
+		res.SlotStart.UTC().Format(time.RFC3339)); err != nil {
+		return fmt.Errorf("failed to lock slot: %w", err)
+	}
+
+	var usage []domain.SlotUsage
+	if err = tx.SelectContext(ctx, &usage, `
+		SELECT slot_start, order_type, COUNT(*) AS orders, SUM(item_count) AS items
+		FROM slot_reservations
+		WHERE slot_start = $1
+		GROUP BY slot_start, order_type`, res.SlotStart); err != nil {
+		return fmt.Errorf("failed to read slot usage: %w", err)
+	}
+	if !domain.SlotHasRoom(limits, usage, res.OrderType, res.ItemCount) {
+		err = domain.ErrSlotFull
+		return err
+	}
+
+	if err = tx.GetContext(ctx, res, `
+		INSERT INTO slot_reservations (slot_start, order_type, item_count)
+		VALUES ($1, $2, $3)
+		RETURNING id, order_id, slot_start, order_type, item_count, created_at`,
+		res.SlotStart, res.OrderType, res.ItemCount); err != nil {
+		return fmt.Errorf("failed to insert slot reservation: %w", err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit slot reservation: %w", err)
+	}
+	return nil
+}
+
+func (r *SlotReservationRepository) Delete(ctx context.Context, reservationID uuid.UUID) error {
+	_, err := r.pool.ForContext(ctx).ExecContext(ctx,
+		`DELETE FROM slot_reservations WHERE id = $1`, reservationID)
+	return err
+}
+
+func (r *SlotReservationRepository) DeleteUnattached(ctx context.Context, createdBefore time.Time) (int, error) {
+	res, err := r.pool.ForContext(ctx).ExecContext(ctx,
+		`DELETE FROM slot_reservations WHERE order_id IS NULL AND created_at < $1`, createdBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete unattached slot reservations: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count deleted slot reservations: %w", err)
+	}
+	return int(n), nil
+}
+
+func (r *SlotReservationRepository) Usage(ctx context.Context, from, to time.Time) ([]domain.SlotUsage, error) {
+	var usage []domain.SlotUsage
+	err := r.pool.ForContext(ctx).SelectContext(ctx, &usage, `
+		SELECT slot_start, order_type, COUNT(*) AS orders, SUM(item_count) AS items
+		FROM slot_reservations
+		WHERE slot_start >= $1 AND slot_start < $2
+		GROUP BY slot_start, order_type`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"tsb-service/internal/modules/order/domain"
+	"tsb-service/pkg/db"
+)
+
+const tableColumns = `id, name, seats, token_version, is_active, created_at, updated_at`
+
+// sessionSelect loads sessions with the name of their table.
+const sessionSelect = `
+	SELECT s.id, s.table_id, t.name AS table_name, s.opened_at, s.closed_at, s.total, s.payment_method
+	FROM table_sessions s
+	JOIN dining_tables t ON t.id = s.table_id`
+
+type TableRepository struct {
+	pool *db.DBPool
+}
+
+func NewTableRepository(pool *db.DBPool) domain.TableRepository {
+	return &TableRepository{pool: pool}
+}
+
+func (r *TableRepository) ListTables(ctx context.Context) ([]*domain.DiningTable, error) {
+	var out []*domain.DiningTable
+	err := r.pool.ForContext(ctx).SelectContext(ctx, &out,
+		`SELECT `+tableColumns+` FROM dining_tables ORDER BY name ASC`)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *TableRepository) GetTable(ctx context.Context, id uuid.UUID) (*domain.DiningTable, error) {
+	var t domain.DiningTable
+	err := r.pool.ForContext(ctx).GetContext(ctx, &t,
+		`SELECT `+tableColumns+` FROM dining_tables WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *TableRepository) CreateTable(ctx context.Context, t *domain.DiningTable) (*domain.DiningTable, error) {
+	var out domain.DiningTable
+	err := r.pool.ForContext(ctx).GetContext(ctx, &out,
+		`INSERT INTO dining_tables (name, seats, is_active)
+		 VALUES ($1, $2, $3)
+		 RETURNING `+tableColumns,
+		t.Name, t.Seats, t.IsActive)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *TableRepository) UpdateTable(ctx context.Context, t *domain.DiningTable) (*domain.DiningTable, error) {
+	var out domain.DiningTable
+	err := r.pool.ForContext(ctx).GetContext(ctx, &out,
+		`UPDATE dining_tables
+		 SET name = $2,
+		     seats = $3,
+		     is_active = $4,
+		     updated_at = NOW()
+		 WHERE id = $1
+		 RETURNING `+tableColumns,
+		t.ID, t.Name, t.Seats, t.IsActive)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *TableRepository) RotateToken(ctx context.Context, id uuid.UUID) (*domain.DiningTable, error) {
+	var out domain.DiningTable
+	err := r.pool.ForContext(ctx).GetContext(ctx, &out,
+		`UPDATE dining_tables
+		 SET token_version = token_version + 1,
+		     updated_at = NOW()
+		 WHERE id = $1
+		 RETURNING `+tableColumns, id)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *TableRepository) OpenSession(ctx context.Context, tableID uuid.UUID) (*domain.TableSession, error) {
+	// The partial unique index on open sessions makes the insert a no-op when
+	// the table already has one, including one opened concurrently.
+	if _, err := r.pool.ForContext(ctx).ExecContext(ctx, `
+		INSERT INTO table_sessions (table_id) VALUES ($1)
+		ON CONFLICT (table_id) WHERE closed_at IS NULL DO NOTHING`, tableID); err != nil {
+		return nil, fmt.Errorf("failed to open table session: %w", err)
+	}
+	var s domain.TableSession
+	err := r.pool.ForContext(ctx).GetContext(ctx, &s,
+		sessionSelect+` WHERE s.table_id = $1 AND s.closed_at IS NULL`, tableID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load table session: %w", err)
+	}
+	return &s, nil
+}
+
+func (r *TableRepository) GetSession(ctx context.Context, id uuid.UUID) (*domain.TableSession, error) {
+	var s domain.TableSession
+	err := r.pool.ForContext(ctx).GetContext(ctx, &s, sessionSelect+` WHERE s.id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *TableRepository) ListOpenSessions(ctx context.Context) ([]*domain.TableSession, error) {
+	var out []*domain.TableSession
+	err := r.pool.ForContext(ctx).SelectContext(ctx, &out,
+		sessionSelect+` WHERE s.closed_at IS NULL ORDER BY s.opened_at ASC`)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *TableRepository) FindSessionOrders(ctx context.Context, sessionID uuid.UUID) ([]*domain.Order, error) {
+	var out []*domain.Order
+	err := r.pool.ForContext(ctx).SelectContext(ctx, &out,
+		`SELECT * FROM orders WHERE table_session_id = $1 ORDER BY created_at ASC`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *TableRepository) CloseSession(ctx context.Context, id uuid.UUID, method domain.TablePaymentMethod, bill func(rounds []*domain.Order) (decimal.Decimal, error)) (*domain.TableSession, error) {
+	tx, err := r.pool.ForContext(ctx).BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// OrderRepository.Save takes the same lock to add a round, so the rounds
+	// read below are all the session will ever have.
+	var locked uuid.UUID
+	err = tx.GetContext(ctx, &locked,
+		`SELECT id FROM table_sessions WHERE id = $1 AND closed_at IS NULL FOR UPDATE`, id)
+	if err != nil {
+		return nil, err
+	}
+	var rounds []*domain.Order
+	err = tx.SelectContext(ctx, &rounds,
+		`SELECT * FROM orders WHERE table_session_id = $1 ORDER BY created_at ASC`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load table rounds: %w", err)
+	}
+	total, err := bill(rounds)
+	if err != nil {
+		return nil, err
+	}
+
+	var s domain.TableSession
+	err = tx.GetContext(ctx, &s, `
+		WITH closed AS (
+			UPDATE table_sessions
+			SET closed_at = now(), total = $2, payment_method = $3
+			WHERE id = $1
+			RETURNING *
+		)
+		SELECT s.id, s.table_id, t.name AS table_name, s.opened_at, s.closed_at, s.total, s.payment_method
+		FROM closed s
+		JOIN dining_tables t ON t.id = s.table_id`, id, total, method)
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &s, nil
+}
@@ -0,0 +1,131 @@
+package interfaces
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"tsb-service/internal/modules/order/domain"
+	"tsb-service/pkg/invoice"
+	"tsb-service/pkg/logging"
+	"tsb-service/pkg/spreadsheet"
+	"tsb-service/pkg/timezone"
+	"tsb-service/pkg/utils"
+)
+
+var exportHeader = []any{
+	"Référence facture", "ID commande", "Date", "Statut", "Type", "Moyen de paiement", "ID paiement Mollie",
+	"Code produit", "Produit", "Quantité", "Prix unitaire", "Taux TVA", "HTVA", "TVA", "TVAC",
+	"Remise emporter", "Remise coupon", "Code coupon", "Frais de livraison", "Frais de transaction",
+	"Pourboire", "Total commande", "Remboursé",
+}
+
+// ExportOrders streams the order lines matching the orderHistory filters as
+// CSV (default) or XLSX (?format=xlsx) for accounting. startDate and endDate
+// are required, either RFC 3339 timestamps or YYYY-MM-DD days (restaurant
+// time, both included); status, orderType and search are optional. The
+// figures of an order appear on its first line only, so columns add up.
+// Admins only.
+func (h *OrderHandler) ExportOrders(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
+	if !utils.GetIsAdmin(ctx) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+
+	format := spreadsheet.Format(strings.ToLower(c.DefaultQuery("format", string(spreadsheet.FormatCSV))))
+	if format != spreadsheet.FormatCSV && format != spreadsheet.FormatXLSX {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or xlsx"})
+		return
+	}
+	start, err := parseExportDate(c.Query("startDate"), false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid startDate: " + err.Error()})
+		return
+	}
+	end, err := parseExportDate(c.Query("endDate"), true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid endDate: " + err.Error()})
+		return
+	}
+	if end.Before(start) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "endDate must not be before startDate"})
+		return
+	}
+
+	filter := domain.OrderHistoryFilter{StartDate: &start, EndDate: &end}
+	if v := c.Query("status"); v != "" {
+		s := domain.OrderStatus(v)
+		filter.Status = &s
+	}
+	if v := c.Query("orderType"); v != "" {
+		t := domain.OrderType(v)
+		filter.OrderType = &t
+	}
+	if v := c.Query("search"); v != "" {
+		filter.Search = &v
+	}
+
+	filename := fmt.Sprintf("commandes-%s-%s.%s",
+		timezone.In(start).Format(time.DateOnly), timezone.In(end).Format(time.DateOnly), format)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Header("Content-Type", format.ContentType())
+	c.Status(http.StatusOK)
+
+	// From here on the status is sent: a failure can only cut the file short.
+	w, err := spreadsheet.NewWriter(c.Writer, format, "Commandes")
+	if err == nil {
+		err = w.WriteRow(exportHeader...)
+	}
+	if err == nil {
+		var lastOrder string
+		err = h.orderService.ExportOrderLines(ctx, filter, func(l *domain.ExportLine) error {
+			orderID := l.OrderID.String()
+			row := []any{
+				invoice.OrderReference(orderID, l.CreatedAt), orderID,
+				timezone.In(l.CreatedAt).Format(time.DateTime), string(l.OrderStatus), string(l.OrderType),
+				string(l.PaymentMethod), l.MolliePaymentID,
+				l.ProductCode, l.ProductName, l.Quantity, l.UnitPrice, l.VatRate, l.LineNet(), l.LineVat(), l.LineTotal,
+			}
+			if orderID != lastOrder {
+				lastOrder = orderID
+				row = append(row, l.TakeawayDiscount, l.CouponDiscount, l.CouponCode, l.DeliveryFee,
+					l.TransactionFee, l.Tip, l.TotalPrice, l.Refunded)
+			}
+			return w.WriteRow(row...)
+		})
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		log.Error("order export failed", zap.String("format", string(format)), zap.Error(err))
+		_ = c.Error(err)
+	}
+}
+
+// parseExportDate reads an RFC 3339 timestamp, or a YYYY-MM-DD day in
+// restaurant time: its first instant, or its last when endOfDay is set.
+func parseExportDate(v string, endOfDay bool) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, fmt.Errorf("required")
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	day, err := time.ParseInLocation(time.DateOnly, v, timezone.Location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected YYYY-MM-DD or an RFC 3339 timestamp")
+	}
+	if endOfDay {
+		// The history filter's end is inclusive; Postgres keeps microseconds.
+		return day.AddDate(0, 0, 1).Add(-time.Microsecond), nil
+	}
+	return day, nil
+}
@@ -13,6 +13,7 @@ import (
 
 	orderApplication "tsb-service/internal/modules/order/application"
 	"tsb-service/internal/modules/order/domain"
+	paymentApplication "tsb-service/internal/modules/payment/application"
 	productApplication "tsb-service/internal/modules/product/application"
 	userApplication "tsb-service/internal/modules/user/application"
 	"tsb-service/pkg/invoice"
@@ -25,17 +26,23 @@ type OrderHandler struct {
 	orderService   orderApplication.OrderService
 	userService    userApplication.UserService
 	productService productApplication.ProductService
+	paymentService paymentApplication.PaymentService
+	tableService   orderApplication.TableService
 }
 
 func NewOrderHandler(
 	orderService orderApplication.OrderService,
 	userService userApplication.UserService,
 	productService productApplication.ProductService,
+	paymentService paymentApplication.PaymentService,
+	tableService orderApplication.TableService,
 ) *OrderHandler {
 	return &OrderHandler{
 		orderService:   orderService,
 		userService:    userService,
 		productService: productService,
+		paymentService: paymentService,
+		tableService:   tableService,
 	}
 }
 
@@ -243,6 +250,8 @@ func (h *OrderHandler) DownloadInvoice(c *gin.Context) {
 	if !order.TakeawayDiscount.IsZero() {
 		d := utils.FormatDecimal(order.TakeawayDiscount)
 		data.TakeawayDiscount = &d
+		pct := order.AppliedTakeawayDiscountPercent().String()
+		data.TakeawayPercent = &pct
 	}
 	if !order.CouponDiscount.IsZero() {
 		d := utils.FormatDecimal(order.CouponDiscount)
@@ -254,6 +263,36 @@ func (h *OrderHandler) DownloadInvoice(c *gin.Context) {
 		data.DeliveryFee = &d
 	}
 
+	// The tip is charged on top of the total, so refunds come out of both
+	paid := totalPrice
+	if order.Tip.IsPositive() {
+		tip := utils.FormatDecimal(order.Tip)
+		data.Tip = &tip
+		paid = paid.Add(order.Tip)
+		totalPaid := utils.FormatDecimal(paid)
+		data.TotalPaid = &totalPaid
+	}
+
+	// Refunds issued since the order was paid
+	refunds, err := h.paymentService.GetRefundsByOrderID(ctx, order.ID)
+	if err != nil {
+		log.Error("invoice: failed to fetch refunds", zap.String("order_id", orderIDStr), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate invoice"})
+		return
+	}
+	if len(refunds) > 0 {
+		refunded := decimal.Zero
+		for _, r := range refunds {
+			data.Refunds = append(data.Refunds, invoice.InvoiceRefund{
+				Reason: r.Reason,
+				Amount: utils.FormatDecimal(r.Amount),
+			})
+			refunded = refunded.Add(r.Amount)
+		}
+		net := utils.FormatDecimal(paid.Sub(refunded))
+		data.NetTotal = &net
+	}
+
 	// Build address from denormalized fields
 	if order.OrderType == domain.OrderTypeDelivery && order.StreetName != nil {
 		data.Address = &invoice.InvoiceAddress{
@@ -0,0 +1,58 @@
+package interfaces
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	orderApplication "tsb-service/internal/modules/order/application"
+	"tsb-service/pkg/invoice"
+	"tsb-service/pkg/logging"
+	"tsb-service/pkg/timezone"
+	"tsb-service/pkg/utils"
+)
+
+type ReportHandler struct {
+	reportService orderApplication.ReportService
+}
+
+func NewReportHandler(reportService orderApplication.ReportService) *ReportHandler {
+	return &ReportHandler{reportService: reportService}
+}
+
+// DownloadDailyReport serves the end-of-day report of :date (YYYY-MM-DD) as
+// the PDF emailed to the restaurant. Admins only.
+func (h *ReportHandler) DownloadDailyReport(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
+	if !utils.GetIsAdmin(ctx) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+
+	date, err := time.ParseInLocation(time.DateOnly, c.Param("date"), timezone.Location)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date, expected YYYY-MM-DD"})
+		return
+	}
+
+	report, err := h.reportService.GetDailyReport(ctx, date)
+	if err != nil {
+		log.Error("daily report: failed to build", zap.String("date", c.Param("date")), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate report"})
+		return
+	}
+	pdfBytes, err := h.reportService.RenderDailyReportPDF(report)
+	if err != nil {
+		log.Error("daily report: failed to render", zap.String("date", c.Param("date")), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate report"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, invoice.DailyReportFilename(report.Date)))
+	c.Data(http.StatusOK, "application/pdf", pdfBytes)
+}
@@ -0,0 +1,249 @@
+package interfaces
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"tsb-service/internal/modules/order/domain"
+	productDomain "tsb-service/internal/modules/product/domain"
+	"tsb-service/pkg/invoice"
+	"tsb-service/pkg/logging"
+	"tsb-service/pkg/ticket"
+	"tsb-service/pkg/utils"
+)
+
+// PrintTicket returns an order's ticket as raw ESC/POS bytes, for POS
+// handhelds to print or forward to a network printer. ?layout is kitchen
+// (default) or customer and ?paper 80 (default) or 58. The kitchen ticket is
+// in the staff's language, the customer ticket in the order's. Admins only.
+func (h *OrderHandler) PrintTicket(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
+	if !utils.GetIsAdmin(ctx) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+
+	orderIDStr := c.Param("id")
+	orderID, err := uuid.Parse(orderIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order ID"})
+		return
+	}
+	layout := ticket.Layout(c.DefaultQuery("layout", string(ticket.LayoutKitchen)))
+	if layout != ticket.LayoutKitchen && layout != ticket.LayoutCustomer {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "layout must be kitchen or customer"})
+		return
+	}
+	paper, err := ticket.ParsePaper(c.DefaultQuery("paper", "80"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "paper must be 58 or 80"})
+		return
+	}
+
+	order, orderProducts, err := h.orderService.GetOrderByID(ctx, orderID)
+	if err != nil || order == nil {
+		if err != nil {
+			log.Error("ticket: failed to fetch order", zap.String("order_id", orderIDStr), zap.Error(err))
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+		return
+	}
+
+	lang := utils.GetLang(ctx)
+	if layout == ticket.LayoutCustomer {
+		lang = order.Language
+	}
+	lang = ticket.Language(lang)
+	ctx = utils.SetLang(ctx, lang)
+
+	data := ticket.TicketData{
+		Reference:       invoice.OrderReference(order.ID.String(), order.CreatedAt),
+		OrderDate:       order.CreatedAt,
+		OrderType:       string(order.OrderType),
+		Language:        lang,
+		ReadyTime:       order.EstimatedReadyTime,
+		AddressExtra:    order.AddressExtra,
+		Note:            order.OrderNote,
+		Total:           utils.FormatDecimal(order.TotalPrice),
+		IsOnlinePayment: order.IsOnlinePayment,
+	}
+	if data.ReadyTime == nil {
+		data.ReadyTime = order.PreferredReadyTime
+	}
+
+	data.Items, err = h.ticketItems(ctx, lang, *orderProducts)
+	if err != nil {
+		log.Error("ticket: failed to fetch products", zap.String("order_id", orderIDStr), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate ticket"})
+		return
+	}
+
+	if len(order.OrderExtra) > 0 {
+		var extras []domain.OrderExtra
+		if err := json.Unmarshal(order.OrderExtra, &extras); err != nil {
+			log.Warn("ticket: failed to unmarshal order_extra", zap.String("order_id", orderIDStr), zap.Error(err))
+		}
+		for _, e := range extras {
+			data.Extras = append(data.Extras, ticket.TicketExtra{Name: e.Name, Options: e.Options})
+		}
+	}
+
+	if order.TableSessionID != nil {
+		session, err := h.tableService.GetSession(ctx, *order.TableSessionID)
+		if err != nil {
+			log.Error("ticket: failed to fetch table session", zap.String("order_id", orderIDStr), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate ticket"})
+			return
+		}
+		data.TableName = &session.TableName
+	}
+
+	if layout == ticket.LayoutCustomer {
+		user, err := h.userService.GetUserByID(ctx, order.UserID.String())
+		if err != nil {
+			log.Error("ticket: failed to fetch user", zap.String("order_id", orderIDStr), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate ticket"})
+			return
+		}
+		data.CustomerName = strings.TrimSpace(user.FirstName + " " + user.LastName)
+		data.CustomerPhone = user.PhoneNumber
+		fillTicketAmounts(&data, order, *orderProducts)
+	}
+
+	if order.OrderType == domain.OrderTypeDelivery && order.StreetName != nil {
+		addr := (&invoice.InvoiceAddress{
+			StreetName:       *order.StreetName,
+			HouseNumber:      deref(order.HouseNumber),
+			BoxNumber:        order.BoxNumber,
+			MunicipalityName: deref(order.MunicipalityName),
+			Postcode:         deref(order.Postcode),
+		}).Format()
+		data.Address = &addr
+	}
+
+	out, err := ticket.Generate(layout, data, paper)
+	if err != nil {
+		log.Error("ticket: failed to render", zap.String("order_id", orderIDStr), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate ticket"})
+		return
+	}
+
+	filename := fmt.Sprintf("%s-%s.bin", layout, data.Reference)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, "application/octet-stream", out)
+}
+
+// ticketItems names the order lines and their selections in lang.
+func (h *OrderHandler) ticketItems(ctx context.Context, lang string, orderProducts []domain.OrderProductRaw) ([]ticket.TicketItem, error) {
+	productIDs := make([]string, len(orderProducts))
+	for i, op := range orderProducts {
+		productIDs[i] = op.ProductID.String()
+	}
+	products, err := h.productService.GetProductNamesForInvoice(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[uuid.UUID]*productDomain.ProductOrderDetails, len(products))
+	for _, p := range products {
+		names[p.ID] = p
+	}
+
+	// Orders repeat the same groups and choices; look each up once.
+	groupNames := map[uuid.UUID]string{}
+	choiceNames := map[uuid.UUID]string{}
+	choiceName := func(id uuid.UUID) string {
+		if n, ok := choiceNames[id]; ok {
+			return n
+		}
+		if choice, err := h.productService.GetChoiceByID(ctx, id); err == nil && choice != nil {
+			choiceNames[id] = choice.GetTranslationFor(lang)
+		}
+		return choiceNames[id]
+	}
+	groupName := func(id uuid.UUID) string {
+		if n, ok := groupNames[id]; ok {
+			return n
+		}
+		if group, err := h.productService.GetChoiceGroupByID(ctx, id); err == nil && group != nil {
+			groupNames[id] = group.GetTranslationFor(lang)
+		}
+		return groupNames[id]
+	}
+
+	items := make([]ticket.TicketItem, 0, len(orderProducts))
+	for _, op := range orderProducts {
+		item := ticket.TicketItem{
+			Quantity:  op.Quantity,
+			LineTotal: utils.FormatDecimal(op.TotalPrice),
+		}
+		if p := names[op.ProductID]; p != nil {
+			item.Name = p.Name
+			item.Code = deref(p.Code)
+		}
+		if op.ProductChoiceID != nil {
+			if n := choiceName(*op.ProductChoiceID); n != "" {
+				item.Options = append(item.Options, n)
+			}
+		}
+		for _, sel := range op.Selections {
+			option := choiceName(sel.ChoiceID)
+			if g := groupName(sel.GroupID); g != "" {
+				option = g + ": " + option
+			}
+			if sel.Quantity > 1 {
+				option += fmt.Sprintf(" x%d", sel.Quantity)
+			}
+			item.Options = append(item.Options, option)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// fillTicketAmounts sets the prices printed on the customer ticket.
+func fillTicketAmounts(data *ticket.TicketData, order *domain.Order, orderProducts []domain.OrderProductRaw) {
+	subtotal := decimal.Zero
+	for _, op := range orderProducts {
+		subtotal = subtotal.Add(op.TotalPrice)
+	}
+	data.Subtotal = utils.FormatDecimal(subtotal)
+
+	if !order.TakeawayDiscount.IsZero() {
+		d := utils.FormatDecimal(order.TakeawayDiscount)
+		data.TakeawayDiscount = &d
+	}
+	if !order.CouponDiscount.IsZero() {
+		d := utils.FormatDecimal(order.CouponDiscount)
+		data.CouponDiscount = &d
+	}
+	if order.DeliveryFee != nil && !order.DeliveryFee.IsZero() {
+		d := utils.FormatDecimal(*order.DeliveryFee)
+		data.DeliveryFee = &d
+	}
+	if order.Tip.IsPositive() {
+		tip := utils.FormatDecimal(order.Tip)
+		paid := utils.FormatDecimal(order.TotalPrice.Add(order.Tip))
+		data.Tip = &tip
+		data.TotalPaid = &paid
+	}
+
+	// The customer said what they will pay with; the driver brings the change.
+	if !order.IsOnlinePayment && order.CashPaymentAmount != nil {
+		given := utils.FormatDecimal(*order.CashPaymentAmount)
+		data.CashGiven = &given
+		if change := order.CashPaymentAmount.Sub(order.TotalPrice); change.IsPositive() {
+			c := utils.FormatDecimal(change)
+			data.ChangeDue = &c
+		}
+	}
+}
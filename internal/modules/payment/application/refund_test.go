@@ -0,0 +1,43 @@
+package application
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	orderDomain "tsb-service/internal/modules/order/domain"
+	"tsb-service/internal/modules/payment/domain"
+)
+
+func TestRefundableItems(t *testing.T) {
+	maki, ramen := uuid.New(), uuid.New()
+	products := []orderDomain.OrderProductRaw{
+		{ID: maki, Quantity: 4, UnitPrice: decimal.RequireFromString("5"), TotalPrice: decimal.RequireFromString("20")},
+		{ID: ramen, Quantity: 1, UnitPrice: decimal.RequireFromString("20"), TotalPrice: decimal.RequireFromString("20")},
+	}
+	previous := []*domain.Refund{
+		{Lines: []domain.RefundLine{{OrderProductID: maki, Quantity: 1}}},
+		{Lines: []domain.RefundLine{{OrderProductID: maki, Quantity: 2}}},
+		{}, // free-amount refund without lines
+	}
+	// 10% takeaway discount plus a 2 EUR coupon on a 40 EUR subtotal.
+	order := &orderDomain.Order{
+		TakeawayDiscount: decimal.RequireFromString("4"),
+		CouponDiscount:   decimal.RequireFromString("2"),
+	}
+
+	items := refundableItems(order, products, previous)
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2", len(items))
+	}
+	if items[0].Refunded != 3 || items[1].Refunded != 0 {
+		t.Errorf("refunded = %d, %d; want 3, 0", items[0].Refunded, items[1].Refunded)
+	}
+	if got := items[0].UnitPrice.StringFixed(2); got != "4.25" {
+		t.Errorf("maki unit price = %s, want 4.25 (discounts spread over items)", got)
+	}
+	if got := items[1].UnitPrice.StringFixed(2); got != "17.00" {
+		t.Errorf("ramen unit price = %s, want 17.00", got)
+	}
+}
@@ -3,23 +3,21 @@ package application
 import (
 	"testing"
 
-	"github.com/VictorAvelar/mollie-api-go/v4/mollie"
 	"github.com/shopspring/decimal"
 
+	orderDomain "tsb-service/internal/modules/order/domain"
+	"tsb-service/internal/modules/payment/domain"
 	"tsb-service/pkg/money"
 )
 
-func line(value string) mollie.PaymentLines {
-	return mollie.PaymentLines{TotalAmount: &mollie.Amount{Value: value, Currency: "EUR"}}
+func line(value string) domain.PaymentLine {
+	return domain.PaymentLine{TotalAmount: decimal.RequireFromString(value)}
 }
 
 func TestRoundingCorrectionLine(t *testing.T) {
 	t.Run("no correction when lines already sum to total", func(t *testing.T) {
-		lines := []mollie.PaymentLines{line("20.00"), line("5.00"), line("-5.00")}
-		corr, err := roundingCorrectionLine(decimal.RequireFromString("20.00"), lines)
-		if err != nil {
-			t.Fatalf("unexpected error: %v", err)
-		}
+		lines := []domain.PaymentLine{line("20.00"), line("5.00"), line("-5.00")}
+		corr := roundingCorrectionLine(decimal.RequireFromString("20.00"), lines)
 		if corr != nil {
 			t.Fatalf("expected no correction, got %+v", corr)
 		}
@@ -27,53 +25,44 @@ func TestRoundingCorrectionLine(t *testing.T) {
 
 	t.Run("positive correction when total exceeds line sum", func(t *testing.T) {
 		// lines sum to 19.95, total snapped to 20.00 → +0.05 surcharge.
-		lines := []mollie.PaymentLines{line("24.95"), line("-5.00")}
-		corr, err := roundingCorrectionLine(decimal.RequireFromString("20.00"), lines)
-		if err != nil {
-			t.Fatalf("unexpected error: %v", err)
-		}
+		lines := []domain.PaymentLine{line("24.95"), line("-5.00")}
+		corr := roundingCorrectionLine(decimal.RequireFromString("20.00"), lines)
 		if corr == nil {
 			t.Fatal("expected a correction line")
 		}
-		if corr.Type != mollie.SurchargeLine {
-			t.Fatalf("expected SurchargeLine, got %v", corr.Type)
+		if corr.Type != domain.PaymentLineSurcharge {
+			t.Fatalf("expected a surcharge line, got %v", corr.Type)
 		}
-		if corr.TotalAmount.Value != "0.05" {
-			t.Fatalf("expected 0.05, got %s", corr.TotalAmount.Value)
+		if corr.TotalAmount.StringFixed(2) != "0.05" {
+			t.Fatalf("expected 0.05, got %s", corr.TotalAmount)
 		}
 	})
 
 	t.Run("negative correction when line sum exceeds total", func(t *testing.T) {
 		// lines sum to 20.05, total snapped to 20.00 → -0.05 discount.
-		lines := []mollie.PaymentLines{line("25.05"), line("-5.00")}
-		corr, err := roundingCorrectionLine(decimal.RequireFromString("20.00"), lines)
-		if err != nil {
-			t.Fatalf("unexpected error: %v", err)
-		}
+		lines := []domain.PaymentLine{line("25.05"), line("-5.00")}
+		corr := roundingCorrectionLine(decimal.RequireFromString("20.00"), lines)
 		if corr == nil {
 			t.Fatal("expected a correction line")
 		}
-		if corr.Type != mollie.DiscountProductLine {
-			t.Fatalf("expected DiscountProductLine, got %v", corr.Type)
+		if corr.Type != domain.PaymentLineDiscount {
+			t.Fatalf("expected a discount line, got %v", corr.Type)
 		}
-		if corr.TotalAmount.Value != "-0.05" {
-			t.Fatalf("expected -0.05, got %s", corr.TotalAmount.Value)
+		if corr.TotalAmount.StringFixed(2) != "-0.05" {
+			t.Fatalf("expected -0.05, got %s", corr.TotalAmount)
 		}
 	})
 
 	t.Run("correction makes lines sum exactly to total", func(t *testing.T) {
-		lines := []mollie.PaymentLines{line("24.93"), line("-5.00")}
+		lines := []domain.PaymentLine{line("24.93"), line("-5.00")}
 		total := decimal.RequireFromString("20.00")
-		corr, err := roundingCorrectionLine(total, lines)
-		if err != nil {
-			t.Fatalf("unexpected error: %v", err)
-		}
+		corr := roundingCorrectionLine(total, lines)
 		if corr != nil {
 			lines = append(lines, *corr)
 		}
 		sum := decimal.Zero
 		for _, l := range lines {
-			sum = sum.Add(decimal.RequireFromString(l.TotalAmount.Value))
+			sum = sum.Add(l.TotalAmount)
 		}
 		if !sum.Equal(total) {
 			t.Fatalf("lines sum %s != total %s", sum, total)
@@ -123,7 +112,7 @@ func TestRoundingCorrectionLineNearFullDiscount(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			var lines []mollie.PaymentLines
+			var lines []domain.PaymentLine
 			rawSum := decimal.Zero
 			for _, p := range tc.products {
 				lines = append(lines, line(p))
@@ -145,23 +134,19 @@ func TestRoundingCorrectionLineNearFullDiscount(t *testing.T) {
 			// TotalPrice as Save computes it: snap the raw composite to 10 cents.
 			total := money.RoundToNearest10Cents(rawSum.Sub(takeaway).Sub(coupon).Add(txFee))
 
-			corr, err := roundingCorrectionLine(total, lines)
-			if err != nil {
-				t.Fatalf("unexpected error: %v", err)
-			}
+			corr := roundingCorrectionLine(total, lines)
 			if corr != nil {
 				// The correction must never exceed one rounding unit; a larger
 				// gap would mean a pricing/rounding bug, not a rounding artifact.
-				mag := decimal.RequireFromString(corr.TotalAmount.Value).Abs()
-				if mag.GreaterThan(tenCents) {
-					t.Fatalf("correction %s exceeds one rounding unit (0.10)", corr.TotalAmount.Value)
+				if corr.TotalAmount.Abs().GreaterThan(tenCents) {
+					t.Fatalf("correction %s exceeds one rounding unit (0.10)", corr.TotalAmount)
 				}
 				lines = append(lines, *corr)
 			}
 
 			sum := decimal.Zero
 			for _, l := range lines {
-				sum = sum.Add(decimal.RequireFromString(l.TotalAmount.Value))
+				sum = sum.Add(l.TotalAmount)
 			}
 			if !sum.Equal(total) {
 				t.Fatalf("lines sum %s != charged total %s", sum, total)
@@ -172,3 +157,39 @@ func TestRoundingCorrectionLineNearFullDiscount(t *testing.T) {
 		})
 	}
 }
+
+// TestPaymentLinesWithTip checks the tip is its own VAT-free line, added after
+// the rounding correction so the lines still sum to the amount charged.
+func TestPaymentLinesWithTip(t *testing.T) {
+	o := orderDomain.Order{
+		OrderType:        orderDomain.OrderTypePickUp,
+		TakeawayDiscount: decimal.RequireFromString("1.30"),
+		TransactionFee:   decimal.RequireFromString("0.30"),
+		// 12.95 - 1.30 + 0.30 = 11.95, snapped to 12.00.
+		TotalPrice: decimal.RequireFromString("12.00"),
+		Tip:        decimal.RequireFromString("1.50"),
+	}
+	op := []orderDomain.OrderProduct{{
+		Product:    orderDomain.Product{Name: "Maki"},
+		Quantity:   1,
+		UnitPrice:  decimal.RequireFromString("12.95"),
+		TotalPrice: decimal.RequireFromString("12.95"),
+		VatRate:    decimal.NewFromInt(6),
+	}}
+
+	lines := paymentLines(o, op)
+	tip := lines[len(lines)-1]
+	if tip.TotalAmount.StringFixed(2) != "1.50" || tip.VATRate == nil || !tip.VATRate.IsZero() || tip.VATAmount == nil || !tip.VATAmount.IsZero() {
+		t.Errorf("last line = %s at %v%% VAT, want the 1.50 tip without VAT", tip.TotalAmount, tip.VATRate)
+	}
+	if corr := lines[len(lines)-2]; corr.Description != "Ajustement" || corr.TotalAmount.StringFixed(2) != "0.05" {
+		t.Errorf("line before the tip = %q %s, want the 0.05 rounding correction", corr.Description, corr.TotalAmount)
+	}
+	sum := decimal.Zero
+	for _, l := range lines {
+		sum = sum.Add(l.TotalAmount)
+	}
+	if !sum.Equal(o.AmountCharged()) {
+		t.Errorf("lines sum %s != amount charged %s", sum, o.AmountCharged())
+	}
+}
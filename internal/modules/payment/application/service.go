@@ -2,11 +2,13 @@ package application
 
 import (
 	"context"
-	"encoding/json"
+	"database/sql"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"time"
 
-	"github.com/VictorAvelar/mollie-api-go/v4/mollie"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
@@ -15,63 +17,139 @@ import (
 	orderApplication "tsb-service/internal/modules/order/application"
 	orderDomain "tsb-service/internal/modules/order/domain"
 	"tsb-service/internal/modules/payment/domain"
-	productApplication "tsb-service/internal/modules/product/application"
 	productDomain "tsb-service/internal/modules/product/domain"
-	userApplication "tsb-service/internal/modules/user/application"
 	userDomain "tsb-service/internal/modules/user/domain"
 	"tsb-service/pkg/brand"
-	es "tsb-service/pkg/email/scaleway"
 )
 
 type PaymentService interface {
 	CreatePayment(ctx context.Context, o orderDomain.Order, op []orderDomain.OrderProduct, u userDomain.User, a *addressDomain.Address, customRedirectURL *string) (*domain.MolliePayment, error)
-	CreateFullRefund(ctx context.Context, externalPaymentID string) error
-	// FetchMollieStatus fetches the authoritative payment status from Mollie
-	// without touching the local DB. The webhook handler persists it only after
-	// the order business logic succeeds (see PersistPaymentStatus).
-	FetchMollieStatus(ctx context.Context, externalMolliePaymentID string) (*domain.PaymentStatusUpdate, error)
+	// CreateFullRefund refunds whatever is left on the payment, covering every
+	// item not refunded yet. It returns a nil refund when nothing is left.
+	CreateFullRefund(ctx context.Context, externalPaymentID string, reason string) (*domain.Refund, error)
+	// RefundSupersededPayment refunds whatever is left on a payment attempt
+	// its order no longer counts on. The refund covers no item, as the items
+	// are paid by the order's latest attempt, and is recorded as superseded so
+	// it stays out of the order's refunds. It returns a nil refund when nothing
+	// is left.
+	RefundSupersededPayment(ctx context.Context, externalPaymentID string) (*domain.Refund, error)
+	// RefundOrder issues a partial refund of the order's payment. Requests that
+	// can't be honoured are rejected with domain.ErrInvalidRefund.
+	RefundOrder(ctx context.Context, orderID uuid.UUID, req domain.RefundRequest) (*domain.Refund, error)
+	GetRefundsByOrderID(ctx context.Context, orderID uuid.UUID) ([]*domain.Refund, error)
+	// FetchPaymentStatus fetches the authoritative payment status from the
+	// provider without touching the local DB. The webhook handler persists it
+	// only after the order business logic succeeds (see PersistPaymentStatus).
+	FetchPaymentStatus(ctx context.Context, externalPaymentID string) (*domain.PaymentStatusUpdate, error)
+	// ParseWebhook returns the ID of the payment a provider webhook is about.
+	ParseWebhook(r *http.Request) (string, error)
 	// PersistPaymentStatus writes the fetched status + timestamps to the local DB.
 	PersistPaymentStatus(ctx context.Context, externalMolliePaymentID string, update *domain.PaymentStatusUpdate) error
 	// WithPaymentLock serializes concurrent webhook deliveries for the same payment.
 	WithPaymentLock(ctx context.Context, paymentID string, fn func(context.Context) error) error
 	UpdatePaymentStatusByOrderID(ctx context.Context, orderID uuid.UUID, status string) (*domain.MolliePayment, error)
+	// GetPaymentByOrderID returns the order's latest payment attempt.
 	GetPaymentByOrderID(ctx context.Context, orderID uuid.UUID) (*domain.MolliePayment, error)
 	GetPaymentByExternalID(ctx context.Context, externalMolliePaymentID string) (*domain.MolliePayment, error)
-	// HandlePaymentPaid processes a paid payment: verifies amount, enriches order, sends email.
-	// Returns the domain order for the caller to publish to PubSub (avoids circular import with resolver).
+	// HandlePaymentPaid processes a paid payment: verifies amount and enqueues the
+	// customer email, staff announcement and payment webhook in the order outbox.
+	// Returns the domain order for the caller to publish to PubSub (avoids
+	// circular import with resolver).
 	HandlePaymentPaid(ctx context.Context, orderID uuid.UUID) (*orderDomain.Order, error)
-	HandlePaymentFailed(ctx context.Context, orderID uuid.UUID) (*orderDomain.Order, error)
+	// HandlePaymentFailed cancels the order of a failed payment, enqueueing
+	// its announcement in the same transaction.
+	HandlePaymentFailed(ctx context.Context, payment *domain.MolliePayment) error
+	// HandleSupersededPaymentPaid enqueues the refund of a payment attempt
+	// paid after its customer retried paying the order (see
+	// orderDomain.SupersededPaymentEffects). The message is keyed by the
+	// payment, so a retried sync enqueues it once.
+	HandleSupersededPaymentPaid(ctx context.Context, payment *domain.MolliePayment) error
 
 	BatchGetPaymentsByOrderIDs(ctx context.Context, orderIDs []string) (map[string][]*domain.MolliePayment, error)
+	// ClaimUnsettledPayments returns up to limit payments still waiting for a
+	// final status more than olderThan but less than maxAge after they were
+	// created, those reconciled longest ago first, and marks them reconciled.
+	ClaimUnsettledPayments(ctx context.Context, olderThan, maxAge time.Duration, limit int) ([]*domain.MolliePayment, error)
 }
 
 type paymentService struct {
-	repo           domain.PaymentRepository
-	mollieClient   mollie.Client
-	orderService   orderApplication.OrderService
-	userService    userApplication.UserService
-	productService productApplication.ProductService
+	repo          domain.PaymentRepository
+	provider      domain.PaymentProvider
+	orderService  orderApplication.OrderService
+	outboxService orderApplication.OutboxService
 }
 
 func NewPaymentService(
 	repo domain.PaymentRepository,
-	mollieClient mollie.Client,
+	provider domain.PaymentProvider,
 	orderService orderApplication.OrderService,
-	userService userApplication.UserService,
-	productService productApplication.ProductService,
+	outboxService orderApplication.OutboxService,
 ) PaymentService {
 	return &paymentService{
-		repo:           repo,
-		mollieClient:   mollieClient,
-		orderService:   orderService,
-		userService:    userService,
-		productService: productService,
+		repo:          repo,
+		provider:      provider,
+		orderService:  orderService,
+		outboxService: outboxService,
 	}
 }
 
 func (s *paymentService) CreatePayment(ctx context.Context, o orderDomain.Order, op []orderDomain.OrderProduct, u userDomain.User, a *addressDomain.Address, customRedirectURL *string) (*domain.MolliePayment, error) {
-	var lines []mollie.PaymentLines
-	serviceType := serviceTypeFromOrderType(o.OrderType)
+	lines := paymentLines(o, op)
+
+	appBaseURL := os.Getenv("APP_BASE_URL")
+	if appBaseURL == "" {
+		return nil, fmt.Errorf("APP_BASE_URL is required")
+	}
+
+	webhookURL := os.Getenv("MOLLIE_WEBHOOK_URL")
+	if webhookURL == "" {
+		return nil, fmt.Errorf("MOLLIE_WEBHOOK_URL is required")
+	}
+
+	redirectURL := appBaseURL + "/order-completed/" + o.ID.String()
+	if customRedirectURL != nil && *customRedirectURL != "" {
+		redirectURL = *customRedirectURL + "/" + o.ID.String()
+	}
+	cancelURL := appBaseURL + "/checkout"
+
+	req := domain.PaymentRequest{
+		OrderID:     o.ID,
+		Amount:      o.AmountCharged(),
+		Description: brand.Current().Name,
+		RedirectURL: redirectURL,
+		CancelURL:   cancelURL,
+		WebhookURL:  webhookURL,
+		Language:    o.Language,
+		Lines:       lines,
+	}
+
+	if o.OrderType == orderDomain.OrderTypeDelivery {
+		req.ShippingAddress = &domain.PaymentAddress{
+			GivenName:       u.FirstName,
+			FamilyName:      u.LastName,
+			StreetAndNumber: a.StreetName + " " + a.HouseNumber,
+			PostalCode:      a.Postcode,
+			City:            a.MunicipalityName,
+			Country:         "BE",
+		}
+	}
+
+	domainPayment, err := s.provider.CreatePayment(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Save(ctx, domainPayment); err != nil {
+		return nil, err
+	}
+	return domainPayment, nil
+}
+
+// paymentLines builds the payment lines of an order. They sum exactly to the
+// amount charged, o.AmountCharged().
+func paymentLines(o orderDomain.Order, op []orderDomain.OrderProduct) []domain.PaymentLine {
+	var lines []domain.PaymentLine
+	serviceType := o.OrderType.ServiceType()
 
 	for _, line := range op {
 		vatRate := line.VatRate
@@ -79,36 +157,36 @@ func (s *paymentService) CreatePayment(ctx context.Context, o orderDomain.Order,
 			vatRate = decimal.NewFromFloat(productDomain.VatCategory(line.Product.VatCategory).VatRatePercent(serviceType))
 		}
 		vatAmount := vatAmountFromGross(line.TotalPrice, vatRate)
-		lines = append(lines, mollie.PaymentLines{
-			Type:         mollie.PhysicalProductLine,
+		lines = append(lines, domain.PaymentLine{
+			Type:         domain.PaymentLinePhysical,
 			Description:  describe(line.Product),
 			Quantity:     int(line.Quantity),
 			QuantityUnit: "pcs",
-			VATRate:      vatRate.StringFixed(2),
-			UnitPrice:    amt(line.UnitPrice),
-			TotalAmount:  amt(line.TotalPrice),
-			VATAmount:    amt(vatAmount),
+			VATRate:      &vatRate,
+			UnitPrice:    line.UnitPrice,
+			TotalAmount:  line.TotalPrice,
+			VATAmount:    &vatAmount,
 		})
 	}
 
 	if o.DeliveryFee != nil && !o.DeliveryFee.IsZero() {
-		lines = append(lines, mollie.PaymentLines{
-			Type:        mollie.ShippingFeeLine,
+		lines = append(lines, domain.PaymentLine{
+			Type:        domain.PaymentLineShippingFee,
 			Description: "Frais de livraison",
 			Quantity:    1,
-			UnitPrice:   amt(*o.DeliveryFee),
-			TotalAmount: amt(*o.DeliveryFee),
+			UnitPrice:   *o.DeliveryFee,
+			TotalAmount: *o.DeliveryFee,
 		})
 	}
 
 	if o.TakeawayDiscount.GreaterThan(decimal.Zero) {
 		neg := o.TakeawayDiscount.Neg()
-		lines = append(lines, mollie.PaymentLines{
-			Type:        mollie.DiscountProductLine,
+		lines = append(lines, domain.PaymentLine{
+			Type:        domain.PaymentLineDiscount,
 			Description: "Remise à emporter",
 			Quantity:    1,
-			UnitPrice:   amt(neg),
-			TotalAmount: amt(neg),
+			UnitPrice:   neg,
+			TotalAmount: neg,
 		})
 	}
 
@@ -118,161 +196,216 @@ func (s *paymentService) CreatePayment(ctx context.Context, o orderDomain.Order,
 		if o.CouponCode != nil {
 			desc = fmt.Sprintf("Coupon %s", *o.CouponCode)
 		}
-		lines = append(lines, mollie.PaymentLines{
-			Type:        mollie.DiscountProductLine,
+		lines = append(lines, domain.PaymentLine{
+			Type:        domain.PaymentLineDiscount,
 			Description: desc,
 			Quantity:    1,
-			UnitPrice:   amt(neg),
-			TotalAmount: amt(neg),
+			UnitPrice:   neg,
+			TotalAmount: neg,
 		})
 	}
 
 	if o.TransactionFee.GreaterThan(decimal.Zero) {
-		lines = append(lines, mollie.PaymentLines{
-			Type:        mollie.SurchargeLine,
+		lines = append(lines, domain.PaymentLine{
+			Type:        domain.PaymentLineSurcharge,
 			Description: "Frais de transaction",
 			Quantity:    1,
-			UnitPrice:   amt(o.TransactionFee),
-			TotalAmount: amt(o.TransactionFee),
+			UnitPrice:   o.TransactionFee,
+			TotalAmount: o.TransactionFee,
 		})
 	}
 
-	// Mollie rejects a payment whose line totals don't sum exactly to the
+	// Providers reject a payment whose line totals don't sum exactly to the
 	// amount. TotalPrice is snapped to 10 cents (clean customer-facing total)
 	// while the lines are built from raw components, so a few cents of rounding
 	// can diverge. Absorb any delta into a correction line.
-	corr, err := roundingCorrectionLine(o.TotalPrice, lines)
-	if err != nil {
-		return nil, err
-	}
-	if corr != nil {
+	if corr := roundingCorrectionLine(o.TotalPrice, lines); corr != nil {
 		lines = append(lines, *corr)
 	}
 
-	appBaseURL := os.Getenv("APP_BASE_URL")
-	if appBaseURL == "" {
-		return nil, fmt.Errorf("APP_BASE_URL is required")
-	}
-
-	webhookURL := os.Getenv("MOLLIE_WEBHOOK_URL")
-	if webhookURL == "" {
-		return nil, fmt.Errorf("MOLLIE_WEBHOOK_URL is required")
+	// The tip goes on top of the corrected lines: it is not part of
+	// TotalPrice and is outside the scope of VAT.
+	if o.Tip.IsPositive() {
+		zero := decimal.Zero
+		lines = append(lines, domain.PaymentLine{
+			Type:        domain.PaymentLineSurcharge,
+			Description: "Pourboire",
+			Quantity:    1,
+			VATRate:     &zero,
+			UnitPrice:   o.Tip,
+			TotalAmount: o.Tip,
+			VATAmount:   &zero,
+		})
 	}
+	return lines
+}
 
-	redirectURL := appBaseURL + "/order-completed/" + o.ID.String()
-	if customRedirectURL != nil && *customRedirectURL != "" {
-		redirectURL = *customRedirectURL + "/" + o.ID.String()
+func (s *paymentService) CreateFullRefund(ctx context.Context, externalPaymentID string, reason string) (*domain.Refund, error) {
+	payment, err := s.GetPaymentByExternalID(ctx, externalPaymentID)
+	if err != nil {
+		return nil, err
 	}
-	cancelURL := appBaseURL + "/checkout"
 
-	localeMap := map[string]mollie.Locale{
-		"fr": "fr_BE",
-		"en": "en_US",
-		"nl": "nl_BE",
-		"zh": "zh_CN",
-	}
-	locale, ok := localeMap[o.Language]
-	if !ok {
-		locale = "fr_BE"
-	}
+	return s.refund(ctx, payment, func(items []domain.RefundableItem, remaining decimal.Decimal) (*domain.Refund, error) {
+		if remaining.IsZero() {
+			return nil, nil
+		}
+		req := domain.RefundRequest{Amount: &remaining, Reason: reason}
+		for _, it := range items {
+			if left := it.Quantity - it.Refunded; left > 0 {
+				req.Lines = append(req.Lines, domain.RefundLineRequest{OrderProductID: it.OrderProductID, Quantity: left})
+			}
+		}
+		return domain.PlanRefund(req, items, remaining)
+	})
+}
 
-	paymentRequest := mollie.CreatePayment{
-		Amount: &mollie.Amount{
-			Value:    o.TotalPrice.StringFixed(2),
-			Currency: "EUR",
-		},
-		Description: brand.Current().Name,
-		CancelURL:   cancelURL,
-		RedirectURL: redirectURL,
-		WebhookURL:  webhookURL,
-		Locale:      locale,
-		Lines:       lines,
+func (s *paymentService) RefundSupersededPayment(ctx context.Context, externalPaymentID string) (*domain.Refund, error) {
+	payment, err := s.GetPaymentByExternalID(ctx, externalPaymentID)
+	if err != nil {
+		return nil, err
 	}
 
-	if o.OrderType == orderDomain.OrderTypeDelivery {
-		address := &mollie.Address{
-			GivenName:       u.FirstName,
-			FamilyName:      u.LastName,
-			StreetAndNumber: a.StreetName + " " + a.HouseNumber,
-			PostalCode:      a.Postcode,
-			City:            a.MunicipalityName,
-			Country:         "BE",
+	return s.refund(ctx, payment, func(_ []domain.RefundableItem, remaining decimal.Decimal) (*domain.Refund, error) {
+		if remaining.IsZero() {
+			return nil, nil
 		}
-		paymentRequest.ShippingAddress = address
-		paymentRequest.BillingAddress = address
-	}
+		return &domain.Refund{Amount: remaining, Reason: "Duplicate payment", Superseded: true}, nil
+	})
+}
 
-	_, externalPayment, err := s.mollieClient.Payments.Create(ctx, paymentRequest, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create Mollie payment: %w", err)
+func (s *paymentService) RefundOrder(ctx context.Context, orderID uuid.UUID, req domain.RefundRequest) (*domain.Refund, error) {
+	payment, err := s.repo.FindByOrderID(ctx, orderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: this order was not paid online", domain.ErrInvalidRefund)
 	}
-
-	// Map Mollie SDK response → domain struct
-	domainPayment, err := mapExternalPayment(externalPayment, o.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to map Mollie payment: %w", err)
+		return nil, fmt.Errorf("failed to find payment: %w", err)
 	}
 
-	if err := s.repo.Save(ctx, domainPayment); err != nil {
-		return nil, err
-	}
-	return domainPayment, nil
+	return s.refund(ctx, payment, func(items []domain.RefundableItem, remaining decimal.Decimal) (*domain.Refund, error) {
+		return domain.PlanRefund(req, items, remaining)
+	})
 }
 
-func (s *paymentService) CreateFullRefund(ctx context.Context, externalPaymentID string) error {
-	payment, err := s.GetPaymentByExternalID(ctx, externalPaymentID)
-	if err != nil {
-		return fmt.Errorf("failed to find payment: %w", err)
-	}
+func (s *paymentService) GetRefundsByOrderID(ctx context.Context, orderID uuid.UUID) ([]*domain.Refund, error) {
+	return s.repo.FindRefundsByOrderID(ctx, orderID)
+}
 
-	if payment.Status != domain.PaymentStatusPaid {
-		return fmt.Errorf("payment is not paid: %s", payment.Status)
-	}
+// refund issues the refund returned by plan through the provider and records
+// it, enqueueing its side effects (see orderDomain.RefundEffects and
+// orderDomain.SupersededRefundEffects). It holds
+// the payment lock so two refunds (or a refund and a webhook) never both pass
+// the remaining-amount check, and re-reads the payment under it.
+func (s *paymentService) refund(
+	ctx context.Context,
+	payment *domain.MolliePayment,
+	plan func(items []domain.RefundableItem, remaining decimal.Decimal) (*domain.Refund, error),
+) (*domain.Refund, error) {
+	var refund *domain.Refund
+	err := s.repo.WithPaymentLock(ctx, payment.MolliePaymentID, func(ctx context.Context) error {
+		current, err := s.repo.FindByExternalID(ctx, payment.MolliePaymentID)
+		if err != nil {
+			return fmt.Errorf("failed to find payment: %w", err)
+		}
+		if current.Status != domain.PaymentStatusPaid {
+			return fmt.Errorf("%w: payment is not paid: %s", domain.ErrInvalidRefund, current.Status)
+		}
 
-	refundRequest := mollie.CreatePaymentRefund{
-		Amount: amt(payment.Amount),
-	}
+		order, orderProducts, err := s.orderService.GetOrderByID(ctx, current.OrderID)
+		if err != nil || order == nil {
+			return fmt.Errorf("failed to retrieve order: %w", err)
+		}
+		previous, err := s.repo.FindRefundsByOrderID(ctx, current.OrderID)
+		if err != nil {
+			return err
+		}
+		var items []orderDomain.OrderProductRaw
+		if orderProducts != nil {
+			items = *orderProducts
+		}
 
-	res, refund, err := s.mollieClient.Refunds.CreatePaymentRefund(ctx, externalPaymentID, refundRequest, nil)
+		refund, err = plan(refundableItems(order, items, previous), current.RemainingRefundable())
+		if err != nil || refund == nil {
+			return err
+		}
+		refund.OrderID = current.OrderID
+		refund.PaymentID = current.ID
+
+		refundID, err := s.provider.Refund(ctx, current.MolliePaymentID, refund.Amount, refund.Reason)
+		if err != nil {
+			return fmt.Errorf("failed to create refund: %w", err)
+		}
+
+		refund.ID = uuid.New()
+		refund.MollieRefundID = refundID
+		event := orderDomain.OutboxEvent{RefundAmount: &refund.Amount, RefundID: &refund.ID}
+		effects := orderDomain.RefundEffects()
+		if refund.Superseded {
+			effects = orderDomain.SupersededRefundEffects()
+		}
+		outbox := orderDomain.NewOutboxMessages(refund.OrderID, event, effects)
+		if err := s.repo.SaveRefund(ctx, refund, outbox...); err != nil {
+			// The money is already on its way back: the caller must not retry
+			// blindly, so say which provider refund went unrecorded.
+			return fmt.Errorf("refund %s issued but not recorded: %w", refundID, err)
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to create refund: %w", err)
+		return nil, err
 	}
+	return refund, nil
+}
 
-	if res.StatusCode != 200 && res.StatusCode != 201 {
-		return fmt.Errorf("failed to create refund: %s", res.Status)
+// refundableItems prices the order items for the refund planner. Order-level
+// discounts (takeaway, coupon) are spread over the items in proportion to
+// their totals, so refunding every item never exceeds what was paid for them.
+func refundableItems(o *orderDomain.Order, products []orderDomain.OrderProductRaw, previous []*domain.Refund) []domain.RefundableItem {
+	refunded := make(map[uuid.UUID]int64)
+	for _, r := range previous {
+		for _, l := range r.Lines {
+			refunded[l.OrderProductID] += l.Quantity
+		}
 	}
 
-	refundedAmount, parseErr := decimal.NewFromString(refund.Amount.Value)
-	if parseErr != nil {
-		return fmt.Errorf("failed to parse refund amount: %w", parseErr)
+	subtotal := decimal.Zero
+	for _, p := range products {
+		subtotal = subtotal.Add(p.TotalPrice)
 	}
-
-	if err := s.repo.MarkAsRefund(ctx, externalPaymentID, refundedAmount); err != nil {
-		return fmt.Errorf("failed to mark payment as refunded: %w", err)
+	ratio := decimal.NewFromInt(1)
+	if subtotal.IsPositive() {
+		discounted := subtotal.Sub(o.TakeawayDiscount).Sub(o.CouponDiscount)
+		ratio = decimal.Max(discounted, decimal.Zero).Div(subtotal)
 	}
 
-	return nil
+	items := make([]domain.RefundableItem, 0, len(products))
+	for _, p := range products {
+		unit := p.UnitPrice
+		if p.Quantity > 0 {
+			unit = p.TotalPrice.Div(decimal.NewFromInt(p.Quantity))
+		}
+		items = append(items, domain.RefundableItem{
+			OrderProductID: p.ID,
+			Quantity:       p.Quantity,
+			Refunded:       refunded[p.ID],
+			UnitPrice:      unit.Mul(ratio),
+		})
+	}
+	return items
 }
 
-// FetchMollieStatus retrieves the authoritative payment status + timestamps from
-// the Mollie API without writing to the local DB. The webhook handler persists
-// this only after the order business logic succeeds, so a failed delivery is
-// retried by Mollie and re-runs the business logic (the stored status is the
-// commit marker).
-func (s *paymentService) FetchMollieStatus(ctx context.Context, externalMolliePaymentID string) (*domain.PaymentStatusUpdate, error) {
-	_, externalPayment, err := s.mollieClient.Payments.Get(ctx, externalMolliePaymentID, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch payment from Mollie: %w", err)
-	}
+// FetchPaymentStatus retrieves the authoritative payment status + timestamps
+// from the provider without writing to the local DB. The webhook handler
+// persists this only after the order business logic succeeds, so a failed
+// delivery is retried by the provider and re-runs the business logic (the
+// stored status is the commit marker).
+func (s *paymentService) FetchPaymentStatus(ctx context.Context, externalPaymentID string) (*domain.PaymentStatusUpdate, error) {
+	return s.provider.FetchStatus(ctx, externalPaymentID)
+}
 
-	return &domain.PaymentStatusUpdate{
-		Status:       domain.PaymentStatus(externalPayment.Status),
-		PaidAt:       externalPayment.PaidAt,
-		AuthorizedAt: externalPayment.AuthorizedAt,
-		CanceledAt:   externalPayment.CanceledAt,
-		ExpiredAt:    externalPayment.ExpiredAt,
-		FailedAt:     externalPayment.FailedAt,
-	}, nil
+func (s *paymentService) ParseWebhook(r *http.Request) (string, error) {
+	return s.provider.ParseWebhook(r)
 }
 
 // PersistPaymentStatus writes the status + timestamps to the local DB.
@@ -316,230 +449,100 @@ func (s *paymentService) BatchGetPaymentsByOrderIDs(ctx context.Context, orderID
 	return s.repo.FindByOrderIDs(ctx, orderIDs)
 }
 
-// HandlePaymentPaid handles the business logic when a payment is confirmed as paid:
-// verifies amount, fetches order/products/user, sends confirmation email.
-// Returns the order so the caller can publish to PubSub (avoids circular import with resolver).
+func (s *paymentService) ClaimUnsettledPayments(ctx context.Context, olderThan, maxAge time.Duration, limit int) ([]*domain.MolliePayment, error) {
+	now := time.Now()
+	return s.repo.ClaimUnsettled(ctx, now.Add(-maxAge), now.Add(-olderThan), limit)
+}
+
+// HandlePaymentPaid handles the business logic when a payment is confirmed as
+// paid: verifies the amount and enqueues the side effects of the payment (see
+// orderDomain.PaidOrderEffects), which refund it if the order was cancelled
+// meanwhile. The messages are keyed by the payment, so a
+// retried sync enqueues nothing twice. Returns the order so the caller can
+// publish to PubSub (avoids circular import with resolver).
 func (s *paymentService) HandlePaymentPaid(ctx context.Context, orderID uuid.UUID) (*orderDomain.Order, error) {
-	order, orderProducts, err := s.orderService.GetOrderByID(ctx, orderID)
+	order, _, err := s.orderService.GetOrderByID(ctx, orderID)
 	if err != nil || order == nil {
 		return nil, fmt.Errorf("failed to retrieve order: %w", err)
 	}
-	if orderProducts == nil {
-		return nil, fmt.Errorf("no order products found for order %s", orderID)
-	}
 
+	payment, err := s.repo.FindByOrderID(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve payment: %w", err)
+	}
 	// Amount verification: log mismatch for manual review, don't block the order
-	payment, paymentErr := s.repo.FindByOrderID(ctx, orderID)
-	if paymentErr == nil && payment != nil && !payment.Amount.Equal(order.TotalPrice) {
+	if !payment.Amount.Equal(order.AmountCharged()) {
 		zap.L().Error("payment amount mismatch",
 			zap.String("order_id", orderID.String()),
 			zap.String("paid", payment.Amount.String()),
-			zap.String("expected", order.TotalPrice.String()),
+			zap.String("expected", order.AmountCharged().String()),
 		)
 	}
 
-	// Load product details
-	productIDs := make([]string, len(*orderProducts))
-	for i, op := range *orderProducts {
-		productIDs[i] = op.ProductID.String()
-	}
-
-	products, err := s.productService.GetProductsByIDs(ctx, productIDs)
-	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve products: %w", err)
-	}
-
-	productMap := make(map[uuid.UUID]productDomain.ProductOrderDetails, len(products))
-	for _, p := range products {
-		productMap[p.ID] = *p
-	}
-
-	orderProductsResponse := make([]orderDomain.OrderProduct, len(*orderProducts))
-	for i, op := range *orderProducts {
-		prod, ok := productMap[op.ProductID]
-		if !ok {
-			return nil, fmt.Errorf("product %s not found", op.ProductID)
-		}
-		orderProductsResponse[i] = orderDomain.OrderProduct{
-			Product: orderDomain.Product{
-				ID:           prod.ID,
-				Code:         prod.Code,
-				CategoryName: prod.CategoryName,
-				Name:         prod.Name,
-				VatCategory:  string(prod.VatCategory),
-			},
-			Quantity:   op.Quantity,
-			UnitPrice:  op.UnitPrice,
-			TotalPrice: op.TotalPrice,
-			VatRate:    op.VatRateApplied,
-		}
+	event := orderDomain.OutboxEvent{Status: order.OrderStatus, PreviousStatus: order.OrderStatus, PaymentID: payment.MolliePaymentID}
+	msgs := orderDomain.NewKeyedOutboxMessages(orderID, payment.ID.String(), event, orderDomain.PaidOrderEffects(order))
+	if err := s.outboxService.Enqueue(ctx, msgs...); err != nil {
+		return nil, fmt.Errorf("failed to enqueue paid order effects: %w", err)
 	}
-
-	u, err := s.userService.GetUserByID(ctx, order.UserID.String())
-	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve user: %w", err)
-	}
-
-	// Send confirmation email (respect user's order-updates preference)
-	if u.NotifyOrderUpdates {
-		if emailErr := es.SendOrderPendingEmail(*u, order.Language, *order, orderProductsResponse); emailErr != nil {
-			zap.L().Error("failed to send order pending email", zap.String("order_id", orderID.String()), zap.Error(emailErr))
-		}
-	}
-
 	return order, nil
 }
 
-// HandlePaymentFailed handles the business logic when a payment is cancelled/failed/expired:
-// updates order status to CANCELLED. Coupon usage rollback is handled centrally by
-// OrderService.UpdateOrder on the transition into CANCELED (covering cash/admin/POS
-// cancellations too), so it is not repeated here. No email is sent — users frequently
-// retry the checkout in a fresh order, and a failure notification on the abandoned
-// attempt would contradict the successful retry.
-func (s *paymentService) HandlePaymentFailed(ctx context.Context, orderID uuid.UUID) (*orderDomain.Order, error) {
-	canceledStatus := orderDomain.OrderStatusCanceled
-	if err := s.orderService.UpdateOrder(ctx, orderID, &canceledStatus, nil, nil); err != nil {
-		return nil, fmt.Errorf("failed to update order status: %w", err)
-	}
-
-	// Fetch the updated order for PubSub notification
-	order, _, orderErr := s.orderService.GetOrderByID(ctx, orderID)
-	if orderErr != nil || order == nil {
-		zap.L().Error("failed to retrieve order after payment failure", zap.String("order_id", orderID.String()), zap.Error(orderErr))
-		return nil, nil
+func (s *paymentService) HandleSupersededPaymentPaid(ctx context.Context, payment *domain.MolliePayment) error {
+	event := orderDomain.OutboxEvent{PaymentID: payment.MolliePaymentID}
+	msgs := orderDomain.NewKeyedOutboxMessages(payment.OrderID, payment.ID.String(), event, orderDomain.SupersededPaymentEffects())
+	if err := s.outboxService.Enqueue(ctx, msgs...); err != nil {
+		return fmt.Errorf("failed to enqueue superseded payment refund: %w", err)
 	}
-
-	return order, nil
+	return nil
 }
 
-// mapExternalPayment converts a Mollie SDK payment object to the domain struct.
-func mapExternalPayment(external *mollie.Payment, orderID uuid.UUID) (*domain.MolliePayment, error) {
-	amount, err := decimal.NewFromString(external.Amount.Value)
-	if err != nil {
-		return nil, fmt.Errorf("failed to convert amount: %w", err)
-	}
-
-	amountRefunded := decimal.Zero
-	if external.AmountRefunded != nil {
-		amountRefunded, err = decimal.NewFromString(external.AmountRefunded.Value)
-		if err != nil {
-			return nil, fmt.Errorf("failed to convert amountRefunded: %w", err)
-		}
-	}
-
-	amountRemaining := decimal.Zero
-	if external.AmountRemaining != nil {
-		amountRemaining, err = decimal.NewFromString(external.AmountRemaining.Value)
-		if err != nil {
-			return nil, fmt.Errorf("failed to convert amountRemaining: %w", err)
-		}
-	}
-
-	amountCaptured := decimal.Zero
-	if external.AmountCaptured != nil {
-		amountCaptured, err = decimal.NewFromString(external.AmountCaptured.Value)
-		if err != nil {
-			return nil, fmt.Errorf("failed to convert amountCaptured: %w", err)
-		}
-	}
-
-	amountChargedBack := decimal.Zero
-	if external.AmountChargedBack != nil {
-		amountChargedBack, err = decimal.NewFromString(external.AmountChargedBack.Value)
-		if err != nil {
-			return nil, fmt.Errorf("failed to convert amountChargedBack: %w", err)
-		}
-	}
-
-	settlementAmount := decimal.Zero
-	if external.SettlementAmount != nil {
-		settlementAmount, err = decimal.NewFromString(external.SettlementAmount.Value)
-		if err != nil {
-			return nil, fmt.Errorf("failed to convert settlementAmount: %w", err)
-		}
-	}
-
-	var metadataJSON string
-	if external.Metadata != nil {
-		raw, marshalErr := json.Marshal(external.Metadata)
-		if marshalErr != nil {
-			return nil, fmt.Errorf("failed to marshal metadata: %w", marshalErr)
+// HandlePaymentFailed handles the business logic when a payment is cancelled/failed/expired:
+// updates order status to CANCELLED as PAYMENT_FAILED, which lets the customer retry
+// the payment for a while (see orderDomain.CheckPaymentRetry). Coupon usage rollback
+// is handled centrally by the order service on the transition into CANCELED
+// (covering cash/admin/POS cancellations too), so it is not repeated here. The
+// subscription event and webhook of the cancellation are enqueued with it (see
+// orderDomain.PaymentFailedEffects), keyed by the payment. No email is
+// sent — users frequently retry the payment or the checkout, and a failure
+// notification on the abandoned attempt would contradict the successful retry.
+func (s *paymentService) HandlePaymentFailed(ctx context.Context, payment *domain.MolliePayment) error {
+	if err := s.orderService.CancelForFailedPayment(ctx, payment.OrderID, payment.ID.String()+"/failed"); err != nil {
+		// An order that already reached a terminal status (e.g. staff completed
+		// it) is left alone; failing here would only make Mollie retry forever.
+		if !errors.Is(err, orderDomain.ErrOrderStatusTerminal) {
+			return fmt.Errorf("failed to update order status: %w", err)
 		}
-		metadataJSON = string(raw)
-	} else {
-		metadataJSON = "null"
+		zap.L().Warn("payment failed on an already terminal order, keeping its status",
+			zap.String("order_id", payment.OrderID.String()), zap.Error(err))
+		return nil
 	}
-
-	linksRaw, err := json.Marshal(external.Links)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal links: %w", err)
-	}
-
-	return &domain.MolliePayment{
-		Resource:                        &external.Resource,
-		MolliePaymentID:                 external.ID,
-		Status:                          domain.PaymentStatus(external.Status),
-		Description:                     &external.Description,
-		CancelURL:                       &external.CancelURL,
-		WebhookURL:                      &external.WebhookURL,
-		CountryCode:                     &external.CountryCode,
-		RestrictPaymentMethodsToCountry: &external.RestrictPaymentMethodsToCountry,
-		ProfileID:                       &external.ProfileID,
-		SettlementID:                    &external.SettlementID,
-		OrderID:                         orderID,
-		IsCancelable:                    external.IsCancelable,
-		Metadata:                        []byte(metadataJSON),
-		Links:                           []byte(linksRaw),
-		CreatedAt:                       *external.CreatedAt,
-		AuthorizedAt:                    external.AuthorizedAt,
-		PaidAt:                          external.PaidAt,
-		CanceledAt:                      external.CanceledAt,
-		ExpiresAt:                       external.ExpiresAt,
-		ExpiredAt:                       external.ExpiredAt,
-		FailedAt:                        external.FailedAt,
-		Amount:                          amount,
-		AmountRefunded:                  amountRefunded,
-		AmountRemaining:                 amountRemaining,
-		AmountCaptured:                  amountCaptured,
-		AmountChargedBack:               amountChargedBack,
-		SettlementAmount:                settlementAmount,
-	}, nil
+	s.outboxService.Notify()
+	return nil
 }
 
-// roundingCorrectionLine returns a Mollie line that absorbs any gap between the
-// charged total and the sum of the existing line totals (as Mollie sees them,
-// i.e. StringFixed(2)), or nil when they already match. Mollie rejects a
-// payment whose line totals don't sum exactly to the amount.
-func roundingCorrectionLine(total decimal.Decimal, lines []mollie.PaymentLines) (*mollie.PaymentLines, error) {
+// roundingCorrectionLine returns a line that absorbs any gap between the
+// charged total and the sum of the existing line totals (as providers see
+// them, i.e. rounded to the cent), or nil when they already match. Providers
+// reject a payment whose line totals don't sum exactly to the amount.
+func roundingCorrectionLine(total decimal.Decimal, lines []domain.PaymentLine) *domain.PaymentLine {
 	var sum decimal.Decimal
 	for _, l := range lines {
-		v, err := decimal.NewFromString(l.TotalAmount.Value)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse line amount %q: %w", l.TotalAmount.Value, err)
-		}
-		sum = sum.Add(v)
+		sum = sum.Add(l.TotalAmount.Round(2))
 	}
 	diff := total.Sub(sum)
 	if diff.IsZero() {
-		return nil, nil
+		return nil
 	}
-	lineType := mollie.SurchargeLine
+	lineType := domain.PaymentLineSurcharge
 	if diff.IsNegative() {
-		lineType = mollie.DiscountProductLine
+		lineType = domain.PaymentLineDiscount
 	}
-	return &mollie.PaymentLines{
+	return &domain.PaymentLine{
 		Type:        lineType,
 		Description: "Ajustement",
 		Quantity:    1,
-		UnitPrice:   amt(diff),
-		TotalAmount: amt(diff),
-	}, nil
-}
-
-func amt(d decimal.Decimal) *mollie.Amount {
-	return &mollie.Amount{
-		Value:    d.StringFixed(2),
-		Currency: "EUR",
+		UnitPrice:   diff,
+		TotalAmount: diff,
 	}
 }
 
@@ -550,13 +553,6 @@ func describe(p orderDomain.Product) string {
 	return fmt.Sprintf("%s %s", p.CategoryName, p.Name)
 }
 
-func serviceTypeFromOrderType(orderType orderDomain.OrderType) productDomain.ServiceType {
-	if orderType == orderDomain.OrderTypeDelivery {
-		return productDomain.ServiceTypeDelivery
-	}
-	return productDomain.ServiceTypeTakeaway
-}
-
 func vatAmountFromGross(gross decimal.Decimal, rate decimal.Decimal) decimal.Decimal {
 	if rate.IsZero() {
 		return decimal.Zero
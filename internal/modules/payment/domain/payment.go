@@ -63,6 +63,8 @@ type MolliePayment struct {
 	AmountCaptured                  decimal.Decimal `db:"amount_captured" json:"amountCaptured"`
 	AmountChargedBack               decimal.Decimal `db:"amount_charged_back" json:"amountChargedBack"`
 	SettlementAmount                decimal.Decimal `db:"settlement_amount" json:"settlementAmount"`
+	// LastReconciledAt is when the reconciler last synced the payment.
+	LastReconciledAt *time.Time `db:"last_reconciled_at" json:"lastReconciledAt,omitempty"`
 }
 
 type PaymentLinks struct {
@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// ErrInvalidWebhook is returned by PaymentProvider.ParseWebhook for a request
+// that is not a webhook of the provider.
+var ErrInvalidWebhook = errors.New("invalid payment webhook")
+
+// PaymentProvider is the payment service provider online orders are paid
+// through: Mollie in production, an in-process fake in local development and
+// tests.
+type PaymentProvider interface {
+	// CreatePayment opens a payment for req and returns it ready to be saved,
+	// with the checkout link the customer pays on in Links.
+	CreatePayment(ctx context.Context, req PaymentRequest) (*MolliePayment, error)
+	// FetchStatus returns the provider's authoritative status of a payment.
+	FetchStatus(ctx context.Context, externalPaymentID string) (*PaymentStatusUpdate, error)
+	// Refund refunds amount of a paid payment and returns the provider's ID
+	// of the refund.
+	Refund(ctx context.Context, externalPaymentID string, amount decimal.Decimal, description string) (string, error)
+	// ParseWebhook returns the ID of the payment a webhook request is about.
+	// The request carries no status: callers fetch it with FetchStatus.
+	ParseWebhook(r *http.Request) (string, error)
+}
+
+// PaymentRequest is a payment to open for an order. Lines sum exactly to
+// Amount.
+type PaymentRequest struct {
+	OrderID     uuid.UUID
+	Amount      decimal.Decimal
+	Description string
+	RedirectURL string
+	CancelURL   string
+	WebhookURL  string
+	// Language is the order's language, used for the checkout page.
+	Language        string
+	Lines           []PaymentLine
+	ShippingAddress *PaymentAddress
+}
+
+// PaymentLineType is the kind of a payment line.
+type PaymentLineType string
+
+const (
+	PaymentLinePhysical    PaymentLineType = "physical"
+	PaymentLineShippingFee PaymentLineType = "shipping_fee"
+	PaymentLineDiscount    PaymentLineType = "discount"
+	PaymentLineSurcharge   PaymentLineType = "surcharge"
+)
+
+// PaymentLine is one line of a payment. Providers charge amounts rounded to
+// the cent. VATRate and VATAmount are set only when known.
+type PaymentLine struct {
+	Type         PaymentLineType
+	Description  string
+	Quantity     int
+	QuantityUnit string
+	VATRate      *decimal.Decimal
+	UnitPrice    decimal.Decimal
+	TotalAmount  decimal.Decimal
+	VATAmount    *decimal.Decimal
+}
+
+// PaymentAddress is the shipping and billing address of a delivery order.
+type PaymentAddress struct {
+	GivenName       string
+	FamilyName      string
+	StreetAndNumber string
+	PostalCode      string
+	City            string
+	Country         string
+}
@@ -0,0 +1,126 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// ErrInvalidRefund wraps every reason a refund request is rejected before it
+// reaches Mollie; the message is meant for the staff member who sent it.
+var ErrInvalidRefund = errors.New("invalid refund")
+
+// Refund is one Mollie refund issued against an order's payment. A superseded
+// refund returns a payment attempt the order no longer counts on (the customer
+// paid twice) rather than part of the order.
+type Refund struct {
+	ID             uuid.UUID       `db:"id" json:"id"`
+	OrderID        uuid.UUID       `db:"order_id" json:"orderId"`
+	PaymentID      uuid.UUID       `db:"payment_id" json:"paymentId"`
+	MollieRefundID string          `db:"mollie_refund_id" json:"mollieRefundId"`
+	Amount         decimal.Decimal `db:"amount" json:"amount"`
+	Reason         string          `db:"reason" json:"reason"`
+	Superseded     bool            `db:"superseded" json:"superseded"`
+	CreatedAt      time.Time       `db:"created_at" json:"createdAt"`
+	Lines          []RefundLine    `db:"-" json:"lines,omitempty"`
+}
+
+// RefundLine is the part of a refund that covers one order item.
+type RefundLine struct {
+	RefundID       uuid.UUID       `db:"refund_id" json:"refundId"`
+	OrderProductID uuid.UUID       `db:"order_product_id" json:"orderProductId"`
+	Quantity       int64           `db:"quantity" json:"quantity"`
+	Amount         decimal.Decimal `db:"amount" json:"amount"`
+}
+
+// RefundRequest is what staff ask to refund. Lines name the order items being
+// refunded; Amount, when set, overrides the amount computed from the lines
+// (e.g. a goodwill gesture, or a refund that isn't tied to any item).
+type RefundRequest struct {
+	Lines  []RefundLineRequest
+	Amount *decimal.Decimal
+	Reason string
+}
+
+type RefundLineRequest struct {
+	OrderProductID uuid.UUID
+	Quantity       int64
+}
+
+// RefundableItem is an order item as seen by the refund planner. UnitPrice is
+// what the customer actually paid per unit, i.e. after order-level discounts.
+type RefundableItem struct {
+	OrderProductID uuid.UUID
+	Quantity       int64
+	Refunded       int64
+	UnitPrice      decimal.Decimal
+}
+
+// PlanRefund validates req against the order items and the amount still
+// refundable on the payment, and returns the refund to issue.
+func PlanRefund(req RefundRequest, items []RefundableItem, remaining decimal.Decimal) (*Refund, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: a reason is required", ErrInvalidRefund)
+	}
+	if len(req.Lines) == 0 && req.Amount == nil {
+		return nil, fmt.Errorf("%w: select the items to refund or enter an amount", ErrInvalidRefund)
+	}
+
+	byID := make(map[uuid.UUID]RefundableItem, len(items))
+	for _, it := range items {
+		byID[it.OrderProductID] = it
+	}
+
+	refund := &Refund{Reason: reason}
+	seen := make(map[uuid.UUID]bool, len(req.Lines))
+	linesTotal := decimal.Zero
+	for _, l := range req.Lines {
+		it, ok := byID[l.OrderProductID]
+		if !ok {
+			return nil, fmt.Errorf("%w: item %s is not part of this order", ErrInvalidRefund, l.OrderProductID)
+		}
+		if seen[l.OrderProductID] {
+			return nil, fmt.Errorf("%w: item %s is listed twice", ErrInvalidRefund, l.OrderProductID)
+		}
+		seen[l.OrderProductID] = true
+		if l.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: quantity must be positive", ErrInvalidRefund)
+		}
+		if left := it.Quantity - it.Refunded; l.Quantity > left {
+			return nil, fmt.Errorf("%w: only %d of item %s can still be refunded", ErrInvalidRefund, left, l.OrderProductID)
+		}
+		amount := it.UnitPrice.Mul(decimal.NewFromInt(l.Quantity)).Round(2)
+		linesTotal = linesTotal.Add(amount)
+		refund.Lines = append(refund.Lines, RefundLine{
+			OrderProductID: l.OrderProductID,
+			Quantity:       l.Quantity,
+			Amount:         amount,
+		})
+	}
+
+	refund.Amount = linesTotal
+	if req.Amount != nil {
+		refund.Amount = req.Amount.Round(2)
+	}
+	if !refund.Amount.IsPositive() {
+		return nil, fmt.Errorf("%w: the refund amount must be positive", ErrInvalidRefund)
+	}
+	if refund.Amount.GreaterThan(remaining) {
+		return nil, fmt.Errorf("%w: only %s EUR can still be refunded", ErrInvalidRefund, remaining.StringFixed(2))
+	}
+	return refund, nil
+}
+
+// RemainingRefundable is the part of the payment that hasn't been refunded yet.
+func (p *MolliePayment) RemainingRefundable() decimal.Decimal {
+	remaining := p.Amount.Sub(p.AmountRefunded)
+	if remaining.IsNegative() {
+		return decimal.Zero
+	}
+	return remaining
+}
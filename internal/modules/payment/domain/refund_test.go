@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestPlanRefund(t *testing.T) {
+	maki, ramen := uuid.New(), uuid.New()
+	items := []RefundableItem{
+		{OrderProductID: maki, Quantity: 3, Refunded: 1, UnitPrice: decimal.RequireFromString("4.50")},
+		{OrderProductID: ramen, Quantity: 1, UnitPrice: decimal.RequireFromString("13.95")},
+	}
+	remaining := decimal.RequireFromString("20.00")
+	amount := func(s string) *decimal.Decimal {
+		d := decimal.RequireFromString(s)
+		return &d
+	}
+
+	t.Run("prices the lines", func(t *testing.T) {
+		refund, err := PlanRefund(RefundRequest{
+			Lines:  []RefundLineRequest{{OrderProductID: maki, Quantity: 2}},
+			Reason: " out of stock ",
+		}, items, remaining)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !refund.Amount.Equal(decimal.RequireFromString("9")) || refund.Reason != "out of stock" {
+			t.Fatalf("refund = %s %q, want 9.00 \"out of stock\"", refund.Amount, refund.Reason)
+		}
+		if len(refund.Lines) != 1 || refund.Lines[0].Quantity != 2 || !refund.Lines[0].Amount.Equal(refund.Amount) {
+			t.Fatalf("lines = %+v", refund.Lines)
+		}
+	})
+
+	t.Run("amount overrides the line prices", func(t *testing.T) {
+		refund, err := PlanRefund(RefundRequest{
+			Lines:  []RefundLineRequest{{OrderProductID: ramen, Quantity: 1}},
+			Amount: amount("5.004"),
+			Reason: "cold soup",
+		}, items, remaining)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if refund.Amount.StringFixed(2) != "5.00" || len(refund.Lines) != 1 {
+			t.Fatalf("refund = %s with %d lines, want 5.00 with 1 line", refund.Amount, len(refund.Lines))
+		}
+	})
+
+	rejected := map[string]RefundRequest{
+		"no reason":             {Amount: amount("1"), Reason: "  "},
+		"nothing to refund":     {Reason: "x"},
+		"unknown item":          {Lines: []RefundLineRequest{{OrderProductID: uuid.New(), Quantity: 1}}, Reason: "x"},
+		"already refunded":      {Lines: []RefundLineRequest{{OrderProductID: maki, Quantity: 3}}, Reason: "x"},
+		"item listed twice":     {Lines: []RefundLineRequest{{OrderProductID: ramen, Quantity: 1}, {OrderProductID: ramen, Quantity: 1}}, Reason: "x"},
+		"zero quantity":         {Lines: []RefundLineRequest{{OrderProductID: ramen}}, Reason: "x"},
+		"more than remaining":   {Amount: amount("20.01"), Reason: "x"},
+		"lines above remaining": {Lines: []RefundLineRequest{{OrderProductID: maki, Quantity: 2}, {OrderProductID: ramen, Quantity: 1}}, Reason: "x"},
+		"negative amount":       {Amount: amount("-2"), Reason: "x"},
+	}
+	for name, req := range rejected {
+		t.Run(name, func(t *testing.T) {
+			if _, err := PlanRefund(req, items, remaining); !errors.Is(err, ErrInvalidRefund) {
+				t.Fatalf("err = %v, want ErrInvalidRefund", err)
+			}
+		})
+	}
+}
+
+func TestRemainingRefundable(t *testing.T) {
+	p := &MolliePayment{Amount: decimal.RequireFromString("30"), AmountRefunded: decimal.RequireFromString("12.5")}
+	if got := p.RemainingRefundable(); !got.Equal(decimal.RequireFromString("17.5")) {
+		t.Fatalf("RemainingRefundable = %s, want 17.5", got)
+	}
+	p.AmountRefunded = decimal.RequireFromString("31")
+	if got := p.RemainingRefundable(); !got.IsZero() {
+		t.Fatalf("RemainingRefundable = %s, want 0", got)
+	}
+}
@@ -2,20 +2,37 @@ package domain
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/shopspring/decimal"
+
+	orderDomain "tsb-service/internal/modules/order/domain"
 )
 
 type PaymentRepository interface {
 	Save(ctx context.Context, payment *MolliePayment) error
-	MarkAsRefund(ctx context.Context, externalPaymentID string, refundedAmount decimal.Decimal) error
+	// SaveRefund records a refund issued by Mollie, with its lines, and adds
+	// its amount to the payment's amount_refunded and its side effects to the
+	// order outbox in the same transaction. refund.ID is set by the caller.
+	SaveRefund(ctx context.Context, refund *Refund, outbox ...*orderDomain.OutboxMessage) error
+	// FindRefundsByOrderID returns the order's refunds, oldest first, with
+	// lines. Superseded payment refunds are left out.
+	FindRefundsByOrderID(ctx context.Context, orderID uuid.UUID) ([]*Refund, error)
 	RefreshStatus(ctx context.Context, externalPaymentID string, update *PaymentStatusUpdate) (*uuid.UUID, error)
+	// An order gets a new payment each time its customer retries a failed
+	// one; the ByOrderID methods act on the latest attempt.
 	UpdateStatusByOrderID(ctx context.Context, orderID uuid.UUID, status PaymentStatus) (*MolliePayment, error)
 	FindByOrderID(ctx context.Context, orderID uuid.UUID) (*MolliePayment, error)
 	FindByExternalID(ctx context.Context, externalPaymentID string) (*MolliePayment, error)
 
+	// FindByOrderIDs returns each order's payments, latest attempt first.
 	FindByOrderIDs(ctx context.Context, orderIDs []string) (map[string][]*MolliePayment, error)
+	// ClaimUnsettled stamps LastReconciledAt on up to limit payments still
+	// open, pending or authorized that were created between createdAfter and
+	// createdBefore, and returns them. Payments never reconciled come first,
+	// then those reconciled longest ago, so a backlog larger than limit is
+	// worked through in turn.
+	ClaimUnsettled(ctx context.Context, createdAfter, createdBefore time.Time, limit int) ([]*MolliePayment, error)
 
 	// WithPaymentLock runs fn while holding a cross-process advisory lock keyed on
 	// the payment ID, serializing concurrent webhook deliveries for the same
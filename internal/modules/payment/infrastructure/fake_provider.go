@@ -0,0 +1,259 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"tsb-service/internal/modules/payment/domain"
+)
+
+// fakePaymentPrefix marks the IDs of fake payments.
+const fakePaymentPrefix = "fake_tr_"
+
+// FakeProvider is an in-process payment provider for local development and
+// integration tests. The checkout link of each payment opens a page served by
+// the provider itself with "pay", "fail" and "expire" buttons: choosing one
+// settles the payment, calls its webhook as Mollie would, then sends the
+// customer to the redirect URL. Payments live in memory only.
+type FakeProvider struct {
+	checkoutURL string
+	client      *http.Client
+
+	mu       sync.Mutex
+	payments map[string]*fakePayment
+}
+
+type fakePayment struct {
+	payment     domain.MolliePayment
+	lines       []domain.PaymentLine
+	redirectURL string
+}
+
+// NewFakeProvider returns a fake provider whose checkout page is served, by
+// ServeHTTP, under checkoutURL: the page of a payment is checkoutURL/<id>.
+func NewFakeProvider(checkoutURL string, client *http.Client) *FakeProvider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &FakeProvider{
+		checkoutURL: strings.TrimSuffix(checkoutURL, "/"),
+		client:      client,
+		payments:    make(map[string]*fakePayment),
+	}
+}
+
+func (p *FakeProvider) CreatePayment(_ context.Context, req domain.PaymentRequest) (*domain.MolliePayment, error) {
+	id := fakePaymentPrefix + strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+
+	var links domain.PaymentLinks
+	links.Checkout.Href = p.checkoutURL + "/" + id
+	links.Checkout.Type = "text/html"
+	linksRaw, err := json.Marshal(links)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal links: %w", err)
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(15 * time.Minute)
+	resource, mode := "payment", "test"
+	payment := domain.MolliePayment{
+		Resource:        &resource,
+		MolliePaymentID: id,
+		Status:          domain.PaymentStatusOpen,
+		Description:     &req.Description,
+		CancelURL:       &req.CancelURL,
+		WebhookURL:      &req.WebhookURL,
+		OrderID:         req.OrderID,
+		IsCancelable:    true,
+		Mode:            &mode,
+		Locale:          &req.Language,
+		Metadata:        json.RawMessage("null"),
+		Links:           linksRaw,
+		CreatedAt:       now,
+		ExpiresAt:       &expiresAt,
+		Amount:          req.Amount.Round(2),
+	}
+
+	p.mu.Lock()
+	p.payments[id] = &fakePayment{payment: payment, lines: req.Lines, redirectURL: req.RedirectURL}
+	p.mu.Unlock()
+	return &payment, nil
+}
+
+func (p *FakeProvider) FetchStatus(_ context.Context, externalPaymentID string) (*domain.PaymentStatusUpdate, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fp, ok := p.payments[externalPaymentID]
+	if !ok {
+		return nil, fmt.Errorf("fake payment %s not found", externalPaymentID)
+	}
+	return &domain.PaymentStatusUpdate{
+		Status:     fp.payment.Status,
+		PaidAt:     fp.payment.PaidAt,
+		CanceledAt: fp.payment.CanceledAt,
+		ExpiredAt:  fp.payment.ExpiredAt,
+		FailedAt:   fp.payment.FailedAt,
+	}, nil
+}
+
+func (p *FakeProvider) Refund(_ context.Context, externalPaymentID string, amount decimal.Decimal, _ string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fp, ok := p.payments[externalPaymentID]
+	if !ok {
+		return "", fmt.Errorf("fake payment %s not found", externalPaymentID)
+	}
+	if fp.payment.Status != domain.PaymentStatusPaid {
+		return "", fmt.Errorf("fake payment %s is %s, not paid", externalPaymentID, fp.payment.Status)
+	}
+	remaining := fp.payment.Amount.Sub(fp.payment.AmountRefunded)
+	if amount.Round(2).GreaterThan(remaining) {
+		return "", fmt.Errorf("refund of %s exceeds the %s left on fake payment %s", amount.StringFixed(2), remaining.StringFixed(2), externalPaymentID)
+	}
+	fp.payment.AmountRefunded = fp.payment.AmountRefunded.Add(amount.Round(2))
+	return "fake_re_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:12], nil
+}
+
+// ParseWebhook reads the payment ID from the "id" form field, as Mollie
+// posts it.
+func (p *FakeProvider) ParseWebhook(r *http.Request) (string, error) {
+	id := r.PostFormValue("id")
+	if !strings.HasPrefix(id, fakePaymentPrefix) {
+		return "", fmt.Errorf("%w: not a fake payment id: %q", domain.ErrInvalidWebhook, id)
+	}
+	return id, nil
+}
+
+// fakeOutcomes are the checkout page actions and the status each settles the
+// payment in.
+var fakeOutcomes = map[string]domain.PaymentStatus{
+	"pay":    domain.PaymentStatusPaid,
+	"fail":   domain.PaymentStatusFailed,
+	"expire": domain.PaymentStatusExpired,
+}
+
+var fakeCheckoutPage = template.Must(template.New("checkout").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>Fake checkout</title></head>
+<body style="font-family: sans-serif; max-width: 32em; margin: 2em auto">
+<h1>Fake checkout</h1>
+<p>{{.Description}} &mdash; payment <code>{{.ID}}</code> ({{.Status}})</p>
+<table>
+{{range .Lines}}<tr><td>{{.Quantity}} &times; {{.Description}}</td><td style="text-align: right">{{.TotalAmount.StringFixed 2}}</td></tr>
+{{end}}<tr><th style="text-align: left">Total</th><th style="text-align: right">&euro; {{.Amount}}</th></tr>
+</table>
+{{if .Open}}<form method="post">
+<button name="action" value="pay">Pay</button>
+<button name="action" value="fail">Fail</button>
+<button name="action" value="expire">Expire</button>
+</form>{{end}}
+</body>
+</html>
+`))
+
+// ServeHTTP serves the checkout page of the payment named by the last path
+// segment. GET shows it; POST settles the payment with the chosen action,
+// calls the webhook and redirects the customer.
+func (p *FakeProvider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := path.Base(r.URL.Path)
+	switch r.Method {
+	case http.MethodGet:
+		p.mu.Lock()
+		fp, ok := p.payments[id]
+		var data map[string]any
+		if ok {
+			data = map[string]any{
+				"ID":          id,
+				"Description": *fp.payment.Description,
+				"Status":      fp.payment.Status,
+				"Open":        fp.payment.Status == domain.PaymentStatusOpen,
+				"Lines":       fp.lines,
+				"Amount":      fp.payment.Amount.StringFixed(2),
+			}
+		}
+		p.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := fakeCheckoutPage.Execute(w, data); err != nil {
+			zap.L().Warn("fake checkout: failed to render page", zap.String("payment_id", id), zap.Error(err))
+		}
+	case http.MethodPost:
+		status, ok := fakeOutcomes[r.PostFormValue("action")]
+		if !ok {
+			http.Error(w, "unknown action", http.StatusBadRequest)
+			return
+		}
+		webhookURL, redirectURL, err := p.settle(id, status)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		// A failed call is left to the reconciler, as with a lost Mollie
+		// webhook.
+		if err := p.callWebhook(r.Context(), webhookURL, id); err != nil {
+			zap.L().Warn("fake checkout: webhook call failed", zap.String("payment_id", id), zap.Error(err))
+		}
+		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// settle moves an open payment to status and returns its webhook and
+// redirect URLs.
+func (p *FakeProvider) settle(id string, status domain.PaymentStatus) (string, string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fp, ok := p.payments[id]
+	if !ok {
+		return "", "", fmt.Errorf("fake payment %s not found", id)
+	}
+	if fp.payment.Status != domain.PaymentStatusOpen {
+		return "", "", fmt.Errorf("fake payment %s is already %s", id, fp.payment.Status)
+	}
+	now := time.Now()
+	fp.payment.Status = status
+	switch status {
+	case domain.PaymentStatusPaid:
+		fp.payment.PaidAt = &now
+	case domain.PaymentStatusFailed:
+		fp.payment.FailedAt = &now
+	case domain.PaymentStatusExpired:
+		fp.payment.ExpiredAt = &now
+	}
+	return *fp.payment.WebhookURL, fp.redirectURL, nil
+}
+
+func (p *FakeProvider) callWebhook(ctx context.Context, webhookURL, id string) error {
+	body := url.Values{"id": {id}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"tsb-service/internal/modules/payment/domain"
+)
+
+func TestFakeProviderCheckoutFlow(t *testing.T) {
+	var webhookIDs []string
+	var provider *FakeProvider
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := provider.ParseWebhook(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		webhookIDs = append(webhookIDs, id)
+	}))
+	defer webhook.Close()
+
+	mux := http.NewServeMux()
+	checkout := httptest.NewServer(mux)
+	defer checkout.Close()
+	provider = NewFakeProvider(checkout.URL+"/pay", webhook.Client())
+	mux.Handle("/pay/", provider)
+
+	ctx := context.Background()
+	p, err := provider.CreatePayment(ctx, domain.PaymentRequest{
+		OrderID:     uuid.New(),
+		Amount:      decimal.RequireFromString("24.50"),
+		Description: "Commande test",
+		RedirectURL: "https://shop.example/order-completed",
+		WebhookURL:  webhook.URL,
+		Lines:       []domain.PaymentLine{{Description: "Maki", Quantity: 2, TotalAmount: decimal.RequireFromString("24.50")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var links domain.PaymentLinks
+	if err := json.Unmarshal(p.Links, &links); err != nil {
+		t.Fatal(err)
+	}
+	if links.Checkout.Href != checkout.URL+"/pay/"+p.MolliePaymentID {
+		t.Fatalf("checkout link = %q", links.Checkout.Href)
+	}
+
+	resp, err := http.Get(links.Checkout.Href)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(page), `value="pay"`) || !strings.Contains(string(page), "Maki") {
+		t.Fatalf("checkout page = %d %s", resp.StatusCode, page)
+	}
+
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err = noRedirect.PostForm(links.Checkout.Href, url.Values{"action": {"pay"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != "https://shop.example/order-completed" {
+		t.Fatalf("pay = %d to %q, want a redirect to the shop", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if len(webhookIDs) != 1 || webhookIDs[0] != p.MolliePaymentID {
+		t.Fatalf("webhook calls = %v", webhookIDs)
+	}
+
+	status, err := provider.FetchStatus(ctx, p.MolliePaymentID)
+	if err != nil || status.Status != domain.PaymentStatusPaid || status.PaidAt == nil {
+		t.Fatalf("FetchStatus = %+v, %v, want paid", status, err)
+	}
+
+	resp, err = noRedirect.PostForm(links.Checkout.Href, url.Values{"action": {"fail"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("settling a paid payment again = %d, want 409", resp.StatusCode)
+	}
+
+	if _, err := provider.Refund(ctx, p.MolliePaymentID, decimal.RequireFromString("20.00"), "refund"); err != nil {
+		t.Fatalf("Refund: %v", err)
+	}
+	if _, err := provider.Refund(ctx, p.MolliePaymentID, decimal.RequireFromString("5.00"), "refund"); err == nil {
+		t.Error("refunding more than was paid must fail")
+	}
+}
+
+func TestFakeProviderParseWebhook(t *testing.T) {
+	provider := NewFakeProvider("http://localhost/pay", nil)
+	for id, valid := range map[string]bool{"fake_tr_abc": true, "tr_abc": false, "": false} {
+		r := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(url.Values{"id": {id}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		got, err := provider.ParseWebhook(r)
+		if valid && (err != nil || got != id) {
+			t.Errorf("ParseWebhook(%q) = %q, %v", id, got, err)
+		}
+		if !valid && !errors.Is(err, domain.ErrInvalidWebhook) {
+			t.Errorf("ParseWebhook(%q) error = %v, want ErrInvalidWebhook", id, err)
+		}
+	}
+}
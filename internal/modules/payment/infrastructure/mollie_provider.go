@@ -0,0 +1,235 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/VictorAvelar/mollie-api-go/v4/mollie"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"tsb-service/internal/modules/payment/domain"
+)
+
+// MollieProvider is the payment provider backed by the Mollie API.
+type MollieProvider struct {
+	client mollie.Client
+}
+
+func NewMollieProvider(client mollie.Client) domain.PaymentProvider {
+	return &MollieProvider{client: client}
+}
+
+var mollieLocales = map[string]mollie.Locale{
+	"fr": "fr_BE",
+	"en": "en_US",
+	"nl": "nl_BE",
+	"zh": "zh_CN",
+}
+
+func (p *MollieProvider) CreatePayment(ctx context.Context, req domain.PaymentRequest) (*domain.MolliePayment, error) {
+	locale, ok := mollieLocales[req.Language]
+	if !ok {
+		locale = "fr_BE"
+	}
+
+	lines := make([]mollie.PaymentLines, len(req.Lines))
+	for i, l := range req.Lines {
+		lines[i] = mollie.PaymentLines{
+			Type:         mollie.PaymentLineType(l.Type),
+			Description:  l.Description,
+			Quantity:     l.Quantity,
+			QuantityUnit: l.QuantityUnit,
+			UnitPrice:    amt(l.UnitPrice),
+			TotalAmount:  amt(l.TotalAmount),
+		}
+		if l.VATRate != nil {
+			lines[i].VATRate = l.VATRate.StringFixed(2)
+		}
+		if l.VATAmount != nil {
+			lines[i].VATAmount = amt(*l.VATAmount)
+		}
+	}
+
+	paymentRequest := mollie.CreatePayment{
+		Amount:      amt(req.Amount),
+		Description: req.Description,
+		CancelURL:   req.CancelURL,
+		RedirectURL: req.RedirectURL,
+		WebhookURL:  req.WebhookURL,
+		Locale:      locale,
+		Lines:       lines,
+	}
+	if a := req.ShippingAddress; a != nil {
+		address := &mollie.Address{
+			GivenName:       a.GivenName,
+			FamilyName:      a.FamilyName,
+			StreetAndNumber: a.StreetAndNumber,
+			PostalCode:      a.PostalCode,
+			City:            a.City,
+			Country:         a.Country,
+		}
+		paymentRequest.ShippingAddress = address
+		paymentRequest.BillingAddress = address
+	}
+
+	_, externalPayment, err := p.client.Payments.Create(ctx, paymentRequest, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Mollie payment: %w", err)
+	}
+
+	payment, err := mapExternalPayment(externalPayment, req.OrderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to map Mollie payment: %w", err)
+	}
+	return payment, nil
+}
+
+func (p *MollieProvider) FetchStatus(ctx context.Context, externalPaymentID string) (*domain.PaymentStatusUpdate, error) {
+	_, externalPayment, err := p.client.Payments.Get(ctx, externalPaymentID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch payment from Mollie: %w", err)
+	}
+
+	return &domain.PaymentStatusUpdate{
+		Status:       domain.PaymentStatus(externalPayment.Status),
+		PaidAt:       externalPayment.PaidAt,
+		AuthorizedAt: externalPayment.AuthorizedAt,
+		CanceledAt:   externalPayment.CanceledAt,
+		ExpiredAt:    externalPayment.ExpiredAt,
+		FailedAt:     externalPayment.FailedAt,
+	}, nil
+}
+
+func (p *MollieProvider) Refund(ctx context.Context, externalPaymentID string, amount decimal.Decimal, description string) (string, error) {
+	refundRequest := mollie.CreatePaymentRefund{
+		Amount:      amt(amount),
+		Description: description,
+	}
+	res, created, err := p.client.Refunds.CreatePaymentRefund(ctx, externalPaymentID, refundRequest, nil)
+	if err != nil {
+		return "", err
+	}
+	if res.StatusCode != 200 && res.StatusCode != 201 {
+		return "", fmt.Errorf("mollie returned %s", res.Status)
+	}
+	return created.ID, nil
+}
+
+// ParseWebhook reads the payment ID Mollie posts as the "id" form field.
+// Mollie standard webhooks are not signed; the status is always re-fetched
+// from the API, so a spoofed webhook cannot change a payment.
+func (p *MollieProvider) ParseWebhook(r *http.Request) (string, error) {
+	id := r.PostFormValue("id")
+	if id == "" {
+		return "", fmt.Errorf("%w: missing payment id", domain.ErrInvalidWebhook)
+	}
+	if !strings.HasPrefix(id, "tr_") {
+		return "", fmt.Errorf("%w: not a Mollie payment id: %q", domain.ErrInvalidWebhook, id)
+	}
+	return id, nil
+}
+
+// mapExternalPayment converts a Mollie SDK payment object to the domain struct.
+func mapExternalPayment(external *mollie.Payment, orderID uuid.UUID) (*domain.MolliePayment, error) {
+	amount, err := decimal.NewFromString(external.Amount.Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert amount: %w", err)
+	}
+
+	amountRefunded := decimal.Zero
+	if external.AmountRefunded != nil {
+		amountRefunded, err = decimal.NewFromString(external.AmountRefunded.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert amountRefunded: %w", err)
+		}
+	}
+
+	amountRemaining := decimal.Zero
+	if external.AmountRemaining != nil {
+		amountRemaining, err = decimal.NewFromString(external.AmountRemaining.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert amountRemaining: %w", err)
+		}
+	}
+
+	amountCaptured := decimal.Zero
+	if external.AmountCaptured != nil {
+		amountCaptured, err = decimal.NewFromString(external.AmountCaptured.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert amountCaptured: %w", err)
+		}
+	}
+
+	amountChargedBack := decimal.Zero
+	if external.AmountChargedBack != nil {
+		amountChargedBack, err = decimal.NewFromString(external.AmountChargedBack.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert amountChargedBack: %w", err)
+		}
+	}
+
+	settlementAmount := decimal.Zero
+	if external.SettlementAmount != nil {
+		settlementAmount, err = decimal.NewFromString(external.SettlementAmount.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert settlementAmount: %w", err)
+		}
+	}
+
+	var metadataJSON string
+	if external.Metadata != nil {
+		raw, marshalErr := json.Marshal(external.Metadata)
+		if marshalErr != nil {
+			return nil, fmt.Errorf("failed to marshal metadata: %w", marshalErr)
+		}
+		metadataJSON = string(raw)
+	} else {
+		metadataJSON = "null"
+	}
+
+	linksRaw, err := json.Marshal(external.Links)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal links: %w", err)
+	}
+
+	return &domain.MolliePayment{
+		Resource:                        &external.Resource,
+		MolliePaymentID:                 external.ID,
+		Status:                          domain.PaymentStatus(external.Status),
+		Description:                     &external.Description,
+		CancelURL:                       &external.CancelURL,
+		WebhookURL:                      &external.WebhookURL,
+		CountryCode:                     &external.CountryCode,
+		RestrictPaymentMethodsToCountry: &external.RestrictPaymentMethodsToCountry,
+		ProfileID:                       &external.ProfileID,
+		SettlementID:                    &external.SettlementID,
+		OrderID:                         orderID,
+		IsCancelable:                    external.IsCancelable,
+		Metadata:                        []byte(metadataJSON),
+		Links:                           []byte(linksRaw),
+		CreatedAt:                       *external.CreatedAt,
+		AuthorizedAt:                    external.AuthorizedAt,
+		PaidAt:                          external.PaidAt,
+		CanceledAt:                      external.CanceledAt,
+		ExpiresAt:                       external.ExpiresAt,
+		ExpiredAt:                       external.ExpiredAt,
+		FailedAt:                        external.FailedAt,
+		Amount:                          amount,
+		AmountRefunded:                  amountRefunded,
+		AmountRemaining:                 amountRemaining,
+		AmountCaptured:                  amountCaptured,
+		AmountChargedBack:               amountChargedBack,
+		SettlementAmount:                settlementAmount,
+	}, nil
+}
+
+func amt(d decimal.Decimal) *mollie.Amount {
+	return &mollie.Amount{
+		Value:    d.StringFixed(2),
+		Currency: "EUR",
+	}
+}
@@ -4,12 +4,12 @@ import (
 	"context"
 	"fmt"
 	"time"
+	orderDomain "tsb-service/internal/modules/order/domain"
 	"tsb-service/internal/modules/payment/domain"
 	"tsb-service/pkg/db"
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
-	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 )
 
@@ -106,24 +106,114 @@ func (r *PaymentRepository) Save(ctx context.Context, payment *domain.MolliePaym
 	return nil
 }
 
-func (r *PaymentRepository) MarkAsRefund(ctx context.Context, externalPaymentID string, refundedAmount decimal.Decimal) error {
-	const query = `
-		UPDATE mollie_payments
-		SET amount_refunded = $1
-		WHERE mollie_payment_id = $2;
+func (r *PaymentRepository) SaveRefund(ctx context.Context, refund *domain.Refund, outbox ...*orderDomain.OutboxMessage) error {
+	var err error
+
+	tx, err := r.pool.ForContext(ctx).BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	const refundQuery = `
+		INSERT INTO order_refunds (id, order_id, payment_id, mollie_refund_id, amount, reason, superseded)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at;
+	`
+	var createdAt time.Time
+	err = tx.GetContext(ctx, &createdAt, refundQuery,
+		refund.ID, refund.OrderID, refund.PaymentID, refund.MollieRefundID, refund.Amount, refund.Reason, refund.Superseded)
+	if err != nil {
+		return fmt.Errorf("failed to insert refund: %w", err)
+	}
+
+	const lineQuery = `
+		INSERT INTO order_refund_lines (refund_id, order_product_id, quantity, amount)
+		VALUES ($1, $2, $3, $4);
 	`
+	for i := range refund.Lines {
+		refund.Lines[i].RefundID = refund.ID
+		l := refund.Lines[i]
+		if _, err = tx.ExecContext(ctx, lineQuery, l.RefundID, l.OrderProductID, l.Quantity, l.Amount); err != nil {
+			return fmt.Errorf("failed to insert refund line: %w", err)
+		}
+	}
 
-	res, err := r.pool.ForContext(ctx).ExecContext(ctx, query, refundedAmount, externalPaymentID)
+	const paymentQuery = `
+		UPDATE mollie_payments
+		SET amount_refunded = amount_refunded + $1
+		WHERE id = $2;
+	`
+	res, err := tx.ExecContext(ctx, paymentQuery, refund.Amount, refund.PaymentID)
 	if err != nil {
-		return fmt.Errorf("failed to mark payment as refunded: %w", err)
+		return fmt.Errorf("failed to update refunded amount: %w", err)
 	}
 	if n, raErr := res.RowsAffected(); raErr == nil && n == 0 {
-		return fmt.Errorf("mark payment as refunded: no payment found for id %s", externalPaymentID)
+		err = fmt.Errorf("update refunded amount: no payment found for id %s", refund.PaymentID)
+		return err
+	}
+
+	// Same insert as the order module's outbox repository.
+	const outboxQuery = `
+		INSERT INTO order_outbox (id, order_id, kind, payload)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (id) DO NOTHING
+	`
+	for _, m := range outbox {
+		if _, err = tx.ExecContext(ctx, outboxQuery, m.ID, m.OrderID, m.Kind, m.Payload); err != nil {
+			return fmt.Errorf("failed to insert outbox message: %w", err)
+		}
 	}
 
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	refund.CreatedAt = createdAt
 	return nil
 }
 
+func (r *PaymentRepository) FindRefundsByOrderID(ctx context.Context, orderID uuid.UUID) ([]*domain.Refund, error) {
+	const refundQuery = `
+		SELECT *
+		FROM order_refunds
+		WHERE order_id = $1 AND NOT superseded
+		ORDER BY created_at;
+	`
+	var refunds []*domain.Refund
+	if err := r.pool.ForContext(ctx).SelectContext(ctx, &refunds, refundQuery, orderID); err != nil {
+		return nil, fmt.Errorf("failed to find refunds by order ID: %w", err)
+	}
+	if len(refunds) == 0 {
+		return refunds, nil
+	}
+
+	const lineQuery = `
+		SELECT l.*
+		FROM order_refund_lines l
+		JOIN order_refunds r ON r.id = l.refund_id
+		WHERE r.order_id = $1 AND NOT r.superseded;
+	`
+	var lines []domain.RefundLine
+	if err := r.pool.ForContext(ctx).SelectContext(ctx, &lines, lineQuery, orderID); err != nil {
+		return nil, fmt.Errorf("failed to find refund lines by order ID: %w", err)
+	}
+	byRefund := make(map[uuid.UUID]*domain.Refund, len(refunds))
+	for _, refund := range refunds {
+		byRefund[refund.ID] = refund
+	}
+	for _, l := range lines {
+		if refund, ok := byRefund[l.RefundID]; ok {
+			refund.Lines = append(refund.Lines, l)
+		}
+	}
+	return refunds, nil
+}
+
 // WithPaymentLock runs fn while holding a Postgres session-level advisory lock
 // keyed on the payment ID. Concurrent webhook deliveries for the same payment —
 // possibly landing on different replicas — block here until the holder releases,
@@ -184,7 +274,12 @@ func (r *PaymentRepository) UpdateStatusByOrderID(ctx context.Context, orderID u
 	const query = `
 		UPDATE mollie_payments
 		SET status = $1
-		WHERE order_id = $2
+		WHERE id = (
+			SELECT id FROM mollie_payments
+			WHERE order_id = $2
+			ORDER BY created_at DESC
+			LIMIT 1
+		)
 		RETURNING *;
 	`
 
@@ -202,6 +297,7 @@ func (r *PaymentRepository) FindByOrderID(ctx context.Context, orderID uuid.UUID
 		SELECT *
 		FROM mollie_payments
 		WHERE order_id = $1
+		ORDER BY created_at DESC
 		LIMIT 1;
 	`
 
@@ -231,11 +327,36 @@ func (r *PaymentRepository) FindByExternalID(ctx context.Context, paymentID stri
 	return &payment, nil
 }
 
+func (r *PaymentRepository) ClaimUnsettled(ctx context.Context, createdAfter, createdBefore time.Time, limit int) ([]*domain.MolliePayment, error) {
+	const query = `
+		UPDATE mollie_payments
+		SET last_reconciled_at = now()
+		WHERE id IN (
+			SELECT id
+			FROM mollie_payments
+			WHERE status IN ($1, $2, $3) AND created_at >= $4 AND created_at < $5
+			ORDER BY last_reconciled_at NULLS FIRST, created_at
+			LIMIT $6
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *;
+	`
+	var payments []*domain.MolliePayment
+	if err := r.pool.ForContext(ctx).SelectContext(ctx, &payments, query,
+		domain.PaymentStatusOpen, domain.PaymentStatusPending, domain.PaymentStatusAuthorized,
+		createdAfter, createdBefore, limit,
+	); err != nil {
+		return nil, fmt.Errorf("failed to claim unsettled payments: %w", err)
+	}
+	return payments, nil
+}
+
 func (r *PaymentRepository) FindByOrderIDs(ctx context.Context, orderIDs []string) (map[string][]*domain.MolliePayment, error) {
 	const query = `
 		SELECT *
 		FROM mollie_payments
 		WHERE order_id = ANY($1::uuid[])
+		ORDER BY created_at DESC
 	`
 
 	var payments []*domain.MolliePayment
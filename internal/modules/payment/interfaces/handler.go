@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -20,28 +19,21 @@ import (
 	"tsb-service/pkg/utils"
 )
 
-// NewOrderNotifier fans out push notifications when an online-payment order
-// transitions to paid. Satisfied by *resolver.Resolver.
-type NewOrderNotifier interface {
-	SendNewOrderPush(order *orderDomain.Order)
-}
-
 type PaymentHandler struct {
-	service  paymentApplication.PaymentService
-	broker   *pubsub.Broker
-	notifier NewOrderNotifier
+	service paymentApplication.PaymentService
+	broker  *pubsub.Broker
 }
 
-func NewPaymentHandler(service paymentApplication.PaymentService, broker *pubsub.Broker, notifier NewOrderNotifier) *PaymentHandler {
-	return &PaymentHandler{service: service, broker: broker, notifier: notifier}
+func NewPaymentHandler(service paymentApplication.PaymentService, broker *pubsub.Broker) *PaymentHandler {
+	return &PaymentHandler{service: service, broker: broker}
 }
 
-// UpdatePaymentStatusHandler handles Mollie webhook callbacks.
+// UpdatePaymentStatusHandler handles payment provider webhook callbacks.
 //
 // Security model: Mollie standard webhooks do NOT include a signature header.
 // The webhook body contains only a payment ID (e.g. "tr_xxx"). We always re-fetch
-// the payment from the Mollie API to get the authoritative status. This means a
-// spoofed webhook cannot change payment state — the Mollie API is the source of truth.
+// the payment from the provider API to get the authoritative status. This means a
+// spoofed webhook cannot change payment state — the provider is the source of truth.
 func (h *PaymentHandler) UpdatePaymentStatusHandler(c *gin.Context) {
 	// The webhook itself runs under the plain request context — only the
 	// service calls that need write access to orders/payments run under an
@@ -49,135 +41,182 @@ func (h *PaymentHandler) UpdatePaymentStatusHandler(c *gin.Context) {
 	// and any future downstream code added here don't silently inherit admin
 	// privileges from a request whose only authenticated party is Mollie.
 	ctx := c.Request.Context()
-	adminCtx := utils.SetIsAdmin(ctx, true)
+	adminCtx := utils.SetJob(utils.SetIsAdmin(ctx, true), "mollie-webhook")
 	log := logging.FromContext(ctx)
 
-	var req struct {
-		ExternalMolliePaymentID string `form:"id" binding:"required"`
+	// A request that is not the provider's webhook is acked so it is not
+	// retried; there is nothing to sync.
+	paymentID, err := h.service.ParseWebhook(c.Request)
+	if errors.Is(err, paymentDomain.ErrInvalidWebhook) {
+		log.Warn("webhook: ignored request", zap.Error(err))
+		c.JSON(http.StatusOK, gin.H{"message": "ignored"})
+		return
 	}
-
-	if err := c.ShouldBind(&req); err != nil {
+	if err != nil {
+		log.Warn("webhook: invalid request", zap.Error(err))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request payload"})
 		return
 	}
 
-	// Validate Mollie payment ID format
-	if !strings.HasPrefix(req.ExternalMolliePaymentID, "tr_") {
-		log.Warn("webhook: invalid payment ID format", zap.String("payment_id", req.ExternalMolliePaymentID))
-		c.JSON(http.StatusOK, gin.H{"message": "ignored"})
-		return
+	// A genuine unknown ID means a spoofed or stale webhook — ack with 200 so
+	// Mollie stops. Any error must return 500 so Mollie retries; acking it
+	// would silently drop the webhook.
+	outcome, err := h.SyncPayment(adminCtx, paymentID)
+	switch {
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "temporary failure"})
+	case outcome == SyncUnknown:
+		c.JSON(http.StatusOK, gin.H{"message": "unknown payment"})
+	case outcome == SyncUnchanged:
+		c.JSON(http.StatusOK, gin.H{"message": "already processed"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "processed"})
 	}
+}
+
+// SyncOutcome is what SyncPayment did with a payment.
+type SyncOutcome string
+
+const (
+	// SyncUnknown means the payment is not one of ours.
+	SyncUnknown SyncOutcome = "unknown"
+	// SyncUnchanged means the stored status already matched Mollie's.
+	SyncUnchanged SyncOutcome = "unchanged"
+	// SyncPaid means the payment was paid and its order released to staff.
+	SyncPaid SyncOutcome = "paid"
+	// SyncFailed means the payment was canceled, failed or expired and its
+	// order cancelled.
+	SyncFailed SyncOutcome = "failed"
+	// SyncRefreshed means the payment is still open; only its status and
+	// timestamps were refreshed.
+	SyncRefreshed SyncOutcome = "refreshed"
+	// SyncSuperseded means the payment is an earlier attempt of an order
+	// whose customer retried paying; only its status was refreshed.
+	SyncSuperseded SyncOutcome = "superseded"
+)
 
-	paymentID := req.ExternalMolliePaymentID
-
-	// Serialize concurrent webhook deliveries for this payment (Mollie can fan out
-	// retries that overlap, possibly across replicas) so the order business logic
-	// runs at most once per transition and the idempotency check below sees a
-	// consistent stored status.
-	if lockErr := h.service.WithPaymentLock(adminCtx, paymentID, func(adminCtx context.Context) error {
-		// Verify the payment exists in our DB. A genuine not-found means a spoofed
-		// or stale ID — ack with 200 so Mollie stops. Any OTHER error (e.g. a
-		// transient DB failure) must return 500 so Mollie retries; acking it would
-		// silently drop the webhook.
-		payment, err := h.service.GetPaymentByExternalID(adminCtx, paymentID)
+// SyncPayment brings the payment with the given Mollie ID in line with
+// Mollie's authoritative status, running the order business logic of the
+// transition and publishing and pushing it. It is what the webhook does, and
+// what the reconciler does for payments whose webhook never arrived. ctx must
+// carry admin rights. An error means the sync should be retried.
+func (h *PaymentHandler) SyncPayment(ctx context.Context, paymentID string) (SyncOutcome, error) {
+	log := logging.FromContext(ctx).With(zap.String("payment_id", paymentID), zap.String("job", utils.GetJob(ctx)))
+
+	// Serialize concurrent syncs of this payment (Mollie can fan out webhook
+	// retries that overlap, possibly across replicas, and the reconciler may
+	// run at the same time) so the order business logic runs at most once per
+	// transition and the idempotency check below sees a consistent stored status.
+	outcome := SyncUnchanged
+	err := h.service.WithPaymentLock(ctx, paymentID, func(ctx context.Context) error {
+		payment, err := h.service.GetPaymentByExternalID(ctx, paymentID)
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
-				log.Warn("webhook: unknown payment ID", zap.String("payment_id", paymentID))
-				c.JSON(http.StatusOK, gin.H{"message": "unknown payment"})
+				log.Warn("payment sync: unknown payment ID")
+				outcome = SyncUnknown
 				return nil
 			}
-			log.Error("webhook: failed to look up payment", zap.String("payment_id", paymentID), zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "temporary failure"})
-			return nil
+			return fmt.Errorf("failed to look up payment: %w", err)
 		}
 
-		// Fetch the authoritative status from Mollie WITHOUT persisting it yet.
-		update, err := h.service.FetchMollieStatus(adminCtx, paymentID)
+		// Fetch the authoritative status from the provider WITHOUT persisting it yet.
+		update, err := h.service.FetchPaymentStatus(ctx, paymentID)
 		if err != nil {
-			log.Error("webhook: failed to fetch payment status from Mollie", zap.String("payment_id", paymentID), zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "temporary failure"})
-			return nil
+			return fmt.Errorf("failed to fetch payment status: %w", err)
 		}
 
 		// Idempotency: the stored status is the commit marker. If it already matches
 		// Mollie, the work for this transition was done (possibly by a concurrent
-		// delivery that held the lock just before us) — nothing more to do.
+		// sync that held the lock just before us) — nothing more to do.
 		if update.Status == payment.Status {
-			c.JSON(http.StatusOK, gin.H{"message": "already processed"})
 			return nil
 		}
 
 		orderID := payment.OrderID
 
+		// Only the order's latest payment attempt drives it: an earlier
+		// attempt expiring after the customer retried must not cancel the
+		// order again.
+		latest, err := h.service.GetPaymentByOrderID(ctx, orderID)
+		if err != nil {
+			return fmt.Errorf("failed to look up latest payment: %w", err)
+		}
+		if latest.ID != payment.ID {
+			if update.Status == paymentDomain.PaymentStatusPaid {
+				// The customer paid an attempt they had given up on (Mollie
+				// reported it failed, then the bank went through): the latest
+				// attempt pays the order, so this one is given back. Enqueued
+				// before the status is persisted so a failed persist retries
+				// into the same keyed message.
+				log.Warn("payment sync: superseded payment attempt was paid, refunding it", zap.String("order_id", orderID.String()))
+				if err := h.service.HandleSupersededPaymentPaid(ctx, payment); err != nil {
+					return fmt.Errorf("failed to handle superseded paid payment: %w", err)
+				}
+			}
+			if persistErr := h.service.PersistPaymentStatus(ctx, paymentID, update); persistErr != nil {
+				return fmt.Errorf("failed to persist payment status: %w", persistErr)
+			}
+			outcome = SyncSuperseded
+			return nil
+		}
+
 		// Run order business logic BEFORE persisting the new status. On failure we
-		// return 500 and leave the stored status untouched, so Mollie's retry re-runs
-		// the logic instead of being short-circuited by an "already processed" status.
+		// leave the stored status untouched, so the retry re-runs the logic instead
+		// of being short-circuited by an "already processed" status.
 		switch update.Status {
 		case paymentDomain.PaymentStatusPaid:
-			order, handleErr := h.service.HandlePaymentPaid(adminCtx, orderID)
+			order, handleErr := h.service.HandlePaymentPaid(ctx, orderID)
 			if handleErr != nil {
-				log.Error("webhook: failed to handle paid payment", zap.String("payment_id", paymentID), zap.Error(handleErr))
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "temporary failure"})
-				return nil
+				return fmt.Errorf("failed to handle paid payment: %w", handleErr)
 			}
-			if persistErr := h.service.PersistPaymentStatus(adminCtx, paymentID, update); persistErr != nil {
-				log.Error("webhook: failed to persist payment status", zap.String("payment_id", paymentID), zap.Error(persistErr))
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "temporary failure"})
-				return nil
+			if persistErr := h.service.PersistPaymentStatus(ctx, paymentID, update); persistErr != nil {
+				return fmt.Errorf("failed to persist payment status: %w", persistErr)
 			}
+			outcome = SyncPaid
+			// HandlePaymentPaid enqueued the staff announcement and the payment
+			// webhook: first time the dashboard and the staff phones hear of this
+			// online-payment order, since CreateOrder suppressed it until payment
+			// confirmed.
 			switch {
 			case order == nil:
 				// nothing to publish
+			case order.OrderStatus == orderDomain.OrderStatusCanceled || order.OrderStatus == orderDomain.OrderStatusFailed:
+				// Cancelled while checkout was still open: the payment is
+				// refunded, staff never hear of the order.
+				log.Warn("payment sync: cancelled order was paid, refunding it", zap.String("order_id", orderID.String()))
 			case order.IsTest:
 				// Store-review test order: stays fully invisible to staff — no
 				// subscription publish, no push. It will auto-cancel after 10 min.
 				// TEMPORARY (revert after launch).
-				log.Info("webhook: store-review test order paid — suppressing publish/push", zap.String("order_id", order.ID.String()))
-			default:
-				gqlOrder := resolver.ToGQLOrder(order)
-				// First time the dashboard sees this online-payment order — publish
-				// orderCreated so admin clients add it to their store. CreateOrder
-				// suppressed that event for online orders until payment confirmed.
-				h.broker.Publish("orderCreated", gqlOrder)
-				h.broker.Publish("orderUpdated", gqlOrder)
-				h.broker.Publish(fmt.Sprintf("orderUpdated:%s", orderID), gqlOrder)
-				// Same rationale applies to push notifications — we only wake up
-				// admin phones and POS handhelds once the payment is confirmed.
-				if h.notifier != nil {
-					h.notifier.SendNewOrderPush(order)
-				}
+				log.Info("payment sync: store-review test order paid — suppressing publish/push", zap.String("order_id", order.ID.String()))
+			case order.HeldForSchedule:
+				// Scheduled for later: the release sweep announces it to staff
+				// shortly before it is due. The customer still gets the update.
+				h.broker.Publish(fmt.Sprintf("orderUpdated:%s", orderID), resolver.ToGQLOrder(order))
 			}
 		case paymentDomain.PaymentStatusCanceled, paymentDomain.PaymentStatusFailed, paymentDomain.PaymentStatusExpired:
-			order, handleErr := h.service.HandlePaymentFailed(adminCtx, orderID)
-			if handleErr != nil {
-				log.Error("webhook: failed to handle failed payment", zap.String("payment_id", paymentID), zap.Error(handleErr))
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "temporary failure"})
-				return nil
-			}
-			if persistErr := h.service.PersistPaymentStatus(adminCtx, paymentID, update); persistErr != nil {
-				log.Error("webhook: failed to persist payment status", zap.String("payment_id", paymentID), zap.Error(persistErr))
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "temporary failure"})
-				return nil
+			// The cancellation enqueues its subscription event and webhook
+			// (see orderDomain.PaymentFailedEffects).
+			if handleErr := h.service.HandlePaymentFailed(ctx, payment); handleErr != nil {
+				return fmt.Errorf("failed to handle failed payment: %w", handleErr)
 			}
-			if order != nil {
-				gqlOrder := resolver.ToGQLOrder(order)
-				h.broker.Publish("orderUpdated", gqlOrder)
-				h.broker.Publish(fmt.Sprintf("orderUpdated:%s", orderID), gqlOrder)
+			if persistErr := h.service.PersistPaymentStatus(ctx, paymentID, update); persistErr != nil {
+				return fmt.Errorf("failed to persist payment status: %w", persistErr)
 			}
+			outcome = SyncFailed
 		default:
 			// Non-terminal status (open/pending/authorized): no business logic, just
 			// persist the refreshed status + timestamps.
-			if persistErr := h.service.PersistPaymentStatus(adminCtx, paymentID, update); persistErr != nil {
-				log.Error("webhook: failed to persist payment status", zap.String("payment_id", paymentID), zap.Error(persistErr))
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "temporary failure"})
-				return nil
+			if persistErr := h.service.PersistPaymentStatus(ctx, paymentID, update); persistErr != nil {
+				return fmt.Errorf("failed to persist payment status: %w", persistErr)
 			}
+			outcome = SyncRefreshed
 		}
-
-		c.JSON(http.StatusOK, gin.H{"message": "processed"})
 		return nil
-	}); lockErr != nil {
-		log.Error("webhook: failed to acquire payment lock", zap.String("payment_id", paymentID), zap.Error(lockErr))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "temporary failure"})
+	})
+	if err != nil {
+		log.Error("payment sync failed", zap.Error(err))
+		return "", err
 	}
+	return outcome, nil
 }
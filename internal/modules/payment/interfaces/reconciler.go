@@ -0,0 +1,119 @@
+package interfaces
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"tsb-service/pkg/logging"
+)
+
+// reconcileBatchSize caps the Mollie API calls of one reconciliation run; a
+// larger backlog drains over the following runs.
+const reconcileBatchSize = 50
+
+// reconcileMaxAge is the age past which an unsettled payment is no longer
+// reconciled. Mollie expires every payment we create long before it, so one
+// still unsettled is stuck and left for manual review rather than polled
+// forever.
+const reconcileMaxAge = 72 * time.Hour
+
+var (
+	reconcilerRunsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "payment_reconciler_runs_total",
+			Help: "Payment reconciliation runs, by result.",
+		},
+		[]string{"result"},
+	)
+
+	reconcilerPaymentsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "payment_reconciler_payments_total",
+			Help: "Payments synced with Mollie by the reconciler, by outcome.",
+		},
+		[]string{"outcome"},
+	)
+
+	reconcilerRunDuration = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "payment_reconciler_run_duration_seconds",
+			Help:    "Duration of payment reconciliation runs.",
+			Buckets: prometheus.DefBuckets,
+		},
+	)
+
+	reconcilerLastSuccess = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "payment_reconciler_last_success_timestamp_seconds",
+			Help: "Unix time of the last payment reconciliation run that completed.",
+		},
+	)
+)
+
+// syncError labels the payments whose sync failed in the reconciler metrics.
+const syncError SyncOutcome = "error"
+
+// ReconcilePayments syncs the payments still unsettled more than olderThan
+// after their creation, as if their webhook had just arrived, and returns how
+// many ended in each outcome. A payment that fails to sync is counted under
+// "error" and retried on a later run; a run where every sync failed (Mollie or
+// the database being down) is a failed run.
+func (h *PaymentHandler) ReconcilePayments(ctx context.Context, olderThan time.Duration) (map[SyncOutcome]int, error) {
+	start := time.Now()
+	defer func() { reconcilerRunDuration.Observe(time.Since(start).Seconds()) }()
+
+	payments, err := h.service.ClaimUnsettledPayments(ctx, olderThan, reconcileMaxAge, reconcileBatchSize)
+	if err != nil {
+		reconcilerRunsTotal.WithLabelValues("error").Inc()
+		return nil, err
+	}
+	counts := make(map[SyncOutcome]int)
+	for _, p := range payments {
+		outcome, err := h.SyncPayment(ctx, p.MolliePaymentID)
+		if err != nil {
+			outcome = syncError
+		}
+		counts[outcome]++
+		reconcilerPaymentsTotal.WithLabelValues(string(outcome)).Inc()
+	}
+	if len(payments) > 0 && counts[syncError] == len(payments) {
+		reconcilerRunsTotal.WithLabelValues("error").Inc()
+		return counts, fmt.Errorf("all %d payment syncs failed", len(payments))
+	}
+	reconcilerRunsTotal.WithLabelValues("ok").Inc()
+	reconcilerLastSuccess.SetToCurrentTime()
+	return counts, nil
+}
+
+// RunReconciler reconciles every interval until ctx is done. ctx must carry
+// admin rights.
+func (h *PaymentHandler) RunReconciler(ctx context.Context, interval, olderThan time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		log := logging.FromContext(ctx)
+		counts, err := h.ReconcilePayments(ctx, olderThan)
+		if err != nil {
+			log.Warn("payment reconciliation failed", zap.Error(err))
+			continue
+		}
+		log.Info("payment reconciliation run",
+			zap.Int("paid", counts[SyncPaid]),
+			zap.Int("failed", counts[SyncFailed]),
+			zap.Int("refreshed", counts[SyncRefreshed]),
+			zap.Int("unchanged", counts[SyncUnchanged]),
+			zap.Int("superseded", counts[SyncSuperseded]),
+			zap.Int("errors", counts[syncError]),
+		)
+	}
+}
@@ -0,0 +1,190 @@
+package interfaces
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	paymentApplication "tsb-service/internal/modules/payment/application"
+	paymentDomain "tsb-service/internal/modules/payment/domain"
+	"tsb-service/pkg/pubsub"
+)
+
+// fakePaymentService serves stored payments and the statuses Mollie reports
+// for them; the methods the reconciler does not use are left unimplemented.
+type fakePaymentService struct {
+	paymentApplication.PaymentService
+	stored    map[string]*paymentDomain.MolliePayment
+	mollie    map[string]paymentDomain.PaymentStatus
+	persisted map[string]paymentDomain.PaymentStatus
+	failed    []uuid.UUID
+	refunded  []string
+}
+
+func newFakePaymentService(payments map[string][2]paymentDomain.PaymentStatus) *fakePaymentService {
+	f := &fakePaymentService{
+		stored:    make(map[string]*paymentDomain.MolliePayment),
+		mollie:    make(map[string]paymentDomain.PaymentStatus),
+		persisted: make(map[string]paymentDomain.PaymentStatus),
+	}
+	for id, statuses := range payments {
+		f.stored[id] = &paymentDomain.MolliePayment{ID: uuid.New(), MolliePaymentID: id, OrderID: uuid.New(), Status: statuses[0], CreatedAt: time.Now()}
+		f.mollie[id] = statuses[1]
+	}
+	return f
+}
+
+func (f *fakePaymentService) ClaimUnsettledPayments(context.Context, time.Duration, time.Duration, int) ([]*paymentDomain.MolliePayment, error) {
+	var payments []*paymentDomain.MolliePayment
+	for _, p := range f.stored {
+		payments = append(payments, p)
+	}
+	return payments, nil
+}
+
+func (f *fakePaymentService) WithPaymentLock(ctx context.Context, _ string, fn func(context.Context) error) error {
+	return fn(ctx)
+}
+
+func (f *fakePaymentService) GetPaymentByExternalID(_ context.Context, id string) (*paymentDomain.MolliePayment, error) {
+	p, ok := f.stored[id]
+	if !ok {
+		return nil, fmt.Errorf("failed to find payment by ID: %w", sql.ErrNoRows)
+	}
+	return p, nil
+}
+
+func (f *fakePaymentService) GetPaymentByOrderID(_ context.Context, orderID uuid.UUID) (*paymentDomain.MolliePayment, error) {
+	var latest *paymentDomain.MolliePayment
+	for _, p := range f.stored {
+		if p.OrderID == orderID && (latest == nil || p.CreatedAt.After(latest.CreatedAt)) {
+			latest = p
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("failed to find payment: %w", sql.ErrNoRows)
+	}
+	return latest, nil
+}
+
+func (f *fakePaymentService) FetchPaymentStatus(_ context.Context, id string) (*paymentDomain.PaymentStatusUpdate, error) {
+	status, ok := f.mollie[id]
+	if !ok || status == "" {
+		return nil, fmt.Errorf("mollie unavailable")
+	}
+	return &paymentDomain.PaymentStatusUpdate{Status: status}, nil
+}
+
+func (f *fakePaymentService) HandlePaymentFailed(_ context.Context, p *paymentDomain.MolliePayment) error {
+	f.failed = append(f.failed, p.OrderID)
+	return nil
+}
+
+func (f *fakePaymentService) HandleSupersededPaymentPaid(_ context.Context, p *paymentDomain.MolliePayment) error {
+	f.refunded = append(f.refunded, p.MolliePaymentID)
+	return nil
+}
+
+func (f *fakePaymentService) PersistPaymentStatus(_ context.Context, id string, update *paymentDomain.PaymentStatusUpdate) error {
+	f.persisted[id] = update.Status
+	return nil
+}
+
+func TestReconcilePayments(t *testing.T) {
+	svc := newFakePaymentService(map[string][2]paymentDomain.PaymentStatus{
+		"tr_expired": {paymentDomain.PaymentStatusOpen, paymentDomain.PaymentStatusExpired},
+		"tr_open":    {paymentDomain.PaymentStatusOpen, paymentDomain.PaymentStatusOpen},
+		"tr_pending": {paymentDomain.PaymentStatusOpen, paymentDomain.PaymentStatusPending},
+		"tr_down":    {paymentDomain.PaymentStatusOpen, ""},
+	})
+	h := NewPaymentHandler(svc, pubsub.NewBroker())
+
+	counts, err := h.ReconcilePayments(context.Background(), 15*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[SyncOutcome]int{SyncFailed: 1, SyncUnchanged: 1, SyncRefreshed: 1, syncError: 1}
+	if fmt.Sprint(counts) != fmt.Sprint(want) {
+		t.Errorf("counts = %v, want %v", counts, want)
+	}
+	if len(svc.failed) != 1 || svc.failed[0] != svc.stored["tr_expired"].OrderID {
+		t.Errorf("the expired payment's order must be cancelled, got %v", svc.failed)
+	}
+	if svc.persisted["tr_expired"] != paymentDomain.PaymentStatusExpired || svc.persisted["tr_pending"] != paymentDomain.PaymentStatusPending {
+		t.Errorf("persisted = %v", svc.persisted)
+	}
+	if _, ok := svc.persisted["tr_down"]; ok {
+		t.Errorf("a payment Mollie could not report on must be left for the next run")
+	}
+}
+
+func TestReconcilePaymentsAllFailed(t *testing.T) {
+	svc := newFakePaymentService(map[string][2]paymentDomain.PaymentStatus{
+		"tr_down":  {paymentDomain.PaymentStatusOpen, ""},
+		"tr_down2": {paymentDomain.PaymentStatusOpen, ""},
+	})
+	h := NewPaymentHandler(svc, pubsub.NewBroker())
+
+	counts, err := h.ReconcilePayments(context.Background(), 15*time.Minute)
+	if err == nil {
+		t.Fatal("a run where every sync failed must fail")
+	}
+	if counts[syncError] != 2 {
+		t.Errorf("counts = %v, want 2 errors", counts)
+	}
+}
+
+func TestSyncPaymentUnknown(t *testing.T) {
+	h := NewPaymentHandler(newFakePaymentService(nil), pubsub.NewBroker())
+	outcome, err := h.SyncPayment(context.Background(), "tr_unknown")
+	if err != nil || outcome != SyncUnknown {
+		t.Errorf("SyncPayment = %q, %v, want unknown", outcome, err)
+	}
+}
+
+func TestSyncPaymentSupersededAttempt(t *testing.T) {
+	svc := newFakePaymentService(map[string][2]paymentDomain.PaymentStatus{
+		"tr_first": {paymentDomain.PaymentStatusOpen, paymentDomain.PaymentStatusExpired},
+		"tr_retry": {paymentDomain.PaymentStatusOpen, paymentDomain.PaymentStatusOpen},
+	})
+	// The customer retried: both attempts belong to the same order.
+	svc.stored["tr_first"].CreatedAt = time.Now().Add(-20 * time.Minute)
+	svc.stored["tr_retry"].OrderID = svc.stored["tr_first"].OrderID
+	h := NewPaymentHandler(svc, pubsub.NewBroker())
+
+	outcome, err := h.SyncPayment(context.Background(), "tr_first")
+	if err != nil || outcome != SyncSuperseded {
+		t.Fatalf("SyncPayment = %q, %v, want superseded", outcome, err)
+	}
+	if len(svc.failed) != 0 {
+		t.Errorf("an earlier attempt expiring must not cancel the order, got %v", svc.failed)
+	}
+	if svc.persisted["tr_first"] != paymentDomain.PaymentStatusExpired {
+		t.Errorf("the earlier attempt's status must still be recorded, got %v", svc.persisted)
+	}
+}
+
+func TestSyncPaymentSupersededAttemptPaid(t *testing.T) {
+	svc := newFakePaymentService(map[string][2]paymentDomain.PaymentStatus{
+		"tr_first": {paymentDomain.PaymentStatusFailed, paymentDomain.PaymentStatusPaid},
+		"tr_retry": {paymentDomain.PaymentStatusPaid, paymentDomain.PaymentStatusPaid},
+	})
+	svc.stored["tr_first"].CreatedAt = time.Now().Add(-20 * time.Minute)
+	svc.stored["tr_retry"].OrderID = svc.stored["tr_first"].OrderID
+	h := NewPaymentHandler(svc, pubsub.NewBroker())
+
+	outcome, err := h.SyncPayment(context.Background(), "tr_first")
+	if err != nil || outcome != SyncSuperseded {
+		t.Fatalf("SyncPayment = %q, %v, want superseded", outcome, err)
+	}
+	if len(svc.refunded) != 1 || svc.refunded[0] != "tr_first" {
+		t.Errorf("the paid earlier attempt must be refunded, got %v", svc.refunded)
+	}
+	if svc.persisted["tr_first"] != paymentDomain.PaymentStatusPaid {
+		t.Errorf("persisted = %v", svc.persisted)
+	}
+}
@@ -3,10 +3,13 @@ package application
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"time"
 
 	"tsb-service/internal/modules/restaurant/domain"
 	"tsb-service/pkg/timezone"
+
+	"github.com/google/uuid"
 )
 
 type RestaurantService interface {
@@ -18,23 +21,51 @@ type RestaurantService interface {
 	UpdateOpeningHours(ctx context.Context, hours json.RawMessage) (*domain.RestaurantConfig, error)
 	UpdateOrderingHours(ctx context.Context, hours json.RawMessage) (*domain.RestaurantConfig, error)
 	UpdatePreparationMinutes(ctx context.Context, minutes int) (*domain.RestaurantConfig, error)
+	// GetPricing returns the current pricing rules (delivery tiers, minimums,
+	// takeaway discount, transaction fee).
+	GetPricing(ctx context.Context) (domain.PricingRules, error)
+	UpdatePricing(ctx context.Context, rules domain.PricingRules) (*domain.RestaurantConfig, error)
+	// UpdateScheduling sets how far ahead orders can be scheduled and how
+	// long before their ready time they are released to staff.
+	UpdateScheduling(ctx context.Context, horizonDays, leadMinutes int) (*domain.RestaurantConfig, error)
+	// UpdateCancellationGrace sets how long customers may cancel a confirmed
+	// order themselves.
+	UpdateCancellationGrace(ctx context.Context, minutes int) (*domain.RestaurantConfig, error)
+	// UpdateAutoApplyEta sets whether confirming an order without a ready
+	// time applies the suggested one.
+	UpdateAutoApplyEta(ctx context.Context, enabled bool) (*domain.RestaurantConfig, error)
+	// UpdateUnconfirmedTimeouts sets how long an order may wait for staff
+	// confirmation before staff are alerted again and before it is cancelled.
+	UpdateUnconfirmedTimeouts(ctx context.Context, escalationMinutes, cancelMinutes int) (*domain.RestaurantConfig, error)
+	UpdateSlotCapacity(ctx context.Context, capacity domain.SlotCapacity) (*domain.RestaurantConfig, error)
 
 	ListOverrides(ctx context.Context, from, to time.Time) ([]*domain.ScheduleOverride, error)
 	UpsertOverride(ctx context.Context, date time.Time, closed bool, schedule json.RawMessage, note *string) (*domain.ScheduleOverride, error)
 	DeleteOverride(ctx context.Context, date time.Time) error
+
+	ListDeliveryZones(ctx context.Context) ([]*domain.DeliveryZone, error)
+	CreateDeliveryZone(ctx context.Context, zone *domain.DeliveryZone) (*domain.DeliveryZone, error)
+	UpdateDeliveryZone(ctx context.Context, zone *domain.DeliveryZone) (*domain.DeliveryZone, error)
+	DeleteDeliveryZone(ctx context.Context, id uuid.UUID) error
+	// ResolveDeliveryZone returns the active zone containing the point,
+	// domain.ErrOutsideDeliveryZones when none does, or (nil, nil) when no
+	// zone is configured and the pricing radius still applies.
+	ResolveDeliveryZone(ctx context.Context, lat, lng float64) (*domain.DeliveryZone, error)
 }
 
 type restaurantService struct {
 	repo          domain.RestaurantRepository
 	overrideRepo  domain.ScheduleOverrideRepository
+	zoneRepo      domain.DeliveryZoneRepository
 	devMode       bool
 	overrideLookahead time.Duration
 }
 
-func NewRestaurantService(repo domain.RestaurantRepository, overrideRepo domain.ScheduleOverrideRepository, devMode bool) RestaurantService {
+func NewRestaurantService(repo domain.RestaurantRepository, overrideRepo domain.ScheduleOverrideRepository, zoneRepo domain.DeliveryZoneRepository, devMode bool) RestaurantService {
 	return &restaurantService{
 		repo:              repo,
 		overrideRepo:      overrideRepo,
+		zoneRepo:          zoneRepo,
 		devMode:           devMode,
 		overrideLookahead: 7 * 24 * time.Hour,
 	}
@@ -45,8 +76,9 @@ func (s *restaurantService) GetConfig(ctx context.Context) (*domain.RestaurantCo
 }
 
 // GetConfigWithOverrides fetches both the config and overrides relevant
-// to "today and the next 7 days" — enough for all current consumers
-// (IsCurrentlyOpen, availableSlotsToday, nextOpeningAt).
+// to "today and the next 7 days", or up to the scheduling horizon when that
+// is further — enough for all current consumers (IsCurrentlyOpen,
+// availableSlots, nextOpeningAt, preferred ready time checks).
 func (s *restaurantService) GetConfigWithOverrides(ctx context.Context) (*domain.RestaurantConfig, map[string]*domain.ScheduleOverride, error) {
 	config, err := s.repo.GetConfig(ctx)
 	if err != nil {
@@ -55,6 +87,9 @@ func (s *restaurantService) GetConfigWithOverrides(ctx context.Context) (*domain
 	now := timezone.In(time.Now())
 	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 	to := today.Add(s.overrideLookahead)
+	if horizonEnd := today.AddDate(0, 0, config.SchedulingHorizonDays+1); horizonEnd.After(to) {
+		to = horizonEnd
+	}
 
 	overrides, err := s.overrideRepo.List(ctx, today, to)
 	if err != nil {
@@ -98,6 +133,55 @@ func (s *restaurantService) UpdatePreparationMinutes(ctx context.Context, minute
 	return s.repo.UpdatePreparationMinutes(ctx, minutes)
 }
 
+func (s *restaurantService) GetPricing(ctx context.Context) (domain.PricingRules, error) {
+	config, err := s.repo.GetConfig(ctx)
+	if err != nil {
+		return domain.PricingRules{}, err
+	}
+	return config.GetPricing()
+}
+
+func (s *restaurantService) UpdatePricing(ctx context.Context, rules domain.PricingRules) (*domain.RestaurantConfig, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	pricingJSON, err := json.Marshal(rules)
+	if err != nil {
+		return nil, fmt.Errorf("marshal pricing rules: %w", err)
+	}
+	return s.repo.UpdatePricing(ctx, pricingJSON)
+}
+
+func (s *restaurantService) UpdateScheduling(ctx context.Context, horizonDays, leadMinutes int) (*domain.RestaurantConfig, error) {
+	return s.repo.UpdateScheduling(ctx, horizonDays, leadMinutes)
+}
+
+func (s *restaurantService) UpdateCancellationGrace(ctx context.Context, minutes int) (*domain.RestaurantConfig, error) {
+	return s.repo.UpdateCancellationGrace(ctx, minutes)
+}
+
+func (s *restaurantService) UpdateAutoApplyEta(ctx context.Context, enabled bool) (*domain.RestaurantConfig, error) {
+	return s.repo.UpdateAutoApplyEta(ctx, enabled)
+}
+
+func (s *restaurantService) UpdateUnconfirmedTimeouts(ctx context.Context, escalationMinutes, cancelMinutes int) (*domain.RestaurantConfig, error) {
+	return s.repo.UpdateUnconfirmedTimeouts(ctx, escalationMinutes, cancelMinutes)
+}
+
+func (s *restaurantService) UpdateSlotCapacity(ctx context.Context, capacity domain.SlotCapacity) (*domain.RestaurantConfig, error) {
+	if err := capacity.Validate(); err != nil {
+		return nil, err
+	}
+	if capacity == nil {
+		capacity = domain.SlotCapacity{}
+	}
+	capacityJSON, err := json.Marshal(capacity)
+	if err != nil {
+		return nil, fmt.Errorf("marshal slot capacity: %w", err)
+	}
+	return s.repo.UpdateSlotCapacity(ctx, capacityJSON)
+}
+
 func (s *restaurantService) ListOverrides(ctx context.Context, from, to time.Time) ([]*domain.ScheduleOverride, error) {
 	return s.overrideRepo.List(ctx, from, to)
 }
@@ -115,3 +199,43 @@ func (s *restaurantService) UpsertOverride(ctx context.Context, date time.Time,
 func (s *restaurantService) DeleteOverride(ctx context.Context, date time.Time) error {
 	return s.overrideRepo.Delete(ctx, date)
 }
+
+func (s *restaurantService) ListDeliveryZones(ctx context.Context) ([]*domain.DeliveryZone, error) {
+	return s.zoneRepo.List(ctx)
+}
+
+func (s *restaurantService) CreateDeliveryZone(ctx context.Context, zone *domain.DeliveryZone) (*domain.DeliveryZone, error) {
+	if err := zone.Validate(); err != nil {
+		return nil, err
+	}
+	return s.zoneRepo.Create(ctx, zone)
+}
+
+func (s *restaurantService) UpdateDeliveryZone(ctx context.Context, zone *domain.DeliveryZone) (*domain.DeliveryZone, error) {
+	if err := zone.Validate(); err != nil {
+		return nil, err
+	}
+	return s.zoneRepo.Update(ctx, zone)
+}
+
+func (s *restaurantService) DeleteDeliveryZone(ctx context.Context, id uuid.UUID) error {
+	return s.zoneRepo.Delete(ctx, id)
+}
+
+func (s *restaurantService) ResolveDeliveryZone(ctx context.Context, lat, lng float64) (*domain.DeliveryZone, error) {
+	zones, err := s.zoneRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list delivery zones: %w", err)
+	}
+	hasActive := false
+	for _, z := range zones {
+		if z.IsActive {
+			hasActive = true
+			break
+		}
+	}
+	if !hasActive {
+		return nil, nil
+	}
+	return domain.FindDeliveryZone(zones, lat, lng)
+}
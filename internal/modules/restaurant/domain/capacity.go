@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"tsb-service/pkg/timezone"
+)
+
+// SlotCapacityRule caps what the kitchen accepts in one 15-minute slot.
+// Weekday ("monday"…"sunday") and OrderType ("DELIVERY", "PICKUP", "DINE_IN")
+// narrow the rule; empty means every day / every order. A rule scoped to an
+// order type only counts orders of that type. Zero maxima mean unlimited.
+type SlotCapacityRule struct {
+	Weekday   string `json:"weekday,omitempty"`
+	OrderType string `json:"orderType,omitempty"`
+	MaxOrders int    `json:"maxOrders"`
+	MaxItems  int    `json:"maxItems"`
+}
+
+// SlotCapacity is the admin-editable list of capacity rules, stored as JSONB
+// in the restaurant_config row. An empty list leaves slots unlimited.
+type SlotCapacity []SlotCapacityRule
+
+var weekdayNames = []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+
+// GetSlotCapacity parses the JSONB slot_capacity column. A missing column
+// means no limits.
+func (c *RestaurantConfig) GetSlotCapacity() (SlotCapacity, error) {
+	if len(c.SlotCapacity) == 0 || string(c.SlotCapacity) == "null" {
+		return nil, nil
+	}
+	var rules SlotCapacity
+	if err := json.Unmarshal(c.SlotCapacity, &rules); err != nil {
+		return nil, fmt.Errorf("parse slot capacity: %w", err)
+	}
+	return rules, nil
+}
+
+// RulesFor returns the rules that apply to the slot starting at t: for the
+// whole-slot scope and for each order type, a rule for that weekday wins over
+// an every-day rule.
+func (s SlotCapacity) RulesFor(t time.Time) []SlotCapacityRule {
+	weekday := strings.ToLower(timezone.In(t).Weekday().String())
+	byScope := make(map[string]SlotCapacityRule)
+	var scopes []string
+	for _, r := range s {
+		if r.Weekday != "" && r.Weekday != weekday {
+			continue
+		}
+		current, seen := byScope[r.OrderType]
+		if !seen {
+			scopes = append(scopes, r.OrderType)
+		}
+		if !seen || (current.Weekday == "" && r.Weekday != "") {
+			byScope[r.OrderType] = r
+		}
+	}
+	out := make([]SlotCapacityRule, 0, len(scopes))
+	for _, scope := range scopes {
+		out = append(out, byScope[scope])
+	}
+	return out
+}
+
+// Validate rejects unknown weekdays or order types, negative maxima and two
+// rules for the same weekday and order type.
+func (s SlotCapacity) Validate() error {
+	seen := make(map[[2]string]bool, len(s))
+	for i, r := range s {
+		if r.Weekday != "" && !slices.Contains(weekdayNames, r.Weekday) {
+			return fmt.Errorf("capacity rule %d: unknown weekday %q", i+1, r.Weekday)
+		}
+		switch r.OrderType {
+		case "", "DELIVERY", "PICKUP", "DINE_IN":
+		default:
+			return fmt.Errorf("capacity rule %d: unknown order type %q", i+1, r.OrderType)
+		}
+		if r.MaxOrders < 0 || r.MaxItems < 0 {
+			return fmt.Errorf("capacity rule %d: maxima cannot be negative", i+1)
+		}
+		key := [2]string{r.Weekday, r.OrderType}
+		if seen[key] {
+			return fmt.Errorf("capacity rule %d: duplicate rule for the same weekday and order type", i+1)
+		}
+		seen[key] = true
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"tsb-service/pkg/timezone"
+)
+
+func TestSlotCapacityRulesFor(t *testing.T) {
+	capacity := SlotCapacity{
+		{MaxOrders: 6},
+		{Weekday: "friday", MaxOrders: 10},
+		{OrderType: "DELIVERY", MaxItems: 20},
+		{Weekday: "saturday", OrderType: "DELIVERY", MaxItems: 30},
+	}
+
+	// 2026-07-10 is a Friday.
+	friday := time.Date(2026, 7, 10, 19, 0, 0, 0, timezone.Location)
+	rules := capacity.RulesFor(friday)
+	if len(rules) != 2 || rules[0].MaxOrders != 10 || rules[1].MaxItems != 20 {
+		t.Errorf("friday rules = %+v, want the friday whole-slot rule and the every-day delivery rule", rules)
+	}
+
+	saturday := friday.AddDate(0, 0, 1)
+	rules = capacity.RulesFor(saturday)
+	if len(rules) != 2 || rules[0].MaxOrders != 6 || rules[1].MaxItems != 30 {
+		t.Errorf("saturday rules = %+v, want the every-day whole-slot rule and the saturday delivery rule", rules)
+	}
+}
+
+func TestSlotCapacityValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		rules   SlotCapacity
+		wantErr bool
+	}{
+		{"empty", nil, false},
+		{"valid", SlotCapacity{{MaxOrders: 5}, {Weekday: "monday", OrderType: "PICKUP", MaxItems: 12}}, false},
+		{"unknown weekday", SlotCapacity{{Weekday: "funday", MaxOrders: 5}}, true},
+		{"unknown order type", SlotCapacity{{OrderType: "CATERING", MaxOrders: 5}}, true},
+		{"negative", SlotCapacity{{MaxOrders: -1}}, true},
+		{"duplicate scope", SlotCapacity{{MaxOrders: 5}, {MaxItems: 10}}, true},
+	}
+	for _, tc := range cases {
+		if err := tc.rules.Validate(); (err != nil) != tc.wantErr {
+			t.Errorf("%s: Validate() = %v, wantErr %v", tc.name, err, tc.wantErr)
+		}
+	}
+}
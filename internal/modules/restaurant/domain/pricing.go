@@ -0,0 +1,131 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// DeliveryFeeTier charges Fee for deliveries strictly closer than
+// MaxDistanceMeters. Tiers are sorted by distance; the last tier's bound is
+// the delivery radius.
+type DeliveryFeeTier struct {
+	MaxDistanceMeters int             `json:"maxDistanceMeters"`
+	Fee               decimal.Decimal `json:"fee"`
+}
+
+// PricingRules holds the admin-editable pricing knobs, stored as JSONB in the
+// restaurant_config row next to the opening hours. The delivery tiers, radius
+// and postcode blocklist only apply while no DeliveryZone is active.
+type PricingRules struct {
+	DeliveryFeeTiers          []DeliveryFeeTier `json:"deliveryFeeTiers"`
+	ExcludedDeliveryPostcodes []string          `json:"excludedDeliveryPostcodes"`
+	DeliveryMinimum           decimal.Decimal   `json:"deliveryMinimum"`
+	PickupMinimum             decimal.Decimal   `json:"pickupMinimum"`
+	// TakeawayDiscountPercent applies to discountable items of PICKUP orders
+	// once the items subtotal reaches TakeawayDiscountThreshold.
+	TakeawayDiscountPercent   decimal.Decimal `json:"takeawayDiscountPercent"`
+	TakeawayDiscountThreshold decimal.Decimal `json:"takeawayDiscountThreshold"`
+	// TransactionFee is charged on online (Mollie) payments to cover PSP costs.
+	TransactionFee decimal.Decimal `json:"transactionFee"`
+}
+
+// DefaultPricingRules mirrors the rules that were hardcoded before pricing
+// became configurable. Used when the stored JSON is missing.
+func DefaultPricingRules() PricingRules {
+	tiers := make([]DeliveryFeeTier, 0, 7)
+	for i, maxDistance := range []int{3000, 4000, 5000, 6000, 7000, 8000, 9000} {
+		tiers = append(tiers, DeliveryFeeTier{MaxDistanceMeters: maxDistance, Fee: decimal.NewFromInt(int64(i))})
+	}
+	return PricingRules{
+		DeliveryFeeTiers:          tiers,
+		ExcludedDeliveryPostcodes: []string{"4610"}, // Beyne-Heusay
+		DeliveryMinimum:           decimal.NewFromInt(25),
+		PickupMinimum:             decimal.Zero,
+		TakeawayDiscountPercent:   decimal.NewFromInt(10),
+		TakeawayDiscountThreshold: decimal.NewFromInt(20),
+		TransactionFee:            decimal.NewFromFloatWithExponent(0.30, -2),
+	}
+}
+
+// GetPricing parses the JSONB pricing column. Falls back to the defaults when
+// the column is not set.
+func (c *RestaurantConfig) GetPricing() (PricingRules, error) {
+	if len(c.Pricing) == 0 || string(c.Pricing) == "null" {
+		return DefaultPricingRules(), nil
+	}
+	var rules PricingRules
+	if err := json.Unmarshal(c.Pricing, &rules); err != nil {
+		return PricingRules{}, fmt.Errorf("parse pricing rules: %w", err)
+	}
+	return rules, nil
+}
+
+// MaxDeliveryDistanceMeters is the delivery radius: the last tier's bound.
+func (p PricingRules) MaxDeliveryDistanceMeters() int {
+	if len(p.DeliveryFeeTiers) == 0 {
+		return 0
+	}
+	return p.DeliveryFeeTiers[len(p.DeliveryFeeTiers)-1].MaxDistanceMeters
+}
+
+// DeliveryFee returns the fee for a delivery at distance meters, and false
+// when the address is outside the delivery radius.
+func (p PricingRules) DeliveryFee(distance float64) (decimal.Decimal, bool) {
+	for _, tier := range p.DeliveryFeeTiers {
+		if distance < float64(tier.MaxDistanceMeters) {
+			return tier.Fee, true
+		}
+	}
+	return decimal.Zero, false
+}
+
+// IsExcludedPostcode reports whether we never deliver to the postcode,
+// regardless of distance.
+func (p PricingRules) IsExcludedPostcode(postcode string) bool {
+	return slices.Contains(p.ExcludedDeliveryPostcodes, strings.TrimSpace(postcode))
+}
+
+// TakeawayDiscountRate returns the takeaway discount as a fraction (0.10 for 10%).
+func (p PricingRules) TakeawayDiscountRate() decimal.Decimal {
+	return p.TakeawayDiscountPercent.Div(decimal.NewFromInt(100))
+}
+
+// Validate rejects rules that would break checkout: unsorted or negative
+// tiers, negative amounts, or a discount outside 0–100%.
+func (p PricingRules) Validate() error {
+	if len(p.DeliveryFeeTiers) == 0 {
+		return fmt.Errorf("at least one delivery fee tier is required")
+	}
+	prev := 0
+	for i, tier := range p.DeliveryFeeTiers {
+		if tier.MaxDistanceMeters <= prev {
+			return fmt.Errorf("delivery fee tier %d: distances must be positive and strictly increasing", i+1)
+		}
+		if tier.Fee.IsNegative() {
+			return fmt.Errorf("delivery fee tier %d: fee cannot be negative", i+1)
+		}
+		prev = tier.MaxDistanceMeters
+	}
+	for _, pc := range p.ExcludedDeliveryPostcodes {
+		if strings.TrimSpace(pc) == "" {
+			return fmt.Errorf("excluded postcodes cannot be blank")
+		}
+	}
+	if p.DeliveryMinimum.IsNegative() || p.PickupMinimum.IsNegative() {
+		return fmt.Errorf("minimum order amounts cannot be negative")
+	}
+	if p.TakeawayDiscountPercent.IsNegative() || p.TakeawayDiscountPercent.GreaterThan(decimal.NewFromInt(100)) {
+		return fmt.Errorf("takeaway discount must be between 0 and 100 percent")
+	}
+	if p.TakeawayDiscountThreshold.IsNegative() {
+		return fmt.Errorf("takeaway discount threshold cannot be negative")
+	}
+	if p.TransactionFee.IsNegative() {
+		return fmt.Errorf("transaction fee cannot be negative")
+	}
+	return nil
+}
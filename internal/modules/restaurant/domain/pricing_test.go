@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestGetPricingFallsBackToDefaults(t *testing.T) {
+	for _, raw := range []json.RawMessage{nil, json.RawMessage("null")} {
+		cfg := &RestaurantConfig{Pricing: raw}
+		rules, err := cfg.GetPricing()
+		if err != nil {
+			t.Fatalf("GetPricing(%q): %v", raw, err)
+		}
+		if got := rules.MaxDeliveryDistanceMeters(); got != 9000 {
+			t.Errorf("default radius = %d, want 9000", got)
+		}
+		if !rules.DeliveryMinimum.Equal(decimal.NewFromInt(25)) {
+			t.Errorf("default delivery minimum = %s, want 25", rules.DeliveryMinimum)
+		}
+	}
+}
+
+func TestGetPricingRoundTrip(t *testing.T) {
+	want := DefaultPricingRules()
+	want.TakeawayDiscountPercent = decimal.NewFromInt(15)
+	raw, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	got, err := (&RestaurantConfig{Pricing: raw}).GetPricing()
+	if err != nil {
+		t.Fatalf("GetPricing: %v", err)
+	}
+	if !got.TakeawayDiscountRate().Equal(decimal.RequireFromString("0.15")) {
+		t.Errorf("takeaway rate = %s, want 0.15", got.TakeawayDiscountRate())
+	}
+}
+
+func TestDeliveryFee(t *testing.T) {
+	rules := DefaultPricingRules()
+	cases := []struct {
+		distance float64
+		wantFee  string
+		wantOK   bool
+	}{
+		{0, "0", true},
+		{2999, "0", true},
+		{3000, "1", true},
+		{8999.9, "6", true},
+		{9000, "0", false},
+		{15000, "0", false},
+	}
+	for _, tc := range cases {
+		fee, ok := rules.DeliveryFee(tc.distance)
+		if ok != tc.wantOK || !fee.Equal(decimal.RequireFromString(tc.wantFee)) {
+			t.Errorf("DeliveryFee(%v) = (%s, %v), want (%s, %v)", tc.distance, fee, ok, tc.wantFee, tc.wantOK)
+		}
+	}
+}
+
+func TestIsExcludedPostcode(t *testing.T) {
+	rules := DefaultPricingRules()
+	if !rules.IsExcludedPostcode(" 4610 ") {
+		t.Error("expected 4610 to be excluded")
+	}
+	if rules.IsExcludedPostcode("4000") {
+		t.Error("expected 4000 to be deliverable")
+	}
+}
+
+func TestPricingRulesValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		mutate  func(*PricingRules)
+		wantErr bool
+	}{
+		{"defaults are valid", func(*PricingRules) {}, false},
+		{"no tiers", func(p *PricingRules) { p.DeliveryFeeTiers = nil }, true},
+		{"unsorted tiers", func(p *PricingRules) {
+			p.DeliveryFeeTiers[1].MaxDistanceMeters = p.DeliveryFeeTiers[0].MaxDistanceMeters
+		}, true},
+		{"negative fee", func(p *PricingRules) { p.DeliveryFeeTiers[0].Fee = decimal.NewFromInt(-1) }, true},
+		{"blank postcode", func(p *PricingRules) { p.ExcludedDeliveryPostcodes = []string{" "} }, true},
+		{"negative minimum", func(p *PricingRules) { p.PickupMinimum = decimal.NewFromInt(-5) }, true},
+		{"discount above 100", func(p *PricingRules) { p.TakeawayDiscountPercent = decimal.NewFromInt(101) }, true},
+		{"negative transaction fee", func(p *PricingRules) { p.TransactionFee = decimal.NewFromInt(-1) }, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rules := DefaultPricingRules()
+			tc.mutate(&rules)
+			err := rules.Validate()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
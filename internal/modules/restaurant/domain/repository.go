@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type RestaurantRepository interface {
@@ -12,6 +14,12 @@ type RestaurantRepository interface {
 	UpdateOpeningHours(ctx context.Context, hours json.RawMessage) (*RestaurantConfig, error)
 	UpdateOrderingHours(ctx context.Context, hours json.RawMessage) (*RestaurantConfig, error)
 	UpdatePreparationMinutes(ctx context.Context, minutes int) (*RestaurantConfig, error)
+	UpdatePricing(ctx context.Context, pricing json.RawMessage) (*RestaurantConfig, error)
+	UpdateScheduling(ctx context.Context, horizonDays, leadMinutes int) (*RestaurantConfig, error)
+	UpdateCancellationGrace(ctx context.Context, minutes int) (*RestaurantConfig, error)
+	UpdateAutoApplyEta(ctx context.Context, enabled bool) (*RestaurantConfig, error)
+	UpdateUnconfirmedTimeouts(ctx context.Context, escalationMinutes, cancelMinutes int) (*RestaurantConfig, error)
+	UpdateSlotCapacity(ctx context.Context, capacity json.RawMessage) (*RestaurantConfig, error)
 }
 
 type ScheduleOverrideRepository interface {
@@ -21,3 +29,11 @@ type ScheduleOverrideRepository interface {
 	Upsert(ctx context.Context, override *ScheduleOverride) (*ScheduleOverride, error)
 	Delete(ctx context.Context, date time.Time) error
 }
+
+type DeliveryZoneRepository interface {
+	List(ctx context.Context) ([]*DeliveryZone, error)
+	Get(ctx context.Context, id uuid.UUID) (*DeliveryZone, error)
+	Create(ctx context.Context, zone *DeliveryZone) (*DeliveryZone, error)
+	Update(ctx context.Context, zone *DeliveryZone) (*DeliveryZone, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
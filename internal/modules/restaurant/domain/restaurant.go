@@ -27,7 +27,42 @@ type RestaurantConfig struct {
 	OpeningHours       json.RawMessage `db:"opening_hours" json:"openingHours"`
 	OrderingHours      json.RawMessage `db:"ordering_hours" json:"orderingHours"`
 	PreparationMinutes int             `db:"preparation_minutes" json:"preparationMinutes"`
-	UpdatedAt          time.Time       `db:"updated_at" json:"updatedAt"`
+	Pricing            json.RawMessage `db:"pricing" json:"pricing"`
+	// SchedulingHorizonDays is how many days past today customers may pick a
+	// ready time for; 0 keeps ordering same-day only.
+	SchedulingHorizonDays int `db:"scheduling_horizon_days" json:"schedulingHorizonDays"`
+	// ScheduledOrderLeadMinutes is how long before its ready time a scheduled
+	// order appears in the staff's live order list.
+	ScheduledOrderLeadMinutes int             `db:"scheduled_order_lead_minutes" json:"scheduledOrderLeadMinutes"`
+	SlotCapacity              json.RawMessage `db:"slot_capacity" json:"slotCapacity"`
+	// CancellationGraceMinutes is how long after placing it a customer may
+	// still cancel a confirmed order themselves.
+	CancellationGraceMinutes int `db:"cancellation_grace_minutes" json:"cancellationGraceMinutes"`
+	// AutoApplyEta makes confirming an order without a ready time apply the
+	// suggested one.
+	AutoApplyEta bool `db:"auto_apply_eta" json:"autoApplyEta"`
+	// UnconfirmedEscalationMinutes is how long an order may wait for staff
+	// confirmation before staff are alerted again; 0 disables the reminder.
+	UnconfirmedEscalationMinutes int `db:"unconfirmed_escalation_minutes" json:"unconfirmedEscalationMinutes"`
+	// UnconfirmedCancelMinutes is how long an order may wait for staff
+	// confirmation before it is cancelled; 0 disables the cancellation.
+	UnconfirmedCancelMinutes int       `db:"unconfirmed_cancel_minutes" json:"unconfirmedCancelMinutes"`
+	UpdatedAt                time.Time `db:"updated_at" json:"updatedAt"`
+}
+
+// CancellationGrace is CancellationGraceMinutes as a duration.
+func (c *RestaurantConfig) CancellationGrace() time.Duration {
+	return time.Duration(max(c.CancellationGraceMinutes, 0)) * time.Minute
+}
+
+// UnconfirmedEscalation is UnconfirmedEscalationMinutes as a duration.
+func (c *RestaurantConfig) UnconfirmedEscalation() time.Duration {
+	return time.Duration(max(c.UnconfirmedEscalationMinutes, 0)) * time.Minute
+}
+
+// UnconfirmedCancel is UnconfirmedCancelMinutes as a duration.
+func (c *RestaurantConfig) UnconfirmedCancel() time.Duration {
+	return time.Duration(max(c.UnconfirmedCancelMinutes, 0)) * time.Minute
 }
 
 // GetOpeningHours parses the JSONB opening_hours into a typed map.
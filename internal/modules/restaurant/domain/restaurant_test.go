@@ -189,6 +189,52 @@ func TestNextOpeningAt_SkipsOverrideClosedDay(t *testing.T) {
 	}
 }
 
+func TestAvailableSlots_FutureDayUsesFullService(t *testing.T) {
+	cfg := configWith(t, weeklyHours(t), 30)
+	cfg.SchedulingHorizonDays = 7
+
+	// Thu 2026-04-23 at 21:00 ordering for Sat 2026-04-25: the whole Saturday
+	// grid is bookable, starting at open + prep.
+	slots := cfg.AvailableSlots(at(t, "2026-04-23", "21:00"), at(t, "2026-04-25", "00:00"), nil)
+	if len(slots) == 0 {
+		t.Fatalf("expected Saturday slots")
+	}
+	if slots[0].Label != "12:30" || slots[len(slots)-1].Label != "23:00" {
+		t.Errorf("expected 12:30–23:00, got %s–%s", slots[0].Label, slots[len(slots)-1].Label)
+	}
+	if slots[0].IsLunchOnlyAllowed {
+		t.Errorf("lunch-only items are weekdays only")
+	}
+}
+
+func TestAvailableSlots_HonorsOverrideOnThatDay(t *testing.T) {
+	cfg := configWith(t, weeklyHours(t), 30)
+	cfg.SchedulingHorizonDays = 7
+	overrides := map[string]*ScheduleOverride{
+		"2026-04-25": {Date: at(t, "2026-04-25", "00:00"), Closed: true},
+	}
+
+	if slots := cfg.AvailableSlots(at(t, "2026-04-23", "12:00"), at(t, "2026-04-25", "12:00"), overrides); len(slots) != 0 {
+		t.Errorf("expected no slots on a closed override day, got %d", len(slots))
+	}
+}
+
+func TestAvailableSlots_OutsideHorizon(t *testing.T) {
+	cfg := configWith(t, weeklyHours(t), 30)
+	cfg.SchedulingHorizonDays = 2
+	now := at(t, "2026-04-22", "10:00")
+
+	if slots := cfg.AvailableSlots(now, at(t, "2026-04-24", "00:00"), nil); len(slots) == 0 {
+		t.Errorf("expected slots on the last day of the horizon")
+	}
+	if slots := cfg.AvailableSlots(now, at(t, "2026-04-25", "00:00"), nil); len(slots) != 0 {
+		t.Errorf("expected no slots past the horizon, got %d", len(slots))
+	}
+	if slots := cfg.AvailableSlots(now, at(t, "2026-04-20", "12:00"), nil); len(slots) != 0 {
+		t.Errorf("expected no slots in the past, got %d", len(slots))
+	}
+}
+
 // TestReviewSlotsToday_ProducesSlotsWhenClosed verifies the store-review bypass:
 // even when the real slot list is empty (restaurant closed), ReviewSlotsToday
 // hands a reviewer a window of bookable fixed-time slots. TEMPORARY feature.
@@ -18,10 +18,20 @@ type TimeSlot struct {
 const slotStepMinutes = 15
 
 // AvailableSlotsToday returns all ordering slots that are still bookable
-// for the current local day, honoring overrides, ordering hours (or opening
-// hours fallback) and the configured preparation buffer. Slots are rounded
-// up to the next quarter-hour.
+// for the current local day. See AvailableSlots.
 func (c *RestaurantConfig) AvailableSlotsToday(now time.Time, overrides map[string]*ScheduleOverride) []TimeSlot {
+	return c.AvailableSlots(now, now, overrides)
+}
+
+// AvailableSlots returns all ordering slots that are still bookable on the
+// local day of `date`, honoring overrides, ordering hours (or opening hours
+// fallback) and the configured preparation buffer counted from `now`. Slots
+// are rounded up to the next quarter-hour. Days before today or beyond the
+// scheduling horizon have no slots.
+func (c *RestaurantConfig) AvailableSlots(now, date time.Time, overrides map[string]*ScheduleOverride) []TimeSlot {
+	if !c.IsWithinSchedulingHorizon(now, date) {
+		return nil
+	}
 	orderingHours, err := c.GetOrderingHours()
 	if err != nil {
 		return nil
@@ -36,12 +46,13 @@ func (c *RestaurantConfig) AvailableSlotsToday(now time.Time, overrides map[stri
 		}
 	}
 
-	schedule, _ := resolveDaySchedule(now, hours, overrides)
+	schedule, _ := resolveDaySchedule(date, hours, overrides)
 	if schedule == nil {
 		return nil
 	}
 
 	local := timezone.In(now)
+	day := timezone.In(date)
 	prep := c.PreparationMinutes
 	if prep <= 0 {
 		prep = 30
@@ -65,8 +76,8 @@ func (c *RestaurantConfig) AvailableSlotsToday(now time.Time, overrides map[stri
 		// The "openPlusPreparation" rule enforces that the first slot of a
 		// service cannot be sooner than (open + prep), even if `now` is much
 		// earlier in the day.
-		openPlusPrep := atLocalMinutes(local, openMins+prep)
-		intervalEnd := atLocalMinutes(local, closeMins)
+		openPlusPrep := atLocalMinutes(day, openMins+prep)
+		intervalEnd := atLocalMinutes(day, closeMins)
 		if intervalEnd.Before(openPlusPrep) {
 			continue
 		}
@@ -91,6 +102,28 @@ func (c *RestaurantConfig) AvailableSlotsToday(now time.Time, overrides map[stri
 	return slots
 }
 
+// IsWithinSchedulingHorizon reports whether t falls on today or on one of
+// the next SchedulingHorizonDays local days.
+func (c *RestaurantConfig) IsWithinSchedulingHorizon(now, t time.Time) bool {
+	days := localDaysBetween(now, t)
+	return days >= 0 && days <= max(c.SchedulingHorizonDays, 0)
+}
+
+// ScheduledOrderLead is how long before its ready time a scheduled order is
+// released to the staff's live order list.
+func (c *RestaurantConfig) ScheduledOrderLead() time.Duration {
+	return time.Duration(max(c.ScheduledOrderLeadMinutes, 0)) * time.Minute
+}
+
+// localDaysBetween counts calendar days from a to b in the restaurant
+// timezone, ignoring the time of day (DST-safe).
+func localDaysBetween(a, b time.Time) int {
+	la, lb := timezone.In(a), timezone.In(b)
+	da := time.Date(la.Year(), la.Month(), la.Day(), 0, 0, 0, 0, time.UTC)
+	db := time.Date(lb.Year(), lb.Month(), lb.Day(), 0, 0, 0, 0, time.UTC)
+	return int(db.Sub(da).Hours() / 24)
+}
+
 // reviewSlotCount is how many synthetic review slots to offer (2h of quarters).
 const reviewSlotCount = 8
 
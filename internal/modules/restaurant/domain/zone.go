@@ -0,0 +1,190 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// ErrOutsideDeliveryZones is returned when delivery zones are configured but
+// none of the active ones contains the address.
+var ErrOutsideDeliveryZones = errors.New("address is outside every delivery zone")
+
+// DeliveryZone is an area we deliver to, drawn by admins as a GeoJSON
+// Polygon or MultiPolygon. Each zone carries its own fee and minimum order,
+// and can ask for extra preparation time for the far side of town.
+type DeliveryZone struct {
+	ID                      uuid.UUID       `db:"id"`
+	Name                    string          `db:"name"`
+	Geometry                json.RawMessage `db:"geometry"`
+	DeliveryFee             decimal.Decimal `db:"delivery_fee"`
+	MinimumOrder            decimal.Decimal `db:"minimum_order"`
+	ExtraPreparationMinutes int             `db:"extra_preparation_minutes"`
+	IsActive                bool            `db:"is_active"`
+	CreatedAt               time.Time       `db:"created_at"`
+	UpdatedAt               time.Time       `db:"updated_at"`
+
+	polygons [][]ring
+}
+
+// ring is a closed linear ring of [lng, lat] positions, as in GeoJSON.
+type ring [][2]float64
+
+// NewDeliveryZone validates the inputs and returns a zone ready to insert.
+func NewDeliveryZone(name string, geometry json.RawMessage, fee, minimum decimal.Decimal, extraPrepMinutes int, active bool) (*DeliveryZone, error) {
+	z := &DeliveryZone{
+		ID:                      uuid.New(),
+		Name:                    strings.TrimSpace(name),
+		Geometry:                geometry,
+		DeliveryFee:             fee,
+		MinimumOrder:            minimum,
+		ExtraPreparationMinutes: extraPrepMinutes,
+		IsActive:                active,
+	}
+	if err := z.Validate(); err != nil {
+		return nil, err
+	}
+	return z, nil
+}
+
+// Validate checks the zone's fields and that its geometry parses.
+func (z *DeliveryZone) Validate() error {
+	if z.Name == "" {
+		return errors.New("zone name is required")
+	}
+	if z.DeliveryFee.IsNegative() {
+		return errors.New("delivery fee cannot be negative")
+	}
+	if z.MinimumOrder.IsNegative() {
+		return errors.New("minimum order cannot be negative")
+	}
+	if z.ExtraPreparationMinutes < 0 || z.ExtraPreparationMinutes > 240 {
+		return errors.New("extra preparation minutes must be between 0 and 240")
+	}
+	polygons, err := parseZoneGeometry(z.Geometry)
+	if err != nil {
+		return err
+	}
+	z.polygons = polygons
+	return nil
+}
+
+// Contains reports whether the point lies inside the zone. Points inside a
+// polygon hole are outside the zone.
+func (z *DeliveryZone) Contains(lat, lng float64) (bool, error) {
+	if z.polygons == nil {
+		polygons, err := parseZoneGeometry(z.Geometry)
+		if err != nil {
+			return false, fmt.Errorf("zone %q: %w", z.Name, err)
+		}
+		z.polygons = polygons
+	}
+	for _, rings := range z.polygons {
+		if !rings[0].contains(lat, lng) {
+			continue
+		}
+		inHole := false
+		for _, hole := range rings[1:] {
+			if hole.contains(lat, lng) {
+				inHole = true
+				break
+			}
+		}
+		if !inHole {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// FindDeliveryZone returns the active zone containing the point. When zones
+// overlap the cheapest one wins, so drawing a generous outer zone never
+// makes the inner one more expensive.
+func FindDeliveryZone(zones []*DeliveryZone, lat, lng float64) (*DeliveryZone, error) {
+	var best *DeliveryZone
+	for _, z := range zones {
+		if !z.IsActive {
+			continue
+		}
+		ok, err := z.Contains(lat, lng)
+		if err != nil {
+			return nil, err
+		}
+		if ok && (best == nil || z.DeliveryFee.LessThan(best.DeliveryFee)) {
+			best = z
+		}
+	}
+	if best == nil {
+		return nil, ErrOutsideDeliveryZones
+	}
+	return best, nil
+}
+
+// contains runs the even-odd ray casting test. GeoJSON positions are
+// [lng, lat]; at city scale treating them as planar coordinates is exact
+// enough.
+func (r ring) contains(lat, lng float64) bool {
+	inside := false
+	for i, j := 0, len(r)-1; i < len(r); j, i = i, i+1 {
+		xi, yi := r[i][0], r[i][1]
+		xj, yj := r[j][0], r[j][1]
+		if (yi > lat) != (yj > lat) && lng < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// parseZoneGeometry accepts a GeoJSON Polygon or MultiPolygon geometry (a
+// bare geometry, or a Feature wrapping one) and returns its polygons.
+func parseZoneGeometry(raw json.RawMessage) ([][]ring, error) {
+	var g struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+		Geometry    json.RawMessage `json:"geometry"`
+	}
+	if len(raw) == 0 {
+		return nil, errors.New("zone geometry is required")
+	}
+	if err := json.Unmarshal(raw, &g); err != nil {
+		return nil, fmt.Errorf("invalid GeoJSON: %w", err)
+	}
+
+	var polygons [][]ring
+	switch g.Type {
+	case "Feature":
+		return parseZoneGeometry(g.Geometry)
+	case "Polygon":
+		var p []ring
+		if err := json.Unmarshal(g.Coordinates, &p); err != nil {
+			return nil, fmt.Errorf("invalid Polygon coordinates: %w", err)
+		}
+		polygons = [][]ring{p}
+	case "MultiPolygon":
+		if err := json.Unmarshal(g.Coordinates, &polygons); err != nil {
+			return nil, fmt.Errorf("invalid MultiPolygon coordinates: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("zone geometry must be a Polygon or MultiPolygon, got %q", g.Type)
+	}
+
+	if len(polygons) == 0 {
+		return nil, errors.New("zone geometry has no polygons")
+	}
+	for _, rings := range polygons {
+		if len(rings) == 0 {
+			return nil, errors.New("zone polygon has no rings")
+		}
+		for _, r := range rings {
+			if len(r) < 4 || r[0] != r[len(r)-1] {
+				return nil, errors.New("zone polygon rings must be closed and have at least 4 positions")
+			}
+		}
+	}
+	return polygons, nil
+}
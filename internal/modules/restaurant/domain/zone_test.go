@@ -0,0 +1,118 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// square returns a closed GeoJSON ring around (lng, lat) with half-side d.
+func square(lng, lat, d float64) [][2]float64 {
+	return [][2]float64{
+		{lng - d, lat - d}, {lng + d, lat - d}, {lng + d, lat + d}, {lng - d, lat + d}, {lng - d, lat - d},
+	}
+}
+
+func zoneWith(t *testing.T, name string, fee int64, geometry map[string]any) *DeliveryZone {
+	t.Helper()
+	raw, err := json.Marshal(geometry)
+	if err != nil {
+		t.Fatalf("marshal geometry: %v", err)
+	}
+	z, err := NewDeliveryZone(name, raw, decimal.NewFromInt(fee), decimal.NewFromInt(20), 0, true)
+	if err != nil {
+		t.Fatalf("NewDeliveryZone(%s): %v", name, err)
+	}
+	return z
+}
+
+func TestDeliveryZoneContains(t *testing.T) {
+	// Centre of Liège, with a hole around the central point.
+	z := zoneWith(t, "centre", 0, map[string]any{
+		"type":        "Polygon",
+		"coordinates": [][][2]float64{square(5.57, 50.63, 0.05), square(5.57, 50.63, 0.005)},
+	})
+
+	cases := []struct {
+		name     string
+		lat, lng float64
+		want     bool
+	}{
+		{"inside", 50.65, 5.59, true},
+		{"outside", 50.75, 5.59, false},
+		{"inside the hole", 50.63, 5.57, false},
+	}
+	for _, tc := range cases {
+		got, err := z.Contains(tc.lat, tc.lng)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: Contains(%v, %v) = %v, want %v", tc.name, tc.lat, tc.lng, got, tc.want)
+		}
+	}
+}
+
+func TestDeliveryZoneMultiPolygonAndFeature(t *testing.T) {
+	z := zoneWith(t, "two banks", 3, map[string]any{
+		"type": "Feature",
+		"geometry": map[string]any{
+			"type":        "MultiPolygon",
+			"coordinates": [][][][2]float64{{square(5.50, 50.60, 0.01)}, {square(5.70, 50.60, 0.01)}},
+		},
+	})
+	for _, lng := range []float64{5.50, 5.70} {
+		if ok, _ := z.Contains(50.60, lng); !ok {
+			t.Errorf("expected lng %v to be inside", lng)
+		}
+	}
+	if ok, _ := z.Contains(50.60, 5.60); ok {
+		t.Error("expected the gap between polygons to be outside")
+	}
+}
+
+func TestFindDeliveryZone(t *testing.T) {
+	outer := zoneWith(t, "outer", 4, map[string]any{"type": "Polygon", "coordinates": [][][2]float64{square(5.57, 50.63, 0.1)}})
+	inner := zoneWith(t, "inner", 1, map[string]any{"type": "Polygon", "coordinates": [][][2]float64{square(5.57, 50.63, 0.02)}})
+	inactive := zoneWith(t, "inactive", 0, map[string]any{"type": "Polygon", "coordinates": [][][2]float64{square(5.57, 50.63, 0.02)}})
+	inactive.IsActive = false
+	zones := []*DeliveryZone{outer, inner, inactive}
+
+	if z, err := FindDeliveryZone(zones, 50.63, 5.57); err != nil || z != inner {
+		t.Fatalf("overlap: got %v, %v; want the cheaper inner zone", z, err)
+	}
+	if z, err := FindDeliveryZone(zones, 50.70, 5.57); err != nil || z != outer {
+		t.Fatalf("outer ring: got %v, %v; want outer", z, err)
+	}
+	if _, err := FindDeliveryZone(zones, 51.00, 5.57); !errors.Is(err, ErrOutsideDeliveryZones) {
+		t.Fatalf("far away: expected ErrOutsideDeliveryZones, got %v", err)
+	}
+}
+
+func TestNewDeliveryZoneValidation(t *testing.T) {
+	valid, _ := json.Marshal(map[string]any{"type": "Polygon", "coordinates": [][][2]float64{square(5.57, 50.63, 0.1)}})
+	open, _ := json.Marshal(map[string]any{"type": "Polygon", "coordinates": [][][2]float64{square(5.57, 50.63, 0.1)[:4]}})
+	point, _ := json.Marshal(map[string]any{"type": "Point", "coordinates": [2]float64{5.57, 50.63}})
+
+	cases := []struct {
+		name     string
+		zoneName string
+		geometry json.RawMessage
+		fee      int64
+		extra    int
+	}{
+		{"blank name", " ", valid, 0, 0},
+		{"negative fee", "z", valid, -1, 0},
+		{"extra minutes out of range", "z", valid, 0, 300},
+		{"open ring", "z", open, 0, 0},
+		{"not a polygon", "z", point, 0, 0},
+		{"missing geometry", "z", nil, 0, 0},
+	}
+	for _, tc := range cases {
+		if _, err := NewDeliveryZone(tc.zoneName, tc.geometry, decimal.NewFromInt(tc.fee), decimal.Zero, tc.extra, true); err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+	}
+}
@@ -7,7 +7,7 @@ import (
 	"tsb-service/pkg/db"
 )
 
-const configColumns = `ordering_enabled, opening_hours, COALESCE(ordering_hours, 'null'::jsonb) AS ordering_hours, preparation_minutes, updated_at`
+const configColumns = `ordering_enabled, opening_hours, COALESCE(ordering_hours, 'null'::jsonb) AS ordering_hours, preparation_minutes, pricing, scheduling_horizon_days, scheduled_order_lead_minutes, slot_capacity, cancellation_grace_minutes, auto_apply_eta, unconfirmed_escalation_minutes, unconfirmed_cancel_minutes, updated_at`
 
 type RestaurantRepository struct {
 	pool *db.DBPool
@@ -70,3 +70,69 @@ func (r *RestaurantRepository) UpdatePreparationMinutes(ctx context.Context, min
 	}
 	return &config, nil
 }
+
+func (r *RestaurantRepository) UpdatePricing(ctx context.Context, pricing json.RawMessage) (*domain.RestaurantConfig, error) {
+	var config domain.RestaurantConfig
+	err := r.pool.ForContext(ctx).GetContext(ctx, &config,
+		`UPDATE restaurant_config SET pricing = $1, updated_at = NOW() WHERE id = TRUE
+		 RETURNING `+configColumns, pricing)
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (r *RestaurantRepository) UpdateScheduling(ctx context.Context, horizonDays, leadMinutes int) (*domain.RestaurantConfig, error) {
+	var config domain.RestaurantConfig
+	err := r.pool.ForContext(ctx).GetContext(ctx, &config,
+		`UPDATE restaurant_config SET scheduling_horizon_days = $1, scheduled_order_lead_minutes = $2, updated_at = NOW() WHERE id = TRUE
+		 RETURNING `+configColumns, horizonDays, leadMinutes)
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (r *RestaurantRepository) UpdateCancellationGrace(ctx context.Context, minutes int) (*domain.RestaurantConfig, error) {
+	var config domain.RestaurantConfig
+	err := r.pool.ForContext(ctx).GetContext(ctx, &config,
+		`UPDATE restaurant_config SET cancellation_grace_minutes = $1, updated_at = NOW() WHERE id = TRUE
+		 RETURNING `+configColumns, minutes)
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (r *RestaurantRepository) UpdateAutoApplyEta(ctx context.Context, enabled bool) (*domain.RestaurantConfig, error) {
+	var config domain.RestaurantConfig
+	err := r.pool.ForContext(ctx).GetContext(ctx, &config,
+		`UPDATE restaurant_config SET auto_apply_eta = $1, updated_at = NOW() WHERE id = TRUE
+		 RETURNING `+configColumns, enabled)
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (r *RestaurantRepository) UpdateUnconfirmedTimeouts(ctx context.Context, escalationMinutes, cancelMinutes int) (*domain.RestaurantConfig, error) {
+	var config domain.RestaurantConfig
+	err := r.pool.ForContext(ctx).GetContext(ctx, &config,
+		`UPDATE restaurant_config SET unconfirmed_escalation_minutes = $1, unconfirmed_cancel_minutes = $2, updated_at = NOW() WHERE id = TRUE
+		 RETURNING `+configColumns, escalationMinutes, cancelMinutes)
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (r *RestaurantRepository) UpdateSlotCapacity(ctx context.Context, capacity json.RawMessage) (*domain.RestaurantConfig, error) {
+	var config domain.RestaurantConfig
+	err := r.pool.ForContext(ctx).GetContext(ctx, &config,
+		`UPDATE restaurant_config SET slot_capacity = $1, updated_at = NOW() WHERE id = TRUE
+		 RETURNING `+configColumns, capacity)
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
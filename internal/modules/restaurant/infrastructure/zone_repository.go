@@ -0,0 +1,79 @@
+package infrastructure
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"tsb-service/internal/modules/restaurant/domain"
+	"tsb-service/pkg/db"
+)
+
+const zoneColumns = `id, name, geometry, delivery_fee, minimum_order, extra_preparation_minutes, is_active, created_at, updated_at`
+
+type DeliveryZoneRepository struct {
+	pool *db.DBPool
+}
+
+func NewDeliveryZoneRepository(pool *db.DBPool) domain.DeliveryZoneRepository {
+	return &DeliveryZoneRepository{pool: pool}
+}
+
+func (r *DeliveryZoneRepository) List(ctx context.Context) ([]*domain.DeliveryZone, error) {
+	var out []*domain.DeliveryZone
+	err := r.pool.ForContext(ctx).SelectContext(ctx, &out,
+		`SELECT `+zoneColumns+` FROM delivery_zones ORDER BY name ASC`)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *DeliveryZoneRepository) Get(ctx context.Context, id uuid.UUID) (*domain.DeliveryZone, error) {
+	var z domain.DeliveryZone
+	err := r.pool.ForContext(ctx).GetContext(ctx, &z,
+		`SELECT `+zoneColumns+` FROM delivery_zones WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	return &z, nil
+}
+
+func (r *DeliveryZoneRepository) Create(ctx context.Context, z *domain.DeliveryZone) (*domain.DeliveryZone, error) {
+	var out domain.DeliveryZone
+	err := r.pool.ForContext(ctx).GetContext(ctx, &out,
+		`INSERT INTO delivery_zones (id, name, geometry, delivery_fee, minimum_order, extra_preparation_minutes, is_active, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		 RETURNING `+zoneColumns,
+		z.ID, z.Name, z.Geometry, z.DeliveryFee, z.MinimumOrder, z.ExtraPreparationMinutes, z.IsActive)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *DeliveryZoneRepository) Update(ctx context.Context, z *domain.DeliveryZone) (*domain.DeliveryZone, error) {
+	var out domain.DeliveryZone
+	err := r.pool.ForContext(ctx).GetContext(ctx, &out,
+		`UPDATE delivery_zones
+		 SET name = $2,
+		     geometry = $3,
+		     delivery_fee = $4,
+		     minimum_order = $5,
+		     extra_preparation_minutes = $6,
+		     is_active = $7,
+		     updated_at = NOW()
+		 WHERE id = $1
+		 RETURNING `+zoneColumns,
+		z.ID, z.Name, z.Geometry, z.DeliveryFee, z.MinimumOrder, z.ExtraPreparationMinutes, z.IsActive)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *DeliveryZoneRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := r.pool.ForContext(ctx).ExecContext(ctx,
+		`DELETE FROM delivery_zones WHERE id = $1`, id)
+	return err
+}
@@ -48,6 +48,9 @@ type UserService interface {
 	FindOrCreateByZitadelID(ctx context.Context, zitadelID, email, firstName, lastName string) (*domain.User, error)
 	// ResolveZitadelID returns the app user UUID for a Zitadel sub (implements middleware.UserLookup)
 	ResolveZitadelID(ctx context.Context, zitadelID, email, firstName, lastName string) (string, error)
+	// HasRole reports whether the user holds the Zitadel role (e.g.
+	// "driver"). A user not linked to Zitadel holds none.
+	HasRole(ctx context.Context, userID, role string) (bool, error)
 }
 
 // ZitadelUserFetcher retrieves a user's profile from Zitadel by sub.
@@ -60,6 +63,8 @@ type ZitadelUserFetcher interface {
 	// DeleteUser permanently removes the Zitadel identity (account deletion).
 	// Idempotent: a missing user is treated as success.
 	DeleteUser(ctx context.Context, userID string) error
+	// HasRole reports whether the Zitadel user was granted role.
+	HasRole(ctx context.Context, userID, role string) (bool, error)
 }
 
 type userService struct {
@@ -78,6 +83,17 @@ func (s *userService) GetUserByID(ctx context.Context, id string) (*domain.User,
 	return s.repo.FindByID(ctx, id)
 }
 
+func (s *userService) HasRole(ctx context.Context, userID, role string) (bool, error) {
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if user.ZitadelUserID == nil || *user.ZitadelUserID == "" || s.zitadelFetcher == nil {
+		return false, nil
+	}
+	return s.zitadelFetcher.HasRole(ctx, *user.ZitadelUserID, role)
+}
+
 func (s *userService) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
 	return s.repo.FindByEmail(ctx, normalizeEmail(email))
 }
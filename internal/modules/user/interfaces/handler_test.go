@@ -65,6 +65,10 @@ func (m *mockUserService) ResolveZitadelID(ctx context.Context, zitadelID, email
 	return "", fmt.Errorf("not implemented")
 }
 
+func (m *mockUserService) HasRole(ctx context.Context, userID, role string) (bool, error) {
+	return false, fmt.Errorf("not implemented")
+}
+
 // Compile-time check
 var _ application.UserService = (*mockUserService)(nil)
 
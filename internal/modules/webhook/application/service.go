@@ -0,0 +1,247 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"tsb-service/internal/modules/webhook/domain"
+	"tsb-service/pkg/logging"
+)
+
+type WebhookService interface {
+	GetEndpoints(ctx context.Context) ([]*domain.Endpoint, error)
+	GetEndpoint(ctx context.Context, id uuid.UUID) (*domain.Endpoint, error)
+	// CreateEndpoint validates and saves endpoint, generating its secret
+	// when left blank.
+	CreateEndpoint(ctx context.Context, endpoint *domain.Endpoint) error
+	UpdateEndpoint(ctx context.Context, endpoint *domain.Endpoint) error
+	DeleteEndpoint(ctx context.Context, id uuid.UUID) error
+
+	// Emit queues the event of type t about key for every endpoint
+	// subscribed to it. The event ID derives from t and key (see
+	// domain.EventID), so emitting the same event again is a no-op.
+	Emit(ctx context.Context, t domain.EventType, key string, data any) error
+	// Notify wakes the worker so fresh deliveries go out without waiting for
+	// the next poll.
+	Notify()
+	// DispatchDue sends the deliveries that are due and returns how many were
+	// attempted.
+	DispatchDue(ctx context.Context) (int, error)
+	// Run dispatches every interval and on Notify until ctx is done.
+	Run(ctx context.Context, interval time.Duration)
+
+	ListDeliveries(ctx context.Context, endpointID uuid.UUID, status *domain.DeliveryStatus, limit int) ([]*domain.Delivery, error)
+	// RetryDelivery makes a dead or pending delivery due now with a fresh
+	// attempt budget.
+	RetryDelivery(ctx context.Context, id uuid.UUID) (*domain.Delivery, error)
+	// PurgeDelivered deletes the deliveries delivered more than olderThan ago
+	// and returns how many were deleted.
+	PurgeDelivered(ctx context.Context, olderThan time.Duration) (int64, error)
+}
+
+const (
+	webhookBatchSize = 20
+	// webhookLease keeps a claimed delivery away from other workers while
+	// the POST runs; it outlasts the sender's timeout.
+	webhookLease = time.Minute
+)
+
+type webhookService struct {
+	repo   domain.WebhookRepository
+	sender domain.Sender
+	wake   chan struct{}
+}
+
+func NewWebhookService(repo domain.WebhookRepository, sender domain.Sender) WebhookService {
+	return &webhookService{
+		repo:   repo,
+		sender: sender,
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+func (s *webhookService) GetEndpoints(ctx context.Context) ([]*domain.Endpoint, error) {
+	return s.repo.FindEndpoints(ctx)
+}
+
+func (s *webhookService) GetEndpoint(ctx context.Context, id uuid.UUID) (*domain.Endpoint, error) {
+	return s.repo.FindEndpointByID(ctx, id)
+}
+
+func (s *webhookService) CreateEndpoint(ctx context.Context, endpoint *domain.Endpoint) error {
+	endpoint.URL = strings.TrimSpace(endpoint.URL)
+	if err := endpoint.Validate(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(endpoint.Secret) == "" {
+		secret, err := domain.GenerateSecret()
+		if err != nil {
+			return err
+		}
+		endpoint.Secret = secret
+	}
+	if endpoint.ID == uuid.Nil {
+		endpoint.ID = uuid.New()
+	}
+	return s.repo.SaveEndpoint(ctx, endpoint)
+}
+
+func (s *webhookService) UpdateEndpoint(ctx context.Context, endpoint *domain.Endpoint) error {
+	endpoint.URL = strings.TrimSpace(endpoint.URL)
+	if err := endpoint.Validate(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(endpoint.Secret) == "" {
+		return fmt.Errorf("webhook secret must not be empty")
+	}
+	return s.repo.UpdateEndpoint(ctx, endpoint)
+}
+
+func (s *webhookService) DeleteEndpoint(ctx context.Context, id uuid.UUID) error {
+	return s.repo.DeleteEndpoint(ctx, id)
+}
+
+func (s *webhookService) Emit(ctx context.Context, t domain.EventType, key string, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook event data: %w", err)
+	}
+	event := &domain.Event{
+		ID:        domain.EventID(t, key),
+		Type:      t,
+		CreatedAt: time.Now().UTC(),
+		Data:      raw,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook event: %w", err)
+	}
+	n, err := s.repo.EnqueueEvent(ctx, event, payload)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		s.Notify()
+	}
+	return nil
+}
+
+func (s *webhookService) Notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *webhookService) DispatchDue(ctx context.Context) (int, error) {
+	deliveries, err := s.repo.ClaimDue(ctx, webhookBatchSize, webhookLease)
+	if err != nil {
+		return 0, err
+	}
+	endpoints := make(map[uuid.UUID]*domain.Endpoint)
+	for _, d := range deliveries {
+		endpoint, ok := endpoints[d.EndpointID]
+		if !ok {
+			if endpoint, err = s.repo.FindEndpointByID(ctx, d.EndpointID); err != nil {
+				// Deleted since the claim (its deliveries went with it), or
+				// unreadable: the lease runs out and the delivery is retried.
+				logging.FromContext(ctx).Warn("failed to load webhook endpoint",
+					zap.String("endpoint_id", d.EndpointID.String()), zap.Error(err))
+				continue
+			}
+			endpoints[d.EndpointID] = endpoint
+		}
+		s.deliver(ctx, endpoint, d)
+	}
+	return len(deliveries), nil
+}
+
+// deliver sends one claimed delivery and records the outcome. Attempts was
+// already incremented by the claim.
+func (s *webhookService) deliver(ctx context.Context, endpoint *domain.Endpoint, d *domain.Delivery) {
+	log := logging.FromContext(ctx).With(
+		zap.String("delivery_id", d.ID.String()),
+		zap.String("endpoint_id", d.EndpointID.String()),
+		zap.String("event_type", string(d.EventType)),
+		zap.Int("attempt", d.Attempts),
+	)
+
+	var (
+		status int
+		err    error
+	)
+	if endpoint.IsActive {
+		status, err = s.sender.Send(ctx, endpoint, d)
+	} else {
+		err = fmt.Errorf("endpoint is disabled")
+	}
+	if err == nil {
+		if markErr := s.repo.MarkDelivered(ctx, d.ID, status); markErr != nil {
+			log.Error("failed to mark webhook delivery delivered", zap.Error(markErr))
+		}
+		return
+	}
+
+	var responseStatus *int
+	if status != 0 {
+		responseStatus = &status
+	}
+	var retryAt *time.Time
+	if endpoint.IsActive && d.Attempts < domain.DeliveryMaxAttempts {
+		at := time.Now().Add(domain.DeliveryBackoff(d.Attempts))
+		retryAt = &at
+		log.Warn("webhook delivery failed, will retry", zap.Time("retry_at", at), zap.Error(err))
+	} else {
+		log.Error("webhook delivery failed, dead-lettered", zap.Error(err))
+	}
+	if markErr := s.repo.MarkFailed(ctx, d.ID, responseStatus, err.Error(), retryAt); markErr != nil {
+		log.Error("failed to record webhook delivery failure", zap.Error(markErr))
+	}
+}
+
+func (s *webhookService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// Drain full batches back to back; a short batch means nothing is due.
+		for {
+			n, err := s.DispatchDue(ctx)
+			if err != nil {
+				logging.FromContext(ctx).Warn("webhook dispatch failed", zap.Error(err))
+				break
+			}
+			if n < webhookBatchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+func (s *webhookService) ListDeliveries(ctx context.Context, endpointID uuid.UUID, status *domain.DeliveryStatus, limit int) ([]*domain.Delivery, error) {
+	return s.repo.ListDeliveries(ctx, endpointID, status, limit)
+}
+
+func (s *webhookService) RetryDelivery(ctx context.Context, id uuid.UUID) (*domain.Delivery, error) {
+	delivery, err := s.repo.RetryDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.Notify()
+	return delivery, nil
+}
+
+func (s *webhookService) PurgeDelivered(ctx context.Context, olderThan time.Duration) (int64, error) {
+	return s.repo.DeleteDelivered(ctx, time.Now().Add(-olderThan))
+}
@@ -0,0 +1,149 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tsb-service/internal/modules/webhook/domain"
+)
+
+type fakeWebhookRepo struct {
+	domain.WebhookRepository
+	endpoint  *domain.Endpoint
+	due       []*domain.Delivery
+	enqueued  []*domain.Event
+	delivered map[uuid.UUID]int
+	failed    map[uuid.UUID]*time.Time
+}
+
+func (f *fakeWebhookRepo) FindEndpointByID(_ context.Context, id uuid.UUID) (*domain.Endpoint, error) {
+	if f.endpoint == nil || f.endpoint.ID != id {
+		return nil, errors.New("not found")
+	}
+	return f.endpoint, nil
+}
+
+func (f *fakeWebhookRepo) EnqueueEvent(_ context.Context, event *domain.Event, _ json.RawMessage) (int, error) {
+	f.enqueued = append(f.enqueued, event)
+	return 1, nil
+}
+
+func (f *fakeWebhookRepo) ClaimDue(context.Context, int, time.Duration) ([]*domain.Delivery, error) {
+	due := f.due
+	f.due = nil
+	return due, nil
+}
+
+func (f *fakeWebhookRepo) MarkDelivered(_ context.Context, id uuid.UUID, status int) error {
+	f.delivered[id] = status
+	return nil
+}
+
+func (f *fakeWebhookRepo) MarkFailed(_ context.Context, id uuid.UUID, _ *int, _ string, retryAt *time.Time) error {
+	f.failed[id] = retryAt
+	return nil
+}
+
+type fakeSender struct {
+	status int
+	err    error
+}
+
+func (f fakeSender) Send(context.Context, *domain.Endpoint, *domain.Delivery) (int, error) {
+	return f.status, f.err
+}
+
+func newFakeWebhookRepo(active bool, due ...*domain.Delivery) *fakeWebhookRepo {
+	endpoint := &domain.Endpoint{ID: uuid.New(), IsActive: active}
+	for _, d := range due {
+		d.ID = uuid.New()
+		d.EndpointID = endpoint.ID
+	}
+	return &fakeWebhookRepo{
+		endpoint:  endpoint,
+		due:       due,
+		delivered: make(map[uuid.UUID]int),
+		failed:    make(map[uuid.UUID]*time.Time),
+	}
+}
+
+func TestDispatchDue(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("delivered on 2xx", func(t *testing.T) {
+		d := &domain.Delivery{Attempts: 1}
+		repo := newFakeWebhookRepo(true, d)
+		svc := NewWebhookService(repo, fakeSender{status: 204})
+		if n, err := svc.DispatchDue(ctx); err != nil || n != 1 {
+			t.Fatalf("DispatchDue = %d, %v", n, err)
+		}
+		if repo.delivered[d.ID] != 204 {
+			t.Errorf("delivery not marked delivered: %v", repo.delivered)
+		}
+	})
+
+	t.Run("retried with backoff", func(t *testing.T) {
+		d := &domain.Delivery{Attempts: 2}
+		repo := newFakeWebhookRepo(true, d)
+		svc := NewWebhookService(repo, fakeSender{status: 500, err: errors.New("endpoint returned 500")})
+		before := time.Now()
+		if _, err := svc.DispatchDue(ctx); err != nil {
+			t.Fatal(err)
+		}
+		retryAt, ok := repo.failed[d.ID]
+		if !ok || retryAt == nil {
+			t.Fatalf("failed delivery must be retried, got %v", repo.failed)
+		}
+		if got := retryAt.Sub(before); got < domain.DeliveryBackoff(2) {
+			t.Errorf("retry in %s, want at least %s", got, domain.DeliveryBackoff(2))
+		}
+	})
+
+	t.Run("dead-lettered after the last attempt", func(t *testing.T) {
+		d := &domain.Delivery{Attempts: domain.DeliveryMaxAttempts}
+		repo := newFakeWebhookRepo(true, d)
+		svc := NewWebhookService(repo, fakeSender{err: errors.New("connection refused")})
+		if _, err := svc.DispatchDue(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if retryAt, ok := repo.failed[d.ID]; !ok || retryAt != nil {
+			t.Errorf("delivery must be dead-lettered, got %v", repo.failed)
+		}
+	})
+
+	t.Run("disabled endpoint is not called", func(t *testing.T) {
+		d := &domain.Delivery{Attempts: 1}
+		repo := newFakeWebhookRepo(false, d)
+		svc := NewWebhookService(repo, fakeSender{status: 200})
+		if _, err := svc.DispatchDue(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := repo.delivered[d.ID]; ok {
+			t.Errorf("delivery to a disabled endpoint must not be sent")
+		}
+		if retryAt, ok := repo.failed[d.ID]; !ok || retryAt != nil {
+			t.Errorf("delivery to a disabled endpoint must be dead-lettered, got %v", repo.failed)
+		}
+	})
+}
+
+func TestEmitUsesStableEventID(t *testing.T) {
+	repo := newFakeWebhookRepo(true)
+	svc := NewWebhookService(repo, fakeSender{})
+	for range 2 {
+		if err := svc.Emit(context.Background(), domain.EventPaymentPaid, "tr_123", map[string]string{"paymentId": "tr_123"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(repo.enqueued) != 2 || repo.enqueued[0].ID != repo.enqueued[1].ID {
+		t.Errorf("re-emitting an event must reuse its ID: %v", repo.enqueued)
+	}
+	if repo.enqueued[0].ID != domain.EventID(domain.EventPaymentPaid, "tr_123") {
+		t.Errorf("event ID must derive from type and key")
+	}
+}
@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type WebhookRepository interface {
+	FindEndpoints(ctx context.Context) ([]*Endpoint, error)
+	FindEndpointByID(ctx context.Context, id uuid.UUID) (*Endpoint, error)
+	SaveEndpoint(ctx context.Context, endpoint *Endpoint) error
+	UpdateEndpoint(ctx context.Context, endpoint *Endpoint) error
+	DeleteEndpoint(ctx context.Context, id uuid.UUID) error
+
+	// EnqueueEvent writes one pending delivery of event per active endpoint
+	// subscribed to its type and returns how many were written. An event
+	// already enqueued for an endpoint is skipped, so emitting it again is
+	// harmless.
+	EnqueueEvent(ctx context.Context, event *Event, payload json.RawMessage) (int, error)
+	// ClaimDue leases up to limit due deliveries, counting the attempt, so no
+	// other worker picks them up before the lease ends.
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*Delivery, error)
+	MarkDelivered(ctx context.Context, id uuid.UUID, responseStatus int) error
+	// MarkFailed records a failed attempt: the delivery is retried at retryAt,
+	// or dead-lettered when retryAt is nil. responseStatus is nil when the
+	// endpoint could not be reached.
+	MarkFailed(ctx context.Context, id uuid.UUID, responseStatus *int, lastErr string, retryAt *time.Time) error
+	ListDeliveries(ctx context.Context, endpointID uuid.UUID, status *DeliveryStatus, limit int) ([]*Delivery, error)
+	// RetryDelivery makes a dead or pending delivery due now with a fresh
+	// attempt budget.
+	RetryDelivery(ctx context.Context, id uuid.UUID) (*Delivery, error)
+	// DeleteDelivered deletes the deliveries delivered before
+	// deliveredBefore and returns how many were deleted. Dead deliveries are
+	// kept for review.
+	DeleteDelivered(ctx context.Context, deliveredBefore time.Time) (int64, error)
+}
+
+// Sender POSTs a delivery's payload, signed with the endpoint secret, to the
+// endpoint. It returns the HTTP response status, or 0 when the endpoint could
+// not be reached; any non-2xx status is an error.
+type Sender interface {
+	Send(ctx context.Context, endpoint *Endpoint, delivery *Delivery) (int, error)
+}
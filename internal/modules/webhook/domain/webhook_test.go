@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	got := Sign("whsec_test", time.Unix(1700000000, 0), []byte(`{"id":1}`))
+	want := "t=1700000000,v1=2f441ba4b3b2d50d28a9ab9d9fd8880376ecd1eb5d0435401553f5d8d0a5dcf8"
+	if got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+}
+
+func TestEndpointValidate(t *testing.T) {
+	cases := []struct {
+		name   string
+		url    string
+		events []string
+		want   error
+	}{
+		{"valid", "https://example.com/hooks", []string{"order.created", "refund.created"}, nil},
+		{"plain http", "http://example.com/hooks", []string{"order.created"}, ErrInvalidURL},
+		{"relative", "/hooks", []string{"order.created"}, ErrInvalidURL},
+		{"no events", "https://example.com/hooks", nil, ErrInvalidEvents},
+		{"unknown event", "https://example.com/hooks", []string{"order.deleted"}, ErrInvalidEvents},
+	}
+	for _, tc := range cases {
+		e := &Endpoint{URL: tc.url, Events: tc.events}
+		if got := e.Validate(); !errors.Is(got, tc.want) {
+			t.Errorf("%s: Validate() = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestEndpointSubscribes(t *testing.T) {
+	e := &Endpoint{IsActive: true, Events: []string{"payment.paid"}}
+	if !e.Subscribes(EventPaymentPaid) || e.Subscribes(EventOrderCreated) {
+		t.Errorf("Subscribes must match the endpoint's events only")
+	}
+	e.IsActive = false
+	if e.Subscribes(EventPaymentPaid) {
+		t.Errorf("a disabled endpoint subscribes to nothing")
+	}
+}
+
+func TestEventID(t *testing.T) {
+	a := EventID(EventPaymentPaid, "tr_123")
+	if a != EventID(EventPaymentPaid, "tr_123") {
+		t.Errorf("EventID must be stable for the same event")
+	}
+	if a == EventID(EventRefundCreated, "tr_123") || a == EventID(EventPaymentPaid, "tr_456") {
+		t.Errorf("EventID must differ across types and keys")
+	}
+}
+
+func TestDeliveryBackoff(t *testing.T) {
+	cases := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, time.Minute},
+		{1, time.Minute},
+		{3, 4 * time.Minute},
+		{10, 6 * time.Hour},
+	}
+	for _, tc := range cases {
+		if got := DeliveryBackoff(tc.attempts); got != tc.want {
+			t.Errorf("DeliveryBackoff(%d) = %s, want %s", tc.attempts, got, tc.want)
+		}
+	}
+}
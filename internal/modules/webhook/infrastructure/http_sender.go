@@ -0,0 +1,60 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"tsb-service/internal/modules/webhook/domain"
+)
+
+// maxErrorBody caps how much of a failed response is kept in the delivery log.
+const maxErrorBody = 512
+
+type HTTPSender struct {
+	httpClient *http.Client
+}
+
+func NewHTTPSender(httpClient *http.Client) domain.Sender {
+	if httpClient == nil {
+		httpClient = &http.Client{
+			Timeout: 10 * time.Second,
+			// A receiver redirecting elsewhere is a misconfiguration, and
+			// following it would send the signed payload to another host.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+	return &HTTPSender{httpClient: httpClient}
+}
+
+func (s *HTTPSender) Send(ctx context.Context, endpoint *domain.Endpoint, delivery *domain.Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "tsb-webhooks/1")
+	req.Header.Set(domain.EventIDHeader, delivery.EventID.String())
+	req.Header.Set(domain.EventTypeHeader, string(delivery.EventType))
+	req.Header.Set(domain.SignatureHeader, domain.Sign(endpoint.Secret, time.Now(), delivery.Payload))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("http request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return resp.StatusCode, fmt.Errorf("endpoint returned %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBody))
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,181 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tsb-service/internal/modules/webhook/domain"
+	"tsb-service/pkg/db"
+)
+
+const endpointColumns = `id, url, events, secret, is_active, created_at, updated_at`
+
+type WebhookRepository struct {
+	pool *db.DBPool
+}
+
+func NewWebhookRepository(pool *db.DBPool) domain.WebhookRepository {
+	return &WebhookRepository{pool: pool}
+}
+
+func (r *WebhookRepository) FindEndpoints(ctx context.Context) ([]*domain.Endpoint, error) {
+	var endpoints []*domain.Endpoint
+	err := r.pool.ForContext(ctx).SelectContext(ctx, &endpoints,
+		`SELECT `+endpointColumns+` FROM webhook_endpoints ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch webhook endpoints: %w", err)
+	}
+	return endpoints, nil
+}
+
+func (r *WebhookRepository) FindEndpointByID(ctx context.Context, id uuid.UUID) (*domain.Endpoint, error) {
+	var endpoint domain.Endpoint
+	err := r.pool.ForContext(ctx).GetContext(ctx, &endpoint,
+		`SELECT `+endpointColumns+` FROM webhook_endpoints WHERE id = $1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("webhook endpoint not found: %w", err)
+	}
+	return &endpoint, nil
+}
+
+func (r *WebhookRepository) SaveEndpoint(ctx context.Context, endpoint *domain.Endpoint) error {
+	err := r.pool.ForContext(ctx).QueryRowxContext(ctx,
+		`INSERT INTO webhook_endpoints (id, url, events, secret, is_active)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING created_at, updated_at`,
+		endpoint.ID, endpoint.URL, endpoint.Events, endpoint.Secret, endpoint.IsActive,
+	).Scan(&endpoint.CreatedAt, &endpoint.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save webhook endpoint: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) UpdateEndpoint(ctx context.Context, endpoint *domain.Endpoint) error {
+	err := r.pool.ForContext(ctx).QueryRowxContext(ctx,
+		`UPDATE webhook_endpoints SET url = $2, events = $3, secret = $4, is_active = $5, updated_at = now()
+		 WHERE id = $1
+		 RETURNING updated_at`,
+		endpoint.ID, endpoint.URL, endpoint.Events, endpoint.Secret, endpoint.IsActive,
+	).Scan(&endpoint.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook endpoint: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) DeleteEndpoint(ctx context.Context, id uuid.UUID) error {
+	_, err := r.pool.ForContext(ctx).ExecContext(ctx, `DELETE FROM webhook_endpoints WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook endpoint: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) EnqueueEvent(ctx context.Context, event *domain.Event, payload json.RawMessage) (int, error) {
+	const query = `
+		INSERT INTO webhook_deliveries (endpoint_id, event_id, event_type, payload)
+		SELECT id, $1, $2, $3
+		FROM webhook_endpoints
+		WHERE is_active AND $2 = ANY(events)
+		ON CONFLICT (endpoint_id, event_id) DO NOTHING
+	`
+	res, err := r.pool.ForContext(ctx).ExecContext(ctx, query, event.ID, string(event.Type), payload)
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue webhook event: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue webhook event: %w", err)
+	}
+	return int(n), nil
+}
+
+func (r *WebhookRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*domain.Delivery, error) {
+	const query = `
+		UPDATE webhook_deliveries
+		SET attempts = attempts + 1,
+		    next_attempt_at = now() + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'PENDING' AND next_attempt_at <= now()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *;
+	`
+	var deliveries []*domain.Delivery
+	if err := r.pool.ForContext(ctx).SelectContext(ctx, &deliveries, query, limit, lease.Seconds()); err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (r *WebhookRepository) MarkDelivered(ctx context.Context, id uuid.UUID, responseStatus int) error {
+	const query = `
+		UPDATE webhook_deliveries
+		SET status = 'DELIVERED', delivered_at = now(), response_status = $2, last_error = NULL
+		WHERE id = $1
+	`
+	if _, err := r.pool.ForContext(ctx).ExecContext(ctx, query, id, responseStatus); err != nil {
+		return fmt.Errorf("failed to mark webhook delivery delivered: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) MarkFailed(ctx context.Context, id uuid.UUID, responseStatus *int, lastErr string, retryAt *time.Time) error {
+	const query = `
+		UPDATE webhook_deliveries
+		SET status = CASE WHEN $4::timestamptz IS NULL THEN 'DEAD' ELSE 'PENDING' END,
+		    next_attempt_at = COALESCE($4::timestamptz, next_attempt_at),
+		    response_status = $2,
+		    last_error = $3
+		WHERE id = $1
+	`
+	if _, err := r.pool.ForContext(ctx).ExecContext(ctx, query, id, responseStatus, lastErr, retryAt); err != nil {
+		return fmt.Errorf("failed to mark webhook delivery failed: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, endpointID uuid.UUID, status *domain.DeliveryStatus, limit int) ([]*domain.Delivery, error) {
+	const query = `
+		SELECT * FROM webhook_deliveries
+		WHERE endpoint_id = $1 AND ($2::text IS NULL OR status = $2)
+		ORDER BY created_at DESC
+		LIMIT $3
+	`
+	var deliveries []*domain.Delivery
+	if err := r.pool.ForContext(ctx).SelectContext(ctx, &deliveries, query, endpointID, status, limit); err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (r *WebhookRepository) RetryDelivery(ctx context.Context, id uuid.UUID) (*domain.Delivery, error) {
+	const query = `
+		UPDATE webhook_deliveries
+		SET status = 'PENDING', attempts = 0, next_attempt_at = now()
+		WHERE id = $1 AND status <> 'DELIVERED'
+		RETURNING *;
+	`
+	var delivery domain.Delivery
+	if err := r.pool.ForContext(ctx).GetContext(ctx, &delivery, query, id); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (r *WebhookRepository) DeleteDelivered(ctx context.Context, deliveredBefore time.Time) (int64, error) {
+	res, err := r.pool.ForContext(ctx).ExecContext(ctx,
+		`DELETE FROM webhook_deliveries WHERE status = 'DELIVERED' AND delivered_at < $1`, deliveredBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge delivered webhook deliveries: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -13,6 +13,7 @@ func DataLoaderMiddleware(
 	pas paymentApplication.PaymentService,
 	prs productApplication.ProductService,
 	uss userApplication.UserService,
+	ets orderApplication.EtaService,
 ) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
@@ -20,7 +21,7 @@ func DataLoaderMiddleware(
 		// Attach DataLoaders into the context, passing in the actual services
 		ctx = productApplication.AttachDataLoaders(ctx, prs)
 		ctx = paymentApplication.AttachDataLoaders(ctx, pas)
-		ctx = orderApplication.AttachDataLoaders(ctx, ors)
+		ctx = orderApplication.AttachDataLoaders(ctx, ors, ets)
 		ctx = userApplication.AttachDataLoaders(ctx, uss)
 
 		// Update the request with the new context
@@ -144,6 +144,7 @@ func (v *OIDCVerifier) tryVerifyAppJWT(c *gin.Context, tokenStr string) bool {
 	zap.L().Debug("app JWT verified", zap.String("deviceID", deviceID.String()))
 	ctx := utils.SetUserID(c.Request.Context(), deviceID.String())
 	ctx = utils.SetIsAdmin(ctx, true)
+	ctx = utils.SetIsPOS(ctx, true)
 	ctx = utils.SetTokenExpiry(ctx, v.appJWT.AccessTokenExpiry(tokenStr))
 	c.Request = c.Request.WithContext(ctx)
 	c.Set(string(utils.UserIDKey), deviceID.String())
@@ -190,7 +191,18 @@ func (v *OIDCVerifier) resolveAppUserID(ctx context.Context, sub, email, givenNa
 	return appID, true
 }
 
-// verifyAndSetContext verifies the JWT and sets userID/isAdmin in context.
+// hasRole reports whether the token grants role. It tries the generic claim
+// first (works with introspection), then falls back to the project-specific
+// claim path (works with JWT access tokens where the role is under
+// urn:zitadel:iam:org:project:{projectID}:roles).
+func (v *OIDCVerifier) hasRole(authCtx *oauth.IntrospectionContext, role string) bool {
+	if authCtx.IsGrantedRole(role) {
+		return true
+	}
+	return v.projectID != "" && authCtx.IsGrantedRoleInProject(v.projectID, role, "")
+}
+
+// verifyAndSetContext verifies the JWT and sets userID/isAdmin/isDriver in context.
 func (v *OIDCVerifier) verifyAndSetContext(c *gin.Context, tokenStr string) bool {
 	authCtx, err := v.authorizer.CheckAuthorization(c.Request.Context(), "Bearer "+tokenStr)
 	if err != nil {
@@ -203,13 +215,8 @@ func (v *OIDCVerifier) verifyAndSetContext(c *gin.Context, tokenStr string) bool
 		return false
 	}
 
-	// Try the generic claim first (works with introspection), then fall back
-	// to the project-specific claim path (works with JWT access tokens where
-	// the role is under urn:zitadel:iam:org:project:{projectID}:roles).
-	isAdmin := authCtx.IsGrantedRole("admin")
-	if !isAdmin && v.projectID != "" {
-		isAdmin = authCtx.IsGrantedRoleInProject(v.projectID, "admin", "")
-	}
+	isAdmin := v.hasRole(authCtx, "admin")
+	isDriver := v.hasRole(authCtx, "driver")
 
 	// Profile claims — the zitadel-go SDK's local JWT path only populates
 	// sub/aud/iss on IntrospectionContext; everything else (including email/
@@ -226,6 +233,7 @@ func (v *OIDCVerifier) verifyAndSetContext(c *gin.Context, tokenStr string) bool
 		zap.Bool("has_given", givenName != ""),
 		zap.Bool("has_family", familyName != ""),
 		zap.Bool("is_admin", isAdmin),
+		zap.Bool("is_driver", isDriver),
 	)
 
 	// Resolve Zitadel sub → app user UUID (with JIT provisioning)
@@ -236,6 +244,7 @@ func (v *OIDCVerifier) verifyAndSetContext(c *gin.Context, tokenStr string) bool
 	ctx := utils.SetUserID(c.Request.Context(), appID)
 	ctx = utils.SetZitadelSub(ctx, sub)
 	ctx = utils.SetIsAdmin(ctx, isAdmin)
+	ctx = utils.SetIsDriver(ctx, isDriver)
 	if expRaw, ok := authCtx.Claims["exp"].(float64); ok {
 		ctx = utils.SetTokenExpiry(ctx, time.Unix(int64(expRaw), 0).UTC())
 	}
@@ -282,7 +291,8 @@ func (v *OIDCVerifier) OptionalAuthMiddleware() gin.HandlerFunc {
 	}
 }
 
-// VerifyToken verifies a raw JWT string and returns the subject plus admin flag.
+// VerifyToken verifies a raw JWT string and returns the subject plus its
+// admin and driver roles.
 // Used by the GraphQL WebSocket InitFunc. Tries Zitadel first, then falls back
 // to the POS app JWT verifier. Returns the raw Zitadel sub for Zitadel tokens,
 // or the device UUID for POS tokens (in which case isPOS=true and the caller
@@ -293,23 +303,19 @@ func (v *OIDCVerifier) OptionalAuthMiddleware() gin.HandlerFunc {
 // expires instead of outliving the credential that authorized them. A zero
 // value means the token had no readable exp claim; callers should treat that
 // as "do not enforce a deadline" rather than "token is already expired".
-func (v *OIDCVerifier) VerifyToken(ctx context.Context, tokenStr string) (subject string, isAdmin, isPOS bool, exp time.Time, err error) {
+func (v *OIDCVerifier) VerifyToken(ctx context.Context, tokenStr string) (subject string, isAdmin, isDriver, isPOS bool, exp time.Time, err error) {
 	authCtx, zitadelErr := v.authorizer.CheckAuthorization(ctx, "Bearer "+tokenStr)
 	if zitadelErr == nil {
-		admin := authCtx.IsGrantedRole("admin")
-		if !admin && v.projectID != "" {
-			admin = authCtx.IsGrantedRoleInProject(v.projectID, "admin", "")
-		}
 		var tokenExp time.Time
 		if expRaw, ok := authCtx.Claims["exp"].(float64); ok {
 			tokenExp = time.Unix(int64(expRaw), 0).UTC()
 		}
-		return authCtx.UserID(), admin, false, tokenExp, nil
+		return authCtx.UserID(), v.hasRole(authCtx, "admin"), v.hasRole(authCtx, "driver"), false, tokenExp, nil
 	}
 	if v.appJWT != nil {
 		if deviceID, appErr := v.appJWT.VerifyAccessToken(tokenStr); appErr == nil {
-			return deviceID.String(), true, true, v.appJWT.AccessTokenExpiry(tokenStr), nil
+			return deviceID.String(), true, false, true, v.appJWT.AccessTokenExpiry(tokenStr), nil
 		}
 	}
-	return "", false, false, time.Time{}, zitadelErr
+	return "", false, false, false, time.Time{}, zitadelErr
 }
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE order_status_history
+ADD COLUMN forced BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE order_status_history
+DROP COLUMN IF EXISTS forced;
@@ -0,0 +1,33 @@
+-- +goose Up
+ALTER TABLE restaurant_config
+ADD COLUMN pricing JSONB NOT NULL DEFAULT '{
+    "deliveryFeeTiers": [
+        {"maxDistanceMeters": 3000, "fee": "0"},
+        {"maxDistanceMeters": 4000, "fee": "1"},
+        {"maxDistanceMeters": 5000, "fee": "2"},
+        {"maxDistanceMeters": 6000, "fee": "3"},
+        {"maxDistanceMeters": 7000, "fee": "4"},
+        {"maxDistanceMeters": 8000, "fee": "5"},
+        {"maxDistanceMeters": 9000, "fee": "6"}
+    ],
+    "excludedDeliveryPostcodes": ["4610"],
+    "deliveryMinimum": "25",
+    "pickupMinimum": "0",
+    "takeawayDiscountPercent": "10",
+    "takeawayDiscountThreshold": "20",
+    "transactionFee": "0.30"
+}'::jsonb;
+
+-- Snapshot of the takeaway discount rate applied to the order, so receipts and
+-- invoices keep showing the rate the customer actually got after a price change.
+ALTER TABLE orders
+ADD COLUMN takeaway_discount_percent NUMERIC(5, 2);
+
+UPDATE orders SET takeaway_discount_percent = 10 WHERE takeaway_discount > 0;
+
+-- +goose Down
+ALTER TABLE orders
+DROP COLUMN IF EXISTS takeaway_discount_percent;
+
+ALTER TABLE restaurant_config
+DROP COLUMN IF EXISTS pricing;
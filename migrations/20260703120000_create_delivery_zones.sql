@@ -0,0 +1,18 @@
+-- +goose Up
+-- Delivery zones drawn by admins as GeoJSON polygons. When at least one
+-- active zone exists it decides delivery eligibility, fee and minimum order;
+-- otherwise checkout falls back to the radius tiers in restaurant_config.pricing.
+CREATE TABLE delivery_zones (
+    id                        UUID          NOT NULL PRIMARY KEY,
+    name                      TEXT          NOT NULL,
+    geometry                  JSONB         NOT NULL,
+    delivery_fee              NUMERIC(10,2) NOT NULL DEFAULT 0,
+    minimum_order             NUMERIC(10,2) NOT NULL DEFAULT 0,
+    extra_preparation_minutes INT           NOT NULL DEFAULT 0,
+    is_active                 BOOLEAN       NOT NULL DEFAULT TRUE,
+    created_at                TIMESTAMPTZ   NOT NULL DEFAULT now(),
+    updated_at                TIMESTAMPTZ   NOT NULL DEFAULT now()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS delivery_zones;
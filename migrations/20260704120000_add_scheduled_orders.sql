@@ -0,0 +1,23 @@
+-- +goose Up
+-- How many days ahead customers may schedule an order, and how long before its
+-- ready time a scheduled order shows up in the staff's live order list.
+ALTER TABLE restaurant_config
+ADD COLUMN scheduling_horizon_days INT NOT NULL DEFAULT 0 CHECK (scheduling_horizon_days BETWEEN 0 AND 60),
+ADD COLUMN scheduled_order_lead_minutes INT NOT NULL DEFAULT 120 CHECK (scheduled_order_lead_minutes BETWEEN 0 AND 1440);
+
+-- Orders scheduled beyond the lead time are held back from staff until the
+-- release sweep clears the flag.
+ALTER TABLE orders
+ADD COLUMN held_for_schedule BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX idx_orders_held_for_schedule ON orders (preferred_ready_time) WHERE held_for_schedule;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_orders_held_for_schedule;
+
+ALTER TABLE orders
+DROP COLUMN IF EXISTS held_for_schedule;
+
+ALTER TABLE restaurant_config
+DROP COLUMN IF EXISTS scheduled_order_lead_minutes,
+DROP COLUMN IF EXISTS scheduling_horizon_days;
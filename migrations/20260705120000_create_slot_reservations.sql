@@ -0,0 +1,25 @@
+-- +goose Up
+-- Per-slot kitchen capacity rules, see restaurant/domain/capacity.go. An empty
+-- list leaves every slot unlimited.
+ALTER TABLE restaurant_config
+ADD COLUMN slot_capacity JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+-- One row per order holding capacity in a 15-minute slot. Rows are reserved
+-- before the order is inserted (order_id is attached right after) and deleted
+-- when the order is cancelled or fails.
+CREATE TABLE slot_reservations (
+    id          UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id    UUID        UNIQUE REFERENCES orders (id) ON DELETE CASCADE,
+    slot_start  TIMESTAMPTZ NOT NULL,
+    order_type  TEXT        NOT NULL,
+    item_count  INT         NOT NULL CHECK (item_count > 0),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_slot_reservations_slot_start ON slot_reservations (slot_start);
+
+-- +goose Down
+DROP TABLE slot_reservations;
+
+ALTER TABLE restaurant_config
+DROP COLUMN IF EXISTS slot_capacity;
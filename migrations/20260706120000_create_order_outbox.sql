@@ -0,0 +1,23 @@
+-- +goose Up
+-- Side effects of order changes (emails, pushes, refunds, subscription
+-- events), written in the same transaction as the change and delivered by the
+-- outbox worker with retries. Rows that exhaust their attempts become DEAD and
+-- can be retried by an admin.
+CREATE TABLE order_outbox (
+    id              UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id        UUID        NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    kind            TEXT        NOT NULL,
+    payload         JSONB       NOT NULL DEFAULT '{}'::jsonb,
+    status          TEXT        NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'DELIVERED', 'DEAD')),
+    attempts        INT         NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_error      TEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    delivered_at    TIMESTAMPTZ
+);
+
+CREATE INDEX idx_order_outbox_due ON order_outbox (next_attempt_at) WHERE status = 'PENDING';
+CREATE INDEX idx_order_outbox_status_created ON order_outbox (status, created_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS order_outbox;
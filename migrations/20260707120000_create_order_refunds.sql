@@ -0,0 +1,30 @@
+-- +goose Up
+-- Every Mollie refund issued for an order, full or partial. Line rows record
+-- which order items (and how many of each) a refund covers so the same dish
+-- can't be refunded twice; a refund of an arbitrary amount has no lines.
+-- mollie_payments.amount_refunded keeps the running total.
+CREATE TABLE order_refunds (
+    id               UUID          PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id         UUID          NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    payment_id       UUID          NOT NULL REFERENCES mollie_payments (id) ON DELETE CASCADE,
+    mollie_refund_id TEXT          NOT NULL UNIQUE,
+    amount           NUMERIC(10,2) NOT NULL CHECK (amount > 0),
+    reason           TEXT          NOT NULL,
+    created_at       TIMESTAMPTZ   NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_order_refunds_order_id ON order_refunds (order_id, created_at);
+
+CREATE TABLE order_refund_lines (
+    refund_id        UUID          NOT NULL REFERENCES order_refunds (id) ON DELETE CASCADE,
+    order_product_id UUID          NOT NULL REFERENCES order_product (id) ON DELETE CASCADE,
+    quantity         INT           NOT NULL CHECK (quantity > 0),
+    amount           NUMERIC(10,2) NOT NULL,
+    PRIMARY KEY (refund_id, order_product_id)
+);
+
+CREATE INDEX idx_order_refund_lines_order_product_id ON order_refund_lines (order_product_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS order_refund_lines;
+DROP TABLE IF EXISTS order_refunds;
@@ -0,0 +1,25 @@
+-- +goose Up
+-- How long after placing it a customer may still cancel a confirmed order
+-- themselves (PENDING orders can always be cancelled).
+ALTER TABLE restaurant_config
+ADD COLUMN cancellation_grace_minutes INT NOT NULL DEFAULT 5 CHECK (cancellation_grace_minutes BETWEEN 0 AND 60);
+
+ALTER TABLE orders
+DROP CONSTRAINT IF EXISTS orders_cancellation_reason_check;
+
+ALTER TABLE orders
+ADD CONSTRAINT orders_cancellation_reason_check
+    CHECK (cancellation_reason IN ('OUT_OF_STOCK', 'KITCHEN_CLOSED', 'DELIVERY_AREA', 'OTHER', 'CUSTOMER_REQUEST'));
+
+-- +goose Down
+UPDATE orders SET cancellation_reason = 'OTHER' WHERE cancellation_reason = 'CUSTOMER_REQUEST';
+
+ALTER TABLE orders
+DROP CONSTRAINT IF EXISTS orders_cancellation_reason_check;
+
+ALTER TABLE orders
+ADD CONSTRAINT orders_cancellation_reason_check
+    CHECK (cancellation_reason IN ('OUT_OF_STOCK', 'KITCHEN_CLOSED', 'DELIVERY_AREA', 'OTHER'));
+
+ALTER TABLE restaurant_config
+DROP COLUMN IF EXISTS cancellation_grace_minutes;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- Idempotency keys sent by clients with createOrder, so a retried request
+-- returns the order it already created instead of placing (and charging) a
+-- second one. A row is claimed before the order is created (order_id NULL)
+-- and completed with the order once it exists; failures delete the claim so
+-- the key stays usable. fingerprint hashes the request payload: reusing a key
+-- for a different order is rejected. Rows past expires_at are free to reuse
+-- and purged hourly.
+CREATE TABLE order_idempotency_keys (
+    user_id     UUID        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    key         TEXT        NOT NULL,
+    fingerprint TEXT        NOT NULL,
+    order_id    UUID        REFERENCES orders (id) ON DELETE CASCADE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at  TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX idx_order_idempotency_keys_expires_at ON order_idempotency_keys (expires_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS order_idempotency_keys;
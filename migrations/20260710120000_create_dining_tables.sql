@@ -0,0 +1,56 @@
+-- +goose Up
+-- Dining tables guests order from by scanning a QR code, and the sessions
+-- that collect a table's rounds (DINE_IN orders) until the bill is settled.
+-- The QR code carries an HMAC-signed token naming the table and its
+-- token_version; bumping the version voids the printed codes.
+CREATE TABLE dining_tables (
+    id            UUID        NOT NULL PRIMARY KEY DEFAULT gen_random_uuid(),
+    name          TEXT        NOT NULL UNIQUE,
+    seats         INT         NOT NULL DEFAULT 0 CHECK (seats >= 0),
+    token_version INT         NOT NULL DEFAULT 1,
+    is_active     BOOLEAN     NOT NULL DEFAULT TRUE,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- total and payment_method are recorded when the session is settled.
+CREATE TABLE table_sessions (
+    id             UUID          NOT NULL PRIMARY KEY DEFAULT gen_random_uuid(),
+    table_id       UUID          NOT NULL REFERENCES dining_tables (id) ON DELETE RESTRICT,
+    opened_at      TIMESTAMPTZ   NOT NULL DEFAULT now(),
+    closed_at      TIMESTAMPTZ,
+    total          NUMERIC(10,2),
+    payment_method TEXT CHECK (payment_method IN ('CASH', 'CARD'))
+);
+
+-- At most one open session per table.
+CREATE UNIQUE INDEX idx_table_sessions_open ON table_sessions (table_id) WHERE closed_at IS NULL;
+
+ALTER TABLE orders
+ADD COLUMN table_session_id UUID REFERENCES table_sessions (id) ON DELETE RESTRICT;
+
+CREATE INDEX idx_orders_table_session_id ON orders (table_session_id) WHERE table_session_id IS NOT NULL;
+
+ALTER TABLE orders
+DROP CONSTRAINT IF EXISTS orders_type_check;
+
+ALTER TABLE orders
+ADD CONSTRAINT orders_type_check CHECK (
+    order_type IN ('DELIVERY', 'PICKUP', 'DINE_IN')
+    AND (order_type = 'DINE_IN') = (table_session_id IS NOT NULL)
+);
+
+-- +goose Down
+DELETE FROM orders WHERE order_type = 'DINE_IN';
+
+ALTER TABLE orders
+DROP CONSTRAINT IF EXISTS orders_type_check;
+
+ALTER TABLE orders
+ADD CONSTRAINT orders_type_check CHECK (order_type IN ('DELIVERY', 'PICKUP'));
+
+ALTER TABLE orders
+DROP COLUMN IF EXISTS table_session_id;
+
+DROP TABLE IF EXISTS table_sessions;
+DROP TABLE IF EXISTS dining_tables;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- Tip the customer added to an online order. It is charged on top of
+-- total_price, carries no VAT and is shared among the staff.
+ALTER TABLE orders
+    ADD COLUMN tip NUMERIC(10, 2) NOT NULL DEFAULT 0
+        CHECK (tip >= 0);
+
+-- +goose Down
+ALTER TABLE orders DROP COLUMN tip;
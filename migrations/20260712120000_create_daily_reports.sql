@@ -0,0 +1,13 @@
+-- +goose Up
+-- Days whose end-of-day report is emailed to accounting. An instance leases
+-- the day (claimed_until) before sending so only one sends it at a time;
+-- sent_at is set once the email went out. A lease that runs out unsent, the
+-- instance having died mid-send, lets the next run retry the day.
+CREATE TABLE daily_reports (
+    day           DATE        PRIMARY KEY,
+    claimed_until TIMESTAMPTZ NOT NULL,
+    sent_at       TIMESTAMPTZ
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS daily_reports;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- The courier side of DELIVERY orders: the driver staff assigned, when the
+-- order left the restaurant and was delivered, and the driver's last
+-- reported position while it is on its way.
+CREATE TABLE order_deliveries (
+    order_id      UUID             NOT NULL PRIMARY KEY REFERENCES orders (id) ON DELETE CASCADE,
+    driver_id     UUID             REFERENCES users (id) ON DELETE SET NULL,
+    assigned_at   TIMESTAMPTZ,
+    dispatched_at TIMESTAMPTZ,
+    delivered_at  TIMESTAMPTZ,
+    latitude      DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90),
+    longitude     DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180),
+    accuracy      DOUBLE PRECISION,
+    heading       DOUBLE PRECISION,
+    located_at    TIMESTAMPTZ
+);
+
+CREATE INDEX idx_order_deliveries_driver_id ON order_deliveries (driver_id) WHERE delivered_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS order_deliveries;
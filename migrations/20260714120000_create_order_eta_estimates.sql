@@ -0,0 +1,28 @@
+-- +goose Up
+-- Whether confirming an order without a ready time applies the suggested one.
+ALTER TABLE restaurant_config
+ADD COLUMN auto_apply_eta BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- The ready time suggested when an order was confirmed, the inputs it was
+-- computed from, the time staff confirmed and when the order was actually
+-- ready, so the estimate can be tuned against reality.
+CREATE TABLE order_eta_estimates (
+    order_id UUID PRIMARY KEY REFERENCES orders (id) ON DELETE CASCADE,
+    suggested_ready_time TIMESTAMPTZ NOT NULL,
+    preparation_minutes INT NOT NULL,
+    kitchen_load INT NOT NULL,
+    item_count INT NOT NULL,
+    travel_seconds INT NOT NULL DEFAULT 0,
+    estimated_ready_time TIMESTAMPTZ,
+    applied BOOLEAN NOT NULL DEFAULT FALSE,
+    actual_ready_time TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_order_eta_estimates_created_at ON order_eta_estimates (created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS order_eta_estimates;
+
+ALTER TABLE restaurant_config
+DROP COLUMN IF EXISTS auto_apply_eta;
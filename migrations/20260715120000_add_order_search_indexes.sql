@@ -0,0 +1,24 @@
+-- +goose Up
+-- Trigram indexes behind the admin order-history search. The indexed
+-- expressions must stay identical to searchUserText / searchOrderText in
+-- internal/modules/order/infrastructure/repository.go for ILIKE to use them.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX idx_users_search_trgm ON users USING gin (
+    (first_name || ' ' || last_name || ' ' || email || ' ' || coalesce(phone_number, '')) gin_trgm_ops
+);
+
+CREATE INDEX idx_orders_search_trgm ON orders USING gin (
+    (coalesce(coupon_code, '') || ' ' || coalesce(street_name, '') || ' ' || coalesce(municipality_name, '') || ' ' || coalesce(order_note, '')) gin_trgm_ops
+);
+
+CREATE INDEX idx_product_translations_name_trgm ON product_translations USING gin (name gin_trgm_ops);
+
+-- Invoice references end with the last 8 hex digits of the order ID.
+CREATE INDEX idx_orders_id_reference ON orders (right(replace(id::text, '-', ''), 8));
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_orders_id_reference;
+DROP INDEX IF EXISTS idx_product_translations_name_trgm;
+DROP INDEX IF EXISTS idx_orders_search_trgm;
+DROP INDEX IF EXISTS idx_users_search_trgm;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- Keyset pagination of order lists walks (created_at, id) newest first, for
+-- every order or for one customer's.
+CREATE INDEX idx_orders_created_at_id ON orders (created_at DESC, id DESC);
+CREATE INDEX idx_orders_user_id_created_at_id ON orders (user_id, created_at DESC, id DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_orders_user_id_created_at_id;
+DROP INDEX IF EXISTS idx_orders_created_at_id;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- Who made each status change, and what came with it. Rows recorded before
+-- this migration have no actor.
+ALTER TABLE order_status_history
+    ADD COLUMN actor_type                    VARCHAR(20),
+    ADD COLUMN actor_id                      TEXT,
+    ADD COLUMN cancellation_reason           VARCHAR(30),
+    ADD COLUMN previous_estimated_ready_time TIMESTAMPTZ,
+    ADD COLUMN estimated_ready_time          TIMESTAMPTZ;
+
+CREATE INDEX idx_order_status_history_actor
+    ON order_status_history (actor_type, actor_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_order_status_history_actor;
+ALTER TABLE order_status_history
+    DROP COLUMN IF EXISTS estimated_ready_time,
+    DROP COLUMN IF EXISTS previous_estimated_ready_time,
+    DROP COLUMN IF EXISTS cancellation_reason,
+    DROP COLUMN IF EXISTS actor_id,
+    DROP COLUMN IF EXISTS actor_type;
@@ -0,0 +1,28 @@
+-- +goose Up
+-- How long an order may wait for staff confirmation before staff are alerted
+-- again, and before it is cancelled as KITCHEN_CLOSED. 0 disables each step;
+-- both stay off until an admin sets them.
+ALTER TABLE restaurant_config
+    ADD COLUMN unconfirmed_escalation_minutes INT NOT NULL DEFAULT 0
+        CHECK (unconfirmed_escalation_minutes BETWEEN 0 AND 240),
+    ADD COLUMN unconfirmed_cancel_minutes     INT NOT NULL DEFAULT 0
+        CHECK (unconfirmed_cancel_minutes BETWEEN 0 AND 240);
+
+-- When a scheduled order reached staff, and when staff were reminded of it;
+-- together with the payment they start the confirmation clock.
+ALTER TABLE orders
+    ADD COLUMN released_at  TIMESTAMPTZ,
+    ADD COLUMN escalated_at TIMESTAMPTZ;
+
+CREATE INDEX idx_orders_pending ON orders (created_at) WHERE order_status = 'PENDING';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_orders_pending;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS escalated_at,
+    DROP COLUMN IF EXISTS released_at;
+
+ALTER TABLE restaurant_config
+    DROP COLUMN IF EXISTS unconfirmed_cancel_minutes,
+    DROP COLUMN IF EXISTS unconfirmed_escalation_minutes;
@@ -0,0 +1,25 @@
+-- +goose Up
+-- An order whose online payment failed is cancelled as PAYMENT_FAILED and
+-- may be paid again for a while, each retry adding a payment to the order.
+ALTER TABLE orders
+DROP CONSTRAINT IF EXISTS orders_cancellation_reason_check;
+
+ALTER TABLE orders
+ADD CONSTRAINT orders_cancellation_reason_check
+    CHECK (cancellation_reason IN ('OUT_OF_STOCK', 'KITCHEN_CLOSED', 'DELIVERY_AREA', 'OTHER', 'CUSTOMER_REQUEST', 'PAYMENT_FAILED'));
+
+-- The latest payment attempt of an order drives it.
+CREATE INDEX IF NOT EXISTS idx_mollie_payments_order_created
+    ON mollie_payments (order_id, created_at DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_mollie_payments_order_created;
+
+UPDATE orders SET cancellation_reason = 'OTHER' WHERE cancellation_reason = 'PAYMENT_FAILED';
+
+ALTER TABLE orders
+DROP CONSTRAINT IF EXISTS orders_cancellation_reason_check;
+
+ALTER TABLE orders
+ADD CONSTRAINT orders_cancellation_reason_check
+    CHECK (cancellation_reason IN ('OUT_OF_STOCK', 'KITCHEN_CLOSED', 'DELIVERY_AREA', 'OTHER', 'CUSTOMER_REQUEST'));
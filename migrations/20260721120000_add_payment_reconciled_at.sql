@@ -0,0 +1,16 @@
+-- +goose Up
+-- When the reconciler last synced a payment whose webhook never arrived, so
+-- each run picks the payments it has not looked at for longest instead of
+-- always the oldest.
+ALTER TABLE mollie_payments
+    ADD COLUMN last_reconciled_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_mollie_payments_unsettled
+    ON mollie_payments (last_reconciled_at NULLS FIRST, created_at)
+    WHERE status IN ('open', 'pending', 'authorized');
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_mollie_payments_unsettled;
+
+ALTER TABLE mollie_payments
+    DROP COLUMN IF EXISTS last_reconciled_at;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- Refunds of a payment attempt an order no longer counts on (the customer paid
+-- again after retrying) return a duplicate payment rather than part of the
+-- order, so invoices, reports and exports leave them out.
+ALTER TABLE order_refunds
+    ADD COLUMN superseded BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE order_refunds
+    DROP COLUMN IF EXISTS superseded;
@@ -270,11 +270,14 @@ func prepareOrderPendingData(u userDomain.User, op []orderDomain.OrderProduct, o
 		OrderType        string
 		SubtotalPrice    string
 		TakeawayDiscount string
+		TakeawayPercent  string
 		HasTakeaway      bool
 		CouponDiscount   string
 		HasCoupon        bool
 		CouponCode       string
 		DeliveryFee      string
+		Tip              string
+		HasTip           bool
 		TotalPrice       string
 		LogoURL          string
 	}{
@@ -283,12 +286,15 @@ func prepareOrderPendingData(u userDomain.User, op []orderDomain.OrderProduct, o
 		OrderType:        string(o.OrderType),
 		SubtotalPrice:    utils.FormatDecimal(subtotal),
 		TakeawayDiscount: utils.FormatDecimal(o.TakeawayDiscount),
+		TakeawayPercent:  o.AppliedTakeawayDiscountPercent().String(),
 		HasTakeaway:      o.TakeawayDiscount.GreaterThan(decimal.Zero),
 		CouponDiscount:   utils.FormatDecimal(o.CouponDiscount),
 		HasCoupon:        o.CouponDiscount.GreaterThan(decimal.Zero),
 		CouponCode:       couponCode,
 		DeliveryFee:      utils.FormatDecimal(deliveryFee),
-		TotalPrice:       utils.FormatDecimal(o.TotalPrice),
+		Tip:              utils.FormatDecimal(o.Tip),
+		HasTip:           o.Tip.IsPositive(),
+		TotalPrice:       utils.FormatDecimal(o.AmountCharged()),
 		LogoURL:          logoURL(),
 	}
 
@@ -378,11 +384,14 @@ func prepareOrderConfirmedData(
 		OrderType          string
 		SubtotalPrice      string
 		TakeawayDiscount   string
+		TakeawayPercent    string
 		HasTakeaway        bool
 		CouponDiscount     string
 		HasCoupon          bool
 		CouponCode         string
 		DeliveryFee        string
+		Tip                string
+		HasTip             bool
 		TotalPrice         string
 		StatusLink         string
 		EstimatedReadyTime string
@@ -394,12 +403,15 @@ func prepareOrderConfirmedData(
 		OrderType:          string(o.OrderType),
 		SubtotalPrice:      utils.FormatDecimal(subtotal),
 		TakeawayDiscount:   utils.FormatDecimal(o.TakeawayDiscount),
+		TakeawayPercent:    o.AppliedTakeawayDiscountPercent().String(),
 		HasTakeaway:        o.TakeawayDiscount.GreaterThan(decimal.Zero),
 		CouponDiscount:     utils.FormatDecimal(o.CouponDiscount),
 		HasCoupon:          o.CouponDiscount.GreaterThan(decimal.Zero),
 		CouponCode:         couponCode,
 		DeliveryFee:        utils.FormatDecimal(deliveryFee),
-		TotalPrice:         utils.FormatDecimal(o.TotalPrice),
+		Tip:                utils.FormatDecimal(o.Tip),
+		HasTip:             o.Tip.IsPositive(),
+		TotalPrice:         utils.FormatDecimal(o.AmountCharged()),
 		StatusLink:         fmt.Sprintf("%s/me?followOrder=%s", os.Getenv("APP_BASE_URL"), o.ID),
 		EstimatedReadyTime: estimatedReadyTime,
 		Address:            addrView,
@@ -584,6 +596,20 @@ func renderRefundIssuedEmailText(path string, u userDomain.User, refundAmount st
 	return renderEmail(path, data, loadTextTemplate)
 }
 
+// --------------------------------------------------------------------------------
+// Duplicate Payment Refunded
+// --------------------------------------------------------------------------------
+
+func renderDuplicatePaymentRefundedEmailHTML(path string, u userDomain.User, refundAmount string) (string, error) {
+	data := prepareRefundIssuedData(u, refundAmount)
+	return renderEmail(path, data, loadHTMLTemplate)
+}
+
+func renderDuplicatePaymentRefundedEmailText(path string, u userDomain.User, refundAmount string) (string, error) {
+	data := prepareRefundIssuedData(u, refundAmount)
+	return renderEmail(path, data, loadTextTemplate)
+}
+
 // --------------------------------------------------------------------------------
 // Account Linked
 // --------------------------------------------------------------------------------
@@ -697,3 +723,37 @@ func renderFeedbackEmailText(path, name, email, serviceType, feedbackType, messa
 	data := prepareFeedbackData(name, email, serviceType, feedbackType, message, lang)
 	return renderEmail(path, data, loadTextTemplate)
 }
+
+// --------------------------------------------------------------------------------
+// Daily report (sent to the restaurant, always in French)
+// --------------------------------------------------------------------------------
+
+func prepareDailyReportData(r orderDomain.DailyReport) any {
+	return struct {
+		Date          string
+		Orders        int
+		Revenue       string
+		TotalVat      string
+		Tips          string
+		Refunds       string
+		Cancellations int
+		LogoURL       string
+	}{
+		Date:          r.Date.Format("02/01/2006"),
+		Orders:        r.Orders,
+		Revenue:       utils.FormatDecimal(r.Revenue),
+		TotalVat:      utils.FormatDecimal(r.TotalVat),
+		Tips:          utils.FormatDecimal(r.Tips),
+		Refunds:       utils.FormatDecimal(r.Refunds.Total),
+		Cancellations: r.Cancellations.Count,
+		LogoURL:       logoURL(),
+	}
+}
+
+func renderDailyReportEmailHTML(path string, r orderDomain.DailyReport) (string, error) {
+	return renderEmail(path, prepareDailyReportData(r), loadHTMLTemplate)
+}
+
+func renderDailyReportEmailText(path string, r orderDomain.DailyReport) (string, error) {
+	return renderEmail(path, prepareDailyReportData(r), loadTextTemplate)
+}
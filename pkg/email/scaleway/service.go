@@ -425,24 +425,28 @@ func SendLoginOtpEmail(user userDomain.User, lang string, code string) error {
 // OTHER is intentionally omitted — we fall back to the generic copy.
 var cancellationReasonLabels = map[string]map[orderDomain.OrderCancellationReason]string{
 	"fr": {
-		orderDomain.OrderCancellationReasonOutOfStock:    "rupture de stock",
-		orderDomain.OrderCancellationReasonKitchenClosed: "cuisine fermée",
-		orderDomain.OrderCancellationReasonDeliveryArea:  "hors zone de livraison",
+		orderDomain.OrderCancellationReasonOutOfStock:      "rupture de stock",
+		orderDomain.OrderCancellationReasonKitchenClosed:   "cuisine fermée",
+		orderDomain.OrderCancellationReasonDeliveryArea:    "hors zone de livraison",
+		orderDomain.OrderCancellationReasonCustomerRequest: "à votre demande",
 	},
 	"en": {
-		orderDomain.OrderCancellationReasonOutOfStock:    "out of stock",
-		orderDomain.OrderCancellationReasonKitchenClosed: "kitchen closed",
-		orderDomain.OrderCancellationReasonDeliveryArea:  "outside delivery area",
+		orderDomain.OrderCancellationReasonOutOfStock:      "out of stock",
+		orderDomain.OrderCancellationReasonKitchenClosed:   "kitchen closed",
+		orderDomain.OrderCancellationReasonDeliveryArea:    "outside delivery area",
+		orderDomain.OrderCancellationReasonCustomerRequest: "at your request",
 	},
 	"nl": {
-		orderDomain.OrderCancellationReasonOutOfStock:    "uitverkocht",
-		orderDomain.OrderCancellationReasonKitchenClosed: "keuken gesloten",
-		orderDomain.OrderCancellationReasonDeliveryArea:  "buiten bezorggebied",
+		orderDomain.OrderCancellationReasonOutOfStock:      "uitverkocht",
+		orderDomain.OrderCancellationReasonKitchenClosed:   "keuken gesloten",
+		orderDomain.OrderCancellationReasonDeliveryArea:    "buiten bezorggebied",
+		orderDomain.OrderCancellationReasonCustomerRequest: "op uw verzoek",
 	},
 	"zh": {
-		orderDomain.OrderCancellationReasonOutOfStock:    "缺货",
-		orderDomain.OrderCancellationReasonKitchenClosed: "厨房已关闭",
-		orderDomain.OrderCancellationReasonDeliveryArea:  "超出配送范围",
+		orderDomain.OrderCancellationReasonOutOfStock:      "缺货",
+		orderDomain.OrderCancellationReasonKitchenClosed:   "厨房已关闭",
+		orderDomain.OrderCancellationReasonDeliveryArea:    "超出配送范围",
+		orderDomain.OrderCancellationReasonCustomerRequest: "应您的要求",
 	},
 }
 
@@ -671,6 +675,54 @@ func SendRefundIssuedEmail(user userDomain.User, lang string, orderID string, re
 	return nil
 }
 
+func SendDuplicatePaymentRefundedEmail(user userDomain.User, lang string, orderID string, refundAmount string) error {
+	newReq := *baseReq
+
+	userFullName := fmt.Sprintf("%s %s", user.FirstName, user.LastName)
+	to := temv1alpha1.CreateEmailRequestAddress{
+		Email: user.Email,
+		Name:  &userFullName,
+	}
+	newReq.To = append(newReq.To, &to)
+
+	path := fmt.Sprintf("templates/%s/duplicate-payment-refunded", lang)
+
+	htmlContent, err := renderDuplicatePaymentRefundedEmailHTML(path, user, refundAmount)
+	if err != nil {
+		return fmt.Errorf("failed to render email template: %w", err)
+	}
+
+	plainTextContent, err := renderDuplicatePaymentRefundedEmailText(path, user, refundAmount)
+	if err != nil {
+		return fmt.Errorf("failed to render email template: %w", err)
+	}
+
+	subjects := map[string]string{
+		"en": "Your duplicate payment has been refunded",
+		"fr": "Votre double paiement a été remboursé",
+		"zh": "您的重复付款已退款",
+		"nl": "Uw dubbele betaling is terugbetaald",
+	}
+
+	subject, ok := subjects[lang]
+	if !ok {
+		subject = subjects["fr"]
+	}
+
+	newReq.Subject = subject
+	newReq.HTML = htmlContent
+	newReq.Text = plainTextContent
+	newReq.AdditionalHeaders = orderThreadHeaders(orderID)
+
+	err = dispatch(&newReq)
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	logger.Debugf("Email sent to %s with subject: %s", user.Email, subject)
+	return nil
+}
+
 func SendAccountLinkedEmail(user userDomain.User, lang string) error {
 	newReq := *baseReq
 
@@ -849,3 +901,44 @@ func SendFeedbackEmail(name, email, serviceType, feedbackType, message, lang str
 	logger.Debugf("Feedback email sent to admin from %s", email)
 	return nil
 }
+
+// SendDailyReportEmail sends the end-of-day report to the restaurant's email
+// address, with its PDF rendering attached.
+func SendDailyReportEmail(r orderDomain.DailyReport, pdfName string, pdf []byte) error {
+	newReq := *baseReq
+
+	name := brandCfg().Name
+	newReq.To = []*temv1alpha1.CreateEmailRequestAddress{
+		{
+			Email: brandCfg().Email,
+			Name:  &name,
+		},
+	}
+
+	path := "templates/fr/daily-report"
+
+	htmlContent, err := renderDailyReportEmailHTML(path, r)
+	if err != nil {
+		return fmt.Errorf("failed to render email template: %w", err)
+	}
+
+	plainTextContent, err := renderDailyReportEmailText(path, r)
+	if err != nil {
+		return fmt.Errorf("failed to render email template: %w", err)
+	}
+
+	newReq.Subject = fmt.Sprintf("Rapport Z du %s - %s", r.Date.Format("02/01/2006"), name)
+	newReq.HTML = htmlContent
+	newReq.Text = plainTextContent
+	newReq.Attachments = []*temv1alpha1.CreateEmailRequestAttachment{
+		{Name: pdfName, Type: "application/pdf", Content: pdf},
+	}
+
+	err = dispatch(&newReq)
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	logger.Debugf("Daily report for %s sent to %s", r.Date.Format(time.DateOnly), brandCfg().Email)
+	return nil
+}
@@ -2,6 +2,7 @@ package scaleway
 
 import (
 	"crypto/rand"
+	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 	"mime"
@@ -23,7 +24,8 @@ import (
  */
 
 // sendViaSMTP converts a Scaleway-shaped CreateEmailRequest into a
-// multipart/alternative MIME message and delivers it via net/smtp.
+// multipart/alternative MIME message (inside multipart/mixed when it has
+// attachments) and delivers it via net/smtp.
 func sendViaSMTP(req *temv1alpha1.CreateEmailRequest) error {
 	if smtpHost == "" {
 		return fmt.Errorf("sendViaSMTP called without SMTP_HOST configured")
@@ -88,6 +90,38 @@ func buildMimeMessage(req *temv1alpha1.CreateEmailRequest, recipients []string)
 		sb.WriteString(h.Key + ": " + h.Value + "\r\n")
 	}
 
+	// Attachments wrap the alternative parts in a multipart/mixed envelope.
+	if len(req.Attachments) > 0 {
+		mixed := boundary
+		boundary, err = mimeBoundary()
+		if err != nil {
+			return nil, err
+		}
+		sb.WriteString("Content-Type: multipart/mixed; boundary=\"" + mixed + "\"\r\n")
+		sb.WriteString("\r\n")
+		sb.WriteString("--" + mixed + "\r\n")
+		writeAlternativeParts(&sb, req, boundary)
+		for _, a := range req.Attachments {
+			if a == nil {
+				continue
+			}
+			sb.WriteString("--" + mixed + "\r\n")
+			sb.WriteString("Content-Type: " + a.Type + "; name=\"" + a.Name + "\"\r\n")
+			sb.WriteString("Content-Disposition: attachment; filename=\"" + a.Name + "\"\r\n")
+			sb.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+			writeBase64Lines(&sb, a.Content)
+		}
+		sb.WriteString("--" + mixed + "--\r\n")
+		return []byte(sb.String()), nil
+	}
+
+	writeAlternativeParts(&sb, req, boundary)
+	return []byte(sb.String()), nil
+}
+
+// writeAlternativeParts writes the text and HTML bodies as a
+// multipart/alternative entity, headers included.
+func writeAlternativeParts(sb *strings.Builder, req *temv1alpha1.CreateEmailRequest, boundary string) {
 	sb.WriteString("Content-Type: multipart/alternative; boundary=\"" + boundary + "\"\r\n")
 	sb.WriteString("\r\n")
 
@@ -106,7 +140,17 @@ func buildMimeMessage(req *temv1alpha1.CreateEmailRequest, recipients []string)
 	}
 
 	sb.WriteString("--" + boundary + "--\r\n")
-	return []byte(sb.String()), nil
+}
+
+// writeBase64Lines writes content base64-encoded in 76-character lines, as
+// RFC 2045 requires.
+func writeBase64Lines(sb *strings.Builder, content []byte) {
+	encoded := base64.StdEncoding.EncodeToString(content)
+	for len(encoded) > 76 {
+		sb.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	sb.WriteString(encoded + "\r\n")
 }
 
 func mimeBoundary() (string, error) {
@@ -56,10 +56,15 @@ type InvoiceData struct {
 	VatBreakdown     []InvoiceVatLine
 	TotalVatAmount   *string
 	TakeawayDiscount *string
+	TakeawayPercent  *string // rate behind TakeawayDiscount, e.g. "10"
 	CouponDiscount   *string
 	CouponCode       *string
 	DeliveryFee      *string
-	Total            string // final total
+	Total            string  // final total
+	Tip              *string // charged on top of Total, outside the scope of VAT
+	TotalPaid        *string // Total plus Tip; set when Tip is
+	Refunds          []InvoiceRefund
+	NetTotal         *string // paid minus refunds; set when Refunds is not empty
 
 	Address *InvoiceAddress
 }
@@ -72,6 +77,11 @@ type InvoiceItem struct {
 	LineTotal string
 }
 
+type InvoiceRefund struct {
+	Reason string
+	Amount string
+}
+
 type InvoiceVatLine struct {
 	Label  string
 	Rate   string
@@ -95,8 +105,9 @@ func (a *InvoiceAddress) Format() string {
 	return addr
 }
 
-// formatOrderRef returns a short human-readable reference from the order UUID.
-func formatOrderRef(orderID string, orderDate time.Time) string {
+// OrderReference is the short human-readable invoice reference of an order,
+// built from its UUID.
+func OrderReference(orderID string, orderDate time.Time) string {
 	id := strings.ReplaceAll(orderID, "-", "")
 	short := id
 	if len(id) > 8 {
@@ -158,7 +169,7 @@ func GeneratePDF(data InvoiceData) ([]byte, error) {
 	pdf.Ln(6)
 
 	// === ORDER INFO ===
-	orderRef := formatOrderRef(data.OrderID, data.OrderDate)
+	orderRef := OrderReference(data.OrderID, data.OrderDate)
 
 	dateFormat := "02/01/2006 15:04"
 	if data.Language == "en" {
@@ -279,7 +290,11 @@ func GeneratePDF(data InvoiceData) ([]byte, error) {
 
 	if data.TakeawayDiscount != nil {
 		pdf.SetTextColor(0, 150, 80)
-		renderTotalLine(l.TakeawayDiscount, "- "+*data.TakeawayDiscount, false)
+		takeawayLabel := l.TakeawayDiscount
+		if data.TakeawayPercent != nil {
+			takeawayLabel += " (-" + *data.TakeawayPercent + "%)"
+		}
+		renderTotalLine(takeawayLabel, "- "+*data.TakeawayDiscount, false)
 	}
 	if data.CouponDiscount != nil {
 		pdf.SetTextColor(0, 150, 80)
@@ -303,6 +318,32 @@ func GeneratePDF(data InvoiceData) ([]byte, error) {
 	pdf.SetTextColor(30, 30, 30)
 	renderTotalLine(l.Total, data.Total, true)
 
+	// Tip, which the VAT note below does not cover
+	if data.Tip != nil {
+		pdf.SetTextColor(60, 60, 60)
+		renderTotalLine(l.Tip, *data.Tip, false)
+		if data.TotalPaid != nil {
+			pdf.SetTextColor(30, 30, 30)
+			renderTotalLine(l.TotalPaid, *data.TotalPaid, true)
+		}
+	}
+
+	// Refunds issued after payment, and what the customer paid in the end
+	if len(data.Refunds) > 0 {
+		pdf.SetTextColor(0, 150, 80)
+		for _, refund := range data.Refunds {
+			label := l.Refund
+			if refund.Reason != "" {
+				label += " (" + refund.Reason + ")"
+			}
+			renderTotalLine(label, "- "+refund.Amount, false)
+		}
+		if data.NetTotal != nil {
+			pdf.SetTextColor(30, 30, 30)
+			renderTotalLine(l.NetTotal, *data.NetTotal, true)
+		}
+	}
+
 	// VAT included note
 	pdf.Ln(2)
 	pdf.SetTextColor(130, 130, 130)
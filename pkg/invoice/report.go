@@ -0,0 +1,206 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+
+	"tsb-service/pkg/timezone"
+)
+
+// DailyReportData is the end-of-day (Z) report sent to accounting. Amounts
+// are preformatted, like InvoiceData; the report is always in French.
+type DailyReportData struct {
+	Date        time.Time
+	GeneratedAt time.Time
+
+	Orders  int
+	Revenue string
+
+	ByOrderType     []DailyReportRow // Key is "DELIVERY", "PICKUP" or "DINE_IN"
+	ByPaymentMethod []DailyReportRow // Key is "ONLINE", "CASH" or "CARD"
+
+	TakeawayDiscounts string
+	CouponDiscounts   string
+	DeliveryFees      string
+	TransactionFees   string
+	Tips              string
+	Refunds           DailyReportRow
+	Cancellations     DailyReportRow
+
+	Vat      []DailyReportVatLine
+	TotalVat string
+}
+
+type DailyReportRow struct {
+	Key    string
+	Count  int
+	Amount string
+}
+
+type DailyReportVatLine struct {
+	SceCode string
+	Rate    string
+	Gross   string
+	Vat     string
+	Net     string
+}
+
+// DailyReportFilename is the attachment name of the report of date.
+func DailyReportFilename(date time.Time) string {
+	return fmt.Sprintf("rapport-z-%s.pdf", date.Format(time.DateOnly))
+}
+
+var reportKeyLabels = map[string]string{
+	"DELIVERY": "Livraison",
+	"PICKUP":   "À emporter",
+	"DINE_IN":  "Sur place",
+	"ONLINE":   "En ligne (Mollie)",
+	"CASH":     "Espèces",
+	"CARD":     "Carte (sur place)",
+}
+
+func reportKeyLabel(key string) string {
+	if l, ok := reportKeyLabels[key]; ok {
+		return l
+	}
+	return key
+}
+
+// GenerateDailyReportPDF renders the end-of-day report and returns the raw
+// bytes.
+func GenerateDailyReportPDF(data DailyReportData) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(20, 20, 20)
+	pdf.SetAutoPageBreak(true, 20)
+
+	pdf.AddUTF8FontFromBytes("DejaVu", "", dejaVuSansRegular)
+	pdf.AddUTF8FontFromBytes("DejaVu", "B", dejaVuSansBold)
+
+	pdf.AddPage()
+	pageW, _ := pdf.GetPageSize()
+	leftMargin, _, rightMargin, _ := pdf.GetMargins()
+	usableW := pageW - leftMargin - rightMargin
+
+	// === HEADER ===
+	logoSize := 14.0 // mm
+	headerY := pdf.GetY()
+	logoReader := io.NopCloser(bytes.NewReader(logoBytes()))
+	pdf.RegisterImageOptionsReader("logo", fpdf.ImageOptions{ImageType: "PNG"}, logoReader)
+	pdf.ImageOptions("logo", leftMargin, headerY, logoSize, logoSize, false, fpdf.ImageOptions{}, 0, "")
+
+	textH := 10.0
+	pdf.SetY(headerY + (logoSize-textH)/2)
+	pdf.SetX(leftMargin + logoSize + 3)
+	pdf.SetFont("DejaVu", "B", 18)
+	pdf.SetTextColor(30, 30, 30)
+	nameW := usableW - logoSize - 3 - usableW*0.40
+	pdf.CellFormat(nameW, textH, cfg().LegalName, "", 0, "L", false, 0, "")
+	pdf.SetTextColor(200, 50, 50)
+	pdf.CellFormat(usableW*0.40, textH, "Rapport Z", "", 1, "R", false, 0, "")
+
+	pdf.SetY(headerY + logoSize + 2)
+	pdf.SetTextColor(100, 100, 100)
+	pdf.SetFont("DejaVu", "", 9)
+	pdf.CellFormat(usableW, 5, cfg().Address, "", 1, "L", false, 0, "")
+	pdf.CellFormat(usableW, 5, fmt.Sprintf("N° d'entreprise: %s", cfg().VAT), "", 1, "L", false, 0, "")
+
+	pdf.Ln(6)
+	pdf.SetDrawColor(220, 220, 220)
+	pdf.Line(leftMargin, pdf.GetY(), pageW-rightMargin, pdf.GetY())
+	pdf.Ln(6)
+
+	pdf.SetTextColor(30, 30, 30)
+	pdf.SetFont("DejaVu", "B", 11)
+	pdf.CellFormat(usableW/2, 6, "Journée du "+data.Date.Format("02/01/2006"), "", 0, "L", false, 0, "")
+	pdf.SetFont("DejaVu", "", 9)
+	pdf.SetTextColor(100, 100, 100)
+	pdf.CellFormat(usableW/2, 6, "Généré le "+timezone.In(data.GeneratedAt).Format("02/01/2006 15:04"), "", 1, "R", false, 0, "")
+	pdf.Ln(4)
+
+	// === TABLES ===
+	section := func(title string) {
+		pdf.Ln(3)
+		pdf.SetTextColor(30, 30, 30)
+		pdf.SetFont("DejaVu", "B", 10)
+		pdf.CellFormat(usableW, 7, title, "", 1, "L", false, 0, "")
+	}
+	header := func(widths []float64, cols ...string) {
+		pdf.SetFillColor(245, 245, 242)
+		pdf.SetTextColor(60, 60, 60)
+		pdf.SetFont("DejaVu", "B", 9)
+		for i, c := range cols {
+			align := "R"
+			if i == 0 {
+				align = "L"
+			}
+			pdf.CellFormat(widths[i], 7, c, "", 0, align, true, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	row := func(widths []float64, bold bool, cols ...string) {
+		pdf.SetTextColor(30, 30, 30)
+		if bold {
+			pdf.SetFont("DejaVu", "B", 9)
+		} else {
+			pdf.SetFont("DejaVu", "", 9)
+		}
+		for i, c := range cols {
+			align := "R"
+			if i == 0 {
+				align = "L"
+			}
+			pdf.CellFormat(widths[i], 6, c, "", 0, align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	eur := func(amount string) string { return amount + " €" }
+
+	countCols := []float64{usableW * 0.50, usableW * 0.20, usableW * 0.30}
+	bucketTable := func(title string, rows []DailyReportRow) {
+		section(title)
+		header(countCols, "", "Commandes", "Montant TTC")
+		for _, r := range rows {
+			row(countCols, false, reportKeyLabel(r.Key), fmt.Sprintf("%d", r.Count), eur(r.Amount))
+		}
+		row(countCols, true, "Total", fmt.Sprintf("%d", data.Orders), eur(data.Revenue))
+	}
+	bucketTable("Ventes par type de commande", data.ByOrderType)
+	bucketTable("Ventes par moyen de paiement", data.ByPaymentMethod)
+
+	section("Remises, frais et pourboires")
+	amountCols := []float64{usableW * 0.70, usableW * 0.30}
+	row(amountCols, false, "Remises emporter", eur("- "+data.TakeawayDiscounts))
+	row(amountCols, false, "Remises coupons", eur("- "+data.CouponDiscounts))
+	row(amountCols, false, "Frais de livraison", eur(data.DeliveryFees))
+	row(amountCols, false, "Frais de transaction", eur(data.TransactionFees))
+	row(amountCols, false, "Pourboires (hors TVA, hors ventes)", eur(data.Tips))
+
+	section("Remboursements et annulations")
+	header(countCols, "", "Nombre", "Montant TTC")
+	row(countCols, false, "Remboursements émis ce jour", fmt.Sprintf("%d", data.Refunds.Count), eur(data.Refunds.Amount))
+	row(countCols, false, "Commandes annulées", fmt.Sprintf("%d", data.Cancellations.Count), eur(data.Cancellations.Amount))
+
+	section("TVA par code SCE")
+	vatCols := []float64{usableW * 0.16, usableW * 0.12, usableW * 0.24, usableW * 0.24, usableW * 0.24}
+	header(vatCols, "Code", "Taux", "Base HTVA", "TVA", "TTC")
+	for _, v := range data.Vat {
+		row(vatCols, false, v.SceCode, v.Rate+"%", eur(v.Net), eur(v.Vat), eur(v.Gross))
+	}
+	row(vatCols, true, "Total", "", "", eur(data.TotalVat), "")
+
+	pdf.Ln(4)
+	pdf.SetTextColor(130, 130, 130)
+	pdf.SetFont("DejaVu", "", 8)
+	pdf.MultiCell(usableW, 4, "La TVA est calculée sur le total des lignes vendues, avant remises et frais, "+
+		"comme sur les factures. Les commandes de test et les paiements en ligne non aboutis sont exclus.", "", "L", false)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to generate PDF: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -20,6 +20,10 @@ type labels struct {
 	CouponDiscount   string
 	DeliveryFee      string
 	TotalVAT         string
+	Tip              string
+	TotalPaid        string
+	Refund           string
+	NetTotal         string
 	ThankYou         string
 	CompanyNumber    string
 	Phone            string
@@ -44,10 +48,14 @@ var translations = map[string]labels{
 		UnitPrice:        "Prix unit.",
 		Total:            "Total",
 		Subtotal:         "Sous-total",
-		TakeawayDiscount: "Remise emporter (-10%)",
+		TakeawayDiscount: "Remise emporter",
 		CouponDiscount:   "Coupon",
 		DeliveryFee:      "Frais de livraison",
 		TotalVAT:         "Total TVA",
+		Tip:              "Pourboire (hors TVA)",
+		TotalPaid:        "Total payé",
+		Refund:           "Remboursement",
+		NetTotal:         "Total après remboursement",
 		ThankYou:         "Merci pour votre commande !",
 		CompanyNumber:    "N° d'entreprise",
 		Phone:            "Tél",
@@ -70,10 +78,14 @@ var translations = map[string]labels{
 		UnitPrice:        "Unit price",
 		Total:            "Total",
 		Subtotal:         "Subtotal",
-		TakeawayDiscount: "Takeaway discount (-10%)",
+		TakeawayDiscount: "Takeaway discount",
 		CouponDiscount:   "Coupon",
 		DeliveryFee:      "Delivery fee",
 		TotalVAT:         "Total VAT",
+		Tip:              "Tip (no VAT)",
+		TotalPaid:        "Total paid",
+		Refund:           "Refund",
+		NetTotal:         "Total after refunds",
 		ThankYou:         "Thank you for your order!",
 		CompanyNumber:    "Company no.",
 		Phone:            "Phone",
@@ -0,0 +1,252 @@
+// Package spreadsheet writes tabular exports as CSV or XLSX one row at a
+// time, so an export of any size streams to the client in constant memory.
+//
+// Cells are strings, integers or decimals; nil pointers and nil give empty
+// cells. Decimals are written with two decimals, as numbers in XLSX so
+// spreadsheet formulas work on them.
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Format is a file format an export can be written in.
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatXLSX Format = "xlsx"
+)
+
+// ContentType is the MIME type of files in format f.
+func (f Format) ContentType() string {
+	if f == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Writer writes rows to an export. Close must be called to complete the
+// file; it does not close the underlying io.Writer.
+type Writer interface {
+	WriteRow(cells ...any) error
+	Close() error
+}
+
+// NewWriter returns a Writer for format f, or an error for an unknown format.
+// sheet names the XLSX worksheet.
+func NewWriter(w io.Writer, f Format, sheet string) (Writer, error) {
+	switch f {
+	case FormatCSV:
+		return NewCSVWriter(w), nil
+	case FormatXLSX:
+		return NewXLSXWriter(w, sheet)
+	default:
+		return nil, fmt.Errorf("unknown export format %q", f)
+	}
+}
+
+type cell struct {
+	text    string
+	numeric bool
+}
+
+func toCell(v any) (cell, error) {
+	switch v := v.(type) {
+	case nil:
+		return cell{}, nil
+	case string:
+		return cell{text: v}, nil
+	case *string:
+		if v == nil {
+			return cell{}, nil
+		}
+		return cell{text: *v}, nil
+	case int:
+		return cell{text: strconv.Itoa(v), numeric: true}, nil
+	case int64:
+		return cell{text: strconv.FormatInt(v, 10), numeric: true}, nil
+	case decimal.Decimal:
+		return cell{text: v.StringFixed(2), numeric: true}, nil
+	case *decimal.Decimal:
+		if v == nil {
+			return cell{}, nil
+		}
+		return cell{text: v.StringFixed(2), numeric: true}, nil
+	default:
+		return cell{}, fmt.Errorf("unsupported cell type %T", v)
+	}
+}
+
+// --------------------------------------------------------------------------------
+// CSV
+// --------------------------------------------------------------------------------
+
+type csvWriter struct {
+	out     io.Writer
+	w       *csv.Writer
+	started bool
+}
+
+// NewCSVWriter writes RFC 4180 CSV, prefixed with a UTF-8 byte order mark so
+// Excel reads accented product names correctly. Text cells that would be read
+// as a formula are escaped.
+func NewCSVWriter(w io.Writer) Writer {
+	return &csvWriter{out: w, w: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) WriteRow(cells ...any) error {
+	if !c.started {
+		c.started = true
+		// Nothing is buffered yet, so the mark lands ahead of the first row.
+		if _, err := io.WriteString(c.out, "\ufeff"); err != nil {
+			return err
+		}
+	}
+	record := make([]string, len(cells))
+	for i, v := range cells {
+		cl, err := toCell(v)
+		if err != nil {
+			return err
+		}
+		if !cl.numeric {
+			cl.text = escapeFormula(cl.text)
+		}
+		record[i] = cl.text
+	}
+	return c.w.Write(record)
+}
+
+// escapeFormula keeps a spreadsheet from evaluating text as a formula, which
+// customer-entered fields (notes, names) could otherwise inject: text that
+// starts like one gets a leading quote. Numbers are written as they are, so
+// negative amounts stay numbers.
+func escapeFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// --------------------------------------------------------------------------------
+// XLSX
+// --------------------------------------------------------------------------------
+
+// xlsxWriter writes a single-sheet workbook. The worksheet is the first zip
+// entry and is streamed row by row; the small workbook parts that reference
+// it are written on Close.
+type xlsxWriter struct {
+	zw    *zip.Writer
+	sheet *bufio.Writer
+	name  string
+	rows  int
+}
+
+// NewXLSXWriter starts an Office Open XML workbook with one worksheet named
+// sheet. Strings are written inline, so the workbook needs no shared string
+// table held in memory.
+func NewXLSXWriter(w io.Writer, sheet string) (Writer, error) {
+	zw := zip.NewWriter(w)
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	x := &xlsxWriter{zw: zw, sheet: bufio.NewWriter(f), name: sheet}
+	if _, err := x.sheet.WriteString(xml.Header +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+func (x *xlsxWriter) WriteRow(cells ...any) error {
+	x.rows++
+	var b strings.Builder
+	fmt.Fprintf(&b, `<row r="%d">`, x.rows)
+	for i, v := range cells {
+		cl, err := toCell(v)
+		if err != nil {
+			return err
+		}
+		if cl.text == "" {
+			continue
+		}
+		ref := columnName(i) + strconv.Itoa(x.rows)
+		if cl.numeric {
+			fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, cl.text)
+			continue
+		}
+		fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+		if err := xml.EscapeText(&b, []byte(cl.text)); err != nil {
+			return err
+		}
+		b.WriteString(`</t></is></c>`)
+	}
+	b.WriteString(`</row>`)
+	_, err := x.sheet.WriteString(b.String())
+	return err
+}
+
+func (x *xlsxWriter) Close() error {
+	if _, err := x.sheet.WriteString(`</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	var name strings.Builder
+	if err := xml.EscapeText(&name, []byte(x.name)); err != nil {
+		return err
+	}
+	parts := []struct{ path, body string }{
+		{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+			`</Types>`},
+		{"_rels/.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+			`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="` + name.String() + `" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`</Relationships>`},
+	}
+	for _, p := range parts {
+		f, err := x.zw.Create(p.path)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, xml.Header+p.body); err != nil {
+			return err
+		}
+	}
+	return x.zw.Close()
+}
+
+// columnName is the spreadsheet name of the zero-based column i: A, B, …, Z,
+// AA, AB, …
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
@@ -0,0 +1,190 @@
+package ticket
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Paper is the width of a thermal printer roll, in millimetres.
+type Paper int
+
+const (
+	Paper58 Paper = 58
+	Paper80 Paper = 80
+)
+
+// ParsePaper reads a roll width: "58" or "80".
+func ParsePaper(s string) (Paper, error) {
+	switch s {
+	case "58":
+		return Paper58, nil
+	case "80":
+		return Paper80, nil
+	default:
+		return 0, fmt.Errorf("unsupported paper width %q", s)
+	}
+}
+
+// Columns is how many characters of the printer's default font (font A,
+// 12×24 dots) fit on a line.
+func (p Paper) Columns() int {
+	if p == Paper58 {
+		return 32
+	}
+	return 48
+}
+
+type align byte
+
+const (
+	alignLeft   align = 0
+	alignCenter align = 1
+)
+
+// ESC/POS commands, as documented in Epson's ESC/POS reference and
+// understood by the common 58/80 mm clones.
+const (
+	esc = 0x1b
+	gs  = 0x1d
+	lf  = 0x0a
+
+	// codePage858 is the ESC t number of PC858: Latin-1 with the euro sign.
+	codePage858 = 19
+)
+
+// builder accumulates an ESC/POS byte stream. Text is encoded in PC858 and
+// wrapped to the paper width, accounting for double-width text; characters
+// the code page lacks print as "?".
+type builder struct {
+	buf   bytes.Buffer
+	cols  int
+	scale int
+}
+
+func newBuilder(p Paper) *builder {
+	b := &builder{cols: p.Columns(), scale: 1}
+	b.buf.Write([]byte{esc, '@', esc, 't', codePage858})
+	return b
+}
+
+func (b *builder) align(a align) {
+	b.buf.Write([]byte{esc, 'a', byte(a)})
+}
+
+func (b *builder) bold(on bool) {
+	n := byte(0)
+	if on {
+		n = 1
+	}
+	b.buf.Write([]byte{esc, 'E', n})
+}
+
+// size sets the character magnification, 1 (normal) or 2 (double) in each
+// direction. Double height keeps the line width; double width halves it.
+func (b *builder) size(width, height int) {
+	b.scale = width
+	n := byte(width-1)<<4 | byte(height-1)
+	b.buf.Write([]byte{gs, '!', n})
+}
+
+// width is how many characters fit on a line at the current size.
+func (b *builder) width() int {
+	return b.cols / b.scale
+}
+
+// line prints s, wrapped on spaces to the line width.
+func (b *builder) line(s string) {
+	for _, l := range wrap(s, b.width()) {
+		b.write(l)
+		b.buf.WriteByte(lf)
+	}
+}
+
+// indented prints s wrapped with every line starting with prefix-wide
+// indentation, the first with prefix itself.
+func (b *builder) indented(prefix, s string) {
+	pad := strings.Repeat(" ", len([]rune(prefix)))
+	for i, l := range wrap(s, b.width()-len([]rune(prefix))) {
+		if i == 0 {
+			b.write(prefix + l)
+		} else {
+			b.write(pad + l)
+		}
+		b.buf.WriteByte(lf)
+	}
+}
+
+// pair prints left and right on one line, right flush against the right
+// margin; left wraps above it when both do not fit.
+func (b *builder) pair(left, right string) {
+	w := b.width()
+	rw := len([]rune(right))
+	lines := wrap(left, max(w-rw-1, 1))
+	for _, l := range lines[:len(lines)-1] {
+		b.write(l)
+		b.buf.WriteByte(lf)
+	}
+	last := lines[len(lines)-1]
+	gap := max(w-len([]rune(last))-rw, 1)
+	b.write(last + strings.Repeat(" ", gap) + right)
+	b.buf.WriteByte(lf)
+}
+
+// rule prints a full-width line of c.
+func (b *builder) rule(c rune) {
+	b.write(strings.Repeat(string(c), b.width()))
+	b.buf.WriteByte(lf)
+}
+
+func (b *builder) feed(lines int) {
+	b.buf.Write([]byte{esc, 'd', byte(lines)})
+}
+
+// cut feeds the paper past the cutter and makes a partial cut.
+func (b *builder) cut() {
+	b.buf.Write([]byte{gs, 'V', 66, 3})
+}
+
+func (b *builder) bytes() []byte {
+	return b.buf.Bytes()
+}
+
+func (b *builder) write(s string) {
+	for _, r := range s {
+		if unicode.IsControl(r) {
+			r = ' '
+		}
+		c, ok := charmap.CodePage858.EncodeRune(r)
+		if !ok {
+			c = '?'
+		}
+		b.buf.WriteByte(c)
+	}
+}
+
+// wrap splits s into lines of at most width runes, breaking on spaces and
+// cutting words longer than a line. It always returns at least one line.
+func wrap(s string, width int) []string {
+	var lines []string
+	var cur []rune
+	for _, word := range strings.Fields(s) {
+		w := []rune(word)
+		if len(cur) > 0 && len(cur)+1+len(w) <= width {
+			cur = append(append(cur, ' '), w...)
+			continue
+		}
+		if len(cur) > 0 {
+			lines = append(lines, string(cur))
+		}
+		for len(w) > width {
+			lines = append(lines, string(w[:width]))
+			w = w[width:]
+		}
+		cur = w
+	}
+	return append(lines, string(cur))
+}
@@ -0,0 +1,301 @@
+// Package ticket renders orders as ESC/POS byte streams for 58 and 80 mm
+// thermal printers: a kitchen ticket to prepare the order and a customer
+// ticket to hand over with it.
+package ticket
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"tsb-service/pkg/brand"
+	"tsb-service/pkg/timezone"
+)
+
+// Layout is which ticket to print for an order.
+type Layout string
+
+const (
+	// LayoutKitchen lists what to prepare, in large type and without prices.
+	LayoutKitchen Layout = "kitchen"
+	// LayoutCustomer is the receipt for the customer or the delivery driver,
+	// with prices, address and cash change due.
+	LayoutCustomer Layout = "customer"
+)
+
+type TicketData struct {
+	Reference string
+	OrderDate time.Time
+	OrderType string     // "DELIVERY", "PICKUP" or "DINE_IN"
+	Language  string     // ticket language; see Language
+	ReadyTime *time.Time // nil when the order is wanted as soon as possible
+	TableName *string    // DINE_IN orders
+
+	CustomerName  string
+	CustomerPhone *string
+	Address       *string // delivery address, on one line
+	AddressExtra  *string
+
+	Items  []TicketItem
+	Note   *string
+	Extras []TicketExtra
+
+	// Amounts are formatted, without currency sign. They only print on
+	// the customer ticket.
+	Subtotal         string
+	TakeawayDiscount *string
+	CouponDiscount   *string
+	DeliveryFee      *string
+	Total            string
+	Tip              *string
+	TotalPaid        *string // Total plus Tip; set when Tip is
+
+	IsOnlinePayment bool
+	CashGiven       *string // cash the customer said they will pay with
+	ChangeDue       *string // CashGiven minus Total; set when CashGiven is
+}
+
+type TicketItem struct {
+	Quantity  int64
+	Code      string
+	Name      string
+	Options   []string // choice and choice-group selections, one per line
+	LineTotal string
+}
+
+type TicketExtra struct {
+	Name    string
+	Options []string
+}
+
+// Generate renders the layout ticket for data.
+func Generate(layout Layout, data TicketData, paper Paper) ([]byte, error) {
+	switch layout {
+	case LayoutKitchen:
+		return GenerateKitchenTicket(data, paper), nil
+	case LayoutCustomer:
+		return GenerateCustomerTicket(data, paper), nil
+	default:
+		return nil, fmt.Errorf("unknown ticket layout %q", layout)
+	}
+}
+
+// GenerateKitchenTicket renders the ticket the kitchen prepares the order
+// from.
+func GenerateKitchenTicket(data TicketData, paper Paper) []byte {
+	l := getLabels(data.Language)
+	b := newBuilder(paper)
+
+	b.align(alignCenter)
+	b.bold(true)
+	b.size(2, 2)
+	b.line(orderTypeLabel(l, data.OrderType))
+	if data.TableName != nil {
+		b.line(l.Table + " " + *data.TableName)
+	}
+	b.size(1, 2)
+	b.line(readyLabel(l, data))
+	b.size(1, 1)
+	b.bold(false)
+	b.line(data.Reference)
+	b.line(l.Ordered + " " + timezone.In(data.OrderDate).Format(l.DateFormat))
+
+	b.align(alignLeft)
+	b.rule('=')
+	for _, it := range data.Items {
+		b.bold(true)
+		b.size(1, 2)
+		b.indented(fmt.Sprintf("%dx ", it.Quantity), itemName(it))
+		b.size(1, 1)
+		b.bold(false)
+		for _, o := range it.Options {
+			b.indented("   - ", o)
+		}
+	}
+	b.rule('=')
+
+	writeNotes(b, l, data)
+	b.feed(3)
+	b.cut()
+	return b.bytes()
+}
+
+// GenerateCustomerTicket renders the receipt handed over with the order.
+func GenerateCustomerTicket(data TicketData, paper Paper) []byte {
+	l := getLabels(data.Language)
+	cfg := brand.Current()
+	b := newBuilder(paper)
+
+	b.align(alignCenter)
+	b.bold(true)
+	b.size(2, 2)
+	b.line(cfg.Name)
+	b.size(1, 1)
+	b.bold(false)
+	b.line(cfg.Address)
+	b.line(l.Phone + ": " + cfg.Phone)
+	b.line(cfg.VAT)
+	b.rule('-')
+
+	b.bold(true)
+	b.size(1, 2)
+	b.line(orderTypeLabel(l, data.OrderType))
+	b.size(1, 1)
+	b.line(readyLabel(l, data))
+	b.bold(false)
+	b.line(data.Reference)
+	b.line(l.Ordered + " " + timezone.In(data.OrderDate).Format(l.DateFormat))
+	if data.TableName != nil {
+		b.line(l.Table + " " + *data.TableName)
+	}
+
+	b.align(alignLeft)
+	if data.CustomerName != "" || data.Address != nil {
+		b.rule('-')
+	}
+	if data.CustomerName != "" {
+		b.line(l.Customer + ": " + data.CustomerName)
+	}
+	if data.CustomerPhone != nil {
+		b.line(l.Phone + ": " + *data.CustomerPhone)
+	}
+	if data.Address != nil {
+		b.bold(true)
+		b.line(l.Address + ": " + *data.Address)
+		b.bold(false)
+		if data.AddressExtra != nil && *data.AddressExtra != "" {
+			b.line(*data.AddressExtra)
+		}
+	}
+
+	b.rule('-')
+	for _, it := range data.Items {
+		b.pair(fmt.Sprintf("%dx %s", it.Quantity, itemName(it)), euros(it.LineTotal))
+		for _, o := range it.Options {
+			b.indented("   - ", o)
+		}
+	}
+	b.rule('-')
+
+	b.pair(l.Subtotal, euros(data.Subtotal))
+	if data.TakeawayDiscount != nil {
+		b.pair(l.TakeawayDisc, "-"+euros(*data.TakeawayDiscount))
+	}
+	if data.CouponDiscount != nil {
+		b.pair(l.CouponDisc, "-"+euros(*data.CouponDiscount))
+	}
+	if data.DeliveryFee != nil {
+		b.pair(l.DeliveryFee, euros(*data.DeliveryFee))
+	}
+	b.bold(true)
+	b.size(1, 2)
+	b.pair(l.Total, euros(data.Total))
+	b.size(1, 1)
+	b.bold(false)
+	if data.Tip != nil {
+		b.pair(l.Tip, euros(*data.Tip))
+	}
+	if data.TotalPaid != nil {
+		b.pair(l.TotalPaid, euros(*data.TotalPaid))
+	}
+	b.line(l.VATIncluded)
+
+	// Dine-in rounds are paid with the table's bill.
+	if data.OrderType != "DINE_IN" {
+		b.rule('-')
+		writePayment(b, l, data)
+	}
+
+	if data.Note != nil || len(data.Extras) > 0 {
+		b.rule('-')
+	}
+	writeNotes(b, l, data)
+
+	b.feed(1)
+	b.align(alignCenter)
+	b.line(l.ThankYou)
+	b.feed(3)
+	b.cut()
+	return b.bytes()
+}
+
+func writePayment(b *builder, l labels, data TicketData) {
+	if data.IsOnlinePayment {
+		b.line(l.PaidOnline)
+		return
+	}
+	b.bold(true)
+	b.line(l.PayCash)
+	b.bold(false)
+	if data.CashGiven != nil {
+		b.pair(l.CashGiven, euros(*data.CashGiven))
+	}
+	if data.ChangeDue != nil {
+		b.bold(true)
+		b.size(1, 2)
+		b.pair(l.ChangeDue, euros(*data.ChangeDue))
+		b.size(1, 1)
+		b.bold(false)
+	}
+}
+
+// writeNotes prints the customer's note and extras, if any.
+func writeNotes(b *builder, l labels, data TicketData) {
+	if data.Note != nil && strings.TrimSpace(*data.Note) != "" {
+		b.bold(true)
+		b.line(l.Note + ":")
+		b.bold(false)
+		b.line(*data.Note)
+	}
+	if len(data.Extras) > 0 {
+		b.bold(true)
+		b.line(l.Extras + ":")
+		b.bold(false)
+		for _, e := range data.Extras {
+			text := l.extraName(e.Name)
+			if len(e.Options) > 0 {
+				opts := make([]string, len(e.Options))
+				for i, o := range e.Options {
+					opts[i] = l.extraName(o)
+				}
+				text += ": " + strings.Join(opts, ", ")
+			}
+			b.indented(" - ", text)
+		}
+	}
+}
+
+func orderTypeLabel(l labels, orderType string) string {
+	switch orderType {
+	case "DELIVERY":
+		return l.TypeDelivery
+	case "DINE_IN":
+		return l.TypeDineIn
+	default:
+		return l.TypePickup
+	}
+}
+
+// readyLabel is when the order is due, with the day when it is not the day
+// it was placed.
+func readyLabel(l labels, data TicketData) string {
+	if data.ReadyTime == nil {
+		return l.ASAP
+	}
+	ready := timezone.In(*data.ReadyTime)
+	if ready.Format(time.DateOnly) != timezone.In(data.OrderDate).Format(time.DateOnly) {
+		return l.ReadyAt + " " + ready.Format(l.DateFormat)
+	}
+	return l.ReadyAt + " " + ready.Format(l.TimeFormat)
+}
+
+func itemName(it TicketItem) string {
+	if it.Code == "" {
+		return it.Name
+	}
+	return it.Code + " " + it.Name
+}
+
+func euros(amount string) string {
+	return amount + " €"
+}
@@ -0,0 +1,135 @@
+package ticket
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/encoding/charmap"
+
+	"tsb-service/pkg/timezone"
+)
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		width int
+		want  []string
+	}{
+		{"fits", "Maki saumon", 32, []string{"Maki saumon"}},
+		{"breaks on spaces", "Maki saumon avocat", 11, []string{"Maki saumon", "avocat"}},
+		{"cuts long words", "abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"empty", "", 10, []string{""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := wrap(tt.in, tt.width)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("wrap(%q, %d) = %q, want %q", tt.in, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPair(t *testing.T) {
+	b := newBuilder(Paper58)
+	start := b.buf.Len()
+	b.pair("2x Maki", "7,00 €")
+	got, _ := charmap.CodePage858.NewDecoder().Bytes(b.bytes()[start:])
+	want := "2x Maki" + strings.Repeat(" ", 32-7-6) + "7,00 €\n"
+	if string(got) != want {
+		t.Errorf("pair = %q, want %q", got, want)
+	}
+}
+
+func sampleTicket() TicketData {
+	placed := time.Date(2026, 10, 16, 18, 5, 0, 0, timezone.Location)
+	ready := placed.Add(40 * time.Minute)
+	note := "Sonnez deux fois"
+	addr := "Rue de la Cathédrale 59, 4000 Liège"
+	cash := "50,00"
+	change := "12,50"
+	return TicketData{
+		Reference:    "TSB-2026-1A2B3C4D",
+		OrderDate:    placed,
+		OrderType:    "DELIVERY",
+		Language:     "fr",
+		ReadyTime:    &ready,
+		CustomerName: "Jean Dupont",
+		Address:      &addr,
+		Items: []TicketItem{
+			{Quantity: 2, Code: "M1", Name: "Maki saumon", Options: []string{"Sauce : sucrée"}, LineTotal: "12,00"},
+			{Quantity: 1, Name: "Gyoza", LineTotal: "25,50"},
+		},
+		Note:      &note,
+		Extras:    []TicketExtra{{Name: "chopsticks"}, {Name: "sauce", Options: []string{"both"}}},
+		Subtotal:  "37,50",
+		Total:     "37,50",
+		CashGiven: &cash,
+		ChangeDue: &change,
+	}
+}
+
+// commands matches the ESC/POS commands the builder emits, to measure the
+// printed text alone.
+var commands = regexp.MustCompile(`(?s)\x1b@|[\x1b\x1d]..`)
+
+func decode(t *testing.T, b []byte) string {
+	t.Helper()
+	s, err := charmap.CodePage858.NewDecoder().Bytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(s)
+}
+
+func TestGenerateKitchenTicket(t *testing.T) {
+	out := GenerateKitchenTicket(sampleTicket(), Paper80)
+	if !bytes.HasPrefix(out, []byte{esc, '@', esc, 't', codePage858}) {
+		t.Errorf("ticket does not start by initializing the printer: % x", out[:5])
+	}
+	if !bytes.HasSuffix(out, []byte{gs, 'V', 66, 3}) {
+		t.Errorf("ticket does not end with a cut")
+	}
+	text := decode(t, out)
+	for _, want := range []string{
+		"LIVRAISON", "Prêt à 18:45", "TSB-2026-1A2B3C4D",
+		"2x M1 Maki saumon", "   - Sauce : sucrée", "1x Gyoza",
+		"Sonnez deux fois", " - Baguettes", " - Sauce: les deux",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("kitchen ticket lacks %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "€") || strings.Contains(text, "Liège") {
+		t.Errorf("kitchen ticket shows prices or the address:\n%s", text)
+	}
+}
+
+func TestGenerateCustomerTicket(t *testing.T) {
+	data := sampleTicket()
+	data.Language = "zh" // falls back to French
+	text := decode(t, GenerateCustomerTicket(data, Paper58))
+	for _, want := range []string{
+		"Client: Jean Dupont", "Adresse: Rue de la Cathédrale", "25,50 €",
+		"Paiement en espèces", "Monnaie à rendre", "12,50 €",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("customer ticket lacks %q:\n%s", want, text)
+		}
+	}
+	for _, line := range strings.Split(commands.ReplaceAllString(text, ""), "\n") {
+		if n := len([]rune(line)); n > Paper58.Columns() {
+			t.Errorf("line %q is %d characters wide, over %d", line, n, Paper58.Columns())
+		}
+	}
+}
+
+func TestGenerateUnknownLayout(t *testing.T) {
+	if _, err := Generate("bar", sampleTicket(), Paper80); err == nil {
+		t.Error("Generate accepted an unknown layout")
+	}
+}
@@ -0,0 +1,166 @@
+package ticket
+
+type labels struct {
+	TypeDelivery string
+	TypePickup   string
+	TypeDineIn   string
+	Table        string
+	Ordered      string
+	ReadyAt      string
+	ASAP         string
+	Customer     string
+	Phone        string
+	Address      string
+	Note         string
+	Extras       string
+	Subtotal     string
+	TakeawayDisc string
+	CouponDisc   string
+	DeliveryFee  string
+	Total        string
+	Tip          string
+	TotalPaid    string
+	PaidOnline   string
+	PayCash      string
+	CashGiven    string
+	ChangeDue    string
+	VATIncluded  string
+	ThankYou     string
+	DateFormat   string
+	TimeFormat   string
+	ExtraNames   map[string]string // OrderExtra names and options
+}
+
+var translations = map[string]labels{
+	"fr": {
+		TypeDelivery: "LIVRAISON",
+		TypePickup:   "À EMPORTER",
+		TypeDineIn:   "SUR PLACE",
+		Table:        "Table",
+		Ordered:      "Commandé le",
+		ReadyAt:      "Prêt à",
+		ASAP:         "Dès que possible",
+		Customer:     "Client",
+		Phone:        "Tél",
+		Address:      "Adresse",
+		Note:         "Note",
+		Extras:       "Extras",
+		Subtotal:     "Sous-total",
+		TakeawayDisc: "Remise emporter",
+		CouponDisc:   "Coupon",
+		DeliveryFee:  "Frais de livraison",
+		Total:        "TOTAL",
+		Tip:          "Pourboire",
+		TotalPaid:    "Total payé",
+		PaidOnline:   "Payé en ligne",
+		PayCash:      "Paiement en espèces",
+		CashGiven:    "Montant remis",
+		ChangeDue:    "Monnaie à rendre",
+		VATIncluded:  "TVA comprise",
+		ThankYou:     "Merci et bon appétit !",
+		DateFormat:   "02/01/2006 15:04",
+		TimeFormat:   "15:04",
+		ExtraNames: map[string]string{
+			"chopsticks": "Baguettes",
+			"sauce":      "Sauce",
+			"sweet":      "sucrée",
+			"salty":      "salée",
+			"both":       "les deux",
+		},
+	},
+	"en": {
+		TypeDelivery: "DELIVERY",
+		TypePickup:   "PICKUP",
+		TypeDineIn:   "DINE-IN",
+		Table:        "Table",
+		Ordered:      "Ordered",
+		ReadyAt:      "Ready at",
+		ASAP:         "As soon as possible",
+		Customer:     "Customer",
+		Phone:        "Phone",
+		Address:      "Address",
+		Note:         "Note",
+		Extras:       "Extras",
+		Subtotal:     "Subtotal",
+		TakeawayDisc: "Takeaway discount",
+		CouponDisc:   "Coupon",
+		DeliveryFee:  "Delivery fee",
+		Total:        "TOTAL",
+		Tip:          "Tip",
+		TotalPaid:    "Total paid",
+		PaidOnline:   "Paid online",
+		PayCash:      "Cash payment",
+		CashGiven:    "Cash given",
+		ChangeDue:    "Change due",
+		VATIncluded:  "VAT included",
+		ThankYou:     "Thank you, enjoy your meal!",
+		DateFormat:   "01/02/2006 3:04 PM",
+		TimeFormat:   "3:04 PM",
+		ExtraNames: map[string]string{
+			"chopsticks": "Chopsticks",
+			"sauce":      "Sauce",
+			"sweet":      "sweet",
+			"salty":      "salty",
+			"both":       "both",
+		},
+	},
+	"nl": {
+		TypeDelivery: "LEVERING",
+		TypePickup:   "AFHALEN",
+		TypeDineIn:   "TER PLAATSE",
+		Table:        "Tafel",
+		Ordered:      "Besteld op",
+		ReadyAt:      "Klaar om",
+		ASAP:         "Zo snel mogelijk",
+		Customer:     "Klant",
+		Phone:        "Tel",
+		Address:      "Adres",
+		Note:         "Opmerking",
+		Extras:       "Extra's",
+		Subtotal:     "Subtotaal",
+		TakeawayDisc: "Afhaalkorting",
+		CouponDisc:   "Coupon",
+		DeliveryFee:  "Leveringskosten",
+		Total:        "TOTAAL",
+		Tip:          "Fooi",
+		TotalPaid:    "Totaal betaald",
+		PaidOnline:   "Online betaald",
+		PayCash:      "Contante betaling",
+		CashGiven:    "Ontvangen bedrag",
+		ChangeDue:    "Terug te geven",
+		VATIncluded:  "Btw inbegrepen",
+		ThankYou:     "Bedankt en smakelijk!",
+		DateFormat:   "02/01/2006 15:04",
+		TimeFormat:   "15:04",
+		ExtraNames: map[string]string{
+			"chopsticks": "Stokjes",
+			"sauce":      "Saus",
+			"sweet":      "zoet",
+			"salty":      "zout",
+			"both":       "beide",
+		},
+	},
+}
+
+// Language is the ticket language used for lang: the code page printers
+// use has no Chinese glyphs, so anything but English and Dutch prints in
+// French.
+func Language(lang string) string {
+	if _, ok := translations[lang]; ok {
+		return lang
+	}
+	return "fr"
+}
+
+func getLabels(lang string) labels {
+	return translations[Language(lang)]
+}
+
+// extraName is the localized name of an order extra or extra option,
+// printed as entered when it is not a known one.
+func (l labels) extraName(s string) string {
+	if n, ok := l.ExtraNames[s]; ok {
+		return n
+	}
+	return s
+}
@@ -22,6 +22,9 @@ type contextKey string
 const LangKey contextKey = "lang"
 const UserIDKey contextKey = "userID"
 const IsAdminKey contextKey = "isAdmin"
+const IsDriverKey contextKey = "isDriver"
+const IsPOSKey contextKey = "isPOS"
+const JobKey contextKey = "job"
 const ZitadelSubKey contextKey = "zitadelSub"
 const TokenExpiryKey contextKey = "tokenExpiry"
 
@@ -69,6 +72,37 @@ func GetIsAdmin(ctx context.Context) bool {
 	return isAdmin
 }
 
+// SetIsDriver records that the caller holds the delivery driver role.
+func SetIsDriver(ctx context.Context, isDriver bool) context.Context {
+	return context.WithValue(ctx, IsDriverKey, isDriver)
+}
+
+func GetIsDriver(ctx context.Context) bool {
+	isDriver, _ := ctx.Value(IsDriverKey).(bool)
+	return isDriver
+}
+
+// SetIsPOS records that the caller is a POS device; its UUID is the user ID.
+func SetIsPOS(ctx context.Context, isPOS bool) context.Context {
+	return context.WithValue(ctx, IsPOSKey, isPOS)
+}
+
+func GetIsPOS(ctx context.Context) bool {
+	isPOS, _ := ctx.Value(IsPOSKey).(bool)
+	return isPOS
+}
+
+// SetJob names the webhook or background job a context runs for, so the
+// changes it makes can be attributed to it.
+func SetJob(ctx context.Context, job string) context.Context {
+	return context.WithValue(ctx, JobKey, job)
+}
+
+func GetJob(ctx context.Context) string {
+	job, _ := ctx.Value(JobKey).(string)
+	return job
+}
+
 // SetTokenExpiry stores the JWT exp claim (UTC) in the context. Zero means
 // "no expiry information available" and callers should not enforce a deadline
 // on that path.